@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/ormasoftchile/cli-replay/pkg/verify"
+	"github.com/spf13/cobra"
+)
+
+var batchTagFlags []string
+var batchFormatFlag string
+var batchReportFileFlag string
+var batchAllowedCommandsFlag string
+var batchQuietFlag bool
+
+var batchCmd = &cobra.Command{
+	Use:   "batch [flags] <dir|scenario.yaml...> -- <command> [args...]",
+	Short: "Run a command under replay interception for each scenario in a directory",
+	Long: `Run the exec lifecycle once per scenario found under the given directories
+or files, each in its own isolated session, and report the aggregate result.
+
+Directories are walked for .yaml/.yml files as 'cli-replay list' does, and
+every document of a multi-document file is run. With --tag, only scenarios
+carrying one of the given tags in meta.tags are run.
+
+The same command runs for every scenario. In its arguments, {scenario} is
+replaced by the scenario file's absolute path and {name} by its meta.name,
+so each scenario can drive its own test.
+
+A line per scenario and a pass/fail summary are printed to stderr. With
+--format, the aggregate report (counts plus each scenario's verification
+result) is written to stderr, or to --report-file. The command exits
+non-zero if any scenario failed.
+
+Examples:
+  cli-replay batch scenarios/ -- make test
+  cli-replay batch --tag smoke scenarios/ -- ./run-test.sh {name}
+  cli-replay batch --format junit --report-file batch.xml scenarios/ -- go test ./e2e/...`,
+	RunE: runBatch,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	batchCmd.Flags().StringArrayVar(&batchTagFlags, "tag", nil, "Only run scenarios with this tag in meta.tags (repeatable)")
+	batchCmd.Flags().StringVar(&batchFormatFlag, "format", "", "Output format for the aggregate report: json or junit")
+	batchCmd.Flags().StringVar(&batchReportFileFlag, "report-file", "", "Write the aggregate report to file instead of stderr")
+	batchCmd.Flags().StringVar(&batchAllowedCommandsFlag, "allowed-commands", "", "Comma-separated list of commands allowed to be intercepted")
+	batchCmd.Flags().BoolVar(&batchQuietFlag, "quiet", false, "Suppress each session's informational stderr; per-scenario lines and the summary still print")
+	batchCmd.ValidArgsFunction = completeScenarioFiles(0)
+	_ = batchCmd.RegisterFlagCompletionFunc("format", completeValues("json", "junit"))
+	rootCmd.AddCommand(batchCmd)
+}
+
+// batchScenario is one scenario document selected for a batch run.
+type batchScenario struct {
+	path     string
+	scn      *scenario.Scenario
+	document int
+	loadErr  error
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	format := strings.ToLower(batchFormatFlag)
+	switch format {
+	case "", "json", "junit":
+	default:
+		return fmt.Errorf("invalid format %q: valid values are json, junit", batchFormatFlag)
+	}
+
+	dashIdx := cmd.ArgsLenAtDash()
+	if dashIdx < 0 {
+		return fmt.Errorf("missing '--' separator: usage: cli-replay batch <dir> -- <command> [args...]")
+	}
+	if dashIdx == 0 {
+		return fmt.Errorf("missing scenario directory before '--'")
+	}
+	childTemplate := args[dashIdx:]
+	if len(childTemplate) == 0 {
+		return fmt.Errorf("missing command after '--': usage: cli-replay batch <dir> -- <command> [args...]")
+	}
+
+	files, err := findScenarioFiles(args[:dashIdx])
+	if err != nil {
+		return err
+	}
+	selected := selectBatchScenarios(files, batchTagFlags)
+	if len(selected) == 0 {
+		return fmt.Errorf("no scenarios found")
+	}
+
+	aggregate := &verify.BatchResult{}
+	for _, b := range selected {
+		entry := runBatchScenario(b, childTemplate)
+		aggregate.Add(entry)
+		if entry.Passed {
+			fmt.Fprintf(os.Stderr, "cli-replay: batch: ✓ %s (%s)\n", entry.File, entry.Scenario)
+		} else {
+			fmt.Fprintf(os.Stderr, "cli-replay: batch: ✗ %s (%s): %s\n", entry.File, entry.Scenario, entry.Error)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "cli-replay: batch: %d passed, %d failed (%d scenarios)\n",
+		aggregate.Passed, aggregate.Failed, aggregate.Total)
+
+	if format != "" {
+		writeBatchReport(aggregate, format)
+	}
+	if aggregate.Failed > 0 {
+		return fmt.Errorf("%d of %d scenarios failed", aggregate.Failed, aggregate.Total)
+	}
+	return nil
+}
+
+// selectBatchScenarios loads every document of files, keeping those with
+// any of tags (all when tags is empty). A file that fails to load is kept
+// as a failed entry, unless a tag filter is set: its tags are unknown.
+func selectBatchScenarios(files []string, tags []string) []batchScenario {
+	var out []batchScenario
+	for _, f := range files {
+		absPath, err := runner.ResolveScenarioPath(f)
+		if err != nil {
+			absPath = f
+		}
+		docs, err := scenario.LoadFileAll(absPath)
+		if err != nil {
+			if len(tags) == 0 {
+				out = append(out, batchScenario{path: absPath, loadErr: err})
+			}
+			continue
+		}
+		for i, scn := range docs {
+			if !hasAnyTag(&scn.Meta, tags) {
+				continue
+			}
+			out = append(out, batchScenario{path: absPath, scn: scn, document: i})
+		}
+	}
+	return out
+}
+
+// runBatchScenario runs one scenario through the exec lifecycle.
+func runBatchScenario(b batchScenario, childTemplate []string) verify.BatchScenario {
+	entry := verify.BatchScenario{File: b.path}
+	if b.loadErr != nil {
+		entry.ExitCode = 1
+		entry.Error = fmt.Sprintf("failed to load scenario: %v", b.loadErr)
+		return entry
+	}
+	entry.Scenario = b.scn.Meta.Name
+
+	commands, err := execCommands(b.scn, batchAllowedCommandsFlag)
+	if err != nil {
+		entry.ExitCode = 1
+		entry.Error = err.Error()
+		return entry
+	}
+	outcome, err := runExecSession(b.path, b.scn, b.document, commands,
+		batchChildArgv(childTemplate, b.path, b.scn.Meta.Name), execSessionOptions{quiet: batchQuietFlag})
+	entry.ExitCode = outcome.ExitCode
+	entry.Result = outcome.Result
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Passed = true
+	return entry
+}
+
+// batchChildArgv fills the {scenario} and {name} placeholders of the
+// command template for one scenario.
+func batchChildArgv(template []string, scenarioPath, name string) []string {
+	r := strings.NewReplacer("{scenario}", scenarioPath, "{name}", name)
+	argv := make([]string, len(template))
+	for i, arg := range template {
+		argv[i] = r.Replace(arg)
+	}
+	return argv
+}
+
+// writeBatchReport writes the aggregate report to --report-file, or to
+// stderr (stdout is reserved for the child processes).
+func writeBatchReport(result *verify.BatchResult, format string) {
+	var w io.Writer = os.Stderr
+	if batchReportFileFlag != "" {
+		f, err := os.Create(batchReportFileFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cli-replay: warning: could not create report file %q: %v\n", batchReportFileFlag, err)
+			return
+		}
+		defer f.Close() //nolint:errcheck
+		w = f
+	}
+
+	var err error
+	switch format {
+	case "json":
+		err = verify.FormatBatchJSON(w, result)
+	case "junit":
+		err = verify.FormatBatchJUnit(w, result, time.Time{})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cli-replay: warning: failed to write report: %v\n", err)
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/pkg/verify"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeBatchRoot creates a fresh root + batch command tree for testing.
+func makeBatchRoot() *cobra.Command {
+	batchTagFlags = nil
+	batchFormatFlag = ""
+	batchReportFileFlag = ""
+	batchAllowedCommandsFlag = ""
+	batchQuietFlag = false
+
+	root := &cobra.Command{
+		Use:           "cli-replay",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	b := &cobra.Command{
+		Use:  "batch [flags] <dir|scenario.yaml...> -- <command> [args...]",
+		RunE: runBatch,
+	}
+	b.Flags().StringArrayVar(&batchTagFlags, "tag", nil, "Only run scenarios with this tag in meta.tags (repeatable)")
+	b.Flags().StringVar(&batchFormatFlag, "format", "", "Output format for the aggregate report: json or junit")
+	b.Flags().StringVar(&batchReportFileFlag, "report-file", "", "Write the aggregate report to file instead of stderr")
+	b.Flags().StringVar(&batchAllowedCommandsFlag, "allowed-commands", "", "Comma-separated list of commands allowed to be intercepted")
+	b.Flags().BoolVar(&batchQuietFlag, "quiet", false, "Suppress each session's informational stderr; per-scenario lines and the summary still print")
+	root.AddCommand(b)
+	return root
+}
+
+// writeBatchFixtures writes a scenario that passes with a no-op child
+// (its only step is optional) and one that fails (its step is required).
+func writeBatchFixtures(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a-passing.yaml"), []byte(`meta:
+  name: passing
+  tags: [smoke]
+steps:
+  - match:
+      argv: [echo, hello]
+    calls: { min: 0, max: 1 }
+    respond:
+      exit: 0
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b-failing.yaml"), []byte(`meta:
+  name: failing
+  tags: [slow]
+steps:
+  - match:
+      argv: [echo, hello]
+    respond:
+      exit: 0
+`), 0600))
+	return dir
+}
+
+func TestBatch_AggregatesResults(t *testing.T) {
+	dir := writeBatchFixtures(t)
+	report := filepath.Join(t.TempDir(), "batch.json")
+
+	root := makeBatchRoot()
+	root.SetArgs(append([]string{"batch", "--quiet", "--format", "json", "--report-file", report, dir, "--"}, trueCmd()...))
+	var err error
+	stderr := captureStderr(t, func() { err = root.Execute() })
+	require.Error(t, err)
+	assert.Equal(t, "1 of 2 scenarios failed", err.Error())
+	assert.Contains(t, stderr, "cli-replay: batch: 1 passed, 1 failed (2 scenarios)")
+	assert.NotContains(t, stderr, "exec session initialized", "--quiet applies to each session")
+
+	data, readErr := os.ReadFile(report)
+	require.NoError(t, readErr)
+	var result verify.BatchResult
+	require.NoError(t, json.Unmarshal(data, &result))
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, result.Scenarios, 2)
+	assert.Equal(t, "passing", result.Scenarios[0].Scenario)
+	assert.True(t, result.Scenarios[0].Passed)
+	assert.Equal(t, "failing", result.Scenarios[1].Scenario)
+	assert.False(t, result.Scenarios[1].Passed)
+	assert.Equal(t, 1, result.Scenarios[1].ExitCode)
+	assert.Equal(t, "scenario verification failed", result.Scenarios[1].Error)
+	require.NotNil(t, result.Scenarios[1].Result)
+	assert.Equal(t, 0, result.Scenarios[1].Result.ConsumedSteps)
+}
+
+func TestBatch_TagFilter(t *testing.T) {
+	dir := writeBatchFixtures(t)
+
+	root := makeBatchRoot()
+	root.SetArgs(append([]string{"batch", "--quiet", "--tag", "smoke", dir, "--"}, trueCmd()...))
+	var err error
+	stderr := captureStderr(t, func() { err = root.Execute() })
+	require.NoError(t, err)
+	assert.Contains(t, stderr, "1 passed, 0 failed (1 scenarios)")
+
+	root = makeBatchRoot()
+	root.SetArgs(append([]string{"batch", "--tag", "unknown", dir, "--"}, trueCmd()...))
+	err = root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no scenarios found")
+}
+
+func TestBatch_ChildArgvPlaceholders(t *testing.T) {
+	got := batchChildArgv([]string{"./run.sh", "{name}", "--scenario={scenario}"}, "/s/a.yaml", "deploy")
+	assert.Equal(t, []string{"./run.sh", "deploy", "--scenario=/s/a.yaml"}, got)
+}
+
+func TestBatch_RequiresDashSeparator(t *testing.T) {
+	root := makeBatchRoot()
+	root.SetArgs([]string{"batch", t.TempDir()})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing '--' separator")
+}
@@ -15,7 +15,7 @@ var cleanTTLFlag string
 var cleanRecursiveFlag bool
 
 var cleanCmd = &cobra.Command{
-	Use:   "clean [scenario.yaml]",
+	Use:   "clean [scenario.yaml...]",
 	Short: "Clean up intercept session",
 	Long: `Clean up a replay session: remove intercept wrappers and delete state.
 
@@ -23,7 +23,8 @@ If no scenario file is given, uses the CLI_REPLAY_SCENARIO environment
 variable (which was set automatically by 'cli-replay run | Invoke-Expression').
 
 This deletes the state file and removes the intercept directory, so the next
-'cli-replay run' starts fresh.
+'cli-replay run' starts fresh. Several files may be given to clean every
+file of a multi-file run.
 
 Use --ttl to clean only sessions older than a given duration.
 Use --recursive with --ttl to walk a directory tree and clean all expired
@@ -32,16 +33,18 @@ sessions under all .cli-replay/ directories found.
 Examples:
   cli-replay clean                            # uses CLI_REPLAY_SCENARIO from env
   cli-replay clean scenario.yaml              # explicit path
+  cli-replay clean setup.yaml deploy.yaml     # every file of a sequence
   cli-replay clean scenario.yaml --ttl 10m    # only expired sessions
   cli-replay clean --ttl 10m --recursive .    # bulk cleanup under current dir
   cli-replay clean --ttl 1h --recursive /path # bulk cleanup under given path`,
-	Args: cobra.MaximumNArgs(1),
+	Args: cobra.ArbitraryArgs,
 	RunE: runClean,
 }
 
 func init() { //nolint:gochecknoinits // Standard cobra pattern
 	cleanCmd.Flags().StringVar(&cleanTTLFlag, "ttl", "", "Only clean sessions older than this duration (e.g., 10m, 1h)")
 	cleanCmd.Flags().BoolVar(&cleanRecursiveFlag, "recursive", false, "Walk directory tree for .cli-replay/ dirs (requires --ttl)")
+	cleanCmd.ValidArgsFunction = completeScenarioFiles(0)
 	rootCmd.AddCommand(cleanCmd)
 }
 
@@ -53,6 +56,9 @@ func runClean(_ *cobra.Command, args []string) error {
 
 	// T024: TTL mode
 	if cleanTTLFlag != "" {
+		if len(args) > 1 {
+			return fmt.Errorf("--ttl accepts at most one path, got %d", len(args))
+		}
 		ttl, err := time.ParseDuration(cleanTTLFlag)
 		if err != nil {
 			return fmt.Errorf("invalid --ttl value %q: %w", cleanTTLFlag, err)
@@ -70,7 +76,15 @@ func runClean(_ *cobra.Command, args []string) error {
 		return runCleanTTL(args, ttl)
 	}
 
-	// Original behavior: clean specific scenario session
+	// Original behavior: clean specific scenario session(s)
+	if len(args) > 1 {
+		for _, arg := range args {
+			if err := runCleanSession([]string{arg}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	return runCleanSession(args)
 }
 
@@ -86,7 +100,7 @@ func runCleanSession(args []string) error {
 		}
 	}
 
-	absPath, err := filepath.Abs(scenarioPath)
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
 	if err != nil {
 		return fmt.Errorf("failed to resolve scenario path: %w", err)
 	}
@@ -113,6 +127,9 @@ func runCleanSession(args []string) error {
 	if err := runner.DeleteState(stateFile); err != nil {
 		return fmt.Errorf("failed to reset state: %w", err)
 	}
+	if err := runner.DeleteScenarioCache(absPath); err != nil {
+		return err
+	}
 
 	fmt.Fprintf(os.Stderr, "cli-replay: state reset for %s\n", scenarioPath)
 
@@ -131,7 +148,7 @@ func runCleanTTL(args []string, ttl time.Duration) error {
 		}
 	}
 
-	absPath, err := filepath.Abs(scenarioPath)
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
 	if err != nil {
 		return fmt.Errorf("failed to resolve scenario path: %w", err)
 	}
@@ -86,6 +86,24 @@ func TestClean_SessionAware(t *testing.T) {
 	assert.FileExists(t, stateFileNone, "sessionless state should NOT be affected by session-B clean")
 }
 
+func TestClean_RemovesScenarioCache(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	scenarioPath := createMinimalScenario(t, tmpDir)
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
+	require.NoError(t, err)
+
+	cachePath := runner.ScenarioCachePath(absPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(cachePath), 0750))
+	require.NoError(t, os.WriteFile(cachePath, []byte("{}"), 0600))
+
+	root := makeCleanRoot()
+	root.SetArgs([]string{"clean", scenarioPath})
+	require.NoError(t, root.Execute())
+
+	assert.NoFileExists(t, cachePath, "clean removes the parsed-scenario cache")
+}
+
 // T022: Clean idempotency — no error when state file doesn't exist
 func TestClean_Idempotency(t *testing.T) {
 	if runtime.GOOS == "windows" {
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate a shell completion script",
+	Long: `Print a completion script for the given shell to stdout. Scenario
+arguments complete to .yaml/.yml files and --format to its valid values.
+
+To load completions in the current shell:
+  bash:       source <(cli-replay completion bash)
+  zsh:        source <(cli-replay completion zsh)
+  fish:       cli-replay completion fish | source
+  powershell: cli-replay completion powershell | Out-String | Invoke-Expression
+
+Examples:
+  cli-replay completion bash > /etc/bash_completion.d/cli-replay
+  cli-replay completion zsh > "${fpath[1]}/_cli-replay"
+  cli-replay completion fish > ~/.config/fish/completions/cli-replay.fish`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	root, w := cmd.Root(), cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return root.GenBashCompletionV2(w, true)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(w)
+	default:
+		return fmt.Errorf("unsupported shell %q: valid values are bash, zsh, fish, powershell", args[0])
+	}
+}
+
+// completeScenarioFiles completes scenario arguments to .yaml and .yml
+// files. Once maxArgs scenarios are given (0 for no limit), nothing more is
+// offered.
+func completeScenarioFiles(maxArgs int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if maxArgs > 0 && len(args) >= maxArgs {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+	}
+}
+
+// completeValues completes a flag to a fixed set of values.
+func completeValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeCompletion runs the root command with args and returns its stdout.
+func executeCompletion(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs(args)
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+	require.NoError(t, rootCmd.Execute())
+	return out.String()
+}
+
+func TestCompletionCommand_Shells(t *testing.T) {
+	for shell, marker := range map[string]string{
+		"bash":       "complete -o default -F __start_cli-replay cli-replay",
+		"zsh":        "#compdef cli-replay",
+		"fish":       "complete -c cli-replay",
+		"powershell": "Register-ArgumentCompleter",
+	} {
+		t.Run(shell, func(t *testing.T) {
+			out := executeCompletion(t, "completion", shell)
+			assert.Contains(t, out, marker)
+		})
+	}
+}
+
+func TestCompletionCommand_InvalidShell(t *testing.T) {
+	rootCmd.SetArgs([]string{"completion", "tcsh"})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	require.Error(t, rootCmd.Execute())
+}
+
+func TestCompletion_FormatValues(t *testing.T) {
+	out := executeCompletion(t, "__complete", "verify", "--format", "")
+	assert.Contains(t, out, "json\n")
+	assert.Contains(t, out, "junit\n")
+	assert.Contains(t, out, "text\n")
+
+	out = executeCompletion(t, "__complete", "exec", "--format", "")
+	assert.Contains(t, out, "json\n")
+	assert.Contains(t, out, "junit\n")
+	assert.NotContains(t, out, "text\n")
+	verifyFormatFlag, execFormatFlag = "text", ""
+}
+
+func TestCompletion_ScenarioFiles(t *testing.T) {
+	out := executeCompletion(t, "__complete", "verify", "")
+	assert.Contains(t, out, "yaml\nyml\n")
+	assert.Contains(t, out, ":8\n", "ShellCompDirectiveFilterFileExt")
+
+	out = executeCompletion(t, "__complete", "verify", "scenario.yaml", "")
+	assert.NotContains(t, out, "yaml\n")
+	assert.Contains(t, out, ":4\n", "ShellCompDirectiveNoFileComp")
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/recorder"
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertFromFlag   string
+	convertToFlag     string
+	convertOutputFlag string
+	convertNameFlag   string
+)
+
+// convertTimestamp is stamped on every entry produced by YAML→JSONL
+// conversion so the output is stable across runs and diffs cleanly.
+var convertTimestamp = time.Unix(0, 0).UTC()
+
+var convertCmd = &cobra.Command{
+	Use:   "convert --from <jsonl|yaml> --to <yaml|jsonl> <input> [-o <output>]",
+	Short: "Convert between JSONL recordings and scenario YAML",
+	Long: `Convert a raw JSONL recording log (as written by the record shims) into a
+scenario YAML file, or flatten a scenario back into JSONL.
+
+JSONL to YAML uses the same conversion as 'cli-replay record'. YAML to JSONL
+emits one entry per step (group children included) with stdout_file and
+stderr_file fixtures inlined, which makes two scenarios easy to compare with
+diff. Templates are emitted verbatim, not rendered.
+
+The converted model is validated before anything is written. Without
+--output the result is written to stdout.
+
+Examples:
+  cli-replay convert --from jsonl --to yaml recording.jsonl -o scenario.yaml
+  cli-replay convert --from yaml --to jsonl scenario.yaml -o recording.jsonl
+  diff <(cli-replay convert --from yaml --to jsonl a.yaml) \
+       <(cli-replay convert --from yaml --to jsonl b.yaml)`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConvert,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	convertCmd.Flags().StringVar(&convertFromFlag, "from", "", "input format: jsonl or yaml (required)")
+	convertCmd.Flags().StringVar(&convertToFlag, "to", "", "output format: yaml or jsonl (required)")
+	convertCmd.Flags().StringVarP(&convertOutputFlag, "output", "o", "", "output file path (default: stdout)")
+	convertCmd.Flags().StringVarP(&convertNameFlag, "name", "n", "", "scenario name for JSONL→YAML (default: input file name)")
+	_ = convertCmd.MarkFlagRequired("from")
+	_ = convertCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(convertCmd)
+}
+
+// runConvert dispatches on the --from/--to pair and writes the result.
+func runConvert(_ *cobra.Command, args []string) error {
+	from := strings.ToLower(convertFromFlag)
+	to := strings.ToLower(convertToFlag)
+	input := args[0]
+
+	var (
+		out []byte
+		err error
+	)
+	switch {
+	case from == "jsonl" && to == "yaml":
+		out, err = convertJSONLToYAML(input)
+	case from == "yaml" && to == "jsonl":
+		out, err = convertYAMLToJSONL(input)
+	default:
+		return fmt.Errorf("unsupported conversion %q to %q: valid pairs are jsonl→yaml and yaml→jsonl",
+			convertFromFlag, convertToFlag)
+	}
+	if err != nil {
+		return err
+	}
+
+	if convertOutputFlag == "" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(convertOutputFlag, out, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", convertOutputFlag, err)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Converted %s to %s\n", input, convertOutputFlag)
+	return nil
+}
+
+// convertJSONLToYAML parses a recording log and renders it as scenario YAML.
+func convertJSONLToYAML(path string) ([]byte, error) {
+	log, err := recorder.ReadRecordingLog(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	commands, err := log.ToRecordedCommands()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	name := convertNameFlag
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	meta := recorder.SessionMetadata{Name: name, RecordedAt: time.Now().UTC()}
+
+	sc, err := recorder.ConvertToScenario(meta, commands)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert to scenario: %w", err)
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+
+	content, err := recorder.GenerateYAML(sc)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// convertYAMLToJSONL loads a scenario and flattens it into a recording log.
+func convertYAMLToJSONL(path string) ([]byte, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path %s: %w", path, err)
+	}
+	sc, err := scenario.LoadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	baseDir := filepath.Dir(absPath)
+	commands, err := recorder.ScenarioToCommands(sc, convertTimestamp, func(rel string) (string, error) {
+		return runner.ReadFixture(sc, baseDir, rel)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var buf bytes.Buffer
+	if err := recorder.WriteRecordingLog(&buf, commands); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeConvertRoot creates a fresh root + convert command tree for testing.
+func makeConvertRoot() *cobra.Command {
+	convertFromFlag = ""
+	convertToFlag = ""
+	convertOutputFlag = ""
+	convertNameFlag = ""
+
+	root := &cobra.Command{
+		Use:           "cli-replay",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	c := &cobra.Command{
+		Use:  "convert",
+		Args: cobra.ExactArgs(1),
+		RunE: runConvert,
+	}
+	c.Flags().StringVar(&convertFromFlag, "from", "", "input format")
+	c.Flags().StringVar(&convertToFlag, "to", "", "output format")
+	c.Flags().StringVarP(&convertOutputFlag, "output", "o", "", "output file path")
+	c.Flags().StringVarP(&convertNameFlag, "name", "n", "", "scenario name")
+	root.AddCommand(c)
+	return root
+}
+
+func runConvertCmd(t *testing.T, args ...string) error {
+	t.Helper()
+	root := makeConvertRoot()
+	root.SetArgs(append([]string{"convert"}, args...))
+	return root.Execute()
+}
+
+func TestConvert_RoundTripYAMLToJSONLToYAML(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "source.yaml")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pods.txt"), []byte("pod1 Running\n"), 0644))
+	require.NoError(t, os.WriteFile(src, []byte(`meta:
+  name: source
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+      stdout_file: pods.txt
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: [kubectl, logs, pod1]
+          respond:
+            exit: 0
+            stdout: "line one\nline two\n"
+  - match:
+      argv: [kubectl, delete, pod, pod1]
+    respond:
+      exit: 1
+      stderr: "forbidden\n"
+`), 0644))
+
+	jsonl := filepath.Join(dir, "recording.jsonl")
+	require.NoError(t, runConvertCmd(t, "--from", "yaml", "--to", "jsonl", src, "-o", jsonl))
+
+	out := filepath.Join(dir, "roundtrip.yaml")
+	require.NoError(t, runConvertCmd(t, "--from", "jsonl", "--to", "yaml", jsonl, "-o", out))
+
+	original, err := scenario.LoadFile(src)
+	require.NoError(t, err)
+	converted, err := scenario.LoadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "recording", converted.Meta.Name, "name defaults to the input file name")
+
+	want := original.FlatSteps()
+	got := converted.FlatSteps()
+	require.Len(t, got, len(want))
+	assert.Equal(t, "pod1 Running\n", got[0].Respond.Stdout, "stdout_file must be inlined")
+	for i := range want {
+		assert.Equal(t, want[i].Match.Argv, got[i].Match.Argv, "step %d argv", i+1)
+		assert.Equal(t, want[i].Respond.Exit, got[i].Respond.Exit, "step %d exit", i+1)
+		assert.Equal(t, want[i].Respond.Stderr, got[i].Respond.Stderr, "step %d stderr", i+1)
+	}
+	assert.Equal(t, "line one\nline two\n", got[1].Respond.Stdout)
+}
+
+func TestConvert_YAMLToJSONLIsStable(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "s.yaml")
+	require.NoError(t, os.WriteFile(src, []byte(`meta:
+  name: s
+steps:
+  - match:
+      argv: [echo, hi]
+    respond:
+      exit: 0
+      stdout: "hi\n"
+`), 0644))
+
+	a := filepath.Join(dir, "a.jsonl")
+	b := filepath.Join(dir, "b.jsonl")
+	require.NoError(t, runConvertCmd(t, "--from", "yaml", "--to", "jsonl", src, "-o", a))
+	require.NoError(t, runConvertCmd(t, "--from", "yaml", "--to", "jsonl", src, "-o", b))
+
+	dataA, err := os.ReadFile(a)
+	require.NoError(t, err)
+	dataB, err := os.ReadFile(b)
+	require.NoError(t, err)
+	assert.Equal(t, string(dataA), string(dataB))
+	assert.Contains(t, string(dataA), `"argv":["echo","hi"]`)
+}
+
+func TestConvert_InvalidRecordingNotWritten(t *testing.T) {
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "bad.jsonl")
+	require.NoError(t, os.WriteFile(jsonl, []byte(`{"timestamp":"2024-01-15T10:00:00Z","argv":["x"],"exit":300,"stdout":"","stderr":""}`+"\n"), 0644))
+
+	out := filepath.Join(dir, "out.yaml")
+	err := runConvertCmd(t, "--from", "jsonl", "--to", "yaml", jsonl, "-o", out)
+	require.Error(t, err)
+	_, statErr := os.Stat(out)
+	assert.True(t, os.IsNotExist(statErr), "nothing may be written for an invalid recording")
+}
+
+func TestConvert_UnsupportedPair(t *testing.T) {
+	err := runConvertCmd(t, "--from", "yaml", "--to", "yaml", "x.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported conversion")
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/ormasoftchile/cli-replay/pkg/verify"
+	"github.com/spf13/cobra"
+)
+
+var (
+	coverageJSONFlag      bool
+	coverageFailUnderFlag float64
+)
+
+var coverageCmd = &cobra.Command{
+	Use:   "coverage [scenario.yaml]",
+	Short: "Report which scenario steps were exercised",
+	Long: `Report step coverage for the current session of a scenario.
+
+Coverage is the percentage of steps invoked at least once. The report also
+shows the percentage of steps that met their calls.min and lists the steps
+that were never invoked.
+
+If no scenario file is given, uses the CLI_REPLAY_SCENARIO environment
+variable. The session is taken from CLI_REPLAY_SESSION.
+
+With --fail-under, the command exits non-zero when coverage is below the
+given percentage, which makes it usable as a CI gate.
+
+Examples:
+  cli-replay coverage scenario.yaml
+  cli-replay coverage scenario.yaml --json
+  cli-replay coverage scenario.yaml --fail-under 80`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCoverage,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	coverageCmd.Flags().BoolVar(&coverageJSONFlag, "json", false, "Write the report as JSON to stdout")
+	coverageCmd.Flags().Float64Var(&coverageFailUnderFlag, "fail-under", 0,
+		"Exit non-zero if step coverage is below this percentage (0-100)")
+	coverageCmd.ValidArgsFunction = completeScenarioFiles(1)
+	rootCmd.AddCommand(coverageCmd)
+}
+
+// CoverageStep is one step's entry in a coverage report.
+type CoverageStep struct {
+	Index int    `json:"index"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+	Min   int    `json:"min"`
+}
+
+// CoverageReport summarizes how much of a scenario a session exercised.
+type CoverageReport struct {
+	Scenario       string         `json:"scenario"`
+	Session        string         `json:"session"`
+	TotalSteps     int            `json:"total_steps"`
+	InvokedSteps   int            `json:"invoked_steps"`
+	MetMinSteps    int            `json:"met_min_steps"`
+	Coverage       float64        `json:"coverage"`
+	MinCoverage    float64        `json:"min_coverage"`
+	AllStepsMetMin bool           `json:"all_steps_met_min"`
+	NeverInvoked   []CoverageStep `json:"never_invoked"`
+	Steps          []CoverageStep `json:"steps"`
+}
+
+func runCoverage(_ *cobra.Command, args []string) error {
+	if coverageFailUnderFlag < 0 || coverageFailUnderFlag > 100 {
+		return fmt.Errorf("--fail-under must be between 0 and 100, got %g", coverageFailUnderFlag)
+	}
+
+	var scenarioPath string
+	if len(args) > 0 {
+		scenarioPath = args[0]
+	} else {
+		scenarioPath = os.Getenv("CLI_REPLAY_SCENARIO")
+		if scenarioPath == "" {
+			return fmt.Errorf("no scenario specified — pass a file or set CLI_REPLAY_SCENARIO")
+		}
+	}
+
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve scenario path: %w", err)
+	}
+
+	scn, err := scenario.LoadFile(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to load scenario: %w", err)
+	}
+
+	session := os.Getenv("CLI_REPLAY_SESSION")
+	if session == "" {
+		session = "default"
+	}
+
+	state, err := runner.ReadState(runner.StateFilePath(absPath))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read state: %w", err)
+		}
+		// No state yet: nothing has been exercised.
+		state = &runner.State{}
+	}
+
+	report := buildCoverageReport(scn, session, state)
+
+	if coverageJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode coverage report: %w", err)
+		}
+	} else {
+		writeCoverageText(os.Stderr, report)
+	}
+
+	if coverageFailUnderFlag > 0 && report.Coverage < coverageFailUnderFlag {
+		return fmt.Errorf("coverage %.1f%% is below --fail-under %.1f%%", report.Coverage, coverageFailUnderFlag)
+	}
+	return nil
+}
+
+// buildCoverageReport computes invocation and min-met coverage from the
+// session's per-step counts.
+func buildCoverageReport(scn *scenario.Scenario, session string, state *runner.State) *CoverageReport {
+	steps := scn.FlatSteps()
+	report := &CoverageReport{
+		Scenario:       scn.Meta.Name,
+		Session:        session,
+		TotalSteps:     len(steps),
+		AllStepsMetMin: state.AllStepsMetMin(steps),
+		NeverInvoked:   []CoverageStep{},
+		Steps:          make([]CoverageStep, 0, len(steps)),
+	}
+
+	for i, step := range steps {
+		count := 0
+		if i < len(state.StepCounts) {
+			count = state.StepCounts[i]
+		}
+		entry := CoverageStep{
+			Index: i,
+			Label: verify.StepLabel(step),
+			Count: count,
+			Min:   step.EffectiveCalls().Min,
+		}
+		report.Steps = append(report.Steps, entry)
+
+		if count >= 1 {
+			report.InvokedSteps++
+		} else {
+			report.NeverInvoked = append(report.NeverInvoked, entry)
+		}
+		if count >= entry.Min {
+			report.MetMinSteps++
+		}
+	}
+
+	report.Coverage = percent(report.InvokedSteps, report.TotalSteps)
+	report.MinCoverage = percent(report.MetMinSteps, report.TotalSteps)
+	return report
+}
+
+// percent returns n/total as a percentage; an empty scenario is fully covered.
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// writeCoverageText renders the human-readable coverage report.
+func writeCoverageText(w io.Writer, r *CoverageReport) {
+	_, _ = fmt.Fprintf(w, "Coverage for %q (session %s)\n", r.Scenario, r.Session)
+	_, _ = fmt.Fprintf(w, "  invoked:  %d/%d steps (%.1f%%)\n", r.InvokedSteps, r.TotalSteps, r.Coverage)
+	_, _ = fmt.Fprintf(w, "  met min:  %d/%d steps (%.1f%%)\n", r.MetMinSteps, r.TotalSteps, r.MinCoverage)
+	if len(r.NeverInvoked) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(w, "  never invoked:")
+	for _, s := range r.NeverInvoked {
+		_, _ = fmt.Fprintf(w, "    Step %d: %s\n", s.Index+1, s.Label)
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeCoverageRoot creates a fresh root + coverage command tree for testing.
+func makeCoverageRoot() *cobra.Command {
+	coverageJSONFlag = false
+	coverageFailUnderFlag = 0
+
+	root := &cobra.Command{
+		Use:           "cli-replay",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	c := &cobra.Command{
+		Use:  "coverage [scenario.yaml]",
+		Args: cobra.MaximumNArgs(1),
+		RunE: runCoverage,
+	}
+	c.Flags().BoolVar(&coverageJSONFlag, "json", false, "JSON output")
+	c.Flags().Float64Var(&coverageFailUnderFlag, "fail-under", 0, "threshold")
+	root.AddCommand(c)
+	return root
+}
+
+// writeCoverageScenario writes a four-step scenario and a state in which
+// step 1 met its min of 2, step 2 was invoked once against a min of 2, and
+// steps 3 and 4 were never invoked.
+func writeCoverageScenario(t *testing.T) (string, *scenario.Scenario, *runner.State) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`meta:
+  name: coverage-test
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+    calls:
+      min: 2
+      max: 3
+  - match:
+      argv: [kubectl, apply]
+    respond:
+      exit: 0
+    calls:
+      min: 2
+      max: 2
+  - match:
+      argv: [kubectl, rollout, status]
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, delete]
+    respond:
+      exit: 0
+    calls:
+      min: 0
+      max: 1
+`), 0644))
+	scn, err := scenario.LoadFile(path)
+	require.NoError(t, err)
+
+	absPath, err := filepath.Abs(path)
+	require.NoError(t, err)
+	state := runner.NewState(absPath, "", 4)
+	state.StepCounts = []int{2, 1, 0, 0}
+	require.NoError(t, runner.WriteState(runner.StateFilePath(absPath), state))
+	return path, scn, state
+}
+
+func TestBuildCoverageReport_PartialConsumption(t *testing.T) {
+	_, scn, state := writeCoverageScenario(t)
+
+	report := buildCoverageReport(scn, "default", state)
+	assert.Equal(t, 4, report.TotalSteps)
+	assert.Equal(t, 2, report.InvokedSteps)
+	assert.InDelta(t, 50.0, report.Coverage, 0.001)
+	// Step 1 (2>=2) and step 4 (min 0) meet their min.
+	assert.Equal(t, 2, report.MetMinSteps)
+	assert.InDelta(t, 50.0, report.MinCoverage, 0.001)
+	assert.False(t, report.AllStepsMetMin)
+
+	require.Len(t, report.NeverInvoked, 2)
+	assert.Equal(t, 2, report.NeverInvoked[0].Index)
+	assert.Equal(t, "kubectl rollout status", report.NeverInvoked[0].Label)
+	assert.Equal(t, "kubectl delete", report.NeverInvoked[1].Label)
+	assert.Equal(t, 2, report.Steps[0].Count)
+}
+
+func TestBuildCoverageReport_NoState(t *testing.T) {
+	_, scn, _ := writeCoverageScenario(t)
+
+	report := buildCoverageReport(scn, "default", &runner.State{})
+	assert.Equal(t, 0, report.InvokedSteps)
+	assert.InDelta(t, 0.0, report.Coverage, 0.001)
+	assert.Len(t, report.NeverInvoked, 4)
+}
+
+func TestCoverage_FailUnderGate(t *testing.T) {
+	path, _, _ := writeCoverageScenario(t)
+	t.Setenv("CLI_REPLAY_SESSION", "")
+
+	root := makeCoverageRoot()
+	root.SetArgs([]string{"coverage", path, "--fail-under", "50"})
+	require.NoError(t, root.Execute(), "50% coverage meets a 50% threshold")
+
+	root = makeCoverageRoot()
+	root.SetArgs([]string{"coverage", path, "--fail-under", "75"})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "below --fail-under")
+}
+
+func TestCoverage_FailUnderOutOfRange(t *testing.T) {
+	path, _, _ := writeCoverageScenario(t)
+
+	root := makeCoverageRoot()
+	root.SetArgs([]string{"coverage", path, "--fail-under", "150"})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "between 0 and 100")
+}
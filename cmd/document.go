@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// loadSelectedScenario loads every document of the scenario file at
+// absPath and returns the one chosen by --index or --name, with its index.
+// A file with a single document needs neither flag.
+func loadSelectedScenario(absPath string, index int, name string) (*scenario.Scenario, int, error) {
+	docs, err := scenario.LoadFileAll(absPath)
+	if err != nil {
+		return nil, -1, err
+	}
+	return selectDocument(docs, index, name)
+}
+
+// selectDocument applies --index or --name to the documents of a file.
+func selectDocument(docs []*scenario.Scenario, index int, name string) (*scenario.Scenario, int, error) {
+	if index != 0 && name != "" {
+		return nil, -1, fmt.Errorf("--index and --name are mutually exclusive")
+	}
+	return scenario.DocumentSelector{Index: index, Name: name}.Select(docs)
+}
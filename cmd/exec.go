@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ormasoftchile/cli-replay/internal/runner"
@@ -18,6 +21,25 @@ var execAllowedCommandsFlag string
 var execFormatFlag string
 var execReportFileFlag string
 var execDryRunFlag bool
+var execFailFastFlag bool
+var execMaxStdinFlag int64
+var execSeedFlag string
+var execIndexFlag int
+var execNameFlag string
+var execDoneFileFlag string
+var execKeepStateFlag bool
+var execQuietFlag bool
+var execProgressFlag bool
+var execWrapBuiltinsFlag bool
+var execTimeoutFlag string
+var execSnapshotFileFlag string
+
+// failFastPollInterval is how often exec --fail-fast checks the mismatch marker.
+const failFastPollInterval = 20 * time.Millisecond
+
+// execTimeoutExitCode is the exit code of an exec run stopped by
+// --timeout, as with coreutils timeout.
+const execTimeoutExitCode = 124
 
 var execCmd = &cobra.Command{
 	Use:   "exec [flags] <scenario.yaml> -- <command> [args...]",
@@ -26,15 +48,29 @@ var execCmd = &cobra.Command{
 
 Sets up the intercept directory, spawns the command as a child process with
 modified PATH, waits for completion, verifies scenario completion, and cleans
-up — all in a single invocation.
+up — all in a single invocation. With --keep-state, the state file and
+intercept directory are left in place and their paths printed, so a failed
+run can be inspected (remove them later with 'cli-replay clean').
 
 This is the recommended approach for CI/CD pipelines where the three-step
 eval/execute/verify pattern is cumbersome.
 
+When every step has met its min count, a JSON completion marker (scenario,
+session, steps_consumed, finished_at) is written to .cli-replay/<session>.done
+next to the scenario, or to --done-file. It is kept after exec exits and
+removed by 'cli-replay clean --ttl' once older than the TTL.
+
+On Unix, sending SIGUSR1 to exec (kill -USR1 <pid>) writes a JSON snapshot
+of the session state, including the step expected next, to stderr or to
+--snapshot-file, without disturbing the child. The signal is not forwarded.
+
 Exit codes:
   0     Child exited 0 AND all scenario steps satisfied
-  1     Scenario verification failed (steps not consumed)
+  1     Scenario verification failed (steps not consumed), the child
+        was served a mismatch under --fail-fast, or the child invoked a
+        command in meta.security.forbidden_commands
   N     Child's non-zero exit code (takes precedence)
+  124   The run exceeded --timeout and the child process tree was killed
   126   Child command found but not executable
   127   Child command not found
   128+N Child killed by signal N (e.g., 130 = SIGINT)
@@ -42,6 +78,9 @@ Exit codes:
 Examples:
   cli-replay exec scenario.yaml -- ./test-script.sh
   cli-replay exec --allowed-commands=kubectl scenario.yaml -- make test
+  cli-replay exec --fail-fast scenario.yaml -- ./deploy.sh
+  cli-replay exec --timeout 10m scenario.yaml -- make e2e
+  cli-replay exec --name teardown scenarios.yaml -- ./teardown.sh
   cli-replay exec scenario.yaml -- bash -c 'kubectl get pods'`,
 	RunE:              runExec,
 	SilenceUsage:      true,
@@ -53,6 +92,20 @@ func init() { //nolint:gochecknoinits // Standard cobra pattern
 	execCmd.Flags().StringVar(&execFormatFlag, "format", "", "Output format for verification report: json or junit")
 	execCmd.Flags().StringVar(&execReportFileFlag, "report-file", "", "Write verification report to file instead of stderr")
 	execCmd.Flags().BoolVar(&execDryRunFlag, "dry-run", false, "Preview the scenario without spawning a child process")
+	execCmd.Flags().BoolVar(&execFailFastFlag, "fail-fast", false, "Terminate the child on the first mismatched command")
+	execCmd.Flags().Int64Var(&execMaxStdinFlag, "max-stdin", 0, "Maximum bytes of piped stdin read for match.stdin (overrides meta.limits.max_stdin_bytes)")
+	execCmd.Flags().StringVar(&execSeedFlag, "seed", "", "Seed for respond.random and respond.jitter, exported as CLI_REPLAY_SEED (time-based if omitted)")
+	execCmd.Flags().IntVar(&execIndexFlag, "index", 0, "In a multi-document file, replay the scenario at this 0-based index")
+	execCmd.Flags().StringVar(&execNameFlag, "name", "", "In a multi-document file, replay the scenario with this meta.name")
+	execCmd.Flags().BoolVar(&execKeepStateFlag, "keep-state", false, "Keep the state file and intercept directory after exec, for debugging")
+	execCmd.Flags().BoolVar(&execQuietFlag, "quiet", false, "Suppress cli-replay's informational stderr (session init, cleanup counts, success summary); errors still print")
+	execCmd.Flags().BoolVar(&execProgressFlag, "progress", false, "Print a line to stderr as each step is served, e.g. [2/5] kubectl get pods ✓")
+	execCmd.Flags().StringVar(&execDoneFileFlag, "done-file", "", "Write the completion marker here instead of .cli-replay/<session>.done")
+	execCmd.Flags().BoolVar(&execWrapBuiltinsFlag, "wrap-builtins", false, "Windows: intercept steps named after CMD built-ins (echo, dir, ...) by rewriting the child .cmd/.bat script or cmd /c line")
+	execCmd.Flags().StringVar(&execTimeoutFlag, "timeout", "", "Kill the child process tree and fail with exit code 124 if the run takes longer (e.g., 10m, 30s)")
+	execCmd.Flags().StringVar(&execSnapshotFileFlag, "snapshot-file", "", "Unix: write the state snapshot taken on SIGUSR1 to this file instead of stderr")
+	execCmd.ValidArgsFunction = completeScenarioFiles(1)
+	_ = execCmd.RegisterFlagCompletionFunc("format", completeValues("json", "junit"))
 	rootCmd.AddCommand(execCmd)
 }
 
@@ -80,6 +133,22 @@ func runExec(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if err := scenario.ValidateMaxStdinBytes(execMaxStdinFlag); err != nil {
+		return fmt.Errorf("--max-stdin %w", err)
+	}
+
+	var timeout time.Duration
+	if execTimeoutFlag != "" {
+		d, err := time.ParseDuration(execTimeoutFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --timeout %q: %w", execTimeoutFlag, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("--timeout must be positive, got %s", execTimeoutFlag)
+		}
+		timeout = d
+	}
+
 	// --- Phase 1: Pre-spawn validation ---
 
 	// Parse args: everything before -- is exec args, everything after is the child command
@@ -100,47 +169,125 @@ func runExec(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("missing command after '--': usage: cli-replay exec <scenario.yaml> -- <command> [args...]")
 	}
 
-	absPath, err := filepath.Abs(scenarioPath)
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
 	if err != nil {
 		return fmt.Errorf("failed to resolve scenario path: %w", err)
 	}
 
 	// Load and validate scenario
-	scn, err := scenario.LoadFile(absPath)
+	scn, document, err := loadSelectedScenario(absPath, execIndexFlag, execNameFlag)
 	if err != nil {
 		return fmt.Errorf("failed to load scenario: %w", err)
 	}
 
+	commands, err := execCommands(scn, execAllowedCommandsFlag)
+	if err != nil {
+		return err
+	}
+
+	// Dry-run mode: preview scenario and exit without side effects
+	if execDryRunFlag {
+		report := runner.BuildDryRunReport(scn)
+		return runner.FormatDryRunReport(report, cmd.OutOrStdout())
+	}
+
+	opts := execSessionOptions{
+		seed:         execSeedFlag,
+		maxStdin:     execMaxStdinFlag,
+		failFast:     execFailFastFlag,
+		keepState:    execKeepStateFlag,
+		progress:     execProgressFlag,
+		quiet:        execQuietFlag,
+		doneFile:     execDoneFileFlag,
+		wrapBuiltins: execWrapBuiltinsFlag,
+		timeout:      timeout,
+		snapshotFile: execSnapshotFileFlag,
+	}
+	if execFormat != "" {
+		opts.report = func(result *verify.VerifyResult) {
+			writeExecReport(result, execFormat, scenarioPath)
+		}
+	}
+	outcome, err := runExecSession(absPath, scn, document, commands, childArgv, opts)
+	ExecExitCode = outcome.ExitCode
+	return err
+}
+
+// execCommands checks that scn can be run under exec and returns the
+// commands to intercept. allowedFlag is the --allowed-commands value.
+func execCommands(scn *scenario.Scenario, allowedFlag string) ([]string, error) {
 	// Validate delays (no max-delay flag in exec, so no cap)
 	// If we add --max-delay later, pass it here
-	for i, step := range scn.FlatSteps() {
-		if err := step.Respond.ValidateDelay(0); err != nil {
-			return fmt.Errorf("step %d: %w", i+1, err)
-		}
+	if err := validateDelays(scn, 0); err != nil {
+		return nil, err
 	}
 
 	// Extract commands and validate allowlist
 	commands := extractCommands(scn)
 	if len(commands) == 0 {
-		return fmt.Errorf("scenario has no steps with a command name")
+		return nil, fmt.Errorf("scenario has no steps with a command name")
 	}
 
 	// Use exec-specific allowlist flag
-	cliList := parseAllowedCommands(execAllowedCommandsFlag)
+	cliList := parseAllowedCommands(allowedFlag)
 	var yamlList []string
 	if scn.Meta.Security != nil {
 		yamlList = scn.Meta.Security.AllowedCommands
 	}
 	if err := validateAllowlist(scn, yamlList, cliList); err != nil {
-		return err
+		return nil, err
 	}
+	return commands, nil
+}
 
-	// Dry-run mode: preview scenario and exit without side effects
-	if execDryRunFlag {
-		report := runner.BuildDryRunReport(scn)
-		return runner.FormatDryRunReport(report, cmd.OutOrStdout())
+// execSessionOptions carries the settings of one exec session, taken from
+// the exec flags or chosen by 'batch'.
+type execSessionOptions struct {
+	seed      string
+	maxStdin  int64
+	failFast  bool
+	keepState bool
+	progress  bool
+	quiet     bool
+	doneFile  string
+	// wrapBuiltins reroutes CMD built-ins named by the scenario to their
+	// intercepts (Windows only).
+	wrapBuiltins bool
+	// timeout stops the child process tree once the run takes longer;
+	// zero means no limit.
+	timeout time.Duration
+	// snapshotFile receives the state snapshot taken on SIGUSR1; empty
+	// writes it to stderr.
+	snapshotFile string
+	// report receives the verification result; nil writes no report.
+	report func(*verify.VerifyResult)
+}
+
+// info returns where the session writes its informational status lines:
+// stderr, or io.Discard when quiet. Warnings and failures always go to
+// stderr, and the child's own stderr is never affected.
+func (o execSessionOptions) info() io.Writer {
+	if o.quiet {
+		return io.Discard
 	}
+	return os.Stderr
+}
+
+// execOutcome is the result of one exec session.
+type execOutcome struct {
+	// ExitCode is what exec exits with: the child's non-zero exit, or 1
+	// when verification or setup failed.
+	ExitCode int
+	// Result is the verification result; nil if the session did not get
+	// as far as verifying or its state could not be read.
+	Result *verify.VerifyResult
+}
 
+// runExecSession runs phases 2-4 of the exec lifecycle for a loaded and
+// validated scenario: it sets up an isolated session, runs childArgv
+// against it and verifies the result. The error describes why the session
+// failed, if it did.
+func runExecSession(absPath string, scn *scenario.Scenario, document int, commands, childArgv []string, opts execSessionOptions) (execOutcome, error) {
 	// T019: TTL cleanup at session startup
 	if scn.Meta.Session != nil && scn.Meta.Session.TTL != "" {
 		ttl, parseErr := time.ParseDuration(scn.Meta.Session.TTL)
@@ -148,23 +295,28 @@ func runExec(cmd *cobra.Command, args []string) error {
 			cliReplayDir := filepath.Join(filepath.Dir(absPath), ".cli-replay")
 			cleaned, _ := runner.CleanExpiredSessions(cliReplayDir, ttl, os.Stderr)
 			if cleaned > 0 {
-				fmt.Fprintf(os.Stderr, "cli-replay: cleaned %d expired sessions\n", cleaned)
+				fmt.Fprintf(opts.info(), "cli-replay: cleaned %d expired sessions\n", cleaned)
 			}
 		}
 	}
 
 	// --- Phase 2: Setup ---
 
-	scenarioHash := hashScenarioFile(absPath)
+	seed, err := sessionSeed(opts.info(), opts.seed, scn)
+	if err != nil {
+		return execOutcome{ExitCode: 1}, err
+	}
+
+	scenarioHash := runner.ScenarioHash(absPath)
 
 	self, err := os.Executable()
 	if err != nil {
-		return fmt.Errorf("failed to locate cli-replay binary: %w", err)
+		return execOutcome{ExitCode: 1}, fmt.Errorf("failed to locate cli-replay binary: %w", err)
 	}
 
 	interceptDir, err := runner.InterceptDirPath(absPath)
 	if err != nil {
-		return fmt.Errorf("failed to create intercept directory: %w", err)
+		return execOutcome{ExitCode: 1}, fmt.Errorf("failed to create intercept directory: %w", err)
 	}
 
 	// Idempotent cleanup guard
@@ -184,7 +336,7 @@ func runExec(cmd *cobra.Command, args []string) error {
 	for _, c := range commands {
 		if err := createIntercept(self, interceptDir, c); err != nil {
 			cleanup()
-			return fmt.Errorf("failed to create intercept for %q: %w", c, err)
+			return execOutcome{ExitCode: 1}, fmt.Errorf("failed to create intercept for %q: %w", c, err)
 		}
 	}
 
@@ -192,9 +344,10 @@ func runExec(cmd *cobra.Command, args []string) error {
 	stateFile := runner.StateFilePathWithSession(absPath, sessionID)
 	state := runner.NewState(absPath, scenarioHash, len(scn.FlatSteps()))
 	state.InterceptDir = interceptDir
+	state.MaxStdinBytes = opts.maxStdin
 	if err := runner.WriteState(stateFile, state); err != nil {
 		cleanup()
-		return fmt.Errorf("failed to initialize state: %w", err)
+		return execOutcome{ExitCode: 1}, fmt.Errorf("failed to initialize state: %w", err)
 	}
 
 	// Fix cleanup to use the correct session-specific state file
@@ -204,26 +357,56 @@ func runExec(cmd *cobra.Command, args []string) error {
 			return
 		}
 		cleaned = true
+		// --keep-state leaves the session artifacts for inspection
+		if opts.keepState {
+			fmt.Fprintf(opts.info(), "cli-replay: --keep-state: kept state file %s\n", stateFile)
+			fmt.Fprintf(opts.info(), "cli-replay: --keep-state: kept intercept dir %s\n", interceptDir)
+			return
+		}
 		_ = os.RemoveAll(interceptDir)
 		_ = runner.DeleteState(stateFile)
+		_ = runner.DeleteScenarioCache(absPath)
 	}
 	defer cleanup()
 
 	// Status to stderr
-	fmt.Fprintf(os.Stderr, "cli-replay: exec session initialized for %q (%d steps, %d commands)\n",
+	fmt.Fprintf(opts.info(), "cli-replay: exec session initialized for %q (%d steps, %d commands)\n",
 		scn.Meta.Name, len(scn.FlatSteps()), len(commands))
-	fmt.Fprintf(os.Stderr, "  child command: %s\n", strings.Join(childArgv, " "))
+	fmt.Fprintf(opts.info(), "  child command: %s\n", strings.Join(childArgv, " "))
 
 	// --- Phase 3: Spawn + Wait ---
 
+	if opts.wrapBuiltins {
+		wrapped, removeWrapped, err := wrapBuiltins(childArgv, interceptDir, commands)
+		if err != nil {
+			return execOutcome{ExitCode: 1}, err
+		}
+		defer removeWrapped()
+		childArgv = wrapped
+	}
+
 	childCmd := exec.Command(childArgv[0], childArgv[1:]...) //nolint:gosec // user-specified command
-	childCmd.Env = runner.BuildChildEnv(interceptDir, sessionID, absPath)
+	childCmd.Env = runner.SetEnv(runner.BuildChildEnv(interceptDir, sessionID, absPath),
+		runner.SeedEnvVar, strconv.FormatInt(seed, 10))
+	childCmd.Env = runner.SetEnv(childCmd.Env, runner.DocumentEnvVar, strconv.Itoa(document))
+	markerFile := filepath.Join(interceptDir, ".fail-fast")
+	if opts.failFast {
+		childCmd.Env = append(childCmd.Env, runner.FailFastEnvVar+"="+markerFile)
+	}
 	childCmd.Stdin = os.Stdin
 	childCmd.Stdout = os.Stdout
 	childCmd.Stderr = os.Stderr
 
 	// Set up signal forwarding (platform-specific: see exec_unix.go / exec_windows.go)
 	postStartHook, cleanupSignals := setupSignalForwarding(childCmd)
+	// Cleanup also terminates the process tree, so --fail-fast reuses it
+	cleanupSignals = sync.OnceFunc(cleanupSignals)
+	// Installed before the child starts, so a SIGUSR1 sent once it runs
+	// is never fatal to exec
+	stopSnapshots := watchSnapshotSignal(func() {
+		writeStateSnapshot(os.Stderr, opts.snapshotFile, stateFile, scn.Meta.Name, sessionID, scn.FlatSteps())
+	})
+	defer stopSnapshots()
 
 	if err := childCmd.Start(); err != nil {
 		// FR-004: If Start() fails and we're on Unix with Setpgid, retry without process group.
@@ -231,17 +414,53 @@ func runExec(cmd *cobra.Command, args []string) error {
 		if retryErr != nil {
 			cleanupSignals()
 			// Determine exit code: command not found = 127, not executable = 126
-			ExecExitCode = exitCodeForStartError(err)
-			return fmt.Errorf("failed to start child process: %w", err)
+			return execOutcome{ExitCode: exitCodeForStartError(err)}, fmt.Errorf("failed to start child process: %w", err)
 		}
 	}
 
 	// Platform-specific post-start hook (Windows: assign to job object + resume)
 	postStartHook()
 
+	var failFast *failFastWatcher
+	if opts.failFast {
+		failFast = watchFailFast(markerFile, func() {
+			cleanupSignals()
+			_ = childCmd.Process.Kill() // direct child, if not in a group or job
+		})
+	}
+
+	var timeoutTimer *time.Timer
+	if opts.timeout > 0 {
+		timeoutTimer = time.AfterFunc(opts.timeout, func() {
+			cleanupSignals()
+			_ = childCmd.Process.Kill() // direct child, if not in a group or job
+		})
+	}
+
+	var progress *progressWatcher
+	if opts.progress {
+		progress = watchProgress(stateFile, scn.FlatSteps(), os.Stderr)
+	}
+
 	waitErr := childCmd.Wait()
+	// A timer that can no longer be stopped has fired
+	timedOut := timeoutTimer != nil && !timeoutTimer.Stop()
+	var failFastMismatch string
+	if failFast != nil {
+		failFastMismatch = failFast.stop()
+	}
+	if progress != nil {
+		progress.stop()
+	}
 	cleanupSignals()
 
+	if failFastMismatch != "" {
+		fmt.Fprintf(os.Stderr, "cli-replay: --fail-fast: stopped child after mismatch: %s\n", failFastMismatch)
+	}
+	if timedOut {
+		fmt.Fprintf(os.Stderr, "cli-replay: --timeout: stopped child after %s\n", opts.timeout)
+	}
+
 	childExitCode := runner.ExitCodeFromError(waitErr)
 
 	// --- Phase 4: Verify + Cleanup ---
@@ -256,20 +475,24 @@ func runExec(cmd *cobra.Command, args []string) error {
 	updatedState, readErr := runner.ReadState(stateFile)
 
 	verificationPassed := false
+	var result *verify.VerifyResult
 	if readErr != nil {
 		fmt.Fprintf(os.Stderr, "cli-replay: warning: could not read state for verification: %v\n", readErr)
 		// Write error result if format is set
-		if execFormat != "" {
-			errResult := verify.BuildErrorResult(scn.Meta.Name, session, "could not read state")
-			writeExecReport(errResult, execFormat, scenarioPath)
+		if opts.report != nil {
+			opts.report(verify.BuildErrorResult(scn.Meta.Name, session, "could not read state"))
 		}
 	} else {
-		verificationPassed = updatedState.AllStepsMetMin(scn.FlatSteps())
-
-		// Build structured result for report
-		if execFormat != "" {
-			result := verify.BuildResult(scn.Meta.Name, session, scn.FlatSteps(), updatedState.StepCounts, scn.GroupRanges())
-			writeExecReport(result, execFormat, scenarioPath)
+		result = verify.BuildResult(scn.Meta.Name, session, scn.FlatSteps(), updatedState.StepCounts, scn.GroupRanges(),
+			verify.WithStepDurations(updatedState.StepDurations),
+			verify.WithServedTimes(updatedState.ServedAt, updatedState.LastServedAt),
+			verify.WithAssertFailures(updatedState.AssertFailures),
+			verify.WithForbiddenCalls(updatedState.ForbiddenCalls))
+		verificationPassed = updatedState.AllStepsMetMin(scn.FlatSteps()) && result.Passed
+
+		// Write structured result for report
+		if opts.report != nil {
+			opts.report(result)
 		}
 
 		if !verificationPassed {
@@ -277,27 +500,63 @@ func runExec(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(os.Stderr, "✗ Scenario %q incomplete\n", scn.Meta.Name)
 			fmt.Fprintf(os.Stderr, "  consumed: %d/%d steps\n", consumed, updatedState.TotalSteps)
 			printPerStepCounts(scn.FlatSteps(), updatedState)
+			printGroupSummary(result)
+			printTimedOutSteps(result)
+			printLateSteps(result)
+			printAssertFailures(result)
+			printForbiddenCalls(result)
 		} else {
 			consumed := countConsumedSteps(updatedState)
-			fmt.Fprintf(os.Stderr, "✓ Scenario %q completed: %d/%d steps consumed\n",
-				scn.Meta.Name, consumed, updatedState.TotalSteps)
+			if !opts.quiet {
+				fmt.Fprintf(os.Stderr, "✓ Scenario %q completed: %d/%d steps consumed\n",
+					scn.Meta.Name, consumed, updatedState.TotalSteps)
+				printGroupSummary(result)
+			}
+			writeExecDoneMarker(absPath, scn.Meta.Name, session, consumed, opts)
 		}
 	}
 
 	// Cleanup runs via defer
 
 	// Determine final exit code
+	if timedOut {
+		return execOutcome{ExitCode: execTimeoutExitCode, Result: result}, fmt.Errorf("timed out after %s (--timeout)", opts.timeout)
+	}
+	if failFastMismatch != "" {
+		return execOutcome{ExitCode: 1, Result: result}, fmt.Errorf("aborted on first mismatch (--fail-fast)")
+	}
+	if result != nil && len(result.ForbiddenCalls) > 0 {
+		return execOutcome{ExitCode: 1, Result: result}, fmt.Errorf("child invoked forbidden command %q (meta.security.forbidden_commands)",
+			filepath.Base(result.ForbiddenCalls[0][0]))
+	}
 	if childExitCode != 0 {
-		ExecExitCode = childExitCode
-		return fmt.Errorf("child process exited with code %d", childExitCode)
+		return execOutcome{ExitCode: childExitCode, Result: result}, fmt.Errorf("child process exited with code %d", childExitCode)
 	}
 	if !verificationPassed {
-		ExecExitCode = 1
-		return fmt.Errorf("scenario verification failed")
+		return execOutcome{ExitCode: 1, Result: result}, fmt.Errorf("scenario verification failed")
 	}
+	return execOutcome{Result: result}, nil
+}
 
-	ExecExitCode = 0
-	return nil
+// writeExecDoneMarker writes the completion marker to --done-file, or to
+// .cli-replay/<session>.done next to the scenario. The marker outlives the
+// session's own cleanup; a failure to write it is only a warning.
+func writeExecDoneMarker(absPath, scenarioName, session string, consumed int, opts execSessionOptions) {
+	path := opts.doneFile
+	if path == "" {
+		path = runner.DoneFilePath(absPath, session)
+	}
+	marker := runner.DoneMarker{
+		Scenario:      scenarioName,
+		Session:       session,
+		StepsConsumed: consumed,
+		FinishedAt:    time.Now().UTC(),
+	}
+	if err := runner.WriteDoneMarker(path, marker); err != nil {
+		fmt.Fprintf(os.Stderr, "cli-replay: warning: %v\n", err)
+		return
+	}
+	fmt.Fprintf(opts.info(), "  done marker: %s\n", path)
 }
 
 // exitCodeForStartError returns the conventional exit code for a process
@@ -316,6 +575,59 @@ func exitCodeForStartError(err error) int {
 	return 1
 }
 
+// failFastWatcher polls the --fail-fast marker file while the child runs.
+type failFastWatcher struct {
+	path     string
+	done     chan struct{}
+	stopped  chan struct{}
+	mismatch string // first line of the marker once seen
+}
+
+// watchFailFast starts polling path and calls kill once an intercept has
+// recorded a mismatch there.
+func watchFailFast(path string, kill func()) *failFastWatcher {
+	w := &failFastWatcher{path: path, done: make(chan struct{}), stopped: make(chan struct{})}
+	go func() {
+		defer close(w.stopped)
+		ticker := time.NewTicker(failFastPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				if w.mismatch = readFailFastMarker(w.path); w.mismatch != "" {
+					kill()
+					return
+				}
+			}
+		}
+	}()
+	return w
+}
+
+// stop ends polling and returns the recorded mismatch, if any. The marker is
+// checked once more so a child that exits between polls is still reported.
+func (w *failFastWatcher) stop() string {
+	close(w.done)
+	<-w.stopped
+	if w.mismatch == "" {
+		w.mismatch = readFailFastMarker(w.path)
+	}
+	return w.mismatch
+}
+
+// readFailFastMarker returns the first mismatch recorded in the marker file,
+// or "" if none has been written yet.
+func readFailFastMarker(path string) string {
+	data, err := os.ReadFile(path) //nolint:gosec // marker inside the intercept dir
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(line)
+}
+
 // writeExecReport writes the structured verification result to the report
 // destination. If --report-file is set, writes to that file. Otherwise,
 // if --format is set, writes to stderr (stdout is reserved for child process).
@@ -0,0 +1,61 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failFastChild issues many commands, recording a mismatch on the second one
+// the way an intercept would, and keeps going unless it is stopped.
+const failFastChild = `for i in $(seq 1 50); do
+  echo "$i" >> "$PROGRESS"
+  if [ "$i" = 2 ]; then
+    echo 'argv mismatch at step 0: received [kubectl delete pods]' >> "$CLI_REPLAY_FAIL_FAST_FILE"
+  fi
+  sleep 0.1
+done`
+
+func TestExecCommand_FailFastKillsChild(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+	progress := filepath.Join(tmpDir, "progress")
+	t.Setenv("PROGRESS", progress)
+
+	root.SetArgs([]string{"exec", "--fail-fast", scenarioPath, "--", "sh", "-c", failFastChild})
+	start := time.Now()
+	err := root.Execute()
+	elapsed := time.Since(start)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--fail-fast")
+	assert.Equal(t, 1, ExecExitCode)
+	assert.Less(t, elapsed, 3*time.Second, "child should be stopped well before it finishes")
+
+	data, readErr := os.ReadFile(progress)
+	require.NoError(t, readErr)
+	iterations := strings.Count(string(data), "\n")
+	assert.Less(t, iterations, 10, "child kept running after the mismatch")
+}
+
+func TestExecCommand_FailFastOffLetsChildRun(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+
+	// Without --fail-fast the marker variable is not set for the child
+	root.SetArgs([]string{"exec", scenarioPath, "--", "sh", "-c", `test -z "$CLI_REPLAY_FAIL_FAST_FILE"`})
+	err := root.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "scenario verification failed")
+	assert.Equal(t, 1, ExecExitCode)
+}
@@ -0,0 +1,138 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// processAlive reports whether pid is running. Zombies count as gone: a
+// grandchild reparented to a PID 1 that does not reap stays a zombie
+// after it was killed.
+func processAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true // no procfs (macOS): signal 0 is all we have
+	}
+	// The state follows the parenthesised command name
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+// readPid waits for a pid file written by a test child.
+func readPid(t *testing.T, path string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid > 0 {
+				return pid
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("pid file %s never appeared", path)
+	return 0
+}
+
+// assertReaped waits briefly for pid to exit and kills it if it did not.
+func assertReaped(t *testing.T, pid int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && processAlive(pid) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if processAlive(pid) {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+		t.Errorf("grandchild %d is still running after exec returned", pid)
+	}
+}
+
+// backgroundGrandchild starts a long-lived grandchild, like a background
+// kubectl port-forward, and records its pid in $PIDFILE.
+const backgroundGrandchild = `sleep 300 >/dev/null 2>&1 &
+echo $! > "$PIDFILE"`
+
+func TestExecCommand_ReapsBackgroundGrandchild(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+	pidFile := filepath.Join(tmpDir, "grandchild.pid")
+	t.Setenv("PIDFILE", pidFile)
+
+	// The child exits at once, leaving the grandchild running
+	root.SetArgs([]string{"exec", scenarioPath, "--", "sh", "-c", backgroundGrandchild})
+	_ = root.Execute() // verification fails: the child runs no intercepted command
+
+	assertReaped(t, readPid(t, pidFile))
+}
+
+func TestExecCommand_FailFastKillsGrandchild(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+	pidFile := filepath.Join(tmpDir, "grandchild.pid")
+	t.Setenv("PIDFILE", pidFile)
+
+	// Record a mismatch the way an intercept would, then keep running
+	child := backgroundGrandchild + `
+echo 'argv mismatch at step 0: received [kubectl delete pods]' >> "$CLI_REPLAY_FAIL_FAST_FILE"
+sleep 30`
+	root.SetArgs([]string{"exec", "--fail-fast", scenarioPath, "--", "sh", "-c", child})
+	start := time.Now()
+	err := root.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--fail-fast")
+	assert.Less(t, time.Since(start), 5*time.Second, "the child is stopped")
+	assertReaped(t, readPid(t, pidFile))
+}
+
+func TestExecCommand_TimeoutKillsChildTree(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+	pidFile := filepath.Join(tmpDir, "grandchild.pid")
+	reportPath := filepath.Join(tmpDir, "report.json")
+	t.Setenv("PIDFILE", pidFile)
+
+	root.SetArgs([]string{"exec", "--timeout", "300ms", "--format", "json", "--report-file", reportPath,
+		scenarioPath, "--", "sh", "-c", backgroundGrandchild + "\nsleep 30"})
+	start := time.Now()
+	err := root.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--timeout")
+	assert.Equal(t, execTimeoutExitCode, ExecExitCode)
+	assert.Less(t, time.Since(start), 5*time.Second, "the child is stopped")
+	assertReaped(t, readPid(t, pidFile))
+
+	report, err := os.ReadFile(reportPath)
+	require.NoError(t, err, "the report is still written")
+	assert.Contains(t, string(report), `"consumed_steps":0`)
+}
+
+func TestExecCommand_TimeoutNotReached(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	scenarioPath := createTestScenario(t, t.TempDir(), singleStepScenario)
+
+	root.SetArgs([]string{"exec", "--timeout", "30s", scenarioPath, "--", "sh", "-c", "exit 3"})
+	err := root.Execute()
+
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "--timeout")
+	assert.Equal(t, 3, ExecExitCode, "the child's own exit code is kept")
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// progressPollInterval is how often exec --progress reads the session state.
+const progressPollInterval = 50 * time.Millisecond
+
+// progressWatcher polls the session state while the child runs and prints
+// a line for every call served since the previous poll. Steps are served
+// by separate intercept processes, so the state file is the only place
+// the parent can see them.
+type progressWatcher struct {
+	stateFile string
+	steps     []scenario.Step
+	w         io.Writer
+	counts    []int
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// watchProgress starts polling stateFile and writing progress lines to w.
+func watchProgress(stateFile string, steps []scenario.Step, w io.Writer) *progressWatcher {
+	p := &progressWatcher{
+		stateFile: stateFile,
+		steps:     steps,
+		w:         w,
+		counts:    make([]int, len(steps)),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(progressPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.poll()
+			}
+		}
+	}()
+	return p
+}
+
+// stop ends polling. The state is read once more so calls served just
+// before the child exited are still reported.
+func (p *progressWatcher) stop() {
+	close(p.done)
+	<-p.stopped
+	p.poll()
+}
+
+// poll prints one line per call served since the last poll, ordered by when
+// each step was last served.
+func (p *progressWatcher) poll() {
+	state, err := runner.ReadState(p.stateFile)
+	if err != nil {
+		return // not written yet, or mid-update; try again next tick
+	}
+	var changed []int
+	for i := range p.counts {
+		if i < len(state.StepCounts) && state.StepCounts[i] > p.counts[i] {
+			changed = append(changed, i)
+		}
+	}
+	servedAt := func(i int) time.Time {
+		if i < len(state.LastServedAt) {
+			return state.LastServedAt[i]
+		}
+		return time.Time{}
+	}
+	sort.SliceStable(changed, func(a, b int) bool {
+		return servedAt(changed[a]).Before(servedAt(changed[b]))
+	})
+
+	consumed := 0
+	for _, c := range p.counts {
+		if c > 0 {
+			consumed++
+		}
+	}
+	for _, i := range changed {
+		if p.counts[i] == 0 {
+			consumed++
+		}
+		for ; p.counts[i] < state.StepCounts[i]; p.counts[i]++ {
+			fmt.Fprintf(p.w, "[%d/%d] %s ✓\n", consumed, len(p.steps), formatArgvShort(p.steps[i].Match.Alternatives()[0]))
+		}
+	}
+}
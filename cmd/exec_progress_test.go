@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressWatcher_ReportsStepsInOrder(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "rollout", "status"}}},
+		{Match: scenario.Match{AnyOf: [][]string{{"kubectl", "logs", "web"}, {"kubectl", "logs", "api"}}}},
+	}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	state := runner.NewState("scenario.yaml", "hash", len(steps))
+
+	var out bytes.Buffer
+	p := watchProgress(stateFile, steps, &out)
+
+	// Serve the way the intercepts would: each call updates the state file
+	serve := func(idx int) {
+		state.StepCounts[idx]++
+		state.RecordServed(idx, time.Now().UTC())
+		require.NoError(t, runner.WriteState(stateFile, state))
+		time.Sleep(2 * progressPollInterval)
+	}
+	serve(0)
+	serve(1)
+	serve(1)
+	state.StepCounts[2]++ // served just before the child exits
+	state.RecordServed(2, time.Now().UTC())
+	require.NoError(t, runner.WriteState(stateFile, state))
+	p.stop()
+
+	assert.Equal(t, "[1/3] kubectl get pods ✓\n"+
+		"[2/3] kubectl rollout status ✓\n"+
+		"[2/3] kubectl rollout status ✓\n"+
+		"[3/3] kubectl logs web ✓\n", out.String())
+}
+
+func TestProgressWatcher_NoStateYet(t *testing.T) {
+	var out bytes.Buffer
+	p := watchProgress(filepath.Join(t.TempDir(), "missing.json"),
+		[]scenario.Step{{Match: scenario.Match{Argv: []string{"git"}}}}, &out)
+	p.stop()
+	assert.Empty(t, out.String())
+}
+
+func TestExecCommand_ProgressFlagRegistered(t *testing.T) {
+	f := execCmd.Flags().Lookup("progress")
+	require.NotNil(t, f)
+	assert.Equal(t, "false", f.DefValue)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// stateSnapshot is the JSON document exec writes on SIGUSR1: the session
+// state as the intercepts last wrote it, plus the step expected next.
+type stateSnapshot struct {
+	Scenario string            `json:"scenario"`
+	Session  string            `json:"session"`
+	TakenAt  time.Time         `json:"taken_at"`
+	NextStep *snapshotNextStep `json:"next_step"` // nil once every step is consumed
+	State    *runner.State     `json:"state"`
+}
+
+// snapshotNextStep identifies the step at the state's current position.
+type snapshotNextStep struct {
+	Index int      `json:"index"`
+	Argv  []string `json:"argv"`
+	Calls int      `json:"calls"`
+}
+
+// buildStateSnapshot reads stateFile and describes it for a snapshot.
+func buildStateSnapshot(stateFile, scenarioName, session string, steps []scenario.Step) (*stateSnapshot, error) {
+	state, err := runner.ReadState(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	snap := &stateSnapshot{
+		Scenario: scenarioName,
+		Session:  session,
+		TakenAt:  time.Now().UTC(),
+		State:    state,
+	}
+	if i := state.CurrentStep; i >= 0 && i < len(steps) {
+		next := &snapshotNextStep{Index: i, Argv: steps[i].Match.PrimaryArgv()}
+		if i < len(state.StepCounts) {
+			next.Calls = state.StepCounts[i]
+		}
+		snap.NextStep = next
+	}
+	return snap, nil
+}
+
+// writeStateSnapshot writes a pretty-printed snapshot of stateFile to
+// snapshotFile, or to w when snapshotFile is empty. The file is replaced
+// atomically, so a reader never sees a partial snapshot. Failures are
+// reported to w; a snapshot never stops the run.
+func writeStateSnapshot(w io.Writer, snapshotFile, stateFile, scenarioName, session string, steps []scenario.Step) {
+	snap, err := buildStateSnapshot(stateFile, scenarioName, session, steps)
+	if err != nil {
+		fmt.Fprintf(w, "cli-replay: warning: snapshot: could not read state: %v\n", err)
+		return
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "cli-replay: warning: snapshot: %v\n", err)
+		return
+	}
+	data = append(data, '\n')
+
+	if snapshotFile == "" {
+		_, _ = w.Write(data)
+		return
+	}
+	if err := writeFileAtomic(snapshotFile, data); err != nil {
+		fmt.Fprintf(w, "cli-replay: warning: snapshot: %v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "cli-replay: snapshot written to %s\n", snapshotFile)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // already renamed on success
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteStateSnapshot(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "apply", "-f", "app.yaml"}}},
+	}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	state := runner.NewState("/tmp/scenario.yaml", "hash", len(steps))
+	state.Advance()
+	require.NoError(t, runner.WriteState(stateFile, state))
+
+	var buf bytes.Buffer
+	writeStateSnapshot(&buf, "", stateFile, "deploy", "abc123", steps)
+
+	var snap stateSnapshot
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &snap), buf.String())
+	assert.Equal(t, "deploy", snap.Scenario)
+	assert.Equal(t, "abc123", snap.Session)
+	require.NotNil(t, snap.NextStep)
+	assert.Equal(t, 1, snap.NextStep.Index)
+	assert.Equal(t, []string{"kubectl", "apply", "-f", "app.yaml"}, snap.NextStep.Argv)
+	assert.Equal(t, []int{1, 0}, snap.State.StepCounts)
+	assert.Contains(t, buf.String(), "\n  \"scenario\": \"deploy\"", "pretty-printed")
+}
+
+func TestWriteStateSnapshot_Complete(t *testing.T) {
+	steps := []scenario.Step{{Match: scenario.Match{Argv: []string{"git", "status"}}}}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	state := runner.NewState("/tmp/scenario.yaml", "hash", len(steps))
+	state.Advance()
+	require.NoError(t, runner.WriteState(stateFile, state))
+
+	snapshotFile := filepath.Join(t.TempDir(), "snapshot.json")
+	var buf bytes.Buffer
+	writeStateSnapshot(&buf, snapshotFile, stateFile, "git", "s1", steps)
+	assert.Contains(t, buf.String(), "snapshot written to "+snapshotFile)
+
+	snap, err := buildStateSnapshot(stateFile, "git", "s1", steps)
+	require.NoError(t, err)
+	assert.Nil(t, snap.NextStep, "nothing is expected once every step is consumed")
+}
+
+func TestWriteStateSnapshot_MissingState(t *testing.T) {
+	var buf bytes.Buffer
+	writeStateSnapshot(&buf, "", filepath.Join(t.TempDir(), "missing.json"), "x", "s", nil)
+	assert.Contains(t, buf.String(), "cli-replay: warning: snapshot: could not read state")
+}
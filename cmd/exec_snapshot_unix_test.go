@@ -0,0 +1,57 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecCommand_SIGUSR1WritesSnapshot(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+	readyFile := filepath.Join(tmpDir, "ready")
+	snapshotFile := filepath.Join(tmpDir, "snapshot.json")
+	t.Setenv("READY", readyFile)
+	t.Setenv("SNAP", snapshotFile)
+
+	// The child signals it is running, then waits for the snapshot
+	child := `touch "$READY"
+i=0
+while [ ! -s "$SNAP" ] && [ $i -lt 100 ]; do sleep 0.05; i=$((i+1)); done`
+	root.SetArgs([]string{"exec", "--snapshot-file", snapshotFile, scenarioPath, "--", "sh", "-c", child})
+	done := make(chan error, 1)
+	go func() { done <- root.Execute() }()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(readyFile)
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond, "child started")
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+
+	select {
+	case <-done: // verification fails: the child runs no intercepted command
+	case <-time.After(10 * time.Second):
+		t.Fatal("exec did not return")
+	}
+
+	data, err := os.ReadFile(snapshotFile)
+	require.NoError(t, err, "the snapshot is written")
+	var snap stateSnapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+	assert.Equal(t, "test-scenario", snap.Scenario)
+	assert.NotEmpty(t, snap.Session)
+	require.NotNil(t, snap.NextStep)
+	assert.Equal(t, 0, snap.NextStep.Index)
+	assert.Equal(t, []string{"echo", "hello"}, snap.NextStep.Argv)
+	require.NotNil(t, snap.State)
+	assert.Equal(t, 1, snap.State.TotalSteps)
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/ormasoftchile/cli-replay/internal/runner"
 	"github.com/spf13/cobra"
@@ -39,6 +40,18 @@ func makeExecRoot() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
 	execFormatFlag = ""
 	execReportFileFlag = ""
 	execDryRunFlag = false
+	execFailFastFlag = false
+	execMaxStdinFlag = 0
+	execSeedFlag = ""
+	execIndexFlag = 0
+	execNameFlag = ""
+	execDoneFileFlag = ""
+	execKeepStateFlag = false
+	execQuietFlag = false
+	execProgressFlag = false
+	execWrapBuiltinsFlag = false
+	execTimeoutFlag = ""
+	execSnapshotFileFlag = ""
 
 	stdout := new(bytes.Buffer)
 	stderr := new(bytes.Buffer)
@@ -59,6 +72,18 @@ func makeExecRoot() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
 	ex.Flags().StringVar(&execFormatFlag, "format", "", "Output format for verification report: json or junit")
 	ex.Flags().StringVar(&execReportFileFlag, "report-file", "", "Write verification report to file instead of stderr")
 	ex.Flags().BoolVar(&execDryRunFlag, "dry-run", false, "Preview the scenario without spawning a child process")
+	ex.Flags().BoolVar(&execFailFastFlag, "fail-fast", false, "Terminate the child on the first mismatched command")
+	ex.Flags().Int64Var(&execMaxStdinFlag, "max-stdin", 0, "Maximum bytes of piped stdin read for match.stdin (overrides meta.limits.max_stdin_bytes)")
+	ex.Flags().StringVar(&execSeedFlag, "seed", "", "Seed for respond.random and respond.jitter, exported as CLI_REPLAY_SEED (time-based if omitted)")
+	ex.Flags().IntVar(&execIndexFlag, "index", 0, "In a multi-document file, replay the scenario at this 0-based index")
+	ex.Flags().StringVar(&execNameFlag, "name", "", "In a multi-document file, replay the scenario with this meta.name")
+	ex.Flags().BoolVar(&execKeepStateFlag, "keep-state", false, "Keep the state file and intercept directory after exec, for debugging")
+	ex.Flags().BoolVar(&execQuietFlag, "quiet", false, "Suppress cli-replay's informational stderr (session init, cleanup counts, success summary); errors still print")
+	ex.Flags().BoolVar(&execProgressFlag, "progress", false, "Print a line to stderr as each step is served, e.g. [2/5] kubectl get pods ✓")
+	ex.Flags().StringVar(&execDoneFileFlag, "done-file", "", "Write the completion marker here instead of .cli-replay/<session>.done")
+	ex.Flags().BoolVar(&execWrapBuiltinsFlag, "wrap-builtins", false, "Windows: intercept steps named after CMD built-ins (echo, dir, ...) by rewriting the child .cmd/.bat script or cmd /c line")
+	ex.Flags().StringVar(&execTimeoutFlag, "timeout", "", "Kill the child process tree and fail with exit code 124 if the run takes longer (e.g., 10m, 30s)")
+	ex.Flags().StringVar(&execSnapshotFileFlag, "snapshot-file", "", "Unix: write the state snapshot taken on SIGUSR1 to this file instead of stderr")
 	root.AddCommand(ex)
 
 	root.SetOut(stdout)
@@ -356,6 +381,24 @@ func TestExecCommand_StateFileCleanup(t *testing.T) {
 	assert.LessOrEqual(t, newStates, 0, "no new state files should remain after exec cleanup")
 }
 
+func TestExecCommand_RemovesScenarioCache(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
+	require.NoError(t, err)
+
+	// Stand in for the cache the child's intercepted calls would write
+	cachePath := runner.ScenarioCachePath(absPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(cachePath), 0750))
+	require.NoError(t, os.WriteFile(cachePath, []byte("{}"), 0600))
+
+	root.SetArgs(append([]string{"exec", scenarioPath, "--"}, trueCmd()...))
+	_ = root.Execute()
+
+	assert.NoFileExists(t, cachePath, "exec cleanup removes the parsed-scenario cache")
+}
+
 // Test with empty scenario (no steps)
 func TestExecCommand_EmptyScenario(t *testing.T) {
 	root, _, _ := makeExecRoot()
@@ -462,6 +505,17 @@ func TestExecCommand_InvalidFormat(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid format")
 }
 
+func TestExecCommand_MaxStdinRejectsNegative(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+
+	root.SetArgs([]string{"exec", "--max-stdin=-1", scenarioPath, "--", "echo", "hello"})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unlimited is not supported")
+}
+
 // T012: --format flag registration
 func TestExecCommand_FormatFlagRegistered(t *testing.T) {
 	root, _, _ := makeExecRoot()
@@ -545,3 +599,231 @@ func TestSetupSignalForwarding_ReturnsCleanup(t *testing.T) {
 		cleanup()
 	}, "cleanup function should not panic when called before process start")
 }
+
+func TestExecCommand_WrapBuiltinsWindowsOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("covered by the Windows integration tests")
+	}
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+
+	root.SetArgs(append([]string{"exec", "--wrap-builtins", scenarioPath, "--"}, trueCmd()...))
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--wrap-builtins is only supported on Windows")
+	assert.Equal(t, 1, ExecExitCode)
+
+	interceptDirs, _ := filepath.Glob(filepath.Join(tmpDir, ".cli-replay", "intercept-*"))
+	assert.Empty(t, interceptDirs, "the session is cleaned up")
+}
+
+func TestExecCommand_SeedExportedToChild(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("CLI_REPLAY_SEED", "1")
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+	out := filepath.Join(tmpDir, "seed.txt")
+
+	root.SetArgs([]string{"exec", "--seed", "42", scenarioPath, "--", "sh", "-c", `printf %s "$CLI_REPLAY_SEED" > "$0"`, out})
+	_ = root.Execute() // verification fails: the child runs no intercepted command
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "42", string(data), "--seed overrides the inherited value")
+}
+
+func TestExecCommand_InvalidSeed(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	scenarioPath := createTestScenario(t, t.TempDir(), singleStepScenario)
+
+	root.SetArgs(append([]string{"exec", "--seed", "abc", scenarioPath, "--"}, trueCmd()...))
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid --seed "abc"`)
+}
+
+func TestExecCommand_InvalidTimeout(t *testing.T) {
+	scenarioPath := createTestScenario(t, t.TempDir(), singleStepScenario)
+
+	for value, want := range map[string]string{
+		"soon": `invalid --timeout "soon"`,
+		"-5s":  "--timeout must be positive",
+	} {
+		root, _, _ := makeExecRoot()
+		root.SetArgs(append([]string{"exec", "--timeout", value, scenarioPath, "--"}, trueCmd()...))
+		err := root.Execute()
+		require.Error(t, err, value)
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+const optionalStepScenario = `meta:
+  name: optional-only
+steps:
+  - match:
+      argv: [echo, hello]
+    calls:
+      min: 0
+      max: 1
+    respond:
+      exit: 0
+`
+
+func TestExecCommand_WritesDoneMarker(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, optionalStepScenario)
+
+	root.SetArgs(append([]string{"exec", scenarioPath, "--"}, trueCmd()...))
+	require.NoError(t, root.Execute())
+
+	markers, err := filepath.Glob(filepath.Join(tmpDir, ".cli-replay", "*.done"))
+	require.NoError(t, err)
+	require.Len(t, markers, 1, "marker survives exec's cleanup")
+
+	marker, err := runner.ReadDoneMarker(markers[0])
+	require.NoError(t, err)
+	assert.Equal(t, "optional-only", marker.Scenario)
+	assert.NotEmpty(t, marker.Session)
+	assert.Equal(t, marker.Session+".done", filepath.Base(markers[0]))
+	assert.Equal(t, 0, marker.StepsConsumed)
+	assert.WithinDuration(t, time.Now(), marker.FinishedAt, time.Minute)
+}
+
+func TestExecCommand_DoneFileOverride(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, optionalStepScenario)
+	donePath := filepath.Join(tmpDir, "out", "scenario.done")
+
+	root.SetArgs(append([]string{"exec", "--done-file", donePath, scenarioPath, "--"}, trueCmd()...))
+	require.NoError(t, root.Execute())
+
+	marker, err := runner.ReadDoneMarker(donePath)
+	require.NoError(t, err)
+	assert.Equal(t, "optional-only", marker.Scenario)
+
+	markers, _ := filepath.Glob(filepath.Join(tmpDir, ".cli-replay", "*.done"))
+	assert.Empty(t, markers)
+}
+
+func TestExecCommand_NoDoneMarkerWhenIncomplete(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+
+	root.SetArgs(append([]string{"exec", scenarioPath, "--"}, trueCmd()...))
+	require.Error(t, root.Execute())
+
+	markers, _ := filepath.Glob(filepath.Join(tmpDir, ".cli-replay", "*.done"))
+	assert.Empty(t, markers)
+}
+
+func TestExecCommand_KeepState(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+	cliReplayDir := filepath.Join(tmpDir, ".cli-replay")
+
+	root.SetArgs(append([]string{"exec", "--keep-state", scenarioPath, "--"}, trueCmd()...))
+	require.Error(t, root.Execute(), "verification still fails")
+
+	states, _ := filepath.Glob(filepath.Join(cliReplayDir, "cli-replay-*.state"))
+	require.Len(t, states, 1, "state file is kept")
+	state, err := runner.ReadState(states[0])
+	require.NoError(t, err)
+	assert.DirExists(t, state.InterceptDir, "intercept dir is kept")
+	entries, err := os.ReadDir(state.InterceptDir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries, "intercepts are kept")
+
+	// Without the flag, the same run cleans up after itself
+	_ = os.RemoveAll(cliReplayDir)
+	root, _, _ = makeExecRoot()
+	root.SetArgs(append([]string{"exec", scenarioPath, "--"}, trueCmd()...))
+	require.Error(t, root.Execute())
+	states, _ = filepath.Glob(filepath.Join(cliReplayDir, "cli-replay-*.state"))
+	assert.Empty(t, states)
+	intercepts, _ := filepath.Glob(filepath.Join(cliReplayDir, "intercept-*"))
+	assert.Empty(t, intercepts)
+}
+
+// captureStderr runs fn with os.Stderr redirected and returns what was
+// written, including the output of any child process that inherits it.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	old := os.Stderr
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stderr = w
+	defer func() { os.Stderr = old }()
+
+	fn()
+
+	require.NoError(t, w.Close())
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	return buf.String()
+}
+
+func TestExecCommand_QuietSuppressesInfo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh for the child's stderr")
+	}
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, optionalStepScenario)
+	child := []string{"sh", "-c", "echo child-stderr >&2"}
+
+	run := func(quiet bool, extra ...string) (string, error) {
+		root, _, _ := makeExecRoot()
+		args := append([]string{"exec"}, extra...)
+		if quiet {
+			args = append(args, "--quiet")
+		}
+		root.SetArgs(append(append(args, scenarioPath, "--"), child...))
+		var err error
+		out := captureStderr(t, func() { err = root.Execute() })
+		return out, err
+	}
+
+	out, err := run(false)
+	require.NoError(t, err)
+	assert.Contains(t, out, "exec session initialized")
+	assert.Contains(t, out, `✓ Scenario "optional-only" completed`)
+	assert.Contains(t, out, "done marker:")
+
+	out, err = run(true)
+	require.NoError(t, err)
+	assert.NotContains(t, out, "exec session initialized")
+	assert.NotContains(t, out, "child command:")
+	assert.NotContains(t, out, "completed")
+	assert.NotContains(t, out, "done marker:")
+	assert.Contains(t, out, "child-stderr", "the child's stderr passes through")
+
+	out, err = run(false, "--keep-state")
+	require.NoError(t, err)
+	assert.Contains(t, out, "--keep-state: kept state file")
+	assert.Contains(t, out, "--keep-state: kept intercept dir")
+
+	out, err = run(true, "--keep-state")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "--keep-state: kept")
+}
+
+func TestExecCommand_QuietKeepsFailureOutput(t *testing.T) {
+	root, _, _ := makeExecRoot()
+	tmpDir := t.TempDir()
+	scenarioPath := createTestScenario(t, tmpDir, singleStepScenario)
+
+	root.SetArgs(append([]string{"exec", "--quiet", scenarioPath, "--"}, trueCmd()...))
+	var err error
+	out := captureStderr(t, func() { err = root.Execute() })
+	require.Error(t, err)
+	assert.NotContains(t, out, "exec session initialized")
+	assert.Contains(t, out, `✗ Scenario "test-scenario" incomplete`)
+	assert.Contains(t, out, "consumed: 0/1 steps")
+}
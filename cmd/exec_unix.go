@@ -20,7 +20,9 @@ var useProcessGroup bool
 //
 // FR-001: Sets Setpgid: true so the child gets its own process group.
 // FR-002: Forwards SIGINT/SIGTERM to the entire process group via Kill(-pgid, sig).
-// FR-003: Cleanup terminates the group (SIGTERM → 100ms → SIGKILL).
+// FR-003: Cleanup terminates the group (SIGTERM → up to 100ms → SIGKILL), so
+// background grandchildren of a child that already exited are reaped too.
+// It returns at once when the group is already gone.
 // FR-004: If cmd.Start() fails due to Setpgid, the caller (exec.go) should call
 //
 //	retryWithoutProcessGroup to clear SysProcAttr and retry.
@@ -67,10 +69,16 @@ func setupSignalForwarding(childCmd *exec.Cmd) (postStart func(), cleanup func()
 		if useProcessGroup {
 			// FR-003: Best-effort cleanup of entire process group.
 			pgid := childCmd.Process.Pid
-			// Send SIGTERM to group — ignore ESRCH (already gone).
-			_ = syscall.Kill(-pgid, syscall.SIGTERM)
-			time.Sleep(100 * time.Millisecond)
-			// Escalate to SIGKILL for any survivors.
+			// Send SIGTERM to group — ESRCH means it is already gone.
+			if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+				return
+			}
+			// Give the group a moment to exit, then escalate to SIGKILL
+			// for any survivors.
+			deadline := time.Now().Add(100 * time.Millisecond)
+			for time.Now().Before(deadline) && syscall.Kill(-pgid, 0) == nil {
+				time.Sleep(10 * time.Millisecond)
+			}
 			_ = syscall.Kill(-pgid, syscall.SIGKILL)
 		}
 	}
@@ -87,3 +95,29 @@ func retryWithoutProcessGroup(childCmd *exec.Cmd) error {
 	useProcessGroup = false
 	return childCmd.Start()
 }
+
+// watchSnapshotSignal calls snapshot each time exec receives SIGUSR1.
+// Unlike SIGINT and SIGTERM, the signal is handled by exec itself and not
+// forwarded to the child. The returned function stops the handler.
+func watchSnapshotSignal(snapshot func()) (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range sigCh {
+			snapshot()
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(sigCh)
+		<-done
+	}
+}
+
+// wrapBuiltins implements exec --wrap-builtins, which only applies to
+// cmd.exe: shells on Unix resolve every intercepted command through PATH.
+func wrapBuiltins(_ []string, _ string, _ []string) ([]string, func(), error) {
+	return nil, nil, fmt.Errorf("--wrap-builtins is only supported on Windows")
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"unsafe"
 
@@ -143,3 +145,64 @@ func setupSignalForwardingFallback(childCmd *exec.Cmd) func() {
 func retryWithoutProcessGroup(_ *exec.Cmd) error {
 	return fmt.Errorf("process start retry not supported on Windows")
 }
+
+// watchSnapshotSignal is a no-op on Windows, which has no SIGUSR1.
+func watchSnapshotSignal(_ func()) (stop func()) {
+	return func() {}
+}
+
+// wrapBuiltins implements exec --wrap-builtins. CMD runs its internal
+// commands (echo, dir, ...) without consulting PATH, so intercepts named
+// after them are never reached. For each scenario command that collides
+// with a built-in, a .cmd shim delegating to the intercept is generated,
+// and the child batch script is rewritten to call the shim wherever it
+// names the built-in. The child must be a .cmd/.bat script or cmd /c; the
+// returned argv runs the rewritten copy and the returned function removes
+// it.
+func wrapBuiltins(childArgv []string, interceptDir string, commands []string) ([]string, func(), error) {
+	builtins := platform.CmdBuiltinCollisions(commands)
+	if len(builtins) == 0 {
+		return childArgv, func() {}, nil
+	}
+	shims, err := platform.WriteBuiltinShims(interceptDir, builtins)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(childArgv[0])); {
+	case ext == ".cmd" || ext == ".bat":
+		script, err := os.ReadFile(childArgv[0]) //nolint:gosec // user-specified child script
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read child script: %w", err)
+		}
+		// Written next to the original so %~dp0 still resolves there
+		base := strings.TrimSuffix(childArgv[0], filepath.Ext(childArgv[0]))
+		wrapped := base + ".cli-replay-wrapped" + ext
+		if err := os.WriteFile(wrapped, []byte(platform.WrapCmdScript(string(script), shims)), 0644); err != nil { //nolint:gosec // script needs to be readable
+			return nil, nil, fmt.Errorf("failed to write wrapped child script: %w", err)
+		}
+		argv := append([]string{wrapped}, childArgv[1:]...)
+		return argv, func() { _ = os.Remove(wrapped) }, nil
+	case isCmdShell(childArgv[0]):
+		for i, arg := range childArgv[1:] {
+			if !strings.EqualFold(arg, "/c") {
+				continue
+			}
+			line := strings.Join(childArgv[i+2:], " ")
+			wrapped := filepath.Join(interceptDir, "_wrapped_child.cmd")
+			content := "@echo off\r\n" + platform.WrapCmdScript(line, shims) + "\r\n"
+			if err := os.WriteFile(wrapped, []byte(content), 0644); err != nil { //nolint:gosec // script needs to be readable
+				return nil, nil, fmt.Errorf("failed to write wrapped child script: %w", err)
+			}
+			return []string{wrapped}, func() {}, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("the child command must be a .cmd/.bat script or cmd /c to wrap %s",
+		strings.Join(builtins, ", "))
+}
+
+// isCmdShell reports whether path names cmd.exe.
+func isCmdShell(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return name == "cmd" || name == "cmd.exe"
+}
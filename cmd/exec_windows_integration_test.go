@@ -79,7 +79,7 @@ func writeScenario(t *testing.T, dir, content string) string {
 
 // writeChildScript creates a .cmd child script that invokes the given commands.
 // On Windows, CMD built-ins (echo, dir, etc.) are NEVER resolved via PATH, so
-// they cannot be intercepted. The scenario must use a custom command name (e.g.
+// they cannot be intercepted without exec --wrap-builtins. The scenario must use a custom command name (e.g.
 // "myapp") that does not shadow any built-in. The exec command copies
 // cli-replay.exe as myapp.exe into the intercept directory and prepends it to
 // PATH, so when the child script calls "myapp hello", Windows finds myapp.exe
@@ -239,10 +239,8 @@ func TestWindows_ExecLifecycle_ChildExitCode(t *testing.T) {
 	binary := ensureBinary(t)
 	tmpDir := t.TempDir()
 
-	// The intercepted command returns exit 42. The child script propagates it.
-	// NOTE: main.go always exits with 1 when any error is returned from
-	// runExec, so the process exit code will be 1 — not 42. We verify that
-	// the child's exit code is reported in stderr and the process is non-zero.
+	// The intercepted command returns exit 42. The child script propagates it,
+	// and main.go exits with the code runExec reports.
 	scenarioPath := writeScenario(t, tmpDir, `
 meta:
   name: win-exit-propagate
@@ -258,7 +256,7 @@ steps:
 
 	_, stderr, exitCode := runCLI(t, binary, "exec", scenarioPath, "--", childScript)
 
-	assert.NotEqual(t, 0, exitCode, "non-zero exit should propagate")
+	assert.Equal(t, 42, exitCode, "non-zero exit should propagate")
 	assert.Contains(t, stderr, "exited with code 42", "stderr should report the child's exit code")
 }
 
@@ -288,6 +286,40 @@ steps:
 	assert.Empty(t, interceptDirs, "intercept dirs should be cleaned up after failure")
 }
 
+// TestWindows_WrapBuiltins_Echo verifies that --wrap-builtins intercepts a
+// step named after the CMD built-in echo, which CMD would otherwise run
+// itself without consulting PATH.
+func TestWindows_WrapBuiltins_Echo(t *testing.T) {
+	binary := ensureBinary(t)
+	tmpDir := t.TempDir()
+	scenarioPath := writeScenario(t, tmpDir, `
+meta:
+  name: win-wrap-echo
+steps:
+  - match:
+      argv: [echo, hello]
+    respond:
+      exit: 0
+      stdout: "replayed by cli-replay\n"
+`)
+	childScript := writeChildScript(t, tmpDir, "echo hello")
+
+	stdout, stderr, exitCode := runCLI(t, binary, "exec", "--wrap-builtins", scenarioPath, "--", childScript)
+
+	assert.Equal(t, 0, exitCode, "exit code should be 0: stderr=%s", stderr)
+	assert.Contains(t, stdout, "replayed by cli-replay")
+	assert.NotContains(t, stdout, "hello", "CMD's own echo must not run")
+	assert.Contains(t, stderr, "1/1 steps consumed")
+
+	wrapped, _ := filepath.Glob(filepath.Join(tmpDir, "*.cli-replay-wrapped.cmd"))
+	assert.Empty(t, wrapped, "the rewritten script is removed")
+
+	// cmd /c lines are wrapped as well
+	stdout, stderr, exitCode = runCLI(t, binary, "exec", "--wrap-builtins", scenarioPath, "--", "cmd", "/c", "echo hello")
+	assert.Equal(t, 0, exitCode, "exit code should be 0: stderr=%s", stderr)
+	assert.Contains(t, stdout, "replayed by cli-replay")
+}
+
 // ---------- Category 2: JSON/JUnit report output ----------
 
 func TestWindows_ExecLifecycle_JSONReport(t *testing.T) {
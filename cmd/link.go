@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ormasoftchile/cli-replay/internal/platform"
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+// defaultLinkDir is where 'link' installs intercepts unless --dir is given.
+const defaultLinkDir = "~/.cli-replay/bin"
+
+var linkDirFlag string
+var linkShellFlag string
+
+var linkCmd = &cobra.Command{
+	Use:   "link <command> [command...]",
+	Short: "Install persistent intercepts for commands",
+	Long: `Create an intercept for each named command in a directory, pointing at
+the cli-replay binary: a symlink on Unix, a .cmd wrapper on Windows.
+
+Once the directory is on PATH, running one of the commands invokes
+cli-replay in intercept mode against CLI_REPLAY_SCENARIO. The PATH update
+needed for the current shell is printed to stdout, so it can be evaluated
+directly. Commands that are already linked are left as they are; an
+existing file that is not a cli-replay intercept is never overwritten.
+
+Examples:
+  cli-replay link kubectl az docker
+  eval "$(cli-replay link --dir ./bin kubectl)"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runLink,
+}
+
+var unlinkCmd = &cobra.Command{
+	Use:   "unlink [command...]",
+	Short: "Remove intercepts installed by 'link'",
+	Long: `Remove the intercepts for the named commands from the link directory.
+With no commands, every cli-replay intercept in the directory is removed.
+Files that are not cli-replay intercepts are left untouched.
+
+Examples:
+  cli-replay unlink kubectl
+  cli-replay unlink --dir ./bin`,
+	RunE: runUnlink,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	linkCmd.Flags().StringVar(&linkDirFlag, "dir", defaultLinkDir, "Directory to create the intercepts in")
+	linkCmd.Flags().StringVar(&linkShellFlag, "shell", "", "Format of the printed PATH update: powershell, pwsh, bash, fish, cmd (auto-detected if omitted)")
+	unlinkCmd.Flags().StringVar(&linkDirFlag, "dir", defaultLinkDir, "Directory the intercepts were created in")
+	rootCmd.AddCommand(linkCmd)
+	rootCmd.AddCommand(unlinkCmd)
+}
+
+func runLink(cmd *cobra.Command, args []string) error {
+	dir, err := resolveLinkDir(linkDirFlag)
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate cli-replay binary: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create link directory: %w", err)
+	}
+
+	p := platform.New()
+	for _, command := range args {
+		if err := validateLinkName(command); err != nil {
+			return err
+		}
+		entry := filepath.Join(dir, p.InterceptFileName(command))
+		if _, err := os.Lstat(entry); err == nil {
+			if !isCLIReplayIntercept(entry, self) {
+				return fmt.Errorf("refusing to overwrite %s: not a cli-replay intercept", entry)
+			}
+			fmt.Fprintf(os.Stderr, "cli-replay: %s already linked\n", command)
+			continue
+		}
+		if _, err := p.CreateIntercept(self, dir, command); err != nil {
+			return fmt.Errorf("failed to link %q: %w", command, err)
+		}
+		fmt.Fprintf(os.Stderr, "cli-replay: linked %s -> %s\n", entry, self)
+	}
+
+	writePathSetup(cmd.OutOrStdout(), detectShell(linkShellFlag), dir)
+	return nil
+}
+
+func runUnlink(_ *cobra.Command, args []string) error {
+	dir, err := resolveLinkDir(linkDirFlag)
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate cli-replay binary: %w", err)
+	}
+
+	p := platform.New()
+	var entries []string
+	if len(args) == 0 {
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to read link directory: %w", err)
+		}
+		for _, e := range dirEntries {
+			entries = append(entries, filepath.Join(dir, e.Name()))
+		}
+	} else {
+		for _, command := range args {
+			if err := validateLinkName(command); err != nil {
+				return err
+			}
+			entries = append(entries, filepath.Join(dir, p.InterceptFileName(command)))
+		}
+	}
+
+	for _, entry := range entries {
+		if _, err := os.Lstat(entry); os.IsNotExist(err) {
+			if len(args) > 0 {
+				fmt.Fprintf(os.Stderr, "cli-replay: %s is not linked\n", filepath.Base(entry))
+			}
+			continue
+		}
+		if !isCLIReplayIntercept(entry, self) {
+			if len(args) > 0 {
+				return fmt.Errorf("refusing to remove %s: not a cli-replay intercept", entry)
+			}
+			continue
+		}
+		if err := os.Remove(entry); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry, err)
+		}
+		fmt.Fprintf(os.Stderr, "cli-replay: unlinked %s\n", entry)
+	}
+	return nil
+}
+
+// resolveLinkDir expands a leading ~ and makes dir absolute.
+func resolveLinkDir(dir string) (string, error) {
+	dir, err := runner.ExpandHome(dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve link directory: %w", err)
+	}
+	return abs, nil
+}
+
+// validateLinkName rejects command names that would escape the link directory.
+func validateLinkName(command string) error {
+	if command == "" || command == "." || command == ".." || strings.ContainsAny(command, `/\`) {
+		return fmt.Errorf("invalid command name %q", command)
+	}
+	return nil
+}
+
+// isCLIReplayIntercept reports whether path is an intercept pointing at
+// self or at another cli-replay binary: a symlink on Unix, a .cmd wrapper
+// invoking it on Windows.
+func isCLIReplayIntercept(path, self string) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return false
+		}
+		return target == self || strings.HasPrefix(filepath.Base(target), "cli-replay")
+	}
+	if strings.EqualFold(filepath.Ext(path), ".cmd") {
+		data, err := os.ReadFile(path) //nolint:gosec // reading an intercept wrapper
+		if err != nil {
+			return false
+		}
+		return strings.Contains(string(data), self) || strings.Contains(string(data), "cli-replay")
+	}
+	return false
+}
+
+// writePathSetup writes the shell command that prepends dir to PATH.
+func writePathSetup(w io.Writer, shell, dir string) {
+	switch shell {
+	case "powershell":
+		fmt.Fprintf(w, "$env:PATH = %s + ';' + $env:PATH\n", psQuote(dir))
+	case "pwsh":
+		fmt.Fprintf(w, "$env:PATH = %s + [IO.Path]::PathSeparator + $env:PATH\n", psQuote(dir))
+	case "fish":
+		fmt.Fprintf(w, "set -gx PATH %s $PATH\n", fishQuote(dir))
+	case "cmd":
+		fmt.Fprintf(w, "set \"PATH=%s;%%PATH%%\"\n", dir)
+	default: // bash / zsh / sh
+		fmt.Fprintf(w, "export PATH='%s':\"$PATH\"\n", shellQuoteEscape(dir))
+	}
+}
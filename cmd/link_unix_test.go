@@ -0,0 +1,116 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeLinkRoot creates a fresh root + link/unlink command tree for testing.
+func makeLinkRoot() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "cli-replay",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	link := &cobra.Command{
+		Use:  "link <command> [command...]",
+		Args: cobra.MinimumNArgs(1),
+		RunE: runLink,
+	}
+	link.Flags().StringVar(&linkDirFlag, "dir", defaultLinkDir, "")
+	link.Flags().StringVar(&linkShellFlag, "shell", "", "")
+	unlink := &cobra.Command{
+		Use:  "unlink [command...]",
+		RunE: runUnlink,
+	}
+	unlink.Flags().StringVar(&linkDirFlag, "dir", defaultLinkDir, "")
+	root.AddCommand(link, unlink)
+	return root
+}
+
+func TestLink_CreatesSymlinksToBinary(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bin")
+	self, err := os.Executable()
+	require.NoError(t, err)
+
+	root := makeLinkRoot()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"link", "--dir", dir, "--shell", "bash", "kubectl", "az"})
+	require.NoError(t, root.Execute())
+
+	for _, name := range []string{"kubectl", "az"} {
+		target, err := os.Readlink(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, self, target)
+	}
+	assert.Equal(t, "export PATH='"+dir+"':\"$PATH\"\n", out.String())
+
+	// Linking again is a no-op
+	root = makeLinkRoot()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"link", "--dir", dir, "kubectl"})
+	require.NoError(t, root.Execute())
+}
+
+func TestLink_RefusesToOverwriteOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kubectl"), []byte("#!/bin/sh\n"), 0600))
+
+	root := makeLinkRoot()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"link", "--dir", dir, "kubectl"})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to overwrite")
+}
+
+func TestLink_RejectsPathInCommandName(t *testing.T) {
+	root := makeLinkRoot()
+	root.SetArgs([]string{"link", "--dir", t.TempDir(), "../kubectl"})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid command name")
+}
+
+func TestUnlink_RemovesLinks(t *testing.T) {
+	dir := t.TempDir()
+	root := makeLinkRoot()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"link", "--dir", dir, "kubectl", "az", "docker"})
+	require.NoError(t, root.Execute())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0600))
+
+	root = makeLinkRoot()
+	root.SetArgs([]string{"unlink", "--dir", dir, "kubectl"})
+	require.NoError(t, root.Execute())
+	_, err := os.Lstat(filepath.Join(dir, "kubectl"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Lstat(filepath.Join(dir, "az"))
+	assert.NoError(t, err, "other links stay")
+
+	// No args removes every remaining intercept but leaves other files
+	root = makeLinkRoot()
+	root.SetArgs([]string{"unlink", "--dir", dir})
+	require.NoError(t, root.Execute())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "notes.txt", entries[0].Name())
+}
+
+func TestResolveLinkDir_ExpandsTilde(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir, err := resolveLinkDir("~/.cli-replay/bin")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".cli-replay", "bin"), dir)
+}
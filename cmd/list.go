@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+)
+
+var listTagFlags []string
+
+var listCmd = &cobra.Command{
+	Use:   "list [dir|scenario.yaml...]",
+	Short: "List scenarios, optionally filtered by tag",
+	Long: `List the scenarios found under the given directories (default: the
+current directory) or in the given files, one per line: path, meta.name and
+meta.tags, separated by tabs.
+
+With --tag, only scenarios carrying that tag in meta.tags are listed. The
+flag may be repeated; a scenario is listed if it has any of the given tags.
+Files that are not valid scenarios are skipped with a warning on stderr.
+
+Examples:
+  cli-replay list scenarios/
+  cli-replay list --tag smoke scenarios/
+  cli-replay list --tag smoke --tag fast scenarios/`,
+	RunE: runList,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	listCmd.Flags().StringArrayVar(&listTagFlags, "tag", nil, "Only list scenarios with this tag in meta.tags (repeatable)")
+	listCmd.ValidArgsFunction = completeScenarioFiles(0)
+	rootCmd.AddCommand(listCmd)
+}
+
+// listedScenario is one scenario document found by 'list'.
+type listedScenario struct {
+	Path string
+	Meta scenario.Meta
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	files, err := findScenarioFiles(args)
+	if err != nil {
+		return err
+	}
+	listed := loadListedScenarios(files, os.Stderr)
+	writeScenarioList(cmd.OutOrStdout(), filterByTags(listed, listTagFlags))
+	return nil
+}
+
+// findScenarioFiles expands paths into scenario files: files are kept as
+// given, directories are walked for .yaml/.yml files. Directories that
+// never hold scenarios (.git, node_modules, .cli-replay, ...) are skipped.
+// The result is sorted.
+func findScenarioFiles(paths []string) ([]string, error) {
+	skipDirs := map[string]bool{
+		".git":         true,
+		".cli-replay":  true,
+		"node_modules": true,
+		"vendor":       true,
+		".terraform":   true,
+		"__pycache__":  true,
+	}
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d os.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			if d.IsDir() {
+				if path != p && skipDirs[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", p, err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadListedScenarios loads every document of each file, warning on warn
+// about files that are not valid scenarios.
+func loadListedScenarios(files []string, warn io.Writer) []listedScenario {
+	var out []listedScenario
+	for _, f := range files {
+		docs, err := scenario.LoadFileAll(f)
+		if err != nil {
+			fmt.Fprintf(warn, "cli-replay: warning: skipping %s: %v\n", f, err)
+			continue
+		}
+		for _, scn := range docs {
+			out = append(out, listedScenario{Path: f, Meta: scn.Meta})
+		}
+	}
+	return out
+}
+
+// filterByTags keeps the scenarios that have any of tags; no tags keeps all.
+func filterByTags(scenarios []listedScenario, tags []string) []listedScenario {
+	if len(tags) == 0 {
+		return scenarios
+	}
+	var out []listedScenario
+	for _, s := range scenarios {
+		if hasAnyTag(&s.Meta, tags) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// hasAnyTag reports whether meta has any of tags; true when tags is empty.
+func hasAnyTag(meta *scenario.Meta, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if meta.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeScenarioList writes one tab-separated line per scenario.
+func writeScenarioList(w io.Writer, scenarios []listedScenario) {
+	for _, s := range scenarios {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Path, s.Meta.Name, strings.Join(s.Meta.Tags, ","))
+	}
+}
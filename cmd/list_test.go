@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeListRoot creates a fresh root + list command tree for testing.
+func makeListRoot() (*cobra.Command, *bytes.Buffer) {
+	listTagFlags = nil
+
+	root := &cobra.Command{
+		Use:           "cli-replay",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	l := &cobra.Command{
+		Use:  "list [dir|scenario.yaml...]",
+		RunE: runList,
+	}
+	l.Flags().StringArrayVar(&listTagFlags, "tag", nil, "Only list scenarios with this tag in meta.tags (repeatable)")
+	root.AddCommand(l)
+
+	stdout := new(bytes.Buffer)
+	root.SetOut(stdout)
+	return root, stdout
+}
+
+func writeTaggedScenario(t *testing.T, path, name, tags string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(`meta:
+  name: `+name+`
+  tags: `+tags+`
+steps:
+  - match:
+      argv: [git, status]
+    respond:
+      exit: 0
+`), 0600))
+}
+
+func TestList_FiltersByTag(t *testing.T) {
+	dir := t.TempDir()
+	smoke := filepath.Join(dir, "smoke.yaml")
+	slow := filepath.Join(dir, "team", "slow.yml")
+	writeTaggedScenario(t, smoke, "quick-check", "[smoke]")
+	writeTaggedScenario(t, slow, "full-deploy", "[slow, destructive]")
+	// Session state is never listed
+	writeTaggedScenario(t, filepath.Join(dir, ".cli-replay", "ignored.yaml"), "ignored", "[smoke]")
+
+	run := func(args ...string) string {
+		root, stdout := makeListRoot()
+		root.SetArgs(append([]string{"list"}, args...))
+		require.NoError(t, root.Execute())
+		return stdout.String()
+	}
+
+	assert.Equal(t, smoke+"\tquick-check\tsmoke\n"+slow+"\tfull-deploy\tslow,destructive\n", run(dir))
+	assert.Equal(t, smoke+"\tquick-check\tsmoke\n", run("--tag", "smoke", dir))
+	assert.Equal(t, slow+"\tfull-deploy\tslow,destructive\n", run("--tag", "destructive", dir))
+	assert.Equal(t, smoke+"\tquick-check\tsmoke\n"+slow+"\tfull-deploy\tslow,destructive\n",
+		run("--tag", "smoke", "--tag", "slow", dir))
+	assert.Empty(t, run("--tag", "unknown", dir))
+	assert.Equal(t, slow+"\tfull-deploy\tslow,destructive\n", run(slow))
+}
+
+func TestList_SkipsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	writeTaggedScenario(t, good, "good", "[smoke]")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ci.yaml"), []byte("jobs: {}\n"), 0600))
+
+	var warn bytes.Buffer
+	files, err := findScenarioFiles([]string{dir})
+	require.NoError(t, err)
+	listed := loadListedScenarios(files, &warn)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "good", listed[0].Meta.Name)
+	assert.Contains(t, warn.String(), "skipping "+filepath.Join(dir, "ci.yaml"))
+}
+
+func TestList_MissingPath(t *testing.T) {
+	root, _ := makeListRoot()
+	root.SetArgs([]string{"list", filepath.Join(t.TempDir(), "missing")})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read")
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var mergeOutputFlag string
+var mergeNameFlag string
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge -o <combined.yaml> <scenario.yaml>...",
+	Short: "Concatenate multiple scenario files into one",
+	Long: `Merge reusable scenario fragments (login, setup, teardown, ...) into a
+single scenario file.
+
+Each input file is loaded and validated individually. Steps are concatenated
+in the order the files are given, and meta.vars are merged with later files
+overriding earlier ones. tags and strip_prefixes are concatenated; other meta
+fields are taken from the last file that sets them. Auto-named groups are renumbered so the merged result
+keeps sequential "group-N" names.
+
+The merge is rejected before anything is written if two fragments define the
+same capture identifier, or if a capture collides with a merged meta.vars key.
+
+Examples:
+  cli-replay merge -o combined.yaml login.yaml deploy.yaml teardown.yaml
+  cli-replay merge -o combined.yaml --name full-flow a.yaml b.yaml`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMerge,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	mergeCmd.Flags().StringVarP(&mergeOutputFlag, "output", "o", "", "output YAML file path (required)")
+	mergeCmd.Flags().StringVar(&mergeNameFlag, "name", "", "name of the merged scenario (default: first file's meta.name)")
+	_ = mergeCmd.MarkFlagRequired("output")
+	mergeCmd.ValidArgsFunction = completeScenarioFiles(0)
+	rootCmd.AddCommand(mergeCmd)
+}
+
+// runMerge loads each scenario argument, merges them, and writes the result.
+func runMerge(_ *cobra.Command, args []string) error {
+	if mergeOutputFlag == "" {
+		return fmt.Errorf("--output flag is required")
+	}
+
+	scenarios := make([]*scenario.Scenario, 0, len(args))
+	for _, path := range args {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path %s: %w", path, err)
+		}
+		scn, err := scenario.LoadFile(absPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		scenarios = append(scenarios, scn)
+	}
+
+	merged, err := scenario.Merge(mergeNameFlag, scenarios...)
+	if err != nil {
+		return fmt.Errorf("failed to merge scenarios: %w", err)
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to marshal merged scenario: %w", err)
+	}
+	if err := os.WriteFile(mergeOutputFlag, data, 0600); err != nil {
+		return fmt.Errorf("failed to write merged scenario: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Merged %d scenario(s) into %s (%d steps)\n",
+		len(scenarios), mergeOutputFlag, len(merged.FlatSteps()))
+	return nil
+}
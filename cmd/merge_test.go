@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeMergeRoot creates a fresh root + merge command tree for testing.
+func makeMergeRoot() *cobra.Command {
+	mergeOutputFlag = ""
+	mergeNameFlag = ""
+
+	root := &cobra.Command{
+		Use:           "cli-replay",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	m := &cobra.Command{
+		Use:  "merge -o <combined.yaml> <scenario.yaml>...",
+		Args: cobra.MinimumNArgs(1),
+		RunE: runMerge,
+	}
+	m.Flags().StringVarP(&mergeOutputFlag, "output", "o", "", "output YAML file path (required)")
+	m.Flags().StringVar(&mergeNameFlag, "name", "", "name of the merged scenario")
+	root.AddCommand(m)
+	return root
+}
+
+func TestMerge_WritesValidCombinedScenario(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.yaml")
+	b := filepath.Join(dir, "b.yaml")
+	out := filepath.Join(dir, "combined.yaml")
+	require.NoError(t, os.WriteFile(a, []byte(`meta:
+  name: setup
+  vars:
+    ns: dev
+steps:
+  - match:
+      argv: [kubectl, create, ns, "{{ .ns }}"]
+    respond:
+      exit: 0
+      capture:
+        ns_id: "abc"
+`), 0644))
+	require.NoError(t, os.WriteFile(b, []byte(`meta:
+  name: teardown
+  vars:
+    ns: prod
+steps:
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: [kubectl, delete, ns]
+          respond:
+            exit: 0
+            stdout: "{{ .capture.ns_id }}"
+`), 0644))
+
+	root := makeMergeRoot()
+	root.SetArgs([]string{"merge", "-o", out, "--name", "full", a, b})
+	require.NoError(t, root.Execute())
+
+	scn, err := scenario.LoadFile(out)
+	require.NoError(t, err, "merged output must be a valid scenario")
+	assert.Equal(t, "full", scn.Meta.Name)
+	assert.Equal(t, "prod", scn.Meta.Vars["ns"])
+	assert.Len(t, scn.FlatSteps(), 2)
+	assert.Equal(t, "group-1", scn.GroupRanges()[0].Name)
+}
+
+func TestMerge_ConflictDoesNotWriteOutput(t *testing.T) {
+	dir := t.TempDir()
+	frag := `meta:
+  name: frag
+steps:
+  - match:
+      argv: [cmd]
+    respond:
+      exit: 0
+      capture:
+        id: "x"
+`
+	a := filepath.Join(dir, "a.yaml")
+	b := filepath.Join(dir, "b.yaml")
+	out := filepath.Join(dir, "combined.yaml")
+	require.NoError(t, os.WriteFile(a, []byte(frag), 0644))
+	require.NoError(t, os.WriteFile(b, []byte(frag), 0644))
+
+	root := makeMergeRoot()
+	root.SetArgs([]string{"merge", "-o", out, a, b})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "capture identifier")
+
+	_, statErr := os.Stat(out)
+	assert.True(t, os.IsNotExist(statErr), "output must not be written on conflict")
+}
+
+func TestMerge_InvalidFragmentRejected(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.yaml")
+	require.NoError(t, os.WriteFile(a, []byte("meta:\n  name: \"\"\nsteps: []\n"), 0644))
+
+	root := makeMergeRoot()
+	root.SetArgs([]string{"merge", "-o", filepath.Join(dir, "out.yaml"), a})
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a.yaml")
+}
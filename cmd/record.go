@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
 	"time"
 
@@ -17,8 +19,23 @@ var (
 	recordName        string
 	recordDescription string
 	recordCommands    []string
+	recordRedact      []string
+	recordRedactEnv   []string
+	recordEnv         []string
+	recordSplit       bool
+	recordNameHash    bool
+	recordNormalize   bool
+	recordNormPattern []string
+	recordReview      bool
+	recordMergeInto   string
+	recordTiming      bool
+	recordMaxDelay    string
+	recordStdinHash   bool
 )
 
+// envNameRe matches environment variable names accepted by --record-env.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 var recordCmd = &cobra.Command{
 	Use:   "record [flags] -- <command> [args...]",
 	Short: "Record a command execution and generate a YAML scenario file",
@@ -39,9 +56,66 @@ Examples:
   # Record only specific commands from a shell script
   cli-replay record --output workflow.yaml --command kubectl --command docker -- bash deploy.sh
 
+  # Record one step per external command a script runs, without listing them
+  cli-replay record --output workflow.yaml --split-commands -- bash deploy.sh
+
   # Record a multi-command script
   cli-replay record --output workflow.yaml -- bash -c "echo step1 && echo step2"
 
+  # Record env-dependent responses as match.env
+  cli-replay record --output env.yaml --record-env AZURE_SUBSCRIPTION,KUBECONFIG -- bash deploy.sh
+
+  # Re-record into a hand-edited scenario, keeping its comments
+  cli-replay record --merge-into deploy.yaml -- bash deploy.sh
+
+  # Scrub tokens from recorded output (regex and env-var values)
+  cli-replay record --output az.yaml --redact 'eyJ[A-Za-z0-9._-]+' --redact-env AZURE_TOKEN -- az account get-access-token
+
+With --record-env, the listed environment variables are snapshotted when
+each command runs and written to the step's match.env, so replay only
+matches when the same values are present. Unset variables are omitted.
+
+With --split-commands (Unix only), every executable on PATH is shimmed, so
+each external command the script invokes becomes its own step, in execution
+order. Shells and env are not shimmed, and commands run by a recorded
+command are not recorded separately. It cannot be combined with --command.
+
+With --name-from-hash, the scenario name is derived from a short hash of
+the recorded argvs instead of the current time, so re-recording the same
+commands yields the same name.
+
+Matches of --redact patterns and values of --redact-env variables are
+replaced with ***REDACTED*** in recorded stdout/stderr, stdin, and
+--record-env values before the scenario is written.
+
+With --normalize, ISO timestamps, durations (1.5s, 250ms), and kubectl
+AGE values (5m, 3h12m, 7d) in recorded stdout/stderr are replaced with
+<TIMESTAMP>, <DURATION>, and <AGE>. Each --normalize-pattern adds a
+PLACEHOLDER=REGEX replacement, applied after the built-in ones.
+
+With --review, the recorded steps are listed before the file is written,
+and you choose which to keep (e.g. 1,3-4) and optionally their calls
+bounds. Review needs an interactive terminal on stdin; otherwise it is
+skipped and every step is written.
+
+With --capture-timing, each step after the first gets a respond.delay equal
+to the time between the start of the previous recorded command and its own,
+so replay reproduces the original pacing. Gaps longer than
+--max-captured-delay (default 30s, 0 disables the cap) are shortened to it.
+It needs --command or --split-commands.
+
+With --stdin-hash, recorded stdin is written as match.stdin_sha256, the
+digest of the piped content, instead of inline match.stdin, which keeps
+large manifests out of the scenario.
+
+With --merge-into, an existing scenario is updated instead of overwritten,
+so comments and hand-added fields survive re-recording. Each recorded
+command updates exit, stdout and stderr of the next existing step with the
+same match.argv (outputs served from stdout_file/stderr_file are left as
+is), and commands with no such step are appended as new steps. The file's
+meta is kept. The result is written back to the file, or to --output when
+given.
+
 The generated YAML file can be used with 'cli-replay run' for deterministic testing.`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runRecord,
@@ -50,12 +124,24 @@ The generated YAML file can be used with 'cli-replay run' for deterministic test
 func init() { //nolint:gochecknoinits // Standard cobra pattern
 	rootCmd.AddCommand(recordCmd)
 
-	recordCmd.Flags().StringVarP(&recordOutputPath, "output", "o", "", "output YAML file path (required)")
+	recordCmd.Flags().StringVarP(&recordOutputPath, "output", "o", "", "output YAML file path (required unless --merge-into is set)")
 	recordCmd.Flags().StringVarP(&recordName, "name", "n", "", "scenario name (default: auto-generated)")
 	recordCmd.Flags().StringVarP(&recordDescription, "description", "d", "", "scenario description")
 	recordCmd.Flags().StringSliceVarP(&recordCommands, "command", "c", []string{}, "commands to intercept (can be repeated)")
-
-	_ = recordCmd.MarkFlagRequired("output")
+	recordCmd.Flags().StringArrayVar(&recordRedact, "redact", nil, "regex whose matches are redacted from recorded output (can be repeated)")
+	recordCmd.Flags().StringSliceVar(&recordRedactEnv, "redact-env", nil, "environment variables whose values are redacted from recorded output (can be repeated)")
+	recordCmd.Flags().BoolVar(&recordNormalize, "normalize", false, "replace timestamps, ages, and durations in recorded output with stable placeholders")
+	recordCmd.Flags().StringArrayVar(&recordNormPattern, "normalize-pattern", nil, "PLACEHOLDER=REGEX replacement applied to recorded output (can be repeated)")
+	recordCmd.Flags().BoolVar(&recordNameHash, "name-from-hash", false, "derive the scenario name from a hash of the recorded commands instead of a timestamp")
+	recordCmd.Flags().BoolVar(&recordSplit, "split-commands", false, "record every external command the script invokes as its own step (Unix only)")
+	recordCmd.Flags().StringSliceVar(&recordEnv, "record-env", nil, "environment variables to snapshot into each step's match.env (comma-separated or repeated)")
+	recordCmd.Flags().BoolVar(&recordReview, "review", false, "choose which recorded steps to keep, and their calls bounds, before writing (interactive terminals only)")
+	recordCmd.Flags().BoolVar(&recordTiming, "capture-timing", false, "write the gap since the previous recorded command as each step's respond.delay")
+	recordCmd.Flags().StringVar(&recordMaxDelay, "max-captured-delay", "30s", "cap on a delay written by --capture-timing (e.g., 30s, 2m; 0 disables the cap)")
+	recordCmd.Flags().BoolVar(&recordStdinHash, "stdin-hash", false, "write recorded stdin as match.stdin_sha256 instead of inline match.stdin")
+	recordCmd.Flags().StringVar(&recordMergeInto, "merge-into", "", "update the responses of this existing scenario in place, keeping its comments, instead of overwriting it")
+
+	recordCmd.MarkFlagsOneRequired("output", "merge-into")
 }
 
 // runRecord is the main handler for the record subcommand.
@@ -65,12 +151,57 @@ func init() { //nolint:gochecknoinits // Standard cobra pattern
 //	1 = setup failure
 //	2 = user command failed (still generates YAML)
 //	3 = YAML generation/validation failed
-func runRecord(_ *cobra.Command, args []string) error {
+func runRecord(cmd *cobra.Command, args []string) error {
+	outputPath := recordOutputPath
+	if recordMergeInto != "" {
+		if recordName != "" || recordDescription != "" || recordNameHash {
+			return fmt.Errorf("--merge-into keeps the existing scenario's meta; it cannot be combined with --name, --description or --name-from-hash")
+		}
+		if _, err := os.Stat(recordMergeInto); err != nil {
+			return fmt.Errorf("cannot merge into %s: %w", recordMergeInto, err)
+		}
+		if outputPath == "" {
+			outputPath = recordMergeInto
+		}
+	}
+
 	// Validate output path
-	if err := validateRecordOutputPath(recordOutputPath); err != nil {
+	if err := validateRecordOutputPath(outputPath); err != nil {
 		return fmt.Errorf("output path not writable: %w", err)
 	}
 
+	for _, name := range recordEnv {
+		if !envNameRe.MatchString(name) {
+			return fmt.Errorf("invalid --record-env variable name %q", name)
+		}
+	}
+
+	if recordNameHash && recordName != "" {
+		return fmt.Errorf("--name-from-hash cannot be combined with --name")
+	}
+
+	filters := recordCommands
+	if recordSplit {
+		if len(recordCommands) > 0 {
+			return fmt.Errorf("--split-commands cannot be combined with --command")
+		}
+		if runtime.GOOS == "windows" {
+			return fmt.Errorf("--split-commands is not supported on Windows yet; list commands with --command")
+		}
+		filters = recorder.DiscoverCommands(os.Getenv("PATH"))
+		if len(filters) == 0 {
+			return fmt.Errorf("--split-commands found no executables on PATH")
+		}
+	}
+
+	maxCapturedDelay, err := time.ParseDuration(recordMaxDelay)
+	if err != nil || maxCapturedDelay < 0 {
+		return fmt.Errorf("invalid --max-captured-delay %q: must be a non-negative duration", recordMaxDelay)
+	}
+	if recordTiming && len(filters) == 0 {
+		return fmt.Errorf("--capture-timing needs --command or --split-commands: a directly recorded command has no previous command to measure from")
+	}
+
 	// Create session metadata
 	meta := recorder.SessionMetadata{
 		Name:        recordName,
@@ -79,11 +210,30 @@ func runRecord(_ *cobra.Command, args []string) error {
 	}
 
 	// Create recording session with platform abstraction
-	session, err := recorder.New(meta, recordCommands, platform.New())
+	session, err := recorder.New(meta, filters, platform.New())
 	if err != nil {
 		return fmt.Errorf("failed to create recording session: %w", err)
 	}
 	defer session.Cleanup() //nolint:errcheck // best-effort cleanup
+	session.RecordEnv = recordEnv
+	session.CaptureTiming = recordTiming
+	session.MaxCapturedDelay = maxCapturedDelay
+
+	if len(recordRedact) > 0 || len(recordRedactEnv) > 0 {
+		redactor, err := recorder.NewRedactor(recordRedact, recordRedactEnv, os.Getenv)
+		if err != nil {
+			return err
+		}
+		session.Redactor = redactor
+	}
+
+	if recordNormalize || len(recordNormPattern) > 0 {
+		normalizer, err := recorder.NewNormalizer(recordNormalize, recordNormPattern)
+		if err != nil {
+			return err
+		}
+		session.Normalizer = normalizer
+	}
 
 	// Setup shims if command filters are specified
 	if err := session.SetupShims(); err != nil {
@@ -97,28 +247,54 @@ func runRecord(_ *cobra.Command, args []string) error {
 	}
 
 	// For shim mode, finalize the session by parsing the JSONL log
-	if len(recordCommands) > 0 {
+	if len(filters) > 0 {
 		if err := session.Finalize(); err != nil {
 			return fmt.Errorf("failed to finalize recording: %w", err)
 		}
 	}
 
+	if recordNameHash {
+		session.UseHashedName()
+	}
+
 	// Convert recorded commands to scenario
 	sc, err := recorder.ConvertToScenario(session.Metadata, session.Commands)
 	if err != nil {
 		return fmt.Errorf("failed to convert to scenario: %w", err)
 	}
+	if recordStdinHash {
+		recorder.HashStdin(sc)
+	}
 
-	// Write YAML file
-	if err := recorder.WriteYAMLFile(recordOutputPath, sc); err != nil {
-		return fmt.Errorf("failed to write YAML file: %w", err)
+	if recordReview {
+		if stdinIsTerminal() {
+			if err := reviewSteps(cmd.InOrStdin(), os.Stderr, sc); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "cli-replay: --review skipped: stdin is not a terminal\n")
+		}
 	}
 
-	// Print success message to stderr (stdout is reserved for command output)
-	fmt.Fprintf(os.Stderr, "✓ Recorded %d command(s) to %s\n", len(session.Commands), recordOutputPath)
-	fmt.Fprintf(os.Stderr, "  Scenario: %s\n", sc.Meta.Name)
-	if sc.Meta.Description != "" {
-		fmt.Fprintf(os.Stderr, "  Description: %s\n", sc.Meta.Description)
+	// Write YAML file, or merge into the existing one
+	if recordMergeInto != "" {
+		stats, err := recorder.MergeIntoYAMLFile(recordMergeInto, outputPath, sc)
+		if err != nil {
+			return fmt.Errorf("failed to merge into %s: %w", recordMergeInto, err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Merged %d command(s) into %s (%d updated, %d added)\n",
+			len(sc.Steps), outputPath, stats.Updated, stats.Added)
+	} else {
+		if err := recorder.WriteYAMLFile(outputPath, sc); err != nil {
+			return fmt.Errorf("failed to write YAML file: %w", err)
+		}
+
+		// Print success message to stderr (stdout is reserved for command output)
+		fmt.Fprintf(os.Stderr, "✓ Recorded %d command(s) to %s\n", len(sc.Steps), outputPath)
+		fmt.Fprintf(os.Stderr, "  Scenario: %s\n", sc.Meta.Name)
+		if sc.Meta.Description != "" {
+			fmt.Fprintf(os.Stderr, "  Description: %s\n", sc.Meta.Description)
+		}
 	}
 
 	// If user command had non-zero exit, still succeed (we captured it) but inform
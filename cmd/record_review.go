@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"golang.org/x/term"
+)
+
+// stdinIsTerminal reports whether stdin is an interactive terminal. It is a
+// variable so tests can stand in for a TTY.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// reviewSteps lists the recorded steps on out and reads from in which ones
+// to keep and, for each kept step, optional calls bounds. The scenario's
+// steps are replaced by the selection. An answer that cannot be parsed is
+// reported and asked again; end of input accepts the defaults (every step,
+// exactly one call each).
+func reviewSteps(in io.Reader, out io.Writer, sc *scenario.Scenario) error {
+	if len(sc.Steps) == 0 {
+		return nil
+	}
+	scanner := bufio.NewScanner(in)
+	ask := func(prompt string, parse func(string) error) error {
+		for {
+			fmt.Fprint(out, prompt)
+			answer := ""
+			if scanner.Scan() {
+				answer = strings.TrimSpace(scanner.Text())
+			} else if err := scanner.Err(); err != nil {
+				return fmt.Errorf("failed to read review input: %w", err)
+			}
+			err := parse(answer)
+			if err == nil {
+				return nil
+			}
+			fmt.Fprintf(out, "  %v\n", err)
+			if answer == "" {
+				return err
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "Recorded %d step(s):\n", len(sc.Steps))
+	for i, elem := range sc.Steps {
+		fmt.Fprintf(out, "  %d. %s\n", i+1, reviewStepSummary(elem.Step))
+	}
+
+	var keep []int
+	err := ask("Steps to keep (e.g. 1,3-4; Enter for all): ", func(answer string) error {
+		var parseErr error
+		keep, parseErr = parseStepSelection(answer, len(sc.Steps))
+		return parseErr
+	})
+	if err != nil {
+		return err
+	}
+
+	selected := make([]scenario.StepElement, 0, len(keep))
+	for _, idx := range keep {
+		elem := sc.Steps[idx]
+		prompt := fmt.Sprintf("Calls for step %d, as min,max or a single count (Enter for exactly once): ", idx+1)
+		err := ask(prompt, func(answer string) error {
+			bounds, parseErr := parseCallBounds(answer)
+			if parseErr == nil {
+				elem.Step.Calls = bounds
+			}
+			return parseErr
+		})
+		if err != nil {
+			return err
+		}
+		selected = append(selected, elem)
+	}
+	sc.Steps = selected
+	return nil
+}
+
+// reviewStepSummary describes a recorded step on one line for review.
+func reviewStepSummary(step *scenario.Step) string {
+	if step == nil {
+		return "(group)"
+	}
+	return fmt.Sprintf("%s  (exit %d, %d bytes stdout, %d bytes stderr)",
+		formatArgvShort(step.Match.Argv), step.Respond.EffectiveExit(), len(step.Respond.Stdout), len(step.Respond.Stderr))
+}
+
+// parseStepSelection parses a comma-separated list of 1-based step numbers
+// and ranges ("1,3-4") into sorted, distinct 0-based indexes. An empty
+// answer or "all" selects every step.
+func parseStepSelection(answer string, total int) ([]int, error) {
+	if answer == "" || strings.EqualFold(answer, "all") {
+		all := make([]int, total)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+	chosen := make([]bool, total)
+	for _, part := range strings.Split(answer, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(lo))
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(hi))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid step selection %q", part)
+		}
+		if start < 1 || end > total || start > end {
+			return nil, fmt.Errorf("step selection %q out of range 1-%d", part, total)
+		}
+		for i := start; i <= end; i++ {
+			chosen[i-1] = true
+		}
+	}
+	var keep []int
+	for i, ok := range chosen {
+		if ok {
+			keep = append(keep, i)
+		}
+	}
+	if len(keep) == 0 {
+		return nil, fmt.Errorf("select at least one step")
+	}
+	return keep, nil
+}
+
+// parseCallBounds parses "min,max" or a single count into calls bounds. An
+// empty answer returns nil, the default of exactly one call.
+func parseCallBounds(answer string) (*scenario.CallBounds, error) {
+	if answer == "" {
+		return nil, nil
+	}
+	lo, hi, hasMax := strings.Cut(answer, ",")
+	minCalls, err := strconv.Atoi(strings.TrimSpace(lo))
+	maxCalls := minCalls
+	if err == nil && hasMax {
+		maxCalls, err = strconv.Atoi(strings.TrimSpace(hi))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid calls %q: use min,max or a single count", answer)
+	}
+	bounds := &scenario.CallBounds{Min: minCalls, Max: maxCalls}
+	if err := bounds.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid calls %q: %w", answer, err)
+	}
+	return bounds, nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// writeCatScript writes a script that cats three files, one recorded step
+// per call when cat is shimmed.
+func writeCatScript(t *testing.T, dir string) string {
+	t.Helper()
+	var body strings.Builder
+	body.WriteString("#!/bin/bash\n")
+	for i, content := range []string{"first\n", "second\n", "third\n"} {
+		f := filepath.Join(dir, fmt.Sprintf("input%d.txt", i+1))
+		require.NoError(t, os.WriteFile(f, []byte(content), 0600))
+		fmt.Fprintf(&body, "cat %s\n", f)
+	}
+	script := filepath.Join(dir, "cats.sh")
+	require.NoError(t, os.WriteFile(script, []byte(body.String()), 0755)) //nolint:gosec // test script
+	return script
+}
+
+func TestRecordCommand_ReviewKeepsSelectedSteps(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash-based shim test")
+	}
+	orig := stdinIsTerminal
+	stdinIsTerminal = func() bool { return true }
+	t.Cleanup(func() { stdinIsTerminal = orig })
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "reviewed.yaml")
+	script := writeCatScript(t, tmpDir)
+
+	// Keep steps 1 and 3; step 1 keeps the default, step 3 gets 0-2 calls
+	answers := strings.NewReader("1,3\n\n0,2\n")
+	_, _, err := executeRecordCmdWithInput([]string{
+		"record", "--output", outputPath, "--review",
+		"--command", "cat",
+		"--", "bash", script,
+	}, answers)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath) //nolint:gosec // test file path
+	require.NoError(t, err)
+	var sc scenario.Scenario
+	require.NoError(t, yaml.Unmarshal(content, &sc))
+
+	require.Len(t, sc.Steps, 2)
+	assert.Contains(t, sc.Steps[0].Step.Respond.Stdout, "first")
+	assert.Nil(t, sc.Steps[0].Step.Calls)
+	assert.Contains(t, sc.Steps[1].Step.Respond.Stdout, "third")
+	assert.Equal(t, &scenario.CallBounds{Min: 0, Max: 2}, sc.Steps[1].Step.Calls)
+	assert.NotContains(t, string(content), "second")
+}
+
+func TestRecordCommand_ReviewSkippedWithoutTTY(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash-based shim test")
+	}
+	orig := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	t.Cleanup(func() { stdinIsTerminal = orig })
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "unreviewed.yaml")
+	script := writeCatScript(t, tmpDir)
+
+	_, _, err := executeRecordCmdWithInput([]string{
+		"record", "--output", outputPath, "--review",
+		"--command", "cat",
+		"--", "bash", script,
+	}, strings.NewReader("2\n"))
+	require.NoError(t, err)
+
+	sc, err := scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	assert.Len(t, sc.Steps, 3, "every step is written when review is skipped")
+}
+
+func TestReviewSteps_RepromptsOnInvalidAnswer(t *testing.T) {
+	sc := &scenario.Scenario{Steps: []scenario.StepElement{
+		{Step: &scenario.Step{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}}},
+		{Step: &scenario.Step{Match: scenario.Match{Argv: []string{"kubectl", "apply"}}}},
+	}}
+	var out bytes.Buffer
+	require.NoError(t, reviewSteps(strings.NewReader("5\n2\n3,1\n2\n"), &out, sc))
+
+	require.Len(t, sc.Steps, 1)
+	assert.Equal(t, []string{"kubectl", "apply"}, sc.Steps[0].Step.Match.Argv)
+	assert.Equal(t, &scenario.CallBounds{Min: 2, Max: 2}, sc.Steps[0].Step.Calls)
+	assert.Contains(t, out.String(), "1. kubectl get pods")
+	assert.Contains(t, out.String(), `step selection "5" out of range 1-2`)
+	assert.Contains(t, out.String(), "min (3) must be <= max (1)")
+}
+
+func TestParseStepSelection(t *testing.T) {
+	got, err := parseStepSelection("", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, got)
+
+	got, err = parseStepSelection("3, 1-2,2", 4)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, got)
+
+	_, err = parseStepSelection("x", 3)
+	assert.EqualError(t, err, `invalid step selection "x"`)
+	_, err = parseStepSelection("2-1", 3)
+	assert.Error(t, err)
+}
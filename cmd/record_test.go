@@ -3,11 +3,13 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
 
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -18,11 +20,29 @@ import (
 // executeRecordCmd creates a fresh record command and executes it with the given args.
 // This avoids global state contamination between tests.
 func executeRecordCmd(args []string) (*bytes.Buffer, *bytes.Buffer, error) { //nolint:unparam // stdout kept for symmetry
+	return executeRecordCmdWithInput(args, nil)
+}
+
+// executeRecordCmdWithInput is executeRecordCmd with in as the command's
+// input, read by --review. The recorded command still inherits os.Stdin.
+func executeRecordCmdWithInput(args []string, in io.Reader) (*bytes.Buffer, *bytes.Buffer, error) {
 	// Reset global flag variables to avoid state leaking between tests
 	recordOutputPath = ""
 	recordName = ""
 	recordDescription = ""
 	recordCommands = nil
+	recordRedact = nil
+	recordRedactEnv = nil
+	recordEnv = nil
+	recordSplit = false
+	recordNameHash = false
+	recordNormalize = false
+	recordNormPattern = nil
+	recordReview = false
+	recordMergeInto = ""
+	recordTiming = false
+	recordMaxDelay = "30s"
+	recordStdinHash = false
 
 	stdout := new(bytes.Buffer)
 	stderr := new(bytes.Buffer)
@@ -43,12 +63,27 @@ func executeRecordCmd(args []string) (*bytes.Buffer, *bytes.Buffer, error) { //n
 	rec.Flags().StringVarP(&recordName, "name", "n", "", "scenario name")
 	rec.Flags().StringVarP(&recordDescription, "description", "d", "", "scenario description")
 	rec.Flags().StringSliceVarP(&recordCommands, "command", "c", []string{}, "commands to intercept")
-	_ = rec.MarkFlagRequired("output")
+	rec.Flags().StringArrayVar(&recordRedact, "redact", nil, "regex to redact")
+	rec.Flags().StringSliceVar(&recordRedactEnv, "redact-env", nil, "env vars to redact")
+	rec.Flags().StringSliceVar(&recordEnv, "record-env", nil, "env vars to record")
+	rec.Flags().BoolVar(&recordSplit, "split-commands", false, "record each external command")
+	rec.Flags().BoolVar(&recordNameHash, "name-from-hash", false, "hash-based name")
+	rec.Flags().BoolVar(&recordNormalize, "normalize", false, "normalize volatile output")
+	rec.Flags().StringArrayVar(&recordNormPattern, "normalize-pattern", nil, "custom normalization")
+	rec.Flags().BoolVar(&recordReview, "review", false, "review steps before writing")
+	rec.Flags().StringVar(&recordMergeInto, "merge-into", "", "merge into existing scenario")
+	rec.Flags().BoolVar(&recordTiming, "capture-timing", false, "record gaps as delays")
+	rec.Flags().StringVar(&recordMaxDelay, "max-captured-delay", "30s", "cap on captured delays")
+	rec.Flags().BoolVar(&recordStdinHash, "stdin-hash", false, "record stdin as a digest")
+	rec.MarkFlagsOneRequired("output", "merge-into")
 	root.AddCommand(rec)
 
 	root.SetOut(stdout)
 	root.SetErr(stderr)
 	root.SetArgs(args)
+	if in != nil {
+		root.SetIn(in)
+	}
 
 	err := root.Execute()
 	return stdout, stderr, err
@@ -85,7 +120,7 @@ func TestRecordCommand_SingleCommand(t *testing.T) {
 	// Verify scenario structure
 	require.Len(t, sc.Steps, 1, "should have exactly one step")
 	assert.Contains(t, sc.Steps[0].Step.Respond.Stdout, "hello world")
-	assert.Equal(t, 0, sc.Steps[0].Step.Respond.Exit)
+	assert.Equal(t, 0, sc.Steps[0].Step.Respond.EffectiveExit())
 	assert.Empty(t, sc.Steps[0].Step.Respond.Stderr)
 
 	// Verify metadata has a name (auto-generated if not specified)
@@ -142,7 +177,7 @@ func TestRecordCommand_NonZeroExit(t *testing.T) {
 	require.NoError(t, err)
 
 	require.Len(t, sc.Steps, 1)
-	assert.Equal(t, 42, sc.Steps[0].Step.Respond.Exit)
+	assert.Equal(t, 42, sc.Steps[0].Step.Respond.EffectiveExit())
 }
 
 func TestRecordCommand_StderrCapture(t *testing.T) {
@@ -171,10 +206,74 @@ func TestRecordCommand_StderrCapture(t *testing.T) {
 	require.NoError(t, err)
 
 	require.Len(t, sc.Steps, 1)
-	assert.Equal(t, 1, sc.Steps[0].Step.Respond.Exit)
+	assert.Equal(t, 1, sc.Steps[0].Step.Respond.EffectiveExit())
 	assert.Contains(t, sc.Steps[0].Step.Respond.Stderr, "errout")
 }
 
+func TestRecordCommand_RedactsSecrets(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix-specific test")
+	}
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "redacted.yaml")
+	// Secrets come from the environment so they never appear in the recorded argv.
+	t.Setenv("FAKE_API_KEY", "key-abc-987")
+	t.Setenv("FAKE_OUTPUT", "token: ghp_FakeToken123 user: alice")
+
+	_, stderr, err := executeRecordCmd([]string{
+		"record", "--output", outputPath,
+		"--redact", `ghp_[A-Za-z0-9]+`, "--redact-env", "FAKE_API_KEY", "--",
+		"sh", "-c", `echo "$FAKE_OUTPUT"; echo "$FAKE_API_KEY" >&2`,
+	})
+	require.NoError(t, err, "stderr: %s", stderr.String())
+
+	content, err := os.ReadFile(outputPath) //nolint:gosec // test file path
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "ghp_FakeToken123")
+	assert.NotContains(t, string(content), "key-abc-987")
+
+	var sc scenario.Scenario
+	require.NoError(t, yaml.Unmarshal(content, &sc))
+	require.Len(t, sc.Steps, 1)
+	assert.Equal(t, "token: ***REDACTED*** user: alice\n", sc.Steps[0].Step.Respond.Stdout)
+	assert.Equal(t, "***REDACTED***\n", sc.Steps[0].Step.Respond.Stderr)
+}
+
+func TestRecordCommand_InvalidRedactPattern(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "out.yaml")
+	_, _, err := executeRecordCmd([]string{"record", "--output", outputPath, "--redact", "(", "--", "echo", "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid redact pattern")
+}
+
+func TestRecordCommand_NormalizesVolatileOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix-specific test")
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "normalized.yaml")
+	_, stderr, err := executeRecordCmd([]string{
+		"record", "--output", outputPath,
+		"--normalize", "--normalize-pattern", `<UID>=uid-[0-9a-f]+`, "--",
+		"sh", "-c", `echo "web-1 Running 3h12m 2024-01-15T10:30:00Z uid-4f2a"; echo "done in 1.25s" >&2`,
+	})
+	require.NoError(t, err, "stderr: %s", stderr.String())
+
+	sc, err := scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	require.Len(t, sc.Steps, 1)
+	assert.Equal(t, "web-1 Running <AGE> <TIMESTAMP> <UID>\n", sc.Steps[0].Step.Respond.Stdout)
+	assert.Equal(t, "done in <DURATION>\n", sc.Steps[0].Step.Respond.Stderr)
+}
+
+func TestRecordCommand_InvalidNormalizePattern(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "out.yaml")
+	_, _, err := executeRecordCmd([]string{"record", "--output", outputPath, "--normalize-pattern", "no-placeholder", "--", "echo", "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid normalize pattern")
+}
+
 func TestRecordCommand_MissingOutputFlag(t *testing.T) {
 	_, _, err := executeRecordCmd([]string{
 		"record", "--", "echo", "test",
@@ -232,6 +331,64 @@ func TestRecordCommand_OverwriteExistingFile(t *testing.T) {
 	assert.NotContains(t, string(content), "old content")
 }
 
+func TestRecordCommand_MergeIntoKeepsComments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses the Unix echo command")
+	}
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "greet.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`# Greeting scenario, edited by hand
+meta:
+  name: greet # stable name
+steps:
+  # The greeting itself
+  - match:
+      argv: [echo, hello world]
+    respond:
+      exit: 0
+      stdout: "stale output\n" # refreshed on re-record
+`), 0600))
+
+	_, _, err := executeRecordCmd([]string{"record", "--merge-into", path, "--", "echo", "hello world"})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path) //nolint:gosec // test file path
+	require.NoError(t, err)
+	for _, comment := range []string{"# Greeting scenario, edited by hand", "# stable name", "# The greeting itself", "# refreshed on re-record"} {
+		assert.Contains(t, string(content), comment)
+	}
+	assert.NotContains(t, string(content), "stale output")
+
+	scn, err := scenario.LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "greet", scn.Meta.Name)
+	require.Len(t, scn.Steps, 1)
+	assert.Equal(t, "hello world\n", scn.Steps[0].Step.Respond.Stdout)
+
+	// A command the scenario lacks is appended, written to --output
+	outputPath := filepath.Join(tmpDir, "greet-extra.yaml")
+	_, _, err = executeRecordCmd([]string{"record", "--merge-into", path, "--output", outputPath, "--", "echo", "bye"})
+	require.NoError(t, err)
+	scn, err = scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	require.Len(t, scn.Steps, 2)
+	assert.Equal(t, []string{"echo", "bye"}, scn.Steps[1].Step.Match.Argv)
+}
+
+func TestRecordCommand_MergeIntoErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	_, _, err := executeRecordCmd([]string{"record", "--merge-into", filepath.Join(tmpDir, "missing.yaml"), "--", "echo", "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot merge into")
+
+	path := filepath.Join(tmpDir, "existing.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("meta:\n  name: x\nsteps: []\n"), 0600))
+	_, _, err = executeRecordCmd([]string{"record", "--merge-into", path, "--name", "other", "--", "echo", "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be combined with --name")
+}
+
 func TestRecordCommand_EmptyOutput(t *testing.T) {
 	tmpDir := t.TempDir()
 	outputPath := filepath.Join(tmpDir, "empty-output.yaml")
@@ -255,7 +412,7 @@ func TestRecordCommand_EmptyOutput(t *testing.T) {
 	require.NoError(t, err)
 
 	require.Len(t, sc.Steps, 1)
-	assert.Equal(t, 0, sc.Steps[0].Step.Respond.Exit)
+	assert.Equal(t, 0, sc.Steps[0].Step.Respond.EffectiveExit())
 	assert.Empty(t, sc.Steps[0].Step.Respond.Stdout)
 	assert.Empty(t, sc.Steps[0].Step.Respond.Stderr)
 }
@@ -318,7 +475,7 @@ func TestRecordCommand_MultiCommandBashC(t *testing.T) {
 
 	require.Len(t, sc.Steps, 1)
 	assert.Equal(t, "first\nsecond\nthird\n", sc.Steps[0].Step.Respond.Stdout)
-	assert.Equal(t, 0, sc.Steps[0].Step.Respond.Exit)
+	assert.Equal(t, 0, sc.Steps[0].Step.Respond.EffectiveExit())
 }
 
 // TestRecordCommand_ShimBasedRecording tests the shim interception path
@@ -368,6 +525,124 @@ func TestRecordCommand_ShimBasedRecording(t *testing.T) {
 	assert.Contains(t, sc.Steps[0].Step.Respond.Stdout, "captured-via-shim")
 }
 
+// TestRecordCommand_ShimRecordsStdin verifies that piped input to a shimmed
+// command is recorded as the generated step's match.stdin.
+func TestRecordCommand_ShimRecordsStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash-based shim test; Windows shim tests in platform/windows_test.go")
+	}
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "stdin-shim.yaml")
+
+	script := filepath.Join(tmpDir, "apply.sh")
+	scriptContent := "#!/bin/bash\nprintf 'apiVersion: v1\\nkind: Pod\\n' | cat\n"
+	require.NoError(t, os.WriteFile(script, []byte(scriptContent), 0755)) //nolint:gosec // test script
+
+	_, _, err := executeRecordCmd([]string{
+		"record", "--output", outputPath,
+		"--command", "cat",
+		"--", "bash", script,
+	})
+	require.NoError(t, err)
+
+	scn, err := scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	steps := scn.FlatSteps()
+	require.Len(t, steps, 1)
+	assert.Equal(t, "cat", steps[0].Match.Argv[0])
+	// The shim reads stdin via $(< file), which drops the trailing newline;
+	// replay normalizes trailing newlines before comparing.
+	assert.Equal(t, "apiVersion: v1\nkind: Pod", steps[0].Match.Stdin)
+}
+
+func TestRecordCommand_StdinHash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash-based shim test; Windows shim tests in platform/windows_test.go")
+	}
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "stdin-hash.yaml")
+	script := filepath.Join(tmpDir, "apply.sh")
+	scriptContent := "#!/bin/bash\nprintf 'apiVersion: v1\\nkind: Pod\\n' | cat\n"
+	require.NoError(t, os.WriteFile(script, []byte(scriptContent), 0755)) //nolint:gosec // test script
+
+	_, _, err := executeRecordCmd([]string{
+		"record", "--output", outputPath,
+		"--command", "cat", "--stdin-hash",
+		"--", "bash", script,
+	})
+	require.NoError(t, err)
+
+	scn, err := scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	steps := scn.FlatSteps()
+	require.Len(t, steps, 1)
+	assert.Empty(t, steps[0].Match.Stdin)
+	assert.Equal(t, matcher.StdinSHA256("apiVersion: v1\nkind: Pod\n"), steps[0].Match.StdinSHA256)
+}
+
+func TestRecordCommand_RecordEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix-specific test")
+	}
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "env.yaml")
+	t.Setenv("CLI_REPLAY_TEST_REGION", "westeurope")
+
+	_, stderr, err := executeRecordCmd([]string{
+		"record", "--output", outputPath,
+		"--record-env", "CLI_REPLAY_TEST_REGION,CLI_REPLAY_TEST_UNSET",
+		"--", "echo", "hi",
+	})
+	require.NoError(t, err, "stderr: %s", stderr.String())
+
+	scn, err := scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	steps := scn.FlatSteps()
+	require.Len(t, steps, 1)
+	assert.Equal(t, map[string]string{"CLI_REPLAY_TEST_REGION": "westeurope"}, steps[0].Match.Env)
+}
+
+func TestRecordCommand_ShimRecordEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash-based shim test; Windows shim tests in platform/windows_test.go")
+	}
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "env-shim.yaml")
+	testFile := filepath.Join(tmpDir, "input.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("data\n"), 0600))
+
+	// The script changes the variable before the shimmed call; the snapshot
+	// must reflect the value at capture time, not at record start.
+	script := filepath.Join(tmpDir, "env.sh")
+	scriptContent := fmt.Sprintf("#!/bin/bash\nexport CLI_REPLAY_TEST_CTX='prod \"east\"'\ncat %s\n", testFile)
+	require.NoError(t, os.WriteFile(script, []byte(scriptContent), 0755)) //nolint:gosec // test script
+	t.Setenv("CLI_REPLAY_TEST_CTX", "dev")
+
+	_, _, err := executeRecordCmd([]string{
+		"record", "--output", outputPath,
+		"--command", "cat", "--record-env", "CLI_REPLAY_TEST_CTX",
+		"--", "bash", script,
+	})
+	require.NoError(t, err)
+
+	scn, err := scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	steps := scn.FlatSteps()
+	require.Len(t, steps, 1)
+	assert.Equal(t, map[string]string{"CLI_REPLAY_TEST_CTX": `prod "east"`}, steps[0].Match.Env)
+}
+
+func TestRecordCommand_RecordEnvInvalidName(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "out.yaml")
+	_, _, err := executeRecordCmd([]string{"record", "--output", outputPath, "--record-env", "BAD-NAME", "--", "echo", "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid --record-env variable name")
+}
+
 // TestRecordCommand_ShimMultipleCommands tests shim-based recording with
 // multiple intercepted commands in a single script execution.
 func TestRecordCommand_ShimMultipleCommands(t *testing.T) {
@@ -456,8 +731,40 @@ func TestRecordCommand_ShimPreservesExitCodes(t *testing.T) {
 	// Should have 2 cat steps
 	require.Len(t, sc.Steps, 2)
 	for _, step := range sc.Steps {
-		assert.Equal(t, 0, step.Step.Respond.Exit)
+		assert.Equal(t, 0, step.Step.Respond.EffectiveExit())
+	}
+}
+
+func TestRecordCommand_ShimRecordsExitInPipeline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash-based shim test; Windows shim tests in platform/windows_test.go")
 	}
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "pipeline.yaml")
+
+	// A command that fails with 42, piped into cat so the pipeline succeeds
+	binDir := filepath.Join(tmpDir, "bin")
+	require.NoError(t, os.Mkdir(binDir, 0750))
+	failer := filepath.Join(binDir, "failer")
+	require.NoError(t, os.WriteFile(failer, []byte("#!/bin/bash\necho partial\nexit 42\n"), 0755)) //nolint:gosec // test script
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	_, _, err := executeRecordCmd([]string{
+		"record", "--output", outputPath,
+		"--command", "failer",
+		"--", "bash", "-c", "failer | cat",
+	})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath) //nolint:gosec // test file path
+	require.NoError(t, err)
+
+	var sc scenario.Scenario
+	require.NoError(t, yaml.Unmarshal(content, &sc))
+	require.Len(t, sc.Steps, 1)
+	assert.Equal(t, []string{"failer"}, sc.Steps[0].Step.Match.Argv)
+	assert.Equal(t, 42, sc.Steps[0].Step.Respond.EffectiveExit())
 }
 
 // --- Validation Tests ---
@@ -554,3 +861,88 @@ func TestRecordCommand_YAMLRoundtrip(t *testing.T) {
 	assert.Equal(t, sc.Meta.Description, sc2.Meta.Description)
 	assert.Len(t, sc2.Steps, len(sc.Steps))
 }
+
+func TestRecordCommand_SplitCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("--split-commands is Unix only")
+	}
+	binDir := t.TempDir()
+	for name, body := range map[string]string{"first-tool": "echo one", "second-tool": "echo two"} {
+		require.NoError(t, os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"+body+"\n"), 0755)) //nolint:gosec // test script must be executable
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	script := filepath.Join(t.TempDir(), "flow.sh")
+	require.NoError(t, os.WriteFile(script, []byte("first-tool\nsecond-tool --flag\n"), 0600))
+	outputPath := filepath.Join(t.TempDir(), "flow.yaml")
+
+	_, stderr, err := executeRecordCmd([]string{"record", "--output", outputPath, "--split-commands", "--", "bash", script})
+	require.NoError(t, err, "stderr: %s", stderr.String())
+
+	scn, err := scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	require.Len(t, scn.Steps, 2)
+	assert.Equal(t, []string{"first-tool"}, scn.Steps[0].Step.Match.Argv)
+	assert.Equal(t, []string{"second-tool", "--flag"}, scn.Steps[1].Step.Match.Argv)
+}
+
+func TestRecordCommand_CaptureTiming(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash-based shim test")
+	}
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "paced-tool"), []byte("#!/bin/sh\necho \"$1\"\n"), 0755)) //nolint:gosec // test script must be executable
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	script := filepath.Join(t.TempDir(), "paced.sh")
+	require.NoError(t, os.WriteFile(script, []byte("paced-tool one\nsleep 1.2\npaced-tool two\n"), 0600))
+	outputPath := filepath.Join(t.TempDir(), "paced.yaml")
+
+	_, stderr, err := executeRecordCmd([]string{"record", "--output", outputPath, "--command", "paced-tool",
+		"--capture-timing", "--max-captured-delay", "1s", "--", "bash", script})
+	require.NoError(t, err, "stderr: %s", stderr.String())
+
+	scn, err := scenario.LoadFile(outputPath)
+	require.NoError(t, err)
+	require.Len(t, scn.Steps, 2)
+	assert.Empty(t, scn.Steps[0].Step.Respond.Delay)
+	assert.Equal(t, "1s", scn.Steps[1].Step.Respond.Delay, "the gap is capped by --max-captured-delay")
+}
+
+func TestRecordCommand_CaptureTimingErrors(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "out.yaml")
+
+	_, _, err := executeRecordCmd([]string{"record", "--output", outputPath, "--capture-timing", "--", "true"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--capture-timing needs --command or --split-commands")
+
+	_, _, err = executeRecordCmd([]string{"record", "--output", outputPath, "--command", "kubectl",
+		"--capture-timing", "--max-captured-delay", "soon", "--", "true"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid --max-captured-delay "soon"`)
+}
+
+func TestRecordCommand_SplitCommandsRejectsCommandFilter(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "out.yaml")
+	_, _, err := executeRecordCmd([]string{"record", "--output", outputPath, "--split-commands", "--command", "kubectl", "--", "true"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be combined")
+}
+
+func TestRecordCommand_NameFromHash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses echo as an executable")
+	}
+	recordName := func(args ...string) string {
+		t.Helper()
+		outputPath := filepath.Join(t.TempDir(), "out.yaml")
+		_, stderr, err := executeRecordCmd(append([]string{"record", "--output", outputPath, "--name-from-hash", "--"}, args...))
+		require.NoError(t, err, "stderr: %s", stderr.String())
+		scn, err := scenario.LoadFile(outputPath)
+		require.NoError(t, err)
+		return scn.Meta.Name
+	}
+
+	first := recordName("echo", "same")
+	assert.Equal(t, first, recordName("echo", "same"))
+	assert.NotEqual(t, first, recordName("echo", "different"))
+	assert.Contains(t, first, "recorded-")
+}
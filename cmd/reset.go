@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset [scenario.yaml]",
+	Short: "Delete the replay state for the current session",
+	Long: `Delete the state file for the current session so the next intercepted
+call starts the scenario from step 1.
+
+Unlike 'clean', reset leaves the intercept directory in place, so an active
+'cli-replay run' session keeps working. The session is taken from
+CLI_REPLAY_SESSION. Resetting a session that has no state is not an error.
+
+If no scenario file is given, uses the CLI_REPLAY_SCENARIO environment
+variable.
+
+Examples:
+  cli-replay reset scenario.yaml
+  CLI_REPLAY_SESSION=ci-42 cli-replay reset scenario.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runReset,
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [scenario.yaml]",
+	Short: "Show where replay would resume for the current session",
+	Long: `Print the step the next intercepted call is expected to match, based on the
+current session's state (CLI_REPLAY_SESSION).
+
+If no scenario file is given, uses the CLI_REPLAY_SCENARIO environment
+variable.
+
+Examples:
+  cli-replay resume scenario.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runResume,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	resetCmd.ValidArgsFunction = completeScenarioFiles(1)
+	rootCmd.AddCommand(resetCmd)
+	resumeCmd.ValidArgsFunction = completeScenarioFiles(1)
+	rootCmd.AddCommand(resumeCmd)
+}
+
+func runReset(_ *cobra.Command, args []string) error {
+	var scenarioPath string
+	if len(args) > 0 {
+		scenarioPath = args[0]
+	} else {
+		scenarioPath = os.Getenv("CLI_REPLAY_SCENARIO")
+		if scenarioPath == "" {
+			return fmt.Errorf("no scenario specified — pass a file or set CLI_REPLAY_SCENARIO")
+		}
+	}
+
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve scenario path: %w", err)
+	}
+
+	if _, err := scenario.LoadFile(absPath); err != nil {
+		return fmt.Errorf("failed to load scenario: %w", err)
+	}
+
+	stateFile := runner.StateFilePath(absPath)
+	if _, statErr := os.Stat(stateFile); os.IsNotExist(statErr) {
+		fmt.Fprintf(os.Stderr, "cli-replay: no state to reset for %s\n", scenarioPath)
+		return nil
+	}
+
+	if err := runner.DeleteState(stateFile); err != nil {
+		return fmt.Errorf("failed to reset state: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "cli-replay: state reset for %s\n", scenarioPath)
+	return nil
+}
+
+func runResume(_ *cobra.Command, args []string) error {
+	var scenarioPath string
+	if len(args) > 0 {
+		scenarioPath = args[0]
+	} else {
+		scenarioPath = os.Getenv("CLI_REPLAY_SCENARIO")
+		if scenarioPath == "" {
+			return fmt.Errorf("no scenario specified — pass a file or set CLI_REPLAY_SCENARIO")
+		}
+	}
+
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve scenario path: %w", err)
+	}
+
+	scn, err := runner.LoadScenario(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to load scenario: %w", err)
+	}
+
+	state, err := runner.ReadState(runner.StateFilePath(absPath))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read state: %w", err)
+		}
+		state = nil
+	}
+
+	fmt.Fprintln(os.Stderr, formatResumeLine(scn, state))
+	return nil
+}
+
+// formatResumeLine describes the step the next intercepted call must match.
+func formatResumeLine(scn *scenario.Scenario, state *runner.State) string {
+	consumed, total, current := statusProgress(scn, state)
+	if current == nil {
+		return fmt.Sprintf("%s: complete (%d/%d steps consumed), nothing to resume", scn.Meta.Name, consumed, total)
+	}
+
+	step := 0
+	if state != nil {
+		step = state.CurrentStep
+	}
+	line := fmt.Sprintf("%s: would resume at step %d/%d: %s",
+		scn.Meta.Name, step+1, total, formatArgvShort(current))
+	ranges := scn.GroupRanges()
+	if gi := runner.FindGroupContaining(ranges, step); gi >= 0 {
+		line += fmt.Sprintf(" (group %q, %s)", ranges[gi].Name, ranges[gi].Mode)
+	}
+	if state == nil {
+		line += " (no state, starts fresh)"
+	}
+	return line
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeResetRoot creates a fresh root + reset command tree for testing.
+func makeResetRoot() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "cli-replay",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.AddCommand(&cobra.Command{
+		Use:  "reset [scenario.yaml]",
+		Args: cobra.MaximumNArgs(1),
+		RunE: runReset,
+	})
+	return root
+}
+
+func TestReset_PartiallyAdvancedScenarioRestartsAtStepZero(t *testing.T) {
+	path, _ := writeStatusScenario(t)
+	t.Setenv("CLI_REPLAY_SESSION", "reset-test")
+
+	var stdout, stderr bytes.Buffer
+	_, err := runner.ExecuteReplay(path, []string{"kubectl", "apply"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	absPath, err := filepath.Abs(path)
+	require.NoError(t, err)
+	stateFile := runner.StateFilePath(absPath)
+	state, err := runner.ReadState(stateFile)
+	require.NoError(t, err)
+	require.Equal(t, 1, state.CurrentStep)
+
+	root := makeResetRoot()
+	root.SetArgs([]string{"reset", path})
+	require.NoError(t, root.Execute())
+	_, err = os.Stat(stateFile)
+	assert.True(t, os.IsNotExist(err), "state file must be removed")
+
+	// The next call must match step 0 again.
+	result, err := runner.ExecuteReplay(path, []string{"kubectl", "apply"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.StepIndex)
+}
+
+func TestReset_Idempotent(t *testing.T) {
+	path, _ := writeStatusScenario(t)
+
+	for i := 0; i < 2; i++ {
+		root := makeResetRoot()
+		root.SetArgs([]string{"reset", path})
+		require.NoError(t, root.Execute())
+	}
+}
+
+func TestReset_OnlyAffectsCurrentSession(t *testing.T) {
+	path, _ := writeStatusScenario(t)
+	absPath, err := filepath.Abs(path)
+	require.NoError(t, err)
+
+	other := runner.StateFilePathWithSession(absPath, "other")
+	require.NoError(t, runner.WriteState(other, runner.NewState(absPath, "", 2)))
+
+	t.Setenv("CLI_REPLAY_SESSION", "mine")
+	require.NoError(t, runner.WriteState(runner.StateFilePath(absPath), runner.NewState(absPath, "", 2)))
+
+	root := makeResetRoot()
+	root.SetArgs([]string{"reset", path})
+	require.NoError(t, root.Execute())
+
+	_, err = os.Stat(runner.StateFilePath(absPath))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(other)
+	assert.NoError(t, err, "other sessions' state must be kept")
+}
+
+func TestFormatResumeLine(t *testing.T) {
+	path, scn := writeStatusScenario(t)
+
+	assert.Contains(t, formatResumeLine(scn, nil), "would resume at step 1/2: kubectl apply (no state")
+
+	absPath, err := filepath.Abs(path)
+	require.NoError(t, err)
+	state := runner.NewState(absPath, "", 2)
+	state.StepCounts[0] = 1
+	state.CurrentStep = 1
+	assert.Equal(t, "status-test: would resume at step 2/2: kubectl rollout status", formatResumeLine(scn, state))
+
+	state.StepCounts[1] = 1
+	state.CurrentStep = 2
+	assert.Contains(t, formatResumeLine(scn, state), "nothing to resume")
+}
+
+func TestFormatResumeLine_InsideGroup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`meta:
+  name: group-resume
+steps:
+  - group:
+      name: checks
+      mode: unordered
+      steps:
+        - match:
+            argv: [az, account, show]
+          respond:
+            exit: 0
+        - match:
+            argv: [kubectl, version]
+          respond:
+            exit: 0
+`), 0644))
+	scn, err := scenario.LoadFile(path)
+	require.NoError(t, err)
+
+	assert.Contains(t, formatResumeLine(scn, nil), `(group "checks", unordered)`)
+}
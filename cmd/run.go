@@ -2,13 +2,13 @@ package cmd
 
 import (
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,9 +20,18 @@ import (
 var runShellFlag string
 var allowedCommandsFlag string
 var runDryRunFlag bool
+var runStartStepFlag int
+var runForceFlag bool
+var runSimulateFileFlag string
+var runAutoSessionFlag bool
+var runMaxStdinFlag int64
+var runMaxDelayFlag string
+var runSeedFlag string
+var runIndexFlag int
+var runNameFlag string
 
 var runCmd = &cobra.Command{
-	Use:   "run <scenario.yaml>",
+	Use:   "run <scenario.yaml> [more.yaml...]",
 	Short: "Start or resume a replay session",
 	Long: `Start or resume a replay session for a scenario.
 
@@ -36,33 +45,92 @@ Usage (PowerShell):
 Usage (bash / zsh):
   eval "$(cli-replay run scenario.yaml)"
 
+Usage (fish):
+  cli-replay run --shell fish scenario.yaml | source
+
+Usage (pwsh):
+  cli-replay run --shell pwsh scenario.yaml | Invoke-Expression
+
 The --shell flag selects the output format. If omitted, the shell is auto-
-detected from the PSModulePath (PowerShell) or SHELL environment variable.`,
-	Args: cobra.ExactArgs(1),
+detected from the PSModulePath (PowerShell) or SHELL environment variable.
+
+With --start-step N, the session starts at flat step index N (0-based, as in
+validation messages): earlier steps are marked as invoked their minimum
+number of times, so the first intercepted call matches step N. Starting
+after a step whose captures are referenced later is refused unless --force
+is given, since those references would render empty.
+
+With --dry-run --simulate-file <cmds.txt>, each line of the file (one
+command per line, shell-style quoting) is matched against the scenario in
+memory and the resulting trace is printed: which step each command would
+match and where the first mismatch occurs. No state is read or written.
+
+With several scenario files, one session intercepts the union of their
+commands and replays the files in order. Each file keeps its own step
+ordering and state (check each with 'cli-replay verify <file>'). Replay moves
+to the next file once the current one is complete, or when a command matches
+the next file and every step of the current one has met its min count.
+Captures set by earlier files are available to templates in later ones.
+--start-step and --simulate-file need a single file.
+
+With --auto-session (or meta.session.auto: pid), the session ID is derived
+from the calling shell's process ID instead of generated at random, so it
+stays the same across runs in one shell and differs between shells.
+
+For a file holding several scenarios separated by ---, --index N (0-based)
+or --name NAME (meta.name) selects the one to replay; the first is used
+otherwise. The choice is exported as CLI_REPLAY_DOCUMENT so intercepts and
+'cli-replay verify' use the same document.
+
+Usage (sequence):
+  eval "$(cli-replay run setup.yaml deploy.yaml teardown.yaml)"`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: runRun,
 }
 
 func init() { //nolint:gochecknoinits // Standard cobra pattern
-	runCmd.Flags().StringVar(&runShellFlag, "shell", "", "Output format: powershell, bash, cmd (auto-detected if omitted)")
+	runCmd.Flags().StringVar(&runShellFlag, "shell", "", "Output format: powershell, pwsh, bash, fish, cmd (auto-detected if omitted)")
 	runCmd.Flags().StringVar(&allowedCommandsFlag, "allowed-commands", "", "Comma-separated list of commands allowed to be intercepted")
 	runCmd.Flags().BoolVar(&runDryRunFlag, "dry-run", false, "Preview the scenario step sequence without creating intercepts")
+	runCmd.Flags().IntVar(&runStartStepFlag, "start-step", 0, "Start the session at this 0-based flat step index")
+	runCmd.Flags().BoolVar(&runForceFlag, "force", false, "With --start-step, skip steps whose captures are referenced later")
+	runCmd.Flags().BoolVar(&runAutoSessionFlag, "auto-session", false, "Derive the session ID from the calling shell's process ID")
+	runCmd.Flags().StringVar(&runMaxDelayFlag, "max-delay", "5m", "Maximum allowed delay + jitter of a step (e.g., 5m, 30s; 0 disables the cap)")
+	runCmd.Flags().StringVar(&runSeedFlag, "seed", "", "Seed for respond.random and respond.jitter, exported as CLI_REPLAY_SEED (time-based if omitted)")
+	runCmd.Flags().Int64Var(&runMaxStdinFlag, "max-stdin", 0, "Maximum bytes of piped stdin read for match.stdin (overrides meta.limits.max_stdin_bytes)")
+	runCmd.Flags().IntVar(&runIndexFlag, "index", 0, "In a multi-document file, replay the scenario at this 0-based index")
+	runCmd.Flags().StringVar(&runNameFlag, "name", "", "In a multi-document file, replay the scenario with this meta.name")
+	runCmd.Flags().StringVar(&runSimulateFileFlag, "simulate-file", "", "With --dry-run, match the commands in this file (one per line) against the scenario")
+	runCmd.ValidArgsFunction = completeScenarioFiles(0)
 	rootCmd.AddCommand(runCmd)
 }
 
 func runRun(cmd *cobra.Command, args []string) error {
+	if err := scenario.ValidateMaxStdinBytes(runMaxStdinFlag); err != nil {
+		return fmt.Errorf("--max-stdin %w", err)
+	}
+	maxDelay, err := time.ParseDuration(runMaxDelayFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --max-delay %q: %w", runMaxDelayFlag, err)
+	}
+	if len(args) > 1 {
+		return runSequence(cmd, args, maxDelay)
+	}
 	scenarioPath := args[0]
 
-	absPath, err := filepath.Abs(scenarioPath)
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
 	if err != nil {
 		return fmt.Errorf("failed to resolve scenario path: %w", err)
 	}
 
 	// Load and validate scenario
-	scn, err := scenario.LoadFile(absPath)
+	scn, document, err := loadSelectedScenario(absPath, runIndexFlag, runNameFlag)
 	if err != nil {
 		return fmt.Errorf("failed to load scenario: %w", err)
 	}
-
+	if err := validateDelays(scn, maxDelay); err != nil {
+		return err
+	}
 	// Extract unique command names from scenario steps (argv[0])
 	commands := extractCommands(scn)
 	if len(commands) == 0 {
@@ -74,26 +142,46 @@ func runRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Reject an unusable --start-step before any side effects
+	if runStartStepFlag != 0 {
+		if err := checkStartStep(scn, runStartStepFlag, runForceFlag); err != nil {
+			return err
+		}
+	}
+
+	if runSimulateFileFlag != "" && !runDryRunFlag {
+		return fmt.Errorf("--simulate-file requires --dry-run")
+	}
+
 	// Dry-run mode: preview scenario and exit without side effects
 	if runDryRunFlag {
 		report := runner.BuildDryRunReport(scn)
-		return runner.FormatDryRunReport(report, cmd.OutOrStdout())
-	}
-
-	// T018: TTL cleanup at session startup
-	if scn.Meta.Session != nil && scn.Meta.Session.TTL != "" {
-		ttl, parseErr := time.ParseDuration(scn.Meta.Session.TTL)
-		if parseErr == nil && ttl > 0 {
-			cliReplayDir := filepath.Join(filepath.Dir(absPath), ".cli-replay")
-			cleaned, _ := runner.CleanExpiredSessions(cliReplayDir, ttl, os.Stderr)
-			if cleaned > 0 {
-				fmt.Fprintf(os.Stderr, "cli-replay: cleaned %d expired sessions\n", cleaned)
+		if runSimulateFileFlag != "" {
+			trace, err := simulateFile(scn, absPath, runSimulateFileFlag)
+			if err != nil {
+				return err
 			}
+			report.Simulation = trace
+		}
+		if err := runner.FormatDryRunReport(report, cmd.OutOrStdout()); err != nil {
+			return err
 		}
+		if report.Simulation != nil && !report.Simulation.Passed() {
+			return fmt.Errorf("simulation of %s failed", runSimulateFileFlag)
+		}
+		return nil
 	}
 
+	seed, err := sessionSeed(os.Stderr, runSeedFlag, scn)
+	if err != nil {
+		return err
+	}
+
+	// T018: TTL cleanup at session startup
+	cleanExpiredOnStart(scn, absPath)
+
 	// Calculate scenario hash for state tracking
-	scenarioHash := hashScenarioFile(absPath)
+	scenarioHash := runner.ScenarioHash(absPath)
 
 	// Locate our own binary to create intercepts
 	self, err := os.Executable()
@@ -117,13 +205,20 @@ func runRun(cmd *cobra.Command, args []string) error {
 	}
 
 	// Generate session ID for parallel isolation
-	sessionID := generateSessionID()
+	sessionID := sessionIDFor(scn)
 
 	// Initialize state (resets to step 0) and store intercept dir
 	// Use session-aware state path so parallel runs don't collide
 	stateFile := runner.StateFilePathWithSession(absPath, sessionID)
 	state := runner.NewState(absPath, scenarioHash, len(scn.FlatSteps()))
 	state.InterceptDir = interceptDir
+	state.MaxStdinBytes = runMaxStdinFlag
+	if runStartStepFlag != 0 {
+		if err := state.SeekTo(scn.FlatSteps(), scn.GroupRanges(), runStartStepFlag); err != nil {
+			_ = os.RemoveAll(interceptDir)
+			return err
+		}
+	}
 	if err := runner.WriteState(stateFile, state); err != nil {
 		_ = os.RemoveAll(interceptDir)
 		return fmt.Errorf("failed to initialize state: %w", err)
@@ -134,27 +229,126 @@ func runRun(cmd *cobra.Command, args []string) error {
 		scn.Meta.Name, len(scn.FlatSteps()), len(commands))
 	fmt.Fprintf(os.Stderr, "  intercept dir: %s\n", interceptDir)
 	fmt.Fprintf(os.Stderr, "  commands: %s\n", strings.Join(commands, ", "))
+	if runStartStepFlag != 0 {
+		fmt.Fprintf(os.Stderr, "  starting at step %d: %s\n", runStartStepFlag,
+			strings.Join(scn.FlatSteps()[runStartStepFlag].Match.PrimaryArgv(), " "))
+	}
 
 	// Detect shell and emit env-setting code to stdout
 	shell := detectShell(runShellFlag)
-	emitShellSetup(shell, interceptDir, absPath, sessionID)
+	emitShellSetup(shell, interceptDir, []string{absPath}, sessionID, seed, document)
 
 	return nil
 }
 
+// cleanExpiredOnStart removes sessions older than the scenario's
+// meta.session.ttl from the .cli-replay directory next to it.
+func cleanExpiredOnStart(scn *scenario.Scenario, absPath string) {
+	if scn.Meta.Session == nil || scn.Meta.Session.TTL == "" {
+		return
+	}
+	ttl, parseErr := time.ParseDuration(scn.Meta.Session.TTL)
+	if parseErr != nil || ttl <= 0 {
+		return
+	}
+	cliReplayDir := filepath.Join(filepath.Dir(absPath), ".cli-replay")
+	cleaned, _ := runner.CleanExpiredSessions(cliReplayDir, ttl, os.Stderr)
+	if cleaned > 0 {
+		fmt.Fprintf(os.Stderr, "cli-replay: cleaned %d expired sessions\n", cleaned)
+	}
+}
+
+// simulateFile parses the command log at path and replays it against scn.
+func simulateFile(scn *scenario.Scenario, scenarioPath, path string) (*runner.SimulationTrace, error) {
+	f, err := os.Open(path) //nolint:gosec // user-provided command log
+	if err != nil {
+		return nil, fmt.Errorf("failed to open simulate file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // read-only file close
+
+	commands, err := runner.ParseCommandLog(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return runner.SimulateCommands(scn, filepath.Dir(scenarioPath), commands), nil
+}
+
+// checkStartStep validates --start-step against the scenario's step range,
+// group boundaries and capture dependencies. Skipping a step whose captures
+// are referenced later is an error unless force is set.
+func checkStartStep(scn *scenario.Scenario, start int, force bool) error {
+	probe := runner.NewState("", "", len(scn.FlatSteps()))
+	if err := probe.SeekTo(scn.FlatSteps(), scn.GroupRanges(), start); err != nil {
+		return err
+	}
+
+	skipped := scn.SkippedCaptureRefs(start)
+	if len(skipped) == 0 {
+		return nil
+	}
+	if force {
+		for _, sc := range skipped {
+			fmt.Fprintf(os.Stderr, "cli-replay: warning: capture %q from skipped step %d is referenced by step %d and will render empty\n",
+				sc.Capture, sc.DefinedAt, sc.ReferencedAt)
+		}
+		return nil
+	}
+	first := skipped[0]
+	return fmt.Errorf("cannot start at step %d: capture %q defined by skipped step %d is referenced by step %d (use --force to skip anyway)",
+		start, first.Capture, first.DefinedAt, first.ReferencedAt)
+}
+
+// validateDelays checks that no step can wait longer than maxDelay, counting
+// the full jitter range. A zero maxDelay only checks that durations parse.
+func validateDelays(scn *scenario.Scenario, maxDelay time.Duration) error {
+	for i, step := range scn.FlatSteps() {
+		if err := step.Respond.ValidateDelay(maxDelay); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if maxDelay == 0 {
+			if _, err := step.Respond.DelayDuration(); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
 // extractCommands returns a de-duplicated, ordered list of command names
-// from step[*].match.argv[0] in the scenario.
+// from step[*].match.argv[0] (every match.any_of entry included) in the
+// scenario, followed by the wrappers in
+// meta.strip_prefixes so wrapped invocations also reach an intercept, and
+// by meta.security.forbidden_commands so calls to them can be refused.
 func extractCommands(scn *scenario.Scenario) []string {
 	seen := make(map[string]bool)
 	var cmds []string
 	for _, step := range scn.FlatSteps() {
-		if len(step.Match.Argv) == 0 {
-			continue
+		for _, argv := range step.Match.Alternatives() {
+			if len(argv) == 0 {
+				continue
+			}
+			name := argv[0]
+			if !seen[name] {
+				seen[name] = true
+				cmds = append(cmds, name)
+			}
 		}
-		name := step.Match.Argv[0]
-		if !seen[name] {
-			seen[name] = true
-			cmds = append(cmds, name)
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+	for _, prefix := range scn.Meta.StripPrefixes {
+		if !seen[prefix] {
+			seen[prefix] = true
+			cmds = append(cmds, prefix)
+		}
+	}
+	if scn.Meta.Security != nil {
+		for _, forbidden := range scn.Meta.Security.ForbiddenCommands {
+			if !seen[forbidden] {
+				seen[forbidden] = true
+				cmds = append(cmds, forbidden)
+			}
 		}
 	}
 	return cmds
@@ -181,8 +375,12 @@ func createIntercept(self, interceptDir, command string) error {
 func detectShell(explicit string) string {
 	if explicit != "" {
 		switch strings.ToLower(explicit) {
-		case "powershell", "pwsh", "ps":
+		case "powershell", "ps":
 			return "powershell"
+		case "pwsh":
+			return "pwsh"
+		case "fish":
+			return "fish"
 		case "bash", "zsh", "sh":
 			return "bash"
 		case "cmd":
@@ -199,6 +397,9 @@ func detectShell(explicit string) string {
 
 	// Check SHELL env (Unix)
 	if shell := os.Getenv("SHELL"); shell != "" {
+		if filepath.Base(shell) == "fish" {
+			return "fish"
+		}
 		return "bash"
 	}
 
@@ -210,34 +411,117 @@ func detectShell(explicit string) string {
 }
 
 // emitShellSetup writes shell-specific commands to stdout that set
-// CLI_REPLAY_SESSION, CLI_REPLAY_SCENARIO, and prepend the intercept directory to PATH.
-// For bash/zsh/sh, also emits a cleanup trap function and trap statement.
-func emitShellSetup(shell, interceptDir, scenarioPath, sessionID string) {
-	writeShellSetup(os.Stdout, shell, interceptDir, scenarioPath, sessionID)
+// CLI_REPLAY_SESSION, CLI_REPLAY_SCENARIO, CLI_REPLAY_SEED and
+// CLI_REPLAY_DOCUMENT, and prepend the intercept directory to PATH.
+// For bash/zsh/sh, also emits a cleanup trap function and trap statement;
+// fish and pwsh get the equivalent exit-event handler.
+func emitShellSetup(shell, interceptDir string, scenarioPaths []string, sessionID string, seed int64, document int) {
+	writeShellSetup(os.Stdout, shell, interceptDir, scenarioPaths, sessionID)
+	writeEnvExport(os.Stdout, shell, runner.SeedEnvVar, strconv.FormatInt(seed, 10))
+	writeEnvExport(os.Stdout, shell, runner.DocumentEnvVar, strconv.Itoa(document))
+}
+
+// writeEnvExport writes the shell command that sets one environment
+// variable. value must not need quoting beyond single quotes.
+func writeEnvExport(w io.Writer, shell, key, value string) {
+	switch shell {
+	case "powershell", "pwsh":
+		fmt.Fprintf(w, "$env:%s = %s\n", key, psQuote(value))
+	case "fish":
+		fmt.Fprintf(w, "set -gx %s %s\n", key, fishQuote(value))
+	case "cmd":
+		fmt.Fprintf(w, "set \"%s=%s\"\n", key, value)
+	default: // bash / zsh / sh
+		fmt.Fprintf(w, "export %s='%s'\n", key, shellQuoteEscape(value))
+	}
 }
 
 // writeShellSetup writes shell-specific setup commands to the given writer.
-// Separated from emitShellSetup for testability.
-func writeShellSetup(w io.Writer, shell, interceptDir, scenarioPath, sessionID string) {
+// Separated from emitShellSetup for testability. CLI_REPLAY_SCENARIO is set
+// to the first path; with more than one path, CLI_REPLAY_SEQUENCE lists them
+// all and the cleanup trap cleans every file.
+func writeShellSetup(w io.Writer, shell, interceptDir string, scenarioPaths []string, sessionID string) {
+	scenarioPath := scenarioPaths[0]
+	var sequence string
+	if len(scenarioPaths) > 1 {
+		sequence = runner.FormatSequence(scenarioPaths)
+	}
 	switch shell {
 	case "powershell":
 		fmt.Fprintf(w, "$env:CLI_REPLAY_SESSION = '%s'\n", sessionID)
 		fmt.Fprintf(w, "$env:CLI_REPLAY_SCENARIO = '%s'\n", strings.ReplaceAll(scenarioPath, "'", "''"))
+		if sequence != "" {
+			fmt.Fprintf(w, "$env:CLI_REPLAY_SEQUENCE = '%s'\n", strings.ReplaceAll(sequence, "'", "''"))
+		}
 		fmt.Fprintf(w, "$env:PATH = '%s' + ';' + $env:PATH\n", strings.ReplaceAll(interceptDir, "'", "''"))
+	case "pwsh":
+		fmt.Fprintf(w, "$env:CLI_REPLAY_SESSION = '%s'\n", sessionID)
+		fmt.Fprintf(w, "$env:CLI_REPLAY_SCENARIO = %s\n", psQuote(scenarioPath))
+		if sequence != "" {
+			fmt.Fprintf(w, "$env:CLI_REPLAY_SEQUENCE = %s\n", psQuote(sequence))
+		}
+		fmt.Fprintf(w, "$env:PATH = %s + [IO.Path]::PathSeparator + $env:PATH\n", psQuote(interceptDir))
+		// Cleanup on exit: resolve the binary as an application so functions
+		// and aliases named cli-replay are bypassed, like `command` in POSIX
+		fmt.Fprintf(w, "$null = Register-EngineEvent -SourceIdentifier PowerShell.Exiting -Action { & (Get-Command -CommandType Application cli-replay | Select-Object -First 1).Source clean %s 2>$null }\n",
+			joinQuoted(scenarioPaths, psQuote))
+	case "fish":
+		fmt.Fprintf(w, "set -gx CLI_REPLAY_SESSION '%s'\n", sessionID)
+		fmt.Fprintf(w, "set -gx CLI_REPLAY_SCENARIO %s\n", fishQuote(scenarioPath))
+		if sequence != "" {
+			fmt.Fprintf(w, "set -gx CLI_REPLAY_SEQUENCE %s\n", fishQuote(sequence))
+		}
+		fmt.Fprintf(w, "set -gx PATH %s $PATH\n", fishQuote(interceptDir))
+		// Cleanup on exit: fish_exit fires once when the shell exits
+		fmt.Fprintf(w, "function _cli_replay_clean --on-event fish_exit\n")
+		fmt.Fprintf(w, "    command cli-replay clean %s 2>/dev/null\n", joinQuoted(scenarioPaths, fishQuote))
+		fmt.Fprintf(w, "end\n")
 	case "cmd":
 		fmt.Fprintf(w, "set \"CLI_REPLAY_SESSION=%s\"\n", sessionID)
 		fmt.Fprintf(w, "set \"CLI_REPLAY_SCENARIO=%s\"\n", scenarioPath)
+		if sequence != "" {
+			fmt.Fprintf(w, "set \"CLI_REPLAY_SEQUENCE=%s\"\n", sequence)
+		}
 		fmt.Fprintf(w, "set \"PATH=%s;%%PATH%%\"\n", interceptDir)
 	default: // bash / zsh / sh
 		fmt.Fprintf(w, "export CLI_REPLAY_SESSION='%s'\n", sessionID)
-		fmt.Fprintf(w, "export CLI_REPLAY_SCENARIO='%s'\n", strings.ReplaceAll(scenarioPath, "'", "'\\''"))
-		fmt.Fprintf(w, "export PATH='%s':\"$PATH\"\n", strings.ReplaceAll(interceptDir, "'", "'\\''"))
+		fmt.Fprintf(w, "export CLI_REPLAY_SCENARIO='%s'\n", shellQuoteEscape(scenarioPath))
+		cleanArgs := "\"$CLI_REPLAY_SCENARIO\""
+		if sequence != "" {
+			fmt.Fprintf(w, "export CLI_REPLAY_SEQUENCE='%s'\n", shellQuoteEscape(sequence))
+			cleanArgs = joinQuoted(scenarioPaths, func(p string) string { return "'" + shellQuoteEscape(p) + "'" })
+		}
+		fmt.Fprintf(w, "export PATH='%s':\"$PATH\"\n", shellQuoteEscape(interceptDir))
 		// Cleanup trap: auto-clean on exit or signal (FR-015, FR-016, FR-017)
-		fmt.Fprintf(w, "_cli_replay_clean() { if [ -n \"${_cli_replay_cleaned:-}\" ]; then return; fi; _cli_replay_cleaned=1; command cli-replay clean \"$CLI_REPLAY_SCENARIO\" 2>/dev/null; }\n")
+		fmt.Fprintf(w, "_cli_replay_clean() { if [ -n \"${_cli_replay_cleaned:-}\" ]; then return; fi; _cli_replay_cleaned=1; command cli-replay clean %s 2>/dev/null; }\n", cleanArgs)
 		fmt.Fprintf(w, "trap '_cli_replay_clean' EXIT INT TERM\n")
 	}
 }
 
+// psQuote returns s as a single-quoted PowerShell string.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// fishQuote returns s as a single-quoted fish string.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// joinQuoted quotes each path with quote and joins them with spaces.
+func joinQuoted(paths []string, quote func(string) string) string {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = quote(p)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuoteEscape escapes s for use inside a single-quoted POSIX shell string.
+func shellQuoteEscape(s string) string {
+	return strings.ReplaceAll(s, "'", "'\\''")
+}
+
 // generateSessionID returns a random hex string for session isolation.
 func generateSessionID() string {
 	b := make([]byte, 8)
@@ -248,6 +532,22 @@ func generateSessionID() string {
 	return hex.EncodeToString(b)
 }
 
+// sessionIDFor returns the session ID for a new run: derived from the
+// calling shell when --auto-session is set or any scenario requests
+// meta.session.auto: pid, random otherwise.
+func sessionIDFor(scenarios ...*scenario.Scenario) string {
+	auto := runAutoSessionFlag
+	for _, scn := range scenarios {
+		if scn.Meta.Session != nil && scn.Meta.Session.Auto == scenario.SessionAutoPID {
+			auto = true
+		}
+	}
+	if auto {
+		return runner.AutoSessionID()
+	}
+	return generateSessionID()
+}
+
 // checkAllowlist validates that all scenario commands are permitted by the
 // effective allowlist (intersection of YAML and CLI flag lists).
 func checkAllowlist(scn *scenario.Scenario) error {
@@ -259,16 +559,6 @@ func checkAllowlist(scn *scenario.Scenario) error {
 	return validateAllowlist(scn, yamlList, cliList)
 }
 
-// hashScenarioFile returns a hex-encoded SHA256 hash of the file content.
-func hashScenarioFile(path string) string {
-	data, err := os.ReadFile(path) //nolint:gosec // user-provided path is expected
-	if err != nil {
-		return ""
-	}
-	h := sha256.Sum256(data)
-	return hex.EncodeToString(h[:])
-}
-
 // parseAllowedCommands splits a comma-separated string into a slice of
 // trimmed, non-empty command names.
 func parseAllowedCommands(flag string) []string {
@@ -334,17 +624,19 @@ func validateAllowlist(scn *scenario.Scenario, yamlList, cliList []string) error
 	}
 
 	for i, step := range scn.FlatSteps() {
-		if len(step.Match.Argv) == 0 {
-			continue
-		}
-		cmd := filepath.Base(step.Match.Argv[0])
-		lookupCmd := cmd
-		if runtime.GOOS == "windows" {
-			lookupCmd = strings.ToLower(cmd)
-		}
-		if !set[lookupCmd] {
-			return fmt.Errorf("command %q is not in the allowed commands list: %v\n  Scenario: %s\n  Step %d: %v",
-				cmd, allowed, scn.Meta.Name, i+1, step.Match.Argv)
+		for _, argv := range step.Match.Alternatives() {
+			if len(argv) == 0 {
+				continue
+			}
+			cmd := filepath.Base(argv[0])
+			lookupCmd := cmd
+			if runtime.GOOS == "windows" {
+				lookupCmd = strings.ToLower(cmd)
+			}
+			if !set[lookupCmd] {
+				return fmt.Errorf("command %q is not in the allowed commands list: %v\n  Scenario: %s\n  Step %d: %v",
+					cmd, allowed, scn.Meta.Name, i+1, argv)
+			}
 		}
 	}
 	return nil
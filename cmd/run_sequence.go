@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+)
+
+// runSequence starts one session that replays several scenario files in
+// order. Every file gets its own state under the same session ID; a single
+// intercept directory covers the union of their commands.
+func runSequence(cmd *cobra.Command, args []string, maxDelay time.Duration) error {
+	if runStartStepFlag != 0 {
+		return fmt.Errorf("--start-step requires a single scenario file")
+	}
+	if runSimulateFileFlag != "" {
+		return fmt.Errorf("--simulate-file requires a single scenario file")
+	}
+	if runIndexFlag != 0 || runNameFlag != "" {
+		return fmt.Errorf("--index and --name require a single scenario file")
+	}
+
+	absPaths := make([]string, 0, len(args))
+	scenarios := make([]*scenario.Scenario, 0, len(args))
+	for _, arg := range args {
+		absPath, err := runner.ResolveScenarioPath(arg)
+		if err != nil {
+			return fmt.Errorf("failed to resolve scenario path: %w", err)
+		}
+		scn, err := scenario.LoadFile(absPath)
+		if err != nil {
+			return fmt.Errorf("failed to load scenario %s: %w", arg, err)
+		}
+		if err := checkAllowlist(scn); err != nil {
+			return err
+		}
+		if err := validateDelays(scn, maxDelay); err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+		absPaths = append(absPaths, absPath)
+		scenarios = append(scenarios, scn)
+	}
+
+	if runDryRunFlag {
+		for _, scn := range scenarios {
+			if err := runner.FormatDryRunReport(runner.BuildDryRunReport(scn), cmd.OutOrStdout()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	seed, err := sessionSeed(os.Stderr, runSeedFlag, scenarios...)
+	if err != nil {
+		return err
+	}
+
+	// Union of commands across files, in first-seen order
+	seen := make(map[string]bool)
+	var commands []string
+	for _, scn := range scenarios {
+		for _, c := range extractCommands(scn) {
+			if !seen[c] {
+				seen[c] = true
+				commands = append(commands, c)
+			}
+		}
+	}
+	if len(commands) == 0 {
+		return fmt.Errorf("scenarios have no steps with a command name")
+	}
+
+	for i, scn := range scenarios {
+		cleanExpiredOnStart(scn, absPaths[i])
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate cli-replay binary: %w", err)
+	}
+
+	// The intercept directory lives next to the first file
+	interceptDir, err := runner.InterceptDirPath(absPaths[0])
+	if err != nil {
+		return fmt.Errorf("failed to create intercept directory: %w", err)
+	}
+	for _, c := range commands {
+		if err := createIntercept(self, interceptDir, c); err != nil {
+			_ = os.RemoveAll(interceptDir)
+			return fmt.Errorf("failed to create intercept for %q: %w", c, err)
+		}
+	}
+
+	sessionID := sessionIDFor(scenarios...)
+	totalSteps := 0
+	for i, scn := range scenarios {
+		state := runner.NewState(absPaths[i], runner.ScenarioHash(absPaths[i]), len(scn.FlatSteps()))
+		state.InterceptDir = interceptDir
+		state.MaxStdinBytes = runMaxStdinFlag
+		if err := runner.WriteState(runner.StateFilePathWithSession(absPaths[i], sessionID), state); err != nil {
+			_ = os.RemoveAll(interceptDir)
+			return fmt.Errorf("failed to initialize state for %s: %w", args[i], err)
+		}
+		totalSteps += len(scn.FlatSteps())
+	}
+
+	fmt.Fprintf(os.Stderr, "cli-replay: session initialized for %d scenarios (%d steps, %d commands)\n",
+		len(scenarios), totalSteps, len(commands))
+	for i, scn := range scenarios {
+		fmt.Fprintf(os.Stderr, "  %d. %q (%d steps)\n", i+1, scn.Meta.Name, len(scn.FlatSteps()))
+	}
+	fmt.Fprintf(os.Stderr, "  intercept dir: %s\n", interceptDir)
+	fmt.Fprintf(os.Stderr, "  commands: %s\n", strings.Join(commands, ", "))
+
+	shell := detectShell(runShellFlag)
+	emitShellSetup(shell, interceptDir, absPaths, sessionID, seed, 0)
+	return nil
+}
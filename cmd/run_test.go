@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/ormasoftchile/cli-replay/internal/runner"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -150,7 +151,7 @@ func TestValidateAllowlist_WindowsCaseInsensitive(t *testing.T) {
 
 func TestEmitShellSetup_BashTrapEmission(t *testing.T) {
 	var buf bytes.Buffer
-	writeShellSetup(&buf, "bash", "/tmp/intercept-dir", "/path/to/scenario.yaml", "session-abc123")
+	writeShellSetup(&buf, "bash", "/tmp/intercept-dir", []string{"/path/to/scenario.yaml"}, "session-abc123")
 	output := buf.String()
 
 	// Should contain export statements
@@ -170,7 +171,7 @@ func TestEmitShellSetup_BashTrapEmission(t *testing.T) {
 func TestEmitShellSetup_DefaultShellTrapEmission(t *testing.T) {
 	// Default shell (empty string mapped to bash-like) should also emit traps
 	var buf bytes.Buffer
-	writeShellSetup(&buf, "", "/tmp/intercept", "/scenario.yaml", "s123")
+	writeShellSetup(&buf, "", "/tmp/intercept", []string{"/scenario.yaml"}, "s123")
 	output := buf.String()
 
 	assert.Contains(t, output, "_cli_replay_clean()")
@@ -179,7 +180,7 @@ func TestEmitShellSetup_DefaultShellTrapEmission(t *testing.T) {
 
 func TestEmitShellSetup_TrapGuardVariable(t *testing.T) {
 	var buf bytes.Buffer
-	writeShellSetup(&buf, "bash", "/tmp/int", "/s.yaml", "s1")
+	writeShellSetup(&buf, "bash", "/tmp/int", []string{"/s.yaml"}, "s1")
 	output := buf.String()
 
 	// Guard variable prevents double-fire
@@ -189,7 +190,7 @@ func TestEmitShellSetup_TrapGuardVariable(t *testing.T) {
 
 func TestEmitShellSetup_TrapUsesCommandPrefix(t *testing.T) {
 	var buf bytes.Buffer
-	writeShellSetup(&buf, "bash", "/tmp/int", "/s.yaml", "s1")
+	writeShellSetup(&buf, "bash", "/tmp/int", []string{"/s.yaml"}, "s1")
 	output := buf.String()
 
 	// 'command' prefix bypasses intercept shims
@@ -198,7 +199,7 @@ func TestEmitShellSetup_TrapUsesCommandPrefix(t *testing.T) {
 
 func TestEmitShellSetup_TrapRedirectsStderr(t *testing.T) {
 	var buf bytes.Buffer
-	writeShellSetup(&buf, "bash", "/tmp/int", "/s.yaml", "s1")
+	writeShellSetup(&buf, "bash", "/tmp/int", []string{"/s.yaml"}, "s1")
 	output := buf.String()
 
 	// stderr suppressed in trap
@@ -209,7 +210,7 @@ func TestEmitShellSetup_TrapRedirectsStderr(t *testing.T) {
 
 func TestEmitShellSetup_PowerShellNoTrap(t *testing.T) {
 	var buf bytes.Buffer
-	writeShellSetup(&buf, "powershell", "/tmp/intercept", "/scenario.yaml", "session-xyz")
+	writeShellSetup(&buf, "powershell", "/tmp/intercept", []string{"/scenario.yaml"}, "session-xyz")
 	output := buf.String()
 
 	// PowerShell should have env var exports
@@ -222,11 +223,48 @@ func TestEmitShellSetup_PowerShellNoTrap(t *testing.T) {
 	assert.NotContains(t, output, "_cli_replay_clean")
 }
 
+func TestEmitShellSetup_FishExitEvent(t *testing.T) {
+	var buf bytes.Buffer
+	writeShellSetup(&buf, "fish", "/tmp/intercept", []string{"/it's/scenario.yaml"}, "session-xyz")
+	output := buf.String()
+
+	assert.Contains(t, output, "set -gx CLI_REPLAY_SESSION 'session-xyz'")
+	assert.Contains(t, output, `set -gx CLI_REPLAY_SCENARIO '/it\'s/scenario.yaml'`)
+	assert.Contains(t, output, "set -gx PATH '/tmp/intercept' $PATH")
+	assert.Contains(t, output, "function _cli_replay_clean --on-event fish_exit")
+	assert.Contains(t, output, `command cli-replay clean '/it\'s/scenario.yaml' 2>/dev/null`)
+	assert.NotContains(t, output, "trap ")
+	assert.NotContains(t, output, "export ")
+}
+
+func TestEmitShellSetup_PwshEngineEvent(t *testing.T) {
+	var buf bytes.Buffer
+	writeShellSetup(&buf, "pwsh", "/tmp/intercept", []string{"/a.yaml", "/b.yaml"}, "session-xyz")
+	output := buf.String()
+
+	assert.Contains(t, output, "$env:CLI_REPLAY_SESSION = 'session-xyz'")
+	assert.Contains(t, output, "$env:CLI_REPLAY_SEQUENCE = ")
+	assert.Contains(t, output, "[IO.Path]::PathSeparator")
+	assert.Contains(t, output, "Register-EngineEvent -SourceIdentifier PowerShell.Exiting")
+	assert.Contains(t, output, "clean '/a.yaml' '/b.yaml' 2>$null")
+	assert.NotContains(t, output, "trap ")
+}
+
+func TestDetectShell_FishAndPwsh(t *testing.T) {
+	assert.Equal(t, "fish", detectShell("fish"))
+	assert.Equal(t, "pwsh", detectShell("pwsh"))
+	assert.Equal(t, "powershell", detectShell("powershell"))
+
+	t.Setenv("PSModulePath", "")
+	t.Setenv("SHELL", "/usr/local/bin/fish")
+	assert.Equal(t, "fish", detectShell(""))
+}
+
 // --- T026: Trap emission test for cmd.exe ---
 
 func TestEmitShellSetup_CmdNoTrap(t *testing.T) {
 	var buf bytes.Buffer
-	writeShellSetup(&buf, "cmd", "/tmp/intercept", "/scenario.yaml", "session-xyz")
+	writeShellSetup(&buf, "cmd", "/tmp/intercept", []string{"/scenario.yaml"}, "session-xyz")
 	output := buf.String()
 
 	// cmd should have set statements
@@ -320,3 +358,335 @@ func assertNoSideEffects(t *testing.T, dir string) {
 		assert.NotEqual(t, ".cli-replay", e.Name(), "dry-run should not create .cli-replay directory")
 	}
 }
+
+func TestCheckStartStep_RefusesSkippedCaptureUnlessForced(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioPath := writeScenarioFile(t, tmpDir, `
+meta:
+  name: "start-step"
+steps:
+  - match:
+      argv: ["az", "group", "create"]
+    respond:
+      exit: 0
+      capture:
+        rg_id: "rg-123"
+  - match:
+      argv: ["az", "group", "list"]
+    respond:
+      exit: 0
+  - match:
+      argv: ["az", "group", "show"]
+    respond:
+      exit: 0
+      stdout: '{{ .capture.rg_id }}'
+`)
+	scn, err := scenario.LoadFile(scenarioPath)
+	require.NoError(t, err)
+
+	err = checkStartStep(scn, 2, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `capture "rg_id"`)
+	assert.Contains(t, err.Error(), "--force")
+
+	assert.NoError(t, checkStartStep(scn, 2, true))
+
+	err = checkStartStep(scn, 5, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of range")
+}
+
+func TestRunDryRun_SimulateFileMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioPath := writeScenarioFile(t, tmpDir, `
+meta:
+  name: "simulate-test"
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+  - match:
+      argv: ["kubectl", "delete", "pod", "pod-1"]
+    respond:
+      exit: 0
+`)
+	cmdsPath := filepath.Join(tmpDir, "cmds.txt")
+	require.NoError(t, os.WriteFile(cmdsPath, []byte("kubectl get pods\nkubectl delete pod pod-2\n"), 0600))
+
+	rootCmd.SetArgs([]string{"run", "--dry-run", "--simulate-file", cmdsPath, scenarioPath})
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+
+	err := rootCmd.Execute()
+	runDryRunFlag = false
+	runSimulateFileFlag = ""
+
+	require.Error(t, err)
+	output := stdout.String()
+	assert.Contains(t, output, "✓ line 1: kubectl get pods → step 1")
+	assert.Contains(t, output, "✗ line 2: kubectl delete pod pod-2")
+	assert.Contains(t, output, "Mismatch at command 2")
+	assertNoSideEffects(t, tmpDir)
+}
+
+func TestRun_SimulateFileRequiresDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioPath := writeScenarioFile(t, tmpDir, `
+meta:
+  name: "simulate-test"
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+`)
+	rootCmd.SetArgs([]string{"run", "--simulate-file", "cmds.txt", scenarioPath})
+	err := rootCmd.Execute()
+	runSimulateFileFlag = ""
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires --dry-run")
+}
+
+func TestEmitShellSetup_SequenceExportsAndCleansAllFiles(t *testing.T) {
+	var buf bytes.Buffer
+	writeShellSetup(&buf, "bash", "/tmp/int", []string{"/a.yaml", "/b'c.yaml"}, "s1")
+	output := buf.String()
+
+	assert.Contains(t, output, "export CLI_REPLAY_SCENARIO='/a.yaml'")
+	assert.Contains(t, output, "export CLI_REPLAY_SEQUENCE='"+runner.FormatSequence([]string{"/a.yaml", `/b'\''c.yaml`})+"'")
+	assert.Contains(t, output, `command cli-replay clean '/a.yaml' '/b'\''c.yaml' 2>/dev/null`)
+}
+
+func TestEmitShellSetup_SingleFileNoSequence(t *testing.T) {
+	var buf bytes.Buffer
+	writeShellSetup(&buf, "powershell", "/tmp/int", []string{"/a.yaml"}, "s1")
+	assert.NotContains(t, buf.String(), "CLI_REPLAY_SEQUENCE")
+}
+
+func TestRun_SequenceRejectsStartStep(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := writeScenarioFile(t, tmpDir, `
+meta:
+  name: "a"
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+  - match:
+      argv: ["kubectl", "get", "svc"]
+    respond:
+      exit: 0
+`)
+	rootCmd.SetArgs([]string{"run", "--start-step", "1", a, a})
+	err := rootCmd.Execute()
+	runStartStepFlag = 0
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--start-step requires a single scenario file")
+}
+
+func TestRunDryRun_Sequence(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.yaml")
+	b := filepath.Join(tmpDir, "b.yaml")
+	require.NoError(t, os.WriteFile(a, []byte("meta:\n  name: phase-a\nsteps:\n  - match:\n      argv: [\"git\", \"pull\"]\n"), 0600))
+	require.NoError(t, os.WriteFile(b, []byte("meta:\n  name: phase-b\nsteps:\n  - match:\n      argv: [\"make\", \"test\"]\n"), 0600))
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"run", "--dry-run", a, b})
+	err := rootCmd.Execute()
+	runDryRunFlag = false
+	rootCmd.SetOut(nil)
+
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "phase-a")
+	assert.Contains(t, out.String(), "phase-b")
+}
+
+func TestSessionIDFor_AutoSession(t *testing.T) {
+	auto := &scenario.Scenario{Meta: scenario.Meta{Name: "a", Session: &scenario.Session{Auto: "pid"}}}
+	plain := &scenario.Scenario{Meta: scenario.Meta{Name: "b"}}
+
+	assert.Equal(t, runner.AutoSessionID(), sessionIDFor(auto))
+	assert.Equal(t, runner.AutoSessionID(), sessionIDFor(plain, auto), "any file in a sequence opts in")
+	assert.NotEqual(t, sessionIDFor(plain), sessionIDFor(plain), "random by default")
+
+	runAutoSessionFlag = true
+	defer func() { runAutoSessionFlag = false }()
+	assert.Equal(t, runner.AutoSessionID(), sessionIDFor(plain))
+}
+
+func TestRun_MaxStdinStoredInState(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioPath := writeScenarioFile(t, tmpDir, `
+meta:
+  name: "max-stdin"
+steps:
+  - match:
+      argv: ["kubectl", "apply", "-f", "-"]
+    respond:
+      exit: 0
+`)
+	rootCmd.SetArgs([]string{"run", "--auto-session", "--max-stdin", "4194304", scenarioPath})
+	err := rootCmd.Execute()
+	runAutoSessionFlag = false
+	runMaxStdinFlag = 0
+	require.NoError(t, err)
+
+	absPath, err := filepath.Abs(scenarioPath)
+	require.NoError(t, err)
+	state, err := runner.ReadState(runner.StateFilePathWithSession(absPath, runner.AutoSessionID()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4194304), state.MaxStdinBytes)
+}
+
+func TestRun_MaxStdinRejectsUnboundedValues(t *testing.T) {
+	for _, v := range []string{"-1", "268435457"} {
+		rootCmd.SetArgs([]string{"run", "--max-stdin", v, "scenario.yaml"})
+		err := rootCmd.Execute()
+		runMaxStdinFlag = 0
+
+		require.Error(t, err, v)
+		assert.Contains(t, err.Error(), "--max-stdin must", v)
+	}
+}
+
+func TestRun_MaxDelayCapsDelayPlusJitter(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioPath := writeScenarioFile(t, tmpDir, `
+meta:
+  name: "jitter-cap"
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+      delay: 1s
+      jitter: 1s
+`)
+	rootCmd.SetArgs([]string{"run", "--dry-run", "--max-delay", "1500ms", scenarioPath})
+	err := rootCmd.Execute()
+	runDryRunFlag = false
+	runMaxDelayFlag = "5m"
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step 1: delay 1s + jitter 1s exceeds max-delay 1.5s")
+
+	rootCmd.SetArgs([]string{"run", "--dry-run", "--max-delay", "2s", scenarioPath})
+	err = rootCmd.Execute()
+	runDryRunFlag = false
+	runMaxDelayFlag = "5m"
+	require.NoError(t, err)
+}
+
+func TestRun_MaxDelayRejectsInvalidDuration(t *testing.T) {
+	rootCmd.SetArgs([]string{"run", "--max-delay", "soon", "scenario.yaml"})
+	err := rootCmd.Execute()
+	runMaxDelayFlag = "5m"
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid --max-delay "soon"`)
+}
+
+func TestExtractCommands_IncludesStripPrefixes(t *testing.T) {
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{Name: "wrapped", StripPrefixes: []string{"sudo", "kubectl"}},
+		Steps: []scenario.StepElement{
+			{Step: &scenario.Step{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}}},
+		},
+	}
+	assert.Equal(t, []string{"kubectl", "sudo"}, extractCommands(scn))
+}
+
+func TestExtractCommands_IncludesForbiddenCommands(t *testing.T) {
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{Name: "egress", Security: &scenario.Security{ForbiddenCommands: []string{"curl", "wget"}}},
+		Steps: []scenario.StepElement{
+			{Step: &scenario.Step{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}}},
+		},
+	}
+	assert.Equal(t, []string{"kubectl", "curl", "wget"}, extractCommands(scn))
+}
+
+func TestExtractCommands_IncludesAnyOf(t *testing.T) {
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{Name: "alternatives"},
+		Steps: []scenario.StepElement{
+			{Step: &scenario.Step{Match: scenario.Match{AnyOf: [][]string{{"kubectl", "get"}, {"oc", "get"}}}}},
+		},
+	}
+	assert.Equal(t, []string{"kubectl", "oc"}, extractCommands(scn))
+}
+
+const multiDocRunScenario = `
+meta:
+  name: deploy
+steps:
+  - match:
+      argv: ["kubectl", "apply"]
+---
+meta:
+  name: teardown
+steps:
+  - match:
+      argv: ["kubectl", "delete"]
+  - match:
+      argv: ["kubectl", "get", "pods"]
+`
+
+func TestRunDryRun_SelectsDocument(t *testing.T) {
+	scenarioPath := writeScenarioFile(t, t.TempDir(), multiDocRunScenario)
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetArgs([]string{"run", "--dry-run", "--name", "teardown", scenarioPath})
+	err := rootCmd.Execute()
+	runDryRunFlag = false
+	runNameFlag = ""
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Scenario: teardown")
+
+	stdout.Reset()
+	rootCmd.SetArgs([]string{"run", "--dry-run", "--index", "1", scenarioPath})
+	err = rootCmd.Execute()
+	runDryRunFlag = false
+	runIndexFlag = 0
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Scenario: teardown")
+}
+
+func TestRun_RejectsUnknownDocument(t *testing.T) {
+	scenarioPath := writeScenarioFile(t, t.TempDir(), multiDocRunScenario)
+
+	rootCmd.SetArgs([]string{"run", "--dry-run", "--name", "rollback", scenarioPath})
+	err := rootCmd.Execute()
+	runDryRunFlag = false
+	runNameFlag = ""
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `no scenario document named "rollback"`)
+
+	rootCmd.SetArgs([]string{"run", "--dry-run", "--index", "2", scenarioPath})
+	err = rootCmd.Execute()
+	runDryRunFlag = false
+	runIndexFlag = 0
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "index 2 out of range")
+}
+
+func TestRun_SelectedDocumentSizesState(t *testing.T) {
+	scenarioPath := writeScenarioFile(t, t.TempDir(), multiDocRunScenario)
+
+	rootCmd.SetArgs([]string{"run", "--auto-session", "--name", "teardown", scenarioPath})
+	err := rootCmd.Execute()
+	runAutoSessionFlag = false
+	runNameFlag = ""
+	require.NoError(t, err)
+
+	absPath, err := filepath.Abs(scenarioPath)
+	require.NoError(t, err)
+	state, err := runner.ReadState(runner.StateFilePathWithSession(absPath, runner.AutoSessionID()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, state.TotalSteps)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/ormasoftchile/cli-replay/schema"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for scenario files",
+	Long: `Print the JSON Schema (draft-07) describing the scenario YAML format:
+meta, steps, groups, match, respond, calls, security, and session.
+
+The schema encodes enums (group mode), ranges (exit 0-255), and mutual
+exclusions (stdout/stdout_file, stderr/stderr_file) for editor completion
+and validation. Semantic rules checked by 'cli-replay validate' (capture
+references, vars conflicts) are not expressible in JSON Schema.
+
+Examples:
+  cli-replay schema > scenario.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: runSchema,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	rootCmd.AddCommand(schemaCmd)
+}
+
+func runSchema(cmd *cobra.Command, _ []string) error {
+	_, err := cmd.OutOrStdout().Write(schema.Scenario)
+	return err
+}
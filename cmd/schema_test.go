@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_EmitsValidJSON(t *testing.T) {
+	root := &cobra.Command{Use: "cli-replay", SilenceUsage: true, SilenceErrors: true}
+	root.AddCommand(&cobra.Command{Use: "schema", Args: cobra.NoArgs, RunE: runSchema})
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"schema"})
+	require.NoError(t, root.Execute())
+
+	var doc struct {
+		Schema      string `json:"$schema"`
+		Definitions struct {
+			Respond struct {
+				Properties struct {
+					Exit struct {
+						Minimum int `json:"minimum"`
+						Maximum int `json:"maximum"`
+					} `json:"exit"`
+				} `json:"properties"`
+				AllOf []json.RawMessage `json:"allOf"`
+			} `json:"respond"`
+			StepGroup struct {
+				Properties struct {
+					Mode struct {
+						Enum []string `json:"enum"`
+					} `json:"mode"`
+				} `json:"properties"`
+			} `json:"step_group"`
+		} `json:"definitions"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Contains(t, doc.Schema, "draft-07")
+	assert.Equal(t, 0, doc.Definitions.Respond.Properties.Exit.Minimum)
+	assert.Equal(t, 255, doc.Definitions.Respond.Properties.Exit.Maximum)
+	assert.Contains(t, doc.Definitions.StepGroup.Properties.Mode.Enum, "unordered")
+	assert.NotEmpty(t, doc.Definitions.Respond.AllOf, "stdout/stdout_file exclusivity")
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// sessionSeed returns the seed a run or exec session exports as
+// CLI_REPLAY_SEED: --seed when given, else a valid CLI_REPLAY_SEED already
+// in the environment, else one taken from the clock. A clock seed is
+// printed to w when a scenario draws random values (respond.random or
+// respond.jitter), so a failing run can be repeated with --seed.
+func sessionSeed(w io.Writer, flag string, scenarios ...*scenario.Scenario) (int64, error) {
+	if flag = strings.TrimSpace(flag); flag != "" {
+		seed, err := strconv.ParseInt(flag, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --seed %q: must be an integer", flag)
+		}
+		return seed, nil
+	}
+	if seed, ok, err := runner.SeedFromEnv(); err == nil && ok {
+		return seed, nil
+	}
+	seed := time.Now().UnixNano()
+	for _, scn := range scenarios {
+		if drawsRandom(scn) {
+			fmt.Fprintf(w, "cli-replay: using seed %d (pass --seed %d to reproduce)\n", seed, seed)
+			break
+		}
+	}
+	return seed, nil
+}
+
+// drawsRandom reports whether any response in scn depends on the seed.
+func drawsRandom(scn *scenario.Scenario) bool {
+	if fb := scn.Meta.Fallback; fb != nil && fb.Jitter != "" {
+		return true
+	}
+	for _, step := range scn.FlatSteps() {
+		if len(step.Respond.Random) > 0 || step.Respond.Jitter != "" {
+			return true
+		}
+	}
+	return false
+}
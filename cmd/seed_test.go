@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+func loadSeedScenario(t *testing.T, respond string) *scenario.Scenario {
+	t.Helper()
+	scn, err := scenario.Load(strings.NewReader(`
+meta:
+  name: seeded
+steps:
+  - match:
+      argv: [flaky]
+    respond:
+` + respond))
+	require.NoError(t, err)
+	return scn
+}
+
+func TestSessionSeed(t *testing.T) {
+	random := loadSeedScenario(t, `      random:
+        - weight: 1
+          response: {exit: 0}
+        - weight: 1
+          response: {exit: 1}
+`)
+	plain := loadSeedScenario(t, "      exit: 0\n")
+
+	t.Run("flag wins over environment", func(t *testing.T) {
+		t.Setenv("CLI_REPLAY_SEED", "7")
+		var log bytes.Buffer
+		seed, err := sessionSeed(&log, "42", random)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), seed)
+		assert.Empty(t, log.String())
+	})
+
+	t.Run("inherited environment", func(t *testing.T) {
+		t.Setenv("CLI_REPLAY_SEED", "7")
+		seed, err := sessionSeed(&bytes.Buffer{}, "", random)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), seed)
+	})
+
+	t.Run("generated seed is logged", func(t *testing.T) {
+		t.Setenv("CLI_REPLAY_SEED", "")
+		var log bytes.Buffer
+		seed, err := sessionSeed(&log, "", random)
+		require.NoError(t, err)
+		assert.Contains(t, log.String(), "--seed "+strconv.FormatInt(seed, 10))
+	})
+
+	t.Run("generated seed is quiet without randomness", func(t *testing.T) {
+		t.Setenv("CLI_REPLAY_SEED", "")
+		var log bytes.Buffer
+		_, err := sessionSeed(&log, "", plain)
+		require.NoError(t, err)
+		assert.Empty(t, log.String())
+	})
+
+	t.Run("invalid flag", func(t *testing.T) {
+		_, err := sessionSeed(&bytes.Buffer{}, "1.5", plain)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid --seed "1.5"`)
+	})
+}
+
+func TestWriteEnvExport(t *testing.T) {
+	tests := map[string]string{
+		"bash":       "export CLI_REPLAY_SEED='42'\n",
+		"powershell": "$env:CLI_REPLAY_SEED = '42'\n",
+		"pwsh":       "$env:CLI_REPLAY_SEED = '42'\n",
+		"fish":       "set -gx CLI_REPLAY_SEED '42'\n",
+		"cmd":        "set \"CLI_REPLAY_SEED=42\"\n",
+	}
+	for shell, want := range tests {
+		var buf bytes.Buffer
+		writeEnvExport(&buf, shell, "CLI_REPLAY_SEED", "42")
+		assert.Equal(t, want, buf.String(), shell)
+	}
+}
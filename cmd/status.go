@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	statusWatchFlag    bool
+	statusIntervalFlag time.Duration
+)
+
+// statusBarWidth is the number of cells in the --watch progress bar.
+const statusBarWidth = 30
+
+var statusCmd = &cobra.Command{
+	Use:   "status [scenario.yaml]",
+	Short: "Show replay progress for a scenario",
+	Long: `Show how far an intercept session has progressed through a scenario.
+
+If no scenario file is given, uses the CLI_REPLAY_SCENARIO environment
+variable. The session is taken from CLI_REPLAY_SESSION.
+
+With --watch, re-reads the state file on a short interval and renders a
+live progress bar (consumed/total steps and the current step's argv) until
+the scenario completes or the command is interrupted. When stderr is not a
+terminal, a plain line is printed each time progress changes.
+
+Examples:
+  cli-replay status scenario.yaml
+  cli-replay status --watch scenario.yaml
+  cli-replay status --watch --interval 1s scenario.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStatus,
+}
+
+func init() { //nolint:gochecknoinits // Standard cobra pattern
+	statusCmd.Flags().BoolVar(&statusWatchFlag, "watch", false,
+		"Continuously refresh progress until the scenario completes")
+	statusCmd.Flags().DurationVar(&statusIntervalFlag, "interval", 250*time.Millisecond,
+		"Refresh interval for --watch")
+	statusCmd.ValidArgsFunction = completeScenarioFiles(1)
+	rootCmd.AddCommand(statusCmd)
+}
+
+func runStatus(_ *cobra.Command, args []string) error {
+	var scenarioPath string
+	if len(args) > 0 {
+		scenarioPath = args[0]
+	} else {
+		scenarioPath = os.Getenv("CLI_REPLAY_SCENARIO")
+		if scenarioPath == "" {
+			return fmt.Errorf("no scenario specified — pass a file or set CLI_REPLAY_SCENARIO")
+		}
+	}
+
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve scenario path: %w", err)
+	}
+
+	scn, err := runner.LoadScenario(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to load scenario: %w", err)
+	}
+
+	stateFile := runner.StateFilePath(absPath)
+
+	if !statusWatchFlag {
+		state, err := runner.ReadState(stateFile)
+		if err != nil {
+			if os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "cli-replay: no state found for %q\n", scn.Meta.Name)
+				return nil
+			}
+			return fmt.Errorf("failed to read state: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, formatStatusLine(scn, state))
+		return nil
+	}
+
+	if statusIntervalFlag <= 0 {
+		return fmt.Errorf("--interval must be positive, got %s", statusIntervalFlag)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	tty := term.IsTerminal(int(os.Stderr.Fd()))
+	_, err = watchStatus(ctx, os.Stderr, tty, scn, stateFile, statusIntervalFlag)
+	return err
+}
+
+// watchStatus polls stateFile every interval and renders progress to w until
+// the scenario completes or ctx is cancelled. On a TTY the progress bar is
+// redrawn in place; otherwise a line is written whenever progress changes.
+// It returns the last state read (nil if no state was ever found).
+func watchStatus(ctx context.Context, w io.Writer, tty bool, scn *scenario.Scenario, stateFile string, interval time.Duration) (*runner.State, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *runner.State
+	lastLine := ""
+	for {
+		state, err := runner.ReadState(stateFile)
+		switch {
+		case err == nil:
+			last = state
+		case !os.IsNotExist(err):
+			// A partially-written file is retried on the next tick.
+			state = last
+		}
+
+		line := formatStatusLine(scn, state)
+		if tty {
+			line = formatStatusBar(scn, state)
+		}
+		if line != lastLine {
+			if tty {
+				_, _ = fmt.Fprintf(w, "\r\033[K%s", line)
+			} else {
+				_, _ = fmt.Fprintln(w, line)
+			}
+			lastLine = line
+		}
+
+		if state != nil && state.IsComplete() {
+			if tty {
+				_, _ = fmt.Fprintln(w)
+			}
+			return last, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if tty {
+				_, _ = fmt.Fprintln(w)
+			}
+			return last, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// statusProgress returns the consumed step count, total step count, and the
+// current step's argv (nil when complete or no state is available).
+func statusProgress(scn *scenario.Scenario, state *runner.State) (consumed, total int, current []string) {
+	steps := scn.FlatSteps()
+	total = len(steps)
+	if state == nil {
+		if total > 0 {
+			current = steps[0].Match.PrimaryArgv()
+		}
+		return 0, total, current
+	}
+	consumed = countConsumedSteps(state)
+	if state.CurrentStep >= 0 && state.CurrentStep < total {
+		current = steps[state.CurrentStep].Match.PrimaryArgv()
+	}
+	return consumed, total, current
+}
+
+// formatStatusLine renders a single plain-text progress line.
+func formatStatusLine(scn *scenario.Scenario, state *runner.State) string {
+	consumed, total, current := statusProgress(scn, state)
+	if state == nil {
+		return fmt.Sprintf("%s: waiting for first call (0/%d steps consumed)", scn.Meta.Name, total)
+	}
+	if current == nil {
+		return fmt.Sprintf("%s: complete (%d/%d steps consumed)", scn.Meta.Name, consumed, total)
+	}
+	return fmt.Sprintf("%s: %d/%d steps consumed, current step %d: %s",
+		scn.Meta.Name, consumed, total, state.CurrentStep+1, strings.Join(current, " "))
+}
+
+// formatStatusBar renders a progress bar line for in-place TTY display.
+func formatStatusBar(scn *scenario.Scenario, state *runner.State) string {
+	consumed, total, current := statusProgress(scn, state)
+	filled := 0
+	if total > 0 {
+		filled = consumed * statusBarWidth / total
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", statusBarWidth-filled)
+	line := fmt.Sprintf("%s [%s] %d/%d", scn.Meta.Name, bar, consumed, total)
+	if current != nil {
+		line += "  " + formatArgvShort(current)
+	}
+	return line
+}
+
+// formatArgvShort joins argv for display, truncating long command lines.
+func formatArgvShort(argv []string) string {
+	const maxLen = 60
+	s := strings.Join(argv, " ")
+	if len([]rune(s)) > maxLen {
+		s = string([]rune(s)[:maxLen-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeStatusScenario(t *testing.T) (string, *scenario.Scenario) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`meta:
+  name: status-test
+steps:
+  - match:
+      argv: [kubectl, apply]
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, rollout, status]
+    respond:
+      exit: 0
+`), 0644))
+	scn, err := scenario.LoadFile(path)
+	require.NoError(t, err)
+	return path, scn
+}
+
+func TestWatchStatus_ReportsFinalCount(t *testing.T) {
+	path, scn := writeStatusScenario(t)
+	stateFile := runner.StateFilePath(path)
+
+	// Drive state forward one step at a time while the watcher polls.
+	go func() {
+		state := runner.NewState(path, "", 2)
+		for i := 0; i < 2; i++ {
+			time.Sleep(30 * time.Millisecond)
+			state.StepCounts[i] = 1
+			state.CurrentStep = i + 1
+			_ = runner.WriteState(stateFile, state)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var buf bytes.Buffer
+	final, err := watchStatus(ctx, &buf, false, scn, stateFile, 5*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, ctx.Err(), "watcher should exit on completion, not timeout")
+	require.NotNil(t, final)
+	assert.Equal(t, []int{1, 1}, final.StepCounts)
+
+	out := buf.String()
+	assert.Contains(t, out, "waiting for first call (0/2 steps consumed)")
+	assert.Contains(t, out, "1/2 steps consumed, current step 2: kubectl rollout status")
+	assert.Contains(t, out, "complete (2/2 steps consumed)")
+}
+
+func TestWatchStatus_StopsOnCancel(t *testing.T) {
+	path, scn := writeStatusScenario(t)
+	stateFile := runner.StateFilePath(path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	final, err := watchStatus(ctx, &buf, true, scn, stateFile, time.Millisecond)
+	require.NoError(t, err)
+	assert.Nil(t, final)
+	assert.Contains(t, buf.String(), "\r\033[K")
+	assert.Contains(t, buf.String(), "0/2")
+}
+
+func TestFormatStatusBar(t *testing.T) {
+	_, scn := writeStatusScenario(t)
+	state := runner.NewState("", "", 2)
+	state.StepCounts[0] = 1
+	state.CurrentStep = 1
+
+	bar := formatStatusBar(scn, state)
+	assert.Contains(t, bar, "1/2")
+	assert.Contains(t, bar, "kubectl rollout status")
+}
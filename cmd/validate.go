@@ -1,26 +1,40 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 // ValidationResult represents the validation outcome for a single scenario file.
 type ValidationResult struct {
-	File   string   `json:"file"`
-	Valid  bool     `json:"valid"`
-	Errors []string `json:"errors"`
+	File     string   `json:"file"`
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
-var validateFormatFlag string
+var (
+	validateFormatFlag   string
+	validateStrictFlag   bool
+	validateWatchFlag    bool
+	validateDebounceFlag time.Duration
+	validateIndexFlag    int
+	validateNameFlag     string
+)
 
 var validateCmd = &cobra.Command{
 	Use:   "validate <file>...",
@@ -31,8 +45,19 @@ Checks schema compliance (required fields, types, ranges) and semantic rules
 (no forward capture references, no min > max, no duplicate capture keys,
 allowlist consistency, group structure).
 
+Also reports warnings for likely mistakes that are still loadable, such as
+adjacent identical steps that should be a single step with calls bounds.
+With --strict, warnings are reported as errors.
+
 Does not create any files, directories, or modify any environment state.
 
+Every document of a multi-document file (scenarios separated by ---) is
+validated; --index N or --name NAME restricts the file checks and warnings
+to one of them.
+
+With --watch, the files are re-validated every time one of them changes,
+until interrupted. Rapid successive saves are debounced into a single run.
+
 Exit code 0 if all files are valid, 1 if any file has errors.
 
 Formats:
@@ -42,7 +67,9 @@ Formats:
 Examples:
   cli-replay validate scenario.yaml
   cli-replay validate a.yaml b.yaml c.yaml
-  cli-replay validate --format json scenario.yaml`,
+  cli-replay validate --format json scenario.yaml
+  cli-replay validate --strict scenario.yaml
+  cli-replay validate --watch scenario.yaml`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runValidate,
 }
@@ -50,6 +77,18 @@ Examples:
 func init() { //nolint:gochecknoinits // Standard cobra pattern
 	validateCmd.Flags().StringVar(&validateFormatFlag, "format", "text",
 		"Output format: text, json")
+	validateCmd.Flags().BoolVar(&validateStrictFlag, "strict", false,
+		"Treat warnings as errors")
+	validateCmd.Flags().BoolVar(&validateWatchFlag, "watch", false,
+		"Re-validate whenever a file changes, until interrupted")
+	validateCmd.Flags().DurationVar(&validateDebounceFlag, "debounce", 200*time.Millisecond,
+		"Quiet period after a change before re-validating with --watch")
+	validateCmd.Flags().IntVar(&validateIndexFlag, "index", 0,
+		"In multi-document files, check only the scenario at this 0-based index")
+	validateCmd.Flags().StringVar(&validateNameFlag, "name", "",
+		"In multi-document files, check only the scenario with this meta.name")
+	validateCmd.ValidArgsFunction = completeScenarioFiles(0)
+	_ = validateCmd.RegisterFlagCompletionFunc("format", completeValues("text", "json"))
 	rootCmd.AddCommand(validateCmd)
 }
 
@@ -65,18 +104,53 @@ func runValidate(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid format %q: valid values are text, json", validateFormatFlag)
 	}
 
-	var results []ValidationResult
-	hasErrors := false
+	if validateWatchFlag {
+		if validateDebounceFlag <= 0 {
+			return fmt.Errorf("--debounce must be positive, got %s", validateDebounceFlag)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		tty := format == "text" && term.IsTerminal(int(os.Stderr.Fd()))
+		return watchValidate(ctx, args, validateDebounceFlag, func() {
+			if tty {
+				fmt.Fprint(os.Stderr, "\033[H\033[2J")
+			}
+			if format == "text" {
+				fmt.Fprintf(os.Stderr, "[%s] validating %d file(s)\n", time.Now().Format("15:04:05"), len(args))
+			}
+			_ = outputValidateResults(validateAll(args), format)
+		})
+	}
+
+	results := validateAll(args)
+	if err := outputValidateResults(results, format); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if !r.Valid {
+			os.Exit(1)
+		}
+	}
 
-	for _, path := range args {
+	return nil
+}
+
+// validateAll validates each path independently, applying --strict.
+func validateAll(paths []string) []ValidationResult {
+	results := make([]ValidationResult, 0, len(paths))
+	for _, path := range paths {
 		result := validateFile(path)
-		results = append(results, result)
-		if !result.Valid {
-			hasErrors = true
+		if validateStrictFlag {
+			result = promoteWarnings(result)
 		}
+		results = append(results, result)
 	}
+	return results
+}
 
-	// Output based on format
+// outputValidateResults writes results in the given format.
+func outputValidateResults(results []ValidationResult, format string) error {
 	switch format {
 	case "text":
 		formatValidateText(results)
@@ -85,20 +159,17 @@ func runValidate(_ *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to encode JSON output: %w", err)
 		}
 	}
-
-	if hasErrors {
-		os.Exit(1)
-	}
-
 	return nil
 }
 
 // validateFile validates a single scenario file and returns a ValidationResult.
-// It calls scenario.LoadFile() which performs strict YAML parsing and all
-// semantic validations. Additionally, it checks that stdout_file and
-// stderr_file references exist relative to the scenario directory.
+// It calls scenario.LoadFileAll() which performs strict YAML parsing and all
+// semantic validations on every document. Additionally, it checks that
+// stdout_file and stderr_file references exist relative to the scenario
+// directory. Findings are labelled with their document when the file holds
+// several and no --index or --name is given.
 func validateFile(path string) ValidationResult {
-	absPath, err := filepath.Abs(path)
+	absPath, err := runner.ResolveScenarioPath(path)
 	if err != nil {
 		return ValidationResult{
 			File:   path,
@@ -107,7 +178,12 @@ func validateFile(path string) ValidationResult {
 		}
 	}
 
-	scn, err := scenario.LoadFile(absPath)
+	docs, err := scenario.LoadFileAll(absPath)
+	if err == nil && (validateIndexFlag != 0 || validateNameFlag != "") {
+		var scn *scenario.Scenario
+		scn, _, err = selectDocument(docs, validateIndexFlag, validateNameFlag)
+		docs = []*scenario.Scenario{scn}
+	}
 	if err != nil {
 		return ValidationResult{
 			File:   path,
@@ -116,39 +192,128 @@ func validateFile(path string) ValidationResult {
 		}
 	}
 
-	// Additional validation: check stdout_file/stderr_file existence
-	var errs []string
-	scenarioDir := filepath.Dir(absPath)
-	for i, step := range scn.FlatSteps() {
-		if step.Respond.StdoutFile != "" {
-			refPath := filepath.Join(scenarioDir, step.Respond.StdoutFile)
-			if _, statErr := os.Stat(refPath); errors.Is(statErr, fs.ErrNotExist) {
-				errs = append(errs, fmt.Sprintf("step %d: stdout_file %q not found relative to scenario directory",
-					i+1, step.Respond.StdoutFile))
-			}
+	var errs, warnings []string
+	for i, scn := range docs {
+		label := ""
+		if len(docs) > 1 {
+			label = fmt.Sprintf("document %d (%s): ", i, scn.Meta.Name)
 		}
-		if step.Respond.StderrFile != "" {
-			refPath := filepath.Join(scenarioDir, step.Respond.StderrFile)
-			if _, statErr := os.Stat(refPath); errors.Is(statErr, fs.ErrNotExist) {
-				errs = append(errs, fmt.Sprintf("step %d: stderr_file %q not found relative to scenario directory",
-					i+1, step.Respond.StderrFile))
-			}
+		for _, e := range fixtureErrors(scn, absPath) {
+			errs = append(errs, label+e)
+		}
+		for _, w := range scenarioWarnings(scn) {
+			warnings = append(warnings, label+w)
 		}
 	}
 
 	if len(errs) > 0 {
 		return ValidationResult{
-			File:   path,
-			Valid:  false,
-			Errors: errs,
+			File:     path,
+			Valid:    false,
+			Errors:   errs,
+			Warnings: warnings,
 		}
 	}
 
 	return ValidationResult{
-		File:   path,
-		Valid:  true,
-		Errors: []string{},
+		File:     path,
+		Valid:    true,
+		Errors:   []string{},
+		Warnings: warnings,
+	}
+}
+
+// fixtureErrors reports stdout_file/stderr_file references of scn that do
+// not exist.
+func fixtureErrors(scn *scenario.Scenario, absPath string) []string {
+	var errs []string
+	scenarioDir := filepath.Dir(absPath)
+	fixturesRoot := scn.FixturesRoot(scenarioDir)
+	relativeTo := "scenario directory"
+	if scn.Meta.FixturesDir != "" {
+		relativeTo = "fixtures_dir"
+	}
+	check := func(step int, field, ref string) {
+		if ref == "" {
+			return
+		}
+		// Paths with $VAR references or glob patterns are resolved as replay does
+		if strings.ContainsAny(ref, "$*?[") {
+			refPath, err := runner.FixturePath(scn, scenarioDir, ref)
+			if err == nil {
+				_, err = os.Stat(refPath)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("step %d: %s: %v", step, field, err))
+			}
+			return
+		}
+		refPath := filepath.Join(fixturesRoot, ref)
+		if _, statErr := os.Stat(refPath); errors.Is(statErr, fs.ErrNotExist) {
+			errs = append(errs, fmt.Sprintf("step %d: %s %q not found relative to %s",
+				step, field, ref, relativeTo))
+		}
+	}
+	for i, step := range scn.FlatSteps() {
+		responses := []scenario.Response{step.Respond}
+		for _, w := range step.Respond.Random {
+			responses = append(responses, w.Response)
+		}
+		for _, r := range responses {
+			check(i+1, "stdout_file", r.StdoutFile)
+			check(i+1, "stderr_file", r.StderrFile)
+		}
+	}
+
+	return errs
+}
+
+// promoteWarnings turns a result's warnings into errors (--strict).
+func promoteWarnings(result ValidationResult) ValidationResult {
+	if len(result.Warnings) == 0 {
+		return result
 	}
+	result.Errors = append(result.Errors, result.Warnings...)
+	result.Warnings = nil
+	result.Valid = false
+	return result
+}
+
+// scenarioWarnings returns non-fatal findings for a loaded scenario.
+func scenarioWarnings(scn *scenario.Scenario) []string {
+	var warnings []string
+	for _, run := range scn.DuplicateAdjacentSteps() {
+		warnings = append(warnings, fmt.Sprintf(
+			"steps %d-%d are identical adjacent steps; consolidate into one step with calls: {min: %d, max: %d}",
+			run.Start+1, run.End, run.Len(), run.Len()))
+	}
+	flatSteps := scn.FlatSteps()
+	for _, sh := range scn.ShadowedSteps() {
+		bounds := flatSteps[sh.ShadowedBy].EffectiveCalls()
+		warnings = append(warnings, fmt.Sprintf(
+			"step %d is shadowed by step %d, which accepts every command it does and keeps matching until its calls max (%d) is used up; give step %d an exact count (min = max) or make the matches distinct",
+			sh.Index+1, sh.ShadowedBy+1, bounds.Max, sh.ShadowedBy+1))
+	}
+	for _, ref := range scn.UndefinedCaptureRefs() {
+		warnings = append(warnings, fmt.Sprintf(
+			"step %d: capture %q is referenced but never defined by any step; it renders empty (typo?)",
+			ref.ReferencedAt+1, ref.Capture))
+	}
+	for i, step := range flatSteps {
+		if step.Match.Wildcards {
+			continue
+		}
+		for _, argv := range step.Match.Alternatives() {
+			for j, elem := range argv {
+				if elem == matcher.WildcardToken {
+					warnings = append(warnings, fmt.Sprintf(
+						"step %d: argv[%d] is %q, which is matched literally; set match.wildcards: true to accept any value there",
+						i+1, j, elem))
+				}
+			}
+		}
+	}
+	return warnings
 }
 
 // formatValidateText writes human-readable validation results to stderr.
@@ -158,6 +323,9 @@ func formatValidateText(results []ValidationResult) {
 		if r.Valid {
 			validCount++
 			fmt.Fprintf(os.Stderr, "✓ %s: valid\n", r.File)
+			for _, w := range r.Warnings {
+				fmt.Fprintf(os.Stderr, "  ! warning: %s\n", w)
+			}
 		} else {
 			fmt.Fprintf(os.Stderr, "✗ %s:\n", r.File)
 			for _, e := range r.Errors {
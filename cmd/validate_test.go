@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -16,6 +17,11 @@ import (
 func makeValidateRoot() *cobra.Command {
 	// Reset global flag state
 	validateFormatFlag = "text"
+	validateStrictFlag = false
+	validateWatchFlag = false
+	validateDebounceFlag = 200 * time.Millisecond
+	validateIndexFlag = 0
+	validateNameFlag = ""
 
 	root := &cobra.Command{
 		Use:           "cli-replay",
@@ -29,6 +35,11 @@ func makeValidateRoot() *cobra.Command {
 		RunE: runValidate,
 	}
 	v.Flags().StringVar(&validateFormatFlag, "format", "text", "Output format: text, json")
+	v.Flags().BoolVar(&validateStrictFlag, "strict", false, "Treat warnings as errors")
+	v.Flags().BoolVar(&validateWatchFlag, "watch", false, "Re-validate on change")
+	v.Flags().DurationVar(&validateDebounceFlag, "debounce", 200*time.Millisecond, "Debounce interval")
+	v.Flags().IntVar(&validateIndexFlag, "index", 0, "Document index")
+	v.Flags().StringVar(&validateNameFlag, "name", "", "Document name")
 	root.AddCommand(v)
 	return root
 }
@@ -61,6 +72,19 @@ func TestValidate_InvalidFile_Errors(t *testing.T) {
 	assert.True(t, foundNameError, "should report empty meta.name error, got: %v", result.Errors)
 }
 
+func TestValidateFile_TildePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	src, err := os.ReadFile("../testdata/scenarios/validate-valid.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "scenarios"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "scenarios", "foo.yaml"), src, 0600))
+
+	result := validateFile("~/scenarios/foo.yaml")
+	assert.True(t, result.Valid, "errors: %v", result.Errors)
+}
+
 func TestValidate_BadYAML_ParseError(t *testing.T) {
 	result := validateFile("../testdata/scenarios/validate-bad-yaml.yaml")
 
@@ -236,6 +260,112 @@ steps:
 	assert.True(t, foundFileError, "should report missing stderr_file, got: %v", result.Errors)
 }
 
+func TestValidate_ShadowedStep_Warning(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `meta:
+  name: shadowed-step
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    calls: {min: 1, max: 5}
+    respond:
+      stdout: "pending"
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      stdout: "ready"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0644))
+
+	result := validateFile(scenarioPath)
+	assert.True(t, result.Valid, "shadowed steps are a warning, not an error")
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "step 2 is shadowed by step 1")
+	assert.Contains(t, result.Warnings[0], "calls max (5)")
+}
+
+func TestValidate_AdjacentDuplicateSteps_Warning(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `meta:
+  name: duplicate-steps
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+      stdout: "pod-1"
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+      stdout: "pod-1"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0644))
+
+	result := validateFile(scenarioPath)
+	assert.True(t, result.Valid, "duplicates are a warning, not an error")
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "steps 1-2 are identical adjacent steps")
+	assert.Contains(t, result.Warnings[0], "calls: {min: 2, max: 2}")
+
+	strict := promoteWarnings(result)
+	assert.False(t, strict.Valid, "--strict turns warnings into errors")
+	assert.Empty(t, strict.Warnings)
+	require.Len(t, strict.Errors, 1)
+	assert.Contains(t, strict.Errors[0], "identical adjacent steps")
+}
+
+func TestValidate_LiteralUnderscore_Warning(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `meta:
+  name: underscore
+steps:
+  - match:
+      argv: [cp, _, /dest]
+    respond:
+      exit: 0
+  - match:
+      argv: [mv, _, /dest]
+      wildcards: true
+    respond:
+      exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0644))
+
+	result := validateFile(scenarioPath)
+	assert.True(t, result.Valid)
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], `step 1: argv[1] is "_", which is matched literally`)
+	assert.Contains(t, result.Warnings[0], "match.wildcards: true")
+}
+
+func TestValidate_AdjacentDistinctResponses_Allowed(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `meta:
+  name: polling
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+      stdout: "Pending"
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+      stdout: "Running"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0644))
+
+	result := validateFile(scenarioPath)
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Warnings, "different responses are the per-call cycling pattern")
+}
+
 // contains checks if s contains substr (case-insensitive-friendly helper).
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsStr(s, substr))
@@ -249,3 +379,123 @@ func containsStr(s, substr string) bool {
 	}
 	return false
 }
+
+const multiDocValidateScenario = `
+meta:
+  name: deploy
+steps:
+  - match:
+      argv: ["kubectl", "apply"]
+    respond:
+      stdout_file: missing.txt
+---
+meta:
+  name: teardown
+steps:
+  - match:
+      argv: ["kubectl", "delete"]
+`
+
+func TestValidateFile_MultiDocumentLabelsFindings(t *testing.T) {
+	makeValidateRoot()
+	path := filepath.Join(t.TempDir(), "multi.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(multiDocValidateScenario), 0600))
+
+	result := validateFile(path)
+	assert.False(t, result.Valid)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "document 0 (deploy): step 1: stdout_file")
+}
+
+func TestValidateFile_MultiDocumentSelector(t *testing.T) {
+	makeValidateRoot()
+	path := filepath.Join(t.TempDir(), "multi.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(multiDocValidateScenario), 0600))
+
+	validateNameFlag = "teardown"
+	result := validateFile(path)
+	assert.True(t, result.Valid, "errors: %v", result.Errors)
+
+	validateNameFlag = ""
+	validateIndexFlag = 2
+	result = validateFile(path)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Errors[0], "index 2 out of range")
+	validateIndexFlag = 0
+}
+
+func TestValidateFile_StdoutFileEnvExpansion(t *testing.T) {
+	makeValidateRoot()
+	testdata := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testdata, "pods.txt"), []byte("pod-a\n"), 0600))
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: env-fixture
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      stdout_file: "${TESTDATA_DIR}/pods.txt"
+`), 0600))
+
+	t.Setenv("TESTDATA_DIR", testdata)
+	result := validateFile(path)
+	assert.True(t, result.Valid, "errors: %v", result.Errors)
+
+	t.Setenv("TESTDATA_DIR", "")
+	result = validateFile(path)
+	assert.False(t, result.Valid)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "step 1: stdout_file: ${TESTDATA_DIR}/pods.txt: fixture not found: $TESTDATA_DIR is unset or empty")
+}
+
+func TestValidateFile_StdoutFileGlob(t *testing.T) {
+	makeValidateRoot()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: glob-fixture
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      stdout_file: "pods-*.json"
+`), 0600))
+
+	result := validateFile(path)
+	assert.False(t, result.Valid)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "step 1: stdout_file: pods-*.json: no fixture matches the pattern")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pods-1.json"), []byte("{}"), 0600))
+	result = validateFile(path)
+	assert.True(t, result.Valid, "errors: %v", result.Errors)
+}
+
+func TestValidate_UndefinedCaptureRef_Warning(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `meta:
+  name: ghost
+steps:
+  - match:
+      argv: [az, group, create]
+    respond:
+      exit: 0
+      capture:
+        group_id: "rg-1"
+  - match:
+      argv: [az, group, show]
+    respond:
+      exit: 0
+      stdout: "{{ .capture.group_id }} {{ .capture.ghost }}"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0644))
+
+	result := validateFile(scenarioPath)
+	assert.True(t, result.Valid, "undefined captures are a warning, not an error")
+	require.Len(t, result.Warnings, 1, "the defined capture is not reported")
+	assert.Contains(t, result.Warnings[0], `step 2: capture "ghost" is referenced but never defined`)
+}
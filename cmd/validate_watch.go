@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ormasoftchile/cli-replay/internal/runner"
+)
+
+// watchValidate calls run once immediately and again after every change to
+// one of paths, until ctx is cancelled. Events arriving within debounce of
+// each other are coalesced into a single run.
+//
+// The parent directories are watched rather than the files themselves, so
+// editors that save by writing a temp file and renaming it over the
+// original keep triggering runs.
+func watchValidate(ctx context.Context, paths []string, debounce time.Duration, run func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer watcher.Close() //nolint:errcheck // best-effort close
+
+	targets := make(map[string]bool, len(paths))
+	dirs := make(map[string]bool)
+	for _, p := range paths {
+		abs, err := runner.ResolveScenarioPath(p)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path %s: %w", p, err)
+		}
+		targets[abs] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	run()
+
+	timer := time.NewTimer(debounce)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !targets[filepath.Clean(ev.Name)] || ev.Op == fsnotify.Chmod {
+				continue
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("file watcher error: %w", err)
+		case <-timer.C:
+			run()
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchScenario = `meta:
+  name: watch
+steps:
+  - match:
+      argv: [echo]
+    respond:
+      exit: 0
+`
+
+// startWatch runs watchValidate in the background and returns a channel
+// that receives once per validation run, plus the watcher's result channel.
+func startWatch(ctx context.Context, t *testing.T, path string, debounce time.Duration) (<-chan struct{}, <-chan error) {
+	t.Helper()
+	runs := make(chan struct{}, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- watchValidate(ctx, []string{path}, debounce, func() { runs <- struct{}{} })
+	}()
+	return runs, done
+}
+
+func waitRun(t *testing.T, runs <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a validation run")
+	}
+}
+
+func TestWatchValidate_RerunsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(watchScenario), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runs, done := startWatch(ctx, t, path, 20*time.Millisecond)
+
+	waitRun(t, runs) // initial run
+	require.NoError(t, os.WriteFile(path, []byte(watchScenario+"# edited\n"), 0644))
+	waitRun(t, runs)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "cancellation must exit cleanly")
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not exit after cancellation")
+	}
+}
+
+func TestWatchValidate_DebouncesRapidSaves(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(watchScenario), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runs, _ := startWatch(ctx, t, path, 300*time.Millisecond)
+	waitRun(t, runs)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(path, []byte(watchScenario), 0644))
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Changes to other files in the directory are ignored.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("x"), 0644))
+
+	waitRun(t, runs)
+	select {
+	case <-runs:
+		t.Fatal("rapid saves must coalesce into a single run")
+	case <-time.After(600 * time.Millisecond):
+	}
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,7 +12,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var verifyFormatFlag string
+var (
+	verifyFormatFlag          string
+	verifyIncludeCapturesFlag bool
+)
 
 var verifyCmd = &cobra.Command{
 	Use:   "verify [scenario.yaml]",
@@ -25,6 +27,10 @@ variable (which was set automatically by 'cli-replay run | Invoke-Expression').
 
 Exit code 0 if all steps are consumed, 1 if steps remain or state is missing.
 
+With --include-captures, the structured report also lists the session's
+captured values. Captures whose names match meta.security.deny_env_vars
+patterns are redacted.
+
 Formats:
   text   Human-readable output to stderr (default)
   json   Compact JSON to stdout (pipe to jq for formatting)
@@ -34,13 +40,18 @@ Examples:
   cli-replay verify                              # uses CLI_REPLAY_SCENARIO from env
   cli-replay verify scenario.yaml                # explicit path
   cli-replay verify scenario.yaml --format json  # JSON output to stdout
-  cli-replay verify scenario.yaml --format junit # JUnit XML to stdout`,
+  cli-replay verify scenario.yaml --format junit # JUnit XML to stdout
+  cli-replay verify scenario.yaml --format json --include-captures`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runVerify,
 }
 
 func init() { //nolint:gochecknoinits // Standard cobra pattern
 	verifyCmd.Flags().StringVar(&verifyFormatFlag, "format", "text", "Output format: text, json, or junit")
+	verifyCmd.Flags().BoolVar(&verifyIncludeCapturesFlag, "include-captures", false,
+		"Include captured values in the report (may contain sensitive data)")
+	verifyCmd.ValidArgsFunction = completeScenarioFiles(1)
+	_ = verifyCmd.RegisterFlagCompletionFunc("format", completeValues("text", "json", "junit"))
 	rootCmd.AddCommand(verifyCmd)
 }
 
@@ -64,13 +75,13 @@ func runVerify(_ *cobra.Command, args []string) error {
 		}
 	}
 
-	absPath, err := filepath.Abs(scenarioPath)
+	absPath, err := runner.ResolveScenarioPath(scenarioPath)
 	if err != nil {
 		return fmt.Errorf("failed to resolve scenario path: %w", err)
 	}
 
-	// Load scenario for metadata and step count
-	scn, err := scenario.LoadFile(absPath)
+	// Load scenario for metadata and step count (the document selected by run)
+	scn, err := runner.LoadScenario(absPath)
 	if err != nil {
 		return fmt.Errorf("failed to load scenario: %w", err)
 	}
@@ -102,7 +113,20 @@ func runVerify(_ *cobra.Command, args []string) error {
 	}
 
 	// Build structured result
-	result := verify.BuildResult(scn.Meta.Name, session, scn.FlatSteps(), state.StepCounts, scn.GroupRanges())
+	buildOpts := []verify.BuildOption{
+		verify.WithStepDurations(state.StepDurations),
+		verify.WithServedTimes(state.ServedAt, state.LastServedAt),
+		verify.WithAssertFailures(state.AssertFailures),
+		verify.WithForbiddenCalls(state.ForbiddenCalls),
+	}
+	if verifyIncludeCapturesFlag {
+		var redact []string
+		if scn.Meta.Security != nil {
+			redact = scn.Meta.Security.DenyEnvVars
+		}
+		buildOpts = append(buildOpts, verify.WithCaptures(state.Captures, redact))
+	}
+	result := verify.BuildResult(scn.Meta.Name, session, scn.FlatSteps(), state.StepCounts, scn.GroupRanges(), buildOpts...)
 
 	// Dispatch based on format
 	if format != "text" {
@@ -124,13 +148,29 @@ func runVerify(_ *cobra.Command, args []string) error {
 		if hasCallBounds {
 			printPerStepCounts(scn.FlatSteps(), state)
 		}
+		printGroupSummary(result)
 		return nil
 	}
 
-	// Incomplete — show per-step detail
-	fmt.Fprintf(os.Stderr, "✗ Scenario %q incomplete\n", scn.Meta.Name)
+	// Incomplete or over budget — show per-step detail
+	verdict := "incomplete"
+	for _, step := range result.Steps {
+		if step.Status == verify.StatusOver {
+			verdict = "exceeded call bounds"
+			break
+		}
+	}
+	if len(result.ForbiddenCalls) > 0 {
+		verdict = "invoked forbidden commands"
+	}
+	fmt.Fprintf(os.Stderr, "✗ Scenario %q %s\n", scn.Meta.Name, verdict)
 	fmt.Fprintf(os.Stderr, "  consumed: %d/%d steps\n", result.ConsumedSteps, result.TotalSteps)
 	printPerStepCounts(scn.FlatSteps(), state)
+	printGroupSummary(result)
+	printTimedOutSteps(result)
+	printLateSteps(result)
+	printAssertFailures(result)
+	printForbiddenCalls(result)
 	os.Exit(1)
 
 	return nil // unreachable but satisfies compiler
@@ -171,6 +211,69 @@ func countConsumedSteps(state *runner.State) int {
 	return count
 }
 
+// printTimedOutSteps prints each step whose service time exceeded its
+// respond.timeout.
+func printTimedOutSteps(result *verify.VerifyResult) {
+	for _, step := range result.Steps {
+		if step.TimedOut {
+			fmt.Fprintf(os.Stderr, "  Step %d: %s — served in %dms, exceeds timeout %s ✗\n",
+				step.Index+1, step.Label, step.DurationMs, step.Timeout)
+		}
+	}
+}
+
+// printLateSteps prints each step first called later after its predecessor
+// than its expect_within allows.
+func printLateSteps(result *verify.VerifyResult) {
+	for _, step := range result.Steps {
+		if step.GapExceeded {
+			fmt.Fprintf(os.Stderr, "  Step %d: %s — called %dms after step %d, exceeds expect_within %s ✗\n",
+				step.Index+1, step.Label, step.GapMs, step.Index, step.ExpectWithin)
+		}
+	}
+}
+
+// printAssertFailures prints each step whose rendered output failed its
+// respond.assert checks.
+func printAssertFailures(result *verify.VerifyResult) {
+	for _, step := range result.Steps {
+		if step.AssertFailure != "" {
+			fmt.Fprintf(os.Stderr, "  Step %d: %s — assert failed: %s ✗\n",
+				step.Index+1, step.Label, step.AssertFailure)
+		}
+	}
+}
+
+// printForbiddenCalls prints each call made to a command in
+// meta.security.forbidden_commands.
+func printForbiddenCalls(result *verify.VerifyResult) {
+	for _, argv := range result.ForbiddenCalls {
+		fmt.Fprintf(os.Stderr, "  forbidden: %s ✗\n", strings.Join(argv, " "))
+	}
+}
+
+// printGroupSummary prints one line per step group saying whether the
+// group was fully satisfied.
+func printGroupSummary(result *verify.VerifyResult) {
+	for _, g := range result.Groups {
+		callWord := "calls"
+		if g.CallCount == 1 {
+			callWord = "call"
+		}
+		if g.Passed {
+			fmt.Fprintf(os.Stderr, "  Group %q: fully satisfied (%d/%d steps, %d %s) ✓\n",
+				g.Name, g.PassedSteps, g.TotalSteps, g.CallCount, callWord)
+			continue
+		}
+		reason := "call bounds exceeded"
+		if !g.MinsMet {
+			reason = "minimum calls not met"
+		}
+		fmt.Fprintf(os.Stderr, "  Group %q: %d/%d steps satisfied, %d %s — %s ✗\n",
+			g.Name, g.PassedSteps, g.TotalSteps, g.CallCount, callWord, reason)
+	}
+}
+
 // printPerStepCounts prints per-step invocation counts with call bounds info.
 func printPerStepCounts(steps []scenario.Step, state *runner.State) {
 	for i, step := range steps {
@@ -182,10 +285,10 @@ func printPerStepCounts(steps []scenario.Step, state *runner.State) {
 
 		// Build step label from first argv elements
 		label := ""
-		if len(step.Match.Argv) > 0 {
-			label = step.Match.Argv[0]
-			if len(step.Match.Argv) > 1 {
-				label += " " + step.Match.Argv[1]
+		if argv := step.Match.PrimaryArgv(); len(argv) > 0 {
+			label = argv[0]
+			if len(argv) > 1 {
+				label += " " + argv[1]
 			}
 		}
 
@@ -196,10 +299,14 @@ func printPerStepCounts(steps []scenario.Step, state *runner.State) {
 
 		status := "✓"
 		suffix := ""
-		if callCount < bounds.Min {
+		switch verify.CallStatus(callCount, bounds) {
+		case verify.StatusUnder:
 			status = "✗"
 			needed := bounds.Min - callCount
 			suffix = fmt.Sprintf(" needs %d more", needed)
+		case verify.StatusOver:
+			status = "✗"
+			suffix = fmt.Sprintf(" %d over max", callCount-bounds.Max)
 		}
 
 		if step.Calls != nil {
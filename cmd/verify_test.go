@@ -19,6 +19,7 @@ import (
 func makeVerifyRoot() *cobra.Command {
 	// Reset global flag state
 	verifyFormatFlag = "text"
+	verifyIncludeCapturesFlag = false
 
 	root := &cobra.Command{
 		Use:           "cli-replay",
@@ -32,6 +33,7 @@ func makeVerifyRoot() *cobra.Command {
 		RunE: runVerify,
 	}
 	v.Flags().StringVar(&verifyFormatFlag, "format", "text", "Output format: text, json, or junit")
+	v.Flags().BoolVar(&verifyIncludeCapturesFlag, "include-captures", false, "Include captured values in the report")
 	root.AddCommand(v)
 	return root
 }
@@ -215,6 +217,63 @@ func TestVerify_FormatJSON_Passed(t *testing.T) {
 	assert.Equal(t, "test-scenario", result["scenario"])
 }
 
+// --include-captures adds the session's captures to the JSON report, with
+// names matching deny_env_vars redacted; captures are omitted by default.
+func TestVerify_FormatJSON_IncludeCaptures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix-specific test")
+	}
+
+	tmpDir := t.TempDir()
+	scenarioPath := filepath.Join(tmpDir, "captures.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`meta:
+  name: captures-scenario
+  security:
+    deny_env_vars: ["*_TOKEN"]
+steps:
+  - match:
+      argv: ["echo", "hello"]
+    respond:
+      exit: 0
+`), 0644))
+	absPath, err := filepath.Abs(scenarioPath)
+	require.NoError(t, err)
+
+	stateFile := runner.StateFilePath(absPath)
+	state := runner.NewState(absPath, "hash123", 1)
+	state.StepCounts = []int{1}
+	state.CurrentStep = 1
+	state.Captures = map[string]string{"rg_id": "abc-123", "API_TOKEN": "s3cret"}
+	require.NoError(t, runner.WriteState(stateFile, state))
+	t.Cleanup(func() { _ = runner.DeleteState(stateFile) })
+
+	runJSON := func(args ...string) map[string]interface{} {
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+
+		root := makeVerifyRoot()
+		root.SetArgs(append([]string{"verify", "--format", "json"}, args...))
+		execErr := root.Execute()
+
+		w.Close()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		os.Stdout = oldStdout
+
+		require.NoError(t, execErr)
+		var result map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &result), "output should be valid JSON: %s", buf.String())
+		return result
+	}
+
+	withFlag := runJSON("--include-captures", scenarioPath)
+	assert.Equal(t, map[string]interface{}{"rg_id": "abc-123", "API_TOKEN": "[REDACTED]"}, withFlag["captures"])
+
+	byDefault := runJSON(scenarioPath)
+	assert.NotContains(t, byDefault, "captures")
+}
+
 // T011: --format junit produces parseable XML for passing scenario
 func TestVerify_FormatJUnit_Passed(t *testing.T) {
 	if runtime.GOOS == "windows" {
@@ -3,6 +3,7 @@ module github.com/ormasoftchile/cli-replay
 go 1.21
 
 require (
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/spf13/cobra v1.8.0
 	github.com/stretchr/testify v1.8.4
 	golang.org/x/sys v0.19.0
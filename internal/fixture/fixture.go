@@ -0,0 +1,226 @@
+// Package fixture resolves and reads the stdout_file/stderr_file fixtures
+// referenced by scenarios. The CLI and the replay engine share it, so
+// fixtures behave the same however a scenario is replayed.
+package fixture
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/envfilter"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// Path resolves a stdout_file/stderr_file reference of scn to the
+// file to read. $VAR and ${VAR} references are expanded from the
+// environment first; variables denied by meta.security.deny_env_vars
+// expand to empty, and a reference that expands an empty variable fails as
+// not found. Without variables, or when meta.fixtures_dir is set, the path
+// must stay within the fixtures root. Otherwise the expanded path may be
+// absolute or lead out of the scenario directory, so fixtures can live
+// elsewhere, such as under $TESTDATA_DIR. A reference containing glob
+// syntax resolves to the most recently modified matching file.
+func Path(scn *scenario.Scenario, scenarioDir, ref string) (string, error) {
+	root := scenarioDir
+	configured := false
+	if scn != nil {
+		root = scn.FixturesRoot(scenarioDir)
+		configured = scn.Meta.FixturesDir != ""
+	}
+	if !strings.Contains(ref, "$") {
+		return Within(root, ref)
+	}
+
+	var deny []string
+	if scn != nil && scn.Meta.Security != nil {
+		deny = scn.Meta.Security.DenyEnvVars
+	}
+	var empty string
+	expanded := os.Expand(ref, func(name string) string {
+		v := os.Getenv(name)
+		if envfilter.IsDenied(name, deny) {
+			v = ""
+		}
+		if v == "" && empty == "" {
+			empty = name
+		}
+		return v
+	})
+	if empty != "" {
+		return "", fmt.Errorf("%s: fixture not found: $%s is unset or empty (path expands to %q): %w",
+			ref, empty, expanded, fs.ErrNotExist)
+	}
+	if configured {
+		if err := CheckWithinRoot(root, expanded); err != nil {
+			return "", fmt.Errorf("%s: %w", ref, err)
+		}
+	} else {
+		root = "" // the variable decides where fixtures live
+	}
+	if filepath.IsAbs(expanded) {
+		return resolveGlob(root, ref, filepath.Clean(expanded))
+	}
+	return resolveGlob(root, ref, filepath.Join(root, expanded))
+}
+
+// Within resolves relPath against root, which it must stay within. Like
+// Path, a reference containing glob syntax resolves to the most recently
+// modified matching file; $VAR references are not expanded.
+func Within(root, relPath string) (string, error) {
+	if err := CheckWithinRoot(root, relPath); err != nil {
+		return "", err
+	}
+	return resolveGlob(root, relPath, filepath.Join(root, relPath))
+}
+
+// resolveGlob returns fullPath unchanged unless ref contains glob
+// syntax, in which case fullPath is treated as a pattern and the most
+// recently modified regular file matching it is returned. Equally recent
+// files resolve to the last in name order, so the choice is stable. When
+// root is set, the chosen file must stay within it.
+func resolveGlob(root, ref, fullPath string) (string, error) {
+	if !strings.ContainsAny(ref, "*?[") {
+		return fullPath, nil
+	}
+	matches, err := filepath.Glob(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("%s: invalid fixture pattern: %w", ref, err)
+	}
+	best := ""
+	var bestMod time.Time
+	for _, m := range matches { // Glob returns matches in name order
+		info, statErr := os.Stat(m)
+		if statErr != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if best == "" || !info.ModTime().Before(bestMod) {
+			best, bestMod = m, info.ModTime()
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%s: no fixture matches the pattern: %w", ref, fs.ErrNotExist)
+	}
+	if root != "" {
+		rel, relErr := filepath.Rel(root, best)
+		if relErr != nil {
+			return "", fmt.Errorf("%s: fixture path escapes %s", ref, root)
+		}
+		if err := CheckWithinRoot(root, rel); err != nil {
+			return "", fmt.Errorf("%s: %w", ref, err)
+		}
+	}
+	return best, nil
+}
+
+// CheckWithinRoot returns an error if relPath is absolute or, joined to
+// root, resolves outside root.
+func CheckWithinRoot(root, relPath string) error {
+	if filepath.IsAbs(relPath) || filepath.VolumeName(relPath) != "" {
+		return fmt.Errorf("%s: absolute fixture paths are not allowed (must be relative to %s)", relPath, root)
+	}
+	rel, err := filepath.Rel(root, filepath.Join(root, relPath))
+	if err != nil || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s: fixture path escapes %s", relPath, root)
+	}
+	return nil
+}
+
+// Read reads the fixture at fullPath, decompressing .gz files.
+// relPath names the fixture in errors.
+func Read(fullPath, relPath string) (string, error) {
+	var buf strings.Builder
+	if err := Copy(&buf, fullPath, relPath); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Copy streams the fixture at fullPath to w, decompressing .gz
+// files, so large fixtures are never held in memory.
+func Copy(w io.Writer, fullPath, relPath string) error {
+	r, err := Open(fullPath, relPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close() //nolint:errcheck
+
+	_, err = io.Copy(w, r) //nolint:gosec // Fixture size is controlled by the scenario author
+	return err
+}
+
+// HasTemplate reports whether the fixture at fullPath contains
+// template syntax ("{{"). The file is scanned in chunks, so checking a
+// large static fixture does not load it into memory.
+func HasTemplate(fullPath, relPath string) (bool, error) {
+	r, err := Open(fullPath, relPath)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close() //nolint:errcheck
+
+	buf := make([]byte, 32*1024)
+	prevBrace := false // the previous chunk ended in '{'
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			if (prevBrace && chunk[0] == '{') || bytes.Contains(chunk, []byte("{{")) {
+				return true, nil
+			}
+			prevBrace = chunk[n-1] == '{'
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// Open opens the fixture at fullPath for reading, decompressing
+// .gz files. relPath names the fixture in errors.
+func Open(fullPath, relPath string) (io.ReadCloser, error) {
+	f, err := os.Open(fullPath) //nolint:gosec // File path is relative to scenario directory
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(filepath.Ext(fullPath), ".gz") {
+		return f, nil
+	}
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: invalid gzip data: %w", relPath, err)
+	}
+	return &gzipReader{zr: zr, f: f, relPath: relPath}, nil
+}
+
+// gzipReader reads a decompressed .gz fixture, naming the fixture in
+// decompression errors.
+type gzipReader struct {
+	zr      *gzip.Reader
+	f       *os.File
+	relPath string
+}
+
+func (g *gzipReader) Read(p []byte) (int, error) {
+	n, err := g.zr.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%s: failed to decompress: %w", g.relPath, err)
+	}
+	return n, err
+}
+
+func (g *gzipReader) Close() error {
+	_ = g.zr.Close()
+	return g.f.Close()
+}
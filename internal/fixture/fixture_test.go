@@ -0,0 +1,46 @@
+package fixture
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWithinRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "fixtures")
+	assert.NoError(t, CheckWithinRoot(root, "a/b.txt"))
+	assert.NoError(t, CheckWithinRoot(root, "a/../b.txt"))
+	assert.Error(t, CheckWithinRoot(root, "../b.txt"))
+	assert.Error(t, CheckWithinRoot(root, "a/../../b.txt"))
+	assert.Error(t, CheckWithinRoot(root, filepath.Join(root, "b.txt")))
+}
+
+func TestHasTemplate(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]struct {
+		content string
+		want    bool
+	}{
+		"static":         {content: "plain { braces } only\n", want: false},
+		"empty":          {content: "", want: false},
+		"action":         {content: "hello {{ .name }}\n", want: true},
+		"chunk boundary": {content: strings.Repeat("x", 32*1024-1) + "{{ .a }}", want: true},
+		"split braces":   {content: strings.Repeat("x", 32*1024-1) + "{ {", want: false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".txt")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+			got, err := HasTemplate(path, filepath.Base(path))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := HasTemplate(filepath.Join(dir, "missing.txt"), "missing.txt")
+	require.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cmdBuiltins lists the internal commands of cmd.exe. CMD resolves these
+// before consulting PATH, so an intercept named after one is never run by
+// a batch script that invokes it by name.
+var cmdBuiltins = map[string]bool{
+	"assoc": true, "break": true, "call": true, "cd": true, "chdir": true,
+	"cls": true, "color": true, "copy": true, "date": true, "del": true,
+	"dir": true, "echo": true, "endlocal": true, "erase": true, "exit": true,
+	"for": true, "ftype": true, "goto": true, "if": true, "md": true,
+	"mkdir": true, "mklink": true, "move": true, "path": true, "pause": true,
+	"popd": true, "prompt": true, "pushd": true, "rd": true, "rem": true,
+	"ren": true, "rename": true, "rmdir": true, "set": true, "setlocal": true,
+	"shift": true, "start": true, "time": true, "title": true, "type": true,
+	"ver": true, "verify": true, "vol": true,
+}
+
+// BuiltinShimDir is the subdirectory of an intercept directory holding
+// the .cmd shims generated for CMD built-ins.
+const BuiltinShimDir = "builtins"
+
+// IsCmdBuiltin reports whether name is a cmd.exe internal command. The
+// comparison is case-insensitive, as in CMD.
+func IsCmdBuiltin(name string) bool {
+	return cmdBuiltins[strings.ToLower(name)]
+}
+
+// CmdBuiltinCollisions returns the commands that share their name with a
+// CMD built-in, sorted and lowercased.
+func CmdBuiltinCollisions(commands []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, c := range commands {
+		name := strings.ToLower(c)
+		if IsCmdBuiltin(name) && !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// WriteBuiltinShims writes a .cmd shim under <interceptDir>/builtins for
+// each of builtins, delegating to the <name>.exe intercept in interceptDir,
+// and returns the shim path for each builtin name.
+func WriteBuiltinShims(interceptDir string, builtins []string) (map[string]string, error) {
+	dir := filepath.Join(interceptDir, BuiltinShimDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create builtin shim directory: %w", err)
+	}
+	shims := make(map[string]string, len(builtins))
+	for _, name := range builtins {
+		intercept := filepath.Join(interceptDir, name+".exe")
+		shimPath := filepath.Join(dir, name+".cmd")
+		content := fmt.Sprintf("@\"%s\" %%*\r\n@exit /B %%ERRORLEVEL%%\r\n", intercept)
+		if err := os.WriteFile(shimPath, []byte(content), 0644); err != nil { //nolint:gosec // shim needs to be readable
+			return nil, fmt.Errorf("failed to write builtin shim for %q: %w", name, err)
+		}
+		shims[strings.ToLower(name)] = shimPath
+	}
+	return shims, nil
+}
+
+// WrapCmdScript rewrites a batch script so that the built-ins in shims
+// run their shim instead: a built-in named at a command position (start
+// of a line, after @, or after &, &&, |, || or an opening parenthesis,
+// outside quotes) becomes `call "<shim>"`. "echo on" and "echo off" are
+// left alone, as are commands inside if/for conditions and scripts
+// reached through call.
+func WrapCmdScript(script string, shims map[string]string) string {
+	if len(shims) == 0 {
+		return script
+	}
+	lines := strings.SplitAfter(script, "\n")
+	for i, line := range lines {
+		lines[i] = wrapCmdLine(line, shims)
+	}
+	return strings.Join(lines, "")
+}
+
+// wrapCmdLine rewrites the built-ins at the command positions of one line.
+func wrapCmdLine(line string, shims map[string]string) string {
+	trimmed := strings.TrimLeft(line, " \t@")
+	if strings.HasPrefix(trimmed, ":") {
+		return line // label or :: comment
+	}
+
+	var b strings.Builder
+	inQuote := false
+	atCommand := true
+	for i := 0; i < len(line); {
+		c := line[i]
+		if atCommand && !inQuote {
+			if c == ' ' || c == '\t' || c == '@' {
+				b.WriteByte(c)
+				i++
+				continue
+			}
+			if name, n := leadingWord(line[i:]); n > 0 {
+				if shim, ok := shims[strings.ToLower(name)]; ok && !isEchoState(name, line[i+n:]) {
+					fmt.Fprintf(&b, "call \"%s\"", shim)
+					i += n
+					atCommand = false
+					continue
+				}
+			}
+			atCommand = false
+		}
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+		case !inQuote && (c == '&' || c == '|' || c == '('):
+			atCommand = true
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+// leadingWord returns the command word at the start of s and its length.
+// The word must end at whitespace, a line end or a command separator, so
+// "echo." and "echo:" forms are not treated as a plain echo.
+func leadingWord(s string) (string, int) {
+	n := 0
+	for n < len(s) && (s[n] >= 'a' && s[n] <= 'z' || s[n] >= 'A' && s[n] <= 'Z') {
+		n++
+	}
+	if n == 0 {
+		return "", 0
+	}
+	if n < len(s) && !strings.ContainsRune(" \t\r\n&|()<>", rune(s[n])) {
+		return "", 0
+	}
+	return s[:n], n
+}
+
+// isEchoState reports whether name with the rest of its line is an
+// "echo on"/"echo off" switch, which must keep reaching CMD.
+func isEchoState(name, rest string) bool {
+	if !strings.EqualFold(name, "echo") {
+		return false
+	}
+	arg := strings.ToLower(strings.TrimSpace(rest))
+	return arg == "on" || arg == "off"
+}
@@ -0,0 +1,61 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdBuiltinCollisions(t *testing.T) {
+	assert.True(t, IsCmdBuiltin("ECHO"))
+	assert.False(t, IsCmdBuiltin("kubectl"))
+	assert.Equal(t, []string{"dir", "echo"},
+		CmdBuiltinCollisions([]string{"kubectl", "Echo", "dir", "echo"}))
+	assert.Empty(t, CmdBuiltinCollisions([]string{"kubectl", "az"}))
+}
+
+func TestWriteBuiltinShims(t *testing.T) {
+	dir := t.TempDir()
+	shims, err := WriteBuiltinShims(dir, []string{"echo"})
+	require.NoError(t, err)
+
+	shimPath := filepath.Join(dir, BuiltinShimDir, "echo.cmd")
+	assert.Equal(t, map[string]string{"echo": shimPath}, shims)
+	data, err := os.ReadFile(shimPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), filepath.Join(dir, "echo.exe"))
+	assert.Contains(t, string(data), "%*")
+}
+
+func TestWrapCmdScript(t *testing.T) {
+	shims := map[string]string{"echo": `C:\i\builtins\echo.cmd`, "dir": `C:\i\builtins\dir.cmd`}
+	call := func(name string) string { return `call "` + shims[name] + `"` }
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"plain", "echo hello\r\n", call("echo") + " hello\r\n"},
+		{"at prefix and case", "  @ECHO hello", "  @" + call("echo") + " hello"},
+		{"echo off kept", "@echo off\r\necho hi\r\n", "@echo off\r\n" + call("echo") + " hi\r\n"},
+		{"chained", "dir /b && echo done | more", call("dir") + " /b && " + call("echo") + " done | more"},
+		{"parenthesized", "(echo a)", "(" + call("echo") + " a)"},
+		{"quoted separator", `echo "a & echo b"`, call("echo") + ` "a & echo b"`},
+		{"bare echo", "echo", call("echo")},
+		{"echo dot form untouched", "echo.", "echo."},
+		{"argument untouched", "myapp echo", "myapp echo"},
+		{"label untouched", ":echo", ":echo"},
+		{"unlisted builtin untouched", "type file.txt", "type file.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, WrapCmdScript(tt.script, shims))
+		})
+	}
+
+	assert.Equal(t, "echo hi", WrapCmdScript("echo hi", nil))
+}
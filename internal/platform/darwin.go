@@ -0,0 +1,163 @@
+//go:build darwin
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// darwinShimPreamble runs before the bash shim logic on macOS. Login shells
+// there run path_helper (from /etc/profile and /etc/zprofile), which moves
+// /usr/bin and /bin ahead of every other PATH entry, so a recorded script
+// that starts `bash -l` or zsh would reach /usr/bin/git instead of the
+// shim, and the shim's own PATH stripping would no longer find its
+// directory at the front. The preamble moves the shim directory back to
+// the front and appends Homebrew's bin directories when they are missing,
+// as GUI apps and launchd jobs get a minimal PATH without them.
+const darwinShimPreamble = `
+# macOS: undo path_helper reordering and keep Homebrew tools reachable
+CLI_REPLAY_REST_PATH=""
+IFS=':' read -ra CLI_REPLAY_PATH_PARTS <<< "$PATH"
+for CLI_REPLAY_PART in "${CLI_REPLAY_PATH_PARTS[@]}"; do
+    if [ -n "$CLI_REPLAY_PART" ] && [ "$CLI_REPLAY_PART" != "%[1]s" ]; then
+        CLI_REPLAY_REST_PATH="${CLI_REPLAY_REST_PATH:+$CLI_REPLAY_REST_PATH:}$CLI_REPLAY_PART"
+    fi
+done
+for CLI_REPLAY_PART in ${HOMEBREW_PREFIX:+"$HOMEBREW_PREFIX/bin"} /opt/homebrew/bin /usr/local/bin; do
+    case ":$CLI_REPLAY_REST_PATH:" in
+        *":$CLI_REPLAY_PART:"*) ;;
+        *) [ -d "$CLI_REPLAY_PART" ] && CLI_REPLAY_REST_PATH="${CLI_REPLAY_REST_PATH:+$CLI_REPLAY_REST_PATH:}$CLI_REPLAY_PART" ;;
+    esac
+done
+export PATH="%[1]s${CLI_REPLAY_REST_PATH:+:$CLI_REPLAY_REST_PATH}"
+`
+
+// sipProtectedDirs are the roots System Integrity Protection keeps
+// read-only, even for root. /usr/local is exempt.
+var sipProtectedDirs = []string{"/System", "/bin", "/sbin", "/usr"}
+
+// darwinPlatform implements Platform for macOS. It shares the bash shims
+// and symlink intercepts of unixPlatform, and adds handling for
+// path_helper's PATH reordering, Homebrew prefixes, /var → /private/var
+// symlinks and SIP-protected directories.
+type darwinPlatform struct {
+	unixPlatform
+}
+
+// newPlatform returns the macOS platform implementation.
+// This is the build-tagged factory called by New() on macOS.
+func newPlatform() Platform {
+	return &darwinPlatform{}
+}
+
+// Name returns "darwin".
+func (d *darwinPlatform) Name() string {
+	return "darwin"
+}
+
+// GenerateShim creates a bash shim like unixPlatform does, with the
+// darwinShimPreamble inserted after the shebang line. shimDir must not be
+// in a SIP-protected directory.
+func (d *darwinPlatform) GenerateShim(command, logPath, shimDir string) (*ShimFile, error) {
+	shim, err := d.unixPlatform.GenerateShim(command, logPath, shimDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNotSIPProtected(shimDir); err != nil {
+		return nil, err
+	}
+	shebang, rest, _ := strings.Cut(shim.Content, "\n")
+	shim.Content = shebang + "\n" + fmt.Sprintf(darwinShimPreamble, shimDir) + rest
+	return shim, nil
+}
+
+// Resolve locates the real binary for command, excluding excludeDir. PATH
+// entries are compared with symlinks resolved, since temporary
+// directories live under /var, a symlink to /private/var, and either
+// spelling may appear in PATH. When PATH has no match, Homebrew's bin
+// directories are tried before /usr/bin and /bin, whose developer tools
+// are often only xcode-select stubs.
+func (d *darwinPlatform) Resolve(command string, excludeDir string) (string, error) {
+	if command == "" {
+		return "", fmt.Errorf("command must be non-empty")
+	}
+	if strings.Contains(command, "/") {
+		if isExecutableFile(command) {
+			return filepath.Abs(command)
+		}
+		return "", fmt.Errorf("command not found: %s", command)
+	}
+
+	dirs := filepath.SplitList(os.Getenv("PATH"))
+	if prefix := os.Getenv("HOMEBREW_PREFIX"); prefix != "" {
+		dirs = append(dirs, filepath.Join(prefix, "bin"))
+	}
+	dirs = append(dirs, "/opt/homebrew/bin", "/usr/local/bin", "/usr/bin", "/bin")
+
+	for _, dir := range dirs {
+		if dir == "" || (excludeDir != "" && samePath(dir, excludeDir)) {
+			continue
+		}
+		candidate := filepath.Join(dir, command)
+		if isExecutableFile(candidate) {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", fmt.Errorf("command not found: %s", command)
+}
+
+// CreateIntercept creates a symlink like unixPlatform does. A symlink
+// keeps the code signature of the cli-replay binary intact; a modified
+// copy would be killed on launch on Apple silicon. targetDir must not be
+// in a SIP-protected directory.
+func (d *darwinPlatform) CreateIntercept(binaryPath, targetDir, command string) (string, error) {
+	if err := checkNotSIPProtected(targetDir); err != nil {
+		return "", err
+	}
+	return d.unixPlatform.CreateIntercept(binaryPath, targetDir, command)
+}
+
+// checkNotSIPProtected returns an error if dir is inside a directory that
+// System Integrity Protection makes read-only.
+func checkNotSIPProtected(dir string) error {
+	resolved := dir
+	if r, err := filepath.EvalSymlinks(dir); err == nil {
+		resolved = r
+	}
+	resolved = filepath.Clean(resolved)
+	if pathWithin(resolved, "/usr/local") {
+		return nil
+	}
+	for _, root := range sipProtectedDirs {
+		if pathWithin(resolved, root) {
+			return fmt.Errorf("%s is protected by System Integrity Protection; use a directory outside /System, /bin, /sbin and /usr (/usr/local is allowed)", dir)
+		}
+	}
+	return nil
+}
+
+// pathWithin reports whether path is root or inside it.
+func pathWithin(path, root string) bool {
+	return path == root || strings.HasPrefix(path, root+"/")
+}
+
+// samePath reports whether a and b name the same directory, resolving
+// symlinks when both exist.
+func samePath(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	ra, errA := filepath.EvalSymlinks(a)
+	rb, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && ra == rb
+}
+
+// isExecutableFile reports whether path is a regular file with an execute
+// bit set.
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0
+}
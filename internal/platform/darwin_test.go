@@ -0,0 +1,121 @@
+//go:build darwin
+
+package platform
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDarwinPlatform_Name(t *testing.T) {
+	assert.Equal(t, "darwin", New().Name())
+}
+
+func TestDarwinPlatform_GenerateShim(t *testing.T) {
+	p := New()
+	shimDir := t.TempDir()
+	logPath := filepath.Join(shimDir, "recording.jsonl")
+
+	shim, err := p.GenerateShim("kubectl", logPath, shimDir)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(shimDir, "kubectl"), shim.EntryPointPath)
+	assert.True(t, strings.HasPrefix(shim.Content, "#!/usr/bin/env bash\n"), "shebang stays first")
+	assert.Contains(t, shim.Content, "undo path_helper reordering")
+	assert.Contains(t, shim.Content, "/opt/homebrew/bin")
+	assert.Contains(t, shim.Content, logPath)
+	assert.Equal(t, os.FileMode(0755), shim.FileMode)
+}
+
+func TestDarwinPlatform_SIPProtectedDirs(t *testing.T) {
+	p := New()
+	_, err := p.GenerateShim("git", "/tmp/log.jsonl", "/usr/bin")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "System Integrity Protection")
+
+	_, err = p.CreateIntercept("/bin/sh", "/System/Library", "git")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "System Integrity Protection")
+
+	assert.NoError(t, checkNotSIPProtected("/usr/local/bin"))
+	assert.NoError(t, checkNotSIPProtected(t.TempDir()))
+}
+
+func TestDarwinPlatform_Resolve_ExcludesShimDirBySymlink(t *testing.T) {
+	p := New()
+	shimDir := t.TempDir() // under /var/folders, a symlink to /private/var/folders
+	require.NoError(t, os.WriteFile(filepath.Join(shimDir, "sh"), []byte("#!/bin/sh\n"), 0755))
+	realShimDir, err := filepath.EvalSymlinks(shimDir)
+	require.NoError(t, err)
+
+	t.Setenv("PATH", shimDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	resolved, err := p.Resolve("sh", realShimDir)
+	require.NoError(t, err)
+	assert.NotEqual(t, shimDir, filepath.Dir(resolved), "the shim dir is excluded under either spelling")
+	assert.True(t, filepath.IsAbs(resolved))
+}
+
+func TestDarwinPlatform_Resolve_HomebrewFallback(t *testing.T) {
+	prefix := t.TempDir()
+	bin := filepath.Join(prefix, "bin")
+	require.NoError(t, os.MkdirAll(bin, 0755))
+	tool := filepath.Join(bin, "cli-replay-brew-tool")
+	require.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\n"), 0755))
+
+	t.Setenv("PATH", "/usr/bin:/bin")
+	t.Setenv("HOMEBREW_PREFIX", prefix)
+	resolved, err := New().Resolve("cli-replay-brew-tool", "")
+	require.NoError(t, err)
+	assert.Equal(t, tool, resolved)
+}
+
+// TestDarwinPlatform_ShimInterceptsHomebrewTool records a Homebrew-installed
+// tool through a shim started from a PATH reordered as path_helper does,
+// with the shim directory behind /usr/bin.
+func TestDarwinPlatform_ShimInterceptsHomebrewTool(t *testing.T) {
+	var brewBin string
+	for _, dir := range []string{os.Getenv("HOMEBREW_PREFIX") + "/bin", "/opt/homebrew/bin", "/usr/local/bin"} {
+		if isExecutableFile(filepath.Join(dir, "brew")) {
+			brewBin = dir
+			break
+		}
+	}
+	if brewBin == "" {
+		t.Skip("Homebrew is not installed")
+	}
+
+	p := New()
+	shimDir := t.TempDir()
+	logPath := filepath.Join(shimDir, "recording.jsonl")
+	shim, err := p.GenerateShim("brew", logPath, shimDir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(shim.EntryPointPath, []byte(shim.Content), shim.FileMode))
+
+	cmd := exec.Command(shim.EntryPointPath, "--version")
+	cmd.Env = append(os.Environ(), "PATH=/usr/bin:/bin:"+shimDir+":"+brewBin, "CLI_REPLAY_IN_SHIM=")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "shim output: %s", out)
+	assert.Contains(t, string(out), "Homebrew")
+
+	f, err := os.Open(logPath)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	require.True(t, scanner.Scan(), "the shim logged the call")
+	var entry struct {
+		Argv []string `json:"argv"`
+		Exit int      `json:"exit"`
+	}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+	assert.Equal(t, []string{"brew", "--version"}, entry.Argv)
+	assert.Equal(t, 0, entry.Exit)
+}
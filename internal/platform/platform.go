@@ -69,6 +69,7 @@ type Platform interface {
 	CommandResolver
 	InterceptFactory
 
-	// Name returns a human-readable platform identifier ("unix" or "windows").
+	// Name returns a human-readable platform identifier ("unix", "darwin"
+	// or "windows").
 	Name() string
 }
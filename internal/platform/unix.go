@@ -36,29 +36,52 @@ if [ -z "$REAL_COMMAND" ] || [ "$REAL_COMMAND" = "${BASH_SOURCE[0]}" ]; then
     exit 127
 fi
 
-# Capture start time (RFC3339 format)
-TIMESTAMP=$(date -u +%%Y-%%m-%%dT%%H:%%M:%%SZ)
+# Capture start time (RFC3339 format, with nanoseconds where date supports %%N)
+TIMESTAMP=$(date -u +%%Y-%%m-%%dT%%H:%%M:%%S.%%NZ)
+case "$TIMESTAMP" in
+    *[!0-9]NZ) TIMESTAMP=$(date -u +%%Y-%%m-%%dT%%H:%%M:%%SZ) ;;
+esac
+
+# Snapshot requested environment variables (CLI_REPLAY_RECORD_ENV=A,B)
+ENV_JSON=""
+if [ -n "$CLI_REPLAY_RECORD_ENV" ]; then
+    IFS=',' read -ra RECORD_ENV_NAMES <<< "$CLI_REPLAY_RECORD_ENV"
+    for ENV_NAME in "${RECORD_ENV_NAMES[@]}"; do
+        if [ -n "$ENV_NAME" ] && [ -n "${!ENV_NAME+x}" ]; then
+            ESC_VALUE=$(printf '%%s' "${!ENV_NAME}" | sed 's/\\/\\\\/g; s/"/\\"/g' | awk '{printf "%%s\\n", $0}' | sed 's/\\n$//')
+            ENV_JSON="$ENV_JSON${ENV_JSON:+,}\"$ENV_NAME\":\"$ESC_VALUE\""
+        fi
+    done
+fi
 
-# Capture stdin if piped (non-TTY)
+# Capture stdin if piped (non-TTY). Inputs over 1 MB (the replay stdin
+# limit) are still passed to the command but not recorded.
 STDIN_FILE=""
 STDIN_CONTENT=""
 if [ ! -t 0 ]; then
     STDIN_FILE=$(mktemp)
     /bin/cat > "$STDIN_FILE"
     if [ -s "$STDIN_FILE" ]; then
-        STDIN_CONTENT=$(< "$STDIN_FILE")
+        STDIN_SIZE=$(wc -c < "$STDIN_FILE")
+        if [ $STDIN_SIZE -le 1048576 ]; then
+            STDIN_CONTENT=$(< "$STDIN_FILE")
+        else
+            echo "cli-replay: stdin larger than 1 MB not recorded" >&2
+        fi
     fi
 fi
 
-# Execute the real command and capture output
+# Execute the real command and capture output. $? is saved on the line
+# right after the command, before any other command can overwrite it.
 STDOUT_FILE=$(mktemp)
 STDERR_FILE=$(mktemp)
-EXIT_CODE=0
 
 if [ -n "$STDIN_FILE" ] && [ -s "$STDIN_FILE" ]; then
-    "$REAL_COMMAND" "$@" <"$STDIN_FILE" >"$STDOUT_FILE" 2>"$STDERR_FILE" || EXIT_CODE=$?
+    "$REAL_COMMAND" "$@" <"$STDIN_FILE" >"$STDOUT_FILE" 2>"$STDERR_FILE"
+    EXIT_CODE="$?"
 else
-    "$REAL_COMMAND" "$@" >"$STDOUT_FILE" 2>"$STDERR_FILE" || EXIT_CODE=$?
+    "$REAL_COMMAND" "$@" >"$STDOUT_FILE" 2>"$STDERR_FILE"
+    EXIT_CODE="$?"
 fi
 
 # Read captured output into variables using bash builtins to avoid
@@ -99,27 +122,24 @@ ESC_STDOUT=$(printf '%%s' "$STDOUT_CONTENT" | sed 's/\\/\\\\/g; s/"/\\"/g' | awk
 ESC_STDERR=$(printf '%%s' "$STDERR_CONTENT" | sed 's/\\/\\\\/g; s/"/\\"/g' | awk '{printf "%%s\\n", $0}' | sed 's/\\n$//')
 ESC_STDIN=$(printf '%%s' "$STDIN_CONTENT" | sed 's/\\/\\\\/g; s/"/\\"/g' | awk '{printf "%%s\\n", $0}' | sed 's/\\n$//')
 
-# Write JSONL entry (include stdin only when non-empty)
+# Write JSONL entry (include stdin and env only when non-empty)
+EXTRA_JSON=""
 if [ -n "$STDIN_CONTENT" ]; then
-    printf '{"timestamp":"%%s","argv":%%s,"exit":%%d,"stdout":"%%s","stderr":"%%s","stdin":"%%s"}\n' \
-        "$TIMESTAMP" "$ARGV_JSON" "$EXIT_CODE" "$ESC_STDOUT" "$ESC_STDERR" "$ESC_STDIN" >> "$LOGFILE"
-else
-    printf '{"timestamp":"%%s","argv":%%s,"exit":%%d,"stdout":"%%s","stderr":"%%s"}\n' \
-        "$TIMESTAMP" "$ARGV_JSON" "$EXIT_CODE" "$ESC_STDOUT" "$ESC_STDERR" >> "$LOGFILE"
+    EXTRA_JSON=",\"stdin\":\"$ESC_STDIN\""
+fi
+if [ -n "$ENV_JSON" ]; then
+    EXTRA_JSON="$EXTRA_JSON,\"env\":{$ENV_JSON}"
 fi
+printf '{"timestamp":"%%s","argv":%%s,"exit":%%d,"stdout":"%%s","stderr":"%%s"%%s}\n' \
+    "$TIMESTAMP" "$ARGV_JSON" "$EXIT_CODE" "$ESC_STDOUT" "$ESC_STDERR" "$EXTRA_JSON" >> "$LOGFILE"
 
-exit $EXIT_CODE
+exit "$EXIT_CODE"
 `
 
-// unixPlatform implements Platform for Unix-like systems (Linux, macOS, FreeBSD, etc.).
+// unixPlatform implements Platform for Unix-like systems (Linux, FreeBSD,
+// etc.). On macOS it is extended by darwinPlatform.
 type unixPlatform struct{}
 
-// newPlatform returns the Unix platform implementation.
-// This is the build-tagged factory called by New() on non-Windows systems.
-func newPlatform() Platform {
-	return &unixPlatform{}
-}
-
 // New returns the Platform for the current OS.
 func New() Platform {
 	return newPlatform()
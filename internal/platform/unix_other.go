@@ -0,0 +1,10 @@
+//go:build !windows && !darwin
+
+package platform
+
+// newPlatform returns the Unix platform implementation.
+// This is the build-tagged factory called by New() on Unix systems other
+// than macOS.
+func newPlatform() Platform {
+	return &unixPlatform{}
+}
@@ -5,6 +5,7 @@ package platform
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,7 +14,11 @@ import (
 
 func TestUnixPlatform_Name(t *testing.T) {
 	p := New()
-	assert.Equal(t, "unix", p.Name())
+	want := "unix"
+	if runtime.GOOS == "darwin" {
+		want = "darwin" // see darwin_test.go
+	}
+	assert.Equal(t, want, p.Name())
 }
 
 func TestUnixPlatform_GenerateShim(t *testing.T) {
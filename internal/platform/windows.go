@@ -54,13 +54,32 @@ var ps1ShimTemplate = "# cli-replay shim for: %s\r\n" +
 	"}\r\n" +
 	"\r\n" +
 	"# Capture start time (RFC3339 format)\r\n" +
-	"$timestamp = (Get-Date).ToUniversalTime().ToString(\"yyyy-MM-ddTHH:mm:ssZ\")\r\n" +
+	"$timestamp = (Get-Date).ToUniversalTime().ToString(\"yyyy-MM-ddTHH:mm:ss.fffffffZ\")\r\n" +
 	"\r\n" +
-	"# Capture stdin if piped\r\n" +
+	"# Snapshot requested environment variables (CLI_REPLAY_RECORD_ENV=A,B)\r\n" +
+	"$envParts = @()\r\n" +
+	"if ($env:CLI_REPLAY_RECORD_ENV) {\r\n" +
+	"    foreach ($envName in $env:CLI_REPLAY_RECORD_ENV.Split(',')) {\r\n" +
+	"        if (-not $envName) { continue }\r\n" +
+	"        $envValue = [Environment]::GetEnvironmentVariable($envName)\r\n" +
+	"        if ($null -ne $envValue) {\r\n" +
+	"            $escValue = ($envValue -replace '\\\\','\\\\' -replace '\"','\\\"' -replace [char]13+[char]10,'\\n' -replace [char]10,'\\n' -replace [char]13,'\\r' -replace [char]9,'\\t')\r\n" +
+	"            $envParts += ('\"' + $envName + '\":\"' + $escValue + '\"')\r\n" +
+	"        }\r\n" +
+	"    }\r\n" +
+	"}\r\n" +
+	"\r\n" +
+	"# Capture stdin if piped. Inputs over 1 MB (the replay stdin limit)\r\n" +
+	"# are still passed to the command but not recorded.\r\n" +
 	"$stdinContent = ''\r\n" +
 	"if ([Console]::IsInputRedirected) {\r\n" +
 	"    $stdinContent = [Console]::In.ReadToEnd()\r\n" +
 	"}\r\n" +
+	"$recordedStdin = $stdinContent\r\n" +
+	"if ($stdinContent.Length -gt 1048576) {\r\n" +
+	"    [Console]::Error.WriteLine('cli-replay: stdin larger than 1 MB not recorded')\r\n" +
+	"    $recordedStdin = ''\r\n" +
+	"}\r\n" +
 	"\r\n" +
 	"# Execute the real command and capture output\r\n" +
 	"$exitCode = 0\r\n" +
@@ -112,13 +131,16 @@ var ps1ShimTemplate = "# cli-replay shim for: %s\r\n" +
 	"$escStdout = ($stdoutContent -replace '\\\\','\\\\' -replace '\"','\\\"' -replace [char]13+[char]10,'\\n' -replace [char]10,'\\n' -replace [char]13,'\\r' -replace [char]9,'\\t')\r\n" +
 	"$escStderr = ($stderrContent -replace '\\\\','\\\\' -replace '\"','\\\"' -replace [char]13+[char]10,'\\n' -replace [char]10,'\\n' -replace [char]13,'\\r' -replace [char]9,'\\t')\r\n" +
 	"\r\n" +
-	"# Write JSONL entry (include stdin when non-empty)\r\n" +
-	"if ($stdinContent) {\r\n" +
-	"    $escStdin = ($stdinContent -replace '\\\\','\\\\' -replace '\"','\\\"' -replace [char]13+[char]10,'\\n' -replace [char]10,'\\n' -replace [char]13,'\\r' -replace [char]9,'\\t')\r\n" +
-	"    $jsonLine = '{\"timestamp\":\"' + $timestamp + '\",\"argv\":' + $argvJson + ',\"exit\":' + $exitCode + ',\"stdout\":\"' + $escStdout + '\",\"stderr\":\"' + $escStderr + '\",\"stdin\":\"' + $escStdin + '\"}'\r\n" +
-	"} else {\r\n" +
-	"    $jsonLine = '{\"timestamp\":\"' + $timestamp + '\",\"argv\":' + $argvJson + ',\"exit\":' + $exitCode + ',\"stdout\":\"' + $escStdout + '\",\"stderr\":\"' + $escStderr + '\"}'\r\n" +
+	"# Write JSONL entry (include stdin and env when non-empty)\r\n" +
+	"$extraJson = ''\r\n" +
+	"if ($recordedStdin) {\r\n" +
+	"    $escStdin = ($recordedStdin -replace '\\\\','\\\\' -replace '\"','\\\"' -replace [char]13+[char]10,'\\n' -replace [char]10,'\\n' -replace [char]13,'\\r' -replace [char]9,'\\t')\r\n" +
+	"    $extraJson = ',\"stdin\":\"' + $escStdin + '\"'\r\n" +
+	"}\r\n" +
+	"if ($envParts.Count -gt 0) {\r\n" +
+	"    $extraJson += ',\"env\":{' + ($envParts -join ',') + '}'\r\n" +
 	"}\r\n" +
+	"$jsonLine = '{\"timestamp\":\"' + $timestamp + '\",\"argv\":' + $argvJson + ',\"exit\":' + $exitCode + ',\"stdout\":\"' + $escStdout + '\",\"stderr\":\"' + $escStderr + '\"' + $extraJson + '}'\r\n" +
 	"Add-Content -Path $LogFile -Value $jsonLine -Encoding UTF8 -NoNewline\r\n" +
 	"Add-Content -Path $LogFile -Value ([char]10) -NoNewline\r\n" +
 	"\r\n" +
@@ -16,6 +16,13 @@ type RecordedCommand struct {
 	Stdout    string    `json:"stdout"`
 	Stderr    string    `json:"stderr"`
 	Stdin     string    `json:"stdin,omitempty"`
+	// Env holds the values of the variables requested via --record-env,
+	// snapshotted when the command ran. Unset variables are omitted.
+	Env map[string]string `json:"env,omitempty"`
+	// Delay is the time since the previous recorded command started. It is
+	// set by Finalize when the session captures timing and becomes the
+	// step's respond.delay.
+	Delay time.Duration `json:"-"`
 }
 
 // Validate checks that the RecordedCommand is valid.
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 	"gopkg.in/yaml.v3"
 )
@@ -30,20 +32,92 @@ func ConvertToScenario(meta SessionMetadata, commands []RecordedCommand) (*scena
 			Match: scenario.Match{
 				Argv:  cmd.Argv,
 				Stdin: cmd.Stdin, // populated when non-empty
+				Env:   cmd.Env,   // populated with --record-env
 			},
 			Respond: scenario.Response{
-				Exit:   cmd.ExitCode,
+				Exit:   scenario.Exit(cmd.ExitCode),
 				Stdout: cmd.Stdout,
 				Stderr: cmd.Stderr,
 			},
 		}
 
+		if cmd.Delay > 0 {
+			step.Respond.Delay = cmd.Delay.String()
+		}
+
 		sc.Steps = append(sc.Steps, scenario.StepElement{Step: &step})
 	}
 
 	return sc, nil
 }
 
+// HashStdin replaces the match.stdin of every step with the equivalent
+// match.stdin_sha256, keeping large piped payloads such as manifests out of
+// the scenario.
+func HashStdin(sc *scenario.Scenario) {
+	for _, elem := range sc.Steps {
+		if elem.Step == nil || elem.Step.Match.Stdin == "" {
+			continue
+		}
+		elem.Step.Match.StdinSHA256 = matcher.StdinSHA256(elem.Step.Match.Stdin)
+		elem.Step.Match.Stdin = ""
+	}
+}
+
+// ScenarioToCommands is the reverse of ConvertToScenario: it flattens the
+// scenario's steps (including group children) into recorded commands with
+// the given timestamp. stdout_file and stderr_file fixtures are inlined via
+// readFile; steps that reference a fixture fail when readFile is nil.
+func ScenarioToCommands(sc *scenario.Scenario, timestamp time.Time, readFile func(string) (string, error)) ([]RecordedCommand, error) {
+	if sc == nil {
+		return nil, fmt.Errorf("scenario cannot be nil")
+	}
+
+	steps := sc.FlatSteps()
+	commands := make([]RecordedCommand, 0, len(steps))
+	for i, step := range steps {
+		stdout, err := inlineFixture(step.Respond.Stdout, step.Respond.StdoutFile, readFile)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		stderr, err := inlineFixture(step.Respond.Stderr, step.Respond.StderrFile, readFile)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+
+		cmd := RecordedCommand{
+			Timestamp: timestamp,
+			Argv:      step.Match.PrimaryArgv(),
+			ExitCode:  step.Respond.EffectiveExit(),
+			Stdout:    stdout,
+			Stderr:    stderr,
+			Stdin:     step.Match.Stdin,
+			Env:       step.Match.Env,
+		}
+		if err := cmd.Validate(); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		commands = append(commands, cmd)
+	}
+
+	return commands, nil
+}
+
+// inlineFixture returns inline, or the contents of file when set.
+func inlineFixture(inline, file string, readFile func(string) (string, error)) (string, error) {
+	if file == "" {
+		return inline, nil
+	}
+	if readFile == nil {
+		return "", fmt.Errorf("fixture %s cannot be read", file)
+	}
+	content, err := readFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read fixture %s: %w", file, err)
+	}
+	return content, nil
+}
+
 // GenerateYAML serializes a scenario to YAML format.
 func GenerateYAML(sc *scenario.Scenario) (string, error) {
 	if sc == nil {
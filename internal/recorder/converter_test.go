@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -48,12 +49,12 @@ func TestConvertToScenario(t *testing.T) {
 	assert.Equal(t, []string{"kubectl", "get", "pods"}, scenario.Steps[0].Step.Match.Argv)
 	assert.Equal(t, "NAME    READY   STATUS\npod1    1/1     Running\n", scenario.Steps[0].Step.Respond.Stdout)
 	assert.Empty(t, scenario.Steps[0].Step.Respond.Stderr)
-	assert.Equal(t, 0, scenario.Steps[0].Step.Respond.Exit)
+	assert.Equal(t, 0, scenario.Steps[0].Step.Respond.EffectiveExit())
 
 	// Step 2
 	assert.Equal(t, []string{"kubectl", "describe", "pod", "pod1"}, scenario.Steps[1].Step.Match.Argv)
 	assert.Equal(t, "Name: pod1\nNamespace: default\n", scenario.Steps[1].Step.Respond.Stdout)
-	assert.Equal(t, 0, scenario.Steps[1].Step.Respond.Exit)
+	assert.Equal(t, 0, scenario.Steps[1].Step.Respond.EffectiveExit())
 }
 
 func TestConvertToScenario_DuplicateCommands(t *testing.T) {
@@ -126,7 +127,7 @@ func TestConvertToScenario_NonZeroExitCode(t *testing.T) {
 	require.NoError(t, err)
 
 	require.Len(t, scenario.Steps, 1)
-	assert.Equal(t, 1, scenario.Steps[0].Step.Respond.Exit)
+	assert.Equal(t, 1, scenario.Steps[0].Step.Respond.EffectiveExit())
 	assert.Equal(t, "Error from server (NotFound): pods \"nonexistent\" not found\n", scenario.Steps[0].Step.Respond.Stderr)
 	assert.Empty(t, scenario.Steps[0].Step.Respond.Stdout)
 }
@@ -157,7 +158,7 @@ func TestGenerateYAML(t *testing.T) {
 					Argv: []string{"kubectl", "get", "pods"},
 				},
 				Respond: scenario.Response{
-					Exit:   0,
+					Exit:   scenario.Exit(0),
 					Stdout: "NAME    READY\npod1    1/1     Running\n",
 					Stderr: "",
 				},
@@ -192,7 +193,7 @@ func TestWriteYAMLFile(t *testing.T) {
 					Argv: []string{"echo", "hello"},
 				},
 				Respond: scenario.Response{
-					Exit:   0,
+					Exit:   scenario.Exit(0),
 					Stdout: "hello\n",
 					Stderr: "",
 				},
@@ -263,6 +264,39 @@ func TestConvertToScenario_WithStdin(t *testing.T) {
 	assert.Empty(t, sc.Steps[1].Step.Match.Stdin)
 }
 
+func TestConvertToScenario_WithDelay(t *testing.T) {
+	meta := SessionMetadata{Name: "timed", RecordedAt: mustParseTime("2024-01-15T10:00:00Z")}
+	commands := []RecordedCommand{
+		{Timestamp: mustParseTime("2024-01-15T10:30:00Z"), Argv: []string{"kubectl", "get", "pods"}},
+		{Timestamp: mustParseTime("2024-01-15T10:30:02Z"), Argv: []string{"kubectl", "apply"}, Delay: 1500 * time.Millisecond},
+	}
+
+	sc, err := ConvertToScenario(meta, commands)
+	require.NoError(t, err)
+	require.Len(t, sc.Steps, 2)
+	assert.Empty(t, sc.Steps[0].Step.Respond.Delay)
+	assert.Equal(t, "1.5s", sc.Steps[1].Step.Respond.Delay)
+	require.NoError(t, sc.Validate())
+}
+
+func TestHashStdin(t *testing.T) {
+	manifest := "apiVersion: v1\nkind: Pod\n"
+	sc := &scenario.Scenario{
+		Meta: scenario.Meta{Name: "hashed"},
+		Steps: []scenario.StepElement{
+			{Step: &scenario.Step{Match: scenario.Match{Argv: []string{"kubectl", "apply", "-f", "-"}, Stdin: manifest}}},
+			{Step: &scenario.Step{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}}},
+		},
+	}
+
+	HashStdin(sc)
+	first := sc.Steps[0].Step.Match
+	assert.Empty(t, first.Stdin)
+	assert.Equal(t, matcher.StdinSHA256(manifest), first.StdinSHA256)
+	assert.Empty(t, sc.Steps[1].Step.Match.StdinSHA256, "steps without stdin are left alone")
+	require.NoError(t, sc.Validate())
+}
+
 func TestConvertToScenario_StdinAppearsInYAML(t *testing.T) {
 	meta := SessionMetadata{
 		Name:        "stdin-yaml-test",
@@ -299,3 +333,48 @@ func mustParseTime(s string) time.Time {
 	}
 	return t
 }
+
+func TestScenarioToCommands_FlattensGroupsAndInlinesFixtures(t *testing.T) {
+	first := scenario.Step{
+		Match:   scenario.Match{Argv: []string{"git", "status"}, Stdin: "in"},
+		Respond: scenario.Response{Exit: scenario.Exit(0), StdoutFile: "status.txt"},
+	}
+	grouped := scenario.Step{
+		Match:   scenario.Match{Argv: []string{"git", "push"}},
+		Respond: scenario.Response{Exit: scenario.Exit(1), Stderr: "rejected\n"},
+	}
+	sc := &scenario.Scenario{
+		Meta: scenario.Meta{Name: "git"},
+		Steps: []scenario.StepElement{
+			{Step: &first},
+			{Group: &scenario.StepGroup{Mode: "unordered", Steps: []scenario.StepElement{{Step: &grouped}}}},
+		},
+	}
+
+	ts := mustParseTime("2024-01-15T10:00:00Z")
+	commands, err := ScenarioToCommands(sc, ts, func(rel string) (string, error) {
+		assert.Equal(t, "status.txt", rel)
+		return "clean\n", nil
+	})
+	require.NoError(t, err)
+	require.Len(t, commands, 2)
+	assert.Equal(t, []string{"git", "status"}, commands[0].Argv)
+	assert.Equal(t, "clean\n", commands[0].Stdout)
+	assert.Equal(t, "in", commands[0].Stdin)
+	assert.Equal(t, ts, commands[0].Timestamp)
+	assert.Equal(t, []string{"git", "push"}, commands[1].Argv)
+	assert.Equal(t, 1, commands[1].ExitCode)
+	assert.Equal(t, "rejected\n", commands[1].Stderr)
+}
+
+func TestScenarioToCommands_FixtureWithoutReader(t *testing.T) {
+	step := scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cat"}},
+		Respond: scenario.Response{StdoutFile: "out.txt"},
+	}
+	sc := &scenario.Scenario{Meta: scenario.Meta{Name: "x"}, Steps: []scenario.StepElement{{Step: &step}}}
+
+	_, err := ScenarioToCommands(sc, mustParseTime("2024-01-15T10:00:00Z"), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out.txt")
+}
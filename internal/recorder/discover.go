@@ -0,0 +1,53 @@
+package recorder
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// splitExcluded lists commands never shimmed by DiscoverCommands. Shells
+// and env run other commands; recording them would capture a whole script
+// as one step and hide the commands it invokes, since nested invocations
+// inside a shim are not recorded.
+var splitExcluded = map[string]bool{
+	"bash": true, "sh": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+	"env": true, "cli-replay": true,
+}
+
+// shimNameRe matches command names that can safely be embedded in a shim.
+var shimNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// DiscoverCommands returns the names of all executables found on pathEnv,
+// for recording every external command a script invokes without listing
+// them up front. Names are unique and sorted; the first PATH entry wins as
+// on lookup. Shells, env, cli-replay itself, and names with characters
+// unsafe for a shim (such as "[") are skipped.
+func DiscoverCommands(pathEnv string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if seen[name] || splitExcluded[name] || !shimNameRe.MatchString(name) {
+				continue
+			}
+			info, err := os.Stat(filepath.Join(dir, name))
+			if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
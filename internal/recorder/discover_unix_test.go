@@ -0,0 +1,88 @@
+//go:build !windows
+
+package recorder
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/internal/platform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverCommands_SkipsShellsAndUnsafeNames(t *testing.T) {
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	writeFakeCommand(t, dirA, "kubectl", "true")
+	writeFakeCommand(t, dirA, "bash", "true")
+	writeFakeCommand(t, dirA, "[", "true")
+	writeFakeCommand(t, dirB, "kubectl", "true")
+	writeFakeCommand(t, dirB, "az", "true")
+	require.NoError(t, os.WriteFile(filepath.Join(dirB, "notes.txt"), []byte("x"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dirB, "subdir"), 0750))
+
+	names := DiscoverCommands(dirA + string(os.PathListSeparator) + dirB)
+	assert.Equal(t, []string{"az", "kubectl"}, names)
+}
+
+func TestRecordingSession_CaptureTimingFromShims(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeCommand(t, binDir, "build-step", "true")
+	script := filepath.Join(t.TempDir(), "paced.sh")
+	require.NoError(t, os.WriteFile(script, []byte("build-step one\nsleep 0.5\nbuild-step two\n"), 0600))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	session, err := New(SessionMetadata{Name: "paced", RecordedAt: time.Now().UTC()}, []string{"build-step"}, platform.New())
+	require.NoError(t, err)
+	defer session.Cleanup() //nolint:errcheck
+	session.CaptureTiming = true
+	require.NoError(t, session.SetupShims())
+
+	var stdout, stderr bytes.Buffer
+	_, err = session.Execute([]string{"bash", script}, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NoError(t, session.Finalize())
+
+	require.Len(t, session.Commands, 2)
+	assert.Zero(t, session.Commands[0].Delay)
+	delay := session.Commands[1].Delay
+	if delay%time.Second == 0 {
+		t.Skip("date has no sub-second precision here")
+	}
+	assert.GreaterOrEqual(t, delay, 450*time.Millisecond)
+	assert.Less(t, delay, 2*time.Second)
+}
+
+func TestRecordingSession_SplitCommandsRecordsEachInvocation(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeCommand(t, binDir, "fetch-data", `echo "data for $1"`)
+	writeFakeCommand(t, binDir, "publish", `echo "published" >&2; exit 2`)
+	script := filepath.Join(t.TempDir(), "workflow.sh")
+	require.NoError(t, os.WriteFile(script, []byte("fetch-data alpha\npublish --force || true\n"), 0600))
+
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	filters := DiscoverCommands(os.Getenv("PATH"))
+	require.Contains(t, filters, "fetch-data")
+	require.NotContains(t, filters, "bash")
+
+	session, err := New(SessionMetadata{Name: "split", RecordedAt: time.Now().UTC()}, filters, platform.New())
+	require.NoError(t, err)
+	defer session.Cleanup() //nolint:errcheck
+	require.NoError(t, session.SetupShims())
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := session.Execute([]string{"bash", script}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	require.NoError(t, session.Finalize())
+
+	require.Len(t, session.Commands, 2, "one step per external command; the shim's own helpers are not recorded")
+	assert.Equal(t, []string{"fetch-data", "alpha"}, session.Commands[0].Argv)
+	assert.Equal(t, "data for alpha", session.Commands[0].Stdout)
+	assert.Equal(t, []string{"publish", "--force"}, session.Commands[1].Argv)
+	assert.Equal(t, 2, session.Commands[1].ExitCode)
+}
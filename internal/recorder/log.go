@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"time"
 )
@@ -12,13 +13,14 @@ import (
 // RecordingEntry represents a single entry in a JSONL log file.
 // This is the internal representation used for JSON unmarshaling.
 type RecordingEntry struct {
-	Timestamp string   `json:"timestamp"`
-	Argv      []string `json:"argv"`
-	Exit      int      `json:"exit"`
-	Stdout    string   `json:"stdout"`
-	Stderr    string   `json:"stderr"`
-	Stdin     string   `json:"stdin,omitempty"`
-	Encoding  string   `json:"encoding,omitempty"` // "" = UTF-8 text, "base64" = raw bytes
+	Timestamp string            `json:"timestamp"`
+	Argv      []string          `json:"argv"`
+	Exit      int               `json:"exit"`
+	Stdout    string            `json:"stdout"`
+	Stderr    string            `json:"stderr"`
+	Stdin     string            `json:"stdin,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Encoding  string            `json:"encoding,omitempty"` // "" = UTF-8 text, "base64" = raw bytes
 }
 
 // RecordingLog represents the JSONL log file structure for parsing recorded commands.
@@ -107,6 +109,7 @@ func (l *RecordingLog) ToRecordedCommands() ([]RecordedCommand, error) {
 			Stdout:    stdout,
 			Stderr:    stderr,
 			Stdin:     entry.Stdin,
+			Env:       entry.Env,
 		}
 
 		if err := cmd.Validate(); err != nil {
@@ -118,3 +121,18 @@ func (l *RecordingLog) ToRecordedCommands() ([]RecordedCommand, error) {
 
 	return commands, nil
 }
+
+// WriteRecordingLog writes commands to w as JSONL, one entry per line, using
+// the same encoding rules as the shim-produced log.
+func WriteRecordingLog(w io.Writer, commands []RecordedCommand) error {
+	encoder := json.NewEncoder(w)
+	for i, cmd := range commands {
+		if err := cmd.Validate(); err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		if err := encoder.Encode(newRecordingEntry(cmd)); err != nil {
+			return fmt.Errorf("failed to write log entry: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"gopkg.in/yaml.v3"
+)
+
+// MergeStats reports what MergeIntoYAML changed.
+type MergeStats struct {
+	Updated int // existing steps whose respond was updated
+	Added   int // recorded commands appended as new steps
+}
+
+// MergeIntoYAML merges the steps of a freshly recorded scenario into the
+// scenario YAML in existing, editing its node tree so hand-written
+// comments, key order and unrelated fields survive re-recording.
+//
+// Each recorded step updates the respond of the first not yet updated
+// step of the first document whose match.argv (or one of its any_of
+// entries) equals the recorded argv, including steps inside groups: exit,
+// stdout and stderr are replaced in place, and a stream served from
+// stdout_file/stderr_file is left as is. Recorded steps that match no
+// existing step are appended to the top-level steps.
+func MergeIntoYAML(existing []byte, sc *scenario.Scenario) ([]byte, MergeStats, error) {
+	var stats MergeStats
+	if sc == nil {
+		return nil, stats, fmt.Errorf("scenario cannot be nil")
+	}
+
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(existing))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, stats, fmt.Errorf("failed to parse existing scenario: %w", err)
+		}
+		docs = append(docs, &doc)
+	}
+	if len(docs) == 0 || len(docs[0].Content) == 0 || docs[0].Content[0].Kind != yaml.MappingNode {
+		return nil, stats, fmt.Errorf("existing scenario is not a YAML mapping")
+	}
+
+	root := docs[0].Content[0]
+	steps := mappingValue(root, "steps")
+	if steps == nil {
+		steps = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "steps"}, steps)
+	}
+	if steps.Kind != yaml.SequenceNode {
+		return nil, stats, fmt.Errorf("existing scenario: steps must be a sequence")
+	}
+
+	candidates := stepNodes(steps)
+	used := make([]bool, len(candidates))
+	for _, recorded := range sc.FlatSteps() {
+		i := matchingStepNode(candidates, used, recorded.Match.Argv)
+		if i < 0 {
+			var node yaml.Node
+			if err := node.Encode(scenario.StepElement{Step: &recorded}); err != nil {
+				return nil, stats, fmt.Errorf("failed to encode step: %w", err)
+			}
+			steps.Content = append(steps.Content, &node)
+			stats.Added++
+			continue
+		}
+		used[i] = true
+		if err := updateRespondNode(candidates[i], &recorded.Respond); err != nil {
+			return nil, stats, err
+		}
+		stats.Updated++
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(detectIndent(existing))
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, stats, fmt.Errorf("failed to write merged scenario: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, stats, fmt.Errorf("failed to write merged scenario: %w", err)
+	}
+	return out.Bytes(), stats, nil
+}
+
+// stepNodes flattens the step mappings of a steps sequence, descending
+// into group children, in the order FlatSteps would list them.
+func stepNodes(steps *yaml.Node) []*yaml.Node {
+	var out []*yaml.Node
+	for _, item := range steps.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		if group := mappingValue(item, "group"); group != nil {
+			if children := mappingValue(group, "steps"); children != nil && children.Kind == yaml.SequenceNode {
+				out = append(out, stepNodes(children)...)
+			}
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// matchingStepNode returns the index of the first unused step node whose
+// match accepts argv, or -1.
+func matchingStepNode(nodes []*yaml.Node, used []bool, argv []string) int {
+	for i, node := range nodes {
+		if used[i] {
+			continue
+		}
+		matchNode := mappingValue(node, "match")
+		if matchNode == nil {
+			continue
+		}
+		var m scenario.Match
+		if err := matchNode.Decode(&m); err != nil {
+			continue
+		}
+		for _, alt := range m.Alternatives() {
+			if slices.Equal(alt, argv) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// updateRespondNode replaces the exit, stdout and stderr values of a
+// step's respond mapping with the recorded ones, and delay when timing was
+// captured. Keys and their comments are kept; a key the step lacks is
+// added only for a non-empty value.
+func updateRespondNode(step *yaml.Node, r *scenario.Response) error {
+	respond := mappingValue(step, "respond")
+	if respond == nil {
+		respond = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		step.Content = append(step.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "respond"}, respond)
+	}
+	if respond.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: respond must be a mapping", respond.Line)
+	}
+
+	if err := setMappingValue(respond, "exit", r.EffectiveExit(), r.EffectiveExit() != 0); err != nil {
+		return err
+	}
+	if mappingValue(respond, "stdout_file") == nil {
+		if err := setMappingValue(respond, "stdout", r.Stdout, r.Stdout != ""); err != nil {
+			return err
+		}
+	}
+	if mappingValue(respond, "stderr_file") == nil {
+		if err := setMappingValue(respond, "stderr", r.Stderr, r.Stderr != ""); err != nil {
+			return err
+		}
+	}
+	if r.Delay != "" {
+		if err := setMappingValue(respond, "delay", r.Delay, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key to value in mapping, keeping the comments of
+// the value it replaces. A missing key is appended only when add is true.
+func setMappingValue(mapping *yaml.Node, key string, value interface{}, add bool) error {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		old := mapping.Content[i+1]
+		node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		mapping.Content[i+1] = &node
+		return nil
+	}
+	if add {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &node)
+	}
+	return nil
+}
+
+// mappingValue returns the value node of key in mapping, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// detectIndent returns the indentation width of the YAML in data: the
+// smallest indent of any content line, or 4 (yaml.Marshal's) when nothing
+// is indented.
+func detectIndent(data []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		n := len(line) - len(trimmed)
+		if n == 0 || trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if indent == 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent < 2 {
+		return 4
+	}
+	return indent
+}
+
+// MergeIntoYAMLFile merges sc into the scenario file at existingPath as
+// MergeIntoYAML does and writes the result to outputPath, which may be
+// existingPath itself. The merged scenario is validated before outputPath
+// is replaced, so a failed merge leaves it untouched.
+func MergeIntoYAMLFile(existingPath, outputPath string, sc *scenario.Scenario) (MergeStats, error) {
+	existing, err := os.ReadFile(existingPath) //nolint:gosec // Path from the --merge-into flag
+	if err != nil {
+		return MergeStats{}, fmt.Errorf("failed to read scenario to merge into: %w", err)
+	}
+	merged, stats, err := MergeIntoYAML(existing, sc)
+	if err != nil {
+		return stats, err
+	}
+
+	// Validate next to the output so includes resolve as they will there
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), ".cli-replay-merge-*.yaml")
+	if err != nil {
+		return stats, fmt.Errorf("failed to write YAML file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) //nolint:errcheck // gone after a successful rename
+	_, err = tmp.Write(merged)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return stats, fmt.Errorf("failed to write YAML file: %w", err)
+	}
+	if _, err := scenario.LoadFileAll(tmpPath); err != nil {
+		return stats, fmt.Errorf("invalid merged scenario: %w", err)
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return stats, fmt.Errorf("failed to write YAML file: %w", err)
+	}
+	return stats, nil
+}
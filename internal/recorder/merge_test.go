@@ -0,0 +1,194 @@
+package recorder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const commentedScenario = `# Deploy smoke test, hand-annotated
+meta:
+  name: deploy # keep this name
+  description: Deploys the web app
+steps:
+  # Check the cluster first
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+      stdout: | # refreshed by re-recording
+        old-pod Running
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: [kubectl, logs, web]
+          respond:
+            exit: 0
+            stdout: "old logs\n"
+        - match:
+            argv: [kubectl, get, svc]
+          respond:
+            exit: 0
+            stdout_file: fixtures/svc.txt
+  # Roll out last
+  - match:
+      argv: [kubectl, apply, -f, app.yaml]
+    calls:
+      min: 1
+    respond:
+      exit: 1
+      stderr: "old error\n"
+`
+
+func recordedScenario(steps ...scenario.Step) *scenario.Scenario {
+	sc := &scenario.Scenario{Meta: scenario.Meta{Name: "recorded"}}
+	for i := range steps {
+		sc.Steps = append(sc.Steps, scenario.StepElement{Step: &steps[i]})
+	}
+	return sc
+}
+
+func recordedStep(argv []string, exit int, stdout, stderr string) scenario.Step {
+	return scenario.Step{
+		Match:   scenario.Match{Argv: argv},
+		Respond: scenario.Response{Exit: scenario.Exit(exit), Stdout: stdout, Stderr: stderr},
+	}
+}
+
+func TestMergeIntoYAML_PreservesCommentsAndUpdatesOutputs(t *testing.T) {
+	sc := recordedScenario(
+		recordedStep([]string{"kubectl", "get", "pods"}, 0, "web-0 Running\nweb-1 Running\n", ""),
+		recordedStep([]string{"kubectl", "logs", "web"}, 0, "new logs\n", ""),
+		recordedStep([]string{"kubectl", "get", "svc"}, 0, "new svc\n", ""),
+		recordedStep([]string{"kubectl", "apply", "-f", "app.yaml"}, 0, "deployment configured\n", ""),
+		recordedStep([]string{"kubectl", "rollout", "status"}, 0, "rolled out\n", ""),
+	)
+
+	merged, stats, err := MergeIntoYAML([]byte(commentedScenario), sc)
+	require.NoError(t, err)
+	assert.Equal(t, MergeStats{Updated: 4, Added: 1}, stats)
+
+	out := string(merged)
+	for _, comment := range []string{
+		"# Deploy smoke test, hand-annotated",
+		"# keep this name",
+		"# Check the cluster first",
+		"# refreshed by re-recording",
+		"# Roll out last",
+	} {
+		assert.Contains(t, out, comment)
+	}
+	assert.NotContains(t, out, "old-pod")
+	assert.NotContains(t, out, "old logs")
+	assert.Contains(t, out, "fixtures/svc.txt", "file-backed output is left as is")
+	assert.NotContains(t, out, "new svc")
+	assert.Contains(t, out, "\n  - match:\n", "two-space indentation is kept")
+
+	docs, err := scenario.LoadAll(bytes.NewReader(merged))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	scn := docs[0]
+	assert.Equal(t, "deploy", scn.Meta.Name, "existing meta is kept")
+	assert.Equal(t, "Deploys the web app", scn.Meta.Description)
+
+	steps := scn.FlatSteps()
+	require.Len(t, steps, 5)
+	assert.Equal(t, "web-0 Running\nweb-1 Running\n", steps[0].Respond.Stdout)
+	assert.Equal(t, "new logs\n", steps[1].Respond.Stdout)
+	assert.Equal(t, "fixtures/svc.txt", steps[2].Respond.StdoutFile)
+	assert.Equal(t, 0, steps[3].Respond.EffectiveExit())
+	assert.Equal(t, "deployment configured\n", steps[3].Respond.Stdout)
+	assert.Empty(t, steps[3].Respond.Stderr)
+	require.NotNil(t, steps[3].Calls)
+	assert.Equal(t, 1, steps[3].Calls.Min, "hand-added fields survive")
+	assert.Equal(t, []string{"kubectl", "rollout", "status"}, steps[4].Match.Argv)
+	assert.Equal(t, "rolled out\n", steps[4].Respond.Stdout)
+}
+
+func TestMergeIntoYAML_RepeatedCommands(t *testing.T) {
+	existing := `meta:
+  name: poll
+steps:
+  - match:
+      argv: [kubectl, get, job]
+    respond:
+      stdout: "pending\n" # first poll
+  - match:
+      argv: [kubectl, get, job]
+    respond:
+      stdout: "pending\n" # second poll
+`
+	sc := recordedScenario(
+		recordedStep([]string{"kubectl", "get", "job"}, 0, "running\n", ""),
+		recordedStep([]string{"kubectl", "get", "job"}, 0, "done\n", ""),
+		recordedStep([]string{"kubectl", "get", "job"}, 0, "done\n", ""),
+	)
+
+	merged, stats, err := MergeIntoYAML([]byte(existing), sc)
+	require.NoError(t, err)
+	assert.Equal(t, MergeStats{Updated: 2, Added: 1}, stats)
+	assert.Contains(t, string(merged), "# first poll")
+	assert.Contains(t, string(merged), "# second poll")
+
+	docs, err := scenario.LoadAll(bytes.NewReader(merged))
+	require.NoError(t, err)
+	steps := docs[0].FlatSteps()
+	require.Len(t, steps, 3)
+	assert.Equal(t, "running\n", steps[0].Respond.Stdout)
+	assert.Equal(t, "done\n", steps[1].Respond.Stdout)
+	assert.Equal(t, "done\n", steps[2].Respond.Stdout)
+}
+
+func TestMergeIntoYAML_UpdatesCapturedDelay(t *testing.T) {
+	existing := `meta:
+  name: paced
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      stdout: "pods\n"
+  - match:
+      argv: [kubectl, apply]
+    respond:
+      delay: 5s # hand-tuned
+`
+	timed := recordedStep([]string{"kubectl", "apply"}, 0, "", "")
+	timed.Respond.Delay = "1.2s"
+	sc := recordedScenario(recordedStep([]string{"kubectl", "get", "pods"}, 0, "pods\n", ""), timed)
+
+	merged, _, err := MergeIntoYAML([]byte(existing), sc)
+	require.NoError(t, err)
+	assert.Contains(t, string(merged), "# hand-tuned")
+
+	docs, err := scenario.LoadAll(bytes.NewReader(merged))
+	require.NoError(t, err)
+	steps := docs[0].FlatSteps()
+	assert.Empty(t, steps[0].Respond.Delay, "no delay is added when none was captured")
+	assert.Equal(t, "1.2s", steps[1].Respond.Delay)
+}
+
+func TestMergeIntoYAML_InvalidExisting(t *testing.T) {
+	sc := recordedScenario(recordedStep([]string{"git"}, 0, "", ""))
+
+	_, _, err := MergeIntoYAML([]byte("- just\n- a list\n"), sc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a YAML mapping")
+
+	_, _, err = MergeIntoYAML([]byte("meta:\n  name: x\nsteps: nope\n"), sc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "steps must be a sequence")
+
+	_, _, err = MergeIntoYAML([]byte("meta: [\n"), sc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse existing scenario")
+}
+
+func TestDetectIndent(t *testing.T) {
+	assert.Equal(t, 2, detectIndent([]byte("meta:\n  name: x\n")))
+	assert.Equal(t, 4, detectIndent([]byte("meta:\n    name: x\n      # deeper comment\n")))
+	assert.Equal(t, 4, detectIndent([]byte("steps: []\n")))
+}
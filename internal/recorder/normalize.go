@@ -0,0 +1,77 @@
+package recorder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NormalizeRule replaces every match of Pattern with Placeholder.
+type NormalizeRule struct {
+	Pattern     *regexp.Regexp
+	Placeholder string
+}
+
+// DefaultNormalizeRules cover the volatile values most often seen in
+// kubectl/az output. Rules apply in order, so fractional durations are
+// replaced before the shorter kubectl AGE forms.
+func DefaultNormalizeRules() []NormalizeRule {
+	return []NormalizeRule{
+		// 2024-01-15T10:30:00Z, 2024-01-15 10:30:00.123+02:00
+		{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?\b`), "<TIMESTAMP>"},
+		// 1.234s, 250ms, 2m3.5s, 15µs
+		{regexp.MustCompile(`\b(\d+h)?(\d+m)?\d+\.\d+s\b|\b\d+(\.\d+)?(ns|us|µs|ms)\b`), "<DURATION>"},
+		// kubectl AGE column: 10s, 5m, 3h12m, 7d, 2y
+		{regexp.MustCompile(`\b\d+[smhdy](\d+[smh])?\b`), "<AGE>"},
+	}
+}
+
+// Normalizer replaces run-dependent values in recorded stdout and stderr
+// with stable placeholders, so re-recording the same behavior produces the
+// same scenario.
+type Normalizer struct {
+	rules []NormalizeRule
+}
+
+// NewNormalizer builds a Normalizer from the default rules (when
+// withDefaults is set) followed by custom patterns of the form
+// "PLACEHOLDER=REGEX". Returns an error for a malformed or invalid pattern.
+func NewNormalizer(withDefaults bool, patterns []string) (*Normalizer, error) {
+	n := &Normalizer{}
+	if withDefaults {
+		n.rules = DefaultNormalizeRules()
+	}
+	for _, p := range patterns {
+		placeholder, expr, ok := strings.Cut(p, "=")
+		if !ok || placeholder == "" || expr == "" {
+			return nil, fmt.Errorf("invalid normalize pattern %q: expected PLACEHOLDER=REGEX", p)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid normalize pattern %q: %w", p, err)
+		}
+		n.rules = append(n.rules, NormalizeRule{Pattern: re, Placeholder: placeholder})
+	}
+	return n, nil
+}
+
+// Normalize returns s with every rule applied. A nil Normalizer returns s
+// unchanged.
+func (n *Normalizer) Normalize(s string) string {
+	if n == nil || s == "" {
+		return s
+	}
+	for _, r := range n.rules {
+		s = r.Pattern.ReplaceAllLiteralString(s, r.Placeholder)
+	}
+	return s
+}
+
+// NormalizeCommand normalizes the stdout and stderr of cmd in place.
+func (n *Normalizer) NormalizeCommand(cmd *RecordedCommand) {
+	if n == nil {
+		return
+	}
+	cmd.Stdout = n.Normalize(cmd.Stdout)
+	cmd.Stderr = n.Normalize(cmd.Stderr)
+}
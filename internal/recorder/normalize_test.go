@@ -0,0 +1,61 @@
+package recorder
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizer_Defaults(t *testing.T) {
+	n, err := NewNormalizer(true, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"created 2024-01-15T10:30:00Z", "created <TIMESTAMP>"},
+		{"at 2024-01-15 10:30:00.123+02:00.", "at <TIMESTAMP>."},
+		{"nginx-7d4f   1/1   Running   0   3h12m", "nginx-7d4f   1/1   Running   0   <AGE>"},
+		{"pod-a 12d\npod-b 45s\n", "pod-a <AGE>\npod-b <AGE>\n"},
+		{"request took 1.234s (250ms in dns)", "request took <DURATION> (<DURATION> in dns)"},
+		{"version v1.28.3", "version v1.28.3"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, n.Normalize(tt.in), tt.in)
+	}
+}
+
+func TestNormalizer_CustomPatterns(t *testing.T) {
+	n, err := NewNormalizer(false, []string{`<ID>=req-[0-9]+`})
+	require.NoError(t, err)
+	assert.Equal(t, "id <ID> at 2024-01-15T10:30:00Z", n.Normalize("id req-991 at 2024-01-15T10:30:00Z"))
+
+	_, err = NewNormalizer(false, []string{"req-[0-9]+"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PLACEHOLDER=REGEX")
+
+	_, err = NewNormalizer(false, []string{"<X>=("})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid normalize pattern")
+}
+
+func TestRecordingSession_Finalize_Normalizes(t *testing.T) {
+	session, err := New(SessionMetadata{Name: "normalize-test"}, []string{"kubectl"}, newTestPlatform())
+	require.NoError(t, err)
+	defer session.Cleanup() //nolint:errcheck // test cleanup
+
+	session.Normalizer, err = NewNormalizer(true, nil)
+	require.NoError(t, err)
+
+	logContent := `{"timestamp":"2024-01-15T10:30:00Z","argv":["kubectl","get","pods"],"exit":0,"stdout":"NAME AGE\nweb 5m\n","stderr":"fetched at 2024-01-15T10:30:00Z\n"}
+`
+	require.NoError(t, os.WriteFile(session.LogFile, []byte(logContent), 0600))
+	require.NoError(t, session.Finalize())
+
+	require.Len(t, session.Commands, 1)
+	assert.Equal(t, "NAME AGE\nweb <AGE>\n", session.Commands[0].Stdout)
+	assert.Equal(t, "fetched at <TIMESTAMP>\n", session.Commands[0].Stderr)
+}
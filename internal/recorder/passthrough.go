@@ -0,0 +1,189 @@
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"gopkg.in/yaml.v3"
+)
+
+// RecordToEnvVar enables passthrough-record mode in intercept mode: the real
+// command is executed and a step describing it is appended to the scenario
+// file named by this variable.
+const RecordToEnvVar = "CLI_REPLAY_RECORD_TO"
+
+// lockTimeout bounds how long Passthrough waits for another intercepted
+// process to finish appending to the same scenario file.
+const lockTimeout = 10 * time.Second
+
+// Passthrough executes the real binary for argv[0], streaming its output to
+// stdout/stderr while capturing it, then appends a step with the observed
+// argv, exit code, stdout and stderr to the scenario at recordTo.
+//
+// The real binary is resolved on PATH, skipping any entry that resolves to
+// the running cli-replay executable. Those directories are also removed from
+// the child's PATH so nested invocations are not intercepted again.
+//
+// The returned exit code is the real command's exit code. A non-nil error
+// with a zero or real exit code means the command ran but the step could not
+// be appended; an error with exit code 127 means the command was not found.
+func Passthrough(recordTo string, argv []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	if len(argv) == 0 {
+		return 1, errors.New("passthrough: empty argv")
+	}
+
+	realPath, childPath, err := resolveReal(argv[0], os.Getenv("PATH"))
+	if err != nil {
+		return 127, err
+	}
+
+	var outBuf, errBuf bytes.Buffer
+	cmd := exec.Command(realPath, argv[1:]...) //nolint:gosec // Passthrough of the intercepted command is the purpose
+	cmd.Stdin = stdin
+	cmd.Stdout = io.MultiWriter(stdout, &outBuf)
+	cmd.Stderr = io.MultiWriter(stderr, &errBuf)
+	cmd.Env = append(withoutEnv(os.Environ(), "PATH"), "PATH="+childPath)
+
+	exitCode := 0
+	if runErr := cmd.Run(); runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return 1, fmt.Errorf("passthrough: failed to run %s: %w", realPath, runErr)
+		}
+		exitCode = exitErr.ExitCode()
+		if exitCode < 0 || exitCode > 255 {
+			exitCode = 1
+		}
+	}
+
+	step := scenario.Step{
+		Match: scenario.Match{Argv: argv},
+		Respond: scenario.Response{
+			Exit:   scenario.Exit(exitCode),
+			Stdout: outBuf.String(),
+			Stderr: errBuf.String(),
+		},
+	}
+	if err := AppendStep(recordTo, step); err != nil {
+		return exitCode, err
+	}
+	return exitCode, nil
+}
+
+// AppendStep appends step to the scenario file at path, creating the file
+// (named after its base filename) if it does not exist. Concurrent appends
+// from separate processes are serialized with a lock file, and the scenario
+// is replaced atomically so readers never see a partial file.
+func AppendStep(path string, step scenario.Step) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve record path: %w", err)
+	}
+
+	unlock, err := acquireLock(absPath+".lock", lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var scn scenario.Scenario
+	data, err := os.ReadFile(absPath) //nolint:gosec // Record path comes from CLI_REPLAY_RECORD_TO
+	switch {
+	case err == nil && len(bytes.TrimSpace(data)) > 0:
+		if err := yaml.Unmarshal(data, &scn); err != nil {
+			return fmt.Errorf("failed to parse existing scenario %s: %w", absPath, err)
+		}
+	case err == nil || os.IsNotExist(err):
+		name := strings.TrimSuffix(filepath.Base(absPath), filepath.Ext(absPath))
+		scn.Meta.Name = name
+	default:
+		return fmt.Errorf("failed to read scenario %s: %w", absPath, err)
+	}
+
+	scn.Steps = append(scn.Steps, scenario.StepElement{Step: &step})
+
+	out, err := yaml.Marshal(&scn)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scenario: %w", err)
+	}
+
+	tmp := absPath + ".tmp"
+	if err := os.WriteFile(tmp, out, 0600); err != nil {
+		return fmt.Errorf("failed to write scenario: %w", err)
+	}
+	if err := os.Rename(tmp, absPath); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to replace scenario: %w", err)
+	}
+	return nil
+}
+
+// acquireLock creates lockPath exclusively, retrying until timeout. The
+// returned function releases the lock.
+func acquireLock(lockPath string, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) //nolint:gosec // Lock path derived from record path
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// resolveReal finds command on pathEnv, skipping directories whose entry
+// for command is the running executable (the intercept). It returns the
+// resolved binary and a PATH value with those directories removed.
+func resolveReal(command, pathEnv string) (realPath, childPath string, err error) {
+	self, _ := os.Executable()
+	selfInfo, _ := os.Stat(self)
+
+	var kept []string
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			continue
+		}
+		candidate, lookErr := exec.LookPath(filepath.Join(dir, command))
+		if lookErr == nil && selfInfo != nil {
+			if info, statErr := os.Stat(candidate); statErr == nil && os.SameFile(info, selfInfo) {
+				continue // intercept entry — would recurse into cli-replay
+			}
+		}
+		kept = append(kept, dir)
+		if realPath == "" && lookErr == nil {
+			realPath = candidate
+		}
+	}
+
+	if realPath == "" {
+		return "", "", fmt.Errorf("passthrough: command not found: %s", command)
+	}
+	return realPath, strings.Join(kept, string(os.PathListSeparator)), nil
+}
+
+// withoutEnv returns env with any entries for key removed.
+func withoutEnv(env []string, key string) []string {
+	out := make([]string, 0, len(env))
+	for _, e := range env {
+		if k, _, ok := strings.Cut(e, "="); ok && strings.EqualFold(k, key) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+//go:build !windows
+
+package recorder
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFakeCommand creates an executable shell script named name in dir.
+func writeFakeCommand(t *testing.T, dir, name, body string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0755)) //nolint:gosec // test script must be executable
+}
+
+func TestPassthrough_RecordsRealOutputAndValidScenario(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeCommand(t, binDir, "greet", `echo "hello $1"; echo "warn" >&2; exit 3`)
+
+	// The intercept directory holds a symlink to the running executable,
+	// which must be skipped to avoid recursing into cli-replay.
+	interceptDir := t.TempDir()
+	self, err := os.Executable()
+	require.NoError(t, err)
+	require.NoError(t, os.Symlink(self, filepath.Join(interceptDir, "greet")))
+
+	t.Setenv("PATH", interceptDir+string(os.PathListSeparator)+binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	recordTo := filepath.Join(t.TempDir(), "recorded.yaml")
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := Passthrough(recordTo, []string{"greet", "world"}, strings.NewReader(""), &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "hello world\n", stdout.String(), "real output must be passed through")
+	assert.Equal(t, "warn\n", stderr.String())
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode, err = Passthrough(recordTo, []string{"greet", "again"}, strings.NewReader(""), &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+
+	scn, err := scenario.LoadFile(recordTo)
+	require.NoError(t, err, "recorded file must be a valid scenario")
+	assert.Equal(t, "recorded", scn.Meta.Name)
+	steps := scn.FlatSteps()
+	require.Len(t, steps, 2)
+	assert.Equal(t, []string{"greet", "world"}, steps[0].Match.Argv)
+	assert.Equal(t, 3, steps[0].Respond.EffectiveExit())
+	assert.Equal(t, "hello world\n", steps[0].Respond.Stdout)
+	assert.Equal(t, "warn\n", steps[0].Respond.Stderr)
+	assert.Equal(t, []string{"greet", "again"}, steps[1].Match.Argv)
+}
+
+func TestPassthrough_ChildPathExcludesIntercept(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeCommand(t, binDir, "showpath", `echo "$PATH"`)
+
+	interceptDir := t.TempDir()
+	self, err := os.Executable()
+	require.NoError(t, err)
+	require.NoError(t, os.Symlink(self, filepath.Join(interceptDir, "showpath")))
+
+	t.Setenv("PATH", interceptDir+string(os.PathListSeparator)+binDir+string(os.PathListSeparator)+"/usr/bin:/bin")
+
+	var stdout, stderr bytes.Buffer
+	_, err = Passthrough(filepath.Join(t.TempDir(), "s.yaml"), []string{"showpath"}, strings.NewReader(""), &stdout, &stderr)
+	require.NoError(t, err)
+	assert.NotContains(t, stdout.String(), interceptDir, "nested calls must not be intercepted again")
+	assert.Contains(t, stdout.String(), binDir)
+}
+
+func TestPassthrough_CommandNotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := Passthrough(filepath.Join(t.TempDir(), "s.yaml"), []string{"no-such-cmd"}, strings.NewReader(""), &stdout, &stderr)
+	require.Error(t, err)
+	assert.Equal(t, 127, exitCode)
+	assert.Contains(t, err.Error(), "command not found")
+}
+
+func TestAppendStep_ConcurrentAppendsAreSafe(t *testing.T) {
+	recordTo := filepath.Join(t.TempDir(), "concurrent.yaml")
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			step := scenario.Step{Match: scenario.Match{Argv: []string{"cmd"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}}
+			assert.NoError(t, AppendStep(recordTo, step))
+		}()
+	}
+	wg.Wait()
+
+	scn, err := scenario.LoadFile(recordTo)
+	require.NoError(t, err)
+	assert.Len(t, scn.FlatSteps(), n, "no appended step may be lost")
+}
@@ -0,0 +1,70 @@
+package recorder
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactedPlaceholder replaces secret values in recorded output.
+const RedactedPlaceholder = "***REDACTED***"
+
+// Redactor scrubs secret values from recorded stdout and stderr before they
+// are written to the recording log or the generated scenario.
+type Redactor struct {
+	patterns []*regexp.Regexp
+	values   []string
+}
+
+// NewRedactor compiles the given regular expressions and resolves the
+// current values of the named environment variables via lookup. Empty
+// environment values are ignored. Returns an error for an invalid pattern.
+func NewRedactor(patterns, envNames []string, lookup func(string) string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	for _, name := range envNames {
+		if v := lookup(name); v != "" {
+			r.values = append(r.values, v)
+		}
+	}
+	// Replace longer values first so a value containing another is not
+	// left partially exposed.
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	return r, nil
+}
+
+// Redact returns s with every secret value and pattern match replaced by
+// RedactedPlaceholder. A nil Redactor returns s unchanged.
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, v := range r.values {
+		s = strings.ReplaceAll(s, v, RedactedPlaceholder)
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, RedactedPlaceholder)
+	}
+	return s
+}
+
+// RedactCommand scrubs the stdout, stderr, stdin and recorded environment
+// values of cmd in place.
+func (r *Redactor) RedactCommand(cmd *RecordedCommand) {
+	if r == nil {
+		return
+	}
+	cmd.Stdout = r.Redact(cmd.Stdout)
+	cmd.Stderr = r.Redact(cmd.Stderr)
+	cmd.Stdin = r.Redact(cmd.Stdin)
+	for k, v := range cmd.Env {
+		cmd.Env[k] = r.Redact(v)
+	}
+}
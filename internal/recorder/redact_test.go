@@ -0,0 +1,64 @@
+package recorder
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_PatternsAndEnvValues(t *testing.T) {
+	env := map[string]string{"AZURE_TOKEN": "tok-secret-42", "EMPTY": ""}
+	r, err := NewRedactor([]string{`eyJ[A-Za-z0-9._-]+`}, []string{"AZURE_TOKEN", "EMPTY", "UNSET"}, func(k string) string { return env[k] })
+	require.NoError(t, err)
+
+	got := r.Redact("access: eyJhbGciOi.payload.sig\nkey: tok-secret-42\nuser: alice\n")
+	assert.Equal(t, "access: ***REDACTED***\nkey: ***REDACTED***\nuser: alice\n", got)
+}
+
+func TestRedactor_InvalidPattern(t *testing.T) {
+	_, err := NewRedactor([]string{"("}, nil, func(string) string { return "" })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid redact pattern")
+}
+
+func TestRedactor_NilIsNoop(t *testing.T) {
+	var r *Redactor
+	cmd := RecordedCommand{Stdout: "token=abc", Stderr: "err"}
+	r.RedactCommand(&cmd)
+	assert.Equal(t, "token=abc", cmd.Stdout)
+	assert.Equal(t, "token=abc", r.Redact("token=abc"))
+}
+
+func TestRecordingSession_Finalize_Redacts(t *testing.T) {
+	session, err := New(SessionMetadata{Name: "redact-test"}, []string{"az"}, newTestPlatform())
+	require.NoError(t, err)
+	defer session.Cleanup() //nolint:errcheck // test cleanup
+
+	session.Redactor, err = NewRedactor([]string{`"accessToken": "[^"]+"`}, nil, func(string) string { return "" })
+	require.NoError(t, err)
+
+	logContent := `{"timestamp":"2024-01-15T10:30:00Z","argv":["az","account","get-access-token"],"exit":0,"stdout":"{\"accessToken\": \"fake-token-123\", \"tenant\": \"t1\"}\n","stderr":""}
+`
+	require.NoError(t, os.WriteFile(session.LogFile, []byte(logContent), 0600))
+	require.NoError(t, session.Finalize())
+
+	require.Len(t, session.Commands, 1)
+	assert.NotContains(t, session.Commands[0].Stdout, "fake-token-123")
+	assert.Equal(t, "{***REDACTED***, \"tenant\": \"t1\"}\n", session.Commands[0].Stdout)
+}
+
+func TestRedactor_RedactCommand_EnvAndStdin(t *testing.T) {
+	env := map[string]string{"AZURE_TOKEN": "tok-secret-42"}
+	r, err := NewRedactor(nil, []string{"AZURE_TOKEN"}, func(k string) string { return env[k] })
+	require.NoError(t, err)
+
+	cmd := RecordedCommand{
+		Stdin: "token=tok-secret-42\n",
+		Env:   map[string]string{"AZURE_TOKEN": "tok-secret-42", "REGION": "eastus"},
+	}
+	r.RedactCommand(&cmd)
+	assert.Equal(t, "token=***REDACTED***\n", cmd.Stdin)
+	assert.Equal(t, map[string]string{"AZURE_TOKEN": RedactedPlaceholder, "REGION": "eastus"}, cmd.Env)
+}
@@ -1,6 +1,9 @@
 package recorder
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +16,15 @@ import (
 	"github.com/ormasoftchile/cli-replay/internal/platform"
 )
 
+// maxRecordedStdinBytes caps how much piped stdin is stored in a recorded
+// step, matching the 1 MB limit applied when replaying match.stdin. Larger
+// inputs are still passed to the command but not recorded.
+const maxRecordedStdinBytes = 1 << 20
+
+// stdinWaitDelay bounds how long Execute waits for the stdin copy after the
+// recorded command exits (e.g. when stdin is an open pipe the command never read).
+const stdinWaitDelay = 100 * time.Millisecond
+
 // SessionMetadata contains user-provided metadata for the generated scenario.
 type SessionMetadata struct {
 	Name        string
@@ -38,10 +50,22 @@ type RecordingSession struct {
 	EndTime   time.Time
 	Commands  []RecordedCommand
 	Filters   []string
+	// RecordEnv lists environment variables whose values are snapshotted
+	// into each recorded command (and emitted as match.env).
+	RecordEnv []string
 	ShimDir   string
 	LogFile   string
 	Metadata  SessionMetadata
-	platform  platform.Platform
+	// Redactor, when set, scrubs secrets from captured stdout/stderr.
+	Redactor *Redactor
+	// Normalizer, when set, replaces volatile values (timestamps,
+	// durations) in captured stdout/stderr with stable placeholders.
+	Normalizer *Normalizer
+	// CaptureTiming makes Finalize set each command's Delay from the log
+	// timestamps, capped at MaxCapturedDelay when it is positive.
+	CaptureTiming    bool
+	MaxCapturedDelay time.Duration
+	platform         platform.Platform
 }
 
 // New creates a new RecordingSession with the given metadata, filters, and platform.
@@ -88,6 +112,27 @@ func New(metadata SessionMetadata, filters []string, plat platform.Platform) (*R
 	return session, nil
 }
 
+// HashedName derives a scenario name from the recorded command sequence:
+// "recorded-" followed by a short hash of the argvs in order. Recording the
+// same commands again yields the same name, unlike the timestamp default.
+func HashedName(commands []RecordedCommand) string {
+	h := sha256.New()
+	for _, c := range commands {
+		for _, arg := range c.Argv {
+			_, _ = io.WriteString(h, arg)
+			_, _ = h.Write([]byte{0})
+		}
+		_, _ = h.Write([]byte{'\n'})
+	}
+	return "recorded-" + hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// UseHashedName replaces the session's scenario name with HashedName of the
+// commands recorded so far. Call it after Execute (and Finalize in shim mode).
+func (s *RecordingSession) UseHashedName() {
+	s.Metadata.Name = HashedName(s.Commands)
+}
+
 // Finalize marks the session as complete and performs cleanup.
 func (s *RecordingSession) Finalize() error {
 	s.EndTime = time.Now().UTC()
@@ -106,11 +151,35 @@ func (s *RecordingSession) Finalize() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse recorded commands: %w", err)
 	}
+	for i := range commands {
+		s.Redactor.RedactCommand(&commands[i])
+		s.Normalizer.NormalizeCommand(&commands[i])
+	}
+	if s.CaptureTiming {
+		captureTiming(commands, s.MaxCapturedDelay)
+	}
 
 	s.Commands = commands
 	return nil
 }
 
+// captureTiming sets the Delay of every command after the first to the
+// time since the previous command started, rounded to milliseconds and
+// capped at maxDelay when it is positive. Commands logged out of start
+// order, as concurrent ones can be, get no delay.
+func captureTiming(commands []RecordedCommand, maxDelay time.Duration) {
+	for i := 1; i < len(commands); i++ {
+		gap := commands[i].Timestamp.Sub(commands[i-1].Timestamp).Round(time.Millisecond)
+		if gap <= 0 {
+			continue
+		}
+		if maxDelay > 0 && gap > maxDelay {
+			gap = maxDelay
+		}
+		commands[i].Delay = gap
+	}
+}
+
 // Cleanup removes the temporary shim directory and all its contents.
 func (s *RecordingSession) Cleanup() error {
 	if s.ShimDir != "" {
@@ -146,6 +215,11 @@ func (s *RecordingSession) SetupShims() error {
 		if err := os.WriteFile(shimFile.EntryPointPath, []byte(shimFile.Content), shimFile.FileMode); err != nil {
 			return fmt.Errorf("failed to write shim for %s: %w", cmd, err)
 		}
+		// WriteFile applies the umask and leaves an existing file's mode
+		// as it was; shims must be executable regardless
+		if err := os.Chmod(shimFile.EntryPointPath, shimFile.FileMode); err != nil {
+			return fmt.Errorf("failed to write shim for %s: %w", cmd, err)
+		}
 
 		// Write companion file if present (Windows dual-file shim pattern)
 		if shimFile.CompanionPath != "" && shimFile.CompanionContent != "" {
@@ -198,6 +272,9 @@ func (s *RecordingSession) executeWithShims(args []string, stdout, stderr io.Wri
 		"CLI_REPLAY_RECORDING_LOG="+s.LogFile,
 		"CLI_REPLAY_SHIM_DIR="+s.ShimDir,
 	)
+	if len(s.RecordEnv) > 0 {
+		newEnv = append(newEnv, "CLI_REPLAY_RECORD_ENV="+strings.Join(s.RecordEnv, ","))
+	}
 
 	// Delegate shell wrapping to the platform
 	command := s.platform.WrapCommand(args, newEnv)
@@ -228,9 +305,22 @@ func (s *RecordingSession) executeAndCapture(args []string, stdout, stderr io.Wr
 	var outBuf, errBuf strings.Builder
 	command.Stdout = io.MultiWriter(stdout, &outBuf)
 	command.Stderr = io.MultiWriter(stderr, &errBuf)
-	command.Stdin = os.Stdin
+
+	// Tee piped stdin so it can be recorded as match.stdin; terminals are
+	// passed through untouched.
+	var inBuf *cappedBuffer
+	if stdinIsPiped() {
+		inBuf = &cappedBuffer{limit: maxRecordedStdinBytes}
+		command.Stdin = io.TeeReader(os.Stdin, inBuf)
+		command.WaitDelay = stdinWaitDelay
+	} else {
+		command.Stdin = os.Stdin
+	}
 
 	runErr := command.Run()
+	if errors.Is(runErr, exec.ErrWaitDelay) {
+		runErr = nil // command succeeded; only the stdin copy was cut short
+	}
 
 	exitCode := 0
 	if runErr != nil {
@@ -248,14 +338,72 @@ func (s *RecordingSession) executeAndCapture(args []string, stdout, stderr io.Wr
 		ExitCode:  exitCode,
 		Stdout:    outBuf.String(),
 		Stderr:    errBuf.String(),
+		Env:       snapshotEnv(s.RecordEnv),
+	}
+	if inBuf != nil {
+		if inBuf.overflow {
+			_, _ = fmt.Fprintln(stderr, "cli-replay: stdin larger than 1 MB not recorded")
+		} else {
+			recorded.Stdin = inBuf.buf.String()
+		}
 	}
+	s.Redactor.RedactCommand(&recorded)
+	s.Normalizer.NormalizeCommand(&recorded)
 
 	s.Commands = append(s.Commands, recorded)
 
 	// Also write to JSONL log for consistency
-	if err := LogRecording(s.LogFile, recorded.Timestamp, recorded.Argv, recorded.ExitCode, recorded.Stdout, recorded.Stderr, recorded.Stdin); err != nil {
+	if err := LogRecordedCommand(s.LogFile, recorded); err != nil {
 		return exitCode, fmt.Errorf("failed to write recording log: %w", err)
 	}
 
 	return exitCode, nil
 }
+
+// snapshotEnv returns the current values of the named environment variables,
+// omitting unset ones. Returns nil when nothing was captured.
+func snapshotEnv(names []string) map[string]string {
+	var env map[string]string
+	for _, name := range names {
+		if v, ok := os.LookupEnv(name); ok {
+			if env == nil {
+				env = make(map[string]string, len(names))
+			}
+			env[name] = v
+		}
+	}
+	return env
+}
+
+// stdinIsPiped reports whether os.Stdin is a pipe or regular file rather
+// than a terminal or character device such as /dev/null.
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// cappedBuffer collects writes up to limit bytes. Once the limit would be
+// exceeded it discards the content and records the overflow, since a
+// truncated stdin could never match on replay.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+// Write implements io.Writer. It never fails, so it cannot disturb the
+// stream it is teed from.
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	if c.overflow {
+		return len(p), nil
+	}
+	if c.buf.Len()+len(p) > c.limit {
+		c.overflow = true
+		c.buf.Reset()
+		return len(p), nil
+	}
+	return c.buf.Write(p)
+}
@@ -238,6 +238,41 @@ func TestRecordingSession_Finalize(t *testing.T) {
 	assert.Equal(t, "Name: pod1\n", session.Commands[1].Stdout)
 }
 
+func TestRecordingSession_Finalize_CaptureTiming(t *testing.T) {
+	session, err := New(SessionMetadata{Name: "timing", RecordedAt: time.Now()}, []string{"kubectl"}, newTestPlatform())
+	require.NoError(t, err)
+	defer session.Cleanup() //nolint:errcheck // test cleanup
+	session.CaptureTiming = true
+	session.MaxCapturedDelay = 30 * time.Second
+
+	logContent := `{"timestamp":"2024-01-15T10:30:00Z","argv":["kubectl","get","pods"],"exit":0,"stdout":"","stderr":""}
+{"timestamp":"2024-01-15T10:30:01.2504Z","argv":["kubectl","apply"],"exit":0,"stdout":"","stderr":""}
+{"timestamp":"2024-01-15T11:30:00Z","argv":["kubectl","rollout"],"exit":0,"stdout":"","stderr":""}
+{"timestamp":"2024-01-15T11:29:59Z","argv":["kubectl","logs"],"exit":0,"stdout":"","stderr":""}
+`
+	require.NoError(t, os.WriteFile(session.LogFile, []byte(logContent), 0600))
+	require.NoError(t, session.Finalize())
+
+	require.Len(t, session.Commands, 4)
+	assert.Zero(t, session.Commands[0].Delay, "the first command has no previous one")
+	assert.Equal(t, 1250*time.Millisecond, session.Commands[1].Delay, "rounded to milliseconds")
+	assert.Equal(t, 30*time.Second, session.Commands[2].Delay, "capped at MaxCapturedDelay")
+	assert.Zero(t, session.Commands[3].Delay, "a command logged out of start order gets no delay")
+}
+
+func TestRecordingSession_Finalize_NoTimingByDefault(t *testing.T) {
+	session, err := New(SessionMetadata{Name: "no-timing", RecordedAt: time.Now()}, []string{"kubectl"}, newTestPlatform())
+	require.NoError(t, err)
+	defer session.Cleanup() //nolint:errcheck // test cleanup
+
+	logContent := `{"timestamp":"2024-01-15T10:30:00Z","argv":["kubectl","get","pods"],"exit":0,"stdout":"","stderr":""}
+{"timestamp":"2024-01-15T10:30:05Z","argv":["kubectl","apply"],"exit":0,"stdout":"","stderr":""}
+`
+	require.NoError(t, os.WriteFile(session.LogFile, []byte(logContent), 0600))
+	require.NoError(t, session.Finalize())
+	assert.Zero(t, session.Commands[1].Delay)
+}
+
 func TestRecordingSession_Finalize_AlreadyFinalized(t *testing.T) {
 	meta := SessionMetadata{
 		Name:        "double-finalize-test",
@@ -504,3 +539,93 @@ func TestRecordingSession_New_DefaultName(t *testing.T) {
 	// Name should have been auto-generated
 	assert.Contains(t, session.Metadata.Name, "recorded-session-")
 }
+
+func TestHashedName_StableForSameCommands(t *testing.T) {
+	record := func(args ...string) string {
+		t.Helper()
+		session, err := New(SessionMetadata{RecordedAt: time.Now()}, nil, newTestPlatform())
+		require.NoError(t, err)
+		defer session.Cleanup() //nolint:errcheck // test cleanup
+		var stdout, stderr bytes.Buffer
+		_, err = session.Execute(args, &stdout, &stderr)
+		require.NoError(t, err)
+		session.UseHashedName()
+		return session.Metadata.Name
+	}
+
+	first := record("echo", "hello")
+	second := record("echo", "hello")
+	other := record("echo", "goodbye")
+
+	assert.Regexp(t, `^recorded-[0-9a-f]{12}$`, first)
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+}
+
+func TestHashedName_ArgvBoundaries(t *testing.T) {
+	a := HashedName([]RecordedCommand{{Argv: []string{"ab", "c"}}})
+	b := HashedName([]RecordedCommand{{Argv: []string{"a", "bc"}}})
+	c := HashedName([]RecordedCommand{{Argv: []string{"a"}}, {Argv: []string{"bc"}}})
+	assert.NotEqual(t, a, b)
+	assert.NotEqual(t, b, c)
+}
+
+// withStdin replaces os.Stdin with a regular file holding content for the
+// duration of the test.
+func withStdin(t *testing.T, content []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	f, err := os.Open(path) //nolint:gosec // test file path
+	require.NoError(t, err)
+	orig := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() {
+		os.Stdin = orig
+		_ = f.Close()
+	})
+}
+
+func TestRecordingSession_Execute_RecordsStdin(t *testing.T) {
+	if isWindows() {
+		t.Skip("uses cat")
+	}
+	withStdin(t, []byte("apiVersion: v1\nkind: Pod\n"))
+
+	session, err := New(SessionMetadata{Name: "stdin-test"}, []string{}, newTestPlatform())
+	require.NoError(t, err)
+	defer session.Cleanup() //nolint:errcheck // test cleanup
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := session.Execute([]string{"cat"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "apiVersion: v1\nkind: Pod\n", stdout.String(), "stdin must still reach the command")
+
+	require.Len(t, session.Commands, 1)
+	assert.Equal(t, "apiVersion: v1\nkind: Pod\n", session.Commands[0].Stdin)
+
+	sc, err := ConvertToScenario(session.Metadata, session.Commands)
+	require.NoError(t, err)
+	assert.Equal(t, "apiVersion: v1\nkind: Pod\n", sc.Steps[0].Step.Match.Stdin)
+}
+
+func TestRecordingSession_Execute_OversizedStdinNotRecorded(t *testing.T) {
+	if isWindows() {
+		t.Skip("uses cat")
+	}
+	withStdin(t, bytes.Repeat([]byte("x"), maxRecordedStdinBytes+1))
+
+	session, err := New(SessionMetadata{Name: "stdin-cap"}, []string{}, newTestPlatform())
+	require.NoError(t, err)
+	defer session.Cleanup() //nolint:errcheck // test cleanup
+
+	var stdout, stderr bytes.Buffer
+	_, err = session.Execute([]string{"cat"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, maxRecordedStdinBytes+1, stdout.Len())
+
+	require.Len(t, session.Commands, 1)
+	assert.Empty(t, session.Commands[0].Stdin)
+	assert.Contains(t, stderr.String(), "stdin larger than 1 MB not recorded")
+}
@@ -15,21 +15,20 @@ import (
 // and the Encoding field is set to "base64" (FR-015).
 // stdin is included in the entry when non-empty (captured from piped input).
 func LogRecording(logPath string, timestamp time.Time, argv []string, exitCode int, stdout, stderr, stdin string) error {
-	entry := RecordingEntry{
-		Timestamp: timestamp.Format(time.RFC3339),
+	return LogRecordedCommand(logPath, RecordedCommand{
+		Timestamp: timestamp,
 		Argv:      argv,
-		Exit:      exitCode,
+		ExitCode:  exitCode,
 		Stdout:    stdout,
 		Stderr:    stderr,
 		Stdin:     stdin,
-	}
+	})
+}
 
-	// If either stdout or stderr contains non-UTF-8 bytes, base64-encode both
-	if !utf8.ValidString(stdout) || !utf8.ValidString(stderr) {
-		entry.Stdout = base64.StdEncoding.EncodeToString([]byte(stdout))
-		entry.Stderr = base64.StdEncoding.EncodeToString([]byte(stderr))
-		entry.Encoding = "base64"
-	}
+// LogRecordedCommand appends cmd, including its env snapshot, to the JSONL
+// log file using the same encoding rules as LogRecording.
+func LogRecordedCommand(logPath string, cmd RecordedCommand) error {
+	entry := newRecordingEntry(cmd)
 
 	// Open log file in append mode
 	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) //nolint:gosec // log file needs to be readable
@@ -46,3 +45,24 @@ func LogRecording(logPath string, timestamp time.Time, argv []string, exitCode i
 
 	return nil
 }
+
+// newRecordingEntry converts cmd to its JSONL representation. If either
+// stdout or stderr contains non-UTF-8 bytes, both are base64-encoded.
+func newRecordingEntry(cmd RecordedCommand) RecordingEntry {
+	entry := RecordingEntry{
+		Timestamp: cmd.Timestamp.Format(time.RFC3339Nano),
+		Argv:      cmd.Argv,
+		Exit:      cmd.ExitCode,
+		Stdout:    cmd.Stdout,
+		Stderr:    cmd.Stderr,
+		Stdin:     cmd.Stdin,
+		Env:       cmd.Env,
+	}
+
+	if !utf8.ValidString(cmd.Stdout) || !utf8.ValidString(cmd.Stderr) {
+		entry.Stdout = base64.StdEncoding.EncodeToString([]byte(cmd.Stdout))
+		entry.Stderr = base64.StdEncoding.EncodeToString([]byte(cmd.Stderr))
+		entry.Encoding = "base64"
+	}
+	return entry
+}
@@ -111,3 +111,26 @@ func TestLogRecording_EmptyStdinOmitted(t *testing.T) {
 	// Stdin should not appear in JSON when empty (omitempty)
 	assert.NotContains(t, string(content), "stdin")
 }
+
+func TestLogRecordedCommand_EnvRoundTrip(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "env.jsonl")
+
+	cmd := RecordedCommand{
+		Timestamp: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		Argv:      []string{"az", "group", "list"},
+		Stdout:    "[]\n",
+		Env:       map[string]string{"AZURE_SUBSCRIPTION": "sub-1"},
+	}
+	require.NoError(t, LogRecordedCommand(logPath, cmd))
+
+	log, err := ReadRecordingLog(logPath)
+	require.NoError(t, err)
+	commands, err := log.ToRecordedCommands()
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	assert.Equal(t, cmd.Env, commands[0].Env)
+
+	sc, err := ConvertToScenario(SessionMetadata{Name: "env", RecordedAt: cmd.Timestamp}, commands)
+	require.NoError(t, err)
+	assert.Equal(t, cmd.Env, sc.Steps[0].Step.Match.Env)
+}
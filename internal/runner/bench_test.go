@@ -55,7 +55,7 @@ func BenchmarkReplayOrchestration_100(b *testing.B) {
 					Argv: []string{"kubectl", "get", fmt.Sprintf("resource-%d", i)},
 				},
 				Respond: scenario.Response{
-					Exit:   0,
+					Exit:   scenario.Exit(0),
 					Stdout: fmt.Sprintf("output line %d\n", i),
 				},
 			},
@@ -66,6 +66,20 @@ func BuildChildEnv(interceptDir, sessionID, scenarioPath string) []string {
 	return result
 }
 
+// SetEnv returns env with key set to value, replacing any existing entry
+// for key (case-insensitively on Windows) rather than appending a duplicate.
+func SetEnv(env []string, key, value string) []string {
+	result := make([]string, 0, len(env)+1)
+	for _, e := range env {
+		k, _, ok := splitEnvVar(e)
+		if ok && (k == key || (runtime.GOOS == "windows" && strings.EqualFold(k, key))) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return append(result, key+"="+value)
+}
+
 // splitEnvVar splits an environment variable string "KEY=VALUE" into key and value.
 // Returns false if the string doesn't contain '='.
 func splitEnvVar(env string) (key, value string, ok bool) {
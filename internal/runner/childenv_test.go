@@ -115,3 +115,11 @@ func envToMap(env []string) map[string]string {
 	}
 	return m
 }
+
+func TestSetEnv_ReplacesExisting(t *testing.T) {
+	env := SetEnv([]string{"A=1", "CLI_REPLAY_SEED=7", "B=2"}, "CLI_REPLAY_SEED", "42")
+	assert.Equal(t, []string{"A=1", "B=2", "CLI_REPLAY_SEED=42"}, env)
+
+	env = SetEnv([]string{"A=1"}, "CLI_REPLAY_SEED", "42")
+	assert.Equal(t, []string{"A=1", "CLI_REPLAY_SEED=42"}, env)
+}
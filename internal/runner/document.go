@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// DocumentEnvVar holds the 0-based index of the document a session replays
+// from a multi-document scenario file. run and exec set it from --index or
+// --name; when unset, the first document is used.
+const DocumentEnvVar = "CLI_REPLAY_DOCUMENT"
+
+// LoadScenario loads the scenario at absPath, selecting the document named
+// by CLI_REPLAY_DOCUMENT.
+func LoadScenario(absPath string) (*scenario.Scenario, error) {
+	if strings.TrimSpace(os.Getenv(DocumentEnvVar)) == "" {
+		return scenario.LoadFile(absPath)
+	}
+	index, err := documentIndex()
+	if err != nil {
+		return nil, err
+	}
+	docs, err := scenario.LoadFileAll(absPath)
+	if err != nil {
+		return nil, err
+	}
+	scn, _, err := scenario.DocumentSelector{Index: index}.Select(docs)
+	return scn, err
+}
+
+// documentIndex returns the document index named by CLI_REPLAY_DOCUMENT,
+// or 0 when it is unset.
+func documentIndex() (int, error) {
+	v := strings.TrimSpace(os.Getenv(DocumentEnvVar))
+	if v == "" {
+		return 0, nil
+	}
+	index, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: must be a document index", DocumentEnvVar, v)
+	}
+	return index, nil
+}
@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMultiDocScenario(t *testing.T) string {
+	t.Helper()
+	scenarioPath := filepath.Join(t.TempDir(), "scenarios.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: deploy
+steps:
+  - match:
+      argv: ["kubectl", "apply"]
+    respond:
+      stdout: "applied\n"
+---
+meta:
+  name: teardown
+steps:
+  - match:
+      argv: ["kubectl", "delete"]
+    respond:
+      stdout: "deleted\n"
+`), 0600))
+	return scenarioPath
+}
+
+func TestLoadScenario_DocumentEnvVar(t *testing.T) {
+	scenarioPath := writeMultiDocScenario(t)
+
+	t.Setenv(DocumentEnvVar, "")
+	scn, err := LoadScenario(scenarioPath)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", scn.Meta.Name)
+
+	t.Setenv(DocumentEnvVar, "1")
+	scn, err = LoadScenario(scenarioPath)
+	require.NoError(t, err)
+	assert.Equal(t, "teardown", scn.Meta.Name)
+
+	t.Setenv(DocumentEnvVar, "2")
+	_, err = LoadScenario(scenarioPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of range")
+
+	t.Setenv(DocumentEnvVar, "teardown")
+	_, err = LoadScenario(scenarioPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be a document index")
+}
+
+func TestExecuteReplay_SelectedDocument(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv(DocumentEnvVar, "1")
+	scenarioPath := writeMultiDocScenario(t)
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "delete"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "teardown", result.ScenarioName)
+	assert.Equal(t, "deleted\n", stdout.String())
+}
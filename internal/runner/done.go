@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// doneSuffix names completion markers in the .cli-replay/ directory.
+const doneSuffix = ".done"
+
+// DoneMarker is the content of the completion marker `exec` writes once a
+// scenario has every step's min count met, so wrapping scripts can detect
+// completion without parsing stderr.
+type DoneMarker struct {
+	Scenario      string    `json:"scenario"`
+	Session       string    `json:"session"`
+	StepsConsumed int       `json:"steps_consumed"`
+	FinishedAt    time.Time `json:"finished_at"`
+}
+
+// DoneFilePath returns the default completion marker path for a session:
+// .cli-replay/<session>.done next to the scenario file.
+func DoneFilePath(scenarioPath, session string) string {
+	return filepath.Join(cliReplayDir(scenarioPath), session+doneSuffix)
+}
+
+// WriteDoneMarker writes m as JSON to path, creating its directory. The
+// file is written to a temporary name and renamed, so readers never see a
+// partial marker.
+func WriteDoneMarker(path string, m DoneMarker) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create done marker directory: %w", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal done marker: %w", err)
+	}
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write done marker: %w", err)
+	}
+	if err := os.Rename(tmpFile, path); err != nil {
+		_ = os.Remove(tmpFile)
+		return fmt.Errorf("failed to write done marker: %w", err)
+	}
+	return nil
+}
+
+// ReadDoneMarker reads a completion marker written by WriteDoneMarker.
+func ReadDoneMarker(path string) (*DoneMarker, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // marker path is derived from the scenario or --done-file
+	if err != nil {
+		return nil, err
+	}
+	var m DoneMarker
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse done marker: %w", err)
+	}
+	return &m, nil
+}
+
+// isDoneFile reports whether name is a completion marker.
+func isDoneFile(name string) bool {
+	return strings.HasSuffix(name, doneSuffix) && len(name) > len(doneSuffix)
+}
@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoneMarker_RoundTrip(t *testing.T) {
+	scenarioPath := filepath.Join(t.TempDir(), "scenario.yaml")
+	path := DoneFilePath(scenarioPath, "abc123")
+	assert.Equal(t, filepath.Join(filepath.Dir(scenarioPath), ".cli-replay", "abc123.done"), path)
+
+	finished := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, WriteDoneMarker(path, DoneMarker{
+		Scenario: "deploy", Session: "abc123", StepsConsumed: 3, FinishedAt: finished,
+	}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"steps_consumed": 3`)
+	assert.Contains(t, string(data), `"finished_at": "2026-01-02T03:04:05Z"`)
+
+	m, err := ReadDoneMarker(path)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", m.Scenario)
+	assert.Equal(t, "abc123", m.Session)
+	assert.Equal(t, 3, m.StepsConsumed)
+	assert.True(t, finished.Equal(m.FinishedAt))
+}
+
+func TestCleanExpiredSessions_RemovesOldDoneMarkers(t *testing.T) {
+	dir := t.TempDir()
+	oldMarker := filepath.Join(dir, "old.done")
+	newMarker := filepath.Join(dir, "new.done")
+	require.NoError(t, WriteDoneMarker(oldMarker, DoneMarker{Scenario: "a"}))
+	require.NoError(t, WriteDoneMarker(newMarker, DoneMarker{Scenario: "b"}))
+	past := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(oldMarker, past, past))
+
+	cleaned, err := CleanExpiredSessions(dir, time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, cleaned, "markers are not sessions")
+	assert.NoFileExists(t, oldMarker)
+	assert.FileExists(t, newMarker)
+}
@@ -21,6 +21,9 @@ type DryRunReport struct {
 	AllowlistIssues []string
 	TemplateVars    []string
 	SessionTTL      string
+	// Simulation, when set, is the trace of a command log replayed against
+	// the scenario (run --dry-run --simulate-file).
+	Simulation *SimulationTrace
 }
 
 // DryRunStep contains per-step information for dry-run display.
@@ -28,6 +31,7 @@ type DryRunStep struct {
 	Index         int
 	MatchArgv     string
 	Exit          int
+	ExitTemplate  string
 	StdoutPreview string
 	CallsMin      int
 	CallsMax      int
@@ -63,11 +67,10 @@ func BuildDryRunReport(scn *scenario.Scenario) *DryRunReport {
 	seen := make(map[string]bool)
 	var commands []string
 	for _, step := range flatSteps {
-		if len(step.Match.Argv) > 0 {
-			cmd := step.Match.Argv[0]
-			if !seen[cmd] {
-				seen[cmd] = true
-				commands = append(commands, cmd)
+		for _, argv := range step.Match.Alternatives() {
+			if len(argv) > 0 && !seen[argv[0]] {
+				seen[argv[0]] = true
+				commands = append(commands, argv[0])
 			}
 		}
 	}
@@ -121,8 +124,9 @@ func BuildDryRunReport(scn *scenario.Scenario) *DryRunReport {
 
 		steps[i] = DryRunStep{
 			Index:         i,
-			MatchArgv:     strings.Join(step.Match.Argv, " "),
-			Exit:          step.Respond.Exit,
+			MatchArgv:     strings.Join(step.Match.PrimaryArgv(), " "),
+			Exit:          step.Respond.EffectiveExit(),
+			ExitTemplate:  step.Respond.ExitTemplate,
 			StdoutPreview: preview,
 			CallsMin:      bounds.Min,
 			CallsMax:      bounds.Max,
@@ -213,6 +217,9 @@ func FormatDryRunReport(report *DryRunReport, w io.Writer) error {
 
 		// Detail line
 		detailParts := []string{fmt.Sprintf("exit %d", step.Exit)}
+		if step.ExitTemplate != "" {
+			detailParts[0] = fmt.Sprintf("exit %s", step.ExitTemplate)
+		}
 		if step.StdoutPreview != "" {
 			detailParts = append(detailParts, fmt.Sprintf("stdout: %s", step.StdoutPreview))
 		}
@@ -240,6 +247,10 @@ func FormatDryRunReport(report *DryRunReport, w io.Writer) error {
 
 	_, _ = fmt.Fprintln(w, "\u2713 No validation errors")
 
+	if report.Simulation != nil {
+		FormatSimulationTrace(report.Simulation, w)
+	}
+
 	return nil
 }
 
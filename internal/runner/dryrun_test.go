@@ -19,11 +19,11 @@ func TestBuildDryRunReport_LinearScenario(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"az", "group", "create"}},
-				Respond: scenario.Response{Exit: 0, Stdout: "created"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "created"},
 			}},
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"az", "vm", "create"}},
-				Respond: scenario.Response{Exit: 0, Stdout: "provisioned"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "provisioned"},
 			}},
 		},
 	}
@@ -48,7 +48,7 @@ func TestBuildDryRunReport_GroupedScenario(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"setup"}},
-				Respond: scenario.Response{Exit: 0, Stdout: "ready"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "ready"},
 			}},
 			{Group: &scenario.StepGroup{
 				Mode: "unordered",
@@ -56,11 +56,11 @@ func TestBuildDryRunReport_GroupedScenario(t *testing.T) {
 				Steps: []scenario.StepElement{
 					{Step: &scenario.Step{
 						Match:   scenario.Match{Argv: []string{"kubectl", "get", "pods"}},
-						Respond: scenario.Response{Exit: 0, Stdout: "pod-1"},
+						Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "pod-1"},
 					}},
 					{Step: &scenario.Step{
 						Match:   scenario.Match{Argv: []string{"kubectl", "get", "svc"}},
-						Respond: scenario.Response{Exit: 0, Stdout: "svc-1"},
+						Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "svc-1"},
 					}},
 				},
 			}},
@@ -89,7 +89,7 @@ func TestBuildDryRunReport_WithCaptures(t *testing.T) {
 			{Step: &scenario.Step{
 				Match: scenario.Match{Argv: []string{"cmd"}},
 				Respond: scenario.Response{
-					Exit:    0,
+					Exit:    scenario.Exit(0),
 					Stdout:  "result",
 					Capture: map[string]string{"rg_id": "rg-1", "vm_id": "vm-1"},
 				},
@@ -116,11 +116,11 @@ func TestBuildDryRunReport_WithAllowlist(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"kubectl", "get", "pods"}},
-				Respond: scenario.Response{Exit: 0},
+				Respond: scenario.Response{Exit: scenario.Exit(0)},
 			}},
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"az", "group", "create"}},
-				Respond: scenario.Response{Exit: 0},
+				Respond: scenario.Response{Exit: scenario.Exit(0)},
 			}},
 		},
 	}
@@ -138,7 +138,7 @@ func TestBuildDryRunReport_StdoutFilePreview(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 0, StdoutFile: "fixtures/output.txt"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), StdoutFile: "fixtures/output.txt"},
 			}},
 		},
 	}
@@ -157,7 +157,7 @@ func TestBuildDryRunReport_SessionTTL(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 0},
+				Respond: scenario.Response{Exit: scenario.Exit(0)},
 			}},
 		},
 	}
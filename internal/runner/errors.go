@@ -3,6 +3,7 @@ package runner
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/ormasoftchile/cli-replay/pkg/matcher"
@@ -60,6 +61,30 @@ func bold(s string, c colorMode) string {
 	return s
 }
 
+func underline(s string, c colorMode) string {
+	if c == colorOn {
+		return "\033[4m" + s + "\033[0m"
+	}
+	return s
+}
+
+// errorWidth returns the column width argv lines are wrapped to: COLUMNS if
+// set, else the width of the stderr terminal. Returns 0 (no wrapping) when
+// neither is available, so piped and CI output stays on one line.
+func errorWidth() int {
+	if v := os.Getenv("COLUMNS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		if w, _, err := term.GetSize(int(os.Stderr.Fd())); err == nil && w > 0 {
+			return w
+		}
+	}
+	return 0
+}
+
 // maxTruncatedArgv is the maximum number of argv elements shown before truncation.
 const maxTruncatedArgv = 12
 
@@ -73,38 +98,120 @@ func formatArgv(argv []string) string {
 	return fmt.Sprintf("%v  ...+%d more", shown, len(argv)-maxTruncatedArgv)
 }
 
-// FormatMismatchError formats a MismatchError for user-friendly output.
-// Uses ElementMatchDetail for per-element diff, shows template patterns,
-// and handles length mismatches with detailed position info.
+// formatArgvLine renders argv after prefix the way formatArgv does, painting
+// each element and highlighting the element at index hl (-1 for none).
+// With width > 0, elements that would run past width continue on a new line
+// aligned under the opening bracket.
+func formatArgvLine(prefix string, argv []string, hl int, paint func(string, colorMode) string, color colorMode, width int) string {
+	shown := argv
+	if len(shown) > maxTruncatedArgv {
+		shown = argv[:maxTruncatedArgv]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix + "[")
+	col := len(prefix) + 1
+	indent := strings.Repeat(" ", len(prefix)+1)
+	for i, a := range shown {
+		tok := paint(a, color)
+		if i == hl {
+			tok = bold(underline(tok, color), color)
+		}
+		need := 1 + len(a)
+		if i == len(shown)-1 {
+			need++ // closing bracket
+		}
+		if i > 0 {
+			if width > 0 && col+need > width {
+				sb.WriteString("\n" + indent)
+				col = len(indent)
+			} else {
+				sb.WriteString(" ")
+				col++
+			}
+		}
+		sb.WriteString(tok)
+		col += len(a)
+	}
+	sb.WriteString("]")
+	if len(argv) > maxTruncatedArgv {
+		fmt.Fprintf(&sb, "  ...+%d more", len(argv)-maxTruncatedArgv)
+	}
+	return sb.String()
+}
+
+// FormatMismatchError formats a MismatchError for user-friendly output,
+// colorized when stderr is a terminal (see resolveColor).
 func FormatMismatchError(err *MismatchError) string {
-	color := resolveColor()
+	return FormatMismatchErrorColor(err, resolveColor() == colorOn)
+}
+
+// FormatMismatchErrorColor formats a MismatchError with ANSI color explicitly
+// enabled or disabled. Uses ElementMatchDetail for per-element diff, shows
+// template patterns, and handles length mismatches with detailed position
+// info. Expected argv is shown in green and received argv in red, with the
+// first differing element underlined; long argv wraps to errorWidth.
+func FormatMismatchErrorColor(err *MismatchError, colorEnabled bool) string {
+	color := colorOff
+	if colorEnabled {
+		color = colorOn
+	}
+	width := errorWidth()
 	var sb strings.Builder
 
 	// Header: 1-based step number
-	sb.WriteString(bold(fmt.Sprintf("Mismatch at step %d of %q:\n",
-		err.StepIndex+1, err.Scenario), color))
+	if err.GroupName != "" {
+		sb.WriteString(bold(fmt.Sprintf("Mismatch at step %d of %q (ordered group %q):\n",
+			err.StepIndex+1, err.Scenario, err.GroupName), color))
+	} else {
+		sb.WriteString(bold(fmt.Sprintf("Mismatch at step %d of %q:\n",
+			err.StepIndex+1, err.Scenario), color))
+	}
 	sb.WriteString("\n")
 
+	// Find first divergence using element-level matching
+	diffPos := findFirstDiff(err.Expected, err.Received)
+
 	// Full expected/received argv
-	sb.WriteString(fmt.Sprintf("  Expected: %s", formatArgv(err.Expected)))
+	sb.WriteString(formatArgvLine("  Expected: ", err.Expected, diffPos, green, color, width))
 	if len(err.Expected) != len(err.Received) {
 		sb.WriteString(fmt.Sprintf("  (%d args)", len(err.Expected)))
 	}
 	sb.WriteString("\n")
 
-	sb.WriteString(fmt.Sprintf("  Received: %s", formatArgv(err.Received)))
+	sb.WriteString(formatArgvLine("  Received: ", err.Received, diffPos, red, color, width))
 	if len(err.Expected) != len(err.Received) {
 		sb.WriteString(fmt.Sprintf("  (%d args)", len(err.Received)))
 	}
 	sb.WriteString("\n")
 
-	// Find first divergence using element-level matching
-	diffPos := findFirstDiff(err.Expected, err.Received)
+	// The diff above is against the closest alternative; list them all
+	if len(err.ExpectedAnyOf) > 1 {
+		sb.WriteString("\n  Expected any of:\n")
+		for _, alt := range err.ExpectedAnyOf {
+			fmt.Fprintf(&sb, "    %s\n", green(formatArgv(alt), color))
+		}
+	}
+
 	if diffPos >= 0 {
 		sb.WriteString("\n")
 		formatDiffDetail(&sb, err, diffPos, color)
 	}
 
+	// Environment expectations (argv matched, match.env did not)
+	if len(err.EnvMismatches) > 0 {
+		sb.WriteString("\n  Environment mismatch:\n")
+		for _, m := range err.EnvMismatches {
+			fmt.Fprintf(&sb, "    %s: expected %s, got %s\n",
+				m.Name, green(fmt.Sprintf("%q", m.Expected), color), red(fmt.Sprintf("%q", m.Actual), color))
+		}
+	}
+
+	if m := err.CwdMismatch; m != nil {
+		fmt.Fprintf(&sb, "\n  Working directory mismatch:\n    expected %s, got %s\n",
+			green(fmt.Sprintf("%q", m.Expected), color), red(fmt.Sprintf("%q", m.Actual), color))
+	}
+
 	// Soft-advance context
 	if err.SoftAdvanced {
 		sb.WriteString("\n")
@@ -114,6 +221,12 @@ func FormatMismatchError(err *MismatchError) string {
 		sb.WriteString("    Neither step matched the received command.\n")
 	}
 
+	if err.Suggestion != nil {
+		sb.WriteString("\n")
+		fmt.Fprintf(&sb, "  Did you mean step %d: %s?\n",
+			err.Suggestion.StepIndex+1, formatArgv(err.Suggestion.Argv))
+	}
+
 	return sb.String()
 }
 
@@ -190,6 +303,32 @@ func findFirstDiff(expected, received []string) int {
 	return -1
 }
 
+// FormatGroupMismatchError formats a GroupMismatchError for user-friendly
+// output, listing the unconsumed group steps closest to the received
+// command first.
+func FormatGroupMismatchError(err *GroupMismatchError) string {
+	color := resolveColor()
+	var sb strings.Builder
+
+	sb.WriteString(bold(fmt.Sprintf("No match in unordered group %q of %q:\n",
+		err.GroupName, err.Scenario), color))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("  Received: %s\n", formatArgv(err.Received)))
+
+	if len(err.Candidates) > 0 {
+		sb.WriteString("\n  Candidates (closest first):\n")
+		for i, idx := range err.Candidates {
+			var argv []string
+			if i < len(err.CandidateArgv) {
+				argv = err.CandidateArgv[i]
+			}
+			fmt.Fprintf(&sb, "    step %d: %s\n", idx+1, formatArgv(argv))
+		}
+	}
+
+	return sb.String()
+}
+
 // maxStdinPreview is the maximum number of characters shown in stdin mismatch errors.
 const maxStdinPreview = 200
 
@@ -201,6 +340,14 @@ func FormatStdinMismatchError(err *StdinMismatchError) string {
 	sb.WriteString(bold(fmt.Sprintf("Mismatch at step %d of %q:\n",
 		err.StepIndex+1, err.Scenario), color))
 	sb.WriteString("\n")
+	if err.ExpectedSHA256 != "" {
+		sb.WriteString("  argv matched, stdin_sha256 mismatch:\n")
+		sb.WriteString(fmt.Sprintf("    expected sha256: %s\n", err.ExpectedSHA256))
+		sb.WriteString(fmt.Sprintf("    received sha256: %s\n", err.ReceivedSHA256))
+		sb.WriteString(fmt.Sprintf("    received (first %d chars):\n", maxStdinPreview))
+		sb.WriteString(indentPreview(err.Received, maxStdinPreview))
+		return sb.String()
+	}
 	sb.WriteString("  argv matched, stdin mismatch:\n")
 
 	sb.WriteString(fmt.Sprintf("    expected (first %d chars):\n", maxStdinPreview))
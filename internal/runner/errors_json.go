@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ErrorFormatEnvVar selects how intercept-mode errors are written to stderr.
+// Set to "json" for one JSON object per error; any other value keeps the
+// human-readable diagnostics.
+const ErrorFormatEnvVar = "CLI_REPLAY_ERROR_FORMAT"
+
+// IsJSONErrorFormat returns true if CLI_REPLAY_ERROR_FORMAT requests JSON.
+func IsJSONErrorFormat() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv(ErrorFormatEnvVar)), "json")
+}
+
+// Error type identifiers used in the "type" field of JSON errors.
+const (
+	ErrorTypeArgvMismatch  = "argv_mismatch"
+	ErrorTypeStdinMismatch = "stdin_mismatch"
+	ErrorTypeGroupMismatch = "group_mismatch"
+	ErrorTypeDisallowed    = "disallowed_command"
+	ErrorTypeForbidden     = "forbidden_command"
+	ErrorTypeGeneric       = "error"
+)
+
+// ErrorCandidate is a step that could have matched the received command.
+type ErrorCandidate struct {
+	StepIndex int      `json:"step_index"`
+	Argv      []string `json:"argv"`
+}
+
+// errorJSON is the wire shape shared by all JSON-formatted replay errors.
+// Expected and Received hold argv arrays for argv and group mismatches,
+// strings for stdin mismatches, and the allowlist and refused command for
+// disallowed commands. StepIndex is omitted for group mismatches,
+// which have no single expected step.
+type errorJSON struct {
+	Type       string           `json:"type"`
+	Message    string           `json:"message"`
+	Scenario   string           `json:"scenario,omitempty"`
+	Group      string           `json:"group,omitempty"`
+	StepIndex  *int             `json:"step_index,omitempty"`
+	Expected   interface{}      `json:"expected,omitempty"`
+	Received   interface{}      `json:"received,omitempty"`
+	Candidates []ErrorCandidate `json:"candidates"`
+}
+
+// MarshalJSON encodes the mismatch with the expected step, its other
+// match.any_of alternatives and, when the engine also tried soft-advancing,
+// the next step as further candidates.
+func (e *MismatchError) MarshalJSON() ([]byte, error) {
+	idx := e.StepIndex
+	candidates := []ErrorCandidate{{StepIndex: e.StepIndex, Argv: nonNilArgv(e.Expected)}}
+	for _, alt := range e.ExpectedAnyOf {
+		if !slices.Equal(alt, e.Expected) {
+			candidates = append(candidates, ErrorCandidate{StepIndex: e.StepIndex, Argv: alt})
+		}
+	}
+	if e.SoftAdvanced {
+		candidates = append(candidates, ErrorCandidate{StepIndex: e.NextStepIndex, Argv: nonNilArgv(e.NextExpected)})
+	}
+	return json.Marshal(errorJSON{
+		Type:       ErrorTypeArgvMismatch,
+		Message:    e.Error(),
+		Scenario:   e.Scenario,
+		Group:      e.GroupName,
+		StepIndex:  &idx,
+		Expected:   nonNilArgv(e.Expected),
+		Received:   nonNilArgv(e.Received),
+		Candidates: candidates,
+	})
+}
+
+// MarshalJSON encodes the stdin mismatch with the full expected and received
+// content; the human formatter's preview truncation is not applied. For
+// match.stdin_sha256, expected is the digest prefixed with "sha256:".
+func (e *StdinMismatchError) MarshalJSON() ([]byte, error) {
+	idx := e.StepIndex
+	expected := e.Expected
+	if e.ExpectedSHA256 != "" {
+		expected = "sha256:" + e.ExpectedSHA256
+	}
+	return json.Marshal(errorJSON{
+		Type:       ErrorTypeStdinMismatch,
+		Message:    e.Error(),
+		Scenario:   e.Scenario,
+		StepIndex:  &idx,
+		Expected:   expected,
+		Received:   e.Received,
+		Candidates: []ErrorCandidate{},
+	})
+}
+
+// MarshalJSON encodes the group mismatch with every unconsumed group step
+// as a candidate.
+func (e *GroupMismatchError) MarshalJSON() ([]byte, error) {
+	candidates := make([]ErrorCandidate, 0, len(e.Candidates))
+	for i, idx := range e.Candidates {
+		var argv []string
+		if i < len(e.CandidateArgv) {
+			argv = e.CandidateArgv[i]
+		}
+		candidates = append(candidates, ErrorCandidate{StepIndex: idx, Argv: nonNilArgv(argv)})
+	}
+	return json.Marshal(errorJSON{
+		Type:       ErrorTypeGroupMismatch,
+		Message:    e.Error(),
+		Scenario:   e.Scenario,
+		Group:      e.GroupName,
+		Received:   nonNilArgv(e.Received),
+		Candidates: candidates,
+	})
+}
+
+// MarshalJSON encodes the refused step with the allowlist as expected and
+// the step's command as received.
+func (e *DisallowedCommandError) MarshalJSON() ([]byte, error) {
+	idx := e.StepIndex
+	return json.Marshal(errorJSON{
+		Type:       ErrorTypeDisallowed,
+		Message:    e.Error(),
+		Scenario:   e.Scenario,
+		StepIndex:  &idx,
+		Expected:   e.Allowed,
+		Received:   e.Command,
+		Candidates: []ErrorCandidate{},
+	})
+}
+
+// MarshalJSON encodes the forbidden call with the refused argv as received.
+func (e *ForbiddenCommandError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorJSON{
+		Type:       ErrorTypeForbidden,
+		Message:    e.Error(),
+		Scenario:   e.Scenario,
+		Received:   nonNilArgv(e.Received),
+		Candidates: []ErrorCandidate{},
+	})
+}
+
+// FormatErrorJSON renders err as a single-line JSON object. Mismatch errors
+// use their own MarshalJSON shapes; any other error is reported with type
+// "error" and its message so every intercept failure stays machine-readable.
+func FormatErrorJSON(err error) string {
+	var v interface{}
+	switch e := err.(type) {
+	case *MismatchError, *StdinMismatchError, *GroupMismatchError, *DisallowedCommandError, *ForbiddenCommandError:
+		v = e
+	default:
+		v = errorJSON{Type: ErrorTypeGeneric, Message: err.Error(), Candidates: []ErrorCandidate{}}
+	}
+	data, mErr := json.Marshal(v)
+	if mErr != nil {
+		data, _ = json.Marshal(errorJSON{Type: ErrorTypeGeneric, Message: err.Error(), Candidates: []ErrorCandidate{}})
+	}
+	return string(data)
+}
+
+// nonNilArgv returns argv, or an empty slice so it encodes as [] not null.
+func nonNilArgv(argv []string) []string {
+	if argv == nil {
+		return []string{}
+	}
+	return argv
+}
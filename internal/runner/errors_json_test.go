@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeErrorJSON formats err as JSON and decodes it into a generic map.
+func decodeErrorJSON(t *testing.T, err error) map[string]interface{} {
+	t.Helper()
+	line := FormatErrorJSON(err)
+	assert.NotContains(t, line, "\n", "JSON error must be a single line")
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &got))
+	return got
+}
+
+func writeErrorScenario(t *testing.T, content string) string {
+	t.Helper()
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestFormatErrorJSON_MismatchError(t *testing.T) {
+	path := writeErrorScenario(t, `
+meta:
+  name: json-argv
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+`)
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(path, []string{"kubectl", "get", "svc"}, &stdout, &stderr)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+
+	got := decodeErrorJSON(t, err)
+	assert.Equal(t, ErrorTypeArgvMismatch, got["type"])
+	assert.Equal(t, "json-argv", got["scenario"])
+	assert.Equal(t, float64(0), got["step_index"])
+	assert.Equal(t, []interface{}{"kubectl", "get", "pods"}, got["expected"])
+	assert.Equal(t, []interface{}{"kubectl", "get", "svc"}, got["received"])
+	require.Len(t, got["candidates"], 1)
+	candidate := got["candidates"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(0), candidate["step_index"])
+	assert.Equal(t, []interface{}{"kubectl", "get", "pods"}, candidate["argv"])
+}
+
+func TestFormatErrorJSON_MismatchError_SoftAdvanceCandidate(t *testing.T) {
+	err := &MismatchError{
+		Scenario:      "soft",
+		StepIndex:     0,
+		Expected:      []string{"a"},
+		Received:      []string{"c"},
+		SoftAdvanced:  true,
+		NextStepIndex: 1,
+		NextExpected:  []string{"b"},
+	}
+
+	got := decodeErrorJSON(t, err)
+	candidates := got["candidates"].([]interface{})
+	require.Len(t, candidates, 2)
+	next := candidates[1].(map[string]interface{})
+	assert.Equal(t, float64(1), next["step_index"])
+	assert.Equal(t, []interface{}{"b"}, next["argv"])
+}
+
+func TestFormatErrorJSON_StdinMismatchError(t *testing.T) {
+	long := strings.Repeat("x", maxStdinPreview+50)
+	err := &StdinMismatchError{
+		Scenario:  "json-stdin",
+		StepIndex: 2,
+		Expected:  "apiVersion: v1\n",
+		Received:  long,
+	}
+
+	got := decodeErrorJSON(t, err)
+	assert.Equal(t, ErrorTypeStdinMismatch, got["type"])
+	assert.Equal(t, "json-stdin", got["scenario"])
+	assert.Equal(t, float64(2), got["step_index"])
+	assert.Equal(t, "apiVersion: v1\n", got["expected"])
+	assert.Equal(t, long, got["received"], "stdin content must not be truncated")
+	assert.Equal(t, []interface{}{}, got["candidates"])
+}
+
+func TestFormatErrorJSON_GroupMismatchError(t *testing.T) {
+	path := writeErrorScenario(t, `
+meta:
+  name: json-group
+steps:
+  - group:
+      mode: unordered
+      name: setup
+      steps:
+        - match:
+            argv: ["cmd", "alpha"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["cmd", "beta"]
+          respond:
+            exit: 0
+`)
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(path, []string{"cmd", "gamma"}, &stdout, &stderr)
+	var gErr *GroupMismatchError
+	require.ErrorAs(t, err, &gErr)
+
+	got := decodeErrorJSON(t, err)
+	assert.Equal(t, ErrorTypeGroupMismatch, got["type"])
+	assert.Equal(t, "json-group", got["scenario"])
+	assert.Equal(t, "setup", got["group"])
+	assert.NotContains(t, got, "step_index")
+	assert.NotContains(t, got, "expected")
+	assert.Equal(t, []interface{}{"cmd", "gamma"}, got["received"])
+
+	candidates := got["candidates"].([]interface{})
+	require.Len(t, candidates, 2)
+	first := candidates[0].(map[string]interface{})
+	second := candidates[1].(map[string]interface{})
+	assert.Equal(t, float64(0), first["step_index"])
+	assert.Equal(t, []interface{}{"cmd", "alpha"}, first["argv"])
+	assert.Equal(t, float64(1), second["step_index"])
+	assert.Equal(t, []interface{}{"cmd", "beta"}, second["argv"])
+}
+
+func TestFormatErrorJSON_GenericError(t *testing.T) {
+	got := decodeErrorJSON(t, errors.New("failed to load scenario"))
+	assert.Equal(t, ErrorTypeGeneric, got["type"])
+	assert.Equal(t, "failed to load scenario", got["message"])
+}
+
+func TestIsJSONErrorFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", false},
+		{"text", false},
+		{"json", true},
+		{"JSON", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(ErrorFormatEnvVar, tt.value)
+			assert.Equal(t, tt.want, IsJSONErrorFormat())
+		})
+	}
+}
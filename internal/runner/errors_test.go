@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -197,3 +198,78 @@ func TestFormatArgv_Long(t *testing.T) {
 	result := formatArgv(args)
 	assert.Contains(t, result, "+3 more")
 }
+
+func TestFormatMismatchErrorColor_Enabled(t *testing.T) {
+	t.Setenv("COLUMNS", "")
+	err := &MismatchError{
+		Scenario:  "test",
+		StepIndex: 0,
+		Expected:  []string{"kubectl", "get", "pods"},
+		Received:  []string{"kubectl", "get", "svc"},
+	}
+
+	formatted := FormatMismatchErrorColor(err, true)
+
+	assert.Contains(t, formatted, "\033[32mkubectl\033[0m", "expected argv should be green")
+	assert.Contains(t, formatted, "\033[31mkubectl\033[0m", "received argv should be red")
+	assert.Contains(t, formatted, "\033[1m\033[4m\033[31msvc", "diff token should be highlighted")
+	assert.Contains(t, formatted, "\033[1m\033[4m\033[32mpods", "diff token should be highlighted")
+}
+
+func TestFormatMismatchErrorColor_Disabled(t *testing.T) {
+	t.Setenv("COLUMNS", "")
+	err := &MismatchError{
+		Scenario:  "test",
+		StepIndex: 0,
+		Expected:  []string{"kubectl", "get", "pods"},
+		Received:  []string{"kubectl", "get", "svc"},
+	}
+
+	formatted := FormatMismatchErrorColor(err, false)
+
+	assert.NotContains(t, formatted, "\033[")
+	assert.Contains(t, formatted, "  Expected: [kubectl get pods]\n")
+	assert.Contains(t, formatted, "  Received: [kubectl get svc]\n")
+}
+
+func TestFormatMismatchError_NoColorEnv(t *testing.T) {
+	t.Setenv("CLI_REPLAY_COLOR", "")
+	t.Setenv("NO_COLOR", "1")
+	err := &MismatchError{
+		Scenario:  "test",
+		StepIndex: 0,
+		Expected:  []string{"kubectl", "get", "pods"},
+		Received:  []string{"kubectl", "get", "svc"},
+	}
+
+	assert.NotContains(t, FormatMismatchError(err), "\033[")
+}
+
+func TestFormatMismatchError_WrapsToColumns(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("COLUMNS", "40")
+	argv := []string{"az", "deployment", "group", "create", "--resource-group", "my-rg", "--template-file", "main.bicep"}
+	err := &MismatchError{
+		Scenario:  "test",
+		StepIndex: 0,
+		Expected:  argv,
+		Received:  append(append([]string{}, argv[:7]...), "other.bicep"),
+	}
+
+	formatted := FormatMismatchError(err)
+
+	assert.Contains(t, formatted, "  Expected: [az deployment group create\n             --resource-group my-rg\n")
+	for _, line := range strings.Split(formatted, "\n") {
+		assert.LessOrEqual(t, len(line), 40, "line exceeds width: %q", line)
+	}
+}
+
+func TestFormatMismatchError_NoWrapWithoutWidth(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("COLUMNS", "")
+	argv := []string{"az", "deployment", "group", "create", "--resource-group", "my-rg", "--template-file", "main.bicep"}
+	err := &MismatchError{Scenario: "test", Expected: argv, Received: []string{"az"}}
+
+	assert.Contains(t, FormatMismatchError(err),
+		"  Expected: [az deployment group create --resource-group my-rg --template-file main.bicep]")
+}
@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// FailFastEnvVar names the marker file an intercept writes when it serves a
+// mismatch. `exec --fail-fast` sets it for the child and watches the file so
+// the child can be stopped on the first mismatch.
+const FailFastEnvVar = "CLI_REPLAY_FAIL_FAST_FILE"
+
+// NoteMismatch appends a one-line description of err to the file named by
+// CLI_REPLAY_FAIL_FAST_FILE. It does nothing when the variable is unset or
+// err is not a MismatchError, GroupMismatchError or MismatchLoopError.
+func NoteMismatch(err error) error {
+	path := os.Getenv(FailFastEnvVar)
+	if path == "" {
+		return nil
+	}
+	var line string
+	var mismatch *MismatchError
+	var group *GroupMismatchError
+	var loop *MismatchLoopError
+	var forbidden *ForbiddenCommandError
+	switch {
+	case errors.As(err, &mismatch):
+		line = fmt.Sprintf("%s: received %v", mismatch.Error(), mismatch.Received)
+	case errors.As(err, &group):
+		line = group.Error()
+	case errors.As(err, &loop):
+		line = loop.Error()
+	case errors.As(err, &forbidden):
+		line = forbidden.Error()
+	default:
+		return nil
+	}
+	f, openErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600) //nolint:gosec // path set by exec
+	if openErr != nil {
+		return fmt.Errorf("failed to write fail-fast marker: %w", openErr)
+	}
+	defer f.Close() //nolint:errcheck
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return fmt.Errorf("failed to write fail-fast marker: %w", err)
+	}
+	return nil
+}
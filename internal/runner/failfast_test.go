@@ -0,0 +1,42 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteMismatch_WritesMarker(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	t.Setenv(FailFastEnvVar, marker)
+
+	require.NoError(t, NoteMismatch(&MismatchError{StepIndex: 1, Received: []string{"kubectl", "delete"}}))
+	require.NoError(t, NoteMismatch(&GroupMismatchError{GroupName: "setup", Received: []string{"az"}}))
+
+	data, err := os.ReadFile(marker)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"argv mismatch at step 1: received [kubectl delete]\n"+
+			"no match in group \"setup\" (index 0): received [az]\n",
+		string(data))
+}
+
+func TestNoteMismatch_IgnoresOtherErrors(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	t.Setenv(FailFastEnvVar, marker)
+
+	require.NoError(t, NoteMismatch(errors.New("failed to load scenario")))
+	require.NoError(t, NoteMismatch(&StdinMismatchError{StepIndex: 0}))
+
+	_, err := os.Stat(marker)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNoteMismatch_NoEnvVar(t *testing.T) {
+	t.Setenv(FailFastEnvVar, "")
+	assert.NoError(t, NoteMismatch(&MismatchError{StepIndex: 0}))
+}
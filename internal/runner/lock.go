@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockState takes an exclusive advisory lock on the state file at path, so
+// that a read-modify-write of the state is atomic across processes. The lock
+// is held on a sibling "<path>.lock" file because WriteState replaces the
+// state file itself. The call blocks until the lock is available; the
+// returned function releases it.
+func LockState(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600) //nolint:gosec // Lock path derived from state file path
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock state: %w", err)
+	}
+
+	return func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"~", home},
+		{"~/x.yaml", filepath.Join(home, "x.yaml")},
+		{"~/scenarios/foo.yaml", filepath.Join(home, "scenarios", "foo.yaml")},
+		{"scenarios/~backup/x.yaml", "scenarios/~backup/x.yaml"},
+		{"a~b.yaml", "a~b.yaml"},
+		{"/abs/~/x.yaml", "/abs/~/x.yaml"},
+	}
+	for _, tt := range tests {
+		got, err := ExpandHome(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestExpandHome_NamedUser(t *testing.T) {
+	u, err := user.Current()
+	if err != nil || u.Username == "" || u.HomeDir == "" {
+		t.Skip("current user not resolvable")
+	}
+	got, err := ExpandHome("~" + u.Username + "/x.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(u.HomeDir, "x.yaml"), got)
+
+	_, err = ExpandHome("~no-such-user-cli-replay/x.yaml")
+	require.Error(t, err)
+}
+
+func TestResolveScenarioPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	got, err := ResolveScenarioPath("~/x.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "x.yaml"), got)
+
+	got, err = ResolveScenarioPath("dir/~x.yaml")
+	require.NoError(t, err)
+	want, err := filepath.Abs(filepath.Join("dir", "~x.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
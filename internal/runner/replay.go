@@ -8,10 +8,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ormasoftchile/cli-replay/internal/fixture"
 	"github.com/ormasoftchile/cli-replay/internal/template"
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
 	"github.com/ormasoftchile/cli-replay/pkg/replay"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 )
@@ -22,6 +27,11 @@ type ReplayResult struct {
 	Matched      bool
 	StepIndex    int
 	ScenarioName string
+	Fallback     bool   // true if meta.fallback was served for an unmatched command
+	Group        string // name of the group containing the matched step, if any
+	SoftAdvanced bool   // true if the match moved past a step whose min count was met
+	StdoutBytes  int    // length of the stdout written for the response
+	StderrBytes  int    // length of the stderr written for the response
 }
 
 // ReplayResponse writes the step's response to stdout/stderr and returns the exit code.
@@ -33,7 +43,7 @@ func ReplayResponse(step *scenario.Step, _ string, stdout, stderr io.Writer) int
 	if step.Respond.Stderr != "" {
 		_, _ = io.WriteString(stderr, step.Respond.Stderr)
 	}
-	return step.Respond.Exit
+	return step.Respond.EffectiveExit()
 }
 
 // ReplayResponseWithFile writes the step's response to stdout/stderr and returns the exit code.
@@ -43,29 +53,25 @@ func ReplayResponseWithFile(step *scenario.Step, scenarioPath string, stdout, st
 
 	// Handle stdout
 	if step.Respond.StdoutFile != "" {
-		content, err := readFile(scenarioDir, step.Respond.StdoutFile)
-		if err != nil {
+		if err := copyFile(stdout, scenarioDir, step.Respond.StdoutFile); err != nil {
 			_, _ = fmt.Fprintf(stderr, "cli-replay: failed to read stdout_file: %v\n", err)
 			return 1
 		}
-		_, _ = io.WriteString(stdout, content)
 	} else if step.Respond.Stdout != "" {
 		_, _ = io.WriteString(stdout, step.Respond.Stdout)
 	}
 
 	// Handle stderr
 	if step.Respond.StderrFile != "" {
-		content, err := readFile(scenarioDir, step.Respond.StderrFile)
-		if err != nil {
+		if err := copyFile(stderr, scenarioDir, step.Respond.StderrFile); err != nil {
 			_, _ = fmt.Fprintf(stderr, "cli-replay: failed to read stderr_file: %v\n", err)
 			return 1
 		}
-		_, _ = io.WriteString(stderr, content)
 	} else if step.Respond.Stderr != "" {
 		_, _ = io.WriteString(stderr, step.Respond.Stderr)
 	}
 
-	return step.Respond.Exit
+	return step.Respond.EffectiveExit()
 }
 
 // ReplayResponseWithTemplate writes the step's response with template rendering.
@@ -87,26 +93,25 @@ func ReplayResponseWithTemplate(step *scenario.Step, scn *scenario.Scenario, sce
 		var denied []string
 		vars, denied = template.MergeVarsFiltered(scn.Meta.Vars, denyPatterns)
 		// T010: Trace denied env vars
-		if IsTraceEnabled(os.Getenv(TraceEnvVar)) {
+		if tw := traceWriter(stderr); tw != nil {
 			for _, name := range denied {
-				WriteDeniedEnvTrace(stderr, name)
+				WriteDeniedEnvTrace(tw, name)
 			}
 		}
 	} else {
 		vars = template.MergeVars(scn.Meta.Vars)
 	}
 
-	// Handle stdout
-	stdoutContent := ""
+	// Handle stdout. Static fixtures are streamed; only templated content
+	// is held in memory for rendering.
+	stdoutContent := step.Respond.Stdout
 	if step.Respond.StdoutFile != "" {
-		content, err := readFile(scenarioDir, step.Respond.StdoutFile)
+		content, err := streamStaticFixture(stdout, scn, scenarioDir, step.Respond.StdoutFile)
 		if err != nil {
 			_, _ = fmt.Fprintf(stderr, "cli-replay: failed to read stdout_file: %v\n", err)
 			return 1
 		}
 		stdoutContent = content
-	} else {
-		stdoutContent = step.Respond.Stdout
 	}
 
 	if stdoutContent != "" {
@@ -119,16 +124,14 @@ func ReplayResponseWithTemplate(step *scenario.Step, scn *scenario.Scenario, sce
 	}
 
 	// Handle stderr
-	stderrContent := ""
+	stderrContent := step.Respond.Stderr
 	if step.Respond.StderrFile != "" {
-		content, err := readFile(scenarioDir, step.Respond.StderrFile)
+		content, err := streamStaticFixture(stderr, scn, scenarioDir, step.Respond.StderrFile)
 		if err != nil {
 			_, _ = fmt.Fprintf(stderr, "cli-replay: failed to read stderr_file: %v\n", err)
 			return 1
 		}
 		stderrContent = content
-	} else {
-		stderrContent = step.Respond.Stderr
 	}
 
 	if stderrContent != "" {
@@ -140,36 +143,135 @@ func ReplayResponseWithTemplate(step *scenario.Step, scn *scenario.Scenario, sce
 		_, _ = io.WriteString(stderr, rendered)
 	}
 
-	return step.Respond.Exit
+	if step.Respond.ExitTemplate != "" {
+		rendered, err := template.RenderWithCaptures(step.Respond.ExitTemplate, vars, captures)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "cli-replay: failed to render exit_template: %v\n", err)
+			return 1
+		}
+		code, err := scenario.ParseExitCode(rendered)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "cli-replay: %v\n", err)
+			return 1
+		}
+		return code
+	}
+
+	return step.Respond.EffectiveExit()
+}
+
+// ReadFixture reads a stdout_file/stderr_file fixture of scn, resolved
+// against meta.fixtures_dir or scenarioDir and decompressing .gz files the
+// same way replay does.
+func ReadFixture(scn *scenario.Scenario, scenarioDir, relPath string) (string, error) {
+	return readScenarioFile(scn, scenarioDir, relPath)
+}
+
+// readScenarioFile reads a stdout_file/stderr_file fixture of scn, resolved
+// against meta.fixtures_dir when set and scenarioDir otherwise.
+func readScenarioFile(scn *scenario.Scenario, scenarioDir, relPath string) (string, error) {
+	fullPath, err := FixturePath(scn, scenarioDir, relPath)
+	if err != nil {
+		return "", err
+	}
+	return fixture.Read(fullPath, relPath)
+}
+
+// streamStaticFixture serves a stdout_file/stderr_file fixture of scn. A
+// fixture without template syntax is copied straight to w and "" is
+// returned; a templated one is read and returned for rendering, leaving w
+// untouched.
+func streamStaticFixture(w io.Writer, scn *scenario.Scenario, scenarioDir, relPath string) (string, error) {
+	fullPath, err := FixturePath(scn, scenarioDir, relPath)
+	if err != nil {
+		return "", err
+	}
+	templated, err := fixture.HasTemplate(fullPath, relPath)
+	if err != nil {
+		return "", err
+	}
+	if templated {
+		return fixture.Read(fullPath, relPath)
+	}
+	return "", fixture.Copy(w, fullPath, relPath)
 }
 
-// readFile reads a file relative to the base directory.
+// FixturePath resolves a stdout_file/stderr_file reference of scn to the
+// file to read, as described by fixture.Path.
+func FixturePath(scn *scenario.Scenario, scenarioDir, ref string) (string, error) {
+	return fixture.Path(scn, scenarioDir, ref)
+}
+
+// readFile reads a file relative to the base directory. The path must stay
+// within baseDir, so a scenario cannot serve arbitrary files such as
+// ../../etc/passwd. Files ending in .gz are transparently decompressed.
 func readFile(baseDir, relPath string) (string, error) {
-	fullPath := filepath.Join(baseDir, relPath)
-	data, err := os.ReadFile(fullPath) //nolint:gosec // File path is relative to scenario directory
+	fullPath, err := fixture.Within(baseDir, relPath)
 	if err != nil {
 		return "", err
 	}
-	return string(data), nil
+	return fixture.Read(fullPath, relPath)
+}
+
+// copyFile is readFile streaming the file to w instead of returning it.
+func copyFile(w io.Writer, baseDir, relPath string) error {
+	fullPath, err := fixture.Within(baseDir, relPath)
+	if err != nil {
+		return err
+	}
+	return fixture.Copy(w, fullPath, relPath)
 }
 
 // ExecuteReplay runs the replay logic for a given scenario and argv.
 // It loads the scenario, checks/creates state, delegates matching to
 // pkg/replay.Engine, writes response output, and persists state.
-//
-//nolint:funlen // Orchestration function with many I/O steps
 func ExecuteReplay(scenarioPath string, argv []string, stdout, stderr io.Writer) (*ReplayResult, error) {
-	// Load scenario
-	absPath, err := filepath.Abs(scenarioPath)
+	return ExecuteReplayWithCaptures(scenarioPath, argv, nil, stdout, stderr)
+}
+
+// ExecuteReplayWithCaptures is ExecuteReplay with additional captures made
+// visible to templates, as set by earlier files of a scenario sequence.
+// Captures recorded in the scenario's own state take precedence.
+func ExecuteReplayWithCaptures(scenarioPath string, argv []string, captures map[string]string, stdout, stderr io.Writer) (*ReplayResult, error) {
+	absPath, err := ResolveScenarioPath(scenarioPath)
 	if err != nil {
 		return &ReplayResult{ExitCode: 1}, fmt.Errorf("failed to resolve scenario path: %w", err)
 	}
+	return executeReplay(absPath, NewFileStateStore(StateFilePath(absPath)), argv, captures, stdout, stderr)
+}
 
-	scn, err := scenario.LoadFile(absPath)
+// ExecuteReplayWithStore is ExecuteReplay with the session state kept in
+// store instead of the scenario's state file, such as a replay.MemoryStore
+// for a session that stays within one process.
+func ExecuteReplayWithStore(scenarioPath string, store replay.StateStore, argv []string, stdout, stderr io.Writer) (*ReplayResult, error) {
+	absPath, err := ResolveScenarioPath(scenarioPath)
+	if err != nil {
+		return &ReplayResult{ExitCode: 1}, fmt.Errorf("failed to resolve scenario path: %w", err)
+	}
+	return executeReplay(absPath, store, argv, nil, stdout, stderr)
+}
+
+// executeReplay matches argv against the scenario at absPath, reading and
+// writing session state through store.
+//
+//nolint:funlen // Orchestration function with many I/O steps
+func executeReplay(absPath string, store replay.StateStore, argv []string, captures map[string]string, stdout, stderr io.Writer) (*ReplayResult, error) {
+	// Load scenario (the document selected by CLI_REPLAY_DOCUMENT). Sessions
+	// kept on disk also keep the parsed scenario there, so repeated intercept
+	// calls skip parsing while the file is unchanged; in-memory sessions
+	// leave no files behind.
+	load := loadScenarioFiles
+	if _, onDisk := store.(*FileStateStore); onDisk {
+		load = loadCachedScenario
+	}
+	scn, files, err := load(absPath)
 	if err != nil {
 		return &ReplayResult{ExitCode: 1}, fmt.Errorf("failed to load scenario: %w", err)
 	}
 
+	// Normalize `sudo kubectl ...` and the like to what the steps expect
+	argv = scn.Meta.StripArgvPrefixes(argv)
+
 	// T020: TTL cleanup before matching (intercept shim path)
 	if scn.Meta.Session != nil && scn.Meta.Session.TTL != "" {
 		if ttl, parseErr := time.ParseDuration(scn.Meta.Session.TTL); parseErr == nil && ttl > 0 {
@@ -181,12 +283,26 @@ func ExecuteReplay(scenarioPath string, argv []string, stdout, stderr io.Writer)
 	}
 
 	flatSteps := scn.FlatSteps()
-	scenarioHash := hashScenarioFile(absPath)
+	scenarioHash := hashScenarioFiles(files)
 	scenarioDir := filepath.Dir(absPath)
 
-	// Load or initialize persisted state
-	stateFile := StateFilePath(absPath)
-	state, err := ReadState(stateFile)
+	// Load or initialize persisted state. The lock is held until the updated
+	// state is written so parallel intercepts cannot lose increments.
+	stateFile := store.Location()
+	unlock, err := store.Lock()
+	if err != nil {
+		return &ReplayResult{ExitCode: 1}, err
+	}
+	locked := true
+	release := func() {
+		if locked {
+			unlock()
+			locked = false
+		}
+	}
+	defer release()
+
+	state, err := readStoreState(store)
 	if err != nil {
 		if os.IsNotExist(err) {
 			state = NewState(absPath, scenarioHash, len(flatSteps))
@@ -194,15 +310,60 @@ func ExecuteReplay(scenarioPath string, argv []string, stdout, stderr io.Writer)
 			return &ReplayResult{ExitCode: 1}, fmt.Errorf("failed to read state: %w", err)
 		}
 	}
+	// State written for an earlier version of the scenario refers to step
+	// indices that may no longer exist, so it is reset (or refused in
+	// strict mode) rather than replayed against the edited file.
+	if state.ScenarioHash != "" && scenarioHash != "" && state.ScenarioHash != scenarioHash {
+		if IsStrictStateEnabled() {
+			return &ReplayResult{ExitCode: 1, ScenarioName: scn.Meta.Name},
+				&StaleStateError{Scenario: scn.Meta.Name, StateFile: stateFile}
+		}
+		if tw := traceWriter(stderr); tw != nil {
+			WriteStaleStateTrace(tw, scn.Meta.Name)
+		}
+		fresh := NewState(absPath, scenarioHash, len(flatSteps))
+		fresh.InterceptDir = state.InterceptDir
+		fresh.MaxStdinBytes = state.MaxStdinBytes
+		state = fresh
+	}
+	if len(captures) > 0 {
+		if state.Captures == nil {
+			state.Captures = make(map[string]string, len(captures))
+		}
+		for k, v := range captures {
+			if _, ok := state.Captures[k]; !ok {
+				state.Captures[k] = v
+			}
+		}
+	}
+
+	// A forbidden command fails whether or not a step would match it, and
+	// is recorded so the run fails even if the child ignores the error
+	if forbiddenErr := checkForbiddenCommand(scn, argv); forbiddenErr != nil {
+		state.RecordForbiddenCall(argv)
+		if err := writeStoreState(store, state); err != nil {
+			_, _ = fmt.Fprintf(stderr, "cli-replay: warning: failed to save state: %v\n", err)
+		}
+		writeDecisionTrace(stderr, TraceRecord{Scenario: scn.Meta.Name, Argv: argv, FromStep: state.CurrentStep}.withError(forbiddenErr))
+		return &ReplayResult{ExitCode: scn.Meta.MismatchExitCode(), ScenarioName: scn.Meta.Name}, forbiddenErr
+	}
 
 	// Check if scenario completed (early exit before creating engine)
 	if state.IsComplete() {
 		_, _ = fmt.Fprintf(stderr, "cli-replay: scenario %q already complete (all %d steps consumed)\n",
 			scn.Meta.Name, state.TotalSteps)
-		return &ReplayResult{ExitCode: 1, ScenarioName: scn.Meta.Name},
+		return &ReplayResult{ExitCode: scn.Meta.CompleteExitCode(), ScenarioName: scn.Meta.Name},
 			fmt.Errorf("scenario already complete")
 	}
 
+	// Global call budget: refuse further matches once the cap is reached
+	if limit := scn.Meta.MaxTotalCalls; limit > 0 {
+		if calls := state.TotalCalls(); calls >= limit {
+			return &ReplayResult{ExitCode: scn.Meta.MismatchExitCode(), ScenarioName: scn.Meta.Name},
+				&CallBudgetExceededError{Scenario: scn.Meta.Name, Limit: limit, Received: argv}
+		}
+	}
+
 	// Build engine options
 	opts := buildEngineOpts(scn, absPath, scenarioDir, state, stderr)
 
@@ -217,42 +378,80 @@ func ExecuteReplay(scenarioPath string, argv []string, stdout, stderr io.Writer)
 	}
 
 	// Execute match — handle stdin if the matched step requires it
+	decision := TraceRecord{Scenario: scn.Meta.Name, Argv: argv, FromStep: state.CurrentStep}
 	result, matchErr := engine.Match(context.Background(), name, args)
 
+	// Defense in depth: the allowlist is checked when a session is set up,
+	// but a scenario edited afterwards must not serve other commands.
+	if matchErr == nil && result.Matched && result.StepIndex < len(flatSteps) {
+		for _, stepArgv := range flatSteps[result.StepIndex].Match.Alternatives() {
+			if err := checkAllowedCommand(scn, result.StepIndex, stepArgv); err != nil {
+				writeDecisionTrace(stderr, decision.withError(err))
+				return &ReplayResult{ExitCode: scn.Meta.MismatchExitCode(), ScenarioName: scn.Meta.Name}, err
+			}
+		}
+	}
+
 	// If argv matched but we need to also validate stdin, re-check.
 	// The engine already did argv matching; we handle stdin at this layer
 	// because stdin reading requires os.Stdin (file I/O).
 	if matchErr == nil && result.Matched {
 		matchedIdx := result.StepIndex
-		if matchedIdx < len(flatSteps) && flatSteps[matchedIdx].Match.Stdin != "" {
-			actualStdin, readErr := readStdin()
+		if matchedIdx < len(flatSteps) && (flatSteps[matchedIdx].Match.Stdin != "" || flatSteps[matchedIdx].Match.StdinSHA256 != "") {
+			expected := flatSteps[matchedIdx].Match
+			actualStdin, readErr := readStdin(stdinLimit(scn, state))
 			if readErr != nil {
 				_, _ = fmt.Fprintf(stderr, "cli-replay: failed to read stdin: %v\n", readErr)
 				return &ReplayResult{ExitCode: 1, ScenarioName: scn.Meta.Name}, readErr
 			}
-			if normalizeStdin(actualStdin) != normalizeStdin(flatSteps[matchedIdx].Match.Stdin) {
-				return &ReplayResult{ExitCode: 1, ScenarioName: scn.Meta.Name},
-					&StdinMismatchError{
-						Scenario:  scn.Meta.Name,
-						StepIndex: matchedIdx,
-						Expected:  flatSteps[matchedIdx].Match.Stdin,
-						Received:  actualStdin,
+			var stdinErr *StdinMismatchError
+			if expected.StdinSHA256 != "" {
+				if digest := matcher.StdinSHA256(actualStdin); !strings.EqualFold(digest, expected.StdinSHA256) {
+					stdinErr = &StdinMismatchError{
+						Scenario:       scn.Meta.Name,
+						StepIndex:      matchedIdx,
+						Received:       actualStdin,
+						ExpectedSHA256: expected.StdinSHA256,
+						ReceivedSHA256: digest,
 					}
+				}
+			} else if normalizeStdin(actualStdin) != normalizeStdin(expected.Stdin) {
+				stdinErr = &StdinMismatchError{
+					Scenario:  scn.Meta.Name,
+					StepIndex: matchedIdx,
+					Expected:  expected.Stdin,
+					Received:  actualStdin,
+				}
+			}
+			if stdinErr != nil {
+				writeDecisionTrace(stderr, decision.withError(stdinErr))
+				return &ReplayResult{ExitCode: scn.Meta.MismatchExitCode(), ScenarioName: scn.Meta.Name}, stdinErr
 			}
 		}
 	}
 
-	// Convert engine errors to runner error types (preserves backward compat)
-	if matchErr != nil {
-		return convertEngineError(matchErr, scn.Meta.Name, state, stateFile)
+	// Unmatched commands get meta.fallback (if configured) without touching state
+	if matchErr != nil && scn.Meta.Fallback != nil && isNoMatchError(matchErr) {
+		release()
+		res, err := serveFallback(engine, scn, scenarioDir, argv, stdout, stderr)
+		if err != nil {
+			writeDecisionTrace(stderr, decision.withError(err))
+		} else {
+			decision.Decision, decision.Exit = TraceDecisionFallback, res.ExitCode
+			writeDecisionTrace(stderr, decision)
+		}
+		return res, err
 	}
 
-	// Write response to stdout/stderr
-	if result.Stdout != "" {
-		_, _ = io.WriteString(stdout, result.Stdout)
-	}
-	if result.Stderr != "" {
-		_, _ = io.WriteString(stderr, result.Stderr)
+	// Convert engine errors to runner error types (preserves backward compat)
+	if matchErr != nil {
+		if loopErr := noteMismatchStreak(scn, store, state, argv, matchErr); loopErr != nil {
+			writeDecisionTrace(stderr, decision.withError(loopErr))
+			return &ReplayResult{ExitCode: scn.Meta.MismatchExitCode(), ScenarioName: scn.Meta.Name}, loopErr
+		}
+		res, err := convertEngineError(matchErr, &scn.Meta, flatSteps, state, stateFile)
+		writeDecisionTrace(stderr, decision.withError(err))
+		return res, err
 	}
 
 	// Sync engine state back to persisted state
@@ -260,31 +459,138 @@ func ExecuteReplay(scenarioPath string, argv []string, stdout, stderr io.Writer)
 	state.CurrentStep = snap.CurrentStep
 	state.StepCounts = snap.StepCounts
 	state.Captures = snap.Captures
+	state.Prev = snap.Prev
 	if snap.ActiveGroup != nil {
 		state.ActiveGroup = snap.ActiveGroup
 	} else {
 		state.ActiveGroup = nil
 	}
 	state.LastUpdated = time.Now().UTC()
+	state.RecordServed(result.StepIndex, state.LastUpdated)
+	state.MismatchStreak, state.LastMismatchArgv = 0, nil
+	if a := flatSteps[result.StepIndex].Respond.Assert; a != nil {
+		state.RecordAssertFailure(result.StepIndex, a.Check(result.Stdout, result.Stderr))
+	}
 
 	// Trace output if enabled
-	if IsTraceEnabled(os.Getenv(TraceEnvVar)) {
-		WriteTraceOutput(stderr, result.StepIndex, argv, result.ExitCode)
+	if tw := traceWriter(stderr); tw != nil {
+		WriteTraceOutput(tw, result.StepIndex, argv, result.ExitCode)
 	}
+	decision.Decision = TraceDecisionServed
+	decision.StepIndex = &result.StepIndex
+	decision.SoftAdvanced = result.SoftAdvanced
+	decision.Group = result.Group
+	decision.Exit = result.ExitCode
+	decision.Captures = stepCaptures(flatSteps, result.StepIndex, result.Captures)
+	writeDecisionTrace(stderr, decision)
 
-	// Save state
-	if err := WriteState(stateFile, state); err != nil {
+	// Save state and release the lock before serving, so a step's delay
+	// does not serialize parallel intercepts.
+	if err := writeStoreState(store, state); err != nil {
+		_, _ = fmt.Fprintf(stderr, "cli-replay: warning: failed to save state: %v\n", err)
+	}
+	release()
+
+	// Serve the response (delay + output), timing it for respond.timeout checks
+	serveStart := time.Now()
+	if result.Delay > 0 {
+		time.Sleep(result.Delay)
+	}
+	// A streamed fixture reaches .prev only once it has been written
+	var streamed *replay.Served
+	if (result.StdoutFile != "" || result.StderrFile != "") && state.Prev != nil {
+		p := *state.Prev
+		streamed = &p
+	}
+	stdoutBytes, stderrBytes, writeErr := writeResponse(result, scn, scenarioDir, streamed, stdout, stderr)
+	if err := recordServeDuration(store, result.StepIndex, time.Since(serveStart), streamed); err != nil {
 		_, _ = fmt.Fprintf(stderr, "cli-replay: warning: failed to save state: %v\n", err)
 	}
+	if writeErr != nil {
+		return &ReplayResult{ExitCode: 1, Matched: true, StepIndex: result.StepIndex, ScenarioName: scn.Meta.Name}, writeErr
+	}
 
 	return &ReplayResult{
 		ExitCode:     result.ExitCode,
 		Matched:      result.Matched,
 		StepIndex:    result.StepIndex,
 		ScenarioName: scn.Meta.Name,
+		Group:        result.Group,
+		SoftAdvanced: result.SoftAdvanced,
+		StdoutBytes:  stdoutBytes,
+		StderrBytes:  stderrBytes,
 	}, nil
 }
 
+// noteMismatchStreak tracks repeats of the same unmatched argv when
+// meta.limits.max_consecutive_mismatches is set, persisting the streak in
+// state. It returns a MismatchLoopError once the streak reaches the limit.
+// State is not touched when loop detection is off.
+func noteMismatchStreak(scn *scenario.Scenario, store replay.StateStore, state *State, argv []string, matchErr error) error {
+	if scn.Meta.Limits == nil || scn.Meta.Limits.MaxConsecutiveMismatches <= 0 || !isNoMatchError(matchErr) {
+		return nil
+	}
+	limit := scn.Meta.Limits.MaxConsecutiveMismatches
+	if state.MismatchStreak > 0 && slices.Equal(state.LastMismatchArgv, argv) {
+		state.MismatchStreak++
+	} else {
+		state.MismatchStreak = 1
+		state.LastMismatchArgv = slices.Clone(argv)
+	}
+	state.LastUpdated = time.Now().UTC()
+	if err := writeStoreState(store, state); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if state.MismatchStreak < limit {
+		return nil
+	}
+	return &MismatchLoopError{Scenario: scn.Meta.Name, Received: argv, Count: state.MismatchStreak, Limit: limit}
+}
+
+// writeDecisionTrace appends rec to the JSONL trace when CLI_REPLAY_TRACE=json.
+func writeDecisionTrace(stderr io.Writer, rec TraceRecord) {
+	if tw := jsonTraceWriter(stderr); tw != nil {
+		rec.Time = time.Now().UTC()
+		WriteJSONTrace(tw, rec)
+	}
+}
+
+// stepCaptures returns the captures set by step idx, with their values
+// taken from the merged captures. Returns nil if the step sets none.
+func stepCaptures(flatSteps []scenario.Step, idx int, merged map[string]string) map[string]string {
+	if idx < 0 || idx >= len(flatSteps) || len(flatSteps[idx].Respond.Capture) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(flatSteps[idx].Respond.Capture))
+	for k := range flatSteps[idx].Respond.Capture {
+		out[k] = merged[k]
+	}
+	return out
+}
+
+// recordServeDuration persists the service time of step idx under the
+// state lock, re-reading the state so concurrent updates are preserved.
+// streamed, when set, is the call's response with the content of its
+// streamed fixtures; it replaces .prev unless another call has been
+// served since.
+func recordServeDuration(store replay.StateStore, idx int, d time.Duration, streamed *replay.Served) error {
+	unlock, err := store.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := readStoreState(store)
+	if err != nil {
+		return err
+	}
+	state.RecordStepDuration(idx, d)
+	if streamed != nil && state.Prev != nil && slices.Equal(state.Prev.Argv, streamed.Argv) {
+		state.Prev = streamed
+	}
+	return writeStoreState(store, state)
+}
+
 // buildEngineOpts constructs replay.Option slice from scenario config and persisted state.
 func buildEngineOpts(scn *scenario.Scenario, absPath, scenarioDir string, state *State, stderr io.Writer) []replay.Option {
 	var opts []replay.Option
@@ -296,6 +602,7 @@ func buildEngineOpts(scn *scenario.Scenario, absPath, scenarioDir string, state
 		StepCounts:  state.StepCounts,
 		ActiveGroup: state.ActiveGroup,
 		Captures:    state.Captures,
+		Prev:        state.Prev,
 	}))
 
 	// Environment variable lookup (uses os.Getenv)
@@ -306,49 +613,213 @@ func buildEngineOpts(scn *scenario.Scenario, absPath, scenarioDir string, state
 		opts = append(opts, replay.WithDenyEnvPatterns(scn.Meta.Security.DenyEnvVars))
 	}
 
-	// File reader for stdout_file/stderr_file
+	// Working directory for match.cwd
+	opts = append(opts, workingDirOption(scenarioDir))
+
+	// File reader for stdout_file/stderr_file; static fixtures are left
+	// for writeResponse to stream, so large ones never sit in memory
 	opts = append(opts, replay.WithFileReader(func(relPath string) (string, error) {
-		return readFile(scenarioDir, relPath)
+		return readScenarioFile(scn, scenarioDir, relPath)
 	}))
+	opts = append(opts, replay.WithFileStreaming(func(relPath string) (bool, error) {
+		fullPath, err := fixture.Path(scn, scenarioDir, relPath)
+		if err != nil {
+			return false, err
+		}
+		templated, err := fixture.HasTemplate(fullPath, relPath)
+		return !templated, err
+	}))
+
+	// Reproducible respond.random selection
+	if seed, ok, err := SeedFromEnv(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "cli-replay: warning: ignoring %s: %v\n", SeedEnvVar, err)
+	} else if ok {
+		opts = append(opts, replay.WithSeed(seed))
+	}
 
 	return opts
 }
 
+// isNoMatchError reports whether err means the argv matched no expected step.
+func isNoMatchError(err error) bool {
+	switch err.(type) {
+	case *replay.MismatchError, *replay.GroupMismatchError:
+		return true
+	default:
+		return false
+	}
+}
+
+// serveFallback writes the scenario's fallback response. State is not
+// written, so step counts and the current step are left untouched.
+func serveFallback(engine *replay.Engine, scn *scenario.Scenario, scenarioDir string, argv []string, stdout, stderr io.Writer) (*ReplayResult, error) {
+	scenarioName := scn.Meta.Name
+	result, err := engine.Fallback(context.Background())
+	if err != nil {
+		return &ReplayResult{ExitCode: 1, StepIndex: -1, ScenarioName: scenarioName}, err
+	}
+	stdoutBytes, stderrBytes, err := writeResponse(result, scn, scenarioDir, nil, stdout, stderr)
+	if err != nil {
+		return &ReplayResult{ExitCode: 1, StepIndex: -1, ScenarioName: scenarioName, Fallback: true}, err
+	}
+	if tw := traceWriter(stderr); tw != nil {
+		WriteFallbackTrace(tw, argv, result.ExitCode)
+	}
+	return &ReplayResult{
+		ExitCode:     result.ExitCode,
+		StepIndex:    -1,
+		ScenarioName: scenarioName,
+		Fallback:     true,
+		StdoutBytes:  stdoutBytes,
+		StderrBytes:  stderrBytes,
+	}, nil
+}
+
+// writeResponse writes a served response: the respond.output chunks in
+// their declared order, or all of stdout followed by all of stderr.
+// Static fixtures the engine left for streaming are copied from disk as
+// they are read. When prev is set, the streamed content is also stored in
+// it, since the engine never saw it. It returns the bytes written per
+// stream; an error means a fixture could not be read.
+func writeResponse(result *replay.Result, scn *scenario.Scenario, scenarioDir string, prev *replay.Served, stdout, stderr io.Writer) (stdoutBytes, stderrBytes int, err error) {
+	if result.Output != nil {
+		for _, chunk := range result.Output {
+			w := stdout
+			if chunk.Stream == scenario.StreamStderr {
+				w = stderr
+			}
+			_, _ = io.WriteString(w, chunk.Text)
+		}
+		return len(result.Stdout), len(result.Stderr), nil
+	}
+	var keepOut, keepErr *strings.Builder
+	if prev != nil {
+		keepOut, keepErr = &strings.Builder{}, &strings.Builder{}
+	}
+	stdoutBytes, err = writeStream(stdout, result.Stdout, result.StdoutFile, scn, scenarioDir, keepOut)
+	if err != nil {
+		return stdoutBytes, 0, fmt.Errorf("failed to read stdout_file: %w", err)
+	}
+	stderrBytes, err = writeStream(stderr, result.Stderr, result.StderrFile, scn, scenarioDir, keepErr)
+	if err != nil {
+		return stdoutBytes, stderrBytes, fmt.Errorf("failed to read stderr_file: %w", err)
+	}
+	if prev != nil {
+		if result.StdoutFile != "" {
+			prev.Stdout = keepOut.String()
+		}
+		if result.StderrFile != "" {
+			prev.Stderr = keepErr.String()
+		}
+	}
+	return stdoutBytes, stderrBytes, nil
+}
+
+// writeStream writes text to w, or streams the fixture file of scn when
+// set, copying the streamed bytes to keep when it is non-nil. As for
+// inline output, errors writing to w are ignored; only a failure to read
+// the fixture is returned.
+func writeStream(w io.Writer, text, file string, scn *scenario.Scenario, scenarioDir string, keep *strings.Builder) (int, error) {
+	if file == "" {
+		if text != "" {
+			_, _ = io.WriteString(w, text)
+		}
+		return len(text), nil
+	}
+	fullPath, err := fixture.Path(scn, scenarioDir, file)
+	if err != nil {
+		return 0, err
+	}
+	cw := &countingWriter{w: w, keep: keep}
+	if err := fixture.Copy(cw, fullPath, file); err != nil && cw.err == nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+// countingWriter counts the bytes written to w, copying them to keep when
+// set, and keeps the first write error, so a failed write can be told
+// apart from a failed fixture read.
+type countingWriter struct {
+	w    io.Writer
+	keep *strings.Builder
+	n    int
+	err  error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	if c.keep != nil {
+		c.keep.Write(p[:n])
+	}
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	return n, err
+}
+
 // convertEngineError maps pkg/replay error types to internal/runner error types
-// for backward compatibility with existing CLI error formatting.
-func convertEngineError(err error, scenarioName string, state *State, stateFile string) (*ReplayResult, error) {
+// for backward compatibility with existing CLI error formatting. Mismatches
+// and a completed scenario exit with the codes set in meta.exit_codes.
+func convertEngineError(err error, meta *scenario.Meta, flatSteps []scenario.Step, state *State, stateFile string) (*ReplayResult, error) {
+	scenarioName := meta.Name
 	switch e := err.(type) {
 	case *replay.MismatchError:
+		expected := e.Expected
+		if len(e.ExpectedAnyOf) > 0 {
+			expected = closestArgv(e.Received, e.ExpectedAnyOf)
+		}
 		return &ReplayResult{
-			ExitCode:     1,
+			ExitCode:     meta.MismatchExitCode(),
 			Matched:      false,
 			StepIndex:    e.StepIndex,
 			ScenarioName: scenarioName,
 		}, &MismatchError{
 			Scenario:      scenarioName,
 			StepIndex:     e.StepIndex,
-			Expected:      e.Expected,
+			Expected:      expected,
 			Received:      e.Received,
 			SoftAdvanced:  e.SoftAdvanced,
 			NextStepIndex: e.NextStepIndex,
 			NextExpected:  e.NextExpected,
+			GroupName:     e.GroupName,
+			ExpectedAnyOf: e.ExpectedAnyOf,
+			EnvMismatches: convertEnvMismatches(e.EnvMismatches),
+			CwdMismatch:   convertCwdMismatch(e.CwdMismatch),
+			Suggestion:    suggestStep(e.Received, e.StepIndex, flatSteps, state),
 		}
 	case *replay.GroupMismatchError:
+		candidates, candidateArgv := rankCandidates(e.Received, e.Candidates, e.CandidateArgv)
 		return &ReplayResult{
-			ExitCode:     1,
+			ExitCode:     meta.MismatchExitCode(),
 			Matched:      false,
 			ScenarioName: scenarioName,
 		}, &GroupMismatchError{
 			Scenario:      scenarioName,
 			GroupName:     e.GroupName,
 			GroupIndex:    e.GroupIndex,
-			Candidates:    e.Candidates,
-			CandidateArgv: e.CandidateArgv,
+			Candidates:    candidates,
+			CandidateArgv: candidateArgv,
 			Received:      e.Received,
 		}
+	case *replay.DependencyError:
+		return &ReplayResult{
+			ExitCode:     meta.MismatchExitCode(),
+			Matched:      false,
+			StepIndex:    e.StepIndex,
+			ScenarioName: scenarioName,
+		}, &DependencyError{
+			Scenario:  scenarioName,
+			GroupName: e.GroupName,
+			StepIndex: e.StepIndex,
+			StepName:  e.StepName,
+			Unmet:     e.Unmet,
+			Received:  e.Received,
+		}
 	case *replay.ScenarioCompleteError:
 		return &ReplayResult{
-			ExitCode:     1,
+			ExitCode:     meta.CompleteExitCode(),
 			ScenarioName: scenarioName,
 		}, fmt.Errorf("scenario already complete")
 	default:
@@ -356,6 +827,34 @@ func convertEngineError(err error, scenarioName string, state *State, stateFile
 	}
 }
 
+// convertEnvMismatches maps engine env mismatches to runner types.
+func convertEnvMismatches(in []replay.EnvMismatch) []EnvMismatch {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]EnvMismatch, len(in))
+	for i, m := range in {
+		out[i] = EnvMismatch{Name: m.Name, Expected: m.Expected, Actual: m.Actual}
+	}
+	return out
+}
+
+// convertCwdMismatch maps an engine cwd mismatch to the runner type.
+func convertCwdMismatch(in *replay.CwdMismatch) *CwdMismatch {
+	if in == nil {
+		return nil
+	}
+	return &CwdMismatch{Expected: in.Expected, Actual: in.Actual}
+}
+
+// workingDirOption configures the engine with the process working directory
+// for match.cwd. An unreadable working directory leaves it empty, so steps
+// with match.cwd do not match.
+func workingDirOption(scenarioDir string) replay.Option {
+	cwd, _ := os.Getwd()
+	return replay.WithWorkingDir(cwd, scenarioDir)
+}
+
 // hashScenarioFile calculates SHA256 hash of the scenario file content.
 func hashScenarioFile(path string) string {
 	data, err := os.ReadFile(path) //nolint:gosec // File path from user input
@@ -372,9 +871,29 @@ type MismatchError struct {
 	StepIndex     int
 	Expected      []string
 	Received      []string
-	SoftAdvanced  bool     // true if we tried soft-advancing past a satisfied step
-	NextStepIndex int      // index of the next step tried (when SoftAdvanced)
-	NextExpected  []string // argv of the next step tried (when SoftAdvanced)
+	SoftAdvanced  bool       // true if we tried soft-advancing past a satisfied step
+	NextStepIndex int        // index of the next step tried (when SoftAdvanced)
+	NextExpected  []string   // argv of the next step tried (when SoftAdvanced)
+	GroupName     string     // ordered group containing StepIndex, if any
+	ExpectedAnyOf [][]string // every alternative when the step uses match.any_of
+	EnvMismatches []EnvMismatch
+	CwdMismatch   *CwdMismatch    // set when argv matched but match.cwd did not
+	Suggestion    *ErrorCandidate // closest other remaining step, if any is similar enough
+}
+
+// EnvMismatch describes a match.env expectation that the environment at
+// invocation did not satisfy.
+type EnvMismatch struct {
+	Name     string
+	Expected string
+	Actual   string
+}
+
+// CwdMismatch describes a match.cwd expectation that the working directory
+// at invocation did not satisfy.
+type CwdMismatch struct {
+	Expected string
+	Actual   string
 }
 
 func (e *MismatchError) Error() string {
@@ -382,19 +901,28 @@ func (e *MismatchError) Error() string {
 }
 
 // StdinMismatchError represents a stdin content mismatch during replay.
+// For a step matched by match.stdin_sha256, Expected is empty and the
+// digests are set instead.
 type StdinMismatchError struct {
-	Scenario  string
-	StepIndex int
-	Expected  string
-	Received  string
+	Scenario       string
+	StepIndex      int
+	Expected       string
+	Received       string
+	ExpectedSHA256 string
+	ReceivedSHA256 string
 }
 
 func (e *StdinMismatchError) Error() string {
+	if e.ExpectedSHA256 != "" {
+		return fmt.Sprintf("stdin mismatch at step %d: sha256 %s does not match stdin_sha256 %s",
+			e.StepIndex, e.ReceivedSHA256, e.ExpectedSHA256)
+	}
 	return fmt.Sprintf("stdin mismatch at step %d", e.StepIndex)
 }
 
 // GroupMismatchError is returned when a command does not match any step
 // within an unordered group and the group's minimum counts are not yet met.
+// Candidates are ordered by similarity to the received argv, closest first.
 type GroupMismatchError struct {
 	Scenario      string
 	GroupName     string
@@ -409,12 +937,171 @@ func (e *GroupMismatchError) Error() string {
 		e.GroupName, e.GroupIndex, e.Received)
 }
 
-// maxStdinBytes is the maximum number of bytes to read from stdin (1 MB).
-const maxStdinBytes = 1 << 20
+// DependencyError is returned when a command matches an unordered group
+// child whose depends_on siblings have not yet met their min counts.
+type DependencyError struct {
+	Scenario  string
+	GroupName string
+	StepIndex int      // flat index of the blocked step
+	StepName  string   // name of the blocked step
+	Unmet     []string // depends_on names that have not met their min
+	Received  []string // the received argv
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("step %d in group %q called before its dependencies %v met their min call counts: received %v",
+		e.StepIndex, e.GroupName, e.Unmet, e.Received)
+}
+
+// StrictStateEnvVar makes replay fail instead of resetting state when the
+// scenario file changed since the state was created.
+const StrictStateEnvVar = "CLI_REPLAY_STRICT_STATE"
+
+// IsStrictStateEnabled returns true if CLI_REPLAY_STRICT_STATE is set to a
+// true value.
+func IsStrictStateEnabled() bool {
+	return IsTraceEnabled(os.Getenv(StrictStateEnvVar))
+}
+
+// SeedEnvVar fixes the seed for respond.random selection, so a run picks
+// the same responses every time.
+const SeedEnvVar = "CLI_REPLAY_SEED"
+
+// SeedFromEnv parses CLI_REPLAY_SEED. ok is false when it is unset.
+func SeedFromEnv() (seed int64, ok bool, err error) {
+	v := strings.TrimSpace(os.Getenv(SeedEnvVar))
+	if v == "" {
+		return 0, false, nil
+	}
+	seed, err = strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("not an integer: %q", v)
+	}
+	return seed, true, nil
+}
+
+// StaleStateError is returned in strict mode when the scenario file no
+// longer matches the hash recorded in its state.
+type StaleStateError struct {
+	Scenario  string
+	StateFile string
+}
+
+func (e *StaleStateError) Error() string {
+	return fmt.Sprintf("scenario %q changed since state was created (state: %s); run 'cli-replay reset' or start a new session",
+		e.Scenario, e.StateFile)
+}
+
+// CallBudgetExceededError is returned when a session has already served
+// meta.max_total_calls matched calls.
+type CallBudgetExceededError struct {
+	Scenario string
+	Limit    int
+	Received []string
+}
+
+func (e *CallBudgetExceededError) Error() string {
+	return fmt.Sprintf("call budget exceeded: scenario %q allows %d calls in total (meta.max_total_calls), received %v",
+		e.Scenario, e.Limit, e.Received)
+}
+
+// MismatchLoopError is returned when the same unmatched command has arrived
+// meta.limits.max_consecutive_mismatches times in a row. It tells the child
+// to stop retrying rather than loop until the test times out.
+type MismatchLoopError struct {
+	Scenario string
+	Received []string
+	Count    int
+	Limit    int
+}
+
+func (e *MismatchLoopError) Error() string {
+	return fmt.Sprintf("aborting replay: scenario %q received unmatched %v %d times in a row (meta.limits.max_consecutive_mismatches: %d); stop retrying this command",
+		e.Scenario, e.Received, e.Count, e.Limit)
+}
+
+// DisallowedCommandError is returned when the matched step's command is not
+// in meta.security.allowed_commands. The step is not served and state is
+// left unchanged.
+type DisallowedCommandError struct {
+	Scenario  string
+	StepIndex int
+	Command   string   // base name of the step's argv[0]
+	Allowed   []string // meta.security.allowed_commands
+}
+
+func (e *DisallowedCommandError) Error() string {
+	return fmt.Sprintf("command %q at step %d is not in the allowed commands list: %v",
+		e.Command, e.StepIndex, e.Allowed)
+}
 
-// readStdin reads stdin content up to maxStdinBytes.
-func readStdin() (string, error) {
-	data, err := io.ReadAll(io.LimitReader(os.Stdin, maxStdinBytes))
+// checkAllowedCommand returns a DisallowedCommandError if the scenario has
+// an allowlist and the base name of argv[0] is not on it. Names compare
+// case-insensitively on Windows, as in 'cli-replay run'.
+func checkAllowedCommand(scn *scenario.Scenario, stepIndex int, argv []string) error {
+	if scn.Meta.Security == nil || len(scn.Meta.Security.AllowedCommands) == 0 || len(argv) == 0 {
+		return nil
+	}
+	command := filepath.Base(argv[0])
+	for _, allowed := range scn.Meta.Security.AllowedCommands {
+		if allowed == command || (runtime.GOOS == "windows" && strings.EqualFold(allowed, command)) {
+			return nil
+		}
+	}
+	return &DisallowedCommandError{
+		Scenario:  scn.Meta.Name,
+		StepIndex: stepIndex,
+		Command:   command,
+		Allowed:   scn.Meta.Security.AllowedCommands,
+	}
+}
+
+// ForbiddenCommandError is returned when the received command is in
+// meta.security.forbidden_commands. No step is served; the call is recorded
+// in state so that exec and verify fail the run.
+type ForbiddenCommandError struct {
+	Scenario string
+	Command  string   // base name of the received argv[0]
+	Received []string // the full received argv
+}
+
+func (e *ForbiddenCommandError) Error() string {
+	return fmt.Sprintf("command %q is forbidden by meta.security.forbidden_commands: received %v",
+		e.Command, e.Received)
+}
+
+// checkForbiddenCommand returns a ForbiddenCommandError if the base name of
+// argv[0] is in meta.security.forbidden_commands. Names compare
+// case-insensitively on Windows, as in checkAllowedCommand.
+func checkForbiddenCommand(scn *scenario.Scenario, argv []string) error {
+	if scn.Meta.Security == nil || len(scn.Meta.Security.ForbiddenCommands) == 0 || len(argv) == 0 {
+		return nil
+	}
+	command := filepath.Base(argv[0])
+	for _, forbidden := range scn.Meta.Security.ForbiddenCommands {
+		if forbidden == command || (runtime.GOOS == "windows" && strings.EqualFold(forbidden, command)) {
+			return &ForbiddenCommandError{Scenario: scn.Meta.Name, Command: command, Received: argv}
+		}
+	}
+	return nil
+}
+
+// stdinLimit returns how many bytes of stdin to read for match.stdin: the
+// session's --max-stdin override, then meta.limits.max_stdin_bytes, then
+// scenario.DefaultMaxStdinBytes.
+func stdinLimit(scn *scenario.Scenario, state *State) int64 {
+	if state != nil && state.MaxStdinBytes > 0 {
+		return state.MaxStdinBytes
+	}
+	if scn.Meta.Limits != nil && scn.Meta.Limits.MaxStdinBytes > 0 {
+		return scn.Meta.Limits.MaxStdinBytes
+	}
+	return scenario.DefaultMaxStdinBytes
+}
+
+// readStdin reads stdin content up to limit bytes.
+func readStdin(limit int64) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(os.Stdin, limit))
 	if err != nil {
 		return "", fmt.Errorf("failed to read stdin: %w", err)
 	}
@@ -424,6 +1111,11 @@ func readStdin() (string, error) {
 // normalizeStdin normalizes stdin content for comparison:
 // converts \r\n to \n and trims trailing newlines.
 func normalizeStdin(s string) string {
-	s = strings.ReplaceAll(s, "\r\n", "\n")
-	return strings.TrimRight(s, "\n")
+	return strings.TrimRight(normalizeNewlines(s), "\n")
+}
+
+// normalizeNewlines converts \r\n line endings to \n, so output captured on
+// Windows compares equal to the same output captured elsewhere.
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
 }
@@ -5,10 +5,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 )
 
 // TestIntegration_SingleStepScenario tests the full replay flow for a single-step scenario.
@@ -463,3 +466,97 @@ steps:
 	// first_pod is empty (not captured yet), base_id is available from step 0
 	assert.Equal(t, "svc for [] base=base-123", stdout1.String())
 }
+
+// TestIntegration_ConcurrentReplaysDoNotLoseIncrements verifies that the
+// state lock makes ExecuteReplay's read-modify-write atomic when many
+// invocations race on the same state file.
+func TestIntegration_ConcurrentReplaysDoNotLoseIncrements(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLI_REPLAY_SESSION", "")
+
+	scenarioContent := `
+meta:
+  name: "concurrent-polling"
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    calls:
+      min: 1
+      max: 1000
+    respond:
+      exit: 0
+      stdout: "Running"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	const calls = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var stdout, stderr bytes.Buffer
+			if _, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	absPath, err := filepath.Abs(scenarioPath)
+	require.NoError(t, err)
+	state, err := ReadState(StateFilePath(absPath))
+	require.NoError(t, err)
+	require.Len(t, state.StepCounts, 1)
+	assert.Equal(t, calls, state.StepCounts[0], "every concurrent call must be counted")
+}
+
+// TestIntegration_SeekToServesStartStepDirectly verifies that a state
+// positioned with SeekTo serves the start step's response on the first call.
+func TestIntegration_SeekToServesStartStepDirectly(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLI_REPLAY_SESSION", "")
+
+	scenarioContent := `
+meta:
+  name: "seek"
+steps:
+  - match:
+      argv: ["kubectl", "apply"]
+    respond:
+      exit: 0
+      stdout: "step 0"
+  - match:
+      argv: ["kubectl", "rollout", "status"]
+    respond:
+      exit: 0
+      stdout: "step 1"
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+      stdout: "step 2"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+	absPath, err := filepath.Abs(scenarioPath)
+	require.NoError(t, err)
+
+	scn, err := scenario.LoadFile(absPath)
+	require.NoError(t, err)
+	state := NewState(absPath, "", 3)
+	require.NoError(t, state.SeekTo(scn.FlatSteps(), scn.GroupRanges(), 2))
+	require.NoError(t, WriteState(StateFilePath(absPath), state))
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err, stderr.String())
+	assert.Equal(t, 2, result.StepIndex)
+	assert.Equal(t, "step 2", stdout.String())
+}
@@ -2,21 +2,29 @@ package runner
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/ormasoftchile/cli-replay/pkg/verify"
 )
 
 func TestReplayResponse_Stdout(t *testing.T) {
 	step := &scenario.Step{
 		Match: scenario.Match{Argv: []string{"cmd"}},
 		Respond: scenario.Response{
-			Exit:   0,
+			Exit:   scenario.Exit(0),
 			Stdout: "hello world\n",
 		},
 	}
@@ -33,7 +41,7 @@ func TestReplayResponse_Stderr(t *testing.T) {
 	step := &scenario.Step{
 		Match: scenario.Match{Argv: []string{"cmd"}},
 		Respond: scenario.Response{
-			Exit:   1,
+			Exit:   scenario.Exit(1),
 			Stderr: "error: something went wrong\n",
 		},
 	}
@@ -50,7 +58,7 @@ func TestReplayResponse_BothStdoutAndStderr(t *testing.T) {
 	step := &scenario.Step{
 		Match: scenario.Match{Argv: []string{"cmd"}},
 		Respond: scenario.Response{
-			Exit:   2,
+			Exit:   scenario.Exit(2),
 			Stdout: "partial output\n",
 			Stderr: "warning: incomplete\n",
 		},
@@ -68,7 +76,7 @@ func TestReplayResponse_ExitCodeOnly(t *testing.T) {
 	step := &scenario.Step{
 		Match: scenario.Match{Argv: []string{"cmd"}},
 		Respond: scenario.Response{
-			Exit: 42,
+			Exit: scenario.Exit(42),
 		},
 	}
 
@@ -84,7 +92,7 @@ func TestReplayResponse_ExitCode255(t *testing.T) {
 	step := &scenario.Step{
 		Match: scenario.Match{Argv: []string{"cmd"}},
 		Respond: scenario.Response{
-			Exit: 255,
+			Exit: scenario.Exit(255),
 		},
 	}
 
@@ -110,7 +118,7 @@ func TestReplayResponse_StdoutFile(t *testing.T) {
 	step := &scenario.Step{
 		Match: scenario.Match{Argv: []string{"cmd"}},
 		Respond: scenario.Response{
-			Exit:       0,
+			Exit:       scenario.Exit(0),
 			StdoutFile: "fixtures/output.txt",
 		},
 	}
@@ -139,7 +147,7 @@ func TestReplayResponse_StderrFile(t *testing.T) {
 	step := &scenario.Step{
 		Match: scenario.Match{Argv: []string{"cmd"}},
 		Respond: scenario.Response{
-			Exit:       1,
+			Exit:       scenario.Exit(1),
 			StderrFile: "fixtures/error.txt",
 		},
 	}
@@ -159,7 +167,7 @@ func TestReplayResponse_FileNotFound(t *testing.T) {
 	step := &scenario.Step{
 		Match: scenario.Match{Argv: []string{"cmd"}},
 		Respond: scenario.Response{
-			Exit:       0,
+			Exit:       scenario.Exit(0),
 			StdoutFile: "nonexistent.txt",
 		},
 	}
@@ -173,7 +181,159 @@ func TestReplayResponse_FileNotFound(t *testing.T) {
 	assert.Contains(t, stderr.String(), "failed to read")
 }
 
+func TestReplayResponse_NestedFixtureLoads(t *testing.T) {
+	tmpDir := t.TempDir()
+	nested := filepath.Join(tmpDir, "fixtures", "kubectl", "pods")
+	require.NoError(t, os.MkdirAll(nested, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(nested, "list.txt"), []byte("pod-a\n"), 0600))
+
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{StdoutFile: "fixtures/kubectl/../kubectl/pods/list.txt"},
+	}
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithFile(step, filepath.Join(tmpDir, "scenario.yaml"), &stdout, &stderr)
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "pod-a\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestReplayResponse_PathTraversalRefused(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioDir := filepath.Join(tmpDir, "scenarios")
+	require.NoError(t, os.MkdirAll(scenarioDir, 0750))
+	secret := filepath.Join(tmpDir, "passwd")
+	require.NoError(t, os.WriteFile(secret, []byte("root:x:0:0\n"), 0600))
+
+	for _, rel := range []string{"../passwd", "fixtures/../../passwd", secret} {
+		t.Run(rel, func(t *testing.T) {
+			step := &scenario.Step{
+				Match:   scenario.Match{Argv: []string{"cmd"}},
+				Respond: scenario.Response{StdoutFile: rel},
+			}
+
+			var stdout, stderr bytes.Buffer
+			exitCode := ReplayResponseWithFile(step, filepath.Join(scenarioDir, "scenario.yaml"), &stdout, &stderr)
+
+			assert.Equal(t, 1, exitCode)
+			assert.Empty(t, stdout.String())
+			assert.Contains(t, stderr.String(), "failed to read stdout_file")
+			assert.Contains(t, stderr.String(), rel)
+		})
+	}
+}
+
 // T026: Unit tests for step ordering enforcement
+func TestReplayResponse_GzipStdoutFileMatchesPlaintext(t *testing.T) {
+	tmpDir := t.TempDir()
+	fixtureDir := filepath.Join(tmpDir, "fixtures")
+	require.NoError(t, os.MkdirAll(fixtureDir, 0750))
+
+	content := []byte(`{"value": [{"name": "rg-1"}, {"name": "rg-2"}]}` + "\n")
+	require.NoError(t, os.WriteFile(filepath.Join(fixtureDir, "groups.json"), content, 0600))
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(fixtureDir, "groups.json.gz"), gz.Bytes(), 0600))
+
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	render := func(file string) string {
+		step := &scenario.Step{
+			Match:   scenario.Match{Argv: []string{"az"}},
+			Respond: scenario.Response{Exit: scenario.Exit(0), StdoutFile: file},
+		}
+		var stdout, stderr bytes.Buffer
+		exitCode := ReplayResponseWithFile(step, scenarioPath, &stdout, &stderr)
+		require.Equal(t, 0, exitCode, stderr.String())
+		return stdout.String()
+	}
+
+	plain := render("fixtures/groups.json")
+	assert.Equal(t, string(content), plain)
+	assert.Equal(t, plain, render("fixtures/groups.json.gz"))
+}
+
+func TestExecuteReplay_StdoutFileGlobServesNewest(t *testing.T) {
+	tmpDir := t.TempDir()
+	fixtureDir := filepath.Join(tmpDir, "fixtures")
+	require.NoError(t, os.MkdirAll(fixtureDir, 0750))
+
+	older := filepath.Join(fixtureDir, "pods-20260101.json")
+	newer := filepath.Join(fixtureDir, "pods-20260102.json")
+	require.NoError(t, os.WriteFile(newer, []byte("newer\n"), 0600))
+	require.NoError(t, os.WriteFile(older, []byte("older\n"), 0600))
+	now := time.Now()
+	require.NoError(t, os.Chtimes(older, now.Add(-time.Hour), now.Add(-time.Hour)))
+	require.NoError(t, os.Chtimes(newer, now, now))
+
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: glob-fixture
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    calls: { min: 1, max: 2 }
+    respond:
+      exit: 0
+      stdout_file: "fixtures/pods-*.json"
+`), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err, stderr.String())
+	assert.Equal(t, "newer\n", stdout.String())
+
+	// Selection follows modification time, not name order
+	require.NoError(t, os.Chtimes(older, now.Add(time.Hour), now.Add(time.Hour)))
+	stdout.Reset()
+	_, err = ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err, stderr.String())
+	assert.Equal(t, "older\n", stdout.String())
+}
+
+func TestFixturePath_Glob(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	_, err := FixturePath(nil, tmpDir, "fixtures/none-*.json")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Contains(t, err.Error(), "fixtures/none-*.json: no fixture matches the pattern")
+
+	_, err = FixturePath(nil, tmpDir, "../*.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "escapes")
+
+	// Without glob syntax the path is taken as is, even if it does not exist
+	got, err := FixturePath(nil, tmpDir, "missing.txt")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, "missing.txt"), got)
+}
+
+func TestReplayResponse_CorruptGzipFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "out.txt.gz"), []byte("not gzip data"), 0600))
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), StdoutFile: "out.txt.gz"},
+	}
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithFile(step, scenarioPath, &stdout, &stderr)
+
+	assert.Equal(t, 1, exitCode)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "failed to read stdout_file")
+	assert.Contains(t, stderr.String(), "out.txt.gz: invalid gzip data")
+}
+
 func TestReplayResponse_StepOrdering(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -345,6 +505,9 @@ steps:
 	require.NoError(t, err)
 	assert.Equal(t, 0, result.ExitCode)
 	assert.Contains(t, stdout2.String(), "done")
+	assert.True(t, result.SoftAdvanced)
+	assert.Equal(t, 1, result.StepIndex)
+	assert.Equal(t, len("done\n"), result.StdoutBytes)
 }
 
 func TestExecuteReplay_HardMismatchWhenMinNotMet(t *testing.T) {
@@ -419,6 +582,14 @@ steps:
 	require.ErrorAs(t, err, &mErr)
 }
 
+func TestNormalizeNewlines(t *testing.T) {
+	crlf, lf := "line1\r\nline2\r\n", "line1\nline2\n"
+	assert.NotEqual(t, crlf, lf)
+	assert.Equal(t, lf, normalizeNewlines(crlf))
+	assert.Equal(t, normalizeNewlines(lf), normalizeNewlines(crlf))
+	assert.Equal(t, "a\rb\n", normalizeNewlines("a\rb\n"), "a lone \\r is kept")
+}
+
 // T031: Tests for normalizeStdin
 
 func TestNormalizeStdin(t *testing.T) {
@@ -454,6 +625,143 @@ func TestStdinMatchFixtureLoads(t *testing.T) {
 	assert.Empty(t, sc.Steps[2].Step.Match.Stdin)
 }
 
+// largeStdinScenario writes a scenario whose single step expects stdin of
+// the given size, with limitsYAML spliced into meta, and returns its path
+// and the matching stdin content.
+func largeStdinScenario(t *testing.T, size int, limitsYAML string) (string, string) {
+	t.Helper()
+	var content, block bytes.Buffer
+	for i := 0; content.Len() < size; i++ {
+		line := fmt.Sprintf("line %d of a large manifest", i)
+		content.WriteString(line + "\n")
+		block.WriteString("        " + line + "\n")
+	}
+	yaml := "meta:\n  name: large-stdin\n" + limitsYAML + `steps:
+  - match:
+      argv: ["kubectl", "apply", "-f", "-"]
+      stdin: |
+` + block.String() + `    respond:
+      exit: 0
+      stdout: "applied\n"
+`
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(yaml), 0644))
+	return path, content.String()
+}
+
+// withStdin points os.Stdin at a file holding content for the test.
+func withStdin(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	orig := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() {
+		os.Stdin = orig
+		_ = f.Close()
+	})
+}
+
+func TestExecuteReplay_MaxStdinBytes(t *testing.T) {
+	argv := []string{"kubectl", "apply", "-f", "-"}
+	size := 2 * scenario.DefaultMaxStdinBytes
+
+	t.Run("default limit truncates", func(t *testing.T) {
+		path, content := largeStdinScenario(t, size, "")
+		withStdin(t, content)
+		_, err := ExecuteReplay(path, argv, &bytes.Buffer{}, &bytes.Buffer{})
+		var sErr *StdinMismatchError
+		require.ErrorAs(t, err, &sErr)
+		assert.Len(t, sErr.Received, scenario.DefaultMaxStdinBytes)
+	})
+
+	t.Run("meta.limits raises the limit", func(t *testing.T) {
+		path, content := largeStdinScenario(t, size, "  limits:\n    max_stdin_bytes: 4194304\n")
+		withStdin(t, content)
+		var stdout bytes.Buffer
+		result, err := ExecuteReplay(path, argv, &stdout, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.ExitCode)
+		assert.Equal(t, "applied\n", stdout.String())
+	})
+
+	t.Run("session override raises the limit", func(t *testing.T) {
+		path, content := largeStdinScenario(t, size, "  limits:\n    max_stdin_bytes: 1024\n")
+		state := NewState(path, hashScenarioFile(path), 1)
+		state.MaxStdinBytes = 4 << 20
+		require.NoError(t, WriteState(StateFilePath(path), state))
+		t.Cleanup(func() { _ = DeleteState(StateFilePath(path)) })
+
+		withStdin(t, content)
+		_, err := ExecuteReplay(path, argv, &bytes.Buffer{}, &bytes.Buffer{})
+		require.NoError(t, err)
+	})
+}
+
+func TestExecuteReplay_StdinSHA256(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	argv := []string{"kubectl", "apply", "-f", "-"}
+	manifest := "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n"
+	writeScenario := func(t *testing.T) string {
+		t.Helper()
+		content := fmt.Sprintf(`
+meta:
+  name: hashed-stdin
+steps:
+  - match:
+      argv: ["kubectl", "apply", "-f", "-"]
+      stdin_sha256: %s
+    respond:
+      stdout: "pod/web created\n"
+`, matcher.StdinSHA256(manifest))
+		path := filepath.Join(t.TempDir(), "scenario.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	t.Run("matching payload served", func(t *testing.T) {
+		path := writeScenario(t)
+		withStdin(t, strings.ReplaceAll(manifest, "\n", "\r\n"))
+		var stdout bytes.Buffer
+		_, err := ExecuteReplay(path, argv, &stdout, &bytes.Buffer{})
+		require.NoError(t, err, "line endings are normalized before hashing")
+		assert.Equal(t, "pod/web created\n", stdout.String())
+	})
+
+	t.Run("different payload rejected", func(t *testing.T) {
+		path := writeScenario(t)
+		other := strings.Replace(manifest, "web", "api", 1)
+		withStdin(t, other)
+		result, err := ExecuteReplay(path, argv, &bytes.Buffer{}, &bytes.Buffer{})
+		var sErr *StdinMismatchError
+		require.ErrorAs(t, err, &sErr)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Equal(t, matcher.StdinSHA256(manifest), sErr.ExpectedSHA256)
+		assert.Equal(t, matcher.StdinSHA256(other), sErr.ReceivedSHA256)
+		assert.Equal(t, other, sErr.Received)
+		assert.Contains(t, err.Error(), "does not match stdin_sha256")
+		assert.Contains(t, FormatStdinMismatchError(sErr), "stdin_sha256 mismatch")
+
+		got := decodeErrorJSON(t, err)
+		assert.Equal(t, "sha256:"+matcher.StdinSHA256(manifest), got["expected"])
+
+		withStdin(t, manifest)
+		_, err = ExecuteReplay(path, argv, &bytes.Buffer{}, &bytes.Buffer{})
+		assert.NoError(t, err, "the step was not consumed by the mismatch")
+	})
+}
+
+func TestStdinLimit(t *testing.T) {
+	scn := &scenario.Scenario{}
+	assert.Equal(t, int64(scenario.DefaultMaxStdinBytes), stdinLimit(scn, &State{}))
+
+	scn.Meta.Limits = &scenario.Limits{MaxStdinBytes: 2048}
+	assert.Equal(t, int64(2048), stdinLimit(scn, &State{}))
+	assert.Equal(t, int64(4096), stdinLimit(scn, &State{MaxStdinBytes: 4096}))
+}
+
 // T027: Unordered group replay tests
 
 func TestExecuteReplay_GroupAnyOrderMatching(t *testing.T) {
@@ -498,6 +806,10 @@ steps:
 		require.NoError(t, rErr, "check %s", arg)
 		assert.Equal(t, 0, result.ExitCode)
 		assert.Contains(t, stdout.String(), arg+" ok")
+		assert.Equal(t, "pre-flight", result.Group)
+		assert.Equal(t, len(arg+" ok\n"), result.StdoutBytes)
+		assert.Zero(t, result.StderrBytes)
+		assert.False(t, result.SoftAdvanced)
 	}
 
 	// After group exhausted, ordered step should match
@@ -506,6 +818,7 @@ steps:
 	require.NoError(t, rErr)
 	assert.Equal(t, 0, result.ExitCode)
 	assert.Contains(t, stdout.String(), "deployed")
+	assert.Empty(t, result.Group)
 }
 
 func TestExecuteReplay_GroupBarrierBlocksOrderedStep(t *testing.T) {
@@ -640,433 +953,2288 @@ steps:
 	assert.Contains(t, stdout2.String(), "after group")
 }
 
-func TestExecuteReplay_GroupMismatchErrorCandidateList(t *testing.T) {
-	tmpDir := t.TempDir()
+func TestExecuteReplay_MatchEnvSelectsStep(t *testing.T) {
 	scenarioContent := `
 meta:
-  name: group-candidates
+  name: match-env
 steps:
   - group:
       mode: unordered
-      name: my-group
       steps:
         - match:
-            argv: ["cmd", "alpha"]
+            argv: ["az", "vm", "list"]
+            env:
+              REGION: eastus
           respond:
             exit: 0
+            stdout: "east vms\n"
         - match:
-            argv: ["cmd", "beta"]
+            argv: ["az", "vm", "list"]
+            env:
+              REGION: westus
           respond:
             exit: 0
+            stdout: "west vms\n"
 `
-	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
-	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
-	require.NoError(t, err)
+	for _, tc := range []struct {
+		region string
+		want   string
+	}{
+		{"eastus", "east vms\n"},
+		{"westus", "west vms\n"},
+	} {
+		t.Run(tc.region, func(t *testing.T) {
+			t.Setenv("REGION", tc.region)
+			scenarioPath := filepath.Join(t.TempDir(), "scenario.yaml")
+			require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+			var stdout, stderr bytes.Buffer
+			_, err := ExecuteReplay(scenarioPath, []string{"az", "vm", "list"}, &stdout, &stderr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, stdout.String())
+		})
+	}
+}
+
+func TestExecuteReplay_MatchEnvMismatchDiagnostics(t *testing.T) {
+	t.Setenv("KUBECONFIG", "/home/me/.kube/dev")
+	scenarioContent := `
+meta:
+  name: match-env-mismatch
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+      env:
+        KUBECONFIG: /home/me/.kube/prod
+    respond:
+      exit: 0
+`
+	scenarioPath := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
 
-	// Send an unknown command
 	var stdout, stderr bytes.Buffer
-	_, err = ExecuteReplay(scenarioPath, []string{"cmd", "gamma"}, &stdout, &stderr)
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
 	require.Error(t, err)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	require.Len(t, mErr.EnvMismatches, 1)
+	assert.Equal(t, EnvMismatch{Name: "KUBECONFIG", Expected: "/home/me/.kube/prod", Actual: "/home/me/.kube/dev"}, mErr.EnvMismatches[0])
 
-	var gErr *GroupMismatchError
-	require.ErrorAs(t, err, &gErr)
-	assert.Equal(t, "my-group", gErr.GroupName)
-	assert.Equal(t, []string{"cmd", "gamma"}, gErr.Received)
-	assert.Len(t, gErr.Candidates, 2)
-	assert.Len(t, gErr.CandidateArgv, 2)
-	assert.Equal(t, []string{"cmd", "alpha"}, gErr.CandidateArgv[0])
-	assert.Equal(t, []string{"cmd", "beta"}, gErr.CandidateArgv[1])
+	t.Setenv("CLI_REPLAY_COLOR", "0")
+	out := FormatMismatchError(mErr)
+	assert.Contains(t, out, "Environment mismatch:")
+	assert.Contains(t, out, `KUBECONFIG: expected "/home/me/.kube/prod", got "/home/me/.kube/dev"`)
 }
 
-func TestExecuteReplay_GroupAllMinZeroImmediateAdvance(t *testing.T) {
+func TestExecuteReplay_MatchCwd(t *testing.T) {
 	tmpDir := t.TempDir()
 	scenarioContent := `
 meta:
-  name: group-min-zero
+  name: match-cwd
 steps:
-  - group:
-      mode: unordered
-      name: optional
-      steps:
-        - match:
-            argv: ["cmd", "opt1"]
-          calls:
-            min: 0
-            max: 3
-          respond:
-            exit: 0
-        - match:
-            argv: ["cmd", "opt2"]
-          calls:
-            min: 0
-            max: 3
-          respond:
-            exit: 0
   - match:
-      argv: ["cmd", "next"]
+      argv: ["make", "build"]
+      cwd: app
     respond:
       exit: 0
-      stdout: "next\n"
+      stdout: "built\n"
 `
 	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
-	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+	appDir := filepath.Join(tmpDir, "app")
+	otherDir := filepath.Join(tmpDir, "other")
+	require.NoError(t, os.Mkdir(appDir, 0750))
+	require.NoError(t, os.Mkdir(otherDir, 0750))
+
+	orig, err := os.Getwd()
 	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.Chdir(orig) })
 
-	// Skip group entirely — mins are 0, barrier is immediately satisfiable
+	require.NoError(t, os.Chdir(otherDir))
 	var stdout, stderr bytes.Buffer
-	result, rErr := ExecuteReplay(scenarioPath, []string{"cmd", "next"}, &stdout, &stderr)
-	require.NoError(t, rErr)
-	assert.Equal(t, 0, result.ExitCode)
-	assert.Contains(t, stdout.String(), "next")
+	_, err = ExecuteReplay(scenarioPath, []string{"make", "build"}, &stdout, &stderr)
+	require.Error(t, err)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	require.NotNil(t, mErr.CwdMismatch)
+	assert.Equal(t, "app", mErr.CwdMismatch.Expected)
+	t.Setenv("CLI_REPLAY_COLOR", "0")
+	assert.Contains(t, FormatMismatchError(mErr), "Working directory mismatch:")
+
+	require.NoError(t, os.Chdir(appDir))
+	stdout.Reset()
+	_, err = ExecuteReplay(scenarioPath, []string{"make", "build"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "built\n", stdout.String())
 }
 
-func TestExecuteReplay_AdjacentGroups(t *testing.T) {
+func TestExecuteReplay_MatchCwdGlob(t *testing.T) {
 	tmpDir := t.TempDir()
 	scenarioContent := `
 meta:
-  name: adjacent-groups
+  name: match-cwd-glob
 steps:
-  - group:
-      mode: unordered
-      name: group-a
-      steps:
-        - match:
-            argv: ["cmd", "a1"]
-          respond:
-            exit: 0
-        - match:
-            argv: ["cmd", "a2"]
-          respond:
-            exit: 0
-  - group:
-      mode: unordered
-      name: group-b
-      steps:
-        - match:
-            argv: ["cmd", "b1"]
-          respond:
-            exit: 0
-        - match:
-            argv: ["cmd", "b2"]
-          respond:
-            exit: 0
+  - match:
+      argv: ["go", "test"]
+      cwd: "services/*"
+    respond:
+      exit: 0
 `
 	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
-	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
-	require.NoError(t, err)
-
-	// Consume group-a in reverse
-	for _, arg := range []string{"a2", "a1"} {
-		var stdout, stderr bytes.Buffer
-		_, rErr := ExecuteReplay(scenarioPath, []string{"cmd", arg}, &stdout, &stderr)
-		require.NoError(t, rErr, "cmd %s", arg)
-	}
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+	svcDir := filepath.Join(tmpDir, "services", "api")
+	require.NoError(t, os.MkdirAll(svcDir, 0750))
 
-	// Now in group-b — consume in reverse
-	for _, arg := range []string{"b2", "b1"} {
-		var stdout, stderr bytes.Buffer
-		_, rErr := ExecuteReplay(scenarioPath, []string{"cmd", arg}, &stdout, &stderr)
-		require.NoError(t, rErr, "cmd %s", arg)
-	}
+	orig, err := os.Getwd()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.Chdir(orig) })
 
-	// All done
+	require.NoError(t, os.Chdir(tmpDir))
 	var stdout, stderr bytes.Buffer
-	_, err = ExecuteReplay(scenarioPath, []string{"cmd", "anything"}, &stdout, &stderr)
+	_, err = ExecuteReplay(scenarioPath, []string{"go", "test"}, &stdout, &stderr)
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "already complete")
+
+	require.NoError(t, os.Chdir(svcDir))
+	_, err = ExecuteReplay(scenarioPath, []string{"go", "test"}, &stdout, &stderr)
+	require.NoError(t, err)
 }
 
-func TestExecuteReplay_GroupAsFirstStep(t *testing.T) {
+func TestExecuteReplay_RespondAssertRecordedForVerify(t *testing.T) {
 	tmpDir := t.TempDir()
 	scenarioContent := `
 meta:
-  name: group-first
+  name: respond-assert
+  vars:
+    group: demo
 steps:
-  - group:
-      mode: unordered
-      steps:
-        - match:
-            argv: ["first", "b"]
-          respond:
-            exit: 0
-        - match:
-            argv: ["first", "a"]
-          respond:
-            exit: 0
+  - match:
+      argv: ["az", "group", "show"]
+    respond:
+      exit: 0
+      stdout: '{"name": "{{ .group }}"}'
+      assert:
+        stdout_contains: '"name": "demo"'
+  - match:
+      argv: ["az", "group", "list"]
+    respond:
+      exit: 0
+      stdout: "{{ .capture.groups }}"
+      assert:
+        stdout_contains: "demo"
 `
 	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
-	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"az", "group", "show"}, &stdout, &stderr)
 	require.NoError(t, err)
+	_, err = ExecuteReplay(scenarioPath, []string{"az", "group", "list"}, &stdout, &stderr)
+	require.NoError(t, err, "a failed assertion still serves the response")
 
-	// Match second declared step first
-	var stdout1, stderr1 bytes.Buffer
-	result, rErr := ExecuteReplay(scenarioPath, []string{"first", "a"}, &stdout1, &stderr1)
-	require.NoError(t, rErr)
-	assert.Equal(t, 0, result.ExitCode)
-	assert.Equal(t, 1, result.StepIndex) // flat index 1 for second group child
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	require.Len(t, state.AssertFailures, 2)
+	assert.Empty(t, state.AssertFailures[0])
+	assert.Equal(t, `stdout does not contain "demo"`, state.AssertFailures[1])
 
-	// Match first declared step second
-	var stdout2, stderr2 bytes.Buffer
-	result2, rErr := ExecuteReplay(scenarioPath, []string{"first", "b"}, &stdout2, &stderr2)
-	require.NoError(t, rErr)
-	assert.Equal(t, 0, result2.ExitCode)
-	assert.Equal(t, 0, result2.StepIndex) // flat index 0 for first group child
+	scn, err := scenario.LoadFile(scenarioPath)
+	require.NoError(t, err)
+	result := verify.BuildResult(scn.Meta.Name, "default", scn.FlatSteps(), state.StepCounts, nil,
+		verify.WithAssertFailures(state.AssertFailures))
+	assert.True(t, result.Steps[0].Passed)
+	assert.False(t, result.Steps[1].Passed)
+	assert.False(t, result.Passed)
 }
 
-func TestExecuteReplay_GroupAsLastStep(t *testing.T) {
+func TestExecuteReplay_FallbackServesUnmatched(t *testing.T) {
 	tmpDir := t.TempDir()
 	scenarioContent := `
 meta:
-  name: group-last
+  name: fallback
+  fallback:
+    exit: 0
+    stdout: "fallback output\n"
 steps:
   - match:
-      argv: ["setup"]
+      argv: ["kubectl", "apply"]
     respond:
       exit: 0
-  - group:
-      mode: unordered
-      steps:
-        - match:
-            argv: ["cleanup", "a"]
-          respond:
-            exit: 0
-        - match:
-            argv: ["cleanup", "b"]
-          respond:
-            exit: 0
+      stdout: "applied\n"
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+      stdout: "pods\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+	absPath, _ := filepath.Abs(scenarioPath)
+	stateFile := StateFilePath(absPath)
+
+	// Consume the first step
+	var stdout1, stderr1 bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "apply"}, &stdout1, &stderr1)
+	require.NoError(t, err)
+	before, err := ReadState(stateFile)
+	require.NoError(t, err)
+
+	// Unexpected probe gets the fallback
+	var stdout2, stderr2 bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "version"}, &stdout2, &stderr2)
+	require.NoError(t, err)
+	assert.True(t, result.Fallback)
+	assert.False(t, result.Matched)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "fallback output\n", stdout2.String())
+
+	// State is untouched
+	after, err := ReadState(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, before.StepCounts, after.StepCounts)
+	assert.Equal(t, before.CurrentStep, after.CurrentStep)
+
+	// Ordering continues normally
+	var stdout3, stderr3 bytes.Buffer
+	result, err = ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout3, &stderr3)
+	require.NoError(t, err)
+	assert.False(t, result.Fallback)
+	assert.Equal(t, "pods\n", stdout3.String())
+}
+
+func TestExecuteReplay_FallbackWithIncludes(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "login.yaml"), []byte(`
+meta:
+  name: login
+steps:
+  - match:
+      argv: ["az", "login"]
+`), 0600))
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: fallback-includes
+  fallback:
+    stdout: "fallback output\n"
+includes: [login.yaml]
+steps:
+  - match:
+      argv: ["kubectl", "apply"]
+`), 0600))
+
+	var stdout bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "version"}, &stdout, &bytes.Buffer{})
+	require.NoError(t, err, "meta.fallback applies in a scenario with includes")
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "fallback output\n", stdout.String())
+}
+
+func TestExecuteReplay_FallbackTrace(t *testing.T) {
+	t.Setenv(TraceEnvVar, "1")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: fallback-trace
+  fallback:
+    exit: 3
+steps:
+  - match:
+      argv: ["git", "status"]
+    respond:
+      exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"git", "config", "user.name"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Contains(t, stderr.String(), "[cli-replay] fallback argv=[git config user.name] exit=3")
+}
+
+func TestExecuteReplay_NoFallbackStillMismatches(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: no-fallback
+steps:
+  - match:
+      argv: ["git", "status"]
+    respond:
+      exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"git", "--version"}, &stdout, &stderr)
+	require.Error(t, err)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+}
+
+func TestExecuteReplay_OrderedGroup(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: ordered-group
+steps:
+  - group:
+      mode: ordered
+      name: rollout
+      steps:
+        - match:
+            argv: ["kubectl", "apply"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["kubectl", "rollout", "status"]
+          respond:
+            exit: 0
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
+	require.NoError(t, err)
+
+	// Second group step called first → mismatch labeled with the group
+	var stdout1, stderr1 bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"kubectl", "rollout", "status"}, &stdout1, &stderr1)
+	require.Error(t, err)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, 0, mErr.StepIndex)
+	assert.Equal(t, "rollout", mErr.GroupName)
+	assert.Contains(t, FormatMismatchError(mErr), `(ordered group "rollout")`)
+
+	for _, argv := range [][]string{
+		{"kubectl", "apply"},
+		{"kubectl", "rollout", "status"},
+		{"kubectl", "get", "pods"},
+	} {
+		var stdout, stderr bytes.Buffer
+		_, err = ExecuteReplay(scenarioPath, argv, &stdout, &stderr)
+		require.NoError(t, err, "argv %v", argv)
+	}
+}
+
+func TestExecuteReplay_GroupDependsOn(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: group-depends-on
+steps:
+  - group:
+      mode: unordered
+      name: setup
+      steps:
+        - name: create-rg
+          match:
+            argv: ["az", "group", "create"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["az", "vm", "create"]
+          depends_on: [create-rg]
+          respond:
+            exit: 0
+            stdout: "vm created\n"
+        - match:
+            argv: ["az", "version"]
+          respond:
+            exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
+	require.NoError(t, err)
+
+	// Dependent child called too early is rejected
+	var stdout1, stderr1 bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"az", "vm", "create"}, &stdout1, &stderr1)
+	require.Error(t, err)
+	var dErr *DependencyError
+	require.ErrorAs(t, err, &dErr)
+	assert.Equal(t, "setup", dErr.GroupName)
+	assert.Equal(t, 1, dErr.StepIndex)
+	assert.Equal(t, []string{"create-rg"}, dErr.Unmet)
+	assert.Contains(t, err.Error(), "called before its dependencies")
+	assert.Equal(t, 1, result.ExitCode)
+
+	// Satisfy the dependency, then the dependent child succeeds
+	var stdout2, stderr2 bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"az", "group", "create"}, &stdout2, &stderr2)
+	require.NoError(t, err)
+
+	var stdout3, stderr3 bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"az", "vm", "create"}, &stdout3, &stderr3)
+	require.NoError(t, err)
+	assert.Equal(t, "vm created\n", stdout3.String())
+}
+
+func TestExecuteReplay_GroupMismatchErrorCandidateList(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: group-candidates
+steps:
+  - group:
+      mode: unordered
+      name: my-group
+      steps:
+        - match:
+            argv: ["cmd", "alpha"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["cmd", "beta"]
+          respond:
+            exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
+	require.NoError(t, err)
+
+	// Send an unknown command
+	var stdout, stderr bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"cmd", "gamma"}, &stdout, &stderr)
+	require.Error(t, err)
+
+	var gErr *GroupMismatchError
+	require.ErrorAs(t, err, &gErr)
+	assert.Equal(t, "my-group", gErr.GroupName)
+	assert.Equal(t, []string{"cmd", "gamma"}, gErr.Received)
+	assert.Len(t, gErr.Candidates, 2)
+	assert.Len(t, gErr.CandidateArgv, 2)
+	assert.Equal(t, []string{"cmd", "alpha"}, gErr.CandidateArgv[0])
+	assert.Equal(t, []string{"cmd", "beta"}, gErr.CandidateArgv[1])
+}
+
+func TestExecuteReplay_GroupAllMinZeroImmediateAdvance(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: group-min-zero
+steps:
+  - group:
+      mode: unordered
+      name: optional
+      steps:
+        - match:
+            argv: ["cmd", "opt1"]
+          calls:
+            min: 0
+            max: 3
+          respond:
+            exit: 0
+        - match:
+            argv: ["cmd", "opt2"]
+          calls:
+            min: 0
+            max: 3
+          respond:
+            exit: 0
+  - match:
+      argv: ["cmd", "next"]
+    respond:
+      exit: 0
+      stdout: "next\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
+	require.NoError(t, err)
+
+	// Skip group entirely — mins are 0, barrier is immediately satisfiable
+	var stdout, stderr bytes.Buffer
+	result, rErr := ExecuteReplay(scenarioPath, []string{"cmd", "next"}, &stdout, &stderr)
+	require.NoError(t, rErr)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Contains(t, stdout.String(), "next")
+}
+
+func TestExecuteReplay_AdjacentGroups(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: adjacent-groups
+steps:
+  - group:
+      mode: unordered
+      name: group-a
+      steps:
+        - match:
+            argv: ["cmd", "a1"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["cmd", "a2"]
+          respond:
+            exit: 0
+  - group:
+      mode: unordered
+      name: group-b
+      steps:
+        - match:
+            argv: ["cmd", "b1"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["cmd", "b2"]
+          respond:
+            exit: 0
 `
 	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
 	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
 	require.NoError(t, err)
 
-	// Ordered step first
-	var stdout1, stderr1 bytes.Buffer
-	_, err = ExecuteReplay(scenarioPath, []string{"setup"}, &stdout1, &stderr1)
-	require.NoError(t, err)
+	// Consume group-a in reverse
+	for _, arg := range []string{"a2", "a1"} {
+		var stdout, stderr bytes.Buffer
+		_, rErr := ExecuteReplay(scenarioPath, []string{"cmd", arg}, &stdout, &stderr)
+		require.NoError(t, rErr, "cmd %s", arg)
+	}
+
+	// Now in group-b — consume in reverse
+	for _, arg := range []string{"b2", "b1"} {
+		var stdout, stderr bytes.Buffer
+		_, rErr := ExecuteReplay(scenarioPath, []string{"cmd", arg}, &stdout, &stderr)
+		require.NoError(t, rErr, "cmd %s", arg)
+	}
+
+	// All done
+	var stdout, stderr bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"cmd", "anything"}, &stdout, &stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already complete")
+}
+
+func TestExecuteReplay_GroupAsFirstStep(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: group-first
+steps:
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: ["first", "b"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["first", "a"]
+          respond:
+            exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
+	require.NoError(t, err)
+
+	// Match second declared step first
+	var stdout1, stderr1 bytes.Buffer
+	result, rErr := ExecuteReplay(scenarioPath, []string{"first", "a"}, &stdout1, &stderr1)
+	require.NoError(t, rErr)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, 1, result.StepIndex) // flat index 1 for second group child
+
+	// Match first declared step second
+	var stdout2, stderr2 bytes.Buffer
+	result2, rErr := ExecuteReplay(scenarioPath, []string{"first", "b"}, &stdout2, &stderr2)
+	require.NoError(t, rErr)
+	assert.Equal(t, 0, result2.ExitCode)
+	assert.Equal(t, 0, result2.StepIndex) // flat index 0 for first group child
+}
+
+func TestExecuteReplay_GroupAsLastStep(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: group-last
+steps:
+  - match:
+      argv: ["setup"]
+    respond:
+      exit: 0
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: ["cleanup", "a"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["cleanup", "b"]
+          respond:
+            exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	err := os.WriteFile(scenarioPath, []byte(scenarioContent), 0600)
+	require.NoError(t, err)
+
+	// Ordered step first
+	var stdout1, stderr1 bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"setup"}, &stdout1, &stderr1)
+	require.NoError(t, err)
+
+	// Group in any order
+	var stdout2, stderr2 bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"cleanup", "b"}, &stdout2, &stderr2)
+	require.NoError(t, err)
+
+	var stdout3, stderr3 bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"cleanup", "a"}, &stdout3, &stderr3)
+	require.NoError(t, err)
+
+	// Scenario complete
+	var stdout4, stderr4 bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"anything"}, &stdout4, &stderr4)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already complete")
+}
+
+// T017: Integration tests for User Story 1 (deny env vars)
+
+func TestReplayResponseWithTemplate_DeniedEnvVarEmptyString(t *testing.T) {
+	// Denied env var with no meta.vars base → renders as empty string
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{
+			Name: "deny-test",
+			Vars: map[string]string{"AWS_KEY": ""},
+			Security: &scenario.Security{
+				DenyEnvVars: []string{"AWS_*"},
+			},
+		},
+	}
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "key={{ .AWS_KEY }}|end"},
+	}
+
+	t.Setenv("AWS_KEY", "real-secret-value")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "key=|end", stdout.String())
+}
+
+func TestReplayResponseWithTemplate_AllowedEnvVarRealValue(t *testing.T) {
+	// Allowed env var → renders with real env value
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{
+			Name: "allow-test",
+			Vars: map[string]string{"HOME_VAR": "default"},
+			Security: &scenario.Security{
+				DenyEnvVars: []string{"AWS_*"},
+			},
+		},
+	}
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "home={{ .HOME_VAR }}"},
+	}
+
+	t.Setenv("HOME_VAR", "/real/home")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "home=/real/home", stdout.String())
+}
+
+func TestReplayResponseWithTemplate_NoSecurityPassthrough(t *testing.T) {
+	// No security section → env vars pass through normally (backward compat)
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{
+			Name: "no-sec-test",
+			Vars: map[string]string{"MY_VAR": "default"},
+		},
+	}
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "val={{ .MY_VAR }}"},
+	}
+
+	t.Setenv("MY_VAR", "env-override")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "val=env-override", stdout.String())
+}
+
+func TestReplayResponseWithTemplate_WildcardDenyAll(t *testing.T) {
+	// Deny-all with "*" — all env overrides suppressed
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{
+			Name: "deny-all-test",
+			Vars: map[string]string{
+				"VAR_A": "base-a",
+				"VAR_B": "base-b",
+			},
+			Security: &scenario.Security{
+				DenyEnvVars: []string{"*"},
+			},
+		},
+	}
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "a={{ .VAR_A }} b={{ .VAR_B }}"},
+	}
+
+	t.Setenv("VAR_A", "env-a")
+	t.Setenv("VAR_B", "env-b")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "a=base-a b=base-b", stdout.String())
+}
+
+func TestReplayResponseWithTemplate_GlobPatterns(t *testing.T) {
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{
+			Name: "glob-test",
+			Vars: map[string]string{
+				"AWS_KEY":      "base-aws",
+				"GITHUB_TOKEN": "base-gh",
+				"NORMAL":       "base-normal",
+			},
+			Security: &scenario.Security{
+				DenyEnvVars: []string{"AWS_*", "GITHUB_TOKEN"},
+			},
+		},
+	}
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "{{ .AWS_KEY }}|{{ .GITHUB_TOKEN }}|{{ .NORMAL }}"},
+	}
+
+	t.Setenv("AWS_KEY", "env-aws")
+	t.Setenv("GITHUB_TOKEN", "env-gh")
+	t.Setenv("NORMAL", "env-normal")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
+	assert.Equal(t, 0, exitCode)
+	// AWS_KEY and GITHUB_TOKEN denied → base values; NORMAL allowed → env value
+	assert.Equal(t, "base-aws|base-gh|env-normal", stdout.String())
+}
+
+func TestReplayResponseWithTemplate_DenyWithTrace(t *testing.T) {
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{
+			Name: "trace-deny-test",
+			Vars: map[string]string{"SECRET": "base"},
+			Security: &scenario.Security{
+				DenyEnvVars: []string{"SECRET"},
+			},
+		},
+	}
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "{{ .SECRET }}"},
+	}
+
+	t.Setenv("SECRET", "real-secret")
+	t.Setenv("CLI_REPLAY_TRACE", "1")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "base", stdout.String())
+	assert.Contains(t, stderr.String(), "cli-replay[trace]: denied env var SECRET")
+}
+
+func TestReplayResponseWithTemplate_DenyPreservesMetaVarsValue(t *testing.T) {
+	// When a denied var has a base value in meta.vars, it keeps that value
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{
+			Name: "preserve-test",
+			Vars: map[string]string{"AWS_KEY": "safe-default-value"},
+			Security: &scenario.Security{
+				DenyEnvVars: []string{"AWS_*"},
+			},
+		},
+	}
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "key={{ .AWS_KEY }}"},
+	}
+
+	t.Setenv("AWS_KEY", "real-secret")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "key=safe-default-value", stdout.String())
+}
+
+// T033: Composability test — both deny_env_vars and session.ttl configured simultaneously.
+func TestReplayResponseWithTemplate_DenyAndSessionTTL_Composability(t *testing.T) {
+	scn := &scenario.Scenario{
+		Meta: scenario.Meta{
+			Name: "composability-test",
+			Vars: map[string]string{"cluster": "prod"},
+			Security: &scenario.Security{
+				DenyEnvVars: []string{"SECRET_*"},
+			},
+			Session: &scenario.Session{
+				TTL: "10m",
+			},
+		},
+	}
+	step := &scenario.Step{
+		Match:   scenario.Match{Argv: []string{"cmd"}},
+		Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "cluster={{ .cluster }}"},
+	}
+
+	// Set env vars — SECRET_TOKEN should be denied, but cluster should work
+	t.Setenv("SECRET_TOKEN", "super-secret")
+	t.Setenv("cluster", "override-cluster")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
+	assert.Equal(t, 0, exitCode)
+	// cluster is NOT denied, env override applies
+	assert.Equal(t, "cluster=override-cluster", stdout.String())
+	// Verify session TTL is parseable (model validation)
+	assert.NotNil(t, scn.Meta.Session)
+	assert.Equal(t, "10m", scn.Meta.Session.TTL)
+}
+
+func TestExecuteReplay_RecordsStepDurationForTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: timeout-test
+steps:
+  - match:
+      argv: ["cmd", "slow"]
+    respond:
+      exit: 0
+      delay: 30ms
+      timeout: 1ms
+  - match:
+      argv: ["cmd", "fast"]
+    respond:
+      exit: 0
+      timeout: 10s
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	for _, argv := range [][]string{{"cmd", "slow"}, {"cmd", "fast"}} {
+		var stdout, stderr bytes.Buffer
+		_, err := ExecuteReplay(scenarioPath, argv, &stdout, &stderr)
+		require.NoError(t, err)
+	}
+
+	absPath, err := filepath.Abs(scenarioPath)
+	require.NoError(t, err)
+	state, err := ReadState(StateFilePath(absPath))
+	require.NoError(t, err)
+	require.Len(t, state.StepDurations, 2)
+	assert.GreaterOrEqual(t, state.StepDurations[0], 30*time.Millisecond, "delay counts toward service time")
+
+	scn, err := scenario.LoadFile(absPath)
+	require.NoError(t, err)
+	result := verify.BuildResult(scn.Meta.Name, "default", scn.FlatSteps(), state.StepCounts, nil,
+		verify.WithStepDurations(state.StepDurations))
+
+	assert.False(t, result.Passed)
+	assert.True(t, result.Steps[0].TimedOut)
+	assert.False(t, result.Steps[0].Passed)
+	assert.GreaterOrEqual(t, result.Steps[0].DurationMs, int64(30))
+	assert.False(t, result.Steps[1].TimedOut)
+	assert.True(t, result.Steps[1].Passed)
+}
+
+func TestExecuteReplay_ExitTemplateFromCapture(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: exit-template-test
+steps:
+  - match:
+      argv: ["probe", "status"]
+    respond:
+      exit: 0
+      capture:
+        code: "7"
+  - match:
+      argv: ["probe", "report"]
+    respond:
+      exit_template: "{{ .capture.code }}"
+      stdout: "status {{ .capture.code }}\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"probe", "status"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+
+	stdout.Reset()
+	result, err = ExecuteReplay(scenarioPath, []string{"probe", "report"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 7, result.ExitCode)
+	assert.Equal(t, "status 7\n", stdout.String())
+}
+
+// streamLog records the writes of several streams in one ordered log.
+type streamLog struct {
+	writes *[]string
+	name   string
+}
+
+func (s streamLog) Write(p []byte) (int, error) {
+	*s.writes = append(*s.writes, s.name+":"+string(p))
+	return len(p), nil
+}
+
+func TestExecuteReplay_OutputInterleaved(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: interleaved
+  vars:
+    count: "2"
+steps:
+  - match:
+      argv: ["kubectl", "apply", "-f", "app.yaml"]
+    respond:
+      output:
+        - stream: stderr
+          text: "applying {{ .count }} resources\n"
+        - stream: stdout
+          text: "deployment.apps/web configured\n"
+        - stream: stderr
+          text: "1/2 done\n"
+        - stream: stdout
+          text: "service/web unchanged\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var writes []string
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "apply", "-f", "app.yaml"},
+		streamLog{&writes, "stdout"}, streamLog{&writes, "stderr"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"stderr:applying 2 resources\n",
+		"stdout:deployment.apps/web configured\n",
+		"stderr:1/2 done\n",
+		"stdout:service/web unchanged\n",
+	}, writes)
+	assert.Equal(t, len("deployment.apps/web configured\nservice/web unchanged\n"), result.StdoutBytes)
+	assert.Equal(t, len("applying 2 resources\n1/2 done\n"), result.StderrBytes)
+}
+
+func TestExecuteReplay_TemplatedCapturePersists(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv("region", "westus")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: templated-capture
+  vars:
+    region: eastus
+steps:
+  - match:
+      argv: ["az", "group", "create"]
+    respond:
+      capture:
+        region_upper: "{{ .region | upper }}"
+  - match:
+      argv: ["az", "group", "show"]
+    respond:
+      stdout: "location {{ .capture.region_upper }}\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"az", "group", "create"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, "WESTUS", state.Captures["region_upper"], "rendered against the env-overridden var")
+
+	_, err = ExecuteReplay(scenarioPath, []string{"az", "group", "show"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "location WESTUS\n", stdout.String())
+}
+
+func TestExecuteReplay_ForbiddenCommand(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: no-egress
+  security:
+    forbidden_commands: ["curl"]
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      stdout: "pods\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"curl", "https://example.com"}, &stdout, &stderr)
+	require.Error(t, err)
+	var forbidden *ForbiddenCommandError
+	require.ErrorAs(t, err, &forbidden)
+	assert.Equal(t, "curl", forbidden.Command)
+	assert.Equal(t, []string{"curl", "https://example.com"}, forbidden.Received)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Empty(t, stdout.String())
+
+	got := decodeErrorJSON(t, err)
+	assert.Equal(t, ErrorTypeForbidden, got["type"])
+	assert.Equal(t, "no-egress", got["scenario"])
+
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"curl", "https://example.com"}}, state.ForbiddenCalls)
+	assert.Equal(t, 0, state.CurrentStep, "no step is consumed")
+
+	_, err = ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err, "allowed commands are still served")
+	assert.Equal(t, "pods\n", stdout.String())
+}
+
+func TestExecuteReplay_ArgvVarsMatchSubstitutedCommand(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv("cluster", "")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: argv-vars
+  vars:
+    cluster: prod-east
+steps:
+  - match:
+      argv: ["kubectl", "--context", "{{ .cluster }}", "get", "pods"]
+    respond:
+      exit: 0
+      stdout: "pods\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	// The literal template text no longer matches
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "--context", "{{ .cluster }}", "get", "pods"}, &stdout, &stderr)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, []string{"kubectl", "--context", "prod-east", "get", "pods"}, mErr.Expected)
+
+	// The substituted command does
+	stdout.Reset()
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "--context", "prod-east", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "pods\n", stdout.String())
+}
+
+func TestExecuteReplay_DisallowedCommandRefused(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: allowlisted
+  security:
+    allowed_commands: [kubectl]
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      stdout: "pods\n"
+  - match:
+      argv: ["/usr/bin/curl", "https://example.com"]
+    respond:
+      stdout: "payload\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	// The curl step matches structurally but is not on the allowlist
+	stdout.Reset()
+	result, replayErr := ExecuteReplay(scenarioPath, []string{"/usr/bin/curl", "https://example.com"}, &stdout, &stderr)
+	var dErr *DisallowedCommandError
+	require.ErrorAs(t, replayErr, &dErr)
+	assert.Equal(t, "curl", dErr.Command)
+	assert.Equal(t, 1, dErr.StepIndex)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Empty(t, stdout.String(), "refused step must not be served")
+
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, state.StepCounts, "refused step is not counted")
+
+	assert.Contains(t, FormatErrorJSON(replayErr), `"type":"disallowed_command"`)
+}
+
+func TestExecuteReplay_MaxTotalCalls(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: runaway-poll
+  max_total_calls: 3
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    calls:
+      min: 1
+      max: 100
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	argv := []string{"kubectl", "get", "pods"}
+	for i := 0; i < 3; i++ {
+		_, err := ExecuteReplay(scenarioPath, argv, &stdout, &stderr)
+		require.NoError(t, err, "call %d", i+1)
+	}
+
+	result, err := ExecuteReplay(scenarioPath, argv, &stdout, &stderr)
+	var bErr *CallBudgetExceededError
+	require.ErrorAs(t, err, &bErr)
+	assert.Equal(t, 3, bErr.Limit)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, err.Error(), "max_total_calls")
+
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, 3, state.TotalCalls())
+}
+
+func TestExecuteReplay_MaxConsecutiveMismatches(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: retry-loop
+  limits:
+    max_consecutive_mismatches: 3
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+  - match:
+      argv: ["kubectl", "delete", "pod", "web"]
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	wrong := []string{"kubectl", "get", "nodes"}
+	for i := 0; i < 2; i++ {
+		_, err := ExecuteReplay(scenarioPath, wrong, &stdout, &stderr)
+		var mErr *MismatchError
+		require.ErrorAs(t, err, &mErr, "call %d", i+1)
+	}
+
+	// A different command breaks the streak
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "svc"}, &stdout, &stderr)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, 1, state.MismatchStreak)
+	assert.Equal(t, []string{"kubectl", "get", "svc"}, state.LastMismatchArgv)
+
+	for i := 0; i < 2; i++ {
+		_, err := ExecuteReplay(scenarioPath, wrong, &stdout, &stderr)
+		require.ErrorAs(t, err, &mErr, "call %d", i+1)
+	}
+	result, err := ExecuteReplay(scenarioPath, wrong, &stdout, &stderr)
+	var loopErr *MismatchLoopError
+	require.ErrorAs(t, err, &loopErr)
+	assert.Equal(t, 3, loopErr.Count)
+	assert.Equal(t, 3, loopErr.Limit)
+	assert.Equal(t, wrong, loopErr.Received)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, err.Error(), "stop retrying")
+
+	state, err = ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, 3, state.MismatchStreak)
+	assert.Equal(t, wrong, state.LastMismatchArgv)
+	assert.Equal(t, 0, state.CurrentStep)
+
+	// A match resets the streak
+	_, err = ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err)
+	state, err = ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Zero(t, state.MismatchStreak)
+	assert.Nil(t, state.LastMismatchArgv)
+}
+
+func TestExecuteReplay_MismatchesWithoutLoopLimitLeaveState(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	scenarioPath := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: no-loop-limit
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+`), 0600))
+
+	var stdout, stderr bytes.Buffer
+	for i := 0; i < 5; i++ {
+		_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "nodes"}, &stdout, &stderr)
+		var mErr *MismatchError
+		require.ErrorAs(t, err, &mErr)
+	}
+	_, err := ReadState(StateFilePath(scenarioPath))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func writeStaleStateScenario(t *testing.T) string {
+	t.Helper()
+	scenarioPath := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: edited
+steps:
+  - match:
+      argv: ["git", "fetch"]
+  - match:
+      argv: ["git", "pull"]
+`), 0600))
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"git", "fetch"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	// Edit the scenario after state was created
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: edited
+steps:
+  - match:
+      argv: ["git", "status"]
+  - match:
+      argv: ["git", "fetch"]
+  - match:
+      argv: ["git", "pull"]
+`), 0600))
+	return scenarioPath
+}
+
+func TestExecuteReplay_ChangedScenarioResetsState(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv(StrictStateEnvVar, "")
+	t.Setenv(TraceEnvVar, "1")
+	scenarioPath := writeStaleStateScenario(t)
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"git", "status"}, &stdout, &stderr)
+	require.NoError(t, err, "stale state must not replay against old indices")
+	assert.Equal(t, 0, result.StepIndex)
+	assert.Contains(t, stderr.String(), "changed since state was created, state reset")
+
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, 3, state.TotalSteps)
+	assert.Equal(t, []int{1, 0, 0}, state.StepCounts)
+	assert.Equal(t, hashScenarioFile(scenarioPath), state.ScenarioHash)
+}
+
+func TestExecuteReplay_ChangedScenarioStrictFails(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv(StrictStateEnvVar, "1")
+	scenarioPath := writeStaleStateScenario(t)
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"git", "status"}, &stdout, &stderr)
+	var sErr *StaleStateError
+	require.ErrorAs(t, err, &sErr)
+	assert.Contains(t, err.Error(), "changed since state was created")
+	assert.Equal(t, 1, result.ExitCode)
+
+	// State is left as it was
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, 2, state.TotalSteps)
+}
+
+// writeStaleFragmentScenario creates a scenario whose steps come from an
+// included fragment, advances it one step, then edits only the fragment.
+func writeStaleFragmentScenario(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	fragPath := filepath.Join(tmpDir, "frag.yaml")
+	require.NoError(t, os.WriteFile(fragPath, []byte(`
+meta:
+  name: frag
+steps:
+  - match:
+      argv: ["git", "fetch"]
+`), 0600))
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: with-fragment
+includes: [frag.yaml]
+steps:
+  - match:
+      argv: ["git", "pull"]
+`), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"git", "fetch"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(fragPath, []byte(`
+meta:
+  name: frag
+steps:
+  - match:
+      argv: ["git", "status"]
+  - match:
+      argv: ["git", "fetch"]
+`), 0600))
+	return scenarioPath
+}
+
+func TestExecuteReplay_ChangedFragmentResetsState(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv(StrictStateEnvVar, "")
+	scenarioPath := writeStaleFragmentScenario(t)
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"git", "status"}, &stdout, &stderr)
+	require.NoError(t, err, "state from before the fragment edit must not be replayed")
+	assert.Equal(t, 0, result.StepIndex)
+
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	assert.Equal(t, 3, state.TotalSteps)
+	assert.Equal(t, []int{1, 0, 0}, state.StepCounts)
+	assert.Equal(t, ScenarioHash(scenarioPath), state.ScenarioHash)
+}
+
+func TestExecuteReplay_ChangedFragmentStrictFails(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv(StrictStateEnvVar, "1")
+	scenarioPath := writeStaleFragmentScenario(t)
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"git", "status"}, &stdout, &stderr)
+	var sErr *StaleStateError
+	require.ErrorAs(t, err, &sErr)
+}
+
+func writeFixturesDirScenario(t *testing.T, stdoutFile string) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "testdata", "pods"), 0750))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "features", "deploy"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "testdata", "pods", "list.txt"), []byte("pod-a\npod-b\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "secret.txt"), []byte("top secret\n"), 0600))
+
+	scenarioContent := `
+meta:
+  name: fixtures-root
+  fixtures_dir: ../../testdata
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      stdout_file: ` + stdoutFile + "\n"
+	scenarioPath := filepath.Join(tmpDir, "features", "deploy", "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+	return scenarioPath
+}
+
+func TestExecuteReplay_FixturesDirResolvesFiles(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	scenarioPath := writeFixturesDirScenario(t, "pods/list.txt")
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err, "stderr: %s", stderr.String())
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "pod-a\npod-b\n", stdout.String())
+}
+
+func TestExecuteReplay_FixturesDirRejectsEscape(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	scenarioPath := writeFixturesDirScenario(t, "../secret.txt")
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fixture path escapes")
+	assert.Equal(t, 1, result.ExitCode)
+	assert.NotContains(t, stdout.String(), "top secret")
+}
+
+func writeEnvFixtureScenario(t *testing.T, meta, stdoutFile string) string {
+	t.Helper()
+	scenarioPath := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: env-fixture
+`+meta+`
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      stdout_file: "`+stdoutFile+`"
+`), 0600))
+	return scenarioPath
+}
+
+func TestExecuteReplay_StdoutFileExpandsEnv(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	testdata := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testdata, "pods.txt"), []byte("pod-a\n"), 0600))
+	t.Setenv("TESTDATA_DIR", testdata)
+
+	for _, ref := range []string{"${TESTDATA_DIR}/pods.txt", "$TESTDATA_DIR/pods.txt"} {
+		t.Run(ref, func(t *testing.T) {
+			scenarioPath := writeEnvFixtureScenario(t, "", ref)
+			var stdout, stderr bytes.Buffer
+			result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+			require.NoError(t, err, "stderr: %s", stderr.String())
+			assert.Equal(t, 0, result.ExitCode)
+			assert.Equal(t, "pod-a\n", stdout.String())
+		})
+	}
+}
+
+func TestExecuteReplay_StdoutFileUnsetEnvNotFound(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv("TESTDATA_DIR", "")
+	scenarioPath := writeEnvFixtureScenario(t, "", "${TESTDATA_DIR}/pods.txt")
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Contains(t, err.Error(), "$TESTDATA_DIR is unset or empty")
+}
+
+func TestFixturePath_EnvStaysWithinFixturesDir(t *testing.T) {
+	scenarioDir := t.TempDir()
+	scn := &scenario.Scenario{Meta: scenario.Meta{Name: "x", FixturesDir: "fixtures"}}
+
+	t.Setenv("FIXTURE_SET", "pods")
+	got, err := FixturePath(scn, scenarioDir, "${FIXTURE_SET}/list.txt")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(scenarioDir, "fixtures", "pods", "list.txt"), got)
+
+	t.Setenv("FIXTURE_SET", "..")
+	_, err = FixturePath(scn, scenarioDir, "${FIXTURE_SET}/secret.txt")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fixture path escapes")
+
+	t.Setenv("FIXTURE_SET", scenarioDir)
+	_, err = FixturePath(scn, scenarioDir, "${FIXTURE_SET}/secret.txt")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "absolute fixture paths are not allowed")
+}
+
+func TestFixturePath_DeniedEnvExpandsEmpty(t *testing.T) {
+	t.Setenv("SECRET_DIR", t.TempDir())
+	scn := &scenario.Scenario{Meta: scenario.Meta{
+		Name:     "x",
+		Security: &scenario.Security{DenyEnvVars: []string{"SECRET_*"}},
+	}}
+	_, err := FixturePath(scn, t.TempDir(), "$SECRET_DIR/key.txt")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "$SECRET_DIR is unset or empty")
+}
+
+func TestExecuteReplay_PrevAcrossInvocations(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: prev-steps
+steps:
+  - match:
+      argv: ["gen"]
+    respond:
+      stdout: "abc"
+  - match:
+      argv: ["echo-last"]
+    respond:
+      stdout: "{{ .prev.stdout }}"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"gen"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	require.NotNil(t, state.Prev, "served response is persisted")
+	assert.Equal(t, "abc", state.Prev.Stdout)
+
+	stdout.Reset()
+	_, err = ExecuteReplay(scenarioPath, []string{"echo-last"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", stdout.String())
+}
+
+func TestExecuteReplay_CallCountAcrossInvocations(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: paged
+steps:
+  - match:
+      argv: ["az", "rest", "--next"]
+    calls:
+      min: 1
+      max: 3
+    respond:
+      stdout: "page {{ .call }} of 3\n"
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	for i := 1; i <= 3; i++ {
+		var stdout, stderr bytes.Buffer
+		_, err := ExecuteReplay(scenarioPath, []string{"az", "rest", "--next"}, &stdout, &stderr)
+		require.NoError(t, err, "stderr: %s", stderr.String())
+		assert.Equal(t, fmt.Sprintf("page %d of 3\n", i), stdout.String())
+	}
+}
+
+func TestExecuteReplay_TraceFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	traceFile := filepath.Join(tmpDir, "trace.log")
+	t.Setenv(TraceEnvVar, "1")
+	t.Setenv(TraceFileEnvVar, traceFile)
+	scenarioContent := `
+meta:
+  name: trace-file
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+      stderr: "warning: real stderr\n"
+  - match:
+      argv: ["kubectl", "get", "svc"]
+    respond:
+      exit: 2
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err)
+	_, err = ExecuteReplay(scenarioPath, []string{"kubectl", "get", "svc"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	assert.Equal(t, "warning: real stderr\n", stderr.String(), "trace lines must not reach stderr")
+	data, err := os.ReadFile(traceFile)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"[cli-replay] step=0 argv=[kubectl get pods] exit=0\n"+
+			"[cli-replay] step=1 argv=[kubectl get svc] exit=2\n",
+		string(data))
+}
+
+func TestReplayResponseWithTemplate_DeniedEnvTraceFile(t *testing.T) {
+	traceFile := filepath.Join(t.TempDir(), "trace.log")
+	t.Setenv(TraceFileEnvVar, traceFile)
+	t.Setenv("SECRET_TOKEN", "leaked")
+
+	scn := &scenario.Scenario{Meta: scenario.Meta{
+		Name:     "denied-trace",
+		Vars:     map[string]string{"SECRET_TOKEN": "placeholder"},
+		Security: &scenario.Security{DenyEnvVars: []string{"SECRET_*"}},
+	}}
+	step := &scenario.Step{Respond: scenario.Response{Stdout: "{{ .SECRET_TOKEN }}"}}
+
+	var stdout, stderr bytes.Buffer
+	ReplayResponseWithTemplate(step, scn, "", nil, &stdout, &stderr)
+
+	assert.Equal(t, "placeholder", stdout.String())
+	assert.Empty(t, stderr.String())
+	data, err := os.ReadFile(traceFile)
+	require.NoError(t, err)
+	assert.Equal(t, "cli-replay[trace]: denied env var SECRET_TOKEN\n", string(data))
+}
+
+func TestExecuteReplay_JSONTrace(t *testing.T) {
+	tmpDir := t.TempDir()
+	traceFile := filepath.Join(tmpDir, "trace.jsonl")
+	t.Setenv(TraceEnvVar, "json")
+	t.Setenv(TraceFileEnvVar, traceFile)
+	scenarioContent := `
+meta:
+  name: json-trace
+steps:
+  - match:
+      argv: ["cmd", "a"]
+    calls:
+      min: 1
+      max: 2
+    respond:
+      exit: 0
+  - match:
+      argv: ["cmd", "b"]
+    respond:
+      exit: 0
+      capture:
+        rg: demo-rg
+  - group:
+      name: checks
+      mode: unordered
+      steps:
+        - match:
+            argv: ["cmd", "c"]
+          respond:
+            exit: 3
+        - match:
+            argv: ["cmd", "d"]
+          respond:
+            exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stderr bytes.Buffer
+	for _, argv := range [][]string{{"cmd", "a"}, {"cmd", "b"}, {"cmd", "c"}} {
+		_, err := ExecuteReplay(scenarioPath, argv, &bytes.Buffer{}, &stderr)
+		require.NoError(t, err)
+	}
+	_, err := ExecuteReplay(scenarioPath, []string{"cmd", "zzz"}, &bytes.Buffer{}, &stderr)
+	require.Error(t, err)
+	assert.Empty(t, stderr.String())
+
+	data, err := os.ReadFile(traceFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 4)
+	records := make([]TraceRecord, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &records[i]), line)
+		assert.Equal(t, "json-trace", records[i].Scenario)
+		assert.False(t, records[i].Time.IsZero())
+	}
+
+	assert.Equal(t, TraceDecisionServed, records[0].Decision)
+	assert.Equal(t, 0, *records[0].StepIndex)
+	assert.False(t, records[0].SoftAdvanced)
+
+	assert.Equal(t, TraceDecisionServed, records[1].Decision)
+	assert.Equal(t, 0, records[1].FromStep)
+	assert.Equal(t, 1, *records[1].StepIndex)
+	assert.True(t, records[1].SoftAdvanced)
+	assert.Equal(t, map[string]string{"rg": "demo-rg"}, records[1].Captures)
+
+	assert.Equal(t, TraceDecisionServed, records[2].Decision)
+	assert.Equal(t, "checks", records[2].Group)
+	assert.Equal(t, 3, records[2].Exit)
+	assert.Equal(t, []string{"cmd", "c"}, records[2].Argv)
+
+	assert.Equal(t, ErrorTypeGroupMismatch, records[3].Decision)
+	assert.Equal(t, "checks", records[3].Group)
+	assert.Equal(t, 1, records[3].Exit)
+	assert.NotEmpty(t, records[3].Error)
+}
+
+func TestExecuteReplay_JSONTraceReplacesHumanTrace(t *testing.T) {
+	t.Setenv(TraceEnvVar, "json")
+	tmpDir := t.TempDir()
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: json-stderr
+steps:
+  - match:
+      argv: ["git", "status"]
+    respond:
+      exit: 0
+`), 0600))
+
+	var stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"git", "status"}, &bytes.Buffer{}, &stderr)
+	require.NoError(t, err)
+
+	assert.NotContains(t, stderr.String(), "[cli-replay] step=")
+	var rec TraceRecord
+	require.NoError(t, json.Unmarshal(stderr.Bytes(), &rec))
+	assert.Equal(t, TraceDecisionServed, rec.Decision)
+	assert.Equal(t, []string{"git", "status"}, rec.Argv)
+}
+
+func TestExecuteReplay_StripPrefixes(t *testing.T) {
+	write := func(t *testing.T, meta string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "scenario.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: wrapped
+`+meta+`steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+      stdout: "pods\n"
+  - match:
+      argv: ["kubectl", "apply", "-f", "deploy.yaml"]
+    respond:
+      exit: 0
+      stdout: "applied\n"
+`), 0600))
+		return path
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		path := write(t, "  strip_prefixes: [sudo, env]\n")
+
+		var stdout bytes.Buffer
+		result, err := ExecuteReplay(path, []string{"sudo", "kubectl", "get", "pods"}, &stdout, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.StepIndex)
+		assert.Equal(t, "pods\n", stdout.String())
+
+		stdout.Reset()
+		_, err = ExecuteReplay(path, []string{"env", "KUBECONFIG=/tmp/kc", "kubectl", "apply", "-f", "deploy.yaml"}, &stdout, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, "applied\n", stdout.String())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		path := write(t, "")
+
+		_, err := ExecuteReplay(path, []string{"sudo", "kubectl", "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{})
+		var mErr *MismatchError
+		require.ErrorAs(t, err, &mErr)
+		assert.Equal(t, []string{"sudo", "kubectl", "get", "pods"}, mErr.Received)
+	})
+}
+
+func TestExecuteReplay_NormalizeFlags(t *testing.T) {
+	write := func(t *testing.T, normalize string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "scenario.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: flag-forms
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods", "--namespace", "prod"]
+`+normalize+`    respond:
+      exit: 0
+      stdout: "pods\n"
+`), 0600))
+		return path
+	}
+	argv := []string{"kubectl", "get", "pods", "--namespace=prod"}
+
+	t.Run("enabled", func(t *testing.T) {
+		path := write(t, "      normalize_flags: true\n")
+		var stdout bytes.Buffer
+		_, err := ExecuteReplay(path, argv, &stdout, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, "pods\n", stdout.String())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		path := write(t, "")
+		_, err := ExecuteReplay(path, argv, &bytes.Buffer{}, &bytes.Buffer{})
+		var mErr *MismatchError
+		require.ErrorAs(t, err, &mErr)
+	})
+}
+
+func TestExecuteReplay_Wildcards(t *testing.T) {
+	write := func(t *testing.T, wildcards string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "scenario.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: wildcards
+steps:
+  - match:
+      argv: ["cp", "_", "/dest"]
+`+wildcards+`    respond:
+      exit: 0
+      stdout: "copied\n"
+`), 0600))
+		return path
+	}
+
+	t.Run("matches any source", func(t *testing.T) {
+		path := write(t, "      wildcards: true\n")
+		var stdout bytes.Buffer
+		_, err := ExecuteReplay(path, []string{"cp", "/tmp/abc123", "/dest"}, &stdout, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, "copied\n", stdout.String())
+	})
+
+	t.Run("rejects wrong destination", func(t *testing.T) {
+		path := write(t, "      wildcards: true\n")
+		_, err := ExecuteReplay(path, []string{"cp", "/tmp/abc123", "/other"}, &bytes.Buffer{}, &bytes.Buffer{})
+		var mErr *MismatchError
+		require.ErrorAs(t, err, &mErr)
+	})
+
+	t.Run("literal without flag", func(t *testing.T) {
+		path := write(t, "")
+		_, err := ExecuteReplay(path, []string{"cp", "/tmp/abc123", "/dest"}, &bytes.Buffer{}, &bytes.Buffer{})
+		var mErr *MismatchError
+		require.ErrorAs(t, err, &mErr)
+
+		_, err = ExecuteReplay(path, []string{"cp", "_", "/dest"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.NoError(t, err)
+	})
+}
+
+func TestExecuteReplay_CustomExitCodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: exit-codes
+  exit_codes:
+    mismatch: 97
+    complete: 98
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 1
+`), 0600))
+
+	result, err := ExecuteReplay(path, []string{"kubectl", "get", "nodes"}, &bytes.Buffer{}, &bytes.Buffer{})
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, 97, result.ExitCode)
+
+	result, err = ExecuteReplay(path, []string{"kubectl", "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ExitCode, "served steps keep their own exit")
+
+	result, err = ExecuteReplay(path, []string{"kubectl", "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already complete")
+	assert.Equal(t, 98, result.ExitCode)
+}
+
+func TestExecuteReplay_CustomExitCodes_DependencyError(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: exit-codes-depends-on
+  exit_codes:
+    mismatch: 97
+steps:
+  - group:
+      mode: unordered
+      steps:
+        - name: create-rg
+          match:
+            argv: ["az", "group", "create"]
+        - match:
+            argv: ["az", "vm", "create"]
+          depends_on: [create-rg]
+`), 0600))
+
+	result, err := ExecuteReplay(path, []string{"az", "vm", "create"}, &bytes.Buffer{}, &bytes.Buffer{})
+	var dErr *DependencyError
+	require.ErrorAs(t, err, &dErr)
+	assert.Equal(t, 97, result.ExitCode)
+}
+
+func TestExecuteReplay_CustomExitCodes_CallBudgetExceeded(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: exit-codes-budget
+  max_total_calls: 1
+  exit_codes:
+    mismatch: 97
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    calls:
+      min: 1
+      max: 5
+`), 0600))
+
+	_, err := ExecuteReplay(path, []string{"kubectl", "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	result, err := ExecuteReplay(path, []string{"kubectl", "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{})
+	var bErr *CallBudgetExceededError
+	require.ErrorAs(t, err, &bErr)
+	assert.Equal(t, 97, result.ExitCode)
+}
+
+func TestExecuteReplay_CustomExitCodes_DisallowedCommand(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: exit-codes-allowlist
+  security:
+    allowed_commands: [kubectl]
+  exit_codes:
+    mismatch: 97
+steps:
+  - match:
+      argv: ["curl", "https://example.com"]
+`), 0600))
+
+	result, err := ExecuteReplay(path, []string{"curl", "https://example.com"}, &bytes.Buffer{}, &bytes.Buffer{})
+	var dErr *DisallowedCommandError
+	require.ErrorAs(t, err, &dErr)
+	assert.Equal(t, 97, result.ExitCode)
+}
+
+func TestExecuteReplay_DefaultExitCodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: default-codes
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+`), 0600))
+
+	result, err := ExecuteReplay(path, []string{"kubectl", "get", "nodes"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+
+	_, err = ExecuteReplay(path, []string{"kubectl", "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	result, err = ExecuteReplay(path, []string{"kubectl", "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+}
+
+func TestExecuteReplay_RandomSeedFromEnv(t *testing.T) {
+	scenarioYAML := `
+meta:
+  name: chaos
+steps:
+  - match:
+      argv: ["curl", "api"]
+    calls: {min: 1, max: 6}
+    respond:
+      random:
+        - weight: 1
+          response: {stdout: "a"}
+        - weight: 1
+          response: {stdout: "b"}
+`
+	run := func(t *testing.T) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "scenario.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(scenarioYAML), 0600))
+		var pattern bytes.Buffer
+		for i := 0; i < 6; i++ {
+			_, err := ExecuteReplay(path, []string{"curl", "api"}, &pattern, &bytes.Buffer{})
+			require.NoError(t, err)
+		}
+		return pattern.String()
+	}
+
+	t.Setenv(SeedEnvVar, "42")
+	assert.Equal(t, "ababbb", run(t))
+	assert.Equal(t, "ababbb", run(t), "a fixed seed reproduces the picks across sessions")
+}
 
-	// Group in any order
-	var stdout2, stderr2 bytes.Buffer
-	_, err = ExecuteReplay(scenarioPath, []string{"cleanup", "b"}, &stdout2, &stderr2)
+func TestSeedFromEnv(t *testing.T) {
+	t.Setenv(SeedEnvVar, "")
+	_, ok, err := SeedFromEnv()
 	require.NoError(t, err)
+	assert.False(t, ok)
 
-	var stdout3, stderr3 bytes.Buffer
-	_, err = ExecuteReplay(scenarioPath, []string{"cleanup", "a"}, &stdout3, &stderr3)
+	t.Setenv(SeedEnvVar, " -17 ")
+	seed, ok, err := SeedFromEnv()
 	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-17), seed)
 
-	// Scenario complete
-	var stdout4, stderr4 bytes.Buffer
-	_, err = ExecuteReplay(scenarioPath, []string{"anything"}, &stdout4, &stderr4)
+	t.Setenv(SeedEnvVar, "abc")
+	_, _, err = SeedFromEnv()
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "already complete")
+	assert.Contains(t, err.Error(), `not an integer: "abc"`)
 }
 
-// T017: Integration tests for User Story 1 (deny env vars)
-
-func TestReplayResponseWithTemplate_DeniedEnvVarEmptyString(t *testing.T) {
-	// Denied env var with no meta.vars base → renders as empty string
-	scn := &scenario.Scenario{
-		Meta: scenario.Meta{
-			Name: "deny-test",
-			Vars: map[string]string{"AWS_KEY": ""},
-			Security: &scenario.Security{
-				DenyEnvVars: []string{"AWS_*"},
-			},
-		},
-	}
-	step := &scenario.Step{
-		Match:   scenario.Match{Argv: []string{"cmd"}},
-		Respond: scenario.Response{Exit: 0, Stdout: "key={{ .AWS_KEY }}|end"},
-	}
+func TestExecuteReplay_JitterSleepsWithinWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: jitter
+steps:
+  - match:
+      argv: ["curl", "api"]
+    respond:
+      exit: 0
+      delay: 20ms
+      jitter: 30ms
+`), 0600))
+	t.Setenv(SeedEnvVar, "7")
 
-	t.Setenv("AWS_KEY", "real-secret-value")
+	start := time.Now()
+	_, err := ExecuteReplay(path, []string{"curl", "api"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	elapsed := time.Since(start)
 
-	var stdout, stderr bytes.Buffer
-	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
-	assert.Equal(t, 0, exitCode)
-	assert.Equal(t, "key=|end", stdout.String())
+	assert.GreaterOrEqual(t, elapsed, 20*time.Millisecond)
+	assert.Less(t, elapsed, 50*time.Millisecond+time.Second, "sleep is bounded by delay + jitter")
 }
 
-func TestReplayResponseWithTemplate_AllowedEnvVarRealValue(t *testing.T) {
-	// Allowed env var → renders with real env value
-	scn := &scenario.Scenario{
-		Meta: scenario.Meta{
-			Name: "allow-test",
-			Vars: map[string]string{"HOME_VAR": "default"},
-			Security: &scenario.Security{
-				DenyEnvVars: []string{"AWS_*"},
-			},
-		},
-	}
-	step := &scenario.Step{
-		Match:   scenario.Match{Argv: []string{"cmd"}},
-		Respond: scenario.Response{Exit: 0, Stdout: "home={{ .HOME_VAR }}"},
-	}
+func TestExecuteReplay_AnyOf(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: alternatives
+steps:
+  - match:
+      any_of:
+        - ["kubectl", "get", "po"]
+        - ["kubectl", "get", "pods", "--all-namespaces"]
+    respond:
+      exit: 0
+      stdout: "pods\n"
+`), 0600))
 
-	t.Setenv("HOME_VAR", "/real/home")
+	var stderr bytes.Buffer
+	_, err := ExecuteReplay(path, []string{"kubectl", "get", "pods"}, &bytes.Buffer{}, &stderr)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, []string{"kubectl", "get", "po"}, mErr.Expected, "diff against the closest alternative")
 
-	var stdout, stderr bytes.Buffer
-	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
-	assert.Equal(t, 0, exitCode)
-	assert.Equal(t, "home=/real/home", stdout.String())
+	formatted := FormatMismatchError(mErr)
+	assert.Contains(t, formatted, "Expected any of:")
+	assert.Contains(t, formatted, "    [kubectl get po]\n")
+	assert.Contains(t, formatted, "    [kubectl get pods --all-namespaces]\n")
+
+	var stdout bytes.Buffer
+	_, err = ExecuteReplay(path, []string{"kubectl", "get", "pods", "--all-namespaces"}, &stdout, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, "pods\n", stdout.String())
 }
 
-func TestReplayResponseWithTemplate_NoSecurityPassthrough(t *testing.T) {
-	// No security section → env vars pass through normally (backward compat)
-	scn := &scenario.Scenario{
-		Meta: scenario.Meta{
-			Name: "no-sec-test",
-			Vars: map[string]string{"MY_VAR": "default"},
-		},
-	}
-	step := &scenario.Step{
-		Match:   scenario.Match{Argv: []string{"cmd"}},
-		Respond: scenario.Response{Exit: 0, Stdout: "val={{ .MY_VAR }}"},
-	}
+func TestExecuteReplay_RecordsGapsForExpectWithin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: latency
+steps:
+  - match:
+      argv: ["deploy"]
+    respond:
+      exit: 0
+  - match:
+      argv: ["status"]
+    expect_within: 20ms
+    respond:
+      exit: 0
+`), 0600))
 
-	t.Setenv("MY_VAR", "env-override")
+	_, err := ExecuteReplay(path, []string{"deploy"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	time.Sleep(60 * time.Millisecond)
+	_, err = ExecuteReplay(path, []string{"status"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
 
-	var stdout, stderr bytes.Buffer
-	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
-	assert.Equal(t, 0, exitCode)
-	assert.Equal(t, "val=env-override", stdout.String())
-}
+	state, err := ReadState(StateFilePath(path))
+	require.NoError(t, err)
+	require.Len(t, state.ServedAt, 2)
+	gap := state.ServedAt[1].Sub(state.LastServedAt[0])
+	assert.GreaterOrEqual(t, gap, 60*time.Millisecond)
 
-func TestReplayResponseWithTemplate_WildcardDenyAll(t *testing.T) {
-	// Deny-all with "*" — all env overrides suppressed
-	scn := &scenario.Scenario{
-		Meta: scenario.Meta{
-			Name: "deny-all-test",
-			Vars: map[string]string{
-				"VAR_A": "base-a",
-				"VAR_B": "base-b",
-			},
-			Security: &scenario.Security{
-				DenyEnvVars: []string{"*"},
-			},
-		},
-	}
-	step := &scenario.Step{
-		Match:   scenario.Match{Argv: []string{"cmd"}},
-		Respond: scenario.Response{Exit: 0, Stdout: "a={{ .VAR_A }} b={{ .VAR_B }}"},
-	}
+	scn, err := scenario.LoadFile(path)
+	require.NoError(t, err)
+	result := verify.BuildResult(scn.Meta.Name, "", scn.FlatSteps(), state.StepCounts, nil,
+		verify.WithServedTimes(state.ServedAt, state.LastServedAt))
+	assert.GreaterOrEqual(t, result.Steps[1].GapMs, int64(60))
+	assert.True(t, result.Steps[1].GapExceeded)
+	assert.False(t, result.Passed)
+}
 
-	t.Setenv("VAR_A", "env-a")
-	t.Setenv("VAR_B", "env-b")
+// chunkWriter records the largest single write it receives. It does not
+// implement io.ReaderFrom, so copies into it go through Write.
+type chunkWriter struct {
+	total, largest int
+	buf            bytes.Buffer
+}
 
-	var stdout, stderr bytes.Buffer
-	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
-	assert.Equal(t, 0, exitCode)
-	assert.Equal(t, "a=base-a b=base-b", stdout.String())
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.total += len(p)
+	c.largest = max(c.largest, len(p))
+	return c.buf.Write(p)
 }
 
-func TestReplayResponseWithTemplate_GlobPatterns(t *testing.T) {
-	scn := &scenario.Scenario{
-		Meta: scenario.Meta{
-			Name: "glob-test",
-			Vars: map[string]string{
-				"AWS_KEY":      "base-aws",
-				"GITHUB_TOKEN": "base-gh",
-				"NORMAL":       "base-normal",
-			},
-			Security: &scenario.Security{
-				DenyEnvVars: []string{"AWS_*", "GITHUB_TOKEN"},
-			},
-		},
-	}
-	step := &scenario.Step{
-		Match:   scenario.Match{Argv: []string{"cmd"}},
-		Respond: scenario.Response{Exit: 0, Stdout: "{{ .AWS_KEY }}|{{ .GITHUB_TOKEN }}|{{ .NORMAL }}"},
+func TestReplayResponse_LargeStaticFixtureStreamed(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	content := strings.Repeat("pod-0 Running 1/1 { ready }\n", 64*1024) // ~1.8 MB
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "big.txt"), []byte(content), 0600))
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "big.txt.gz"), gz.Bytes(), 0600))
+
+	scn := &scenario.Scenario{Meta: scenario.Meta{Name: "big"}}
+	for _, fixture := range []string{"big.txt", "big.txt.gz"} {
+		step := &scenario.Step{
+			Match:   scenario.Match{Argv: []string{"kubectl", "get", "pods"}},
+			Respond: scenario.Response{StdoutFile: fixture},
+		}
+
+		var file, tmpl chunkWriter
+		var stderr bytes.Buffer
+		assert.Equal(t, 0, ReplayResponseWithFile(step, scenarioPath, &file, &stderr), fixture)
+		assert.Equal(t, 0, ReplayResponseWithTemplate(step, scn, scenarioPath, nil, &tmpl, &stderr), fixture)
+		assert.Empty(t, stderr.String())
+
+		for _, w := range []*chunkWriter{&file, &tmpl} {
+			assert.Equal(t, len(content), w.total, fixture)
+			assert.Less(t, w.largest, len(content), "%s is written in chunks, not as one buffer", fixture)
+			assert.Equal(t, content, w.buf.String(), fixture)
+		}
 	}
+}
 
-	t.Setenv("AWS_KEY", "env-aws")
-	t.Setenv("GITHUB_TOKEN", "env-gh")
-	t.Setenv("NORMAL", "env-normal")
+func TestExecuteReplay_StreamsStaticFixture(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	content := strings.Repeat("pod-0 Running 1/1 { ready }\n", 64*1024) // ~1.8 MB
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "big.txt"), []byte(content), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "ns.txt"), []byte("ns={{ .ns }}\n"), 0600))
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: streamed
+  vars:
+    ns: prod
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+      stdout_file: big.txt
+  - match:
+      argv: ["kubectl", "get", "ns"]
+    respond:
+      exit: 0
+      stdout_file: ns.txt
+  - match:
+      argv: ["kubectl", "get", "all"]
+    respond:
+      exit: 0
+      stdout_file: big.txt
+      prepend: "header\n"
+`), 0600))
 
-	var stdout, stderr bytes.Buffer
-	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
-	assert.Equal(t, 0, exitCode)
-	// AWS_KEY and GITHUB_TOKEN denied → base values; NORMAL allowed → env value
-	assert.Equal(t, "base-aws|base-gh|env-normal", stdout.String())
-}
+	var big chunkWriter
+	var stderr bytes.Buffer
+	result, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pods"}, &big, &stderr)
+	require.NoError(t, err)
+	assert.Empty(t, stderr.String())
+	assert.Equal(t, content, big.buf.String())
+	assert.Less(t, big.largest, len(content), "the static fixture is streamed in chunks, not as one buffer")
+	assert.Equal(t, len(content), result.StdoutBytes)
 
-func TestReplayResponseWithTemplate_DenyWithTrace(t *testing.T) {
-	scn := &scenario.Scenario{
-		Meta: scenario.Meta{
-			Name: "trace-deny-test",
-			Vars: map[string]string{"SECRET": "base"},
-			Security: &scenario.Security{
-				DenyEnvVars: []string{"SECRET"},
-			},
-		},
-	}
-	step := &scenario.Step{
-		Match:   scenario.Match{Argv: []string{"cmd"}},
-		Respond: scenario.Response{Exit: 0, Stdout: "{{ .SECRET }}"},
-	}
+	state, err := ReadState(StateFilePath(scenarioPath))
+	require.NoError(t, err)
+	require.NotNil(t, state.Prev)
+	assert.Equal(t, content, state.Prev.Stdout, "a streamed fixture still reaches .prev")
 
-	t.Setenv("SECRET", "real-secret")
-	t.Setenv("CLI_REPLAY_TRACE", "1")
+	var ns bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"kubectl", "get", "ns"}, &ns, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "ns=prod\n", ns.String(), "a templated fixture is still rendered")
 
-	var stdout, stderr bytes.Buffer
-	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
-	assert.Equal(t, 0, exitCode)
-	assert.Equal(t, "base", stdout.String())
-	assert.Contains(t, stderr.String(), "cli-replay[trace]: denied env var SECRET")
+	var framed bytes.Buffer
+	_, err = ExecuteReplay(scenarioPath, []string{"kubectl", "get", "all"}, &framed, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "header\n"+content, framed.String(), "a framed fixture is read and rendered")
 }
 
-func TestReplayResponseWithTemplate_DenyPreservesMetaVarsValue(t *testing.T) {
-	// When a denied var has a base value in meta.vars, it keeps that value
-	scn := &scenario.Scenario{
-		Meta: scenario.Meta{
-			Name: "preserve-test",
-			Vars: map[string]string{"AWS_KEY": "safe-default-value"},
-			Security: &scenario.Security{
-				DenyEnvVars: []string{"AWS_*"},
-			},
-		},
-	}
-	step := &scenario.Step{
-		Match:   scenario.Match{Argv: []string{"cmd"}},
-		Respond: scenario.Response{Exit: 0, Stdout: "key={{ .AWS_KEY }}"},
-	}
-
-	t.Setenv("AWS_KEY", "real-secret")
+func TestExecuteReplay_PrevAfterStdoutFile(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "out.txt"), []byte("abc"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "err.txt"), []byte("oops"), 0600))
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(`
+meta:
+  name: prev-file
+steps:
+  - match:
+      argv: ["cmd", "one"]
+    respond:
+      exit: 0
+      stdout_file: out.txt
+      stderr_file: err.txt
+  - match:
+      argv: ["cmd", "two"]
+    respond:
+      exit: 0
+      stdout: "[{{ .prev.stdout }}|{{ .prev.stderr }}]"
+`), 0600))
 
 	var stdout, stderr bytes.Buffer
-	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
-	assert.Equal(t, 0, exitCode)
-	assert.Equal(t, "key=safe-default-value", stdout.String())
+	_, err := ExecuteReplay(scenarioPath, []string{"cmd", "one"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", stdout.String())
+
+	stdout.Reset()
+	_, err = ExecuteReplay(scenarioPath, []string{"cmd", "two"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "[abc|oops]", stdout.String())
 }
 
-// T033: Composability test — both deny_env_vars and session.ttl configured simultaneously.
-func TestReplayResponseWithTemplate_DenyAndSessionTTL_Composability(t *testing.T) {
-	scn := &scenario.Scenario{
-		Meta: scenario.Meta{
-			Name: "composability-test",
-			Vars: map[string]string{"cluster": "prod"},
-			Security: &scenario.Security{
-				DenyEnvVars: []string{"SECRET_*"},
-			},
-			Session: &scenario.Session{
-				TTL: "10m",
-			},
-		},
-	}
+func TestReplayResponseWithTemplate_TemplatedFixtureRendered(t *testing.T) {
+	tmpDir := t.TempDir()
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	// The "{{" straddles the 32 KiB scan chunk boundary
+	content := strings.Repeat("a", 32*1024-1) + "{{ .ns }}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "out.txt"), []byte(content), 0600))
+
+	scn := &scenario.Scenario{Meta: scenario.Meta{Name: "tmpl", Vars: map[string]string{"ns": "prod"}}}
 	step := &scenario.Step{
-		Match:   scenario.Match{Argv: []string{"cmd"}},
-		Respond: scenario.Response{Exit: 0, Stdout: "cluster={{ .cluster }}"},
+		Match:   scenario.Match{Argv: []string{"kubectl"}},
+		Respond: scenario.Response{StdoutFile: "out.txt"},
 	}
 
-	// Set env vars — SECRET_TOKEN should be denied, but cluster should work
-	t.Setenv("SECRET_TOKEN", "super-secret")
-	t.Setenv("cluster", "override-cluster")
-
 	var stdout, stderr bytes.Buffer
-	exitCode := ReplayResponseWithTemplate(step, scn, "/fake/path/scenario.yaml", nil, &stdout, &stderr)
-	assert.Equal(t, 0, exitCode)
-	// cluster is NOT denied, env override applies
-	assert.Equal(t, "cluster=override-cluster", stdout.String())
-	// Verify session TTL is parseable (model validation)
-	assert.NotNil(t, scn.Meta.Session)
-	assert.Equal(t, "10m", scn.Meta.Session.TTL)
+	assert.Equal(t, 0, ReplayResponseWithTemplate(step, scn, scenarioPath, nil, &stdout, &stderr))
+	assert.Empty(t, stderr.String())
+	assert.Equal(t, strings.Repeat("a", 32*1024-1)+"prod\n", stdout.String())
 }
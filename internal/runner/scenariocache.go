@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// scenarioCacheVersion is bumped whenever the cache file layout changes.
+const scenarioCacheVersion = 2
+
+// parseScenarioFile loads and validates every document of a scenario file.
+// It is a variable so tests can count how often the cache falls through to
+// a full parse.
+var parseScenarioFile = scenario.LoadFileAllWithInfo
+
+// scenarioCache is the on-disk form of a parsed scenario file. It is valid
+// only while every file the load read still has the recorded hash, every
+// environment variable it consulted still has the recorded value, and the
+// cli-replay binary that wrote it is the one reading it.
+type scenarioCache struct {
+	Version int                  `json:"version"`
+	Binary  string               `json:"binary"`
+	Files   map[string]string    `json:"files"`
+	Env     map[string]string    `json:"env"`
+	Docs    []*scenario.Scenario `json:"docs"`
+}
+
+// ScenarioCachePath returns the path of the parsed-scenario cache for a
+// scenario file. It lives in .cli-replay/ next to the state files.
+func ScenarioCachePath(scenarioPath string) string {
+	hash := sha256.Sum256([]byte(scenarioPath))
+	return filepath.Join(cliReplayDir(scenarioPath), fmt.Sprintf("cli-replay-%s.scenario", hex.EncodeToString(hash[:])[:16]))
+}
+
+// DeleteScenarioCache removes the parsed-scenario cache of a scenario file.
+// Deleting a missing cache is not an error.
+func DeleteScenarioCache(scenarioPath string) error {
+	err := os.Remove(ScenarioCachePath(scenarioPath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete scenario cache: %w", err)
+	}
+	return nil
+}
+
+// loadCachedScenario is LoadScenario for the intercept path: the scenario
+// documents are served from the parsed-scenario cache when it is still
+// valid, so repeated intercept calls skip parsing and validation. On a miss
+// the file is parsed in full and the cache rewritten (best effort). It also
+// returns the files the scenario loads, for ScenarioHash.
+func loadCachedScenario(absPath string) (*scenario.Scenario, []string, error) {
+	index, err := documentIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cachePath := ScenarioCachePath(absPath)
+	binary := binaryStamp()
+	docs, files := readScenarioCache(cachePath, binary)
+	if docs == nil {
+		var info *scenario.LoadInfo
+		docs, info, err = parseScenarioFile(absPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		writeScenarioCache(cachePath, binary, docs, info)
+		files = info.Files
+	}
+
+	scn, _, err := scenario.DocumentSelector{Index: index}.Select(docs)
+	return scn, files, err
+}
+
+// loadScenarioFiles is LoadScenario that also returns the files the
+// scenario loads, without touching the parsed-scenario cache.
+func loadScenarioFiles(absPath string) (*scenario.Scenario, []string, error) {
+	index, err := documentIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	docs, info, err := parseScenarioFile(absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	scn, _, err := scenario.DocumentSelector{Index: index}.Select(docs)
+	return scn, info.Files, err
+}
+
+// ScenarioHash returns the hash state files record for the scenario at
+// absPath, so a session can tell that the scenario changed under it. It
+// covers the scenario file and every file it includes, since editing a
+// fragment shifts the flat step indices too. Returns "" if the scenario
+// cannot be loaded.
+func ScenarioHash(absPath string) string {
+	_, info, err := scenario.LoadFileAllWithInfo(absPath)
+	if err != nil {
+		return ""
+	}
+	return hashScenarioFiles(info.Files)
+}
+
+// hashScenarioFiles hashes the content of files together. A single file
+// hashes as hashScenarioFile does, so state written before includes were
+// hashed stays valid. Returns "" if any file cannot be read.
+func hashScenarioFiles(files []string) string {
+	if len(files) == 1 {
+		return hashScenarioFile(files[0])
+	}
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+	h := sha256.New()
+	for _, path := range sorted {
+		fileHash := hashScenarioFile(path)
+		if fileHash == "" {
+			return ""
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", path, fileHash)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readScenarioCache returns the cached documents and the files they were
+// loaded from, or nil when the cache is missing, unreadable or stale.
+func readScenarioCache(cachePath, binary string) ([]*scenario.Scenario, []string) {
+	data, err := os.ReadFile(cachePath) //nolint:gosec // Path derived from the scenario path
+	if err != nil {
+		return nil, nil
+	}
+	var c scenarioCache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, nil
+	}
+	if c.Version != scenarioCacheVersion || c.Binary != binary || len(c.Docs) == 0 {
+		return nil, nil
+	}
+	files := make([]string, 0, len(c.Files))
+	for path, want := range c.Files {
+		if hashScenarioFile(path) != want {
+			return nil, nil
+		}
+		files = append(files, path)
+	}
+	for name, want := range c.Env {
+		if os.Getenv(name) != want {
+			return nil, nil
+		}
+	}
+	return c.Docs, files
+}
+
+// writeScenarioCache records docs with what their load read. Failures are
+// ignored: the next call simply parses again.
+func writeScenarioCache(cachePath, binary string, docs []*scenario.Scenario, info *scenario.LoadInfo) {
+	c := scenarioCache{
+		Version: scenarioCacheVersion,
+		Binary:  binary,
+		Files:   make(map[string]string, len(info.Files)),
+		Env:     info.Env,
+		Docs:    docs,
+	}
+	for _, path := range info.Files {
+		hash := hashScenarioFile(path)
+		if hash == "" {
+			return
+		}
+		c.Files[path] = hash
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0750); err != nil {
+		return
+	}
+	tmp := fmt.Sprintf("%s.tmp.%d", cachePath, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, cachePath); err != nil {
+		_ = os.Remove(tmp)
+	}
+}
+
+// binaryStamp identifies the running cli-replay binary, so a cache written
+// by a different build (whose scenario model may differ) is never used.
+func binaryStamp() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	fi, err := os.Stat(exe)
+	if err != nil {
+		return exe
+	}
+	return fmt.Sprintf("%s:%d:%s", exe, fi.Size(), fi.ModTime().UTC().Format(time.RFC3339Nano))
+}
@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// countParses replaces the scenario parser with one that counts its calls.
+func countParses(t *testing.T) *int {
+	t.Helper()
+	var n int
+	orig := parseScenarioFile
+	parseScenarioFile = func(path string) ([]*scenario.Scenario, *scenario.LoadInfo, error) {
+		n++
+		return orig(path)
+	}
+	t.Cleanup(func() { parseScenarioFile = orig })
+	return &n
+}
+
+const cachedScenario = `meta:
+  name: cached
+  vars:
+    ns: default
+includes:
+  - login.yaml
+steps:
+  - match:
+      argv: [kubectl, get, pods, -n, "{{ .ns }}"]
+    respond:
+      exit: 0
+      stdout: "web-0\n"
+`
+
+const cachedFragment = `meta:
+  name: login
+steps:
+  - match:
+      argv: [az, login]
+    respond:
+      exit: 0
+      stdout: "logged in\n"
+`
+
+func writeCachedScenario(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(cachedScenario), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "login.yaml"), []byte(cachedFragment), 0600))
+	return path
+}
+
+func TestExecuteReplay_ScenarioCacheSkipsParse(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv(DocumentEnvVar, "")
+	t.Setenv("ns", "")
+	parses := countParses(t)
+	path := writeCachedScenario(t)
+
+	var stdout1, stdout2 bytes.Buffer
+	result1, err := ExecuteReplay(path, []string{"az", "login"}, &stdout1, &bytes.Buffer{})
+	require.NoError(t, err)
+	result2, err := ExecuteReplay(path, []string{"kubectl", "get", "pods", "-n", "default"}, &stdout2, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, *parses, "second call is served from the cache")
+	assert.Equal(t, "logged in\n", stdout1.String())
+	assert.Equal(t, "web-0\n", stdout2.String())
+	assert.Equal(t, "cached", result2.ScenarioName)
+	assert.Equal(t, 1, result2.StepIndex)
+	assert.Equal(t, result1.ScenarioName, result2.ScenarioName)
+	assert.FileExists(t, ScenarioCachePath(path))
+}
+
+func TestLoadCachedScenario_IdenticalToParse(t *testing.T) {
+	t.Setenv(DocumentEnvVar, "")
+	t.Setenv("ns", "")
+	parses := countParses(t)
+	path := writeCachedScenario(t)
+
+	parsed, err := LoadScenario(path)
+	require.NoError(t, err)
+	first, _, err := loadCachedScenario(path)
+	require.NoError(t, err)
+	cached, _, err := loadCachedScenario(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, *parses)
+	assert.Equal(t, parsed, first)
+	assert.Equal(t, parsed, cached)
+}
+
+func TestLoadCachedScenario_Invalidation(t *testing.T) {
+	t.Setenv(DocumentEnvVar, "")
+	t.Setenv("ns", "")
+	parses := countParses(t)
+	path := writeCachedScenario(t)
+	dir := filepath.Dir(path)
+
+	load := func() *scenario.Scenario {
+		t.Helper()
+		scn, _, err := loadCachedScenario(path)
+		require.NoError(t, err)
+		return scn
+	}
+
+	load()
+	load()
+	assert.Equal(t, 1, *parses)
+
+	// The scenario file changes
+	require.NoError(t, os.WriteFile(path, []byte(cachedScenario+"  - match:\n      argv: [kubectl, logs]\n"), 0600))
+	assert.Len(t, load().FlatSteps(), 3)
+	assert.Equal(t, 2, *parses)
+
+	// An included file changes
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "login.yaml"),
+		[]byte(cachedFragment+"  - match:\n      argv: [az, account, show]\n"), 0600))
+	assert.Len(t, load().FlatSteps(), 4)
+	assert.Equal(t, 3, *parses)
+
+	// An environment variable rendered into argv changes
+	t.Setenv("ns", "prod")
+	assert.Equal(t, []string{"kubectl", "get", "pods", "-n", "prod"}, load().FlatSteps()[2].Match.Argv)
+	assert.Equal(t, 4, *parses)
+
+	// A corrupt cache falls back to a full parse
+	require.NoError(t, os.WriteFile(ScenarioCachePath(path), []byte("{"), 0600))
+	load()
+	assert.Equal(t, 5, *parses)
+	load()
+	assert.Equal(t, 5, *parses)
+}
+
+func TestLoadCachedScenario_SelectedDocument(t *testing.T) {
+	parses := countParses(t)
+	path := writeMultiDocScenario(t)
+
+	t.Setenv(DocumentEnvVar, "1")
+	scn, _, err := loadCachedScenario(path)
+	require.NoError(t, err)
+	assert.Equal(t, "teardown", scn.Meta.Name)
+
+	t.Setenv(DocumentEnvVar, "")
+	scn, _, err = loadCachedScenario(path)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", scn.Meta.Name)
+	assert.Equal(t, 1, *parses, "every document is cached")
+
+	t.Setenv(DocumentEnvVar, "2")
+	_, _, err = loadCachedScenario(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of range")
+}
+
+func TestScenarioCache_RoundTripsTestdata(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "testdata", "scenarios", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, p := range paths {
+		docs, info, err := scenario.LoadFileAllWithInfo(p)
+		if err != nil {
+			continue // invalid fixtures are never cached
+		}
+		t.Run(filepath.Base(p), func(t *testing.T) {
+			cachePath := filepath.Join(t.TempDir(), "cache.scenario")
+			writeScenarioCache(cachePath, "test", docs, info)
+			cached, files := readScenarioCache(cachePath, "test")
+			require.NotNil(t, cached)
+			assert.Equal(t, docs, cached)
+			assert.ElementsMatch(t, info.Files, files)
+			stale, _ := readScenarioCache(cachePath, "other-binary")
+			assert.Nil(t, stale)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/pkg/replay"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// SequenceEnvVar lists the scenario files of a multi-file run, separated by
+// os.PathListSeparator. When set, intercepts replay the files in order.
+const SequenceEnvVar = "CLI_REPLAY_SEQUENCE"
+
+// ParseSequence splits a CLI_REPLAY_SEQUENCE value into scenario paths.
+func ParseSequence(v string) []string {
+	var paths []string
+	for _, p := range filepath.SplitList(v) {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// FormatSequence joins scenario paths into a CLI_REPLAY_SEQUENCE value.
+func FormatSequence(paths []string) string {
+	return strings.Join(paths, string(os.PathListSeparator))
+}
+
+// sequenceMember is one scenario file of a sequence with its session state.
+type sequenceMember struct {
+	path  string
+	scn   *scenario.Scenario
+	state *State // nil if no state has been written yet
+}
+
+// ExecuteSequenceReplay replays argv against a sequence of scenario files.
+// Each file keeps its own state and step ordering; the active file is the
+// first one not yet complete. When a command does not match the active file
+// but every step there has met its min count, and the command matches the
+// next file, the active file is closed and replay moves on. Captures set by
+// earlier files are visible to templates in later ones.
+func ExecuteSequenceReplay(paths []string, argv []string, stdout, stderr io.Writer) (*ReplayResult, error) {
+	if len(paths) == 0 {
+		return &ReplayResult{ExitCode: 1}, fmt.Errorf("empty scenario sequence")
+	}
+
+	members := make([]sequenceMember, 0, len(paths))
+	for _, p := range paths {
+		absPath, err := ResolveScenarioPath(p)
+		if err != nil {
+			return &ReplayResult{ExitCode: 1}, fmt.Errorf("failed to resolve scenario path: %w", err)
+		}
+		scn, err := scenario.LoadFile(absPath)
+		if err != nil {
+			return &ReplayResult{ExitCode: 1}, fmt.Errorf("failed to load scenario %s: %w", p, err)
+		}
+		m := sequenceMember{path: absPath, scn: scn}
+		if st, err := ReadState(StateFilePath(absPath)); err == nil {
+			m.state = st
+		} else if !os.IsNotExist(err) {
+			return &ReplayResult{ExitCode: 1}, fmt.Errorf("failed to read state: %w", err)
+		}
+		members = append(members, m)
+	}
+
+	captures := make(map[string]string)
+	active := -1
+	for i, m := range members {
+		if m.state != nil && m.state.IsComplete() {
+			mergeCaptures(captures, m.state.Captures)
+			continue
+		}
+		active = i
+		break
+	}
+	if active < 0 {
+		_, _ = fmt.Fprintf(stderr, "cli-replay: scenario sequence already complete (all %d files consumed)\n", len(members))
+		return &ReplayResult{ExitCode: members[len(members)-1].scn.Meta.CompleteExitCode()}, fmt.Errorf("scenario sequence already complete")
+	}
+
+	// Advance past files whose work is done when the command belongs later
+	for active+1 < len(members) {
+		cur := members[active]
+		if !cur.minsMet() || cur.matches(argv, captures) {
+			break
+		}
+		next := members[active+1]
+		nextCaptures := copyCaptures(captures)
+		if cur.state != nil {
+			mergeCaptures(nextCaptures, cur.state.Captures)
+		}
+		if !next.matches(argv, nextCaptures) {
+			break
+		}
+		if err := closeScenarioState(cur.path); err != nil {
+			return &ReplayResult{ExitCode: 1}, err
+		}
+		captures = nextCaptures
+		active++
+	}
+
+	return ExecuteReplayWithCaptures(members[active].path, argv, captures, stdout, stderr)
+}
+
+// minsMet reports whether every step of the member has met its min count.
+func (m sequenceMember) minsMet() bool {
+	if m.state == nil {
+		return NewState("", "", len(m.scn.FlatSteps())).AllStepsMetMin(m.scn.FlatSteps())
+	}
+	return m.state.AllStepsMetMin(m.scn.FlatSteps())
+}
+
+// matches reports whether argv would match the member's next step, using an
+// in-memory engine so no state is changed.
+func (m sequenceMember) matches(argv []string, captures map[string]string) bool {
+	st := m.state
+	if st == nil {
+		st = NewState(m.path, "", len(m.scn.FlatSteps()))
+	}
+	seeded := copyCaptures(captures)
+	mergeCaptures(seeded, st.Captures)
+	engine := replay.New(m.scn,
+		replay.WithInitialState(replay.StateSnapshot{
+			CurrentStep: st.CurrentStep,
+			TotalSteps:  st.TotalSteps,
+			StepCounts:  st.StepCounts,
+			ActiveGroup: st.ActiveGroup,
+			Captures:    seeded,
+		}),
+		replay.WithEnvLookup(os.Getenv),
+		workingDirOption(filepath.Dir(m.path)),
+		replay.WithFileReader(func(string) (string, error) { return "", nil }),
+	)
+	argv = m.scn.Meta.StripArgvPrefixes(argv)
+	var name string
+	var args []string
+	if len(argv) > 0 {
+		name, args = argv[0], argv[1:]
+	}
+	_, err := engine.Match(context.Background(), name, args)
+	return err == nil
+}
+
+// closeScenarioState marks a scenario's session as complete so a sequence
+// moves on to the next file. Step counts are kept for verification.
+func closeScenarioState(absPath string) error {
+	stateFile := StateFilePath(absPath)
+	unlock, err := LockState(stateFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	scn, err := scenario.LoadFile(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to load scenario: %w", err)
+	}
+	state, err := ReadState(stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read state: %w", err)
+		}
+		state = NewState(absPath, ScenarioHash(absPath), len(scn.FlatSteps()))
+	}
+	state.CurrentStep = state.TotalSteps
+	state.ActiveGroup = nil
+	state.LastUpdated = time.Now().UTC()
+	return WriteState(stateFile, state)
+}
+
+// mergeCaptures copies src into dst; later files override earlier values.
+func mergeCaptures(dst, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+// copyCaptures returns a shallow copy of m.
+func copyCaptures(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	mergeCaptures(out, m)
+	return out
+}
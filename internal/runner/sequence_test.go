@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSequenceFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestParseSequence_RoundTrip(t *testing.T) {
+	paths := []string{"/tmp/a.yaml", "/tmp/b.yaml"}
+	assert.Equal(t, paths, ParseSequence(FormatSequence(paths)))
+	assert.Nil(t, ParseSequence(""))
+}
+
+func TestExecuteSequenceReplay_CaptureSharedAcrossFiles(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "seq-captures")
+	dir := t.TempDir()
+	a := writeSequenceFile(t, dir, "a.yaml", `
+meta:
+  name: phase-a
+steps:
+  - match:
+      argv: ["az", "group", "create"]
+    respond:
+      exit: 0
+      capture:
+        rg: rg-1234
+`)
+	b := writeSequenceFile(t, dir, "b.yaml", `
+meta:
+  name: phase-b
+steps:
+  - match:
+      argv: ["az", "vm", "create"]
+    respond:
+      exit: 0
+      stdout: "vm in {{ .capture.rg }}\n"
+`)
+	paths := []string{a, b}
+
+	var stdout, stderr bytes.Buffer
+	result, err := ExecuteSequenceReplay(paths, []string{"az", "group", "create"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "phase-a", result.ScenarioName)
+
+	stdout.Reset()
+	result, err = ExecuteSequenceReplay(paths, []string{"az", "vm", "create"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "phase-b", result.ScenarioName)
+	assert.Equal(t, "vm in rg-1234\n", stdout.String())
+
+	// The sequence is now exhausted
+	_, err = ExecuteSequenceReplay(paths, []string{"az", "vm", "create"}, &stdout, &stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sequence already complete")
+}
+
+func TestExecuteSequenceReplay_AdvancesWhenMinsMet(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "seq-advance")
+	dir := t.TempDir()
+	a := writeSequenceFile(t, dir, "a.yaml", `
+meta:
+  name: phase-a
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    calls:
+      min: 1
+      max: 5
+`)
+	b := writeSequenceFile(t, dir, "b.yaml", `
+meta:
+  name: phase-b
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      stdout: "from b\n"
+  - match:
+      argv: ["kubectl", "apply"]
+`)
+	paths := []string{a, b}
+	var stdout, stderr bytes.Buffer
+
+	_, err := ExecuteSequenceReplay(paths, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	// Polling on the same command stays in file A while it has budget
+	result, err := ExecuteSequenceReplay(paths, []string{"kubectl", "get", "pods"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "phase-a", result.ScenarioName)
+	assert.Empty(t, stdout.String())
+
+	// A command only file B expects moves the session on
+	result, err = ExecuteSequenceReplay(paths, []string{"kubectl", "apply"}, &stdout, &stderr)
+	require.Error(t, err, "b's first step must be consumed before its second")
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, "phase-a", mErr.Scenario)
+	_ = result
+
+	stateA, err := ReadState(StateFilePath(a))
+	require.NoError(t, err)
+	assert.False(t, stateA.IsComplete())
+}
+
+func TestExecuteSequenceReplay_ClosesFinishedFile(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "seq-close")
+	dir := t.TempDir()
+	a := writeSequenceFile(t, dir, "a.yaml", `
+meta:
+  name: phase-a
+steps:
+  - match:
+      argv: ["git", "fetch"]
+    calls:
+      min: 1
+      max: 3
+`)
+	b := writeSequenceFile(t, dir, "b.yaml", `
+meta:
+  name: phase-b
+steps:
+  - match:
+      argv: ["make", "build"]
+  - match:
+      argv: ["git", "fetch"]
+`)
+	paths := []string{a, b}
+	var stdout, stderr bytes.Buffer
+
+	_, err := ExecuteSequenceReplay(paths, []string{"git", "fetch"}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	result, err := ExecuteSequenceReplay(paths, []string{"make", "build"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "phase-b", result.ScenarioName)
+
+	stateA, err := ReadState(StateFilePath(a))
+	require.NoError(t, err)
+	assert.True(t, stateA.IsComplete(), "file A is closed once B takes over")
+	assert.Equal(t, []int{1}, stateA.StepCounts, "counts are kept for verification")
+
+	// git fetch now belongs to file B, not A's leftover budget
+	result, err = ExecuteSequenceReplay(paths, []string{"git", "fetch"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "phase-b", result.ScenarioName)
+}
+
+func TestExecuteSequenceReplay_NoAdvanceBeforeMinsMet(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "seq-mins")
+	dir := t.TempDir()
+	a := writeSequenceFile(t, dir, "a.yaml", `
+meta:
+  name: phase-a
+steps:
+  - match:
+      argv: ["git", "fetch"]
+`)
+	b := writeSequenceFile(t, dir, "b.yaml", `
+meta:
+  name: phase-b
+steps:
+  - match:
+      argv: ["make", "build"]
+`)
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteSequenceReplay([]string{a, b}, []string{"make", "build"}, &stdout, &stderr)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, "phase-a", mErr.Scenario)
+}
@@ -0,0 +1,19 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+)
+
+// AutoSessionID derives a session ID from the parent process of the current
+// one. For `eval "$(cli-replay run ...)"` that is the calling shell, so the
+// ID is the same for every run within a shell's lifetime and differs between
+// concurrently running shells.
+func AutoSessionID() string {
+	return autoSessionID(os.Getppid())
+}
+
+// autoSessionID formats the session ID for a parent process ID.
+func autoSessionID(ppid int) string {
+	return fmt.Sprintf("pid-%d", ppid)
+}
@@ -0,0 +1,25 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoSessionID_StablePerShell(t *testing.T) {
+	assert.Equal(t, autoSessionID(4242), autoSessionID(4242))
+	assert.Equal(t, "pid-4242", autoSessionID(4242))
+	assert.Equal(t, AutoSessionID(), AutoSessionID())
+}
+
+func TestAutoSessionID_DistinctShellsGetDistinctState(t *testing.T) {
+	scenarioPath := "/tmp/project/scenario.yaml"
+	shellA := autoSessionID(1001)
+	shellB := autoSessionID(1002)
+	assert.NotEqual(t, shellA, shellB)
+
+	pathA := StateFilePathWithSession(scenarioPath, shellA)
+	pathB := StateFilePathWithSession(scenarioPath, shellB)
+	assert.NotEqual(t, pathA, pathB)
+	assert.Equal(t, pathA, StateFilePathWithSession(scenarioPath, autoSessionID(1001)))
+}
@@ -0,0 +1,225 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ormasoftchile/cli-replay/pkg/replay"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// SimulationTrace is the result of replaying a command log against a
+// scenario in memory. No state file is read or written.
+type SimulationTrace struct {
+	Entries []SimulationEntry
+	// MismatchAt is the index into Entries of the first command that did
+	// not match, or -1 if every command matched.
+	MismatchAt int
+	// Unmet lists the steps that had not reached their minimum call count
+	// when the log ended. Only populated when every command matched.
+	Unmet []SimulationUnmet
+}
+
+// SimulationUnmet is a step left below its minimum call count.
+type SimulationUnmet struct {
+	StepIndex int
+	Argv      []string
+	Count     int
+	Min       int
+}
+
+// SimulationEntry describes how one command from the log was handled.
+type SimulationEntry struct {
+	Line      int      // 1-based line number in the command log
+	Argv      []string // command as parsed from the log
+	Matched   bool
+	StepIndex int      // matched flat step index, or the expected step on mismatch (-1 if unknown)
+	Expected  []string // argv of the expected step on mismatch
+	ExitCode  int      // exit code the matched step would return
+	Error     string   // mismatch reason when Matched is false
+}
+
+// Passed reports whether every command matched and all step minimums were met.
+func (t *SimulationTrace) Passed() bool {
+	return t.MismatchAt < 0 && len(t.Unmet) == 0
+}
+
+// SimulatedCommand is one argv read from a command log, with its line number.
+type SimulatedCommand struct {
+	Line int
+	Argv []string
+}
+
+// ParseCommandLog reads one command per line from r. Blank lines and lines
+// starting with '#' are skipped. Arguments are split on whitespace, with
+// single quotes, double quotes and backslash escapes handled as in a POSIX
+// shell.
+func ParseCommandLog(r io.Reader) ([]SimulatedCommand, error) {
+	var commands []SimulatedCommand
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		argv, err := splitCommandLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		commands = append(commands, SimulatedCommand{Line: lineNum, Argv: argv})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading command log: %w", err)
+	}
+	return commands, nil
+}
+
+// splitCommandLine splits a command line into arguments.
+func splitCommandLine(line string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// SimulateCommands replays commands against scn using an in-memory replay
+// engine, so ordering, call bounds and group semantics are exactly those of
+// a real session. Simulation stops at the first command that does not match.
+func SimulateCommands(scn *scenario.Scenario, scenarioDir string, commands []SimulatedCommand) *SimulationTrace {
+	opts := []replay.Option{
+		replay.WithEnvLookup(os.Getenv),
+		workingDirOption(scenarioDir),
+		replay.WithFileReader(func(relPath string) (string, error) {
+			return readScenarioFile(scn, scenarioDir, relPath)
+		}),
+	}
+	if scn.Meta.Security != nil && len(scn.Meta.Security.DenyEnvVars) > 0 {
+		opts = append(opts, replay.WithDenyEnvPatterns(scn.Meta.Security.DenyEnvVars))
+	}
+	engine := replay.New(scn, opts...)
+	flatSteps := scn.FlatSteps()
+
+	trace := &SimulationTrace{MismatchAt: -1}
+	for _, c := range commands {
+		entry := SimulationEntry{Line: c.Line, Argv: c.Argv, StepIndex: -1}
+		var name string
+		var args []string
+		if len(c.Argv) > 0 {
+			name, args = c.Argv[0], c.Argv[1:]
+		}
+
+		result, err := engine.Match(context.Background(), name, args)
+		if result != nil {
+			entry.StepIndex = result.StepIndex
+			entry.ExitCode = result.ExitCode
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			if _, ok := err.(*replay.ScenarioCompleteError); ok {
+				entry.StepIndex = -1
+			}
+			if entry.StepIndex >= 0 && entry.StepIndex < len(flatSteps) {
+				entry.Expected = flatSteps[entry.StepIndex].Match.PrimaryArgv()
+			}
+			trace.Entries = append(trace.Entries, entry)
+			trace.MismatchAt = len(trace.Entries) - 1
+			break
+		}
+		entry.Matched = true
+		trace.Entries = append(trace.Entries, entry)
+	}
+
+	if trace.MismatchAt < 0 {
+		counts := engine.StepCounts()
+		for i, step := range flatSteps {
+			count := 0
+			if i < len(counts) {
+				count = counts[i]
+			}
+			if min := step.EffectiveCalls().Min; count < min {
+				trace.Unmet = append(trace.Unmet, SimulationUnmet{StepIndex: i, Argv: step.Match.PrimaryArgv(), Count: count, Min: min})
+			}
+		}
+	}
+	return trace
+}
+
+// FormatSimulationTrace writes a human-readable simulation trace to w.
+func FormatSimulationTrace(trace *SimulationTrace, w io.Writer) {
+	_, _ = fmt.Fprintf(w, "\nSimulation (%d command(s)):\n", len(trace.Entries))
+	for _, e := range trace.Entries {
+		cmd := strings.Join(e.Argv, " ")
+		if e.Matched {
+			_, _ = fmt.Fprintf(w, " ✓ line %d: %s → step %d (exit %d)\n", e.Line, cmd, e.StepIndex+1, e.ExitCode)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, " ✗ line %d: %s\n", e.Line, cmd)
+		if e.Expected != nil {
+			_, _ = fmt.Fprintf(w, "     expected step %d: %s\n", e.StepIndex+1, strings.Join(e.Expected, " "))
+		}
+		_, _ = fmt.Fprintf(w, "     %s\n", e.Error)
+	}
+
+	switch {
+	case trace.MismatchAt >= 0:
+		_, _ = fmt.Fprintf(w, "✗ Mismatch at command %d (line %d)\n", trace.MismatchAt+1, trace.Entries[trace.MismatchAt].Line)
+	case len(trace.Unmet) > 0:
+		for _, u := range trace.Unmet {
+			_, _ = fmt.Fprintf(w, "✗ step %d not satisfied: %s (%d of min %d calls)\n",
+				u.StepIndex+1, strings.Join(u.Argv, " "), u.Count, u.Min)
+		}
+	default:
+		_, _ = fmt.Fprintln(w, "✓ All commands matched and all steps satisfied")
+	}
+}
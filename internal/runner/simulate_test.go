@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const simulateScenario = `
+meta:
+  name: "simulate"
+steps:
+  - match:
+      argv: ["az", "login"]
+    respond:
+      exit: 0
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: ["kubectl", "get", "pods"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["kubectl", "get", "svc"]
+          respond:
+            exit: 0
+  - match:
+      argv: ["kubectl", "delete", "pod", "web 0"]
+    respond:
+      exit: 3
+`
+
+func loadSimulateScenario(t *testing.T) *scenario.Scenario {
+	t.Helper()
+	scn, err := scenario.Load(strings.NewReader(simulateScenario))
+	require.NoError(t, err)
+	return scn
+}
+
+func parseLog(t *testing.T, log string) []SimulatedCommand {
+	t.Helper()
+	cmds, err := ParseCommandLog(strings.NewReader(log))
+	require.NoError(t, err)
+	return cmds
+}
+
+func TestParseCommandLog(t *testing.T) {
+	cmds := parseLog(t, `# setup
+az login
+
+kubectl delete pod "web 0"
+echo 'a "b"' c\ d
+`)
+	require.Len(t, cmds, 3)
+	assert.Equal(t, 2, cmds[0].Line)
+	assert.Equal(t, []string{"az", "login"}, cmds[0].Argv)
+	assert.Equal(t, []string{"kubectl", "delete", "pod", "web 0"}, cmds[1].Argv)
+	assert.Equal(t, []string{"echo", `a "b"`, "c d"}, cmds[2].Argv)
+}
+
+func TestParseCommandLog_UnterminatedQuote(t *testing.T) {
+	_, err := ParseCommandLog(strings.NewReader("az login\necho \"oops\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestSimulateCommands_AllMatch(t *testing.T) {
+	scn := loadSimulateScenario(t)
+	// Group children in reverse order are fine for an unordered group.
+	cmds := parseLog(t, `az login
+kubectl get svc
+kubectl get pods
+kubectl delete pod "web 0"
+`)
+
+	trace := SimulateCommands(scn, t.TempDir(), cmds)
+	require.True(t, trace.Passed())
+	assert.Equal(t, -1, trace.MismatchAt)
+	require.Len(t, trace.Entries, 4)
+	assert.Equal(t, []int{0, 2, 1, 3}, []int{
+		trace.Entries[0].StepIndex, trace.Entries[1].StepIndex,
+		trace.Entries[2].StepIndex, trace.Entries[3].StepIndex,
+	})
+	assert.Equal(t, 3, trace.Entries[3].ExitCode)
+
+	var buf bytes.Buffer
+	FormatSimulationTrace(trace, &buf)
+	assert.Contains(t, buf.String(), "✓ line 4: kubectl delete pod web 0 → step 4 (exit 3)")
+	assert.Contains(t, buf.String(), "All commands matched")
+}
+
+func TestSimulateCommands_MismatchAtStep(t *testing.T) {
+	scn := loadSimulateScenario(t)
+	cmds := parseLog(t, `az login
+kubectl get pods
+kubectl get svc
+kubectl delete pod web-1
+kubectl get nodes
+`)
+
+	trace := SimulateCommands(scn, t.TempDir(), cmds)
+	assert.False(t, trace.Passed())
+	assert.Equal(t, 3, trace.MismatchAt)
+	require.Len(t, trace.Entries, 4, "simulation stops at the first mismatch")
+	miss := trace.Entries[3]
+	assert.False(t, miss.Matched)
+	assert.Equal(t, 3, miss.StepIndex)
+	assert.Equal(t, []string{"kubectl", "delete", "pod", "web 0"}, miss.Expected)
+
+	var buf bytes.Buffer
+	FormatSimulationTrace(trace, &buf)
+	assert.Contains(t, buf.String(), "✗ line 4: kubectl delete pod web-1")
+	assert.Contains(t, buf.String(), "expected step 4: kubectl delete pod web 0")
+	assert.Contains(t, buf.String(), "Mismatch at command 4 (line 4)")
+}
+
+func TestSimulateCommands_UnmetSteps(t *testing.T) {
+	scn := loadSimulateScenario(t)
+	trace := SimulateCommands(scn, t.TempDir(), parseLog(t, "az login\n"))
+
+	assert.Equal(t, -1, trace.MismatchAt)
+	assert.False(t, trace.Passed())
+	require.Len(t, trace.Unmet, 3)
+	assert.Equal(t, 1, trace.Unmet[0].StepIndex)
+}
@@ -9,8 +9,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
+	"github.com/ormasoftchile/cli-replay/pkg/replay"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 )
 
@@ -26,6 +28,21 @@ type State struct {
 	InterceptDir  string            `json:"intercept_dir,omitempty"`
 	LastUpdated   time.Time         `json:"last_updated"`
 	Captures      map[string]string `json:"captures,omitempty"`
+	StepDurations []time.Duration   `json:"step_durations,omitempty"`  // longest service time per step
+	ServedAt      []time.Time       `json:"served_at,omitempty"`       // first call per step, for expect_within
+	LastServedAt  []time.Time       `json:"last_served_at,omitempty"`  // most recent call per step
+	Prev          *replay.Served    `json:"prev,omitempty"`            // last served response, exposed as .prev
+	MaxStdinBytes int64             `json:"max_stdin_bytes,omitempty"` // --max-stdin override of meta.limits
+	// MismatchStreak counts consecutive mismatches of LastMismatchArgv, for
+	// meta.limits.max_consecutive_mismatches.
+	MismatchStreak   int      `json:"mismatch_streak,omitempty"`
+	LastMismatchArgv []string `json:"last_mismatch_argv,omitempty"`
+	// AssertFailures holds, per step, the first respond.assert failure of
+	// any call; empty when every call passed.
+	AssertFailures []string `json:"assert_failures,omitempty"`
+	// ForbiddenCalls holds the argv of every call to a command in
+	// meta.security.forbidden_commands.
+	ForbiddenCalls [][]string `json:"forbidden_calls,omitempty"`
 }
 
 // IsInGroup returns true if the state is currently inside a step group.
@@ -119,6 +136,89 @@ func (s *State) AdvanceStep(idx int) {
 	s.LastUpdated = time.Now().UTC()
 }
 
+// SeekTo positions the state at flat step n, as if every earlier step had
+// been invoked exactly its minimum number of times. n must not point inside
+// a step group other than at the group's first step.
+func (s *State) SeekTo(steps []scenario.Step, ranges []scenario.GroupRange, n int) error {
+	if n < 0 || n >= len(steps) {
+		return fmt.Errorf("start step %d out of range: scenario has steps 0-%d", n, len(steps)-1)
+	}
+	if gi := FindGroupContaining(ranges, n); gi >= 0 && ranges[gi].Start != n {
+		return fmt.Errorf("start step %d is inside group %q; start at its first step (%d)", n, ranges[gi].Name, ranges[gi].Start)
+	}
+
+	if len(s.StepCounts) < len(steps) {
+		s.StepCounts = make([]int, len(steps))
+	}
+	for i := 0; i < n; i++ {
+		s.StepCounts[i] = steps[i].EffectiveCalls().Min
+	}
+	s.CurrentStep = n
+	s.ActiveGroup = nil
+	s.LastUpdated = time.Now().UTC()
+	return nil
+}
+
+// RecordStepDuration records the time spent serving step idx, keeping the
+// longest duration observed across calls.
+func (s *State) RecordStepDuration(idx int, d time.Duration) {
+	if idx < 0 || idx >= s.TotalSteps {
+		return
+	}
+	if len(s.StepDurations) < s.TotalSteps {
+		grown := make([]time.Duration, s.TotalSteps)
+		copy(grown, s.StepDurations)
+		s.StepDurations = grown
+	}
+	if d > s.StepDurations[idx] {
+		s.StepDurations[idx] = d
+	}
+}
+
+// RecordServed records that step idx was called at t: the first call sets
+// ServedAt, every call updates LastServedAt.
+func (s *State) RecordServed(idx int, t time.Time) {
+	if idx < 0 || idx >= s.TotalSteps {
+		return
+	}
+	if len(s.ServedAt) < s.TotalSteps {
+		grown := make([]time.Time, s.TotalSteps)
+		copy(grown, s.ServedAt)
+		s.ServedAt = grown
+	}
+	if len(s.LastServedAt) < s.TotalSteps {
+		grown := make([]time.Time, s.TotalSteps)
+		copy(grown, s.LastServedAt)
+		s.LastServedAt = grown
+	}
+	if s.ServedAt[idx].IsZero() {
+		s.ServedAt[idx] = t
+	}
+	s.LastServedAt[idx] = t
+}
+
+// RecordAssertFailure records a respond.assert failure for step idx. Only
+// the first failure is kept, so a later passing call does not hide it.
+func (s *State) RecordAssertFailure(idx int, failure string) {
+	if idx < 0 || idx >= s.TotalSteps || failure == "" {
+		return
+	}
+	if len(s.AssertFailures) < s.TotalSteps {
+		grown := make([]string, s.TotalSteps)
+		copy(grown, s.AssertFailures)
+		s.AssertFailures = grown
+	}
+	if s.AssertFailures[idx] == "" {
+		s.AssertFailures[idx] = failure
+	}
+}
+
+// RecordForbiddenCall records a call to a forbidden command.
+func (s *State) RecordForbiddenCall(argv []string) {
+	s.ForbiddenCalls = append(s.ForbiddenCalls, slices.Clone(argv))
+	s.LastUpdated = time.Now().UTC()
+}
+
 // AllStepsConsumed returns true if every step has been invoked at least once.
 func (s *State) AllStepsConsumed() bool {
 	if s.StepCounts == nil {
@@ -191,6 +291,15 @@ func (s *State) AllStepsMetMin(steps []scenario.Step) bool {
 	return true
 }
 
+// TotalCalls returns the number of matched calls across all steps.
+func (s *State) TotalCalls() int {
+	total := 0
+	for _, c := range s.StepCounts {
+		total += c
+	}
+	return total
+}
+
 // IsComplete returns true if all steps have been consumed.
 func (s *State) IsComplete() bool {
 	return s.CurrentStep >= s.TotalSteps
@@ -302,8 +411,10 @@ func WriteState(path string, state *State) error {
 	return nil
 }
 
-// DeleteState removes the state file at the given path.
-// Does not return an error if the file doesn't exist.
+// DeleteState removes the state file at the given path. Does not return an
+// error if the file doesn't exist. The lock file is left in place: removing
+// it would let a process still waiting on the old file and one that creates
+// a new file both hold the lock.
 func DeleteState(path string) error {
 	err := os.Remove(path)
 	if err != nil && !os.IsNotExist(err) {
@@ -327,7 +438,9 @@ func NewState(scenarioPath, scenarioHash string, totalSteps int) *State {
 
 // CleanExpiredSessions scans a .cli-replay/ directory for state files older
 // than the given TTL. For each expired state file, it removes the associated
-// intercept directory (if any) and the state file itself.
+// intercept directory (if any) and the state file itself. Completion
+// markers (*.done) older than the TTL are removed too, without counting
+// as sessions.
 // Returns the number of cleaned sessions and any error.
 // Files with last_updated in the future are treated as active (with a warning
 // written to stderr if warnWriter is non-nil).
@@ -349,6 +462,12 @@ func CleanExpiredSessions(cliReplayDir string, ttl time.Duration, warnWriter io.
 			continue
 		}
 		name := entry.Name()
+		if isDoneFile(name) {
+			if info, infoErr := entry.Info(); infoErr == nil && now.Sub(info.ModTime()) > ttl {
+				_ = os.Remove(filepath.Join(cliReplayDir, name))
+			}
+			continue
+		}
 		// Only process state files matching cli-replay-*.state
 		if !isStateFile(name) {
 			continue
@@ -388,7 +507,7 @@ func CleanExpiredSessions(cliReplayDir string, ttl time.Duration, warnWriter io.
 			}
 		}
 
-		// Remove state file
+		// Remove state file; the lock file stays, as in DeleteState
 		if removeErr := os.Remove(stateFile); removeErr != nil {
 			if os.IsNotExist(removeErr) {
 				// Already removed (race condition) — count it
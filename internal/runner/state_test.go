@@ -215,6 +215,20 @@ func TestDeleteState(t *testing.T) {
 	assert.True(t, os.IsNotExist(err))
 }
 
+func TestDeleteState_KeepsLockFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "delete.state")
+
+	unlock, err := LockState(stateFile)
+	require.NoError(t, err)
+	require.NoError(t, WriteState(stateFile, &State{TotalSteps: 1, LastUpdated: time.Now().UTC()}))
+	require.NoError(t, DeleteState(stateFile))
+	unlock()
+
+	// Another process may still be blocked on this lock file
+	assert.FileExists(t, stateFile+".lock")
+}
+
 func TestDeleteState_NotFound(t *testing.T) {
 	// Deleting non-existent file should not error
 	err := DeleteState("/nonexistent/path/state.state")
@@ -1040,3 +1054,43 @@ func TestCleanExpiredSessions_Benchmark50Files(t *testing.T) {
 	assert.Equal(t, 50, cleaned)
 	assert.Less(t, elapsed, 2*time.Second, "CleanExpiredSessions with 50 files should complete in < 2s")
 }
+
+func TestState_SeekTo(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"a"}}},
+		{Match: scenario.Match{Argv: []string{"b"}}, Calls: &scenario.CallBounds{Min: 2, Max: 3}},
+		{Match: scenario.Match{Argv: []string{"c"}}},
+	}
+	state := NewState("/test/scenario.yaml", "", 3)
+
+	require.NoError(t, state.SeekTo(steps, nil, 2))
+	assert.Equal(t, 2, state.CurrentStep)
+	assert.Equal(t, []int{1, 2, 0}, state.StepCounts, "skipped steps are marked at their min")
+}
+
+func TestState_SeekTo_Rejections(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"a"}}},
+		{Match: scenario.Match{Argv: []string{"b"}}},
+		{Match: scenario.Match{Argv: []string{"c"}}},
+	}
+	ranges := []scenario.GroupRange{{Start: 1, End: 3, Name: "checks", Mode: "unordered"}}
+
+	state := NewState("/test/scenario.yaml", "", 3)
+	err := state.SeekTo(steps, ranges, 3)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of range")
+
+	err = state.SeekTo(steps, ranges, 2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `inside group "checks"`)
+
+	assert.NoError(t, state.SeekTo(steps, ranges, 1), "a group's first step is a valid start")
+}
+
+func TestState_TotalCalls(t *testing.T) {
+	state := NewState("/s.yaml", "", 3)
+	assert.Equal(t, 0, state.TotalCalls())
+	state.StepCounts = []int{2, 0, 5}
+	assert.Equal(t, 7, state.TotalCalls())
+}
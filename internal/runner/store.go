@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ormasoftchile/cli-replay/pkg/replay"
+)
+
+// FileStateStore is the replay.StateStore sessions are kept in by default:
+// a JSON state file, locked across processes. Snapshots carry the rest of
+// the runner's State in their Data, so the file format is that of
+// WriteState. Embedders that never hand off to a child process can pass
+// replay.NewMemoryStore() to ExecuteReplayWithStore instead.
+type FileStateStore struct {
+	Path string
+}
+
+// NewFileStateStore returns a store for the state file at path.
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{Path: path}
+}
+
+// Lock takes the advisory lock on the state file.
+func (f *FileStateStore) Lock() (func(), error) { return LockState(f.Path) }
+
+// Read loads the state file as a snapshot, or returns nil if it does not
+// exist yet.
+func (f *FileStateStore) Read() (*replay.StateSnapshot, error) {
+	state, err := ReadState(f.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	snap, err := state.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// Write atomically replaces the state file with snap.
+func (f *FileStateStore) Write(snap replay.StateSnapshot) error {
+	state, err := stateFromSnapshot(snap)
+	if err != nil {
+		return err
+	}
+	return WriteState(f.Path, state)
+}
+
+// Delete removes the state file.
+func (f *FileStateStore) Delete() error { return DeleteState(f.Path) }
+
+// Location returns the state file path.
+func (f *FileStateStore) Location() string { return f.Path }
+
+// snapshot returns s as an engine snapshot whose Data holds s in full.
+func (s *State) snapshot() (replay.StateSnapshot, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return replay.StateSnapshot{}, fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return replay.StateSnapshot{
+		CurrentStep: s.CurrentStep,
+		TotalSteps:  s.TotalSteps,
+		StepCounts:  s.StepCounts,
+		ActiveGroup: s.ActiveGroup,
+		Captures:    s.Captures,
+		Prev:        s.Prev,
+		Data:        data,
+	}, nil
+}
+
+// stateFromSnapshot rebuilds the State held in snap.Data. The engine's
+// fields of snap take precedence over the copies in Data.
+func stateFromSnapshot(snap replay.StateSnapshot) (*State, error) {
+	var state State
+	if len(snap.Data) > 0 {
+		if err := json.Unmarshal(snap.Data, &state); err != nil {
+			return nil, fmt.Errorf("failed to parse state: %w", err)
+		}
+	}
+	state.CurrentStep = snap.CurrentStep
+	state.TotalSteps = snap.TotalSteps
+	state.StepCounts = snap.StepCounts
+	state.ActiveGroup = snap.ActiveGroup
+	state.Captures = snap.Captures
+	state.Prev = snap.Prev
+	return &state, nil
+}
+
+// readStoreState returns the State kept in store, or an error satisfying
+// os.IsNotExist if none has been written. The state file is read directly,
+// without a round trip through a snapshot.
+func readStoreState(store replay.StateStore) (*State, error) {
+	if f, ok := store.(*FileStateStore); ok {
+		return ReadState(f.Path)
+	}
+	snap, err := store.Read()
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, os.ErrNotExist
+	}
+	return stateFromSnapshot(*snap)
+}
+
+// writeStoreState replaces the State kept in store.
+func writeStoreState(store replay.StateStore, state *State) error {
+	if f, ok := store.(*FileStateStore); ok {
+		return WriteState(f.Path, state)
+	}
+	snap, err := state.snapshot()
+	if err != nil {
+		return err
+	}
+	return store.Write(snap)
+}
@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/pkg/replay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const storeScenario = `
+meta:
+  name: stores
+steps:
+  - match:
+      argv: ["git", "fetch"]
+    respond:
+      exit: 0
+      capture:
+        remote: origin
+  - match:
+      argv: ["git", "status"]
+    calls:
+      min: 1
+      max: 2
+    respond:
+      exit: 0
+      stdout: "on {{ .capture.remote }}\n"
+  - match:
+      argv: ["git", "push"]
+    respond:
+      exit: 0
+`
+
+func TestStateStores_IdenticalProgression(t *testing.T) {
+	commands := [][]string{
+		{"git", "fetch"},
+		{"git", "status"},
+		{"git", "status"},
+		{"git", "push"},
+	}
+	type step struct {
+		index  int
+		stdout string
+		counts []int
+	}
+	run := func(t *testing.T, path string, store replay.StateStore) []step {
+		t.Helper()
+		var out []step
+		for _, argv := range commands {
+			var stdout bytes.Buffer
+			res, err := ExecuteReplayWithStore(path, store, argv, &stdout, &bytes.Buffer{})
+			require.NoError(t, err)
+			st, err := readStoreState(store)
+			require.NoError(t, err)
+			out = append(out, step{index: res.StepIndex, stdout: stdout.String(), counts: st.StepCounts})
+		}
+		_, err := ExecuteReplayWithStore(path, store, []string{"git", "push"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.Error(t, err)
+		return out
+	}
+
+	fileDir := t.TempDir()
+	filePath := filepath.Join(fileDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(filePath, []byte(storeScenario), 0600))
+	fileSteps := run(t, filePath, NewFileStateStore(StateFilePath(filePath)))
+
+	memDir := t.TempDir()
+	memPath := filepath.Join(memDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(memPath, []byte(storeScenario), 0600))
+	memSteps := run(t, memPath, replay.NewMemoryStore())
+
+	assert.Equal(t, fileSteps, memSteps)
+	assert.Equal(t, "on origin\n", memSteps[1].stdout)
+	assert.Equal(t, []int{1, 2, 1}, memSteps[3].counts)
+
+	_, err := os.Stat(StateFilePath(filePath))
+	require.NoError(t, err, "file store writes the state file")
+
+	entries, err := os.ReadDir(memDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "memory store leaves no files on disk")
+	assert.Equal(t, "scenario.yaml", entries[0].Name())
+}
+
+func TestFileStateStore_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := NewFileStateStore(path)
+	snap, err := store.Read()
+	require.NoError(t, err)
+	assert.Nil(t, snap, "no snapshot before the state file exists")
+
+	st := NewState("/tmp/s.yaml", "hash", 2)
+	st.MismatchStreak = 3
+	require.NoError(t, WriteState(path, st))
+
+	snap, err = store.Read()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	snap.CurrentStep = 1
+	snap.StepCounts = []int{1, 0}
+	require.NoError(t, store.Write(*snap))
+
+	got, err := ReadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.CurrentStep)
+	assert.Equal(t, []int{1, 0}, got.StepCounts)
+	assert.Equal(t, "hash", got.ScenarioHash, "runner fields survive a snapshot round trip")
+	assert.Equal(t, 3, got.MismatchStreak)
+
+	require.NoError(t, store.Delete())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, path, store.Location())
+}
@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// suggestionMaxRatio bounds how different a step may be from the received
+// command and still be suggested: the edit distance must not exceed this
+// fraction of the longer of the two joined argv strings.
+const suggestionMaxRatio = 0.4
+
+// closestStep returns the step whose argv is most similar to received, by
+// edit distance over the space-joined argv. Elements that already match
+// (including template patterns) count as identical. Returns false when no
+// step is within suggestionMaxRatio of the received command.
+func closestStep(received []string, steps []ErrorCandidate) (ErrorCandidate, bool) {
+	best, bestDist := -1, 0
+	for i, s := range steps {
+		d, limit := argvDistance(s.Argv, received)
+		if float64(d) > float64(limit)*suggestionMaxRatio {
+			continue
+		}
+		if best < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	if best < 0 {
+		return ErrorCandidate{}, false
+	}
+	return steps[best], true
+}
+
+// rankCandidates reorders candidates (and the parallel argv slice) so the
+// step most similar to received comes first. The sort is stable, so equally
+// distant candidates keep scenario order.
+func rankCandidates(received []string, candidates []int, argv [][]string) ([]int, [][]string) {
+	if len(candidates) != len(argv) || len(candidates) < 2 {
+		return candidates, argv
+	}
+	type ranked struct {
+		idx  int
+		argv []string
+		dist int
+	}
+	items := make([]ranked, len(candidates))
+	for i := range candidates {
+		d, _ := argvDistance(argv[i], received)
+		items[i] = ranked{idx: candidates[i], argv: argv[i], dist: d}
+	}
+	sort.SliceStable(items, func(a, b int) bool { return items[a].dist < items[b].dist })
+
+	outIdx := make([]int, len(items))
+	outArgv := make([][]string, len(items))
+	for i, it := range items {
+		outIdx[i], outArgv[i] = it.idx, it.argv
+	}
+	return outIdx, outArgv
+}
+
+// suggestStep returns the remaining step closest to received, excluding the
+// expected step itself (its diff is already shown). Returns nil when no
+// other step is similar enough.
+func suggestStep(received []string, expectedIdx int, steps []scenario.Step, state *State) *ErrorCandidate {
+	var others []ErrorCandidate
+	for _, c := range remainingCandidates(steps, state) {
+		if c.StepIndex != expectedIdx {
+			others = append(others, c)
+		}
+	}
+	best, ok := closestStep(received, others)
+	if !ok {
+		return nil
+	}
+	return &best
+}
+
+// remainingCandidates lists the steps at or after the current position that
+// still have call budget left, one entry per match.any_of alternative.
+func remainingCandidates(steps []scenario.Step, state *State) []ErrorCandidate {
+	var out []ErrorCandidate
+	for i := state.CurrentStep; i < len(steps); i++ {
+		count := 0
+		if i < len(state.StepCounts) {
+			count = state.StepCounts[i]
+		}
+		if count >= steps[i].EffectiveCalls().Max {
+			continue
+		}
+		for _, argv := range steps[i].Match.Alternatives() {
+			out = append(out, ErrorCandidate{StepIndex: i, Argv: argv})
+		}
+	}
+	return out
+}
+
+// closestArgv returns the alternative most similar to received, the first
+// one on a tie.
+func closestArgv(received []string, alternatives [][]string) []string {
+	best, bestDist := 0, -1
+	for i, alt := range alternatives {
+		if d, _ := argvDistance(alt, received); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return alternatives[best]
+}
+
+// argvDistance returns the edit distance between expected and received, and
+// the length of the longer joined string. Expected elements that match the
+// received element at the same position are replaced by it first, so a
+// wildcard or regex step is not penalized for what it already accepts.
+func argvDistance(expected, received []string) (int, int) {
+	normalized := make([]string, len(expected))
+	for i, e := range expected {
+		if i < len(received) && matcher.ElementMatchDetail(e, received[i]).Matched {
+			normalized[i] = received[i]
+		} else {
+			normalized[i] = e
+		}
+	}
+	a := strings.Join(normalized, " ")
+	b := strings.Join(received, " ")
+	limit := len(a)
+	if len(b) > limit {
+		limit = len(b)
+	}
+	return levenshtein(a, b), limit
+}
+
+// levenshtein returns the edit distance between a and b, counted in runes.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
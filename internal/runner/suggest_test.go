@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("kubectl", "kubectl"))
+	assert.Equal(t, 3, levenshtein("kitten", "sitting"))
+	assert.Equal(t, 4, levenshtein("", "pods"))
+}
+
+func TestClosestStep_NearMiss(t *testing.T) {
+	steps := []ErrorCandidate{
+		{StepIndex: 0, Argv: []string{"git", "status"}},
+		{StepIndex: 1, Argv: []string{"kubectl", "get", "pods", "-n", "prod"}},
+		{StepIndex: 2, Argv: []string{"kubectl", "apply", "-f", "app.yaml"}},
+	}
+
+	best, ok := closestStep([]string{"kubectl", "get", "pods", "-n", "prd"}, steps)
+	require.True(t, ok)
+	assert.Equal(t, 1, best.StepIndex)
+}
+
+func TestClosestStep_TemplateElementsCountAsMatching(t *testing.T) {
+	steps := []ErrorCandidate{
+		{StepIndex: 0, Argv: []string{"az", "group", "create", "--name", "{{ .any }}"}},
+		{StepIndex: 1, Argv: []string{"az", "vm", "list"}},
+	}
+
+	best, ok := closestStep([]string{"az", "group", "creat", "--name", "my-resource-group-0001"}, steps)
+	require.True(t, ok)
+	assert.Equal(t, 0, best.StepIndex)
+}
+
+func TestClosestStep_WildlyDifferentSuggestsNothing(t *testing.T) {
+	steps := []ErrorCandidate{
+		{StepIndex: 0, Argv: []string{"kubectl", "get", "pods"}},
+		{StepIndex: 1, Argv: []string{"az", "vm", "list"}},
+	}
+
+	_, ok := closestStep([]string{"terraform", "apply", "-auto-approve"}, steps)
+	assert.False(t, ok)
+
+	_, ok = closestStep([]string{"kubectl"}, nil)
+	assert.False(t, ok)
+}
+
+func TestRankCandidates_ClosestFirst(t *testing.T) {
+	idx, argv := rankCandidates(
+		[]string{"cmd", "gamma2"},
+		[]int{3, 4, 5},
+		[][]string{{"cmd", "alpha"}, {"cmd", "beta"}, {"cmd", "gamma"}},
+	)
+	assert.Equal(t, []int{5, 3, 4}, idx)
+	assert.Equal(t, []string{"cmd", "gamma"}, argv[0])
+	assert.Equal(t, []string{"cmd", "alpha"}, argv[1])
+}
+
+func TestExecuteReplay_MismatchSuggestsClosestRemainingStep(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv("CLI_REPLAY_COLOR", "0")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: suggest
+steps:
+  - match:
+      argv: ["git", "status"]
+    respond:
+      exit: 0
+  - match:
+      argv: ["kubectl", "get", "pods", "-n", "prod"]
+    respond:
+      exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"kubectl", "get", "pod", "-n", "prod"}, &stdout, &stderr)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	require.NotNil(t, mErr.Suggestion)
+	assert.Equal(t, 1, mErr.Suggestion.StepIndex)
+	assert.Contains(t, FormatMismatchError(mErr), "Did you mean step 2: [kubectl get pods -n prod]?")
+}
+
+func TestExecuteReplay_MismatchNoSuggestionForUnrelatedCommand(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv("CLI_REPLAY_COLOR", "0")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: suggest-none
+steps:
+  - match:
+      argv: ["git", "status"]
+    respond:
+      exit: 0
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"terraform", "apply", "-auto-approve"}, &stdout, &stderr)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Nil(t, mErr.Suggestion)
+	assert.NotContains(t, FormatMismatchError(mErr), "Did you mean")
+}
+
+func TestExecuteReplay_GroupMismatchRanksCandidates(t *testing.T) {
+	t.Setenv("CLI_REPLAY_SESSION", "")
+	t.Setenv("CLI_REPLAY_COLOR", "0")
+	tmpDir := t.TempDir()
+	scenarioContent := `
+meta:
+  name: group-rank
+steps:
+  - group:
+      mode: unordered
+      name: setup
+      steps:
+        - match:
+            argv: ["az", "group", "create"]
+          respond:
+            exit: 0
+        - match:
+            argv: ["az", "vm", "create"]
+          respond:
+            exit: 0
+`
+	scenarioPath := filepath.Join(tmpDir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenarioContent), 0600))
+
+	var stdout, stderr bytes.Buffer
+	_, err := ExecuteReplay(scenarioPath, []string{"az", "vm", "craete"}, &stdout, &stderr)
+	var gErr *GroupMismatchError
+	require.ErrorAs(t, err, &gErr)
+	assert.Equal(t, []int{1, 0}, gErr.Candidates)
+	assert.Equal(t, []string{"az", "vm", "create"}, gErr.CandidateArgv[0])
+
+	out := FormatGroupMismatchError(gErr)
+	assert.Contains(t, out, "Candidates (closest first):")
+	assert.Less(t, strings.Index(out, "step 2:"), strings.Index(out, "step 1:"))
+}
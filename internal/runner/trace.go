@@ -1,19 +1,153 @@
 package runner
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"time"
 )
 
 // TraceEnvVar is the environment variable name for enabling trace mode.
 const TraceEnvVar = "CLI_REPLAY_TRACE"
 
+// TraceFileEnvVar names a file that trace lines are appended to instead of
+// stderr. Setting it enables trace mode on its own.
+const TraceFileEnvVar = "CLI_REPLAY_TRACE_FILE"
+
+// traceWriter returns the destination for human-readable trace lines, or
+// nil when they are off (including when CLI_REPLAY_TRACE=json selects the
+// JSONL trace instead).
+func traceWriter(stderr io.Writer) io.Writer {
+	v := os.Getenv(TraceEnvVar)
+	if IsJSONTrace(v) {
+		return nil
+	}
+	if os.Getenv(TraceFileEnvVar) != "" || IsTraceEnabled(v) {
+		return traceDestination(stderr)
+	}
+	return nil
+}
+
+// jsonTraceWriter returns the destination for JSONL trace records, or nil
+// unless CLI_REPLAY_TRACE=json.
+func jsonTraceWriter(stderr io.Writer) io.Writer {
+	if !IsJSONTrace(os.Getenv(TraceEnvVar)) {
+		return nil
+	}
+	return traceDestination(stderr)
+}
+
+// traceDestination returns the CLI_REPLAY_TRACE_FILE file when set, stderr
+// otherwise.
+func traceDestination(stderr io.Writer) io.Writer {
+	if path := os.Getenv(TraceFileEnvVar); path != "" {
+		return &traceFile{path: path, fallback: stderr}
+	}
+	return stderr
+}
+
+// traceFile appends each write to a file, so concurrent intercepts can share
+// one trace without holding it open. A write that cannot reach the file goes
+// to fallback instead.
+type traceFile struct {
+	path     string
+	fallback io.Writer
+}
+
+func (t *traceFile) Write(p []byte) (int, error) {
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600) //nolint:gosec // user-chosen trace file
+	if err != nil {
+		_, _ = fmt.Fprintf(t.fallback, "cli-replay: warning: cannot open trace file: %v\n", err)
+		return t.fallback.Write(p)
+	}
+	defer f.Close() //nolint:errcheck
+	return f.Write(p)
+}
+
+// Decision values of a TraceRecord besides the error types (ErrorType*)
+// reported for commands that were not served.
+const (
+	TraceDecisionServed   = "served"
+	TraceDecisionFallback = "fallback"
+)
+
+// TraceRecord is one line of the JSONL trace enabled by CLI_REPLAY_TRACE=json,
+// describing how a single intercepted command was decided.
+type TraceRecord struct {
+	Time         time.Time         `json:"time"`
+	Scenario     string            `json:"scenario"`
+	Argv         []string          `json:"argv"`
+	Decision     string            `json:"decision"`
+	FromStep     int               `json:"from_step"` // session position before matching
+	StepIndex    *int              `json:"step_index,omitempty"`
+	SoftAdvanced bool              `json:"soft_advanced,omitempty"`
+	Group        string            `json:"group,omitempty"`
+	Exit         int               `json:"exit"`
+	Captures     map[string]string `json:"captures,omitempty"` // captures set by the served step
+	Error        string            `json:"error,omitempty"`
+}
+
+// WriteJSONTrace writes rec as a single JSON line. It is written in one call
+// so records from concurrent intercepts do not interleave.
+func WriteJSONTrace(w io.Writer, rec TraceRecord) {
+	if rec.Argv == nil {
+		rec.Argv = []string{}
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// withError fills the decision fields of rec from a replay error.
+func (rec TraceRecord) withError(err error) TraceRecord {
+	rec.Decision = ErrorTypeGeneric
+	rec.Exit = 1
+	rec.Error = err.Error()
+	switch e := err.(type) {
+	case *MismatchError:
+		rec.Decision = ErrorTypeArgvMismatch
+		rec.StepIndex = &e.StepIndex
+		rec.SoftAdvanced = e.SoftAdvanced
+		rec.Group = e.GroupName
+	case *StdinMismatchError:
+		rec.Decision = ErrorTypeStdinMismatch
+		rec.StepIndex = &e.StepIndex
+	case *GroupMismatchError:
+		rec.Decision = ErrorTypeGroupMismatch
+		rec.Group = e.GroupName
+	case *DisallowedCommandError:
+		rec.Decision = ErrorTypeDisallowed
+		rec.StepIndex = &e.StepIndex
+	case *ForbiddenCommandError:
+		rec.Decision = ErrorTypeForbidden
+	case *DependencyError:
+		rec.StepIndex = &e.StepIndex
+		rec.Group = e.GroupName
+	}
+	return rec
+}
+
 // WriteTraceOutput writes trace information to the given writer.
 func WriteTraceOutput(w io.Writer, stepIndex int, argv []string, exitCode int) {
 	_, _ = fmt.Fprintf(w, "[cli-replay] step=%d argv=%v exit=%d\n", stepIndex, argv, exitCode)
 }
 
+// WriteFallbackTrace writes a trace line for a command served by the
+// scenario's meta.fallback response instead of a step.
+func WriteFallbackTrace(w io.Writer, argv []string, exitCode int) {
+	_, _ = fmt.Fprintf(w, "[cli-replay] fallback argv=%v exit=%d\n", argv, exitCode)
+}
+
+// WriteStaleStateTrace writes a trace line when state is reset because the
+// scenario file changed since it was created.
+func WriteStaleStateTrace(w io.Writer, scenarioName string) {
+	_, _ = fmt.Fprintf(w, "cli-replay[trace]: scenario %q changed since state was created, state reset\n", scenarioName)
+}
+
 // WriteDeniedEnvTrace writes a trace line for a denied environment variable
 // substitution. Called when CLI_REPLAY_TRACE is enabled and an env var
 // override is suppressed by a deny pattern.
@@ -21,6 +155,11 @@ func WriteDeniedEnvTrace(w io.Writer, varName string) {
 	_, _ = fmt.Fprintf(w, "cli-replay[trace]: denied env var %s\n", varName)
 }
 
+// IsJSONTrace reports whether a CLI_REPLAY_TRACE value selects the JSONL trace.
+func IsJSONTrace(envValue string) bool {
+	return strings.EqualFold(strings.TrimSpace(envValue), "json")
+}
+
 // IsTraceEnabled returns true if trace mode should be enabled.
 func IsTraceEnabled(envValue string) bool {
 	switch strings.ToLower(envValue) {
@@ -2,9 +2,13 @@ package runner
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTraceOutput_Format(t *testing.T) {
@@ -80,3 +84,60 @@ func TestWriteDeniedEnvTrace_MultipleVars(t *testing.T) {
 	assert.Contains(t, output, "denied env var AWS_KEY")
 	assert.Contains(t, output, "denied env var GITHUB_TOKEN")
 }
+
+func TestTraceWriter_Destination(t *testing.T) {
+	var stderr bytes.Buffer
+
+	t.Setenv(TraceEnvVar, "")
+	t.Setenv(TraceFileEnvVar, "")
+	assert.Nil(t, traceWriter(&stderr), "tracing off")
+
+	t.Setenv(TraceEnvVar, "1")
+	assert.Equal(t, &stderr, traceWriter(&stderr), "CLI_REPLAY_TRACE alone keeps stderr")
+
+	t.Setenv(TraceEnvVar, "")
+	t.Setenv(TraceFileEnvVar, filepath.Join(t.TempDir(), "trace.log"))
+	w := traceWriter(&stderr)
+	require.NotNil(t, w, "a trace file enables tracing")
+	WriteTraceOutput(w, 0, []string{"git", "status"}, 0)
+	WriteTraceOutput(w, 1, []string{"git", "push"}, 1)
+
+	data, err := os.ReadFile(os.Getenv(TraceFileEnvVar))
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"), "writes are appended")
+	assert.Empty(t, stderr.String())
+}
+
+func TestTraceWriter_UnwritableFileFallsBackToStderr(t *testing.T) {
+	var stderr bytes.Buffer
+	t.Setenv(TraceFileEnvVar, filepath.Join(t.TempDir(), "missing", "trace.log"))
+
+	WriteTraceOutput(traceWriter(&stderr), 0, []string{"git", "status"}, 0)
+
+	assert.Contains(t, stderr.String(), "cannot open trace file")
+	assert.Contains(t, stderr.String(), "[cli-replay] step=0 argv=[git status] exit=0")
+}
+
+func TestTraceWriter_JSONMode(t *testing.T) {
+	var stderr bytes.Buffer
+	t.Setenv(TraceFileEnvVar, "")
+
+	t.Setenv(TraceEnvVar, "json")
+	assert.Nil(t, traceWriter(&stderr), "json replaces the human trace")
+	assert.Equal(t, &stderr, jsonTraceWriter(&stderr))
+
+	t.Setenv(TraceEnvVar, "1")
+	assert.Nil(t, jsonTraceWriter(&stderr))
+}
+
+func TestWriteJSONTrace_SingleLine(t *testing.T) {
+	var buf bytes.Buffer
+	idx := 2
+	WriteJSONTrace(&buf, TraceRecord{Scenario: "s", Decision: TraceDecisionServed, StepIndex: &idx})
+
+	out := buf.String()
+	assert.Equal(t, 1, strings.Count(out, "\n"))
+	assert.Contains(t, out, `"argv":[]`)
+	assert.Contains(t, out, `"step_index":2`)
+	assert.NotContains(t, out, "soft_advanced")
+}
@@ -12,13 +12,15 @@ import (
 )
 
 // Render renders a Go text/template with the given variables.
-// Uses missingkey=error to fail on undefined variables.
+// Uses missingkey=error to fail on undefined variables. The rendering.Funcs
+// helpers are registered as for responses, so a non-numeric int or float
+// still converts to 0.
 func Render(tmpl string, vars map[string]string) (string, error) {
 	if tmpl == "" {
 		return "", nil
 	}
 
-	t, err := template.New("response").Option("missingkey=error").Parse(tmpl)
+	t, err := template.New("response").Option("missingkey=error").Funcs(rendering.Funcs()).Parse(tmpl)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
@@ -354,3 +354,36 @@ func TestRenderWithCaptures_SpecialCharsInCaptureValue(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "PATH=/usr/local/bin:$HOME", result)
 }
+
+func TestRenderWithCaptures_NumericFormatting(t *testing.T) {
+	captures := map[string]string{"count": "42", "ratio": " 0.5 "}
+
+	result, err := RenderWithCaptures(`id-{{ printf "%05d" (int .capture.count) }} {{ printf "%.2f" (float .capture.ratio) }}`, nil, captures)
+	require.NoError(t, err)
+	assert.Equal(t, "id-00042 0.50", result)
+}
+
+func TestRenderWithCaptures_CaseHelpers(t *testing.T) {
+	result, err := RenderWithCaptures(`{{ .region | upper }} {{ lower .capture.name }}`,
+		map[string]string{"region": "eastus"}, map[string]string{"name": "Web-0"})
+	require.NoError(t, err)
+	assert.Equal(t, "EASTUS web-0", result)
+}
+
+func TestRenderWithCaptures_NonNumericDefaultsToZero(t *testing.T) {
+	captures := map[string]string{"count": "many"}
+
+	result, err := RenderWithCaptures(`{{ int .capture.count }} {{ float .capture.count }} {{ int .capture.missing }}`, nil, captures)
+	require.NoError(t, err)
+	assert.Equal(t, "0 0 0", result)
+}
+
+func TestRender_NumericHelpers(t *testing.T) {
+	result, err := Render(`{{ printf "%03d" (int .count) }}`, map[string]string{"count": "7"})
+	require.NoError(t, err)
+	assert.Equal(t, "007", result)
+
+	result, err = Render(`{{ int .count }} {{ float .ratio }}`, map[string]string{"count": "many", "ratio": "1.5x"})
+	require.NoError(t, err)
+	assert.Equal(t, "0 0", result)
+}
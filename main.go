@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/ormasoftchile/cli-replay/cmd"
+	"github.com/ormasoftchile/cli-replay/internal/recorder"
 	"github.com/ormasoftchile/cli-replay/internal/runner"
 )
 
@@ -28,6 +29,10 @@ func main() {
 	// Management mode: run cobra command tree
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		// exec reports the child's exit code (or its own failure code)
+		if cmd.ExecExitCode > 0 {
+			os.Exit(cmd.ExecExitCode)
+		}
 		os.Exit(1)
 	}
 }
@@ -37,6 +42,15 @@ func main() {
 // It reads CLI_REPLAY_SCENARIO, loads the scenario, matches os.Args
 // against the next expected step, and returns the canned response.
 func runIntercept() int {
+	// Passthrough-record mode: run the real command and append it as a step
+	if recordTo := os.Getenv(recorder.RecordToEnvVar); recordTo != "" {
+		exitCode, err := recorder.Passthrough(recordTo, interceptArgv(), os.Stdin, os.Stdout, os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cli-replay: %v\n", err)
+		}
+		return exitCode
+	}
+
 	scenarioPath := os.Getenv("CLI_REPLAY_SCENARIO")
 	if scenarioPath == "" {
 		fmt.Fprintf(os.Stderr, "cli-replay: no scenario specified\n")
@@ -45,30 +59,60 @@ func runIntercept() int {
 		return 1
 	}
 
-	// Build argv: replace os.Args[0] (full path to symlink) with just the base command name
-	argv := make([]string, len(os.Args))
-	copy(argv, os.Args)
-	base := filepath.Base(argv[0])
-	base = strings.TrimSuffix(base, ".exe")
-	base = strings.TrimSuffix(base, ".cmd")
-	argv[0] = base
+	argv := interceptArgv()
 
-	result, err := runner.ExecuteReplay(scenarioPath, argv, os.Stdout, os.Stderr)
+	var result *runner.ReplayResult
+	var err error
+	if sequence := runner.ParseSequence(os.Getenv(runner.SequenceEnvVar)); len(sequence) > 1 {
+		result, err = runner.ExecuteSequenceReplay(sequence, argv, os.Stdout, os.Stderr)
+	} else {
+		result, err = runner.ExecuteReplay(scenarioPath, argv, os.Stdout, os.Stderr)
+	}
 	if err != nil {
-		// Format and display typed errors with rich diagnostics
+		// Let `exec --fail-fast` know the child was served a mismatch
+		if noteErr := runner.NoteMismatch(err); noteErr != nil {
+			fmt.Fprintf(os.Stderr, "cli-replay: %v\n", noteErr)
+		}
+		// Format and display typed errors with rich diagnostics, or as a
+		// single JSON line when CLI_REPLAY_ERROR_FORMAT=json
+		if runner.IsJSONErrorFormat() {
+			fmt.Fprintln(os.Stderr, runner.FormatErrorJSON(err))
+			return failureExitCode(result)
+		}
 		switch e := err.(type) {
 		case *runner.MismatchError:
 			fmt.Fprint(os.Stderr, runner.FormatMismatchError(e))
 		case *runner.StdinMismatchError:
 			fmt.Fprint(os.Stderr, runner.FormatStdinMismatchError(e))
+		case *runner.GroupMismatchError:
+			fmt.Fprint(os.Stderr, runner.FormatGroupMismatchError(e))
 		default:
 			fmt.Fprintf(os.Stderr, "cli-replay: %v\n", err)
 		}
-		if result != nil {
-			return result.ExitCode
-		}
-		return 1
+		return failureExitCode(result)
 	}
 
 	return result.ExitCode
 }
+
+// failureExitCode returns the exit code of a failed replay. The runner sets
+// it from meta.exit_codes for mismatches and completed scenarios; errors
+// raised before the scenario is loaded carry no result and exit 1.
+func failureExitCode(result *runner.ReplayResult) int {
+	if result != nil {
+		return result.ExitCode
+	}
+	return 1
+}
+
+// interceptArgv returns os.Args with argv[0] (full path to the symlink or
+// wrapper) replaced by just the base command name.
+func interceptArgv() []string {
+	argv := make([]string, len(os.Args))
+	copy(argv, os.Args)
+	base := filepath.Base(argv[0])
+	base = strings.TrimSuffix(base, ".exe")
+	base = strings.TrimSuffix(base, ".cmd")
+	argv[0] = base
+	return argv
+}
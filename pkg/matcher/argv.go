@@ -29,6 +29,46 @@ func ArgvMatch(expected, received []string) bool {
 	return true
 }
 
+// WildcardToken is the argv element that match.wildcards treats as matching
+// any single argument.
+const WildcardToken = "_"
+
+// ExpandWildcards returns argv with every bare WildcardToken element
+// replaced by the {{ .any }} template, so ArgvMatch accepts any value at
+// those positions. argv is not modified.
+func ExpandWildcards(argv []string) []string {
+	out := make([]string, len(argv))
+	for i, elem := range argv {
+		if elem == WildcardToken {
+			elem = "{{ .any }}"
+		}
+		out[i] = elem
+	}
+	return out
+}
+
+// longFlagAssignRe matches a --flag=value element, capturing the flag.
+var longFlagAssignRe = regexp.MustCompile(`^(--[A-Za-z0-9][A-Za-z0-9._-]*)=`)
+
+// NormalizeFlags returns argv with every --flag=value element split into
+// --flag and value, so both spellings of a long option compare equal.
+// Elements after a bare "--" belong to another command and are left alone,
+// as are short options and template elements. argv is not modified.
+func NormalizeFlags(argv []string) []string {
+	out := make([]string, 0, len(argv))
+	for i, elem := range argv {
+		if elem == "--" {
+			return append(out, argv[i:]...)
+		}
+		if m := longFlagAssignRe.FindStringSubmatch(elem); m != nil {
+			out = append(out, m[1], elem[len(m[0]):])
+			continue
+		}
+		out = append(out, elem)
+	}
+	return out
+}
+
 // elementMatch checks if a single expected pattern matches a received value.
 func elementMatch(pattern, value string) bool {
 	// Fast path: exact literal match (vast majority of cases)
@@ -313,3 +313,54 @@ func TestElementMatchDetail_RegexInvalid(t *testing.T) {
 	assert.Equal(t, "regex", d.Kind)
 	assert.Contains(t, d.FailReason, "invalid regex")
 }
+
+func TestNormalizeFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		argv []string
+		want []string
+	}{
+		{"empty", []string{}, []string{}},
+		{"no flags", []string{"kubectl", "get", "pods"}, []string{"kubectl", "get", "pods"}},
+		{"long flag with value", []string{"kubectl", "get", "pods", "--namespace=prod"}, []string{"kubectl", "get", "pods", "--namespace", "prod"}},
+		{"separate form unchanged", []string{"kubectl", "--namespace", "prod"}, []string{"kubectl", "--namespace", "prod"}},
+		{"empty value", []string{"cmd", "--label="}, []string{"cmd", "--label", ""}},
+		{"value containing equals", []string{"cmd", "--set=image.tag=v2"}, []string{"cmd", "--set", "image.tag=v2"}},
+		{"dashes and dots in name", []string{"cmd", "--dry-run=client", "--log.level=debug"}, []string{"cmd", "--dry-run", "client", "--log.level", "debug"}},
+		{"multiple flags", []string{"az", "--name=vm1", "--resource-group=rg"}, []string{"az", "--name", "vm1", "--resource-group", "rg"}},
+		{"short flag untouched", []string{"kubectl", "-nprod", "-o=json"}, []string{"kubectl", "-nprod", "-o=json"}},
+		{"bare double dash stops", []string{"kubectl", "exec", "--container=app", "--", "env", "--x=1"}, []string{"kubectl", "exec", "--container", "app", "--", "env", "--x=1"}},
+		{"not a flag name", []string{"cmd", "--=value", "---x=1"}, []string{"cmd", "--=value", "---x=1"}},
+		{"positional with equals", []string{"env", "FOO=bar"}, []string{"env", "FOO=bar"}},
+		{"template value", []string{"cmd", `--namespace={{ .any }}`}, []string{"cmd", "--namespace", "{{ .any }}"}},
+		{"template element untouched", []string{"cmd", `{{ .regex "--ns=.*" }}`}, []string{"cmd", `{{ .regex "--ns=.*" }}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeFlags(tt.argv))
+		})
+	}
+}
+
+func TestNormalizeFlags_DoesNotModifyInput(t *testing.T) {
+	argv := []string{"kubectl", "--namespace=prod"}
+	_ = NormalizeFlags(argv)
+	assert.Equal(t, []string{"kubectl", "--namespace=prod"}, argv)
+}
+
+func TestExpandWildcards(t *testing.T) {
+	argv := []string{"cp", "_", "/dest", "_x", "__"}
+	got := ExpandWildcards(argv)
+	assert.Equal(t, []string{"cp", "{{ .any }}", "/dest", "_x", "__"}, got)
+	assert.Equal(t, []string{"cp", "_", "/dest", "_x", "__"}, argv, "input must not be modified")
+
+	assert.True(t, ArgvMatch(got[:3], []string{"cp", "/tmp/abc123", "/dest"}))
+	assert.False(t, ArgvMatch(got[:3], []string{"cp", "/tmp/abc123", "/other"}))
+	assert.False(t, ArgvMatch(got[:3], []string{"cp", "/dest"}), "_ stands for exactly one argument")
+}
+
+func TestNormalizeFlags_BothFormsMatch(t *testing.T) {
+	expected := NormalizeFlags([]string{"kubectl", "get", "pods", "--namespace", "{{ .any }}"})
+	assert.True(t, ArgvMatch(expected, NormalizeFlags([]string{"kubectl", "get", "pods", "--namespace=prod"})))
+	assert.True(t, ArgvMatch(expected, NormalizeFlags([]string{"kubectl", "get", "pods", "--namespace", "prod"})))
+}
@@ -0,0 +1,42 @@
+package matcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// SHA256Matches reports whether the hex-encoded SHA256 digest of token
+// equals digest. The comparison ignores case.
+func SHA256Matches(token, digest string) bool {
+	return strings.EqualFold(SHA256Hex(token), digest)
+}
+
+// SHA256Hex returns the hex-encoded SHA256 digest of s.
+func SHA256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// StdinSHA256 returns the digest match.stdin_sha256 is compared with: the
+// SHA256 of stdin with \r\n line endings converted to \n and trailing
+// newlines trimmed, the same normalization match.stdin comparisons use.
+func StdinSHA256(stdin string) string {
+	return SHA256Hex(strings.TrimRight(strings.ReplaceAll(stdin, "\r\n", "\n"), "\n"))
+}
+
+// ApplyArgvHashes checks the received tokens at the positions in hashes
+// (argv index to SHA256 digest) and returns expected with those positions
+// set to the received tokens, so ArgvMatch compares every other position
+// as usual. ok is false when a hashed position is missing from received or
+// its digest differs. expected is not modified.
+func ApplyArgvHashes(expected, received []string, hashes map[int]string) ([]string, bool) {
+	out := append([]string(nil), expected...)
+	for i, digest := range hashes {
+		if i < 0 || i >= len(received) || i >= len(out) || !SHA256Matches(received[i], digest) {
+			return nil, false
+		}
+		out[i] = received[i]
+	}
+	return out, true
+}
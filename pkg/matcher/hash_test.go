@@ -0,0 +1,32 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sha256 of "hello"
+const helloDigest = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestSHA256Matches(t *testing.T) {
+	assert.True(t, SHA256Matches("hello", helloDigest))
+	assert.True(t, SHA256Matches("hello", "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"))
+	assert.False(t, SHA256Matches("hello!", helloDigest))
+}
+
+func TestApplyArgvHashes(t *testing.T) {
+	expected := []string{"cmd", "--data", "<blob>"}
+	hashes := map[int]string{2: helloDigest}
+
+	got, ok := ApplyArgvHashes(expected, []string{"cmd", "--data", "hello"}, hashes)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"cmd", "--data", "hello"}, got)
+	assert.Equal(t, "<blob>", expected[2], "expected must not be modified")
+
+	_, ok = ApplyArgvHashes(expected, []string{"cmd", "--data", "goodbye"}, hashes)
+	assert.False(t, ok)
+
+	_, ok = ApplyArgvHashes(expected, []string{"cmd", "--data"}, hashes)
+	assert.False(t, ok)
+}
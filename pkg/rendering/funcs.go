@@ -0,0 +1,35 @@
+package rendering
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Funcs returns the helper functions registered on every response template.
+// Captures and vars are strings, so int and float convert them for numeric
+// formatting, as in {{ printf "%05d" (int .capture.count) }}. A value that
+// is not a number converts to 0, in line with missing captures rendering
+// empty. upper and lower change the case of a value, as in
+// {{ .region | upper }}.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"int": func(v interface{}) int {
+			n, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(v)))
+			if err != nil {
+				return 0
+			}
+			return n
+		},
+		"float": func(v interface{}) float64 {
+			f, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(v)), 64)
+			if err != nil {
+				return 0
+			}
+			return f
+		},
+		"upper": func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+		"lower": func(v interface{}) string { return strings.ToLower(fmt.Sprint(v)) },
+	}
+}
@@ -13,21 +13,31 @@ import (
 // Vars are top-level keys, captures are nested under the "capture" namespace.
 // Uses missingkey=zero so that unresolved capture references (from optional
 // steps or unordered group siblings) resolve to empty string instead of
-// erroring.
+// erroring. The Funcs helpers are available.
 func RenderWithCaptures(tmpl string, vars map[string]string, captures map[string]string) (string, error) {
+	return RenderWithContext(tmpl, vars, captures, nil)
+}
+
+// RenderWithContext is RenderWithCaptures with additional implicit values
+// (such as "prev") placed at the top level of the template data. Implicit
+// values take precedence over vars of the same name.
+func RenderWithContext(tmpl string, vars map[string]string, captures map[string]string, implicit map[string]interface{}) (string, error) {
 	if tmpl == "" {
 		return "", nil
 	}
 
-	t, err := template.New("response").Option("missingkey=zero").Parse(tmpl)
+	t, err := template.New("response").Option("missingkey=zero").Funcs(Funcs()).Parse(tmpl)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	data := make(map[string]interface{}, len(vars)+1)
+	data := make(map[string]interface{}, len(vars)+len(implicit)+1)
 	for k, v := range vars {
 		data[k] = v
 	}
+	for k, v := range implicit {
+		data[k] = v
+	}
 
 	captureMap := make(map[string]string)
 	for k, v := range captures {
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ormasoftchile/cli-replay/pkg/matcher"
 	"github.com/ormasoftchile/cli-replay/pkg/rendering"
@@ -17,7 +20,8 @@ import (
 // (name + args), it returns the matched response or an error.
 //
 // Engine has zero file I/O and zero CLI coupling — all I/O is provided
-// through Option callbacks or via the scenario data pre-loaded at creation.
+// through Option callbacks (including WithStateStore) or via the scenario
+// data pre-loaded at creation. Open is the file-backed convenience.
 type Engine struct {
 	mu         sync.Mutex
 	scn        *scenario.Scenario
@@ -37,27 +41,16 @@ func New(scn *scenario.Scenario, opts ...Option) *Engine {
 	if cfg.matchFunc == nil {
 		cfg.matchFunc = matcher.ArgvMatch
 	}
+	if !cfg.seeded {
+		cfg.seed = time.Now().UnixNano()
+	}
 
 	flat := scn.FlatSteps()
 	st := newState(len(flat))
 
 	// Restore state from snapshot if provided
 	if cfg.initialState != nil {
-		snap := cfg.initialState
-		st.currentStep = snap.CurrentStep
-		st.totalSteps = snap.TotalSteps
-		if len(snap.StepCounts) == len(st.stepCounts) {
-			copy(st.stepCounts, snap.StepCounts)
-		}
-		if snap.ActiveGroup != nil {
-			v := *snap.ActiveGroup
-			st.activeGroup = &v
-		}
-		if snap.Captures != nil {
-			for k, v := range snap.Captures {
-				st.captures[k] = v
-			}
-		}
+		st = restoreState(len(flat), cfg.initialState)
 	}
 
 	return &Engine{
@@ -90,6 +83,21 @@ func (e *Engine) matchCore(_ context.Context, name string, args []string, stdin
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	if e.cfg.store != nil {
+		unlock, err := e.cfg.store.Lock()
+		if err != nil {
+			return &Result{ExitCode: 1}, fmt.Errorf("failed to lock state: %w", err)
+		}
+		defer unlock()
+		snap, err := e.cfg.store.Read()
+		if err != nil {
+			return &Result{ExitCode: 1}, fmt.Errorf("failed to read state: %w", err)
+		}
+		if snap != nil {
+			e.st = restoreState(len(e.flatSteps), snap)
+		}
+	}
+
 	argv := append([]string{name}, args...)
 
 	if e.st.isComplete() {
@@ -100,7 +108,7 @@ func (e *Engine) matchCore(_ context.Context, name string, args []string, stdin
 	stepIndex := e.st.currentStep
 	for stepIndex < len(e.flatSteps) {
 		grIdx := findGroupContaining(e.groupRanges, stepIndex)
-		if grIdx >= 0 {
+		if grIdx >= 0 && !e.groupRanges[grIdx].Ordered() {
 			gr := e.groupRanges[grIdx]
 			if e.st.groupAllMaxesHit(gr, e.flatSteps) {
 				stepIndex = gr.End
@@ -124,15 +132,25 @@ func (e *Engine) matchCore(_ context.Context, name string, args []string, stdin
 		return &Result{ExitCode: 1}, &ScenarioCompleteError{TotalSteps: e.st.totalSteps}
 	}
 
-	// Determine group membership
+	// Determine group membership. Ordered groups use the ordered path and
+	// only contribute their name to error reporting.
 	grIdx := findGroupContaining(e.groupRanges, stepIndex)
+	if grIdx >= 0 && e.groupRanges[grIdx].Ordered() {
+		grIdx = -1
+	}
 
 	var matchedStep *scenario.Step
 	var matchedIndex int
+	var softAdvanced bool
 
 	if grIdx >= 0 {
 		// ─── Group path: unordered matching ───
-		matchedStep, matchedIndex = e.matchInGroup(grIdx, stepIndex, argv)
+		var blockedIndex int
+		matchedStep, matchedIndex, blockedIndex = e.matchInGroup(grIdx, stepIndex, argv)
+
+		if matchedStep == nil && blockedIndex >= 0 {
+			return e.dependencyResult(grIdx, blockedIndex, argv)
+		}
 
 		if matchedStep == nil {
 			gr := e.groupRanges[grIdx]
@@ -143,9 +161,10 @@ func (e *Engine) matchCore(_ context.Context, name string, args []string, stdin
 
 				if gr.End < len(e.flatSteps) {
 					retryStep := &e.flatSteps[gr.End]
-					if e.cfg.matchFunc(retryStep.Match.Argv, argv) {
+					if e.stepMatches(retryStep, argv) {
 						matchedIndex = gr.End
 						matchedStep = retryStep
+						softAdvanced = true
 					}
 				}
 
@@ -153,9 +172,10 @@ func (e *Engine) matchCore(_ context.Context, name string, args []string, stdin
 					if gr.End < len(e.flatSteps) {
 						return &Result{ExitCode: 1, StepIndex: gr.End},
 							&MismatchError{
-								StepIndex: gr.End,
-								Expected:  e.flatSteps[gr.End].Match.Argv,
-								Received:  argv,
+								StepIndex:     gr.End,
+								Expected:      e.flatSteps[gr.End].Match.PrimaryArgv(),
+								ExpectedAnyOf: e.flatSteps[gr.End].Match.AnyOf,
+								Received:      argv,
 							}
 					}
 					return &Result{ExitCode: 1}, &ScenarioCompleteError{TotalSteps: e.st.totalSteps}
@@ -167,8 +187,14 @@ func (e *Engine) matchCore(_ context.Context, name string, args []string, stdin
 	} else {
 		// ─── Ordered path ───
 		var mErr error
-		matchedStep, matchedIndex, mErr = e.matchOrdered(stepIndex, argv)
+		matchedStep, matchedIndex, softAdvanced, mErr = e.matchOrdered(stepIndex, argv)
 		if mErr != nil {
+			if me, ok := mErr.(*MismatchError); ok {
+				if og := findGroupContaining(e.groupRanges, me.StepIndex); og >= 0 && e.groupRanges[og].Ordered() {
+					me.GroupName = e.groupRanges[og].Name
+				}
+				me.ExpectedAnyOf = e.flatSteps[me.StepIndex].Match.AnyOf
+			}
 			return &Result{ExitCode: 1, StepIndex: stepIndex}, mErr
 		}
 	}
@@ -176,6 +202,17 @@ func (e *Engine) matchCore(_ context.Context, name string, args []string, stdin
 	// By this point we have a valid match.
 
 	// Stdin validation (only when stdin is provided)
+	if stdin != nil && matchedStep.Match.StdinSHA256 != "" {
+		if digest := matcher.StdinSHA256(*stdin); !strings.EqualFold(digest, matchedStep.Match.StdinSHA256) {
+			return &Result{ExitCode: 1},
+				&StdinMismatchError{
+					StepIndex:      matchedIndex,
+					Received:       *stdin,
+					ExpectedSHA256: matchedStep.Match.StdinSHA256,
+					ReceivedSHA256: digest,
+				}
+		}
+	}
 	if stdin != nil && matchedStep.Match.Stdin != "" {
 		if normalizeStdin(*stdin) != normalizeStdin(matchedStep.Match.Stdin) {
 			return &Result{ExitCode: 1},
@@ -202,28 +239,87 @@ func (e *Engine) matchCore(_ context.Context, name string, args []string, stdin
 		if e.st.stepBudgetRemaining(matchedIndex, bounds.Max) <= 0 {
 			e.st.currentStep = matchedIndex + 1
 		}
+		og := findGroupContaining(e.groupRanges, matchedIndex)
+		switch {
+		case og >= 0 && e.groupRanges[og].Ordered():
+			if e.st.currentStep >= e.groupRanges[og].End {
+				e.st.exitGroup()
+			} else {
+				e.st.enterGroup(og)
+			}
+		case og < 0:
+			e.st.exitGroup()
+		}
 	}
 
 	// Render response
-	stdout, stderr, exitCode, err := e.renderResponse(matchedStep)
+	resp, err := e.renderResponse(matchedStep, matchedIndex)
 	if err != nil {
 		return &Result{ExitCode: 1, StepIndex: matchedIndex, Matched: true}, err
 	}
 
-	// Merge captures
+	// Merge captures. Values are rendered with the same data as the
+	// response, so a step sees the captures of earlier steps, not its own.
 	if len(matchedStep.Respond.Capture) > 0 {
-		for k, v := range matchedStep.Respond.Capture {
+		captures, err := e.renderCaptures(matchedStep, matchedIndex)
+		if err != nil {
+			return &Result{ExitCode: 1, StepIndex: matchedIndex, Matched: true}, err
+		}
+		for k, v := range captures {
 			e.st.captures[k] = v
 		}
 	}
+	e.st.prev = &Served{Argv: argv, Stdout: resp.stdout, Stderr: resp.stderr, Exit: resp.exitCode}
+
+	res := &Result{
+		Stdout:       resp.stdout,
+		Stderr:       resp.stderr,
+		StdoutFile:   resp.stdoutFile,
+		StderrFile:   resp.stderrFile,
+		ExitCode:     resp.exitCode,
+		StepIndex:    matchedIndex,
+		Matched:      true,
+		SoftAdvanced: softAdvanced,
+		Delay:        e.serveDelay(matchedStep, matchedIndex),
+		Captures:     e.st.snapshotCaptures(),
+		Output:       resp.output,
+	}
+	if g := findGroupContaining(e.groupRanges, matchedIndex); g >= 0 {
+		res.Group = e.groupRanges[g].Name
+	}
+	if e.cfg.store != nil {
+		if err := e.cfg.store.Write(e.snapshotLocked()); err != nil {
+			return res, fmt.Errorf("failed to write state: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// Fallback renders the scenario's meta.fallback response using the current
+// vars and captures. It does not consume a step or otherwise change state.
+// Returns an error if the scenario has no fallback.
+func (e *Engine) Fallback(_ context.Context) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
+	if e.scn.Meta.Fallback == nil {
+		return &Result{ExitCode: 1, StepIndex: -1}, fmt.Errorf("scenario %q has no fallback response", e.scn.Meta.Name)
+	}
+	step := &scenario.Step{Respond: *e.scn.Meta.Fallback}
+	resp, err := e.renderResponse(step, -1)
+	if err != nil {
+		return &Result{ExitCode: 1, StepIndex: -1, Fallback: true}, fmt.Errorf("fallback: %w", err)
+	}
 	return &Result{
-		Stdout:    stdout,
-		Stderr:    stderr,
-		ExitCode:  exitCode,
-		StepIndex: matchedIndex,
-		Matched:   true,
-		Captures:  e.st.snapshotCaptures(),
+		Stdout:     resp.stdout,
+		Stderr:     resp.stderr,
+		StdoutFile: resp.stdoutFile,
+		StderrFile: resp.stderrFile,
+		ExitCode:   resp.exitCode,
+		StepIndex:  -1,
+		Fallback:   true,
+		Captures:   e.st.snapshotCaptures(),
+		Output:     resp.output,
 	}, nil
 }
 
@@ -255,20 +351,33 @@ func (e *Engine) Captures() map[string]string {
 }
 
 // Reset resets the engine to its initial state, allowing the scenario
-// to be replayed from the beginning.
-func (e *Engine) Reset() {
+// to be replayed from the beginning. With a state store, the stored
+// snapshot is deleted too.
+func (e *Engine) Reset() error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.st = newState(len(e.flatSteps))
+	if e.cfg.store != nil {
+		unlock, err := e.cfg.store.Lock()
+		if err != nil {
+			return fmt.Errorf("failed to lock state: %w", err)
+		}
+		defer unlock()
+		if err := e.cfg.store.Delete(); err != nil {
+			return fmt.Errorf("failed to delete state: %w", err)
+		}
+	}
+	return nil
 }
 
 // ─── internal helpers ───
 
 // matchOrdered implements the ordered-path matching logic.
-// Returns (matchedStep, matchedIndex, nil) on success, or (nil, idx, error) on mismatch.
-func (e *Engine) matchOrdered(stepIndex int, argv []string) (*scenario.Step, int, error) {
+// Returns (matchedStep, matchedIndex, softAdvanced, nil) on success, or
+// (nil, idx, false, error) on mismatch.
+func (e *Engine) matchOrdered(stepIndex int, argv []string) (*scenario.Step, int, bool, error) {
 	expectedStep := &e.flatSteps[stepIndex]
-	matched := e.cfg.matchFunc(expectedStep.Match.Argv, argv)
+	matched := e.stepMatches(expectedStep, argv)
 
 	softAdvanced := false
 	origStepIndex := stepIndex
@@ -280,7 +389,7 @@ func (e *Engine) matchOrdered(stepIndex int, argv []string) (*scenario.Step, int
 
 			nextIdx := stepIndex + 1
 			nextGrIdx := findGroupContaining(e.groupRanges, nextIdx)
-			if nextGrIdx >= 0 {
+			if nextGrIdx >= 0 && !e.groupRanges[nextGrIdx].Ordered() {
 				// Soft-advance into a group
 				gr := e.groupRanges[nextGrIdx]
 				e.st.currentStep = nextIdx
@@ -291,17 +400,17 @@ func (e *Engine) matchOrdered(stepIndex int, argv []string) (*scenario.Step, int
 					if e.st.stepBudgetRemaining(i, grBounds.Max) <= 0 {
 						continue
 					}
-					if e.cfg.matchFunc(e.flatSteps[i].Match.Argv, argv) {
-						return &e.flatSteps[i], i, nil
+					if e.stepMatches(&e.flatSteps[i], argv) {
+						return &e.flatSteps[i], i, true, nil
 					}
 				}
-				return nil, origStepIndex, &MismatchError{
+				return nil, origStepIndex, false, &MismatchError{
 					StepIndex:     origStepIndex,
-					Expected:      e.flatSteps[origStepIndex].Match.Argv,
+					Expected:      e.flatSteps[origStepIndex].Match.PrimaryArgv(),
 					Received:      argv,
 					SoftAdvanced:  true,
 					NextStepIndex: nextIdx,
-					NextExpected:  e.flatSteps[nextIdx].Match.Argv,
+					NextExpected:  e.flatSteps[nextIdx].Match.PrimaryArgv(),
 				}
 			}
 
@@ -309,44 +418,198 @@ func (e *Engine) matchOrdered(stepIndex int, argv []string) (*scenario.Step, int
 			stepIndex++
 			e.st.currentStep = stepIndex
 			expectedStep = &e.flatSteps[stepIndex]
-			matched = e.cfg.matchFunc(expectedStep.Match.Argv, argv)
+			matched = e.stepMatches(expectedStep, argv)
 		}
 	}
 
 	if matched {
-		return expectedStep, stepIndex, nil
+		return expectedStep, stepIndex, softAdvanced, nil
 	}
 
 	mErr := &MismatchError{
 		StepIndex: stepIndex,
-		Expected:  expectedStep.Match.Argv,
+		Expected:  expectedStep.Match.PrimaryArgv(),
 		Received:  argv,
 	}
+	if !softAdvanced && e.argvMatches(expectedStep, argv) {
+		mErr.EnvMismatches = e.envMismatches(expectedStep)
+		if !e.cwdMatches(expectedStep) {
+			mErr.CwdMismatch = &CwdMismatch{Expected: expectedStep.Match.Cwd, Actual: e.cfg.workingDir}
+		}
+	}
 	if softAdvanced {
 		mErr.SoftAdvanced = true
 		mErr.NextStepIndex = stepIndex
-		mErr.NextExpected = expectedStep.Match.Argv
+		mErr.NextExpected = expectedStep.Match.PrimaryArgv()
 		mErr.StepIndex = origStepIndex
-		mErr.Expected = e.flatSteps[origStepIndex].Match.Argv
+		mErr.Expected = e.flatSteps[origStepIndex].Match.PrimaryArgv()
+	}
+	return nil, stepIndex, false, mErr
+}
+
+// stepMatches reports whether argv, the process environment and the working
+// directory satisfy the step's match criteria.
+func (e *Engine) stepMatches(step *scenario.Step, argv []string) bool {
+	if !e.argvMatches(step, argv) {
+		return false
+	}
+	return len(e.envMismatches(step)) == 0 && e.cwdMatches(step)
+}
+
+// cwdMatches reports whether the configured working directory satisfies the
+// step's match.cwd. A relative pattern is taken from the scenario directory.
+// When the literal paths differ, both sides are compared again with symlinks
+// resolved, so /tmp and /private/tmp style aliases still match.
+func (e *Engine) cwdMatches(step *scenario.Step) bool {
+	if step.Match.Cwd == "" {
+		return true
+	}
+	cwd := e.cfg.workingDir
+	if cwd == "" {
+		return false
+	}
+	pattern := filepath.FromSlash(step.Match.Cwd)
+	base := e.cfg.scenarioDir
+	if filepath.IsAbs(pattern) {
+		base = ""
+	}
+	if ok, _ := filepath.Match(filepath.Join(base, pattern), filepath.Clean(cwd)); ok {
+		return true
+	}
+	if base != "" {
+		base = evalSymlinks(base)
+	} else if !hasGlobMeta(pattern) {
+		pattern = evalSymlinks(pattern)
+	}
+	ok, _ := filepath.Match(filepath.Join(base, pattern), evalSymlinks(cwd))
+	return ok
+}
+
+// evalSymlinks returns path with symlinks resolved, or path cleaned when it
+// cannot be resolved.
+func evalSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// hasGlobMeta reports whether pattern contains filepath.Match syntax.
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// argvMatches compares argv with the step's match.argv, or each of its
+// match.any_of entries, normalizing flag forms on both sides first when the
+// step sets match.normalize_flags and expanding _ wildcards when it sets
+// match.wildcards. Positions listed in match.argv_hash are compared by
+// SHA256 digest. With match.arity any, only the command name is compared.
+func (e *Engine) argvMatches(step *scenario.Step, argv []string) bool {
+	if step.Match.AnyArity() && len(argv) > 0 {
+		argv = argv[:1]
+	}
+	if step.Match.NormalizeFlags {
+		argv = matcher.NormalizeFlags(argv)
+	}
+	for _, expected := range step.Match.Alternatives() {
+		if step.Match.NormalizeFlags {
+			expected = matcher.NormalizeFlags(expected)
+		}
+		if step.Match.Wildcards {
+			expected = matcher.ExpandWildcards(expected)
+		}
+		if hashes := step.Match.ArgvHashes(); hashes != nil {
+			var ok bool
+			if expected, ok = matcher.ApplyArgvHashes(expected, argv, hashes); !ok {
+				continue
+			}
+		}
+		if e.cfg.matchFunc(expected, argv) {
+			return true
+		}
+	}
+	return false
+}
+
+// envMismatches returns the step's match.env expectations that the current
+// environment (via the configured env lookup) does not satisfy, sorted by name.
+func (e *Engine) envMismatches(step *scenario.Step) []EnvMismatch {
+	if len(step.Match.Env) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(step.Match.Env))
+	for name := range step.Match.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []EnvMismatch
+	for _, name := range names {
+		actual := ""
+		if e.cfg.envLookup != nil {
+			actual = e.cfg.envLookup(name)
+		}
+		if expected := step.Match.Env[name]; actual != expected {
+			out = append(out, EnvMismatch{Name: name, Expected: expected, Actual: actual})
+		}
 	}
-	return nil, stepIndex, mErr
+	return out
 }
 
-// matchInGroup implements unordered matching within a group.
-func (e *Engine) matchInGroup(grIdx int, _ int, argv []string) (*scenario.Step, int) {
+// matchInGroup implements unordered matching within a group. A child whose
+// depends_on siblings have not yet met their min counts cannot be matched;
+// if such a child is the only match, its flat index is returned as blocked.
+func (e *Engine) matchInGroup(grIdx int, _ int, argv []string) (matched *scenario.Step, matchedIndex, blockedIndex int) {
 	gr := e.groupRanges[grIdx]
 	e.st.enterGroup(grIdx)
 
+	blockedIndex = -1
 	for i := gr.Start; i < gr.End; i++ {
 		bounds := e.flatSteps[i].EffectiveCalls()
 		if e.st.stepBudgetRemaining(i, bounds.Max) <= 0 {
 			continue
 		}
-		if e.cfg.matchFunc(e.flatSteps[i].Match.Argv, argv) {
-			return &e.flatSteps[i], i
+		if e.stepMatches(&e.flatSteps[i], argv) {
+			if len(e.unmetDependencies(gr, i)) > 0 {
+				if blockedIndex < 0 {
+					blockedIndex = i
+				}
+				continue
+			}
+			return &e.flatSteps[i], i, -1
+		}
+	}
+	return nil, -1, blockedIndex
+}
+
+// unmetDependencies returns the depends_on names of the group child at flat
+// index idx whose steps have not yet reached their min call counts.
+func (e *Engine) unmetDependencies(gr scenario.GroupRange, idx int) []string {
+	var unmet []string
+	for _, dep := range e.flatSteps[idx].DependsOn {
+		for j := gr.Start; j < gr.End; j++ {
+			if e.flatSteps[j].Name != dep {
+				continue
+			}
+			if e.st.stepCounts[j] < e.flatSteps[j].EffectiveCalls().Min {
+				unmet = append(unmet, dep)
+			}
+			break
 		}
 	}
-	return nil, -1
+	return unmet
+}
+
+func (e *Engine) dependencyResult(grIdx, idx int, argv []string) (*Result, error) {
+	gr := e.groupRanges[grIdx]
+	return &Result{ExitCode: 1, StepIndex: idx},
+		&DependencyError{
+			GroupName: gr.Name,
+			StepIndex: idx,
+			StepName:  e.flatSteps[idx].Name,
+			Unmet:     e.unmetDependencies(gr, idx),
+			Received:  argv,
+		}
 }
 
 func (e *Engine) groupMismatchResult(grIdx int, argv []string) (*Result, error) {
@@ -357,7 +620,7 @@ func (e *Engine) groupMismatchResult(grIdx int, argv []string) (*Result, error)
 		bounds := e.flatSteps[i].EffectiveCalls()
 		if e.st.stepBudgetRemaining(i, bounds.Max) > 0 {
 			candidates = append(candidates, i)
-			candidateArgv = append(candidateArgv, e.flatSteps[i].Match.Argv)
+			candidateArgv = append(candidateArgv, e.flatSteps[i].Match.PrimaryArgv())
 		}
 	}
 	return &Result{ExitCode: 1, StepIndex: gr.Start},
@@ -370,19 +633,54 @@ func (e *Engine) groupMismatchResult(grIdx int, argv []string) (*Result, error)
 		}
 }
 
+// renderedResponse is a response ready to serve.
+type renderedResponse struct {
+	stdout, stderr string
+	// stdoutFile and stderrFile name static fixtures left for the caller to
+	// stream (see WithFileStreaming); their content is not in stdout/stderr.
+	stdoutFile, stderrFile string
+	output                 []Chunk
+	exitCode               int
+}
+
 // renderResponse renders the step's stdout/stderr with template variables and captures.
-func (e *Engine) renderResponse(step *scenario.Step) (stdout, stderr string, exitCode int, err error) {
+// stepIndex is the matched flat step, or -1 for the fallback response.
+// For a respond.output list it also returns the rendered chunks in order,
+// with stdout and stderr holding their concatenation per stream.
+func (e *Engine) renderResponse(step *scenario.Step, stepIndex int) (renderedResponse, error) {
+	if len(step.Respond.Random) > 0 {
+		chosen := pickWeighted(step.Respond.Random, e.cfg.seed, stepIndex, e.callCount(stepIndex))
+		chosen.RetryAfter = step.Respond.RetryAfter
+		return e.renderResponse(&scenario.Step{Match: step.Match, Respond: chosen}, stepIndex)
+	}
 	vars := e.mergeVars()
+	implicit := e.implicitData(stepIndex)
+	retryAfter, _ := step.Respond.RetryAfterDuration()
+	if retryAfter > 0 {
+		implicit["retry_after"] = retryAfter.String()
+	}
 
-	// Resolve stdout content
+	var out renderedResponse
+	var err error
+
+	// Resolve stdout content. A fixture is left for the caller to stream
+	// only when the step neither transforms nor checks that stream.
+	unframed := step.Respond.Assert == nil && len(step.Respond.Output) == 0
 	stdoutContent := step.Respond.Stdout
 	if step.Respond.StdoutFile != "" {
+		out.stdoutFile, err = e.streamedFile(step.Respond.StdoutFile,
+			unframed && step.Respond.Prepend == "" && step.Respond.Append == "")
+		if err != nil {
+			return renderedResponse{}, fmt.Errorf("failed to read stdout_file: %w", err)
+		}
+	}
+	if step.Respond.StdoutFile != "" && out.stdoutFile == "" {
 		if e.cfg.fileReader == nil {
-			return "", "", 1, fmt.Errorf("stdout_file %q specified but no file reader configured", step.Respond.StdoutFile)
+			return renderedResponse{}, fmt.Errorf("stdout_file %q specified but no file reader configured", step.Respond.StdoutFile)
 		}
 		content, readErr := e.cfg.fileReader(step.Respond.StdoutFile)
 		if readErr != nil {
-			return "", "", 1, fmt.Errorf("failed to read stdout_file: %w", readErr)
+			return renderedResponse{}, fmt.Errorf("failed to read stdout_file: %w", readErr)
 		}
 		stdoutContent = content
 	}
@@ -390,31 +688,192 @@ func (e *Engine) renderResponse(step *scenario.Step) (stdout, stderr string, exi
 	// Resolve stderr content
 	stderrContent := step.Respond.Stderr
 	if step.Respond.StderrFile != "" {
+		out.stderrFile, err = e.streamedFile(step.Respond.StderrFile, unframed && retryAfter == 0)
+		if err != nil {
+			return renderedResponse{}, fmt.Errorf("failed to read stderr_file: %w", err)
+		}
+	}
+	if step.Respond.StderrFile != "" && out.stderrFile == "" {
 		if e.cfg.fileReader == nil {
-			return "", "", 1, fmt.Errorf("stderr_file %q specified but no file reader configured", step.Respond.StderrFile)
+			return renderedResponse{}, fmt.Errorf("stderr_file %q specified but no file reader configured", step.Respond.StderrFile)
 		}
 		content, readErr := e.cfg.fileReader(step.Respond.StderrFile)
 		if readErr != nil {
-			return "", "", 1, fmt.Errorf("failed to read stderr_file: %w", readErr)
+			return renderedResponse{}, fmt.Errorf("failed to read stderr_file: %w", readErr)
 		}
 		stderrContent = content
 	}
 
 	// Render templates
 	if stdoutContent != "" {
-		stdoutContent, err = rendering.RenderWithCaptures(stdoutContent, vars, e.st.captures)
+		stdoutContent, err = rendering.RenderWithContext(stdoutContent, vars, e.st.captures, implicit)
 		if err != nil {
-			return "", "", 1, fmt.Errorf("failed to render stdout template: %w", err)
+			return renderedResponse{}, fmt.Errorf("failed to render stdout template: %w", err)
 		}
 	}
+	if step.Respond.Prepend != "" || step.Respond.Append != "" {
+		prepend, renderErr := rendering.RenderWithContext(step.Respond.Prepend, vars, e.st.captures, implicit)
+		if renderErr != nil {
+			return renderedResponse{}, fmt.Errorf("failed to render prepend template: %w", renderErr)
+		}
+		appendix, renderErr := rendering.RenderWithContext(step.Respond.Append, vars, e.st.captures, implicit)
+		if renderErr != nil {
+			return renderedResponse{}, fmt.Errorf("failed to render append template: %w", renderErr)
+		}
+		stdoutContent = frameStdout(prepend, stdoutContent, appendix)
+	}
 	if stderrContent != "" {
-		stderrContent, err = rendering.RenderWithCaptures(stderrContent, vars, e.st.captures)
+		stderrContent, err = rendering.RenderWithContext(stderrContent, vars, e.st.captures, implicit)
 		if err != nil {
-			return "", "", 1, fmt.Errorf("failed to render stderr template: %w", err)
+			return renderedResponse{}, fmt.Errorf("failed to render stderr template: %w", err)
 		}
 	}
+	if len(step.Respond.Output) > 0 {
+		out.output, stdoutContent, stderrContent, err = e.renderOutput(step.Respond.Output, vars, implicit)
+		if err != nil {
+			return renderedResponse{}, err
+		}
+	}
+	if retryAfter > 0 {
+		hinted := appendRetryHint(stderrContent, retryAfter)
+		if out.output != nil {
+			out.output = append(out.output, Chunk{Stream: scenario.StreamStderr, Text: hinted[len(stderrContent):]})
+		}
+		stderrContent = hinted
+	}
 
-	return stdoutContent, stderrContent, step.Respond.Exit, nil
+	out.exitCode = step.Respond.EffectiveExit()
+	if step.Respond.ExitTemplate != "" {
+		rendered, renderErr := rendering.RenderWithContext(step.Respond.ExitTemplate, vars, e.st.captures, implicit)
+		if renderErr != nil {
+			return renderedResponse{}, fmt.Errorf("failed to render exit_template: %w", renderErr)
+		}
+		out.exitCode, err = scenario.ParseExitCode(rendered)
+		if err != nil {
+			return renderedResponse{}, err
+		}
+	}
+
+	out.stdout, out.stderr = stdoutContent, stderrContent
+	return out, nil
+}
+
+// streamedFile returns path if the fixture can be left for the caller to
+// stream: streaming is enabled, allowed for this stream of the step, and
+// the fixture has no template syntax. Otherwise it returns "".
+func (e *Engine) streamedFile(path string, allowed bool) (string, error) {
+	if e.cfg.staticFile == nil || !allowed {
+		return "", nil
+	}
+	static, err := e.cfg.staticFile(path)
+	if err != nil || !static {
+		return "", err
+	}
+	return path, nil
+}
+
+// renderOutput renders each respond.output chunk and returns the chunks
+// along with the concatenated text of each stream.
+func (e *Engine) renderOutput(chunks []scenario.OutputChunk, vars map[string]string, implicit map[string]interface{}) (output []Chunk, stdout, stderr string, err error) {
+	var outBuf, errBuf strings.Builder
+	output = make([]Chunk, 0, len(chunks))
+	for i, c := range chunks {
+		text, renderErr := rendering.RenderWithContext(c.Text, vars, e.st.captures, implicit)
+		if renderErr != nil {
+			return nil, "", "", fmt.Errorf("failed to render output[%d] template: %w", i, renderErr)
+		}
+		if c.Stream == scenario.StreamStderr {
+			errBuf.WriteString(text)
+		} else {
+			outBuf.WriteString(text)
+		}
+		output = append(output, Chunk{Stream: c.Stream, Text: text})
+	}
+	return output, outBuf.String(), errBuf.String(), nil
+}
+
+// frameStdout wraps body with the rendered prepend and append lines. Each
+// non-empty part is written as whole lines: a missing trailing newline is
+// added to prepend and append, and to body when append follows it.
+func frameStdout(prepend, body, appendix string) string {
+	var b strings.Builder
+	if prepend != "" {
+		b.WriteString(prepend)
+		if !strings.HasSuffix(prepend, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString(body)
+	if appendix != "" {
+		if body != "" && !strings.HasSuffix(body, "\n") {
+			b.WriteByte('\n')
+		}
+		b.WriteString(appendix)
+		if !strings.HasSuffix(appendix, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// appendRetryHint adds the respond.retry_after line to stderr, starting it
+// on a new line when stderr does not end with one.
+func appendRetryHint(stderr string, retryAfter time.Duration) string {
+	if stderr != "" && !strings.HasSuffix(stderr, "\n") {
+		stderr += "\n"
+	}
+	return stderr + "cli-replay: retry-after=" + retryAfter.String() + "\n"
+}
+
+// implicitData builds the template values that are not declared in the
+// scenario: .prev, .call (1-based invocation count of the matched step, 0
+// for the fallback) and .total_calls (matched calls across all steps,
+// including this one).
+func (e *Engine) implicitData(stepIndex int) map[string]interface{} {
+	call := e.callCount(stepIndex)
+	total := 0
+	for _, c := range e.st.stepCounts {
+		total += c
+	}
+	return map[string]interface{}{
+		"prev":        e.st.prevData(),
+		"call":        call,
+		"total_calls": total,
+	}
+}
+
+// renderCaptures renders the respond.capture values of step as templates
+// against the vars, the current captures and the implicit data.
+func (e *Engine) renderCaptures(step *scenario.Step, stepIndex int) (map[string]string, error) {
+	vars := e.mergeVars()
+	implicit := e.implicitData(stepIndex)
+	out := make(map[string]string, len(step.Respond.Capture))
+	for k, v := range step.Respond.Capture {
+		rendered, err := rendering.RenderWithContext(v, vars, e.st.captures, implicit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render capture %q: %w", k, err)
+		}
+		out[k] = rendered
+	}
+	return out, nil
+}
+
+// serveDelay returns respond.delay plus the seeded jitter draw for the
+// current call of the step. Unparseable durations count as zero; run and
+// exec reject them before a session starts.
+func (e *Engine) serveDelay(step *scenario.Step, stepIndex int) time.Duration {
+	delay, _ := step.Respond.DelayDuration()
+	jitter, _ := step.Respond.JitterDuration()
+	return delay + drawJitter(jitter, e.cfg.seed, stepIndex, e.callCount(stepIndex))
+}
+
+// callCount returns the 1-based invocation count of step stepIndex, or 0
+// for the fallback.
+func (e *Engine) callCount(stepIndex int) int {
+	if stepIndex >= 0 && stepIndex < len(e.st.stepCounts) {
+		return e.st.stepCounts[stepIndex]
+	}
+	return 0
 }
 
 // mergeVars builds the template variable map: scenario meta.vars → option vars → env lookup.
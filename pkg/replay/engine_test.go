@@ -2,12 +2,18 @@ package replay
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 )
 
@@ -23,7 +29,7 @@ func leafStep(argv []string, stdout string, exit int) scenario.StepElement {
 	return scenario.StepElement{
 		Step: &scenario.Step{
 			Match:   scenario.Match{Argv: argv},
-			Respond: scenario.Response{Exit: exit, Stdout: stdout},
+			Respond: scenario.Response{Exit: scenario.Exit(exit), Stdout: stdout},
 		},
 	}
 }
@@ -32,7 +38,7 @@ func leafStepWithCalls(argv []string, stdout string, exit, min, max int) scenari
 	return scenario.StepElement{
 		Step: &scenario.Step{
 			Match:   scenario.Match{Argv: argv},
-			Respond: scenario.Response{Exit: exit, Stdout: stdout},
+			Respond: scenario.Response{Exit: scenario.Exit(exit), Stdout: stdout},
 			Calls:   &scenario.CallBounds{Min: min, Max: max},
 		},
 	}
@@ -42,7 +48,7 @@ func leafStepWithCapture(argv []string, stdout string, exit int, captures map[st
 	return scenario.StepElement{
 		Step: &scenario.Step{
 			Match:   scenario.Match{Argv: argv},
-			Respond: scenario.Response{Exit: exit, Stdout: stdout, Capture: captures},
+			Respond: scenario.Response{Exit: scenario.Exit(exit), Stdout: stdout, Capture: captures},
 		},
 	}
 }
@@ -146,7 +152,7 @@ func TestEngine_Stderr(t *testing.T) {
 		scenario.StepElement{
 			Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 1, Stderr: "error msg\n"},
+				Respond: scenario.Response{Exit: scenario.Exit(1), Stderr: "error msg\n"},
 			},
 		},
 	)
@@ -192,14 +198,17 @@ func TestEngine_SoftAdvance(t *testing.T) {
 	ctx := context.Background()
 
 	// Call step 0 once (meets min)
-	_, err := eng.Match(ctx, "cmd", []string{"a"})
+	r, err := eng.Match(ctx, "cmd", []string{"a"})
 	require.NoError(t, err)
+	assert.False(t, r.SoftAdvanced)
 
 	// Call step 1 directly — should soft-advance
-	r, err := eng.Match(ctx, "cmd", []string{"b"})
+	r, err = eng.Match(ctx, "cmd", []string{"b"})
 	require.NoError(t, err)
 	assert.Equal(t, "b\n", r.Stdout)
 	assert.Equal(t, 1, r.StepIndex)
+	assert.True(t, r.SoftAdvanced)
+	assert.Empty(t, r.Group)
 }
 
 func TestEngine_UnorderedGroup(t *testing.T) {
@@ -216,6 +225,7 @@ func TestEngine_UnorderedGroup(t *testing.T) {
 	r1, err := eng.Match(ctx, "cmd", []string{"b"})
 	require.NoError(t, err)
 	assert.Equal(t, "b\n", r1.Stdout)
+	assert.Equal(t, "mygroup", r1.Group)
 
 	r2, err := eng.Match(ctx, "cmd", []string{"a"})
 	require.NoError(t, err)
@@ -239,13 +249,176 @@ func TestEngine_GroupMismatch(t *testing.T) {
 	assert.Equal(t, "mygroup", gErr.GroupName)
 }
 
+func TestEngine_MatchEnv(t *testing.T) {
+	east := leafStep([]string{"az", "vm", "list"}, "east", 0)
+	east.Step.Match.Env = map[string]string{"REGION": "eastus"}
+	west := leafStep([]string{"az", "vm", "list"}, "west", 0)
+	west.Step.Match.Env = map[string]string{"REGION": "westus"}
+	scn := buildScenario("env", groupStep("regions", east, west))
+
+	env := map[string]string{"REGION": "westus"}
+	eng := New(scn, WithEnvLookup(func(k string) string { return env[k] }))
+	ctx := context.Background()
+
+	r, err := eng.Match(ctx, "az", []string{"vm", "list"})
+	require.NoError(t, err)
+	assert.Equal(t, "west", r.Stdout)
+
+	env["REGION"] = "eastus"
+	r, err = eng.Match(ctx, "az", []string{"vm", "list"})
+	require.NoError(t, err)
+	assert.Equal(t, "east", r.Stdout)
+}
+
+func TestEngine_MatchEnvMismatchDetail(t *testing.T) {
+	step := leafStep([]string{"kubectl", "get", "pods"}, "", 0)
+	step.Step.Match.Env = map[string]string{"KUBECONFIG": "/prod", "NS": "default"}
+	eng := New(buildScenario("env", step), WithEnvLookup(func(k string) string {
+		if k == "NS" {
+			return "default"
+		}
+		return ""
+	}))
+
+	_, err := eng.Match(context.Background(), "kubectl", []string{"get", "pods"})
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, []EnvMismatch{{Name: "KUBECONFIG", Expected: "/prod", Actual: ""}}, mErr.EnvMismatches)
+}
+
+func TestEngine_MatchArgvHash(t *testing.T) {
+	blob := strings.Repeat("QUJD", 4096)
+	sum := sha256.Sum256([]byte(blob))
+	step := leafStep([]string{"deploy", "--payload", "<blob>", "--wait"}, "deployed", 0)
+	step.Step.Match.ArgvHash = []scenario.ArgvHash{{Index: 2, SHA256: hex.EncodeToString(sum[:])}}
+	ctx := context.Background()
+
+	eng := New(buildScenario("hash", step))
+	_, err := eng.Match(ctx, "deploy", []string{"--payload", blob + "x", "--wait"})
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+
+	_, err = eng.Match(ctx, "deploy", []string{"--payload", blob, "--nowait"})
+	require.ErrorAs(t, err, &mErr)
+
+	r, err := eng.Match(ctx, "deploy", []string{"--payload", blob, "--wait"})
+	require.NoError(t, err)
+	assert.Equal(t, "deployed", r.Stdout)
+}
+
+func TestEngine_MatchArityAny(t *testing.T) {
+	ctx := context.Background()
+
+	anyStep := leafStepWithCalls([]string{"git"}, "ok", 0, 1, 3)
+	anyStep.Step.Match.Arity = scenario.ArityAny
+	eng := New(buildScenario("arity-any", anyStep))
+	_, err := eng.Match(ctx, "git", []string{"log", "--oneline"})
+	require.NoError(t, err)
+	_, err = eng.Match(ctx, "git", nil)
+	require.NoError(t, err)
+	_, err = eng.Match(ctx, "hg", []string{"log"})
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr, "the command name must still match")
+
+	exact := leafStepWithCalls([]string{"git"}, "ok", 0, 1, 3)
+	exact.Step.Match.Arity = scenario.ArityExact
+	eng = New(buildScenario("arity-exact", exact))
+	_, err = eng.Match(ctx, "git", []string{"log", "--oneline"})
+	require.ErrorAs(t, err, &mErr)
+	_, err = eng.Match(ctx, "git", nil)
+	require.NoError(t, err)
+}
+
+func TestEngine_Fallback(t *testing.T) {
+	scn := buildScenario("fallback", leafStepWithCapture([]string{"create"}, "", 0, map[string]string{"id": "42"}))
+	scn.Meta.Fallback = &scenario.Response{Exit: scenario.Exit(0), Stdout: "probe id={{ .capture.id }}"}
+	eng := New(scn)
+	ctx := context.Background()
+
+	_, err := eng.Match(ctx, "create", nil)
+	require.NoError(t, err)
+	counts := eng.StepCounts()
+
+	r, err := eng.Fallback(ctx)
+	require.NoError(t, err)
+	assert.True(t, r.Fallback)
+	assert.False(t, r.Matched)
+	assert.Equal(t, "probe id=42", r.Stdout)
+	assert.Equal(t, counts, eng.StepCounts(), "fallback must not consume steps")
+}
+
+func TestEngine_FallbackAbsent(t *testing.T) {
+	eng := New(buildScenario("none", leafStep([]string{"a"}, "", 0)))
+	_, err := eng.Fallback(context.Background())
+	require.Error(t, err)
+}
+
+func TestEngine_OrderedGroup(t *testing.T) {
+	group := groupStep("deploy",
+		leafStep([]string{"cmd", "a"}, "a", 0),
+		leafStep([]string{"cmd", "b"}, "b", 0),
+	)
+	group.Group.Mode = scenario.GroupModeOrdered
+	scn := buildScenario("ordered", group, leafStep([]string{"cmd", "c"}, "c", 0))
+	eng := New(scn)
+	ctx := context.Background()
+
+	// Out of order inside the group is a mismatch attributed to the group
+	_, err := eng.Match(ctx, "cmd", []string{"b"})
+	require.Error(t, err)
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, 0, mErr.StepIndex)
+	assert.Equal(t, "deploy", mErr.GroupName)
+
+	for _, arg := range []string{"a", "b", "c"} {
+		r, err := eng.Match(ctx, "cmd", []string{arg})
+		require.NoError(t, err, "cmd %s", arg)
+		assert.Equal(t, arg, r.Stdout)
+	}
+	assert.Nil(t, eng.Snapshot().ActiveGroup)
+}
+
+func TestEngine_GroupDependsOn(t *testing.T) {
+	login := leafStep([]string{"az", "login"}, "logged in", 0)
+	login.Step.Name = "login"
+	deploy := leafStep([]string{"az", "deploy"}, "deployed", 0)
+	deploy.Step.DependsOn = []string{"login"}
+	other := leafStep([]string{"az", "version"}, "2.0", 0)
+
+	scn := buildScenario("deps", groupStep("setup", deploy, other, login))
+	eng := New(scn)
+	ctx := context.Background()
+
+	// deploy before login is rejected
+	_, err := eng.Match(ctx, "az", []string{"deploy"})
+	require.Error(t, err)
+	var dErr *DependencyError
+	require.ErrorAs(t, err, &dErr)
+	assert.Equal(t, "setup", dErr.GroupName)
+	assert.Equal(t, 0, dErr.StepIndex)
+	assert.Equal(t, []string{"login"}, dErr.Unmet)
+	assert.Equal(t, []int{0, 0, 0}, eng.StepCounts(), "rejected call must not be counted")
+
+	// Independent children remain unordered
+	_, err = eng.Match(ctx, "az", []string{"version"})
+	require.NoError(t, err)
+
+	_, err = eng.Match(ctx, "az", []string{"login"})
+	require.NoError(t, err)
+
+	r, err := eng.Match(ctx, "az", []string{"deploy"})
+	require.NoError(t, err)
+	assert.Equal(t, "deployed", r.Stdout)
+}
+
 func TestEngine_CaptureChain(t *testing.T) {
 	scn := buildScenario("captures",
 		leafStepWithCapture([]string{"create"}, "created", 0, map[string]string{"id": "abc-123"}),
 		scenario.StepElement{
 			Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"show"}},
-				Respond: scenario.Response{Exit: 0, Stdout: "id={{ .capture.id }}"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "id={{ .capture.id }}"},
 			},
 		},
 	)
@@ -261,6 +434,92 @@ func TestEngine_CaptureChain(t *testing.T) {
 	assert.Equal(t, "id=abc-123", r2.Stdout)
 }
 
+func TestEngine_TemplatedCapture(t *testing.T) {
+	scn := buildScenario("templated-captures",
+		leafStepWithCapture([]string{"create"}, "", 0, map[string]string{"region_upper": "{{ .region | upper }}", "id": "vm-{{ .call }}"}),
+		leafStepWithCapture([]string{"tag"}, "", 0, map[string]string{"tag": "{{ .capture.region_upper }}/{{ .capture.id }}"}),
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match:   scenario.Match{Argv: []string{"show"}},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "{{ .capture.region_upper }} {{ .capture.tag }}"},
+			},
+		},
+	)
+	scn.Meta.Vars = map[string]string{"region": "eastus"}
+	eng := New(scn)
+	ctx := context.Background()
+
+	r1, err := eng.Match(ctx, "create", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "EASTUS", r1.Captures["region_upper"], "the rendered value is stored, not the template")
+	assert.Equal(t, "vm-1", r1.Captures["id"])
+
+	_, err = eng.Match(ctx, "tag", nil)
+	require.NoError(t, err)
+
+	r3, err := eng.Match(ctx, "show", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "EASTUS EASTUS/vm-1", r3.Stdout)
+}
+
+func TestEngine_TemplatedCaptureInvalid(t *testing.T) {
+	scn := buildScenario("bad-capture",
+		leafStepWithCapture([]string{"create"}, "", 0, map[string]string{"id": "{{ .id"}),
+	)
+	r, err := New(scn).Match(context.Background(), "create", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to render capture "id"`)
+	assert.Equal(t, 1, r.ExitCode)
+}
+
+func TestEngine_ExitTemplateFromCapture(t *testing.T) {
+	scn := buildScenario("exit-template",
+		leafStepWithCapture([]string{"check"}, "", 0, map[string]string{"status": "3"}),
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match:   scenario.Match{Argv: []string{"report"}},
+				Respond: scenario.Response{ExitTemplate: "{{ .capture.status }}"},
+			},
+		},
+	)
+	eng := New(scn)
+	ctx := context.Background()
+
+	_, err := eng.Match(ctx, "check", nil)
+	require.NoError(t, err)
+
+	r, err := eng.Match(ctx, "report", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, r.ExitCode)
+}
+
+func TestEngine_ExitTemplateInvalidValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		tmpl     string
+		contains string
+	}{
+		{name: "non-integer", tmpl: "{{ .status }}", contains: "not an integer"},
+		{name: "out of range", tmpl: "300", contains: "range 0-255"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scn := buildScenario("exit-template-invalid", scenario.StepElement{
+				Step: &scenario.Step{
+					Match:   scenario.Match{Argv: []string{"report"}},
+					Respond: scenario.Response{ExitTemplate: tt.tmpl},
+				},
+			})
+			scn.Meta.Vars = map[string]string{"status": "failed"}
+
+			r, err := New(scn).Match(context.Background(), "report", nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.contains)
+			assert.Equal(t, 1, r.ExitCode)
+		})
+	}
+}
+
 func TestEngine_CaptureInGroup(t *testing.T) {
 	scn := buildScenario("capture-group",
 		leafStepWithCapture([]string{"setup"}, "ready", 0, map[string]string{"base": "base-1"}),
@@ -269,7 +528,7 @@ func TestEngine_CaptureInGroup(t *testing.T) {
 			scenario.StepElement{
 				Step: &scenario.Step{
 					Match:   scenario.Match{Argv: []string{"cmd", "b"}},
-					Respond: scenario.Response{Exit: 0, Stdout: "base={{ .capture.base }} a={{ .capture.val_a }}"},
+					Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "base={{ .capture.base }} a={{ .capture.val_a }}"},
 				},
 			},
 		),
@@ -324,7 +583,7 @@ func TestEngine_TemplateVars(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 0, Stdout: "region={{ .region }}"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "region={{ .region }}"},
 			}},
 		},
 	}
@@ -344,7 +603,7 @@ func TestEngine_WithVarsOverride(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 0, Stdout: "region={{ .region }}"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "region={{ .region }}"},
 			}},
 		},
 	}
@@ -364,7 +623,7 @@ func TestEngine_WithEnvLookup(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 0, Stdout: "region={{ .region }}"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "region={{ .region }}"},
 			}},
 		},
 	}
@@ -389,7 +648,7 @@ func TestEngine_WithDenyEnvPatterns(t *testing.T) {
 		Steps: []scenario.StepElement{
 			{Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 0, Stdout: "key={{ .SECRET_KEY }}"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "key={{ .SECRET_KEY }}"},
 			}},
 		},
 	}
@@ -414,7 +673,7 @@ func TestEngine_WithFileReader(t *testing.T) {
 		scenario.StepElement{
 			Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 0, StdoutFile: "fixtures/output.txt"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), StdoutFile: "fixtures/output.txt"},
 			},
 		},
 	)
@@ -430,12 +689,317 @@ func TestEngine_WithFileReader(t *testing.T) {
 	assert.Equal(t, "file content\n", r.Stdout)
 }
 
+func TestEngine_WithFileStreaming(t *testing.T) {
+	fileStep := func(resp scenario.Response) scenario.StepElement {
+		return scenario.StepElement{Step: &scenario.Step{Match: scenario.Match{Argv: []string{"cmd"}}, Respond: resp}}
+	}
+	scn := buildScenario("streamed",
+		fileStep(scenario.Response{StdoutFile: "static.txt", StderrFile: "static.txt"}),
+		fileStep(scenario.Response{StdoutFile: "templated.txt"}),
+		fileStep(scenario.Response{StdoutFile: "static.txt", Append: "!"}),
+		fileStep(scenario.Response{StderrFile: "static.txt", RetryAfter: "5s"}),
+	)
+	var reads []string
+	eng := New(scn,
+		WithFileReader(func(path string) (string, error) {
+			reads = append(reads, path)
+			return "read " + path, nil
+		}),
+		WithFileStreaming(func(path string) (bool, error) { return path == "static.txt", nil }),
+		WithStateStore(NewMemoryStore()),
+	)
+	ctx := context.Background()
+
+	r, err := eng.Match(ctx, "cmd", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "static.txt", r.StdoutFile)
+	assert.Equal(t, "static.txt", r.StderrFile)
+	assert.Empty(t, r.Stdout)
+	assert.Empty(t, reads, "a static fixture is left for the caller to stream")
+
+	r, err = eng.Match(ctx, "cmd", nil)
+	require.NoError(t, err)
+	assert.Empty(t, r.StdoutFile)
+	assert.Equal(t, "read templated.txt", r.Stdout)
+
+	r, err = eng.Match(ctx, "cmd", nil)
+	require.NoError(t, err)
+	assert.Empty(t, r.StdoutFile, "append frames the fixture, so it is read")
+	assert.Equal(t, "read static.txt\n!\n", r.Stdout)
+
+	r, err = eng.Match(ctx, "cmd", nil)
+	require.NoError(t, err)
+	assert.Empty(t, r.StderrFile, "retry_after appends to stderr, so it is read")
+	assert.Contains(t, r.Stderr, "read static.txt")
+}
+
+func TestEngine_PrependAppend(t *testing.T) {
+	scn := buildScenario("framed",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match: scenario.Match{Argv: []string{"curl"}},
+				Respond: scenario.Response{
+					Exit:    scenario.Exit(0),
+					Prepend: "HTTP/1.1 {{ .status }}",
+					Stdout:  `{"region":"{{ .region }}"}`,
+					Append:  "# end {{ .call }}\n",
+				},
+			},
+		},
+	)
+	scn.Meta.Vars = map[string]string{"status": "200 OK", "region": "eastus"}
+	eng := New(scn)
+
+	r, err := eng.Match(context.Background(), "curl", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\n{\"region\":\"eastus\"}\n# end 1\n", r.Stdout)
+}
+
+func TestEngine_PrependAppendWrapStdoutFile(t *testing.T) {
+	scn := buildScenario("framed-file",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match:   scenario.Match{Argv: []string{"cmd"}},
+				Respond: scenario.Response{Exit: scenario.Exit(0), StdoutFile: "body.txt", Prepend: "status: {{ .capture.state }}\n"},
+			},
+		},
+	)
+	eng := New(scn,
+		WithInitialState(StateSnapshot{TotalSteps: 1, Captures: map[string]string{"state": "ready"}}),
+		WithFileReader(func(string) (string, error) { return "line 1\nline 2\n", nil }),
+	)
+
+	r, err := eng.Match(context.Background(), "cmd", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "status: ready\nline 1\nline 2\n", r.Stdout)
+}
+
+func TestFrameStdout(t *testing.T) {
+	tests := []struct {
+		name                   string
+		prepend, body, appendx string
+		want                   string
+	}{
+		{"prepend only", "HEAD", "body\n", "", "HEAD\nbody\n"},
+		{"append only", "", "body", "TAIL", "body\nTAIL\n"},
+		{"empty body", "HEAD\n", "", "TAIL\n", "HEAD\nTAIL\n"},
+		{"newlines kept", "HEAD\n", "body\n", "TAIL\n", "HEAD\nbody\nTAIL\n"},
+		{"body without newline and no append", "HEAD", "body", "", "HEAD\nbody"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, frameStdout(tt.prepend, tt.body, tt.appendx))
+		})
+	}
+}
+
+func randomScenario() *scenario.Scenario {
+	return buildScenario("chaos",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match: scenario.Match{Argv: []string{"curl"}},
+				Respond: scenario.Response{Random: []scenario.WeightedResponse{
+					{Weight: 1, Response: scenario.Response{Exit: scenario.Exit(0), Stdout: "a"}},
+					{Weight: 1, Response: scenario.Response{Exit: scenario.Exit(7), Stdout: "b"}},
+				}},
+				Calls: &scenario.CallBounds{Min: 1, Max: 8},
+			},
+		},
+	)
+}
+
+func TestEngine_RandomResponseSeeded(t *testing.T) {
+	serve := func() string {
+		eng := New(randomScenario(), WithSeed(42))
+		var pattern string
+		for i := 0; i < 8; i++ {
+			r, err := eng.Match(context.Background(), "curl", nil)
+			require.NoError(t, err)
+			if r.Stdout == "b" {
+				assert.Equal(t, 7, r.ExitCode, "exit comes from the chosen entry")
+			}
+			pattern += r.Stdout
+		}
+		return pattern
+	}
+
+	assert.Equal(t, "ababbbbb", serve())
+	assert.Equal(t, "ababbbbb", serve(), "the same seed reproduces the same pattern")
+}
+
+func TestEngine_RandomResponseResumedEngineKeepsPattern(t *testing.T) {
+	first := New(randomScenario(), WithSeed(42))
+	for i := 0; i < 3; i++ {
+		_, err := first.Match(context.Background(), "curl", nil)
+		require.NoError(t, err)
+	}
+
+	// A new engine restored from the snapshot continues the seeded sequence,
+	// as each intercept process does.
+	resumed := New(randomScenario(), WithSeed(42), WithInitialState(first.Snapshot()))
+	r, err := resumed.Match(context.Background(), "curl", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "b", r.Stdout)
+}
+
+func TestPickWeighted_FollowsWeights(t *testing.T) {
+	entries := []scenario.WeightedResponse{
+		{Weight: 1, Response: scenario.Response{Stdout: "rare"}},
+		{Weight: 3, Response: scenario.Response{Stdout: "common"}},
+	}
+	counts := map[string]int{}
+	for call := 1; call <= 4000; call++ {
+		counts[pickWeighted(entries, 7, 0, call).Stdout]++
+	}
+	assert.InDelta(t, 1000, counts["rare"], 150)
+	assert.InDelta(t, 3000, counts["common"], 150)
+}
+
+func TestEngine_JitterSeeded(t *testing.T) {
+	scn := buildScenario("latency",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match:   scenario.Match{Argv: []string{"curl"}},
+				Respond: scenario.Response{Delay: "100ms", Jitter: "50ms"},
+				Calls:   &scenario.CallBounds{Min: 1, Max: 20},
+			},
+		},
+	)
+	delays := func() []time.Duration {
+		eng := New(scn, WithSeed(42))
+		var out []time.Duration
+		for i := 0; i < 20; i++ {
+			r, err := eng.Match(context.Background(), "curl", nil)
+			require.NoError(t, err)
+			out = append(out, r.Delay)
+		}
+		return out
+	}
+
+	first := delays()
+	distinct := map[time.Duration]bool{}
+	for _, d := range first {
+		assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+		assert.LessOrEqual(t, d, 150*time.Millisecond)
+		distinct[d] = true
+	}
+	assert.Greater(t, len(distinct), 1, "jitter varies between calls")
+	assert.Equal(t, first, delays(), "the same seed reproduces the same delays")
+}
+
+func TestEngine_DelayWithoutJitter(t *testing.T) {
+	scn := buildScenario("latency",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match:   scenario.Match{Argv: []string{"curl"}},
+				Respond: scenario.Response{Delay: "100ms"},
+			},
+		},
+	)
+	r, err := New(scn).Match(context.Background(), "curl", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 100*time.Millisecond, r.Delay)
+}
+
+func TestEngine_Output(t *testing.T) {
+	scn := buildScenario("output",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match: scenario.Match{Argv: []string{"terraform", "apply"}},
+				Respond: scenario.Response{
+					Output: []scenario.OutputChunk{
+						{Stream: "stderr", Text: "Applying {{ .env }}...\n"},
+						{Stream: "stdout", Text: "resource 1\n"},
+						{Stream: "stderr", Text: "50%\n"},
+						{Stream: "stdout", Text: "resource 2\n"},
+					},
+					RetryAfter: "5s",
+				},
+			},
+		},
+	)
+	scn.Meta.Vars = map[string]string{"env": "staging"}
+
+	r, err := New(scn).Match(context.Background(), "terraform", []string{"apply"})
+	require.NoError(t, err)
+	assert.Equal(t, []Chunk{
+		{Stream: "stderr", Text: "Applying staging...\n"},
+		{Stream: "stdout", Text: "resource 1\n"},
+		{Stream: "stderr", Text: "50%\n"},
+		{Stream: "stdout", Text: "resource 2\n"},
+		{Stream: "stderr", Text: "cli-replay: retry-after=5s\n"},
+	}, r.Output)
+	assert.Equal(t, "resource 1\nresource 2\n", r.Stdout)
+	assert.Equal(t, "Applying staging...\n50%\ncli-replay: retry-after=5s\n", r.Stderr)
+}
+
+func TestEngine_OutputAbsent(t *testing.T) {
+	scn := buildScenario("plain", leafStep([]string{"git", "status"}, "clean", 0))
+	r, err := New(scn).Match(context.Background(), "git", []string{"status"})
+	require.NoError(t, err)
+	assert.Nil(t, r.Output)
+	assert.Equal(t, "clean", r.Stdout)
+}
+
+func TestEngine_RetryAfter(t *testing.T) {
+	scn := buildScenario("retry",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match: scenario.Match{Argv: []string{"az", "deployment", "show"}},
+				Respond: scenario.Response{
+					Exit:       scenario.Exit(1),
+					Stdout:     `{"retryAfter": "{{ .retry_after }}"}`,
+					Stderr:     "not ready",
+					RetryAfter: "90s",
+				},
+			},
+		},
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match:   scenario.Match{Argv: []string{"az", "deployment", "show"}},
+				Respond: scenario.Response{Stdout: "done"},
+			},
+		},
+	)
+	eng := New(scn)
+
+	r, err := eng.Match(context.Background(), "az", []string{"deployment", "show"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"retryAfter": "1m30s"}`, r.Stdout)
+	assert.Equal(t, "not ready\ncli-replay: retry-after=1m30s\n", r.Stderr)
+	assert.Equal(t, 1, r.ExitCode)
+
+	r, err = eng.Match(context.Background(), "az", []string{"deployment", "show"})
+	require.NoError(t, err)
+	assert.Empty(t, r.Stderr, "no hint without retry_after")
+}
+
+func TestEngine_RetryAfterWithRandom(t *testing.T) {
+	scn := buildScenario("retry",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match: scenario.Match{Argv: []string{"curl"}},
+				Respond: scenario.Response{
+					RetryAfter: "2s",
+					Random: []scenario.WeightedResponse{
+						{Weight: 1, Response: scenario.Response{Exit: scenario.Exit(1), Stdout: "retry in {{ .retry_after }}"}},
+					},
+				},
+			},
+		},
+	)
+	r, err := New(scn).Match(context.Background(), "curl", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "retry in 2s", r.Stdout)
+	assert.Equal(t, "cli-replay: retry-after=2s\n", r.Stderr)
+}
+
 func TestEngine_FileReaderNotConfigured(t *testing.T) {
 	scn := buildScenario("file",
 		scenario.StepElement{
 			Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}},
-				Respond: scenario.Response{Exit: 0, StdoutFile: "fixtures/output.txt"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), StdoutFile: "fixtures/output.txt"},
 			},
 		},
 	)
@@ -465,7 +1029,7 @@ func TestEngine_StdinMatch(t *testing.T) {
 		scenario.StepElement{
 			Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}, Stdin: "expected input"},
-				Respond: scenario.Response{Exit: 0, Stdout: "ok"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "ok"},
 			},
 		},
 	)
@@ -482,7 +1046,7 @@ func TestEngine_StdinMismatch(t *testing.T) {
 		scenario.StepElement{
 			Step: &scenario.Step{
 				Match:   scenario.Match{Argv: []string{"cmd"}, Stdin: "expected"},
-				Respond: scenario.Response{Exit: 0, Stdout: "ok"},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "ok"},
 			},
 		},
 	)
@@ -497,6 +1061,28 @@ func TestEngine_StdinMismatch(t *testing.T) {
 	assert.Equal(t, "wrong", sErr.Received)
 }
 
+func TestEngine_StdinSHA256(t *testing.T) {
+	scn := buildScenario("stdin-hash",
+		scenario.StepElement{
+			Step: &scenario.Step{
+				Match:   scenario.Match{Argv: []string{"cmd"}, StdinSHA256: matcher.StdinSHA256("payload")},
+				Respond: scenario.Response{Exit: scenario.Exit(0), Stdout: "ok"},
+			},
+		},
+	)
+
+	_, err := New(scn).MatchWithStdin(context.Background(), "cmd", nil, "other")
+	var sErr *StdinMismatchError
+	require.ErrorAs(t, err, &sErr)
+	assert.Empty(t, sErr.Expected)
+	assert.Equal(t, matcher.StdinSHA256("other"), sErr.ReceivedSHA256)
+	assert.Contains(t, err.Error(), "does not match stdin_sha256")
+
+	r, err := New(scn).MatchWithStdin(context.Background(), "cmd", nil, "payload\n")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", r.Stdout)
+}
+
 func TestEngine_Reset(t *testing.T) {
 	scn := buildScenario("reset",
 		leafStep([]string{"cmd"}, "output", 0),
@@ -636,3 +1222,88 @@ func TestIsDenied(t *testing.T) {
 		})
 	}
 }
+
+func TestEngine_PrevExposesLastServedResponse(t *testing.T) {
+	scn := buildScenario("prev",
+		leafStep([]string{"gen", "id"}, `first[{{ .prev.stdout }}]`, 3),
+		leafStep([]string{"echo"}, `{{ .prev.stdout }}|{{ .prev.exit }}|{{ index .prev.argv 1 }}`, 0),
+	)
+	eng := New(scn)
+
+	r, err := eng.Match(context.Background(), "gen", []string{"id"})
+	require.NoError(t, err)
+	assert.Equal(t, "first[]", r.Stdout, ".prev is empty on the first step")
+
+	r, err = eng.Match(context.Background(), "echo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first[]|3|id", r.Stdout)
+
+	snap := eng.Snapshot()
+	require.NotNil(t, snap.Prev)
+	assert.Equal(t, []string{"echo"}, snap.Prev.Argv)
+}
+
+func TestEngine_PrevRestoredFromSnapshot(t *testing.T) {
+	scn := buildScenario("prev-resume",
+		leafStep([]string{"a"}, "abc", 0),
+		leafStep([]string{"b"}, "got {{ .prev.stdout }}", 0),
+	)
+	first := New(scn)
+	_, err := first.Match(context.Background(), "a", nil)
+	require.NoError(t, err)
+
+	resumed := New(scn, WithInitialState(first.Snapshot()))
+	r, err := resumed.Match(context.Background(), "b", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "got abc", r.Stdout)
+}
+
+func TestEngine_CallCountInTemplates(t *testing.T) {
+	scn := buildScenario("pagination",
+		leafStep([]string{"login"}, "ok", 0),
+		leafStepWithCalls([]string{"list"}, "page {{ .call }} of 3 (call {{ .total_calls }})", 0, 1, 3),
+	)
+	eng := New(scn)
+
+	_, err := eng.Match(context.Background(), "login", nil)
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		r, err := eng.Match(context.Background(), "list", nil)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("page %d of 3 (call %d)", i, i+1), r.Stdout)
+	}
+}
+
+func TestEngine_AnyOf(t *testing.T) {
+	newEngine := func() *Engine {
+		return New(buildScenario("alternatives",
+			scenario.StepElement{Step: &scenario.Step{
+				Match:   scenario.Match{AnyOf: [][]string{{"kubectl", "get", "po"}, {"kubectl", "get", "pods"}}},
+				Respond: scenario.Response{Stdout: "pods\n"},
+			}},
+			leafStep([]string{"kubectl", "logs"}, "logs\n", 0),
+		))
+	}
+	ctx := context.Background()
+
+	for _, args := range [][]string{{"get", "po"}, {"get", "pods"}} {
+		eng := newEngine()
+		r, err := eng.Match(ctx, "kubectl", args)
+		require.NoError(t, err)
+		assert.Equal(t, "pods\n", r.Stdout)
+		assert.Equal(t, 0, r.StepIndex)
+
+		// One slot consumed: the other alternative no longer matches
+		_, err = eng.Match(ctx, "kubectl", []string{"get", "po"})
+		var mErr *MismatchError
+		require.ErrorAs(t, err, &mErr)
+		assert.Equal(t, 1, mErr.StepIndex)
+	}
+
+	eng := newEngine()
+	_, err := eng.Match(ctx, "kubectl", []string{"get", "svc"})
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, []string{"kubectl", "get", "po"}, mErr.Expected)
+	assert.Equal(t, [][]string{{"kubectl", "get", "po"}, {"kubectl", "get", "pods"}}, mErr.ExpectedAnyOf)
+}
@@ -10,6 +10,31 @@ type MismatchError struct {
 	SoftAdvanced  bool
 	NextStepIndex int
 	NextExpected  []string
+	GroupName     string // set when StepIndex is inside an ordered group
+	// ExpectedAnyOf holds every alternative when the expected step uses
+	// match.any_of; Expected is then the first of them.
+	ExpectedAnyOf [][]string
+	// EnvMismatches lists failed match.env expectations when the argv
+	// itself matched the expected step.
+	EnvMismatches []EnvMismatch
+	// CwdMismatch is set when the argv matched the expected step but the
+	// working directory did not satisfy its match.cwd.
+	CwdMismatch *CwdMismatch
+}
+
+// CwdMismatch describes a match.cwd expectation that the working directory
+// at invocation did not satisfy.
+type CwdMismatch struct {
+	Expected string // match.cwd as written in the scenario
+	Actual   string
+}
+
+// EnvMismatch describes a match.env expectation that the environment at
+// invocation did not satisfy.
+type EnvMismatch struct {
+	Name     string
+	Expected string
+	Actual   string
 }
 
 func (e *MismatchError) Error() string {
@@ -31,15 +56,37 @@ func (e *GroupMismatchError) Error() string {
 		e.GroupName, e.GroupIndex, e.Received)
 }
 
+// DependencyError is returned when a command matches an unordered group
+// child whose depends_on siblings have not yet met their min counts.
+type DependencyError struct {
+	GroupName string
+	StepIndex int
+	StepName  string
+	Unmet     []string
+	Received  []string
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("step %d in group %q called before its dependencies %v met their min call counts: received %v",
+		e.StepIndex, e.GroupName, e.Unmet, e.Received)
+}
+
 // StdinMismatchError is returned when the command argv matches but stdin
-// content does not match the expected value.
+// content does not match the expected value. For match.stdin_sha256,
+// Expected is empty and the digests are set instead.
 type StdinMismatchError struct {
-	StepIndex int
-	Expected  string
-	Received  string
+	StepIndex      int
+	Expected       string
+	Received       string
+	ExpectedSHA256 string
+	ReceivedSHA256 string
 }
 
 func (e *StdinMismatchError) Error() string {
+	if e.ExpectedSHA256 != "" {
+		return fmt.Sprintf("stdin mismatch at step %d: sha256 %s does not match stdin_sha256 %s",
+			e.StepIndex, e.ReceivedSHA256, e.ExpectedSHA256)
+	}
 	return fmt.Sprintf("stdin mismatch at step %d", e.StepIndex)
 }
 
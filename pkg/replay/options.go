@@ -21,12 +21,29 @@ type engineConfig struct {
 	// If nil, file-based responses return an error.
 	fileReader func(path string) (string, error)
 
+	// staticFile, when set, reports whether a stdout_file/stderr_file
+	// fixture has no template syntax, so the caller can stream it.
+	staticFile func(path string) (bool, error)
+
+	// workingDir is the directory the command was invoked from, checked
+	// against match.cwd. scenarioDir anchors relative match.cwd patterns.
+	workingDir  string
+	scenarioDir string
+
 	// matchFunc overrides the default argv matching function.
 	// If nil, uses pkg/matcher.ArgvMatch.
 	matchFunc func(expected, received []string) bool
 
 	// initialState seeds the engine from a previously persisted snapshot.
 	initialState *StateSnapshot
+
+	// store, when set, is read before and written after every match.
+	store StateStore
+
+	// seed drives respond.random selection. When seeded is false, New
+	// picks a seed from the clock.
+	seed   int64
+	seeded bool
 }
 
 // WithVars sets additional template variables that override scenario meta.vars.
@@ -61,6 +78,37 @@ func WithFileReader(fn func(path string) (string, error)) Option {
 	}
 }
 
+// WithFileStreaming lets the caller stream static stdout_file/stderr_file
+// fixtures instead of the engine reading them into memory. isStatic
+// reports whether a fixture has no template syntax; such a fixture is
+// named in Result.StdoutFile/StderrFile instead of being read, unless the
+// step transforms or checks that stream (prepend/append, retry_after,
+// output, assert). The engine never sees a streamed fixture's content, so
+// .prev holds nothing for that stream unless the caller restores it with
+// WithInitialState, as the CLI does.
+func WithFileStreaming(isStatic func(path string) (bool, error)) Option {
+	return func(c *engineConfig) {
+		c.staticFile = isStatic
+	}
+}
+
+// WithWorkingDir sets the invocation working directory checked against
+// match.cwd, and the scenario directory relative match.cwd patterns are
+// resolved against. Without it, steps with match.cwd never match.
+func WithWorkingDir(cwd, scenarioDir string) Option {
+	return func(c *engineConfig) {
+		c.workingDir, c.scenarioDir = cwd, scenarioDir
+	}
+}
+
+// WithSeed fixes the seed for respond.random selection, so the same step
+// and call count always pick the same entry, even across engines.
+func WithSeed(seed int64) Option {
+	return func(c *engineConfig) {
+		c.seed, c.seeded = seed, true
+	}
+}
+
 // WithMatchFunc overrides the default argv matching function.
 // This is the extensibility point for custom matching strategies.
 func WithMatchFunc(fn func(expected, received []string) bool) Option {
@@ -0,0 +1,53 @@
+package replay
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// pickWeighted returns the respond.random entry served for the given call of
+// a step. The choice depends only on seed, stepIndex and call, so a fixed
+// seed reproduces the same pattern even when every call runs in a new
+// process. Weights are positive; Validate rejects anything else.
+func pickWeighted(entries []scenario.WeightedResponse, seed int64, stepIndex, call int) scenario.Response {
+	total := 0
+	for _, w := range entries {
+		total += w.Weight
+	}
+	n := callRand(seed, int64(stepIndex), int64(call)).Intn(total)
+	for _, w := range entries {
+		if n < w.Weight {
+			return w.Response
+		}
+		n -= w.Weight
+	}
+	return entries[len(entries)-1].Response
+}
+
+// drawJitter returns the extra wait for the given call of a step, uniform
+// in [0, jitter] and reproducible in the same way as pickWeighted.
+func drawJitter(jitter time.Duration, seed int64, stepIndex, call int) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(callRand(seed, int64(stepIndex), int64(call), jitterSalt).Int63n(int64(jitter) + 1))
+}
+
+// jitterSalt keeps the jitter draw of a call independent of its
+// respond.random pick.
+const jitterSalt = 1
+
+// callRand returns a generator determined entirely by the given values.
+func callRand(values ...int64) *rand.Rand {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, v := range values {
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		_, _ = h.Write(buf[:])
+	}
+	return rand.New(rand.NewSource(int64(h.Sum64()))) //nolint:gosec // chaos testing, not security sensitive
+}
@@ -3,16 +3,44 @@
 // (e.g., from gert) and has zero file I/O, zero CLI coupling, and is thread-safe.
 package replay
 
+import "time"
+
 // Result contains the outcome of a single match operation.
 type Result struct {
 	Stdout   string
 	Stderr   string
 	ExitCode int
 
+	// StdoutFile and StderrFile name static fixtures the caller streams to
+	// stdout/stderr, with the path as given in the scenario. Set only with
+	// WithFileStreaming; Stdout/Stderr then hold nothing for that stream.
+	StdoutFile string
+	StderrFile string
+
 	// StepIndex is the flat index of the matched step in the scenario.
 	StepIndex int
 	// Matched is true if the command was matched to a step.
 	Matched bool
+	// Fallback is true if the response came from the scenario's meta.fallback.
+	Fallback bool
+	// SoftAdvanced is true if the match moved past a step or unordered group
+	// whose min count was already met.
+	SoftAdvanced bool
+	// Group is the name of the group containing the matched step, if any.
+	Group string
+	// Delay is how long to wait before writing the response: respond.delay
+	// plus a seeded draw from [0, respond.jitter]. The engine never sleeps.
+	Delay time.Duration
 	// Captures accumulated after this match (snapshot, not a reference).
 	Captures map[string]string
+	// Output holds the rendered respond.output chunks, to be written in
+	// order; Stdout and Stderr then hold their concatenation per stream.
+	// Nil when the response has no output list.
+	Output []Chunk
+}
+
+// Chunk is one rendered respond.output entry.
+type Chunk struct {
+	Stream string // "stdout" or "stderr"
+	Text   string
 }
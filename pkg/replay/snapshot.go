@@ -1,5 +1,7 @@
 package replay
 
+import "encoding/json"
+
 // StateSnapshot is a serializable representation of the engine's internal
 // state. Use it to persist state between invocations (e.g., to file) or
 // to initialise an engine from previously saved progress.
@@ -9,6 +11,21 @@ type StateSnapshot struct {
 	StepCounts  []int
 	ActiveGroup *int
 	Captures    map[string]string
+	// Prev is the most recently served response, or nil before the first.
+	Prev *Served
+	// Data is opaque state kept by the caller driving the engine, such as
+	// the CLI's call timings. The engine carries it through unchanged.
+	Data json.RawMessage
+}
+
+// Served describes a response served for a matched step. The most recent
+// one is exposed to templates as .prev (.prev.stdout, .prev.stderr,
+// .prev.exit, .prev.argv).
+type Served struct {
+	Argv   []string `json:"argv"`
+	Stdout string   `json:"stdout,omitempty"`
+	Stderr string   `json:"stderr,omitempty"`
+	Exit   int      `json:"exit"`
 }
 
 // WithInitialState seeds the engine with a previously persisted state snapshot.
@@ -23,7 +40,11 @@ func WithInitialState(snap StateSnapshot) Option {
 func (e *Engine) Snapshot() StateSnapshot {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	return e.snapshotLocked()
+}
 
+// snapshotLocked is Snapshot for callers already holding e.mu.
+func (e *Engine) snapshotLocked() StateSnapshot {
 	var ag *int
 	if e.st.activeGroup != nil {
 		v := *e.st.activeGroup
@@ -39,5 +60,7 @@ func (e *Engine) Snapshot() StateSnapshot {
 		StepCounts:  counts,
 		ActiveGroup: ag,
 		Captures:    e.st.snapshotCaptures(),
+		Prev:        e.st.prev.clone(),
+		Data:        append(json.RawMessage(nil), e.st.data...),
 	}
 }
@@ -1,6 +1,8 @@
 package replay
 
 import (
+	"encoding/json"
+
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 )
 
@@ -13,6 +15,8 @@ type state struct {
 	stepCounts  []int
 	activeGroup *int
 	captures    map[string]string
+	prev        *Served
+	data        json.RawMessage // StateSnapshot.Data, carried through
 }
 
 func newState(totalSteps int) *state {
@@ -24,6 +28,27 @@ func newState(totalSteps int) *state {
 	}
 }
 
+// restoreState builds a state for totalSteps flat steps from a snapshot.
+// Step counts are only restored when their length matches.
+func restoreState(totalSteps int, snap *StateSnapshot) *state {
+	st := newState(totalSteps)
+	st.currentStep = snap.CurrentStep
+	st.totalSteps = snap.TotalSteps
+	if len(snap.StepCounts) == len(st.stepCounts) {
+		copy(st.stepCounts, snap.StepCounts)
+	}
+	if snap.ActiveGroup != nil {
+		v := *snap.ActiveGroup
+		st.activeGroup = &v
+	}
+	for k, v := range snap.Captures {
+		st.captures[k] = v
+	}
+	st.prev = snap.Prev.clone()
+	st.data = append(json.RawMessage(nil), snap.Data...)
+	return st
+}
+
 func (s *state) isComplete() bool {
 	return s.currentStep >= s.totalSteps
 }
@@ -87,3 +112,33 @@ func (s *state) snapshotCaptures() map[string]string {
 	}
 	return out
 }
+
+// clone returns a deep copy of s (nil for nil).
+func (s *Served) clone() *Served {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.Argv = append([]string(nil), s.Argv...)
+	return &c
+}
+
+// prevData returns the template value of .prev. Every key is present so
+// that references on the first step render as empty rather than
+// "<no value>".
+func (s *state) prevData() map[string]interface{} {
+	p := s.prev
+	if p == nil {
+		p = &Served{}
+	}
+	argv := p.Argv
+	if argv == nil {
+		argv = []string{}
+	}
+	return map[string]interface{}{
+		"stdout": p.Stdout,
+		"stderr": p.Stderr,
+		"exit":   p.Exit,
+		"argv":   argv,
+	}
+}
@@ -0,0 +1,134 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/ormasoftchile/cli-replay/internal/fixture"
+	"github.com/ormasoftchile/cli-replay/pkg/scenario"
+)
+
+// StateStore persists engine state between matches. An engine with a store
+// holds the store's lock while it reads the stored snapshot, matches and
+// writes the new snapshot back, so engines sharing a store advance through
+// the same session without losing updates. The CLI keeps its sessions in a
+// file-backed StateStore.
+type StateStore interface {
+	// Lock takes an exclusive lock for a read-modify-write of the snapshot
+	// and returns the function that releases it.
+	Lock() (func(), error)
+	// Read returns the stored snapshot, or nil if nothing is stored yet.
+	Read() (*StateSnapshot, error)
+	// Write replaces the stored snapshot.
+	Write(snap StateSnapshot) error
+	// Delete removes the stored snapshot. Deleting an empty store is not
+	// an error.
+	Delete() error
+	// Location describes where the snapshot is kept, for messages.
+	Location() string
+}
+
+// MemoryStore is a StateStore that keeps the snapshot in memory. It is safe
+// for concurrent use and leaves nothing on disk.
+type MemoryStore struct {
+	lock sync.Mutex // held by Lock
+	mu   sync.Mutex // guards snap
+	snap *StateSnapshot
+}
+
+// NewMemoryStore returns an empty in-memory state store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Lock takes the store's lock.
+func (m *MemoryStore) Lock() (func(), error) {
+	m.lock.Lock()
+	return m.lock.Unlock, nil
+}
+
+// Read returns a copy of the stored snapshot, or nil if none is stored.
+func (m *MemoryStore) Read() (*StateSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.snap == nil {
+		return nil, nil
+	}
+	c := m.snap.clone()
+	return &c, nil
+}
+
+// Write stores a copy of snap.
+func (m *MemoryStore) Write(snap StateSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := snap.clone()
+	m.snap = &c
+	return nil
+}
+
+// Delete clears the stored snapshot.
+func (m *MemoryStore) Delete() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snap = nil
+	return nil
+}
+
+// Location returns "memory".
+func (m *MemoryStore) Location() string { return "memory" }
+
+// WithStateStore makes the engine read and write its state through store.
+// A snapshot already in the store takes precedence over WithInitialState.
+func WithStateStore(store StateStore) Option {
+	return func(c *engineConfig) {
+		c.store = store
+	}
+}
+
+// Open loads the scenario file at path and creates an engine for it. Unlike
+// New, the engine reads stdout_file/stderr_file fixtures from disk the same
+// way the CLI does: relative to the scenario's fixtures root, with $VAR
+// references expanded, glob patterns resolved to the newest match and .gz
+// files decompressed. Options are applied after the default file reader,
+// so WithFileReader still overrides it.
+func Open(path string, opts ...Option) (*Engine, error) {
+	scn, err := scenario.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve scenario path: %w", err)
+	}
+	dir := filepath.Dir(absPath)
+	reader := WithFileReader(func(rel string) (string, error) {
+		fullPath, err := fixture.Path(scn, dir, rel)
+		if err != nil {
+			return "", err
+		}
+		return fixture.Read(fullPath, rel)
+	})
+	return New(scn, append([]Option{reader}, opts...)...), nil
+}
+
+// clone returns a deep copy of the snapshot.
+func (s StateSnapshot) clone() StateSnapshot {
+	c := s
+	c.StepCounts = append([]int(nil), s.StepCounts...)
+	if s.ActiveGroup != nil {
+		v := *s.ActiveGroup
+		c.ActiveGroup = &v
+	}
+	if s.Captures != nil {
+		c.Captures = make(map[string]string, len(s.Captures))
+		for k, v := range s.Captures {
+			c.Captures[k] = v
+		}
+	}
+	c.Prev = s.Prev.clone()
+	c.Data = append(json.RawMessage(nil), s.Data...)
+	return c
+}
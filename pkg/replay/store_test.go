@@ -0,0 +1,231 @@
+package replay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_MemoryStore_MultiStep(t *testing.T) {
+	scn := buildScenario("in-process",
+		leafStepWithCapture([]string{"az", "group", "create"}, `{"id": "rg-1"}`, 0, map[string]string{"rg": "rg-1"}),
+		leafStep([]string{"az", "vm", "create"}, "vm in {{ .capture.rg }}\n", 0),
+		leafStep([]string{"az", "group", "delete"}, "", 0),
+	)
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	// Each command gets a fresh engine, as separate intercept processes would
+	r, err := New(scn, WithStateStore(store)).Match(ctx, "az", []string{"group", "create"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.StepIndex)
+
+	r, err = New(scn, WithStateStore(store)).Match(ctx, "az", []string{"vm", "create"})
+	require.NoError(t, err)
+	assert.Equal(t, "vm in rg-1\n", r.Stdout)
+
+	eng := New(scn, WithStateStore(store))
+	_, err = eng.Match(ctx, "az", []string{"group", "delete"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, eng.Remaining())
+
+	snap, err := store.Read()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	assert.Equal(t, []int{1, 1, 1}, snap.StepCounts)
+	assert.Equal(t, 3, snap.CurrentStep)
+
+	_, err = New(scn, WithStateStore(store)).Match(ctx, "az", []string{"group", "create"})
+	var cErr *ScenarioCompleteError
+	require.ErrorAs(t, err, &cErr)
+
+	require.NoError(t, eng.Reset())
+	snap, err = store.Read()
+	require.NoError(t, err)
+	assert.Nil(t, snap)
+	_, err = New(scn, WithStateStore(store)).Match(ctx, "az", []string{"group", "create"})
+	require.NoError(t, err)
+}
+
+func TestEngine_MemoryStore_MismatchLeavesState(t *testing.T) {
+	scn := buildScenario("mismatch",
+		leafStep([]string{"cmd", "one"}, "", 0),
+		leafStep([]string{"cmd", "two"}, "", 0),
+	)
+	store := NewMemoryStore()
+	eng := New(scn, WithStateStore(store))
+	ctx := context.Background()
+
+	_, err := eng.Match(ctx, "cmd", []string{"one"})
+	require.NoError(t, err)
+	_, err = eng.Match(ctx, "cmd", []string{"three"})
+	var mErr *MismatchError
+	require.ErrorAs(t, err, &mErr)
+
+	snap, err := store.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, snap.StepCounts)
+}
+
+func TestMemoryStore_ReturnsCopies(t *testing.T) {
+	store := NewMemoryStore()
+	require.NoError(t, store.Write(StateSnapshot{StepCounts: []int{1}, Captures: map[string]string{"k": "v"}}))
+
+	snap, err := store.Read()
+	require.NoError(t, err)
+	snap.StepCounts[0] = 9
+	snap.Captures["k"] = "changed"
+
+	again, err := store.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, again.StepCounts)
+	assert.Equal(t, "v", again.Captures["k"])
+}
+
+type failingStore struct{ MemoryStore }
+
+func (f *failingStore) Write(StateSnapshot) error { return errors.New("disk full") }
+
+func TestEngine_StoreWriteError(t *testing.T) {
+	scn := buildScenario("failing", leafStep([]string{"cmd"}, "out\n", 0))
+	_, err := New(scn, WithStateStore(&failingStore{})).Match(context.Background(), "cmd", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to write state: disk full")
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pods.txt"), []byte("web-0\n"), 0600))
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: opened
+steps:
+  - match:
+      argv: ["kubectl", "get", "pods"]
+    respond:
+      exit: 0
+      stdout_file: pods.txt
+  - match:
+      argv: ["kubectl", "get", "secrets"]
+    respond:
+      exit: 0
+      stdout_file: ../secrets.txt
+`), 0600))
+
+	eng, err := Open(path, WithStateStore(NewMemoryStore()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	r, err := eng.Match(ctx, "kubectl", []string{"get", "pods"})
+	require.NoError(t, err)
+	assert.Equal(t, "web-0\n", r.Stdout)
+
+	_, err = eng.Match(ctx, "kubectl", []string{"get", "secrets"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fixture path escapes")
+
+	_, err = Open(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestOpen_ResolvesFixturesLikeTheCLI(t *testing.T) {
+	dir := t.TempDir()
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte("compressed\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt.gz"), gz.Bytes(), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "prod"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prod", "pods.txt"), []byte("from env\n"), 0600))
+	older := filepath.Join(dir, "run-1.log")
+	require.NoError(t, os.WriteFile(older, []byte("old\n"), 0600))
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(older, past, past))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run-2.log"), []byte("newest\n"), 0600))
+	t.Setenv("FIXTURE_SET", filepath.Join(dir, "prod"))
+
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meta:
+  name: fixtures
+steps:
+  - match:
+      argv: ["cat", "big"]
+    respond:
+      exit: 0
+      stdout_file: big.txt.gz
+  - match:
+      argv: ["cat", "pods"]
+    respond:
+      exit: 0
+      stdout_file: ${FIXTURE_SET}/pods.txt
+  - match:
+      argv: ["cat", "log"]
+    respond:
+      exit: 0
+      stdout_file: run-*.log
+`), 0600))
+
+	eng, err := Open(path, WithStateStore(NewMemoryStore()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	r, err := eng.Match(ctx, "cat", []string{"big"})
+	require.NoError(t, err)
+	assert.Equal(t, "compressed\n", r.Stdout)
+
+	r, err = eng.Match(ctx, "cat", []string{"pods"})
+	require.NoError(t, err)
+	assert.Equal(t, "from env\n", r.Stdout)
+
+	r, err = eng.Match(ctx, "cat", []string{"log"})
+	require.NoError(t, err)
+	assert.Equal(t, "newest\n", r.Stdout)
+}
+
+func TestEngine_SharedStore_ConcurrentMatches(t *testing.T) {
+	const calls = 50
+	scn := buildScenario("concurrent", leafStepWithCalls([]string{"poll"}, "", 0, 1, calls))
+	store := NewMemoryStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := New(scn, WithStateStore(store)).Match(context.Background(), "poll", nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	snap, err := store.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []int{calls}, snap.StepCounts, "the store lock keeps every increment")
+	assert.Equal(t, "memory", store.Location())
+}
+
+func TestEngine_StoreOverridesInitialState(t *testing.T) {
+	scn := buildScenario("precedence",
+		leafStep([]string{"cmd", "one"}, "", 0),
+		leafStep([]string{"cmd", "two"}, "", 0),
+	)
+	store := NewMemoryStore()
+	require.NoError(t, store.Write(StateSnapshot{CurrentStep: 1, TotalSteps: 2, StepCounts: []int{1, 0}}))
+
+	eng := New(scn, WithStateStore(store), WithInitialState(StateSnapshot{TotalSteps: 2, StepCounts: []int{0, 0}}))
+	r, err := eng.Match(context.Background(), "cmd", []string{"two"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.StepIndex)
+}
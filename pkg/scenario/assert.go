@@ -0,0 +1,45 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ResponseAssert holds expectations on a step's rendered output, checked on
+// every call and reported by verify. They guard against templates or
+// fixtures that unexpectedly render empty or wrong.
+type ResponseAssert struct {
+	StdoutMinBytes int    `yaml:"stdout_min_bytes,omitempty"`
+	StdoutContains string `yaml:"stdout_contains,omitempty"`
+	StderrMinBytes int    `yaml:"stderr_min_bytes,omitempty"`
+	StderrContains string `yaml:"stderr_contains,omitempty"`
+}
+
+// Validate checks that the byte minimums are not negative.
+func (a *ResponseAssert) Validate() error {
+	if a.StdoutMinBytes < 0 {
+		return errors.New("stdout_min_bytes must be >= 0")
+	}
+	if a.StderrMinBytes < 0 {
+		return errors.New("stderr_min_bytes must be >= 0")
+	}
+	return nil
+}
+
+// Check evaluates the assertions against the rendered stdout and stderr of
+// one call. It returns a description of the first failed assertion, or ""
+// when all of them pass.
+func (a *ResponseAssert) Check(stdout, stderr string) string {
+	switch {
+	case len(stdout) < a.StdoutMinBytes:
+		return fmt.Sprintf("stdout is %d bytes, stdout_min_bytes is %d", len(stdout), a.StdoutMinBytes)
+	case a.StdoutContains != "" && !strings.Contains(stdout, a.StdoutContains):
+		return fmt.Sprintf("stdout does not contain %q", a.StdoutContains)
+	case len(stderr) < a.StderrMinBytes:
+		return fmt.Sprintf("stderr is %d bytes, stderr_min_bytes is %d", len(stderr), a.StderrMinBytes)
+	case a.StderrContains != "" && !strings.Contains(stderr, a.StderrContains):
+		return fmt.Sprintf("stderr does not contain %q", a.StderrContains)
+	}
+	return ""
+}
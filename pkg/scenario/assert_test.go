@@ -0,0 +1,31 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseAssert_Check(t *testing.T) {
+	a := &ResponseAssert{StdoutMinBytes: 5, StdoutContains: "Running", StderrContains: "warn"}
+
+	assert.Empty(t, a.Check("web-0 Running\n", "warn: deprecated\n"))
+	assert.Equal(t, "stdout is 0 bytes, stdout_min_bytes is 5", a.Check("", "warn"))
+	assert.Equal(t, `stdout does not contain "Running"`, a.Check("web-0 Pending\n", "warn"))
+	assert.Equal(t, `stderr does not contain "warn"`, a.Check("web-0 Running\n", ""))
+}
+
+func TestResponseAssert_Validate(t *testing.T) {
+	require.NoError(t, (&ResponseAssert{StdoutMinBytes: 1}).Validate())
+
+	r := Response{Assert: &ResponseAssert{StdoutMinBytes: -1}}
+	err := r.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "assert: stdout_min_bytes must be >= 0")
+
+	w := WeightedResponse{Weight: 1, Response: Response{Assert: &ResponseAssert{StdoutContains: "x"}}}
+	err = w.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "assert belongs on the enclosing respond")
+}
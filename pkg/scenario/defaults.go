@@ -0,0 +1,185 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults holds scenario-wide values merged into every step at load time.
+type Defaults struct {
+	Respond *Response `yaml:"respond,omitempty"`
+}
+
+// Validate checks that the defaults section is valid.
+func (d *Defaults) Validate() error {
+	if d.Respond == nil {
+		return nil
+	}
+	if err := d.Respond.Validate(); err != nil {
+		return fmt.Errorf("respond: %w", err)
+	}
+	if len(d.Respond.Capture) > 0 {
+		return errors.New("respond: capture is not supported (captures are per step)")
+	}
+	return nil
+}
+
+// respondSlots groups the respond keys that fill the same role. A step that
+// sets any key of a slot keeps that slot entirely, so a step with
+// stdout_file never inherits a default stdout (they are mutually exclusive)
+// and an explicit `exit: 0` is never replaced by a default exit. random
+// and output replace every output field, so each counts as setting each
+// output slot.
+var respondSlots = []struct {
+	keys  []string
+	apply func(dst *Response, src *Response)
+}{
+	{[]string{"exit", "exit_template", "random"}, func(dst, src *Response) {
+		dst.Exit, dst.ExitTemplate = src.Exit, src.ExitTemplate
+	}},
+	{[]string{"stdout", "stdout_file", "random", "output"}, func(dst, src *Response) {
+		dst.Stdout, dst.StdoutFile = src.Stdout, src.StdoutFile
+	}},
+	{[]string{"stderr", "stderr_file", "random", "output"}, func(dst, src *Response) {
+		dst.Stderr, dst.StderrFile = src.Stderr, src.StderrFile
+	}},
+	{[]string{"prepend", "random", "output"}, func(dst, src *Response) { dst.Prepend = src.Prepend }},
+	{[]string{"append", "random", "output"}, func(dst, src *Response) { dst.Append = src.Append }},
+	{[]string{"output", "stdout", "stdout_file", "stderr", "stderr_file", "prepend", "append", "random"}, func(dst, src *Response) {
+		dst.Output = src.Output
+	}},
+	{[]string{"random", "output"}, func(dst, src *Response) { dst.Random = src.Random }},
+	{[]string{"delay", "jitter"}, func(dst, src *Response) { dst.Delay, dst.Jitter = src.Delay, src.Jitter }},
+	{[]string{"timeout"}, func(dst, src *Response) { dst.Timeout = src.Timeout }},
+	{[]string{"retry_after"}, func(dst, src *Response) { dst.RetryAfter = src.RetryAfter }},
+	{[]string{"capture"}, func(dst, src *Response) { dst.Capture = src.Capture }},
+	{[]string{"assert"}, func(dst, src *Response) { dst.Assert = src.Assert }},
+}
+
+// needsResponseResolution reports whether load must resolve defaults or
+// response references before validation.
+func (s *Scenario) needsResponseResolution() bool {
+	if s.Meta.Defaults != nil && s.Meta.Defaults.Respond != nil {
+		return true
+	}
+	for _, step := range s.FlatSteps() {
+		if step.RespondRef != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveResponses merges response layers into every local step (group
+// children included). For each slot, precedence is: the step's own
+// respond, then the meta.responses entry named by respond_ref, then
+// meta.defaults.respond. Presence is read from the document node rather
+// than the decoded values, since a decoded empty stdout or zero delay
+// cannot be told apart from an omitted one. Resolved references are
+// cleared; unknown ones are left for Validate to report. Steps spliced in
+// by includes are not affected; they were resolved against their own
+// file's meta.
+func (s *Scenario) resolveResponses(doc *yaml.Node) {
+	root := documentRoot(doc)
+	responseNodes := mappingValue(mappingValue(root, "meta"), "responses")
+
+	stepNodes := mappingValue(root, "steps")
+	var nodes []*yaml.Node
+	if stepNodes != nil && stepNodes.Kind == yaml.SequenceNode {
+		nodes = stepNodes.Content
+	}
+
+	resolve := func(step *Step, node *yaml.Node) {
+		set := presentSlots(mappingValue(node, "respond"))
+		if step.RespondRef != "" {
+			tpl, ok := s.Meta.Responses[step.RespondRef]
+			if !ok {
+				return
+			}
+			tplSet := presentSlots(mappingValue(responseNodes, step.RespondRef))
+			for i, slot := range respondSlots {
+				if !set[i] && tplSet[i] {
+					slot.apply(&step.Respond, &tpl)
+					set[i] = true
+				}
+			}
+			step.RespondRef = ""
+		}
+		if s.Meta.Defaults != nil && s.Meta.Defaults.Respond != nil {
+			for i, slot := range respondSlots {
+				if !set[i] {
+					slot.apply(&step.Respond, s.Meta.Defaults.Respond)
+				}
+			}
+		}
+	}
+
+	for i := range s.Steps {
+		var node *yaml.Node
+		if i < len(nodes) {
+			node = nodes[i]
+		}
+		elem := &s.Steps[i]
+		if elem.Group != nil {
+			children := mappingValue(mappingValue(node, "group"), "steps")
+			for j := range elem.Group.Steps {
+				var child *yaml.Node
+				if children != nil && j < len(children.Content) {
+					child = children.Content[j]
+				}
+				if elem.Group.Steps[j].Step != nil {
+					resolve(elem.Group.Steps[j].Step, child)
+				}
+			}
+			continue
+		}
+		if elem.Step != nil {
+			resolve(elem.Step, node)
+		}
+	}
+}
+
+// presentSlots reports, per respondSlots entry, whether the respond mapping
+// sets any of the slot's keys.
+func presentSlots(respondNode *yaml.Node) []bool {
+	set := make([]bool, len(respondSlots))
+	for i, slot := range respondSlots {
+		for _, key := range slot.keys {
+			if mappingValue(respondNode, key) != nil {
+				set[i] = true
+				break
+			}
+		}
+	}
+	return set
+}
+
+// documentRoot unwraps a document node to its top-level content node.
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc != nil && doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil if
+// node is not a mapping or has no such key.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			if v := node.Content[i+1]; v.Kind == yaml.AliasNode {
+				return v.Alias
+			}
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
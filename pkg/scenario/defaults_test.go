@@ -0,0 +1,336 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_DefaultsRespondInherited(t *testing.T) {
+	yaml := `
+meta:
+  name: defaults
+  defaults:
+    respond:
+      exit: 3
+      stdout: "ok\n"
+      delay: 10ms
+steps:
+  - match:
+      argv: ["cmd", "inherit"]
+  - match:
+      argv: ["cmd", "partial"]
+    respond:
+      stderr: "warning"
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+	require.Len(t, sc.Steps, 2)
+
+	inherit := sc.Steps[0].Step.Respond
+	assert.Equal(t, 3, inherit.EffectiveExit())
+	assert.Equal(t, "ok\n", inherit.Stdout)
+	assert.Equal(t, "10ms", inherit.Delay)
+
+	partial := sc.Steps[1].Step.Respond
+	assert.Equal(t, 3, partial.EffectiveExit())
+	assert.Equal(t, "ok\n", partial.Stdout)
+	assert.Equal(t, "warning", partial.Stderr)
+}
+
+func TestLoad_DefaultsRetryAfter(t *testing.T) {
+	yaml := `
+meta:
+  name: defaults
+  defaults:
+    respond:
+      retry_after: 2s
+steps:
+  - match:
+      argv: ["cmd", "inherit"]
+  - match:
+      argv: ["cmd", "override"]
+    respond:
+      retry_after: 5s
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+	require.Len(t, sc.Steps, 2)
+	assert.Equal(t, "2s", sc.Steps[0].Step.Respond.RetryAfter)
+	assert.Equal(t, "5s", sc.Steps[1].Step.Respond.RetryAfter)
+}
+
+func TestLoad_DefaultsOutput(t *testing.T) {
+	yaml := `
+meta:
+  name: defaults
+  defaults:
+    respond:
+      stdout: "default out"
+      stderr: "default err"
+steps:
+  - match:
+      argv: ["cmd", "interleaved"]
+    respond:
+      output:
+        - stream: stderr
+          text: "progress"
+        - stream: stdout
+          text: "data"
+  - match:
+      argv: ["cmd", "plain"]
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+	require.Len(t, sc.Steps, 2)
+	r := sc.Steps[0].Step.Respond
+	assert.Empty(t, r.Stdout, "output keeps the step from inheriting stdout")
+	assert.Empty(t, r.Stderr)
+	assert.Equal(t, []OutputChunk{{Stream: "stderr", Text: "progress"}, {Stream: "stdout", Text: "data"}}, r.Output)
+	assert.Equal(t, "default out", sc.Steps[1].Step.Respond.Stdout)
+}
+
+func TestLoad_DefaultsPrependAppend(t *testing.T) {
+	yaml := `
+meta:
+  name: defaults
+  defaults:
+    respond:
+      prepend: "STATUS 200"
+      append: "END"
+steps:
+  - match:
+      argv: ["cmd", "inherit"]
+    respond:
+      stdout: "body"
+  - match:
+      argv: ["cmd", "override"]
+    respond:
+      prepend: "STATUS 404"
+      append: ""
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+
+	inherit := sc.Steps[0].Step.Respond
+	assert.Equal(t, "STATUS 200", inherit.Prepend)
+	assert.Equal(t, "END", inherit.Append)
+
+	override := sc.Steps[1].Step.Respond
+	assert.Equal(t, "STATUS 404", override.Prepend)
+	assert.Empty(t, override.Append, "an explicit empty append is kept")
+}
+
+func TestLoad_DefaultsNotMergedIntoRandom(t *testing.T) {
+	yaml := `
+meta:
+  name: defaults
+  defaults:
+    respond:
+      exit: 0
+      stdout: "ok"
+      delay: 10ms
+steps:
+  - match:
+      argv: ["cmd"]
+    respond:
+      random:
+        - weight: 1
+          response: {exit: 1, stderr: "boom"}
+        - weight: 1
+          response: {stdout: "fine"}
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+
+	r := sc.Steps[0].Step.Respond
+	assert.Empty(t, r.Stdout, "random replaces the default output")
+	assert.Equal(t, "10ms", r.Delay, "non-output defaults still apply")
+	require.Len(t, r.Random, 2)
+	assert.Equal(t, "boom", r.Random[0].Response.Stderr)
+}
+
+func TestLoad_DefaultsRespondOverridden(t *testing.T) {
+	yaml := `
+meta:
+  name: defaults
+  defaults:
+    respond:
+      exit: 1
+      stdout: "default"
+      stderr: "default err"
+steps:
+  - match:
+      argv: ["cmd", "override"]
+    respond:
+      exit: 0
+      stdout_file: out.txt
+      stderr: ""
+  - match:
+      argv: ["cmd", "template"]
+    respond:
+      exit_template: "{{ .code }}"
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+
+	override := sc.Steps[0].Step.Respond
+	assert.Equal(t, Exit(0), override.Exit, "explicit exit: 0 must not be replaced by the default")
+	assert.Empty(t, override.Stdout, "stdout_file keeps the stdout slot")
+	assert.Equal(t, "out.txt", override.StdoutFile)
+	assert.Empty(t, override.Stderr, "explicit empty stderr must not be replaced")
+
+	template := sc.Steps[1].Step.Respond
+	assert.Nil(t, template.Exit, "exit_template keeps the exit slot")
+	assert.Equal(t, "{{ .code }}", template.ExitTemplate)
+	assert.Equal(t, "default", template.Stdout)
+}
+
+func TestLoad_DefaultsRespondAppliesToGroupChildren(t *testing.T) {
+	yaml := `
+meta:
+  name: defaults
+  defaults:
+    respond:
+      exit: 2
+steps:
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: ["a"]
+        - match:
+            argv: ["b"]
+          respond:
+            exit: 0
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+
+	steps := sc.FlatSteps()
+	require.Len(t, steps, 2)
+	assert.Equal(t, 2, steps[0].Respond.EffectiveExit())
+	assert.Equal(t, 0, steps[1].Respond.EffectiveExit())
+}
+
+func TestLoad_DefaultsMergedStepStillValidated(t *testing.T) {
+	yaml := `
+meta:
+  name: defaults
+  defaults:
+    respond:
+      exit: 4
+steps:
+  - match:
+      argv: ["cmd"]
+    respond:
+      exit_template: "{{ .code }}"
+      timeout: "-1s"
+`
+	_, err := Load(strings.NewReader(yaml))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+}
+
+func TestDefaults_Validate(t *testing.T) {
+	valid := Defaults{Respond: &Response{Exit: Exit(1), Stdout: "ok"}}
+	assert.NoError(t, valid.Validate())
+
+	badExit := Defaults{Respond: &Response{Exit: Exit(300)}}
+	assert.ErrorContains(t, badExit.Validate(), "respond: exit must be in range 0-255")
+
+	withCapture := Defaults{Respond: &Response{Capture: map[string]string{"id": "x"}}}
+	assert.ErrorContains(t, withCapture.Validate(), "capture is not supported")
+
+	meta := Meta{Name: "m", Defaults: &Defaults{Respond: &Response{Exit: Exit(300)}}}
+	assert.ErrorContains(t, meta.Validate(), "defaults: respond:")
+}
+
+func TestLoad_RespondRefResolved(t *testing.T) {
+	yaml := `
+meta:
+  name: refs
+  responses:
+    not-found:
+      exit: 1
+      stderr: "Error from server (NotFound)\n"
+steps:
+  - match:
+      argv: ["kubectl", "get", "pod", "a"]
+    respond_ref: not-found
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: ["kubectl", "get", "pod", "b"]
+          respond_ref: not-found
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+
+	for i, step := range sc.FlatSteps() {
+		assert.Equal(t, 1, step.Respond.EffectiveExit(), "step %d", i)
+		assert.Equal(t, "Error from server (NotFound)\n", step.Respond.Stderr, "step %d", i)
+		assert.Empty(t, step.RespondRef, "step %d: resolved ref should be cleared", i)
+	}
+}
+
+func TestLoad_RespondRefOverriddenByStep(t *testing.T) {
+	yaml := `
+meta:
+  name: refs
+  defaults:
+    respond:
+      delay: 5ms
+      stdout: "default\n"
+  responses:
+    failure:
+      exit: 2
+      stderr: "boom\n"
+      delay: 1ms
+steps:
+  - match:
+      argv: ["cmd"]
+    respond_ref: failure
+    respond:
+      exit: 0
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+
+	r := sc.Steps[0].Step.Respond
+	assert.Equal(t, 0, r.EffectiveExit(), "step respond overrides the template")
+	assert.Equal(t, "boom\n", r.Stderr, "template fills what the step leaves unset")
+	assert.Equal(t, "1ms", r.Delay, "template takes precedence over defaults")
+	assert.Equal(t, "default\n", r.Stdout, "defaults fill what neither sets")
+}
+
+func TestLoad_RespondRefUndefined(t *testing.T) {
+	yaml := `
+meta:
+  name: refs
+  responses:
+    ok:
+      exit: 0
+steps:
+  - match:
+      argv: ["cmd"]
+    respond_ref: missing
+`
+	_, err := Load(strings.NewReader(yaml))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `step 0: respond_ref "missing" is not defined in meta.responses`)
+}
+
+func TestMeta_ResponsesValidation(t *testing.T) {
+	valid := Meta{Name: "m", Responses: map[string]Response{"ok": {Exit: Exit(0)}}}
+	assert.NoError(t, valid.Validate())
+
+	bad := Meta{Name: "m", Responses: map[string]Response{"bad": {Exit: Exit(300)}}}
+	assert.ErrorContains(t, bad.Validate(), "responses.bad: exit must be in range 0-255")
+
+	unnamed := Meta{Name: "m", Responses: map[string]Response{" ": {}}}
+	assert.ErrorContains(t, unnamed.Validate(), "name must be non-empty")
+}
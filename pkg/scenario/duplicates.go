@@ -0,0 +1,80 @@
+package scenario
+
+import "reflect"
+
+// DuplicateRun describes a run of consecutive, exactly identical ordered
+// steps that use default call bounds. Such runs are almost always a
+// copy-paste mistake and can be consolidated into a single step with
+// calls: {min: N, max: N}.
+type DuplicateRun struct {
+	Start int // Inclusive flat index of the first duplicate step
+	End   int // Exclusive flat index (Start + number of identical steps)
+}
+
+// Len returns the number of identical steps in the run.
+func (r DuplicateRun) Len() int {
+	return r.End - r.Start
+}
+
+// DuplicateAdjacentSteps scans the ordered steps of the scenario (top-level
+// steps and children of ordered groups) for runs of adjacent steps with
+// identical match criteria and responses and no explicit calls bounds.
+// Adjacent steps with different responses are the intentional per-call
+// cycling pattern and are not reported. Children of unordered groups are
+// not ordered relative to each other and are never reported.
+func (s *Scenario) DuplicateAdjacentSteps() []DuplicateRun {
+	var runs []DuplicateRun
+	flatIdx := 0
+	var top []indexedStep
+
+	flushTop := func() {
+		runs = append(runs, duplicateRuns(top)...)
+		top = nil
+	}
+
+	for _, elem := range s.Steps {
+		switch {
+		case elem.Step != nil:
+			top = append(top, indexedStep{flatIdx, elem.Step})
+			flatIdx++
+		case elem.Group != nil:
+			// A group is a boundary between top-level neighbours.
+			flushTop()
+			var children []indexedStep
+			for _, child := range elem.Group.Steps {
+				if child.Step == nil {
+					continue
+				}
+				children = append(children, indexedStep{flatIdx, child.Step})
+				flatIdx++
+			}
+			if elem.Group.Mode == GroupModeOrdered {
+				runs = append(runs, duplicateRuns(children)...)
+			}
+		}
+	}
+	flushTop()
+	return runs
+}
+
+// indexedStep pairs a step with its flat index.
+type indexedStep struct {
+	flat int
+	step *Step
+}
+
+// duplicateRuns returns the runs of identical adjacent steps in seq.
+func duplicateRuns(seq []indexedStep) []DuplicateRun {
+	var runs []DuplicateRun
+	for i := 0; i < len(seq); {
+		j := i + 1
+		for j < len(seq) && seq[i].step.Calls == nil && reflect.DeepEqual(seq[i].step, seq[j].step) {
+			j++
+		}
+		if j-i > 1 {
+			runs = append(runs, DuplicateRun{Start: seq[i].flat, End: seq[j-1].flat + 1})
+		}
+		i = j
+	}
+	return runs
+}
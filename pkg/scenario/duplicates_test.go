@@ -0,0 +1,139 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateAdjacentSteps(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []DuplicateRun
+	}{
+		{
+			name: "identical adjacent steps flagged as one run",
+			yaml: `
+meta:
+  name: dup
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+  - match:
+      argv: [b]
+    respond:
+      exit: 0
+  - match:
+      argv: [b]
+    respond:
+      exit: 0
+  - match:
+      argv: [b]
+    respond:
+      exit: 0
+`,
+			want: []DuplicateRun{{Start: 1, End: 4}},
+		},
+		{
+			name: "different responses allowed",
+			yaml: `
+meta:
+  name: cycling
+steps:
+  - match:
+      argv: [status]
+    respond:
+      exit: 0
+      stdout: pending
+  - match:
+      argv: [status]
+    respond:
+      exit: 0
+      stdout: done
+`,
+		},
+		{
+			name: "explicit calls bounds not flagged",
+			yaml: `
+meta:
+  name: bounds
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+    calls:
+      min: 1
+      max: 2
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+    calls:
+      min: 1
+      max: 2
+`,
+		},
+		{
+			name: "group boundary separates neighbours and unordered children ignored",
+			yaml: `
+meta:
+  name: groups
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: [a]
+          respond:
+            exit: 0
+        - match:
+            argv: [a]
+          respond:
+            exit: 0
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+`,
+		},
+		{
+			name: "ordered group children flagged",
+			yaml: `
+meta:
+  name: ordered
+steps:
+  - match:
+      argv: [x]
+    respond:
+      exit: 0
+  - group:
+      mode: ordered
+      steps:
+        - match:
+            argv: [a]
+          respond:
+            exit: 0
+        - match:
+            argv: [a]
+          respond:
+            exit: 0
+`,
+			want: []DuplicateRun{{Start: 1, End: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scn := mustLoad(t, tt.yaml)
+			assert.Equal(t, tt.want, scn.DuplicateAdjacentSteps())
+		})
+	}
+}
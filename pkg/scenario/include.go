@@ -0,0 +1,183 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Include positions control where an included fragment's steps are spliced
+// relative to the including scenario's local steps.
+const (
+	IncludeBefore = "before"
+	IncludeAfter  = "after"
+)
+
+// Include references a scenario fragment whose steps are spliced into the
+// including scenario at load time. In YAML an include is either a plain path
+// string (spliced before the local steps) or a mapping with path and position.
+type Include struct {
+	Path     string `yaml:"path"`
+	Position string `yaml:"position,omitempty"`
+}
+
+// EffectivePosition returns the include position, defaulting to "before".
+func (inc *Include) EffectivePosition() string {
+	if inc.Position == "" {
+		return IncludeBefore
+	}
+	return inc.Position
+}
+
+// Validate checks that the include entry is valid.
+func (inc *Include) Validate() error {
+	if strings.TrimSpace(inc.Path) == "" {
+		return errors.New("path must be non-empty")
+	}
+	switch inc.EffectivePosition() {
+	case IncludeBefore, IncludeAfter:
+		return nil
+	default:
+		return fmt.Errorf("invalid position %q: must be %q or %q", inc.Position, IncludeBefore, IncludeAfter)
+	}
+}
+
+// UnmarshalYAML accepts either a scalar path or a {path, position} mapping.
+func (inc *Include) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		inc.Path = value.Value
+		return nil
+	}
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: include must be a path or a mapping", value.Line)
+	}
+	for i := 0; i < len(value.Content)-1; i += 2 {
+		key := value.Content[i].Value
+		if key != "path" && key != "position" {
+			return fmt.Errorf("line %d: field %s not found in type include", value.Content[i].Line, key)
+		}
+	}
+	type rawInclude Include
+	var raw rawInclude
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	*inc = Include(raw)
+	return nil
+}
+
+// MarshalYAML emits the short scalar form when no position is set.
+func (inc Include) MarshalYAML() (interface{}, error) {
+	if inc.Position == "" {
+		return inc.Path, nil
+	}
+	type rawInclude Include
+	return rawInclude(inc), nil
+}
+
+// expandIncludes loads every included fragment (resolved relative to baseDir)
+// and splices its steps around the scenario's local steps. The scenario keeps
+// its own meta; vars from includes are merged underneath its own vars, and
+// security and session are taken from a fragment only when the scenario sets
+// none. The scenario's own argv is rendered against those merged vars,
+// with getenv overrides. The stack holds the absolute paths of the files
+// currently being loaded and is used to detect cycles.
+func (s *Scenario) expandIncludes(baseDir string, stack []string, info *LoadInfo, getenv func(string) string) error {
+	var before, after []*Scenario
+	for i := range s.Includes {
+		inc := &s.Includes[i]
+		if err := inc.Validate(); err != nil {
+			return fmt.Errorf("includes[%d]: %w", i, err)
+		}
+		path := inc.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		fragments, err := loadFile(path, stack, info)
+		if err != nil {
+			return fmt.Errorf("includes[%d] %q: %w", i, inc.Path, err)
+		}
+		fragment := fragments[0]
+		if inc.EffectivePosition() == IncludeAfter {
+			after = append(after, fragment)
+		} else {
+			before = append(before, fragment)
+		}
+	}
+
+	// The file's own argv is rendered once the fragments' vars are known,
+	// so its steps can use vars an include supplies. As in the merged
+	// meta, its own vars and security settings take precedence.
+	scope := &Scenario{Meta: Meta{Vars: make(map[string]string), Security: s.Meta.Security}, Steps: s.Steps}
+	for _, fragment := range append(append([]*Scenario(nil), before...), after...) {
+		for k, v := range fragment.Meta.Vars {
+			scope.Meta.Vars[k] = v
+		}
+		if s.Meta.Security == nil && fragment.Meta.Security != nil {
+			scope.Meta.Security = fragment.Meta.Security
+		}
+	}
+	for k, v := range s.Meta.Vars {
+		scope.Meta.Vars[k] = v
+	}
+	if err := scope.renderArgvVars(getenv); err != nil {
+		return err
+	}
+
+	local := &Scenario{Meta: s.Meta, Steps: s.Steps}
+	parts := make([]*Scenario, 0, len(before)+len(after)+1)
+	parts = append(parts, before...)
+	parts = append(parts, local)
+	parts = append(parts, after...)
+
+	merged, err := Merge(s.Meta.Name, parts...)
+	if err != nil {
+		return fmt.Errorf("includes: %w", err)
+	}
+
+	// The including scenario's own settings take precedence over fragments.
+	for k, v := range s.Meta.Vars {
+		merged.Meta.Vars[k] = v
+	}
+	meta := s.Meta
+	meta.Vars = merged.Meta.Vars
+	if meta.Security == nil {
+		meta.Security = merged.Meta.Security
+	}
+	if meta.Session == nil {
+		meta.Session = merged.Meta.Session
+	}
+
+	s.Meta = meta
+	s.Steps = merged.Steps
+	s.Includes = nil
+	return nil
+}
+
+// includeCycle returns a descriptive error if path is already on the stack.
+func includeCycle(path string, stack []string) error {
+	for i, p := range stack {
+		if p == path {
+			chain := make([]string, 0, len(stack)-i+1)
+			for _, q := range stack[i:] {
+				chain = append(chain, filepath.Base(q))
+			}
+			chain = append(chain, filepath.Base(path))
+			return fmt.Errorf("include cycle detected: %s", strings.Join(chain, " -> "))
+		}
+	}
+	return nil
+}
+
+// workingDir returns the current working directory, or "." if unavailable.
+func workingDir() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return wd
+}
@@ -0,0 +1,377 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScenarioFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func flatArgv0(scn *Scenario) []string {
+	var out []string
+	for _, step := range scn.FlatSteps() {
+		out = append(out, strings.Join(step.Match.Argv, " "))
+	}
+	return out
+}
+
+func TestLoadFile_IncludesTwoLevelFlattening(t *testing.T) {
+	dir := t.TempDir()
+	writeScenarioFile(t, dir, "fragments/login.yaml", `
+meta:
+  name: login
+  vars:
+    user: fragment-user
+steps:
+  - match:
+      argv: [az, login]
+    respond:
+      exit: 0
+`)
+	// setup.yaml includes login.yaml relative to its own directory
+	writeScenarioFile(t, dir, "fragments/setup.yaml", `
+meta:
+  name: setup
+includes:
+  - login.yaml
+steps:
+  - match:
+      argv: [az, account, set]
+    respond:
+      exit: 0
+`)
+	writeScenarioFile(t, dir, "fragments/teardown.yaml", `
+meta:
+  name: teardown
+steps:
+  - match:
+      argv: [az, logout]
+    respond:
+      exit: 0
+`)
+	main := writeScenarioFile(t, dir, "main.yaml", `
+meta:
+  name: main
+  vars:
+    user: main-user
+includes:
+  - fragments/setup.yaml
+  - path: fragments/teardown.yaml
+    position: after
+steps:
+  - match:
+      argv: [kubectl, apply]
+    respond:
+      exit: 0
+`)
+
+	scn, err := LoadFile(main)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"az login",
+		"az account set",
+		"kubectl apply",
+		"az logout",
+	}, flatArgv0(scn))
+	assert.Equal(t, "main", scn.Meta.Name)
+	assert.Equal(t, "main-user", scn.Meta.Vars["user"], "parent vars override included vars")
+	assert.Nil(t, scn.Includes, "includes are consumed by expansion")
+}
+
+func TestLoadFile_IncludeVarsInherited(t *testing.T) {
+	dir := t.TempDir()
+	writeScenarioFile(t, dir, "common.yaml", `
+meta:
+  name: common
+  vars:
+    region: eastus
+steps:
+  - match:
+      argv: [az, login]
+    respond:
+      exit: 0
+`)
+	main := writeScenarioFile(t, dir, "main.yaml", `
+meta:
+  name: main
+includes: [common.yaml]
+steps:
+  - match:
+      argv: [az, group, list]
+    respond:
+      exit: 0
+      stdout: "{{ .region }}"
+`)
+
+	scn, err := LoadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, "eastus", scn.Meta.Vars["region"])
+}
+
+func TestLoadFile_IncludeVarsInParentArgv(t *testing.T) {
+	dir := t.TempDir()
+	writeScenarioFile(t, dir, "frag.yaml", `
+meta:
+  name: frag
+  vars:
+    ns: staging
+    ctx: aks-dev
+steps:
+  - match:
+      argv: [kubectl, config, use-context, "{{ .ctx }}"]
+    respond:
+      exit: 0
+`)
+	main := writeScenarioFile(t, dir, "main.yaml", `
+meta:
+  name: main
+  vars:
+    ctx: aks-prod
+  security:
+    deny_env_vars: ["ctx"]
+includes: [frag.yaml]
+steps:
+  - match:
+      argv: [kubectl, -n, "{{ .ns }}", --context, "{{ .ctx }}", get, pods]
+    respond:
+      exit: 0
+`)
+
+	scn, err := LoadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"kubectl config use-context aks-dev",
+		"kubectl -n staging --context aks-prod get pods",
+	}, flatArgv0(scn), "fragment steps keep their own vars; the parent's argv sees the merged ones")
+
+	t.Setenv("ns", "prod")
+	t.Setenv("ctx", "denied")
+	scn, err = LoadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, "kubectl -n prod --context aks-prod get pods", flatArgv0(scn)[1],
+		"env overrides a fragment's var; deny_env_vars still blocks it")
+}
+
+func TestLoadFile_IncludeKeepsParentMeta(t *testing.T) {
+	dir := t.TempDir()
+	writeScenarioFile(t, dir, "common.yaml", `
+meta:
+  name: common
+  description: shared login
+  tags: [fragment]
+  security:
+    allowed_commands: [az, kubectl]
+steps:
+  - match:
+      argv: [az, login]
+`)
+	main := writeScenarioFile(t, dir, "main.yaml", `
+meta:
+  name: main
+  description: deploy flow
+  max_total_calls: 20
+  fixtures_dir: testdata
+  session:
+    ttl: 10m
+  defaults:
+    respond:
+      stderr: "warn\n"
+  responses:
+    ok:
+      stdout: "ok\n"
+includes: [common.yaml]
+steps:
+  - match:
+      argv: [kubectl, apply]
+    respond_ref: ok
+`)
+
+	scn, err := LoadFile(main)
+	require.NoError(t, err)
+	meta := scn.Meta
+	assert.Equal(t, "main", meta.Name)
+	assert.Equal(t, "deploy flow", meta.Description)
+	assert.Equal(t, 20, meta.MaxTotalCalls)
+	assert.Equal(t, "testdata", meta.FixturesDir)
+	require.NotNil(t, meta.Session)
+	assert.Equal(t, "10m", meta.Session.TTL)
+	require.NotNil(t, meta.Defaults)
+	assert.Contains(t, meta.Responses, "ok")
+	assert.Empty(t, meta.Tags, "fragment tags do not leak into the including file")
+	require.NotNil(t, meta.Security, "security falls back to the fragment's")
+	assert.Equal(t, []string{"az", "kubectl"}, meta.Security.AllowedCommands)
+
+	steps := scn.FlatSteps()
+	require.Len(t, steps, 2)
+	assert.Equal(t, "ok\n", steps[1].Respond.Stdout)
+	assert.Equal(t, "warn\n", steps[1].Respond.Stderr)
+}
+
+func TestLoadFile_IncludeKeepsMetaFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		meta  string
+		check func(t *testing.T, meta Meta)
+	}{
+		{"fallback", "  fallback:\n    stdout: \"unmatched\\n\"\n", func(t *testing.T, meta Meta) {
+			require.NotNil(t, meta.Fallback)
+			assert.Equal(t, "unmatched\n", meta.Fallback.Stdout)
+		}},
+		{"limits", "  limits:\n    max_stdin_bytes: 4096\n    max_consecutive_mismatches: 5\n", func(t *testing.T, meta Meta) {
+			require.NotNil(t, meta.Limits)
+			assert.Equal(t, int64(4096), meta.Limits.MaxStdinBytes)
+			assert.Equal(t, 5, meta.Limits.MaxConsecutiveMismatches)
+		}},
+		{"strip_prefixes", "  strip_prefixes: [sudo, env]\n", func(t *testing.T, meta Meta) {
+			assert.Equal(t, []string{"sudo", "env"}, meta.StripPrefixes)
+		}},
+		{"exit_codes", "  exit_codes:\n    mismatch: 90\n    complete: 91\n", func(t *testing.T, meta Meta) {
+			assert.Equal(t, 90, meta.MismatchExitCode())
+			assert.Equal(t, 91, meta.CompleteExitCode())
+		}},
+		{"tags", "  tags: [smoke, k8s]\n", func(t *testing.T, meta Meta) {
+			assert.Equal(t, []string{"smoke", "k8s"}, meta.Tags)
+			assert.True(t, meta.HasTag("smoke"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeScenarioFile(t, dir, "common.yaml", "meta:\n  name: common\nsteps:\n  - match:\n      argv: [az, login]\n")
+			main := writeScenarioFile(t, dir, "main.yaml", "meta:\n  name: main\n"+tt.meta+
+				"includes: [common.yaml]\nsteps:\n  - match:\n      argv: [kubectl, apply]\n")
+
+			scn, err := LoadFile(main)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"az login", "kubectl apply"}, flatArgv0(scn))
+			tt.check(t, scn.Meta)
+		})
+	}
+}
+
+func TestLoadFile_IncludeOnlyScenario(t *testing.T) {
+	dir := t.TempDir()
+	writeScenarioFile(t, dir, "a.yaml", "meta:\n  name: a\nsteps:\n  - match:\n      argv: [a]\n    respond:\n      exit: 0\n")
+	main := writeScenarioFile(t, dir, "main.yaml", "meta:\n  name: main\nincludes: [a.yaml]\n")
+
+	scn, err := LoadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, flatArgv0(scn))
+}
+
+func TestLoadFile_IncludeMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	main := writeScenarioFile(t, dir, "main.yaml", `
+meta:
+  name: main
+includes: [missing.yaml]
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+`)
+
+	_, err := LoadFile(main)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `includes[0] "missing.yaml"`)
+	assert.Contains(t, err.Error(), "failed to open scenario file")
+}
+
+func TestLoadFile_IncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeScenarioFile(t, dir, "a.yaml", "meta:\n  name: a\nincludes: [b.yaml]\nsteps:\n  - match:\n      argv: [a]\n    respond:\n      exit: 0\n")
+	writeScenarioFile(t, dir, "b.yaml", "meta:\n  name: b\nincludes: [a.yaml]\nsteps:\n  - match:\n      argv: [b]\n    respond:\n      exit: 0\n")
+
+	_, err := LoadFile(filepath.Join(dir, "a.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "include cycle detected: a.yaml -> b.yaml -> a.yaml")
+}
+
+func TestLoadFile_IncludeSelfCycle(t *testing.T) {
+	dir := t.TempDir()
+	main := writeScenarioFile(t, dir, "self.yaml", "meta:\n  name: self\nincludes: [self.yaml]\nsteps:\n  - match:\n      argv: [a]\n    respond:\n      exit: 0\n")
+
+	_, err := LoadFile(main)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "include cycle detected")
+}
+
+func TestLoad_IncludeInvalidPosition(t *testing.T) {
+	_, err := Load(strings.NewReader(`
+meta:
+  name: main
+includes:
+  - path: a.yaml
+    position: middle
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid position "middle"`)
+}
+
+func TestLoad_IncludeUnknownField(t *testing.T) {
+	_, err := Load(strings.NewReader(`
+meta:
+  name: main
+includes:
+  - file: a.yaml
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field file not found")
+}
+
+func TestLoadFileAllWithInfo_RecordsFilesAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	fragment := writeScenarioFile(t, dir, "fragments/login.yaml", `
+meta:
+  name: login
+  vars:
+    user: fragment-user
+steps:
+  - match:
+      argv: [az, login, -u, "{{ .user }}"]
+    respond:
+      exit: 0
+`)
+	main := writeScenarioFile(t, dir, "main.yaml", `
+meta:
+  name: main
+includes:
+  - fragments/login.yaml
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+`)
+	t.Setenv("user", "env-user")
+
+	docs, info, err := LoadFileAllWithInfo(main)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, []string{"az login -u env-user", "kubectl get pods"}, flatArgv0(docs[0]))
+	assert.Equal(t, []string{main, fragment}, info.Files)
+	assert.Equal(t, map[string]string{"user": "env-user"}, info.Env)
+}
@@ -5,45 +5,221 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Load parses a scenario from the given reader with strict field validation.
-// Unknown fields in the YAML will cause an error.
+// Unknown fields in the YAML will cause an error. Relative include paths are
+// resolved against the current working directory. For a multi-document
+// stream, every document is validated and the first one is returned.
 func Load(r io.Reader) (*Scenario, error) {
-	decoder := yaml.NewDecoder(r)
-	decoder.KnownFields(true)
+	docs, err := load(r, workingDir(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return docs[0], nil
+}
 
-	var scenario Scenario
-	if err := decoder.Decode(&scenario); err != nil {
-		if err == io.EOF {
-			return nil, fmt.Errorf("empty scenario file")
-		}
-		return nil, fmt.Errorf("failed to parse scenario: %w", err)
+// LoadAll parses every document of a multi-document YAML stream (separated
+// by ---) as a scenario, in order. Each document is validated on its own.
+func LoadAll(r io.Reader) ([]*Scenario, error) {
+	return load(r, workingDir(), nil, nil)
+}
+
+// LoadFile loads a scenario from the given file path. Relative include paths
+// are resolved against the directory containing the file. For a
+// multi-document file, the first document is returned.
+func LoadFile(path string) (*Scenario, error) {
+	docs, err := loadFile(path, nil, nil)
+	if err != nil {
+		return nil, err
 	}
+	return docs[0], nil
+}
+
+// LoadFileAll loads every scenario document of the file at path.
+func LoadFileAll(path string) ([]*Scenario, error) {
+	return loadFile(path, nil, nil)
+}
+
+// LoadInfo describes what loading a scenario read, so a caller that caches
+// the loaded scenarios can tell when they are stale.
+type LoadInfo struct {
+	// Files lists the absolute paths of the scenario file and of every file
+	// it includes, in load order.
+	Files []string
+	// Env holds the environment variables consulted to render match.argv
+	// vars, with the values seen ("" when unset).
+	Env map[string]string
+}
 
-	if err := scenario.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid scenario: %w", err)
+// LoadFileAllWithInfo is LoadFileAll that also reports what the load read.
+func LoadFileAllWithInfo(path string) ([]*Scenario, *LoadInfo, error) {
+	info := &LoadInfo{Env: make(map[string]string)}
+	docs, err := loadFile(path, nil, info)
+	if err != nil {
+		return nil, nil, err
 	}
+	return docs, info, nil
+}
 
-	return &scenario, nil
+// getenv is os.Getenv, recording each lookup in the info.
+func (info *LoadInfo) getenv(name string) string {
+	v := os.Getenv(name)
+	info.Env[name] = v
+	return v
 }
 
-// LoadFile loads a scenario from the given file path.
-func LoadFile(path string) (*Scenario, error) {
-	f, err := os.Open(path) //nolint:gosec // File path comes from user input, expected behavior
+// DocumentSelector picks one scenario of a multi-document file. A non-empty
+// Name selects the first document with that meta.name; otherwise Index
+// selects by 0-based position.
+type DocumentSelector struct {
+	Index int
+	Name  string
+}
+
+// Select returns the scenario chosen by sel and its index in docs.
+func (sel DocumentSelector) Select(docs []*Scenario) (*Scenario, int, error) {
+	if sel.Name != "" {
+		for i, doc := range docs {
+			if doc.Meta.Name == sel.Name {
+				return doc, i, nil
+			}
+		}
+		return nil, -1, fmt.Errorf("no scenario document named %q (have %s)", sel.Name, documentNames(docs))
+	}
+	if sel.Index < 0 || sel.Index >= len(docs) {
+		return nil, -1, fmt.Errorf("scenario document index %d out of range (file has %d documents)", sel.Index, len(docs))
+	}
+	return docs[sel.Index], sel.Index, nil
+}
+
+// documentNames lists the quoted meta.name of each document.
+func documentNames(docs []*Scenario) string {
+	names := make([]string, len(docs))
+	for i, doc := range docs {
+		names[i] = strconv.Quote(doc.Meta.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// loadFile opens path and loads it, tracking the include stack for cycle
+// detection. Files read are recorded in info when it is non-nil.
+func loadFile(path string, stack []string, info *LoadInfo) ([]*Scenario, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve scenario path: %w", err)
+	}
+	if err := includeCycle(absPath, stack); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(absPath) //nolint:gosec // File path comes from user input, expected behavior
 	if err != nil {
 		return nil, fmt.Errorf("failed to open scenario file: %w", err)
 	}
 	defer func() { _ = f.Close() }()
+	if info != nil {
+		info.Files = append(info.Files, absPath)
+	}
+
+	return load(f, filepath.Dir(absPath), append(stack, absPath), info)
+}
 
-	scenario, err := Load(f)
+// load decodes every document of the stream, then expands includes and
+// validates each one. Errors name the document when there are several.
+func load(r io.Reader, baseDir string, stack []string, info *LoadInfo) ([]*Scenario, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	// Decode the document nodes first so the document count is known, then
+	// decode each one strictly in step with them.
+	var nodes []*yaml.Node
+	nodeDecoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := nodeDecoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse scenario: %w", err)
+		}
+		nodes = append(nodes, &doc)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var docs []*Scenario
+	var docNodes []*yaml.Node
+	for i, node := range nodes {
+		var scenario Scenario
+		if err := decoder.Decode(&scenario); err != nil {
+			return nil, fmt.Errorf("failed to parse scenario: %s%w", documentPrefix(i, len(nodes)), err)
+		}
+		if isEmptyDocument(node) {
+			continue
+		}
+		docs = append(docs, &scenario)
+		docNodes = append(docNodes, node)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("empty scenario file")
 	}
 
-	return scenario, nil
+	for i, scenario := range docs {
+		if err := scenario.prepare(docNodes[i], baseDir, stack, info); err != nil {
+			return nil, fmt.Errorf("invalid scenario: %s%w", documentPrefix(i, len(docs)), err)
+		}
+	}
+	return docs, nil
+}
+
+// prepare resolves response layers, expands includes, renders argv
+// variables against the merged vars and validates a decoded scenario.
+func (s *Scenario) prepare(doc *yaml.Node, baseDir string, stack []string, info *LoadInfo) error {
+	// Defaults and response references need key presence, which the
+	// decoded struct does not keep.
+	if s.needsResponseResolution() {
+		s.resolveResponses(doc)
+	}
+
+	getenv := os.Getenv
+	if info != nil {
+		getenv = info.getenv
+	}
+	// With includes, argv is rendered once the fragments' vars are merged
+	if len(s.Includes) > 0 {
+		if err := s.expandIncludes(baseDir, stack, info, getenv); err != nil {
+			return err
+		}
+	} else if err := s.renderArgvVars(getenv); err != nil {
+		return err
+	}
+
+	return s.Validate()
+}
+
+// isEmptyDocument reports whether a decoded document has no content, as
+// for a trailing --- separator.
+func isEmptyDocument(doc *yaml.Node) bool {
+	root := documentRoot(doc)
+	return root == nil || root.Kind == 0 || (root.Kind == yaml.ScalarNode && root.Tag == "!!null")
+}
+
+// documentPrefix labels errors with the failing document when a stream
+// holds several.
+func documentPrefix(i, total int) string {
+	if total < 2 {
+		return ""
+	}
+	return fmt.Sprintf("document %d: ", i)
 }
 
 // MarshalYAML implements custom YAML marshaling for StepElement.
@@ -39,11 +39,11 @@ steps:
 	require.Len(t, scenario.Steps, 2)
 
 	assert.Equal(t, []string{"kubectl", "get", "pods"}, scenario.Steps[0].Step.Match.Argv)
-	assert.Equal(t, 0, scenario.Steps[0].Step.Respond.Exit)
+	assert.Equal(t, 0, scenario.Steps[0].Step.Respond.EffectiveExit())
 	assert.Equal(t, "pod-1 Running", scenario.Steps[0].Step.Respond.Stdout)
 
 	assert.Equal(t, []string{"kubectl", "delete", "pod", "pod-1"}, scenario.Steps[1].Step.Match.Argv)
-	assert.Equal(t, 0, scenario.Steps[1].Step.Respond.Exit)
+	assert.Equal(t, 0, scenario.Steps[1].Step.Respond.EffectiveExit())
 	assert.Equal(t, "pod deleted", scenario.Steps[1].Step.Respond.Stderr)
 }
 
@@ -478,7 +478,7 @@ meta:
   name: bad
 steps:
   - group:
-      mode: ordered
+      mode: random
       steps:
         - match:
             argv: ["cmd"]
@@ -600,3 +600,90 @@ steps:
 	require.NoError(t, err)
 	assert.Equal(t, "capture-group-valid", scn.Meta.Name)
 }
+
+const multiDocScenario = `
+meta:
+  name: deploy
+steps:
+  - match:
+      argv: ["kubectl", "apply", "-f", "app.yaml"]
+---
+meta:
+  name: teardown
+steps:
+  - match:
+      argv: ["kubectl", "delete", "-f", "app.yaml"]
+  - match:
+      argv: ["kubectl", "get", "pods"]
+---
+`
+
+func TestLoadAll_MultiDocument(t *testing.T) {
+	docs, err := LoadAll(strings.NewReader(multiDocScenario))
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "deploy", docs[0].Meta.Name)
+	assert.Equal(t, "teardown", docs[1].Meta.Name)
+	assert.Len(t, docs[1].Steps, 2)
+
+	// Load keeps returning the first document
+	first, err := Load(strings.NewReader(multiDocScenario))
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", first.Meta.Name)
+}
+
+func TestLoadAll_ValidatesEveryDocument(t *testing.T) {
+	content := multiDocScenario + `
+meta:
+  name: broken
+steps: []
+`
+	_, err := LoadAll(strings.NewReader(content))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid scenario: document 2:")
+
+	_, err = Load(strings.NewReader(content))
+	require.Error(t, err, "later documents are validated too")
+}
+
+func TestLoadAll_StrictPerDocument(t *testing.T) {
+	content := multiDocScenario + `
+meta:
+  name: typo
+steps:
+  - match:
+      argv: ["ls"]
+    respnd:
+      exit: 0
+`
+	_, err := LoadAll(strings.NewReader(content))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "document 2:")
+	assert.Contains(t, err.Error(), "respnd")
+}
+
+func TestDocumentSelector_Select(t *testing.T) {
+	docs, err := LoadAll(strings.NewReader(multiDocScenario))
+	require.NoError(t, err)
+
+	scn, idx, err := DocumentSelector{Name: "teardown"}.Select(docs)
+	require.NoError(t, err)
+	assert.Equal(t, 1, idx)
+	assert.Equal(t, "teardown", scn.Meta.Name)
+
+	scn, idx, err = DocumentSelector{Index: 0}.Select(docs)
+	require.NoError(t, err)
+	assert.Equal(t, 0, idx)
+	assert.Equal(t, "deploy", scn.Meta.Name)
+
+	_, _, err = DocumentSelector{Index: 2}.Select(docs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "index 2 out of range (file has 2 documents)")
+
+	_, _, err = DocumentSelector{Index: -1}.Select(docs)
+	require.Error(t, err)
+
+	_, _, err = DocumentSelector{Name: "rollback"}.Select(docs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `no scenario document named "rollback" (have "deploy", "teardown")`)
+}
@@ -0,0 +1,156 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// autoGroupNameRe matches group names generated by Validate ("group-N").
+var autoGroupNameRe = regexp.MustCompile(`^group-[0-9]+$`)
+
+// Merge concatenates the steps of the given scenarios in order and merges
+// their meta, with later scenarios overriding earlier ones: meta.vars and
+// meta.responses are merged key by key, tags and strip_prefixes are
+// concatenated without duplicates, and every other field (description,
+// security, session, fallback, defaults, limits, exit_codes,
+// max_total_calls, fixtures_dir) is taken from the last scenario that sets
+// it.
+//
+// Each input is expected to be individually valid (e.g. loaded via LoadFile).
+// Capture identifiers defined by more than one scenario, and captures that
+// collide with a merged meta.vars key, are reported as errors. Groups carrying
+// auto-generated names are renamed so that the merged result keeps the
+// sequential "group-N" numbering. The merged scenario is validated before it
+// is returned.
+func Merge(name string, scenarios ...*Scenario) (*Scenario, error) {
+	if len(scenarios) == 0 {
+		return nil, errors.New("at least one scenario is required")
+	}
+
+	merged := &Scenario{
+		Meta: Meta{Name: name},
+	}
+
+	captureOwner := make(map[string]int) // capture ID → index of defining scenario
+	for i, scn := range scenarios {
+		if scn == nil {
+			return nil, fmt.Errorf("scenario %d: is nil", i)
+		}
+		if merged.Meta.Name == "" {
+			merged.Meta.Name = scn.Meta.Name
+		}
+
+		mergeMeta(&merged.Meta, &scn.Meta)
+
+		for _, step := range scn.FlatSteps() {
+			for key := range step.Respond.Capture {
+				if owner, exists := captureOwner[key]; exists && owner != i {
+					return nil, fmt.Errorf("capture identifier %q is defined by both scenario %d (%s) and scenario %d (%s)",
+						key, owner, scenarios[owner].Meta.Name, i, scn.Meta.Name)
+				}
+				captureOwner[key] = i
+			}
+		}
+
+		for _, elem := range scn.Steps {
+			merged.Steps = append(merged.Steps, copyStepElement(elem))
+		}
+	}
+
+	for key, owner := range captureOwner {
+		if _, exists := merged.Meta.Vars[key]; exists {
+			return nil, fmt.Errorf("capture identifier %q (scenario %d: %s) conflicts with merged meta.vars key %q",
+				key, owner, scenarios[owner].Meta.Name, key)
+		}
+	}
+
+	// Clear auto-generated group names so Validate renumbers them sequentially.
+	for _, elem := range merged.Steps {
+		if elem.Group != nil && autoGroupNameRe.MatchString(elem.Group.Name) {
+			elem.Group.Name = ""
+		}
+	}
+
+	if err := merged.Validate(); err != nil {
+		return nil, fmt.Errorf("merged scenario is invalid: %w", err)
+	}
+
+	return merged, nil
+}
+
+// copyStepElement returns a copy of elem whose Step and Group pointers do not
+// alias the original, so that merging never mutates the input scenarios.
+func copyStepElement(elem StepElement) StepElement {
+	if elem.Step != nil {
+		step := *elem.Step
+		return StepElement{Step: &step}
+	}
+	if elem.Group != nil {
+		group := *elem.Group
+		group.Steps = make([]StepElement, len(elem.Group.Steps))
+		for i, child := range elem.Group.Steps {
+			group.Steps[i] = copyStepElement(child)
+		}
+		return StepElement{Group: &group}
+	}
+	return elem
+}
+
+// mergeMeta merges src into dst as described on Merge. Name is left to
+// the caller.
+func mergeMeta(dst, src *Meta) {
+	for k, v := range src.Vars {
+		if dst.Vars == nil {
+			dst.Vars = make(map[string]string)
+		}
+		dst.Vars[k] = v
+	}
+	for k, v := range src.Responses {
+		if dst.Responses == nil {
+			dst.Responses = make(map[string]Response)
+		}
+		dst.Responses[k] = v
+	}
+	dst.Tags = appendUnique(dst.Tags, src.Tags)
+	dst.StripPrefixes = appendUnique(dst.StripPrefixes, src.StripPrefixes)
+
+	if src.Description != "" {
+		dst.Description = src.Description
+	}
+	if src.Security != nil {
+		dst.Security = src.Security
+	}
+	if src.Session != nil {
+		dst.Session = src.Session
+	}
+	if src.Fallback != nil {
+		dst.Fallback = src.Fallback
+	}
+	if src.Defaults != nil {
+		dst.Defaults = src.Defaults
+	}
+	if src.Limits != nil {
+		dst.Limits = src.Limits
+	}
+	if src.ExitCodes != nil {
+		dst.ExitCodes = src.ExitCodes
+	}
+	if src.MaxTotalCalls != 0 {
+		dst.MaxTotalCalls = src.MaxTotalCalls
+	}
+	if src.FixturesDir != "" {
+		dst.FixturesDir = src.FixturesDir
+	}
+}
+
+// appendUnique appends the entries of src missing from dst, in order.
+func appendUnique(dst, src []string) []string {
+	for _, s := range src {
+		if !slices.Contains(dst, s) {
+			dst = append(dst, s)
+		}
+	}
+	return dst
+}
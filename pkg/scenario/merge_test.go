@@ -0,0 +1,261 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLoad(t *testing.T, yamlContent string) *Scenario {
+	t.Helper()
+	scn, err := Load(strings.NewReader(yamlContent))
+	require.NoError(t, err)
+	return scn
+}
+
+func TestMerge_ConcatenatesStepsInOrder(t *testing.T) {
+	login := mustLoad(t, `
+meta:
+  name: login
+steps:
+  - match:
+      argv: [az, login]
+    respond:
+      exit: 0
+`)
+	deploy := mustLoad(t, `
+meta:
+  name: deploy
+steps:
+  - match:
+      argv: [kubectl, apply]
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, rollout, status]
+    respond:
+      exit: 0
+`)
+
+	merged, err := Merge("", login, deploy)
+	require.NoError(t, err)
+
+	assert.Equal(t, "login", merged.Meta.Name, "name defaults to the first scenario's name")
+	flat := merged.FlatSteps()
+	require.Len(t, flat, 3)
+	assert.Equal(t, []string{"az", "login"}, flat[0].Match.Argv)
+	assert.Equal(t, []string{"kubectl", "apply"}, flat[1].Match.Argv)
+	assert.Equal(t, []string{"kubectl", "rollout", "status"}, flat[2].Match.Argv)
+}
+
+func TestMerge_ExplicitName(t *testing.T) {
+	a := mustLoad(t, "meta:\n  name: a\nsteps:\n  - match:\n      argv: [a]\n    respond:\n      exit: 0\n")
+
+	merged, err := Merge("combined", a)
+	require.NoError(t, err)
+	assert.Equal(t, "combined", merged.Meta.Name)
+}
+
+func TestMerge_VarsLaterOverride(t *testing.T) {
+	a := mustLoad(t, `
+meta:
+  name: a
+  vars:
+    cluster: dev
+    region: eastus
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+`)
+	b := mustLoad(t, `
+meta:
+  name: b
+  vars:
+    cluster: prod
+steps:
+  - match:
+      argv: [b]
+    respond:
+      exit: 0
+`)
+
+	merged, err := Merge("", a, b)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", merged.Meta.Vars["cluster"])
+	assert.Equal(t, "eastus", merged.Meta.Vars["region"])
+}
+
+func TestMerge_MetaFieldsSurvive(t *testing.T) {
+	first := mustLoad(t, `
+meta:
+  name: first
+  description: first fragment
+  tags: [smoke, k8s]
+  strip_prefixes: [sudo]
+  max_total_calls: 10
+  fixtures_dir: fixtures
+  limits:
+    max_consecutive_mismatches: 3
+  exit_codes:
+    mismatch: 90
+  fallback:
+    stdout: "first fallback\n"
+  responses:
+    ok:
+      stdout: "ok\n"
+steps:
+  - match:
+      argv: [az, login]
+`)
+	second := mustLoad(t, `
+meta:
+  name: second
+  tags: [k8s, deploy]
+  strip_prefixes: [sudo, env]
+  defaults:
+    respond:
+      stderr: "warn\n"
+  exit_codes:
+    complete: 91
+  responses:
+    created:
+      stdout: "created\n"
+steps:
+  - match:
+      argv: [kubectl, apply]
+`)
+
+	merged, err := Merge("", first, second)
+	require.NoError(t, err)
+	meta := merged.Meta
+
+	assert.Equal(t, "first fragment", meta.Description, "kept when a later fragment sets none")
+	assert.Equal(t, []string{"smoke", "k8s", "deploy"}, meta.Tags)
+	assert.Equal(t, []string{"sudo", "env"}, meta.StripPrefixes)
+	assert.Equal(t, 10, meta.MaxTotalCalls)
+	assert.Equal(t, "fixtures", meta.FixturesDir)
+	require.NotNil(t, meta.Limits)
+	assert.Equal(t, 3, meta.Limits.MaxConsecutiveMismatches)
+	require.NotNil(t, meta.Fallback)
+	assert.Equal(t, "first fallback\n", meta.Fallback.Stdout)
+	require.NotNil(t, meta.Defaults)
+	assert.Equal(t, "warn\n", meta.Defaults.Respond.Stderr)
+	require.NotNil(t, meta.ExitCodes)
+	assert.Equal(t, 91, meta.CompleteExitCode(), "the last fragment's exit_codes win")
+	assert.Equal(t, 1, meta.MismatchExitCode())
+	assert.Contains(t, meta.Responses, "ok")
+	assert.Contains(t, meta.Responses, "created")
+
+	assert.Equal(t, []string{"smoke", "k8s"}, first.Meta.Tags, "inputs are not modified")
+}
+
+func TestMerge_CaptureConflictAcrossFragments(t *testing.T) {
+	a := mustLoad(t, `
+meta:
+  name: a
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+      capture:
+        id: "1"
+`)
+	b := mustLoad(t, `
+meta:
+  name: b
+steps:
+  - match:
+      argv: [b]
+    respond:
+      exit: 0
+      capture:
+        id: "2"
+`)
+
+	_, err := Merge("", a, b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `capture identifier "id" is defined by both`)
+}
+
+func TestMerge_CaptureConflictsWithMergedVars(t *testing.T) {
+	a := mustLoad(t, `
+meta:
+  name: a
+steps:
+  - match:
+      argv: [a]
+    respond:
+      exit: 0
+      capture:
+        cluster: from-capture
+`)
+	b := mustLoad(t, `
+meta:
+  name: b
+  vars:
+    cluster: prod
+steps:
+  - match:
+      argv: [b]
+    respond:
+      exit: 0
+`)
+
+	_, err := Merge("", a, b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `conflicts with merged meta.vars key "cluster"`)
+}
+
+func TestMerge_RenumbersAutoNamedGroups(t *testing.T) {
+	fragment := `
+meta:
+  name: frag
+steps:
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: [x]
+          respond:
+            exit: 0
+`
+	named := mustLoad(t, `
+meta:
+  name: named
+steps:
+  - group:
+      mode: unordered
+      name: pre-flight
+      steps:
+        - match:
+            argv: [y]
+          respond:
+            exit: 0
+`)
+	a := mustLoad(t, fragment)
+	b := mustLoad(t, fragment)
+	require.Equal(t, "group-1", a.Steps[0].Group.Name)
+	require.Equal(t, "group-1", b.Steps[0].Group.Name)
+
+	merged, err := Merge("", a, named, b)
+	require.NoError(t, err)
+
+	ranges := merged.GroupRanges()
+	require.Len(t, ranges, 3)
+	assert.Equal(t, "group-1", ranges[0].Name)
+	assert.Equal(t, "pre-flight", ranges[1].Name)
+	assert.Equal(t, "group-3", ranges[2].Name)
+
+	// Inputs are not mutated
+	assert.Equal(t, "group-1", b.Steps[0].Group.Name)
+}
+
+func TestMerge_NoScenarios(t *testing.T) {
+	_, err := Merge("x")
+	require.Error(t, err)
+}
@@ -5,15 +5,23 @@ package scenario
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template/parse"
 	"time"
 )
 
+// SessionAutoPID derives the session ID from the parent process of
+// `cli-replay run`, i.e. the calling shell.
+const SessionAutoPID = "pid"
+
 // Session defines session lifecycle configuration.
 type Session struct {
-	TTL string `yaml:"ttl,omitempty"`
+	TTL  string `yaml:"ttl,omitempty"`
+	Auto string `yaml:"auto,omitempty"`
 }
 
 // Validate checks that the session configuration is valid.
@@ -27,13 +35,17 @@ func (s *Session) Validate() error {
 			return fmt.Errorf("ttl must be positive, got %s", s.TTL)
 		}
 	}
+	if s.Auto != "" && s.Auto != SessionAutoPID {
+		return fmt.Errorf("invalid auto %q: valid value is %q", s.Auto, SessionAutoPID)
+	}
 	return nil
 }
 
 // Scenario represents a complete test definition loaded from a YAML file.
 type Scenario struct {
-	Meta  Meta          `yaml:"meta"`
-	Steps []StepElement `yaml:"steps"`
+	Meta     Meta          `yaml:"meta"`
+	Includes []Include     `yaml:"includes,omitempty"`
+	Steps    []StepElement `yaml:"steps"`
 }
 
 // Validate checks that the scenario is valid.
@@ -41,6 +53,11 @@ func (s *Scenario) Validate() error {
 	if err := s.Meta.Validate(); err != nil {
 		return fmt.Errorf("meta: %w", err)
 	}
+	for i := range s.Includes {
+		if err := s.Includes[i].Validate(); err != nil {
+			return fmt.Errorf("includes[%d]: %w", i, err)
+		}
+	}
 	if len(s.Steps) == 0 {
 		return errors.New("steps must contain at least one step")
 	}
@@ -49,6 +66,9 @@ func (s *Scenario) Validate() error {
 		if err := elem.Validate(); err != nil {
 			return fmt.Errorf("step %d: %w", i, err)
 		}
+		if elem.Step != nil && len(elem.Step.DependsOn) > 0 {
+			return fmt.Errorf("step %d: depends_on is only supported on unordered group children", i)
+		}
 		// Auto-name groups
 		if elem.Group != nil && elem.Group.Name == "" {
 			groupIdx++
@@ -58,6 +78,30 @@ func (s *Scenario) Validate() error {
 		}
 	}
 
+	for i, step := range s.FlatSteps() {
+		if i == 0 && step.ExpectWithin != "" {
+			return errors.New("step 0: expect_within needs a preceding step to measure from")
+		}
+		for _, argv := range step.Match.Alternatives() {
+			if containsString(s.Meta.StripPrefixes, argv[0]) {
+				return fmt.Errorf("step %d: argv starts with %q, which meta.strip_prefixes removes before matching", i, argv[0])
+			}
+			if s.Meta.Security != nil && containsString(s.Meta.Security.ForbiddenCommands, filepath.Base(argv[0])) {
+				return fmt.Errorf("step %d: command %q is in meta.security.forbidden_commands", i, filepath.Base(argv[0]))
+			}
+		}
+		if step.RespondRef == "" {
+			continue
+		}
+		if _, ok := s.Meta.Responses[step.RespondRef]; !ok {
+			return fmt.Errorf("step %d: respond_ref %q is not defined in meta.responses", i, step.RespondRef)
+		}
+	}
+
+	if err := s.validateExitCodes(); err != nil {
+		return err
+	}
+
 	// Cross-cutting validation: capture-vs-vars conflicts and forward references
 	if err := s.validateCaptures(); err != nil {
 		return err
@@ -66,6 +110,41 @@ func (s *Scenario) Validate() error {
 	return nil
 }
 
+// validateExitCodes rejects a configured meta.exit_codes value that a step
+// or the fallback also exits with, since a caller could not tell a served
+// response from a replay failure. Templated exits are not known until
+// render time and are not checked.
+func (s *Scenario) validateExitCodes() error {
+	codes := s.Meta.ExitCodes
+	if codes == nil {
+		return nil
+	}
+	check := func(where string, r *Response) error {
+		if r.ExitTemplate != "" {
+			return nil
+		}
+		exit := r.EffectiveExit()
+		if codes.Mismatch != nil && exit == *codes.Mismatch {
+			return fmt.Errorf("%s: exit %d is also meta.exit_codes.mismatch", where, exit)
+		}
+		if codes.Complete != nil && exit == *codes.Complete {
+			return fmt.Errorf("%s: exit %d is also meta.exit_codes.complete", where, exit)
+		}
+		return nil
+	}
+	if s.Meta.Fallback != nil {
+		if err := check("meta.fallback", s.Meta.Fallback); err != nil {
+			return err
+		}
+	}
+	for i, step := range s.FlatSteps() {
+		if err := check(fmt.Sprintf("step %d", i), &step.Respond); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // validateCaptures checks for capture identifier conflicts with meta.vars
 // and for forward references in template expressions.
 func (s *Scenario) validateCaptures() error {
@@ -81,30 +160,27 @@ func (s *Scenario) validateCaptures() error {
 	}
 
 	// Check forward references: accumulate defined captures, then check
-	// template references in stdout/stderr for each step.
+	// template references in stdout/stderr/exit_template for each step.
 	defined := make(map[string]int) // capture ID → flat step index where first defined
 	for i, step := range flatSteps {
-		// Check templates in this step's stdout and stderr for capture references
-		for _, tmplStr := range []string{step.Respond.Stdout, step.Respond.Stderr} {
-			refs := extractCaptureRefs(tmplStr)
-			for _, ref := range refs {
-				if defIdx, ok := defined[ref]; ok {
-					_ = defIdx // defined earlier, OK
-				} else {
-					// Check if it's defined at a later step (forward reference)
-					futureIdx := -1
-					for j := i + 1; j < len(flatSteps); j++ {
-						if _, exists := flatSteps[j].Respond.Capture[ref]; exists {
-							futureIdx = j
-							break
-						}
-					}
-					if futureIdx >= 0 {
-						return fmt.Errorf("step %d references capture %q first defined at step %d (forward reference)", i, ref, futureIdx)
+		// Check templates in this step's stdout, stderr and exit_template for capture references
+		for _, ref := range stepCaptureRefs(step) {
+			if defIdx, ok := defined[ref]; ok {
+				_ = defIdx // defined earlier, OK
+			} else {
+				// Check if it's defined at a later step (forward reference)
+				futureIdx := -1
+				for j := i + 1; j < len(flatSteps); j++ {
+					if _, exists := flatSteps[j].Respond.Capture[ref]; exists {
+						futureIdx = j
+						break
 					}
-					// Not defined anywhere — that's OK (will resolve to empty string at runtime
-					// for unordered groups or optional steps)
 				}
+				if futureIdx >= 0 {
+					return fmt.Errorf("step %d references capture %q first defined at step %d (forward reference)", i, ref, futureIdx)
+				}
+				// Not defined anywhere — that's OK (will resolve to empty string at runtime
+				// for unordered groups or optional steps)
 			}
 		}
 
@@ -119,6 +195,112 @@ func (s *Scenario) validateCaptures() error {
 	return nil
 }
 
+// SkippedCapture describes a capture that would never be set when replay
+// starts part-way through a scenario.
+type SkippedCapture struct {
+	Capture      string // capture identifier
+	DefinedAt    int    // flat index of the skipped step that defines it
+	ReferencedAt int    // flat index of the first step that references it
+}
+
+// SkippedCaptureRefs returns the captures defined only by steps before the
+// flat index start that are referenced by templates at or after start.
+// Starting replay at start would render those references as empty strings.
+func (s *Scenario) SkippedCaptureRefs(start int) []SkippedCapture {
+	flatSteps := s.FlatSteps()
+	if start <= 0 || start > len(flatSteps) {
+		return nil
+	}
+
+	skipped := make(map[string]int) // capture ID → defining skipped step
+	for i := 0; i < start; i++ {
+		for key := range flatSteps[i].Respond.Capture {
+			if _, exists := skipped[key]; !exists {
+				skipped[key] = i
+			}
+		}
+	}
+
+	var out []SkippedCapture
+	reported := make(map[string]bool)
+	redefined := make(map[string]bool)
+	for i := start; i < len(flatSteps); i++ {
+		for _, ref := range stepCaptureRefs(flatSteps[i]) {
+			defIdx, ok := skipped[ref]
+			if !ok || redefined[ref] || reported[ref] {
+				continue
+			}
+			reported[ref] = true
+			out = append(out, SkippedCapture{Capture: ref, DefinedAt: defIdx, ReferencedAt: i})
+		}
+		for key := range flatSteps[i].Respond.Capture {
+			redefined[key] = true
+		}
+	}
+	return out
+}
+
+// UndefinedCapture describes a template reference to a capture that no
+// step of the scenario defines.
+type UndefinedCapture struct {
+	Capture      string // capture identifier
+	ReferencedAt int    // flat index of the first step that references it
+}
+
+// UndefinedCaptureRefs returns the captures referenced by step templates
+// that no step defines, in order of first reference. Validate accepts such
+// references (they render empty), but they are usually typos.
+func (s *Scenario) UndefinedCaptureRefs() []UndefinedCapture {
+	flatSteps := s.FlatSteps()
+	defined := make(map[string]bool)
+	for _, step := range flatSteps {
+		for key := range step.Respond.Capture {
+			defined[key] = true
+		}
+	}
+
+	var out []UndefinedCapture
+	reported := make(map[string]bool)
+	for i, step := range flatSteps {
+		for _, ref := range stepCaptureRefs(step) {
+			if defined[ref] || reported[ref] {
+				continue
+			}
+			reported[ref] = true
+			out = append(out, UndefinedCapture{Capture: ref, ReferencedAt: i})
+		}
+	}
+	return out
+}
+
+// stepCaptureRefs returns the capture identifiers referenced by a step's
+// stdout, prepend, append, stderr, exit_template and output templates, including
+// those of respond.random entries, and by its capture values.
+func stepCaptureRefs(step Step) []string {
+	var refs []string
+	responses := []Response{step.Respond}
+	for _, w := range step.Respond.Random {
+		responses = append(responses, w.Response)
+	}
+	for _, r := range responses {
+		for _, tmplStr := range []string{r.Stdout, r.Prepend, r.Append, r.Stderr, r.ExitTemplate} {
+			refs = append(refs, extractCaptureRefs(tmplStr)...)
+		}
+		for _, chunk := range r.Output {
+			refs = append(refs, extractCaptureRefs(chunk.Text)...)
+		}
+	}
+	keys := make([]string, 0, len(step.Respond.Capture))
+	for k := range step.Respond.Capture {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		refs = append(refs, extractCaptureRefs(step.Respond.Capture[k])...)
+	}
+	return refs
+}
+
 // extractCaptureRefs parses a Go template string and returns all capture
 // identifiers referenced via {{ .capture.X }} patterns, using the
 // text/template/parse AST for accurate detection.
@@ -212,9 +394,15 @@ type GroupRange struct {
 	Start    int    // Inclusive flat index of first group child
 	End      int    // Exclusive flat index (Start + len(group.Steps))
 	Name     string // Group name (resolved, never empty)
+	Mode     string // Group mode ("unordered" or "ordered")
 	TopIndex int    // Index of the group in the top-level Steps array
 }
 
+// Ordered reports whether the group's children must match sequentially.
+func (gr GroupRange) Ordered() bool {
+	return gr.Mode == GroupModeOrdered
+}
+
 // GroupRanges returns the flat-index ranges for all groups in the scenario.
 func (s *Scenario) GroupRanges() []GroupRange {
 	var ranges []GroupRange
@@ -233,6 +421,7 @@ func (s *Scenario) GroupRanges() []GroupRange {
 				Start:    flatIdx,
 				End:      flatIdx + childCount,
 				Name:     elem.Group.Name,
+				Mode:     elem.Group.Mode,
 				TopIndex: i,
 			})
 			flatIdx += childCount
@@ -262,7 +451,16 @@ func (se *StepElement) Validate() error {
 	return se.Group.Validate()
 }
 
-// StepGroup defines a group of steps with unordered matching semantics.
+// Group modes.
+const (
+	// GroupModeUnordered lets group children match in any order.
+	GroupModeUnordered = "unordered"
+	// GroupModeOrdered requires group children to match sequentially.
+	GroupModeOrdered = "ordered"
+)
+
+// StepGroup defines a named group of steps with unordered or ordered
+// matching semantics.
 type StepGroup struct {
 	Mode  string        `yaml:"mode"`
 	Name  string        `yaml:"name,omitempty"`
@@ -271,8 +469,8 @@ type StepGroup struct {
 
 // Validate checks that the step group is valid.
 func (sg *StepGroup) Validate() error {
-	if sg.Mode != "unordered" {
-		return fmt.Errorf("unsupported group mode %q: only \"unordered\" is supported", sg.Mode)
+	if sg.Mode != GroupModeUnordered && sg.Mode != GroupModeOrdered {
+		return fmt.Errorf("unsupported group mode %q: must be %q or %q", sg.Mode, GroupModeUnordered, GroupModeOrdered)
 	}
 	if len(sg.Steps) == 0 {
 		return errors.New("group must contain at least one step")
@@ -287,23 +485,208 @@ func (sg *StepGroup) Validate() error {
 		if err := elem.Step.Validate(); err != nil {
 			return fmt.Errorf("step %d: %w", i, err)
 		}
+		if sg.Mode == GroupModeOrdered && len(elem.Step.DependsOn) > 0 {
+			return fmt.Errorf("step %d: depends_on is only supported in unordered groups", i)
+		}
+	}
+	return sg.validateDependencies()
+}
+
+// validateDependencies checks that depends_on entries reference uniquely
+// named siblings within the group and that the dependencies form a DAG.
+func (sg *StepGroup) validateDependencies() error {
+	byName := make(map[string]int)
+	for i, elem := range sg.Steps {
+		name := elem.Step.Name
+		if name == "" {
+			continue
+		}
+		if prev, dup := byName[name]; dup {
+			return fmt.Errorf("step %d: duplicate step name %q (also used by step %d)", i, name, prev)
+		}
+		byName[name] = i
+	}
+
+	for i, elem := range sg.Steps {
+		for _, dep := range elem.Step.DependsOn {
+			j, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("step %d: depends_on %q does not name a step in this group", i, dep)
+			}
+			if j == i {
+				return fmt.Errorf("step %d: depends_on %q refers to itself", i, dep)
+			}
+		}
+	}
+
+	// Depth-first search for cycles: 1 = visiting, 2 = done.
+	marks := make([]int, len(sg.Steps))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		path = append(path, sg.Steps[i].Step.Name)
+		switch marks[i] {
+		case 1:
+			for k, n := range path {
+				if n == path[len(path)-1] {
+					path = path[k:]
+					break
+				}
+			}
+			return fmt.Errorf("depends_on cycle detected: %s", strings.Join(path, " -> "))
+		case 2:
+			return nil
+		}
+		marks[i] = 1
+		for _, dep := range sg.Steps[i].Step.DependsOn {
+			if err := visit(byName[dep], path); err != nil {
+				return err
+			}
+		}
+		marks[i] = 2
+		return nil
+	}
+	for i := range sg.Steps {
+		if err := visit(i, nil); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 // Meta contains scenario metadata including identification and template variables.
 type Meta struct {
-	Name        string            `yaml:"name"`
-	Description string            `yaml:"description,omitempty"`
-	Vars        map[string]string `yaml:"vars,omitempty"`
-	Security    *Security         `yaml:"security,omitempty"`
-	Session     *Session          `yaml:"session,omitempty"`
+	Name        string              `yaml:"name"`
+	Description string              `yaml:"description,omitempty"`
+	Vars        map[string]string   `yaml:"vars,omitempty"`
+	Security    *Security           `yaml:"security,omitempty"`
+	Session     *Session            `yaml:"session,omitempty"`
+	Fallback    *Response           `yaml:"fallback,omitempty"`
+	Defaults    *Defaults           `yaml:"defaults,omitempty"`
+	Responses   map[string]Response `yaml:"responses,omitempty"`
+	// MaxTotalCalls caps the matched calls across all steps of a session;
+	// zero means no cap.
+	MaxTotalCalls int `yaml:"max_total_calls,omitempty"`
+	// FixturesDir, relative to the scenario file, replaces the scenario's
+	// directory as the base for stdout_file/stderr_file.
+	FixturesDir string `yaml:"fixtures_dir,omitempty"`
+	// Limits overrides built-in caps such as the stdin read size.
+	Limits *Limits `yaml:"limits,omitempty"`
+	// StripPrefixes lists wrapper commands (sudo, env, ...) removed from the
+	// front of an incoming argv before matching.
+	StripPrefixes []string `yaml:"strip_prefixes,omitempty"`
+	// ExitCodes overrides the exit code of intercepts that fail on a
+	// mismatch or an already-complete scenario.
+	ExitCodes *ExitCodes `yaml:"exit_codes,omitempty"`
+	// Tags label the scenario (smoke, slow, ...) so a directory of
+	// scenarios can be filtered, as by 'list --tag'.
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// HasTag reports whether meta.tags contains tag.
+func (m *Meta) HasTag(tag string) bool {
+	return slices.Contains(m.Tags, tag)
+}
+
+// DefaultErrorExitCode is the exit code of a failed intercept unless
+// meta.exit_codes overrides it.
+const DefaultErrorExitCode = 1
+
+// ExitCodes sets the exit codes cli-replay itself returns from an
+// intercept, so they can be told apart from the exits of served steps.
+type ExitCodes struct {
+	// Mismatch is returned when a command matches no expected step.
+	Mismatch *int `yaml:"mismatch,omitempty"`
+	// Complete is returned when every step has already been consumed.
+	Complete *int `yaml:"complete,omitempty"`
+}
+
+// Validate checks that the exit codes are in range 0-255.
+func (e *ExitCodes) Validate() error {
+	if e.Mismatch != nil && (*e.Mismatch < 0 || *e.Mismatch > 255) {
+		return fmt.Errorf("mismatch must be in range 0-255, got %d", *e.Mismatch)
+	}
+	if e.Complete != nil && (*e.Complete < 0 || *e.Complete > 255) {
+		return fmt.Errorf("complete must be in range 0-255, got %d", *e.Complete)
+	}
+	return nil
+}
+
+// MismatchExitCode returns the exit code for a command that matches no
+// expected step.
+func (m *Meta) MismatchExitCode() int {
+	if m.ExitCodes != nil && m.ExitCodes.Mismatch != nil {
+		return *m.ExitCodes.Mismatch
+	}
+	return DefaultErrorExitCode
+}
+
+// CompleteExitCode returns the exit code for a command received after
+// every step has been consumed.
+func (m *Meta) CompleteExitCode() int {
+	if m.ExitCodes != nil && m.ExitCodes.Complete != nil {
+		return *m.ExitCodes.Complete
+	}
+	return DefaultErrorExitCode
+}
+
+// DefaultMaxStdinBytes is how much piped stdin is read for match.stdin when
+// no limit is configured (1 MB).
+const DefaultMaxStdinBytes = 1 << 20
+
+// MaxStdinBytesCeiling bounds a configured stdin limit (256 MB). Stdin is
+// read into memory, so an unlimited read is not supported.
+const MaxStdinBytesCeiling = 256 << 20
+
+// Limits overrides built-in size limits for a scenario.
+type Limits struct {
+	// MaxStdinBytes caps how much piped stdin is read for match.stdin.
+	// Zero means DefaultMaxStdinBytes.
+	MaxStdinBytes int64 `yaml:"max_stdin_bytes,omitempty"`
+	// MaxConsecutiveMismatches aborts replay once the same unmatched argv
+	// arrives this many times in a row. Zero disables loop detection.
+	MaxConsecutiveMismatches int `yaml:"max_consecutive_mismatches,omitempty"`
+}
+
+// Validate checks that the limits are within range.
+func (l *Limits) Validate() error {
+	if err := ValidateMaxStdinBytes(l.MaxStdinBytes); err != nil {
+		return fmt.Errorf("max_stdin_bytes %w", err)
+	}
+	if l.MaxConsecutiveMismatches < 0 {
+		return fmt.Errorf("max_consecutive_mismatches must be >= 0, got %d", l.MaxConsecutiveMismatches)
+	}
+	return nil
+}
+
+// ValidateMaxStdinBytes checks a stdin limit: zero selects the default,
+// negative values and values above MaxStdinBytesCeiling are rejected.
+func ValidateMaxStdinBytes(n int64) error {
+	if n < 0 {
+		return fmt.Errorf("must be >= 0 (unlimited is not supported), got %d", n)
+	}
+	if n > MaxStdinBytesCeiling {
+		return fmt.Errorf("must not exceed %d bytes, got %d", MaxStdinBytesCeiling, n)
+	}
+	return nil
 }
 
 // Security defines constraints on which commands may be intercepted.
 type Security struct {
 	AllowedCommands []string `yaml:"allowed_commands,omitempty"`
-	DenyEnvVars     []string `yaml:"deny_env_vars,omitempty"`
+	// ForbiddenCommands are commands the child must never run. They are
+	// intercepted even when no step names them, and any call fails the
+	// run whether or not a step would match it.
+	ForbiddenCommands []string `yaml:"forbidden_commands,omitempty"`
+	DenyEnvVars       []string `yaml:"deny_env_vars,omitempty"`
+}
+
+// FixturesRoot returns the directory stdout_file/stderr_file paths resolve
+// against: meta.fixtures_dir joined to scenarioDir, or scenarioDir itself.
+func (s *Scenario) FixturesRoot(scenarioDir string) string {
+	if s.Meta.FixturesDir == "" {
+		return scenarioDir
+	}
+	return filepath.Join(scenarioDir, s.Meta.FixturesDir)
 }
 
 // Validate checks that the meta section is valid.
@@ -311,21 +694,77 @@ func (m *Meta) Validate() error {
 	if strings.TrimSpace(m.Name) == "" {
 		return errors.New("name must be non-empty")
 	}
+	for i, tag := range m.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("tags[%d]: must be a non-empty string", i)
+		}
+	}
+	if m.MaxTotalCalls < 0 {
+		return fmt.Errorf("max_total_calls must be >= 0, got %d", m.MaxTotalCalls)
+	}
+	if filepath.IsAbs(m.FixturesDir) || filepath.VolumeName(m.FixturesDir) != "" || strings.HasPrefix(m.FixturesDir, "/") || strings.HasPrefix(m.FixturesDir, `\`) {
+		return fmt.Errorf("fixtures_dir must be relative to the scenario file, got %q", m.FixturesDir)
+	}
 	if m.Security != nil {
 		if err := m.Security.Validate(); err != nil {
 			return fmt.Errorf("security: %w", err)
 		}
 	}
+	if m.Limits != nil {
+		if err := m.Limits.Validate(); err != nil {
+			return fmt.Errorf("limits: %w", err)
+		}
+	}
+	if m.ExitCodes != nil {
+		if err := m.ExitCodes.Validate(); err != nil {
+			return fmt.Errorf("exit_codes: %w", err)
+		}
+	}
+	if err := validateStripPrefixes(m.StripPrefixes); err != nil {
+		return err
+	}
 	if m.Session != nil {
 		if err := m.Session.Validate(); err != nil {
 			return fmt.Errorf("session: %w", err)
 		}
 	}
+	if m.Fallback != nil {
+		if err := m.Fallback.Validate(); err != nil {
+			return fmt.Errorf("fallback: %w", err)
+		}
+		if len(m.Fallback.Capture) > 0 {
+			return errors.New("fallback: capture is not supported (fallback responses do not change state)")
+		}
+		if len(m.Fallback.Random) > 0 {
+			return errors.New("fallback: random is not supported (fallback responses do not change state)")
+		}
+	}
+	if m.Defaults != nil {
+		if err := m.Defaults.Validate(); err != nil {
+			return fmt.Errorf("defaults: %w", err)
+		}
+	}
+	for name, r := range m.Responses {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("responses: name must be non-empty")
+		}
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("responses.%s: %w", name, err)
+		}
+	}
 	return nil
 }
 
 // Validate checks that the security configuration is valid.
 func (s *Security) Validate() error {
+	for i, command := range s.ForbiddenCommands {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("forbidden_commands[%d]: must be non-empty", i)
+		}
+		if containsString(s.AllowedCommands, command) {
+			return fmt.Errorf("forbidden_commands[%d]: %q is also in allowed_commands", i, command)
+		}
+	}
 	for i, pattern := range s.DenyEnvVars {
 		if pattern == "" {
 			return fmt.Errorf("deny_env_vars[%d]: must be non-empty", i)
@@ -336,10 +775,16 @@ func (s *Security) Validate() error {
 
 // Step represents a single command-response pair within a scenario.
 type Step struct {
-	Match   Match       `yaml:"match"`
-	Respond Response    `yaml:"respond"`
-	Calls   *CallBounds `yaml:"calls,omitempty"`
-	When    string      `yaml:"when,omitempty"`
+	Name       string      `yaml:"name,omitempty"`
+	Match      Match       `yaml:"match"`
+	Respond    Response    `yaml:"respond"`
+	RespondRef string      `yaml:"respond_ref,omitempty"`
+	Calls      *CallBounds `yaml:"calls,omitempty"`
+	When       string      `yaml:"when,omitempty"`
+	DependsOn  []string    `yaml:"depends_on,omitempty"`
+	// ExpectWithin bounds the gap between the last call of the previous
+	// flat step and the first call of this one (Go duration format).
+	ExpectWithin string `yaml:"expect_within,omitempty"`
 }
 
 // CallBounds specifies the allowed invocation range for a step.
@@ -389,47 +834,386 @@ func (s *Step) Validate() error {
 			return fmt.Errorf("calls: %w", err)
 		}
 	}
+	within, err := s.ExpectWithinDuration()
+	if err != nil {
+		return err
+	}
+	if s.ExpectWithin != "" && within <= 0 {
+		return fmt.Errorf("expect_within %q must be positive", s.ExpectWithin)
+	}
 	return nil
 }
 
+// ExpectWithinDuration parses expect_within. Returns zero if it is not set.
+func (s *Step) ExpectWithinDuration() (time.Duration, error) {
+	if s.ExpectWithin == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s.ExpectWithin)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expect_within %q: %w", s.ExpectWithin, err)
+	}
+	return d, nil
+}
+
 // Match contains criteria for identifying an incoming CLI command.
 type Match struct {
-	Argv  []string `yaml:"argv"`
-	Stdin string   `yaml:"stdin,omitempty"`
+	Argv []string `yaml:"argv,omitempty"`
+	// AnyOf lists equivalent argv patterns; the step matches if any of them
+	// does. Exactly one of Argv and AnyOf is set.
+	AnyOf [][]string `yaml:"any_of,omitempty"`
+	Stdin string     `yaml:"stdin,omitempty"`
+	// StdinSHA256 matches stdin by the hex SHA256 digest of its normalized
+	// content instead of the content itself. Mutually exclusive with Stdin.
+	StdinSHA256 string            `yaml:"stdin_sha256,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"`
+	// Cwd restricts the step to invocations from a working directory: a
+	// path or filepath.Match glob, relative paths taken from the scenario
+	// file's directory.
+	Cwd string `yaml:"cwd,omitempty"`
+	// ArgvHash matches the listed argv positions by the SHA256 digest of
+	// the received token instead of the literal in argv, which then only
+	// holds a placeholder.
+	ArgvHash []ArgvHash `yaml:"argv_hash,omitempty"`
+	// NormalizeFlags makes --flag=value and --flag value equivalent.
+	NormalizeFlags bool `yaml:"normalize_flags,omitempty"`
+	// Wildcards makes a bare "_" argv element match any single argument.
+	// Without it, "_" is compared literally.
+	Wildcards bool `yaml:"wildcards,omitempty"`
+	// Arity is ArityExact (the default) or ArityAny, which matches on the
+	// command name alone and ignores every argument.
+	Arity string `yaml:"arity,omitempty"`
+}
+
+// Match arities.
+const (
+	// ArityExact compares every argv element.
+	ArityExact = "exact"
+	// ArityAny compares only argv[0]; any arguments are accepted.
+	ArityAny = "any"
+)
+
+// AnyArity reports whether the match ignores arguments (arity: any).
+func (m *Match) AnyArity() bool {
+	return m.Arity == ArityAny
+}
+
+// ArgvHash matches one argv position by the SHA256 digest of its value.
+type ArgvHash struct {
+	Index  int    `yaml:"index"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// sha256HexRe matches a hex-encoded SHA256 digest.
+var sha256HexRe = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// ArgvHashes returns match.argv_hash as a map from argv index to digest,
+// or nil when none are set.
+func (m *Match) ArgvHashes() map[int]string {
+	if len(m.ArgvHash) == 0 {
+		return nil
+	}
+	out := make(map[int]string, len(m.ArgvHash))
+	for _, h := range m.ArgvHash {
+		out[h.Index] = h.SHA256
+	}
+	return out
+}
+
+// Alternatives returns the argv patterns the step accepts: the any_of
+// entries, or argv alone.
+func (m *Match) Alternatives() [][]string {
+	if len(m.AnyOf) > 0 {
+		return m.AnyOf
+	}
+	if len(m.Argv) == 0 {
+		return nil
+	}
+	return [][]string{m.Argv}
+}
+
+// PrimaryArgv returns argv, or the first any_of entry, for labels and
+// diagnostics that show a single command.
+func (m *Match) PrimaryArgv() []string {
+	if len(m.AnyOf) > 0 {
+		return m.AnyOf[0]
+	}
+	return m.Argv
 }
 
 // Validate checks that the match criteria is valid.
 func (m *Match) Validate() error {
-	if len(m.Argv) == 0 {
+	if len(m.Argv) > 0 && len(m.AnyOf) > 0 {
+		return errors.New("argv and any_of are mutually exclusive")
+	}
+	if len(m.AnyOf) == 0 {
+		if err := m.validateArgv(m.Argv); err != nil {
+			return err
+		}
+	}
+	for i, argv := range m.AnyOf {
+		if err := m.validateArgv(argv); err != nil {
+			return fmt.Errorf("any_of[%d]: %w", i, err)
+		}
+	}
+	for name := range m.Env {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			return fmt.Errorf("env: invalid variable name %q", name)
+		}
+	}
+	if err := m.validateArgvHash(); err != nil {
+		return err
+	}
+	if m.StdinSHA256 != "" {
+		if m.Stdin != "" {
+			return errors.New("stdin and stdin_sha256 are mutually exclusive")
+		}
+		if !sha256HexRe.MatchString(m.StdinSHA256) {
+			return errors.New("stdin_sha256: must be a 64-character hex digest")
+		}
+	}
+	switch m.Arity {
+	case "", ArityExact:
+	case ArityAny:
+		for _, argv := range m.Alternatives() {
+			if len(argv) != 1 {
+				return fmt.Errorf("arity: any matches on the command name only, so argv must hold just the command (got %d elements)", len(argv))
+			}
+		}
+	default:
+		return fmt.Errorf("arity: must be %q or %q, got %q", ArityExact, ArityAny, m.Arity)
+	}
+	if m.Cwd != "" {
+		if _, err := filepath.Match(m.Cwd, ""); err != nil {
+			return fmt.Errorf("cwd: invalid pattern %q: %w", m.Cwd, err)
+		}
+	}
+	return nil
+}
+
+// validateArgvHash checks that every argv_hash entry names a distinct
+// position of argv and holds a SHA256 hex digest.
+func (m *Match) validateArgvHash() error {
+	if len(m.ArgvHash) == 0 {
+		return nil
+	}
+	if len(m.AnyOf) > 0 {
+		return errors.New("argv_hash: not supported with any_of")
+	}
+	if m.NormalizeFlags {
+		return errors.New("argv_hash: not supported with normalize_flags (positions would shift)")
+	}
+	seen := make(map[int]bool, len(m.ArgvHash))
+	for i, h := range m.ArgvHash {
+		if h.Index < 1 || h.Index >= len(m.Argv) {
+			return fmt.Errorf("argv_hash[%d]: index %d out of range (argv has %d elements; index 0 is the command name)", i, h.Index, len(m.Argv))
+		}
+		if seen[h.Index] {
+			return fmt.Errorf("argv_hash[%d]: duplicate index %d", i, h.Index)
+		}
+		seen[h.Index] = true
+		if !sha256HexRe.MatchString(h.SHA256) {
+			return fmt.Errorf("argv_hash[%d]: sha256 must be a 64-character hex digest", i)
+		}
+	}
+	return nil
+}
+
+// validateArgv checks one argv pattern of the match.
+func (m *Match) validateArgv(argv []string) error {
+	if len(argv) == 0 {
 		return errors.New("argv must be non-empty")
 	}
+	for i, elem := range argv {
+		if argvCaptureRe.MatchString(elem) {
+			return fmt.Errorf("argv[%d]: captures cannot be used in match.argv (they are only set after a step matches)", i)
+		}
+	}
+	if m.Wildcards && argv[0] == "_" {
+		return errors.New("argv[0]: the _ wildcard cannot stand for the command name")
+	}
 	return nil
 }
 
 // Response defines the output for a matched command.
 type Response struct {
-	Exit       int               `yaml:"exit"`
-	Stdout     string            `yaml:"stdout,omitempty"`
-	Stderr     string            `yaml:"stderr,omitempty"`
-	StdoutFile string            `yaml:"stdout_file,omitempty"`
-	StderrFile string            `yaml:"stderr_file,omitempty"`
-	Delay      string            `yaml:"delay,omitempty"`
-	Capture    map[string]string `yaml:"capture,omitempty"`
+	// Exit is the exit code to return; nil when omitted, which exits 0
+	// (see EffectiveExit). An omitted exit can be filled in by
+	// meta.defaults.respond or a respond_ref.
+	Exit         *int              `yaml:"exit,omitempty"`
+	ExitTemplate string            `yaml:"exit_template,omitempty"`
+	Stdout       string            `yaml:"stdout,omitempty"`
+	Stderr       string            `yaml:"stderr,omitempty"`
+	StdoutFile   string            `yaml:"stdout_file,omitempty"`
+	StderrFile   string            `yaml:"stderr_file,omitempty"`
+	Prepend      string            `yaml:"prepend,omitempty"`
+	Append       string            `yaml:"append,omitempty"`
+	Delay        string            `yaml:"delay,omitempty"`
+	Jitter       string            `yaml:"jitter,omitempty"`
+	Timeout      string            `yaml:"timeout,omitempty"`
+	Capture      map[string]string `yaml:"capture,omitempty"`
+	// RetryAfter, when set, appends a "cli-replay: retry-after=<d>" line
+	// to stderr and is available to templates as .retry_after.
+	RetryAfter string `yaml:"retry_after,omitempty"`
+	// Output writes ordered chunks to stdout and stderr, for tools that
+	// interleave the two. It replaces stdout, stderr, their _file forms,
+	// prepend and append.
+	Output []OutputChunk `yaml:"output,omitempty"`
+	// Random serves one of several responses per call, chosen by weight.
+	// It replaces exit, stdout and stderr; delay, timeout and capture stay
+	// on the enclosing respond.
+	Random []WeightedResponse `yaml:"random,omitempty"`
+	// Assert checks the rendered output of every call; failures are
+	// recorded in state and fail verification.
+	Assert *ResponseAssert `yaml:"assert,omitempty"`
+}
+
+// EffectiveExit returns the exit code to serve: Exit, or 0 when omitted.
+func (r *Response) EffectiveExit() int {
+	if r.Exit == nil {
+		return 0
+	}
+	return *r.Exit
+}
+
+// Output streams of a respond.output chunk.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// OutputChunk is one entry of respond.output: text, rendered as a
+// template, written to stream.
+type OutputChunk struct {
+	Stream string `yaml:"stream"`
+	Text   string `yaml:"text"`
+}
+
+// Validate checks that the chunk names a known stream.
+func (c *OutputChunk) Validate() error {
+	if c.Stream != StreamStdout && c.Stream != StreamStderr {
+		return fmt.Errorf("stream must be stdout or stderr, got %q", c.Stream)
+	}
+	return nil
+}
+
+// Exit returns a pointer to code, for setting Response.Exit.
+func Exit(code int) *int {
+	return &code
+}
+
+// WeightedResponse is one choice of respond.random. An entry is picked
+// with probability Weight divided by the sum of all weights.
+type WeightedResponse struct {
+	Weight   int      `yaml:"weight"`
+	Response Response `yaml:"response"`
+}
+
+// Validate checks that the entry has a positive weight and a response that
+// only sets output fields.
+func (w *WeightedResponse) Validate() error {
+	if w.Weight <= 0 {
+		return fmt.Errorf("weight must be > 0, got %d", w.Weight)
+	}
+	r := w.Response
+	if len(r.Random) > 0 || len(r.Capture) > 0 || r.Delay != "" || r.Jitter != "" || r.Timeout != "" {
+		return errors.New("response: random, capture, delay, jitter and timeout belong on the enclosing respond")
+	}
+	if r.Assert != nil {
+		return errors.New("response: assert belongs on the enclosing respond")
+	}
+	if r.RetryAfter != "" {
+		return errors.New("response: retry_after belongs on the enclosing respond")
+	}
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("response: %w", err)
+	}
+	return nil
+}
+
+// ParseExitCode parses the rendered value of an exit_template into an exit
+// code in range 0-255. Surrounding whitespace is ignored.
+func ParseExitCode(rendered string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(rendered))
+	if err != nil {
+		return 0, fmt.Errorf("exit_template rendered %q: not an integer", rendered)
+	}
+	if code < 0 || code > 255 {
+		return 0, fmt.Errorf("exit_template rendered %d: exit must be in range 0-255", code)
+	}
+	return code, nil
+}
+
+// TimeoutDuration parses respond.timeout. Returns zero if no timeout is set.
+func (r *Response) TimeoutDuration() (time.Duration, error) {
+	if r.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", r.Timeout, err)
+	}
+	return d, nil
+}
+
+// DelayDuration parses respond.delay. Returns zero if no delay is set.
+func (r *Response) DelayDuration() (time.Duration, error) {
+	if r.Delay == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.Delay)
+	if err != nil {
+		return 0, fmt.Errorf("invalid delay %q: %w", r.Delay, err)
+	}
+	return d, nil
+}
+
+// JitterDuration parses respond.jitter. Returns zero if no jitter is set.
+func (r *Response) JitterDuration() (time.Duration, error) {
+	if r.Jitter == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.Jitter)
+	if err != nil {
+		return 0, fmt.Errorf("invalid jitter %q: %w", r.Jitter, err)
+	}
+	return d, nil
+}
+
+// RetryAfterDuration parses respond.retry_after. Returns zero if no retry
+// hint is set.
+func (r *Response) RetryAfterDuration() (time.Duration, error) {
+	if r.RetryAfter == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.RetryAfter)
+	if err != nil {
+		return 0, fmt.Errorf("invalid retry_after %q: %w", r.RetryAfter, err)
+	}
+	return d, nil
 }
 
-// ValidateDelay checks that the delay does not exceed the given maximum.
-// A zero maxDelay disables the cap. Returns nil if no delay is set.
+// ValidateDelay checks that the longest possible wait, delay plus jitter,
+// does not exceed the given maximum. A zero maxDelay disables the cap.
+// Returns nil if neither delay nor jitter is set.
 func (r *Response) ValidateDelay(maxDelay time.Duration) error {
-	if r.Delay == "" || maxDelay == 0 {
+	if (r.Delay == "" && r.Jitter == "") || maxDelay == 0 {
 		return nil
 	}
-	d, err := time.ParseDuration(r.Delay)
+	d, err := r.DelayDuration()
 	if err != nil {
-		return fmt.Errorf("invalid delay %q: %w", r.Delay, err)
+		return err
 	}
-	if d > maxDelay {
+	j, err := r.JitterDuration()
+	if err != nil {
+		return err
+	}
+	if r.Jitter == "" && d > maxDelay {
 		return fmt.Errorf("delay %s exceeds max-delay %s", r.Delay, maxDelay)
 	}
+	if d+j > maxDelay {
+		return fmt.Errorf("delay %s + jitter %s exceeds max-delay %s", d, r.Jitter, maxDelay)
+	}
 	return nil
 }
 
@@ -438,19 +1222,69 @@ var captureIdentifierRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 
 // Validate checks that the response is valid.
 func (r *Response) Validate() error {
-	if r.Exit < 0 || r.Exit > 255 {
+	if exit := r.EffectiveExit(); exit < 0 || exit > 255 {
 		return errors.New("exit must be in range 0-255")
 	}
+	if r.ExitTemplate != "" && r.EffectiveExit() != 0 {
+		return errors.New("exit and exit_template are mutually exclusive")
+	}
 	if r.Stdout != "" && r.StdoutFile != "" {
 		return errors.New("stdout and stdout_file are mutually exclusive")
 	}
 	if r.Stderr != "" && r.StderrFile != "" {
 		return errors.New("stderr and stderr_file are mutually exclusive")
 	}
+	if len(r.Output) > 0 {
+		if r.Stdout != "" || r.StdoutFile != "" || r.Stderr != "" || r.StderrFile != "" || r.Prepend != "" || r.Append != "" {
+			return errors.New("output is mutually exclusive with stdout, stderr, stdout_file, stderr_file, prepend and append")
+		}
+		for i := range r.Output {
+			if err := r.Output[i].Validate(); err != nil {
+				return fmt.Errorf("output[%d]: %w", i, err)
+			}
+		}
+	}
+	timeout, err := r.TimeoutDuration()
+	if err != nil {
+		return err
+	}
+	if r.Timeout != "" && timeout <= 0 {
+		return fmt.Errorf("timeout %q must be positive", r.Timeout)
+	}
+	jitter, err := r.JitterDuration()
+	if err != nil {
+		return err
+	}
+	if jitter < 0 {
+		return fmt.Errorf("jitter %q must not be negative", r.Jitter)
+	}
+	retryAfter, err := r.RetryAfterDuration()
+	if err != nil {
+		return err
+	}
+	if r.RetryAfter != "" && retryAfter <= 0 {
+		return fmt.Errorf("retry_after %q must be positive", r.RetryAfter)
+	}
+	if r.Assert != nil {
+		if err := r.Assert.Validate(); err != nil {
+			return fmt.Errorf("assert: %w", err)
+		}
+	}
 	for key := range r.Capture {
 		if !captureIdentifierRe.MatchString(key) {
 			return fmt.Errorf("capture identifier %q must match [a-zA-Z_][a-zA-Z0-9_]*", key)
 		}
 	}
+	if len(r.Random) > 0 {
+		if r.EffectiveExit() != 0 || r.ExitTemplate != "" || r.Stdout != "" || r.StdoutFile != "" ||
+			r.Stderr != "" || r.StderrFile != "" || r.Prepend != "" || r.Append != "" || len(r.Output) > 0 {
+			return errors.New("random cannot be combined with exit, stdout or stderr fields (set them in each entry)")
+		}
+		for i := range r.Random {
+			if err := r.Random[i].Validate(); err != nil {
+				return fmt.Errorf("random[%d]: %w", i, err)
+			}
+		}
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package scenario
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,7 +24,7 @@ func TestScenario_Validate(t *testing.T) {
 				Steps: []StepElement{
 					{Step: &Step{
 						Match:   Match{Argv: []string{"kubectl", "get", "pods"}},
-						Respond: Response{Exit: 0, Stdout: "pod-output"},
+						Respond: Response{Exit: Exit(0), Stdout: "pod-output"},
 					}},
 				},
 			},
@@ -40,11 +41,11 @@ func TestScenario_Validate(t *testing.T) {
 				Steps: []StepElement{
 					{Step: &Step{
 						Match:   Match{Argv: []string{"cmd1"}},
-						Respond: Response{Exit: 0},
+						Respond: Response{Exit: Exit(0)},
 					}},
 					{Step: &Step{
 						Match:   Match{Argv: []string{"cmd2", "arg"}},
-						Respond: Response{Exit: 1, Stderr: "error"},
+						Respond: Response{Exit: Exit(1), Stderr: "error"},
 					}},
 				},
 			},
@@ -116,6 +117,67 @@ func TestMeta_Validate(t *testing.T) {
 			wantErr:     true,
 			errContains: "name must be non-empty",
 		},
+		{
+			name:    "tags",
+			meta:    Meta{Name: "test", Tags: []string{"smoke", "slow"}},
+			wantErr: false,
+		},
+		{
+			name:        "blank tag",
+			meta:        Meta{Name: "test", Tags: []string{"smoke", " "}},
+			wantErr:     true,
+			errContains: "tags[1]: must be a non-empty string",
+		},
+		{
+			name:    "max_total_calls set",
+			meta:    Meta{Name: "test", MaxTotalCalls: 50},
+			wantErr: false,
+		},
+		{
+			name:        "negative max_total_calls",
+			meta:        Meta{Name: "test", MaxTotalCalls: -1},
+			wantErr:     true,
+			errContains: "max_total_calls must be >= 0",
+		},
+		{
+			name:    "relative fixtures_dir",
+			meta:    Meta{Name: "test", FixturesDir: "../testdata"},
+			wantErr: false,
+		},
+		{
+			name:        "absolute fixtures_dir",
+			meta:        Meta{Name: "test", FixturesDir: "/srv/fixtures"},
+			wantErr:     true,
+			errContains: "fixtures_dir must be relative",
+		},
+		{
+			name:    "raised stdin limit",
+			meta:    Meta{Name: "test", Limits: &Limits{MaxStdinBytes: 8 << 20}},
+			wantErr: false,
+		},
+		{
+			name:        "negative stdin limit",
+			meta:        Meta{Name: "test", Limits: &Limits{MaxStdinBytes: -1}},
+			wantErr:     true,
+			errContains: "unlimited is not supported",
+		},
+		{
+			name:        "stdin limit above ceiling",
+			meta:        Meta{Name: "test", Limits: &Limits{MaxStdinBytes: MaxStdinBytesCeiling + 1}},
+			wantErr:     true,
+			errContains: "limits: max_stdin_bytes must not exceed",
+		},
+		{
+			name:    "mismatch loop limit",
+			meta:    Meta{Name: "test", Limits: &Limits{MaxConsecutiveMismatches: 5}},
+			wantErr: false,
+		},
+		{
+			name:        "negative mismatch loop limit",
+			meta:        Meta{Name: "test", Limits: &Limits{MaxConsecutiveMismatches: -1}},
+			wantErr:     true,
+			errContains: "limits: max_consecutive_mismatches must be >= 0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -142,7 +204,7 @@ func TestStep_Validate(t *testing.T) {
 			name: "valid step",
 			step: Step{
 				Match:   Match{Argv: []string{"cmd"}},
-				Respond: Response{Exit: 0},
+				Respond: Response{Exit: Exit(0)},
 			},
 			wantErr: false,
 		},
@@ -151,7 +213,7 @@ func TestStep_Validate(t *testing.T) {
 			step: Step{
 				Match: Match{Argv: []string{"cmd", "arg1", "arg2"}},
 				Respond: Response{
-					Exit:   1,
+					Exit:   Exit(1),
 					Stdout: "out",
 					Stderr: "err",
 				},
@@ -190,6 +252,57 @@ func TestMatch_Validate(t *testing.T) {
 			match:   Match{Argv: []string{"cmd", "arg1", "arg2"}},
 			wantErr: false,
 		},
+		{
+			name:    "valid argv_hash",
+			match:   Match{Argv: []string{"cmd", "<blob>"}, ArgvHash: []ArgvHash{{Index: 1, SHA256: "abababababababababababababababababababababababababababababababab"}}},
+			wantErr: false,
+		},
+		{
+			name:        "argv_hash index out of range",
+			match:       Match{Argv: []string{"cmd", "<blob>"}, ArgvHash: []ArgvHash{{Index: 2, SHA256: "abababababababababababababababababababababababababababababababab"}}},
+			wantErr:     true,
+			errContains: "argv_hash[0]: index 2 out of range",
+		},
+		{
+			name:        "argv_hash on command name",
+			match:       Match{Argv: []string{"cmd", "<blob>"}, ArgvHash: []ArgvHash{{Index: 0, SHA256: "abababababababababababababababababababababababababababababababab"}}},
+			wantErr:     true,
+			errContains: "out of range",
+		},
+		{
+			name:        "argv_hash invalid digest",
+			match:       Match{Argv: []string{"cmd", "<blob>"}, ArgvHash: []ArgvHash{{Index: 1, SHA256: "abc"}}},
+			wantErr:     true,
+			errContains: "64-character hex digest",
+		},
+		{
+			name:    "arity any with command only",
+			match:   Match{Argv: []string{"git"}, Arity: ArityAny},
+			wantErr: false,
+		},
+		{
+			name:        "arity any with arguments",
+			match:       Match{Argv: []string{"git", "log"}, Arity: ArityAny},
+			wantErr:     true,
+			errContains: "argv must hold just the command",
+		},
+		{
+			name:        "unknown arity",
+			match:       Match{Argv: []string{"git"}, Arity: "loose"},
+			wantErr:     true,
+			errContains: `arity: must be "exact" or "any", got "loose"`,
+		},
+		{
+			name:    "valid cwd glob",
+			match:   Match{Argv: []string{"cmd"}, Cwd: "services/*"},
+			wantErr: false,
+		},
+		{
+			name:        "invalid cwd pattern",
+			match:       Match{Argv: []string{"cmd"}, Cwd: "app/[x"},
+			wantErr:     true,
+			errContains: "cwd: invalid pattern",
+		},
 		{
 			name:        "empty argv",
 			match:       Match{Argv: []string{}},
@@ -202,6 +315,39 @@ func TestMatch_Validate(t *testing.T) {
 			wantErr:     true,
 			errContains: "argv must be non-empty",
 		},
+		{
+			name:    "literal underscore without wildcards",
+			match:   Match{Argv: []string{"_", "arg"}},
+			wantErr: false,
+		},
+		{
+			name:    "wildcard argument",
+			match:   Match{Argv: []string{"cp", "_", "/dest"}, Wildcards: true},
+			wantErr: false,
+		},
+		{
+			name:        "wildcard command name",
+			match:       Match{Argv: []string{"_", "/dest"}, Wildcards: true},
+			wantErr:     true,
+			errContains: "argv[0]: the _ wildcard cannot stand for the command name",
+		},
+		{
+			name:    "any_of alternatives",
+			match:   Match{AnyOf: [][]string{{"kubectl", "get", "po"}, {"kubectl", "get", "pods"}}},
+			wantErr: false,
+		},
+		{
+			name:        "argv and any_of both set",
+			match:       Match{Argv: []string{"kubectl"}, AnyOf: [][]string{{"kubectl"}}},
+			wantErr:     true,
+			errContains: "argv and any_of are mutually exclusive",
+		},
+		{
+			name:        "empty any_of entry",
+			match:       Match{AnyOf: [][]string{{"kubectl"}, {}}},
+			wantErr:     true,
+			errContains: "any_of[1]: argv must be non-empty",
+		},
 	}
 
 	for _, tt := range tests {
@@ -281,7 +427,7 @@ func TestStep_EffectiveCalls(t *testing.T) {
 	t.Run("nil calls returns default {1,1}", func(t *testing.T) {
 		step := Step{
 			Match:   Match{Argv: []string{"cmd"}},
-			Respond: Response{Exit: 0},
+			Respond: Response{Exit: Exit(0)},
 		}
 		ec := step.EffectiveCalls()
 		assert.Equal(t, 1, ec.Min)
@@ -291,7 +437,7 @@ func TestStep_EffectiveCalls(t *testing.T) {
 	t.Run("explicit calls returned as-is", func(t *testing.T) {
 		step := Step{
 			Match:   Match{Argv: []string{"cmd"}},
-			Respond: Response{Exit: 0},
+			Respond: Response{Exit: Exit(0)},
 			Calls:   &CallBounds{Min: 2, Max: 5},
 		}
 		ec := step.EffectiveCalls()
@@ -311,7 +457,7 @@ func TestStep_Validate_WithCalls(t *testing.T) {
 			name: "valid step with calls",
 			step: Step{
 				Match:   Match{Argv: []string{"cmd"}},
-				Respond: Response{Exit: 0},
+				Respond: Response{Exit: Exit(0)},
 				Calls:   &CallBounds{Min: 1, Max: 5},
 			},
 			wantErr: false,
@@ -320,7 +466,7 @@ func TestStep_Validate_WithCalls(t *testing.T) {
 			name: "calls min only defaults max to min",
 			step: Step{
 				Match:   Match{Argv: []string{"cmd"}},
-				Respond: Response{Exit: 0},
+				Respond: Response{Exit: Exit(0)},
 				Calls:   &CallBounds{Min: 3, Max: 0},
 			},
 			wantErr: false, // defaulting: max = min = 3
@@ -329,7 +475,7 @@ func TestStep_Validate_WithCalls(t *testing.T) {
 			name: "calls max:0 min:0 rejected",
 			step: Step{
 				Match:   Match{Argv: []string{"cmd"}},
-				Respond: Response{Exit: 0},
+				Respond: Response{Exit: Exit(0)},
 				Calls:   &CallBounds{Min: 0, Max: 0},
 			},
 			wantErr:     true,
@@ -339,7 +485,7 @@ func TestStep_Validate_WithCalls(t *testing.T) {
 			name: "calls min > max rejected",
 			step: Step{
 				Match:   Match{Argv: []string{"cmd"}},
-				Respond: Response{Exit: 0},
+				Respond: Response{Exit: Exit(0)},
 				Calls:   &CallBounds{Min: 5, Max: 3},
 			},
 			wantErr:     true,
@@ -536,6 +682,35 @@ func TestSecurity_DenyEnvVars_Validation(t *testing.T) {
 	})
 }
 
+func TestSecurity_ForbiddenCommands_Validation(t *testing.T) {
+	t.Run("empty entry rejected", func(t *testing.T) {
+		sec := Security{ForbiddenCommands: []string{"curl", ""}}
+		err := sec.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "forbidden_commands[1]: must be non-empty")
+	})
+
+	t.Run("entry also allowed rejected", func(t *testing.T) {
+		sec := Security{AllowedCommands: []string{"kubectl", "curl"}, ForbiddenCommands: []string{"curl"}}
+		err := sec.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `forbidden_commands[0]: "curl" is also in allowed_commands`)
+	})
+
+	t.Run("step using a forbidden command rejected", func(t *testing.T) {
+		scn := Scenario{
+			Meta: Meta{Name: "test", Security: &Security{ForbiddenCommands: []string{"curl"}}},
+			Steps: []StepElement{
+				{Step: &Step{Match: Match{Argv: []string{"kubectl", "get", "pods"}}}},
+				{Step: &Step{Match: Match{Argv: []string{"/usr/bin/curl", "https://example.com"}}}},
+			},
+		}
+		err := scn.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `step 1: command "curl" is in meta.security.forbidden_commands`)
+	})
+}
+
 func TestDenyEnvVarsYAMLParsing(t *testing.T) {
 	yamlContent := `
 meta:
@@ -620,6 +795,17 @@ func TestSession_Validate(t *testing.T) {
 			wantErr:     true,
 			errContains: "ttl must be positive",
 		},
+		{
+			name:    "auto pid",
+			session: Session{Auto: "pid"},
+			wantErr: false,
+		},
+		{
+			name:        "unknown auto mode",
+			session:     Session{Auto: "tty"},
+			wantErr:     true,
+			errContains: "invalid auto",
+		},
 	}
 
 	for _, tt := range tests {
@@ -722,90 +908,118 @@ func TestResponse_Validate(t *testing.T) {
 	}{
 		{
 			name:     "valid response with exit only",
-			response: Response{Exit: 0},
+			response: Response{Exit: Exit(0)},
 			wantErr:  false,
 		},
 		{
 			name:     "valid response with stdout",
-			response: Response{Exit: 0, Stdout: "output"},
+			response: Response{Exit: Exit(0), Stdout: "output"},
 			wantErr:  false,
 		},
 		{
 			name:     "valid response with stderr",
-			response: Response{Exit: 1, Stderr: "error"},
+			response: Response{Exit: Exit(1), Stderr: "error"},
 			wantErr:  false,
 		},
 		{
 			name:     "valid response with stdout_file",
-			response: Response{Exit: 0, StdoutFile: "file.txt"},
+			response: Response{Exit: Exit(0), StdoutFile: "file.txt"},
 			wantErr:  false,
 		},
 		{
 			name:     "valid response with stderr_file",
-			response: Response{Exit: 1, StderrFile: "error.txt"},
+			response: Response{Exit: Exit(1), StderrFile: "error.txt"},
 			wantErr:  false,
 		},
 		{
 			name:     "valid exit code 255",
-			response: Response{Exit: 255},
+			response: Response{Exit: Exit(255)},
 			wantErr:  false,
 		},
 		{
 			name:        "exit code negative",
-			response:    Response{Exit: -1},
+			response:    Response{Exit: Exit(-1)},
 			wantErr:     true,
 			errContains: "exit must be in range 0-255",
 		},
 		{
 			name:        "exit code too large",
-			response:    Response{Exit: 256},
+			response:    Response{Exit: Exit(256)},
 			wantErr:     true,
 			errContains: "exit must be in range 0-255",
 		},
 		{
 			name:        "stdout and stdout_file mutually exclusive",
-			response:    Response{Exit: 0, Stdout: "out", StdoutFile: "file.txt"},
+			response:    Response{Exit: Exit(0), Stdout: "out", StdoutFile: "file.txt"},
 			wantErr:     true,
 			errContains: "stdout and stdout_file are mutually exclusive",
 		},
 		{
 			name:        "stderr and stderr_file mutually exclusive",
-			response:    Response{Exit: 0, Stderr: "err", StderrFile: "file.txt"},
+			response:    Response{Exit: Exit(0), Stderr: "err", StderrFile: "file.txt"},
 			wantErr:     true,
 			errContains: "stderr and stderr_file are mutually exclusive",
 		},
+		{
+			name:     "valid exit_template",
+			response: Response{ExitTemplate: "{{ .capture.status }}"},
+			wantErr:  false,
+		},
+		{
+			name:        "nonzero exit with exit_template rejected",
+			response:    Response{Exit: Exit(1), ExitTemplate: "{{ .capture.status }}"},
+			wantErr:     true,
+			errContains: "exit and exit_template are mutually exclusive",
+		},
+		{
+			name:     "valid timeout",
+			response: Response{Exit: Exit(0), Timeout: "500ms"},
+			wantErr:  false,
+		},
+		{
+			name:        "invalid timeout duration",
+			response:    Response{Exit: Exit(0), Timeout: "soon"},
+			wantErr:     true,
+			errContains: "invalid timeout",
+		},
+		{
+			name:        "non-positive timeout rejected",
+			response:    Response{Exit: Exit(0), Timeout: "0s"},
+			wantErr:     true,
+			errContains: "must be positive",
+		},
 		// T011: Capture identifier validation tests
 		{
 			name:     "valid capture identifiers",
-			response: Response{Exit: 0, Capture: map[string]string{"rg_id": "val", "_underscore": "v", "CamelCase": "v"}},
+			response: Response{Exit: Exit(0), Capture: map[string]string{"rg_id": "val", "_underscore": "v", "CamelCase": "v"}},
 			wantErr:  false,
 		},
 		{
 			name:     "valid capture with empty map",
-			response: Response{Exit: 0, Capture: map[string]string{}},
+			response: Response{Exit: Exit(0), Capture: map[string]string{}},
 			wantErr:  false,
 		},
 		{
 			name:        "capture identifier starting with digit rejected",
-			response:    Response{Exit: 0, Capture: map[string]string{"1bad": "val"}},
+			response:    Response{Exit: Exit(0), Capture: map[string]string{"1bad": "val"}},
 			wantErr:     true,
 			errContains: "capture identifier \"1bad\" must match",
 		},
 		{
 			name:        "capture identifier with hyphen rejected",
-			response:    Response{Exit: 0, Capture: map[string]string{"my-id": "val"}},
+			response:    Response{Exit: Exit(0), Capture: map[string]string{"my-id": "val"}},
 			wantErr:     true,
 			errContains: "capture identifier \"my-id\" must match",
 		},
 		{
 			name:        "capture identifier with spaces rejected",
-			response:    Response{Exit: 0, Capture: map[string]string{"my id": "val"}},
+			response:    Response{Exit: Exit(0), Capture: map[string]string{"my id": "val"}},
 			wantErr:     true,
 			errContains: "capture identifier \"my id\" must match",
 		},
 		{
 			name:        "capture empty key rejected",
-			response:    Response{Exit: 0, Capture: map[string]string{"": "val"}},
+			response:    Response{Exit: Exit(0), Capture: map[string]string{"": "val"}},
 			wantErr:     true,
 			errContains: "must match",
 		},
@@ -836,7 +1050,7 @@ func TestStepElement_Validate(t *testing.T) {
 		{
 			name: "valid leaf step",
 			elem: StepElement{Step: &Step{
-				Match: Match{Argv: []string{"cmd"}}, Respond: Response{Exit: 0},
+				Match: Match{Argv: []string{"cmd"}}, Respond: Response{Exit: Exit(0)},
 			}},
 		},
 		{
@@ -845,7 +1059,7 @@ func TestStepElement_Validate(t *testing.T) {
 				Mode: "unordered",
 				Name: "g1",
 				Steps: []StepElement{
-					{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}},
+					{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
 				},
 			}},
 		},
@@ -858,8 +1072,8 @@ func TestStepElement_Validate(t *testing.T) {
 		{
 			name: "both step and group",
 			elem: StepElement{
-				Step:  &Step{Match: Match{Argv: []string{"cmd"}}, Respond: Response{Exit: 0}},
-				Group: &StepGroup{Mode: "unordered", Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}}}},
+				Step:  &Step{Match: Match{Argv: []string{"cmd"}}, Respond: Response{Exit: Exit(0)}},
+				Group: &StepGroup{Mode: "unordered", Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}}}},
 			},
 			wantErr:     true,
 			errContains: "not both",
@@ -891,8 +1105,8 @@ func TestStepGroup_Validate(t *testing.T) {
 			group: StepGroup{
 				Mode: "unordered",
 				Steps: []StepElement{
-					{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}},
-					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: 0}}},
+					{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}},
 				},
 			},
 		},
@@ -908,7 +1122,7 @@ func TestStepGroup_Validate(t *testing.T) {
 				Mode: "unordered",
 				Steps: []StepElement{
 					{Group: &StepGroup{Mode: "unordered", Steps: []StepElement{
-						{Step: &Step{Match: Match{Argv: []string{"x"}}, Respond: Response{Exit: 0}}},
+						{Step: &Step{Match: Match{Argv: []string{"x"}}, Respond: Response{Exit: Exit(0)}}},
 					}}},
 				},
 			},
@@ -918,8 +1132,8 @@ func TestStepGroup_Validate(t *testing.T) {
 		{
 			name: "unknown mode rejected",
 			group: StepGroup{
-				Mode:  "ordered",
-				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}}},
+				Mode:  "random",
+				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}}},
 			},
 			wantErr:     true,
 			errContains: "unsupported group mode",
@@ -933,6 +1147,84 @@ func TestStepGroup_Validate(t *testing.T) {
 			wantErr:     true,
 			errContains: "group children must be leaf steps",
 		},
+		{
+			name: "valid ordered group",
+			group: StepGroup{
+				Mode: "ordered",
+				Steps: []StepElement{
+					{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}},
+				},
+			},
+		},
+		{
+			name: "depends_on in ordered group rejected",
+			group: StepGroup{
+				Mode: "ordered",
+				Steps: []StepElement{
+					{Step: &Step{Name: "a", Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}, DependsOn: []string{"a"}}},
+				},
+			},
+			wantErr:     true,
+			errContains: "depends_on is only supported in unordered groups",
+		},
+		{
+			name: "valid depends_on",
+			group: StepGroup{
+				Mode: "unordered",
+				Steps: []StepElement{
+					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}, DependsOn: []string{"a"}}},
+					{Step: &Step{Name: "a", Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
+				},
+			},
+		},
+		{
+			name: "depends_on unknown name rejected",
+			group: StepGroup{
+				Mode: "unordered",
+				Steps: []StepElement{
+					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}, DependsOn: []string{"missing"}}},
+				},
+			},
+			wantErr:     true,
+			errContains: `depends_on "missing" does not name a step in this group`,
+		},
+		{
+			name: "depends_on self rejected",
+			group: StepGroup{
+				Mode: "unordered",
+				Steps: []StepElement{
+					{Step: &Step{Name: "a", Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}, DependsOn: []string{"a"}}},
+				},
+			},
+			wantErr:     true,
+			errContains: "refers to itself",
+		},
+		{
+			name: "depends_on cycle rejected",
+			group: StepGroup{
+				Mode: "unordered",
+				Steps: []StepElement{
+					{Step: &Step{Name: "a", Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}, DependsOn: []string{"b"}}},
+					{Step: &Step{Name: "b", Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}, DependsOn: []string{"a"}}},
+				},
+			},
+			wantErr:     true,
+			errContains: "depends_on cycle detected: a -> b -> a",
+		},
+		{
+			name: "duplicate step name rejected",
+			group: StepGroup{
+				Mode: "unordered",
+				Steps: []StepElement{
+					{Step: &Step{Name: "a", Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Name: "a", Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}},
+				},
+			},
+			wantErr:     true,
+			errContains: `duplicate step name "a"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -948,18 +1240,234 @@ func TestStepGroup_Validate(t *testing.T) {
 	}
 }
 
+func TestMeta_FallbackValidation(t *testing.T) {
+	valid := Meta{Name: "m", Fallback: &Response{Exit: Exit(0), Stdout: "ok"}}
+	assert.NoError(t, valid.Validate())
+
+	badExit := Meta{Name: "m", Fallback: &Response{Exit: Exit(300)}}
+	err := badExit.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fallback: exit must be in range 0-255")
+
+	withCapture := Meta{Name: "m", Fallback: &Response{Capture: map[string]string{"id": "x"}}}
+	err = withCapture.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "capture is not supported")
+}
+
+func TestResponse_ValidateRandom(t *testing.T) {
+	entry := func(weight int, r Response) WeightedResponse { return WeightedResponse{Weight: weight, Response: r} }
+	tests := []struct {
+		name        string
+		resp        Response
+		errContains string
+	}{
+		{"valid", Response{Random: []WeightedResponse{entry(1, Response{Stdout: "ok"}), entry(3, Response{Exit: Exit(1)})}, Delay: "10ms"}, ""},
+		{"zero weight", Response{Random: []WeightedResponse{entry(0, Response{})}}, "random[0]: weight must be > 0, got 0"},
+		{"negative weight", Response{Random: []WeightedResponse{entry(1, Response{}), entry(-2, Response{})}}, "random[1]: weight must be > 0, got -2"},
+		{"combined with stdout", Response{Stdout: "x", Random: []WeightedResponse{entry(1, Response{})}}, "random cannot be combined with exit, stdout or stderr fields"},
+		{"combined with exit", Response{Exit: Exit(2), Random: []WeightedResponse{entry(1, Response{})}}, "random cannot be combined"},
+		{"nested random", Response{Random: []WeightedResponse{entry(1, Response{Random: []WeightedResponse{entry(1, Response{})}})}}, "random[0]: response: random, capture, delay, jitter and timeout belong on the enclosing respond"},
+		{"nested delay", Response{Random: []WeightedResponse{entry(1, Response{Delay: "1s"})}}, "belong on the enclosing respond"},
+		{"invalid entry", Response{Random: []WeightedResponse{entry(1, Response{Exit: Exit(300)})}}, "random[0]: response: exit must be in range 0-255"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.resp.Validate()
+			if tt.errContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+
+	fallback := Meta{Name: "m", Fallback: &Response{Random: []WeightedResponse{entry(1, Response{})}}}
+	err := fallback.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fallback: random is not supported")
+}
+
+func TestResponse_Jitter(t *testing.T) {
+	tests := []struct {
+		name        string
+		resp        Response
+		maxDelay    time.Duration
+		errContains string
+	}{
+		{"within cap", Response{Delay: "1s", Jitter: "500ms"}, 2 * time.Second, ""},
+		{"total equals cap", Response{Delay: "1s", Jitter: "1s"}, 2 * time.Second, ""},
+		{"jitter only", Response{Jitter: "3s"}, 2 * time.Second, "delay 0s + jitter 3s exceeds max-delay 2s"},
+		{"total exceeds cap", Response{Delay: "1500ms", Jitter: "1s"}, 2 * time.Second, "delay 1.5s + jitter 1s exceeds max-delay 2s"},
+		{"no cap", Response{Delay: "1h", Jitter: "1h"}, 0, ""},
+		{"invalid jitter", Response{Jitter: "soon"}, 2 * time.Second, "invalid jitter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.resp.ValidateDelay(tt.maxDelay)
+			if tt.errContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+
+	err := (&Response{Jitter: "-1s"}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `jitter "-1s" must not be negative`)
+
+	err = (&Response{Jitter: "soon"}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid jitter "soon"`)
+}
+
+func TestResponse_RetryAfter(t *testing.T) {
+	d, err := (&Response{RetryAfter: "2s"}).RetryAfterDuration()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, d)
+	assert.NoError(t, (&Response{RetryAfter: "2s"}).Validate())
+
+	err = (&Response{RetryAfter: "later"}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid retry_after "later"`)
+
+	err = (&Response{RetryAfter: "0s"}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `retry_after "0s" must be positive`)
+
+	err = (&WeightedResponse{Weight: 1, Response: Response{RetryAfter: "2s"}}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "retry_after belongs on the enclosing respond")
+}
+
+func TestResponse_Output(t *testing.T) {
+	chunks := []OutputChunk{{Stream: "stderr", Text: "progress 50%\n"}, {Stream: "stdout", Text: "data\n"}}
+	assert.NoError(t, (&Response{Output: chunks}).Validate())
+
+	err := (&Response{Output: chunks, Stdout: "data\n"}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output is mutually exclusive with stdout")
+
+	err = (&Response{Output: chunks, StderrFile: "err.txt"}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output is mutually exclusive")
+
+	err = (&Response{Output: []OutputChunk{{Stream: "stdlog", Text: "x"}}}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `output[0]: stream must be stdout or stderr, got "stdlog"`)
+
+	err = (&Response{Output: chunks, Random: []WeightedResponse{{Weight: 1, Response: Response{Stdout: "a"}}}}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "random cannot be combined")
+
+	assert.NoError(t, (&WeightedResponse{Weight: 1, Response: Response{Output: chunks}}).Validate())
+}
+
+func TestScenario_Validate_ForwardReferenceInOutput(t *testing.T) {
+	scn := Scenario{
+		Meta: Meta{Name: "output-forward-ref"},
+		Steps: []StepElement{
+			{Step: &Step{
+				Match:   Match{Argv: []string{"cmd1"}},
+				Respond: Response{Output: []OutputChunk{{Stream: "stdout", Text: "id={{ .capture.id }}"}}},
+			}},
+			{Step: &Step{
+				Match:   Match{Argv: []string{"cmd2"}},
+				Respond: Response{Capture: map[string]string{"id": "1"}},
+			}},
+		},
+	}
+	err := scn.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "forward reference")
+}
+
+func TestMeta_ExitCodes(t *testing.T) {
+	code := func(n int) *int { return &n }
+
+	var unset Meta
+	assert.Equal(t, DefaultErrorExitCode, unset.MismatchExitCode())
+	assert.Equal(t, DefaultErrorExitCode, unset.CompleteExitCode())
+
+	set := Meta{Name: "m", ExitCodes: &ExitCodes{Mismatch: code(97), Complete: code(0)}}
+	require.NoError(t, set.Validate())
+	assert.Equal(t, 97, set.MismatchExitCode())
+	assert.Equal(t, 0, set.CompleteExitCode())
+
+	err := (&Meta{Name: "m", ExitCodes: &ExitCodes{Mismatch: code(256)}}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit_codes: mismatch must be in range 0-255, got 256")
+
+	err = (&Meta{Name: "m", ExitCodes: &ExitCodes{Complete: code(-1)}}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit_codes: complete must be in range 0-255, got -1")
+}
+
+func TestScenario_Validate_ExitCodeCollisions(t *testing.T) {
+	code := func(n int) *int { return &n }
+	build := func(codes *ExitCodes, exits ...int) Scenario {
+		scn := Scenario{Meta: Meta{Name: "codes", ExitCodes: codes}}
+		for _, exit := range exits {
+			scn.Steps = append(scn.Steps, StepElement{Step: &Step{Match: Match{Argv: []string{"cmd"}}, Respond: Response{Exit: Exit(exit)}}})
+		}
+		return scn
+	}
+
+	ok := build(&ExitCodes{Mismatch: code(97), Complete: code(98)}, 0, 1)
+	assert.NoError(t, ok.Validate())
+
+	defaults := build(nil, 1)
+	assert.NoError(t, defaults.Validate(), "the default code may be shared with steps")
+
+	mismatch := build(&ExitCodes{Mismatch: code(97)}, 0, 97)
+	err := mismatch.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step 1: exit 97 is also meta.exit_codes.mismatch")
+
+	complete := build(&ExitCodes{Complete: code(98)}, 98)
+	err = complete.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step 0: exit 98 is also meta.exit_codes.complete")
+
+	fallback := build(&ExitCodes{Mismatch: code(97)}, 0)
+	fallback.Meta.Fallback = &Response{Exit: Exit(97)}
+	err = fallback.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "meta.fallback: exit 97 is also meta.exit_codes.mismatch")
+
+	templated := build(&ExitCodes{Mismatch: code(97)})
+	templated.Steps = append(templated.Steps, StepElement{Step: &Step{Match: Match{Argv: []string{"cmd"}}, Respond: Response{ExitTemplate: "97"}}})
+	assert.NoError(t, templated.Validate(), "templated exits are not checked")
+}
+
+func TestScenario_DependsOnOutsideGroupRejected(t *testing.T) {
+	scn := Scenario{
+		Meta: Meta{Name: "deps"},
+		Steps: []StepElement{
+			{Step: &Step{Name: "a", Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
+			{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}, DependsOn: []string{"a"}}},
+		},
+	}
+	err := scn.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "depends_on is only supported on unordered group children")
+}
+
 func TestScenario_AutoNamingGroups(t *testing.T) {
 	scn := Scenario{
 		Meta: Meta{Name: "group-test"},
 		Steps: []StepElement{
-			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
 			{Group: &StepGroup{
 				Mode:  "unordered",
-				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: 0}}}},
+				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}}},
 			}},
 			{Group: &StepGroup{
 				Mode:  "unordered",
-				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"c"}}, Respond: Response{Exit: 0}}}},
+				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"c"}}, Respond: Response{Exit: Exit(0)}}}},
 			}},
 		},
 	}
@@ -976,11 +1484,11 @@ func TestScenario_ExplicitNamePreserved(t *testing.T) {
 		Steps: []StepElement{
 			{Group: &StepGroup{
 				Mode: "unordered", Name: "pre-flight",
-				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}}},
+				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}}},
 			}},
 			{Group: &StepGroup{
 				Mode:  "unordered",
-				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: 0}}}},
+				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}}},
 			}},
 		},
 	}
@@ -994,15 +1502,15 @@ func TestScenario_FlatSteps(t *testing.T) {
 	scn := Scenario{
 		Meta: Meta{Name: "flat-test"},
 		Steps: []StepElement{
-			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
 			{Group: &StepGroup{
 				Mode: "unordered", Name: "g1",
 				Steps: []StepElement{
-					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: 0}}},
-					{Step: &Step{Match: Match{Argv: []string{"c"}}, Respond: Response{Exit: 0}}},
+					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"c"}}, Respond: Response{Exit: Exit(0)}}},
 				},
 			}},
-			{Step: &Step{Match: Match{Argv: []string{"d"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"d"}}, Respond: Response{Exit: Exit(0)}}},
 		},
 	}
 
@@ -1018,19 +1526,19 @@ func TestScenario_GroupRanges(t *testing.T) {
 	scn := Scenario{
 		Meta: Meta{Name: "ranges-test"},
 		Steps: []StepElement{
-			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
 			{Group: &StepGroup{
 				Mode: "unordered", Name: "g1",
 				Steps: []StepElement{
-					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: 0}}},
-					{Step: &Step{Match: Match{Argv: []string{"c"}}, Respond: Response{Exit: 0}}},
+					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"c"}}, Respond: Response{Exit: Exit(0)}}},
 				},
 			}},
-			{Step: &Step{Match: Match{Argv: []string{"d"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"d"}}, Respond: Response{Exit: Exit(0)}}},
 			{Group: &StepGroup{
 				Mode: "unordered", Name: "g2",
 				Steps: []StepElement{
-					{Step: &Step{Match: Match{Argv: []string{"e"}}, Respond: Response{Exit: 0}}},
+					{Step: &Step{Match: Match{Argv: []string{"e"}}, Respond: Response{Exit: Exit(0)}}},
 				},
 			}},
 		},
@@ -1039,8 +1547,8 @@ func TestScenario_GroupRanges(t *testing.T) {
 	ranges := scn.GroupRanges()
 	require.Len(t, ranges, 2)
 
-	assert.Equal(t, GroupRange{Start: 1, End: 3, Name: "g1", TopIndex: 1}, ranges[0])
-	assert.Equal(t, GroupRange{Start: 4, End: 5, Name: "g2", TopIndex: 3}, ranges[1])
+	assert.Equal(t, GroupRange{Start: 1, End: 3, Name: "g1", Mode: "unordered", TopIndex: 1}, ranges[0])
+	assert.Equal(t, GroupRange{Start: 4, End: 5, Name: "g2", Mode: "unordered", TopIndex: 3}, ranges[1])
 }
 
 // T012: Capture-vs-vars conflict and forward-reference detection tests
@@ -1054,7 +1562,7 @@ func TestScenario_Validate_CaptureVarsConflict(t *testing.T) {
 		Steps: []StepElement{
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd"}},
-				Respond: Response{Exit: 0, Capture: map[string]string{"region": "westus"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"region": "westus"}},
 			}},
 		},
 	}
@@ -1072,7 +1580,7 @@ func TestScenario_Validate_CaptureNoConflict(t *testing.T) {
 		Steps: []StepElement{
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd"}},
-				Respond: Response{Exit: 0, Capture: map[string]string{"rg_id": "val"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"rg_id": "val"}},
 			}},
 		},
 	}
@@ -1085,11 +1593,11 @@ func TestScenario_Validate_ForwardReference(t *testing.T) {
 		Steps: []StepElement{
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd1"}},
-				Respond: Response{Exit: 0, Stdout: "val={{ .capture.vm_id }}"},
+				Respond: Response{Exit: Exit(0), Stdout: "val={{ .capture.vm_id }}"},
 			}},
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd2"}},
-				Respond: Response{Exit: 0, Capture: map[string]string{"vm_id": "vm-1"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"vm_id": "vm-1"}},
 			}},
 		},
 	}
@@ -1104,11 +1612,11 @@ func TestScenario_Validate_NoForwardReference(t *testing.T) {
 		Steps: []StepElement{
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd1"}},
-				Respond: Response{Exit: 0, Capture: map[string]string{"rg_id": "val"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"rg_id": "val"}},
 			}},
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd2"}},
-				Respond: Response{Exit: 0, Stdout: "rg={{ .capture.rg_id }}"},
+				Respond: Response{Exit: Exit(0), Stdout: "rg={{ .capture.rg_id }}"},
 			}},
 		},
 	}
@@ -1121,11 +1629,11 @@ func TestScenario_Validate_CaptureInStderr(t *testing.T) {
 		Steps: []StepElement{
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd1"}},
-				Respond: Response{Exit: 0, Stderr: "err={{ .capture.x }}"},
+				Respond: Response{Exit: Exit(0), Stderr: "err={{ .capture.x }}"},
 			}},
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd2"}},
-				Respond: Response{Exit: 0, Capture: map[string]string{"x": "val"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"x": "val"}},
 			}},
 		},
 	}
@@ -1134,6 +1642,29 @@ func TestScenario_Validate_CaptureInStderr(t *testing.T) {
 	assert.Contains(t, err.Error(), "forward reference")
 }
 
+func TestScenario_Validate_ForwardReferenceInCaptureValue(t *testing.T) {
+	scn := Scenario{
+		Meta: Meta{Name: "capture-forward-ref"},
+		Steps: []StepElement{
+			{Step: &Step{
+				Match:   Match{Argv: []string{"cmd1"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"label": "rg-{{ .capture.rg_id }}"}},
+			}},
+			{Step: &Step{
+				Match:   Match{Argv: []string{"cmd2"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"rg_id": "val"}},
+			}},
+		},
+	}
+	err := scn.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `step 0 references capture "rg_id" first defined at step 1 (forward reference)`)
+
+	// Referencing an earlier step's capture is fine
+	scn.Steps[0], scn.Steps[1] = scn.Steps[1], scn.Steps[0]
+	assert.NoError(t, scn.Validate())
+}
+
 func TestScenario_Validate_UndefinedCaptureNotAnError(t *testing.T) {
 	// Referencing a capture that is never defined is NOT a validation error
 	// (it will resolve to empty string at runtime for unordered groups/optional steps)
@@ -1142,7 +1673,7 @@ func TestScenario_Validate_UndefinedCaptureNotAnError(t *testing.T) {
 		Steps: []StepElement{
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd"}},
-				Respond: Response{Exit: 0, Stdout: "val={{ .capture.nonexistent }}"},
+				Respond: Response{Exit: Exit(0), Stdout: "val={{ .capture.nonexistent }}"},
 			}},
 		},
 	}
@@ -1160,13 +1691,13 @@ func TestScenario_Validate_CaptureWithGroup(t *testing.T) {
 				Steps: []StepElement{
 					{Step: &Step{
 						Match:   Match{Argv: []string{"cmd1"}},
-						Respond: Response{Exit: 0, Capture: map[string]string{"id": "val"}},
+						Respond: Response{Exit: Exit(0), Capture: map[string]string{"id": "val"}},
 					}},
 				},
 			}},
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd2"}},
-				Respond: Response{Exit: 0, Stdout: "id={{ .capture.id }}"},
+				Respond: Response{Exit: Exit(0), Stdout: "id={{ .capture.id }}"},
 			}},
 		},
 	}
@@ -1232,3 +1763,193 @@ steps:
 	require.NoError(t, err)
 	assert.Nil(t, scn.Steps[0].Step.Respond.Capture)
 }
+
+func TestParseExitCode(t *testing.T) {
+	tests := []struct {
+		rendered    string
+		want        int
+		errContains string
+	}{
+		{rendered: "0", want: 0},
+		{rendered: " 42\n", want: 42},
+		{rendered: "255", want: 255},
+		{rendered: "256", errContains: "range 0-255"},
+		{rendered: "-1", errContains: "range 0-255"},
+		{rendered: "oops", errContains: "not an integer"},
+		{rendered: "", errContains: "not an integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rendered, func(t *testing.T) {
+			got, err := ParseExitCode(tt.rendered)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScenario_SkippedCaptureRefs(t *testing.T) {
+	scn := Scenario{
+		Meta: Meta{Name: "skip-captures"},
+		Steps: []StepElement{
+			{Step: &Step{
+				Match:   Match{Argv: []string{"create"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"id": "abc", "unused": "x"}},
+			}},
+			{Step: &Step{
+				Match:   Match{Argv: []string{"list"}},
+				Respond: Response{Exit: Exit(0), Stdout: "plain"},
+			}},
+			{Step: &Step{
+				Match:   Match{Argv: []string{"show"}},
+				Respond: Response{Exit: Exit(0), Stdout: "id={{ .capture.id }}"},
+			}},
+		},
+	}
+
+	skipped := scn.SkippedCaptureRefs(1)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, SkippedCapture{Capture: "id", DefinedAt: 0, ReferencedAt: 2}, skipped[0])
+
+	assert.Empty(t, scn.SkippedCaptureRefs(0), "starting at step 0 skips nothing")
+}
+
+func TestScenario_SkippedCaptureRefs_RedefinedBeforeUse(t *testing.T) {
+	scn := Scenario{
+		Meta: Meta{Name: "redefined"},
+		Steps: []StepElement{
+			{Step: &Step{
+				Match:   Match{Argv: []string{"create"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"id": "old"}},
+			}},
+			{Step: &Step{
+				Match:   Match{Argv: []string{"recreate"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"id": "new"}},
+			}},
+			{Step: &Step{
+				Match:   Match{Argv: []string{"show"}},
+				Respond: Response{Exit: Exit(0), Stdout: "{{ .capture.id }}"},
+			}},
+		},
+	}
+
+	assert.Empty(t, scn.SkippedCaptureRefs(1), "capture set again by a replayed step is not lost")
+}
+
+func TestScenario_UndefinedCaptureRefs(t *testing.T) {
+	scn := Scenario{
+		Meta: Meta{Name: "ghost-captures"},
+		Steps: []StepElement{
+			{Step: &Step{
+				Match:   Match{Argv: []string{"create"}},
+				Respond: Response{Exit: Exit(0), Capture: map[string]string{"id": "abc"}},
+			}},
+			{Step: &Step{
+				Match:   Match{Argv: []string{"show"}},
+				Respond: Response{Exit: Exit(0), Stdout: "{{ .capture.id }} {{ .capture.ghost }}"},
+			}},
+			{Step: &Step{
+				Match:   Match{Argv: []string{"again"}},
+				Respond: Response{Exit: Exit(0), Stderr: "{{ .capture.ghost }}"},
+			}},
+		},
+	}
+	require.NoError(t, scn.Validate(), "undefined references stay valid")
+
+	undefined := scn.UndefinedCaptureRefs()
+	require.Len(t, undefined, 1, "each capture is reported once")
+	assert.Equal(t, UndefinedCapture{Capture: "ghost", ReferencedAt: 1}, undefined[0])
+}
+
+func TestScenario_Validate_ExpectWithin(t *testing.T) {
+	build := func(within ...string) Scenario {
+		scn := Scenario{Meta: Meta{Name: "timed"}}
+		for _, w := range within {
+			scn.Steps = append(scn.Steps, StepElement{Step: &Step{Match: Match{Argv: []string{"cmd"}}, ExpectWithin: w}})
+		}
+		return scn
+	}
+
+	ok := build("", "2s")
+	assert.NoError(t, ok.Validate())
+	d, err := ok.Steps[1].Step.ExpectWithinDuration()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, d)
+
+	first := build("2s", "")
+	err = first.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expect_within needs a preceding step")
+
+	invalid := build("", "soon")
+	err = invalid.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid expect_within "soon"`)
+
+	negative := build("", "-1s")
+	err = negative.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `expect_within "-1s" must be positive`)
+}
+
+func TestResponse_ExitOmittedOrExplicit(t *testing.T) {
+	scn := mustLoad(t, `
+meta:
+  name: exits
+steps:
+  - match:
+      argv: [git, status]
+    respond:
+      exit: 0
+  - match:
+      argv: [git, fetch]
+    respond:
+      stdout: "fetched\n"
+  - match:
+      argv: [git, push]
+    respond:
+      exit: 3
+`)
+	steps := scn.FlatSteps()
+	require.Len(t, steps, 3)
+
+	assert.Equal(t, Exit(0), steps[0].Respond.Exit, "explicit exit: 0 is kept")
+	assert.Nil(t, steps[1].Respond.Exit, "omitted exit stays unset")
+	assert.Equal(t, Exit(3), steps[2].Respond.Exit)
+
+	assert.Equal(t, 0, steps[0].Respond.EffectiveExit())
+	assert.Equal(t, 0, steps[1].Respond.EffectiveExit(), "omitted exit defaults to 0")
+	assert.Equal(t, 3, steps[2].Respond.EffectiveExit())
+
+	// Round trip: explicit exits are written back, an omitted one is not
+	data, err := yaml.Marshal(scn)
+	require.NoError(t, err)
+	var out struct {
+		Steps []struct {
+			Respond map[string]interface{} `yaml:"respond"`
+		} `yaml:"steps"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &out))
+	require.Len(t, out.Steps, 3)
+	assert.Equal(t, 0, out.Steps[0].Respond["exit"])
+	assert.NotContains(t, out.Steps[1].Respond, "exit")
+	assert.Equal(t, 3, out.Steps[2].Respond["exit"])
+
+	again := mustLoad(t, string(data))
+	assert.Equal(t, scn.FlatSteps(), again.FlatSteps())
+}
+
+func TestResponse_Validate_Exit(t *testing.T) {
+	assert.NoError(t, (&Response{}).Validate(), "omitted exit is valid")
+	assert.NoError(t, (&Response{Exit: Exit(255)}).Validate())
+	assert.ErrorContains(t, (&Response{Exit: Exit(256)}).Validate(), "exit must be in range 0-255")
+	assert.ErrorContains(t, (&Response{Exit: Exit(-1)}).Validate(), "exit must be in range 0-255")
+	assert.NoError(t, (&Response{Exit: Exit(0), ExitTemplate: "{{ .code }}"}).Validate(),
+		"exit: 0 alongside exit_template stays accepted")
+	assert.ErrorContains(t, (&Response{Exit: Exit(2), ExitTemplate: "{{ .code }}"}).Validate(),
+		"mutually exclusive")
+}
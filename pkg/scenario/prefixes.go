@@ -0,0 +1,69 @@
+package scenario
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Wrapper commands meta.strip_prefixes may remove from an incoming argv.
+const (
+	PrefixSudo  = "sudo"
+	PrefixDoas  = "doas"
+	PrefixEnv   = "env"
+	PrefixNohup = "nohup"
+)
+
+// supportedPrefixes lists the wrappers strip_prefixes understands.
+var supportedPrefixes = []string{PrefixSudo, PrefixDoas, PrefixEnv, PrefixNohup}
+
+// envAssignRe matches a NAME=value token as accepted by env(1).
+var envAssignRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// validateStripPrefixes checks that every prefix is a supported wrapper.
+func validateStripPrefixes(prefixes []string) error {
+	for _, p := range prefixes {
+		if !containsString(supportedPrefixes, p) {
+			return fmt.Errorf("strip_prefixes: unsupported prefix %q (supported: %s)", p, strings.Join(supportedPrefixes, ", "))
+		}
+	}
+	return nil
+}
+
+// StripArgvPrefixes removes leading wrapper commands listed in
+// meta.strip_prefixes from argv, so `sudo kubectl get pods` and
+// `env KUBECONFIG=x kubectl get pods` match a `kubectl get pods` step.
+// Stripping is conservative: it only applies to argv[0], a wrapper given
+// options (such as `sudo -u admin`) is left alone since its arguments cannot
+// be told apart from the command, and a wrapper with nothing after it is
+// kept. For env, the NAME=value tokens that follow it are removed too.
+// Returns argv unchanged when no prefix applies.
+func (m *Meta) StripArgvPrefixes(argv []string) []string {
+	if len(m.StripPrefixes) == 0 {
+		return argv
+	}
+	out := argv
+	for len(out) > 1 && containsString(m.StripPrefixes, out[0]) {
+		rest := out[1:]
+		if out[0] == PrefixEnv {
+			for len(rest) > 0 && envAssignRe.MatchString(rest[0]) {
+				rest = rest[1:]
+			}
+		}
+		if len(rest) == 0 || strings.HasPrefix(rest[0], "-") {
+			break
+		}
+		out = rest
+	}
+	return out
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripArgvPrefixes(t *testing.T) {
+	meta := Meta{StripPrefixes: []string{"sudo", "env"}}
+	tests := []struct {
+		name string
+		argv []string
+		want []string
+	}{
+		{"no prefix", []string{"kubectl", "get", "pods"}, []string{"kubectl", "get", "pods"}},
+		{"sudo", []string{"sudo", "kubectl", "get", "pods"}, []string{"kubectl", "get", "pods"}},
+		{"env assignments", []string{"env", "FOO=bar", "KUBECONFIG=/tmp/k", "kubectl", "get"}, []string{"kubectl", "get"}},
+		{"nested wrappers", []string{"sudo", "env", "FOO=bar", "kubectl"}, []string{"kubectl"}},
+		{"sudo with options kept", []string{"sudo", "-u", "admin", "kubectl"}, []string{"sudo", "-u", "admin", "kubectl"}},
+		{"env with options kept", []string{"env", "-i", "kubectl"}, []string{"env", "-i", "kubectl"}},
+		{"bare wrapper kept", []string{"sudo"}, []string{"sudo"}},
+		{"env with only assignments kept", []string{"env", "FOO=bar"}, []string{"env", "FOO=bar"}},
+		{"later arguments untouched", []string{"kubectl", "exec", "pod", "--", "sudo", "ls"}, []string{"kubectl", "exec", "pod", "--", "sudo", "ls"}},
+		{"unlisted wrapper untouched", []string{"nohup", "kubectl"}, []string{"nohup", "kubectl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, meta.StripArgvPrefixes(tt.argv))
+		})
+	}
+}
+
+func TestStripArgvPrefixes_DisabledByDefault(t *testing.T) {
+	argv := []string{"sudo", "kubectl", "get", "pods"}
+	assert.Equal(t, argv, (&Meta{}).StripArgvPrefixes(argv))
+}
+
+func TestLoad_StripPrefixesValidation(t *testing.T) {
+	_, err := Load(strings.NewReader(`
+meta:
+  name: bad-prefix
+  strip_prefixes: [sudo, xargs]
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported prefix "xargs"`)
+
+	_, err = Load(strings.NewReader(`
+meta:
+  name: shadowed-step
+  strip_prefixes: [sudo]
+steps:
+  - match:
+      argv: [sudo, kubectl, get, pods]
+    respond:
+      exit: 0
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "meta.strip_prefixes removes before matching")
+}
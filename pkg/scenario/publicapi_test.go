@@ -129,7 +129,7 @@ func TestEffectiveCalls_Defaults(t *testing.T) {
 	t.Run("nil calls defaults to min=1 max=1", func(t *testing.T) {
 		step := Step{
 			Match:   Match{Argv: []string{"cmd"}},
-			Respond: Response{Exit: 0},
+			Respond: Response{Exit: Exit(0)},
 		}
 		bounds := step.EffectiveCalls()
 		assert.Equal(t, 1, bounds.Min)
@@ -139,7 +139,7 @@ func TestEffectiveCalls_Defaults(t *testing.T) {
 	t.Run("explicit calls preserved", func(t *testing.T) {
 		step := Step{
 			Match:   Match{Argv: []string{"cmd"}},
-			Respond: Response{Exit: 0},
+			Respond: Response{Exit: Exit(0)},
 			Calls:   &CallBounds{Min: 0, Max: 5},
 		}
 		bounds := step.EffectiveCalls()
@@ -154,16 +154,16 @@ func TestFlatSteps_MixedStepsAndGroups(t *testing.T) {
 	scn := Scenario{
 		Meta: Meta{Name: "flat-test"},
 		Steps: []StepElement{
-			{Step: &Step{Match: Match{Argv: []string{"setup"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"setup"}}, Respond: Response{Exit: Exit(0)}}},
 			{Group: &StepGroup{
 				Mode: "unordered",
 				Name: "checks",
 				Steps: []StepElement{
-					{Step: &Step{Match: Match{Argv: []string{"check-a"}}, Respond: Response{Exit: 0}}},
-					{Step: &Step{Match: Match{Argv: []string{"check-b"}}, Respond: Response{Exit: 0}}},
+					{Step: &Step{Match: Match{Argv: []string{"check-a"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"check-b"}}, Respond: Response{Exit: Exit(0)}}},
 				},
 			}},
-			{Step: &Step{Match: Match{Argv: []string{"teardown"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"teardown"}}, Respond: Response{Exit: Exit(0)}}},
 		},
 	}
 
@@ -179,8 +179,8 @@ func TestFlatSteps_NoGroups(t *testing.T) {
 	scn := Scenario{
 		Meta: Meta{Name: "linear"},
 		Steps: []StepElement{
-			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}},
-			{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
+			{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}},
 		},
 	}
 	flat := scn.FlatSteps()
@@ -191,7 +191,7 @@ func TestFlatSteps_EmptyGroup(t *testing.T) {
 	scn := Scenario{
 		Meta: Meta{Name: "empty-group"},
 		Steps: []StepElement{
-			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
 			{Group: &StepGroup{Mode: "unordered", Name: "empty", Steps: nil}},
 		},
 	}
@@ -205,17 +205,17 @@ func TestGroupRanges_SingleGroup(t *testing.T) {
 	scn := Scenario{
 		Meta: Meta{Name: "group-range"},
 		Steps: []StepElement{
-			{Step: &Step{Match: Match{Argv: []string{"s1"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"s1"}}, Respond: Response{Exit: Exit(0)}}},
 			{Group: &StepGroup{
 				Mode: "unordered",
 				Name: "grp",
 				Steps: []StepElement{
-					{Step: &Step{Match: Match{Argv: []string{"g1"}}, Respond: Response{Exit: 0}}},
-					{Step: &Step{Match: Match{Argv: []string{"g2"}}, Respond: Response{Exit: 0}}},
-					{Step: &Step{Match: Match{Argv: []string{"g3"}}, Respond: Response{Exit: 0}}},
+					{Step: &Step{Match: Match{Argv: []string{"g1"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"g2"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"g3"}}, Respond: Response{Exit: Exit(0)}}},
 				},
 			}},
-			{Step: &Step{Match: Match{Argv: []string{"s2"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"s2"}}, Respond: Response{Exit: Exit(0)}}},
 		},
 	}
 
@@ -234,15 +234,15 @@ func TestGroupRanges_MultipleGroups(t *testing.T) {
 			{Group: &StepGroup{
 				Mode:  "unordered",
 				Name:  "first",
-				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}}},
+				Steps: []StepElement{{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}}},
 			}},
-			{Step: &Step{Match: Match{Argv: []string{"mid"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"mid"}}, Respond: Response{Exit: Exit(0)}}},
 			{Group: &StepGroup{
 				Mode: "unordered",
 				Name: "second",
 				Steps: []StepElement{
-					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: 0}}},
-					{Step: &Step{Match: Match{Argv: []string{"c"}}, Respond: Response{Exit: 0}}},
+					{Step: &Step{Match: Match{Argv: []string{"b"}}, Respond: Response{Exit: Exit(0)}}},
+					{Step: &Step{Match: Match{Argv: []string{"c"}}, Respond: Response{Exit: Exit(0)}}},
 				},
 			}},
 		},
@@ -264,7 +264,7 @@ func TestGroupRanges_NoGroups(t *testing.T) {
 	scn := Scenario{
 		Meta: Meta{Name: "no-group"},
 		Steps: []StepElement{
-			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: 0}}},
+			{Step: &Step{Match: Match{Argv: []string{"a"}}, Respond: Response{Exit: Exit(0)}}},
 		},
 	}
 	assert.Empty(t, scn.GroupRanges())
@@ -276,7 +276,7 @@ func TestStepElement_YAMLRoundTrip_LeafStep(t *testing.T) {
 	original := StepElement{
 		Step: &Step{
 			Match:   Match{Argv: []string{"kubectl", "get", "pods"}},
-			Respond: Response{Exit: 0, Stdout: "pod-1 Running"},
+			Respond: Response{Exit: Exit(0), Stdout: "pod-1 Running"},
 		},
 	}
 
@@ -299,8 +299,8 @@ func TestStepElement_YAMLRoundTrip_Group(t *testing.T) {
 			Mode: "unordered",
 			Name: "checks",
 			Steps: []StepElement{
-				{Step: &Step{Match: Match{Argv: []string{"check-a"}}, Respond: Response{Exit: 0}}},
-				{Step: &Step{Match: Match{Argv: []string{"check-b"}}, Respond: Response{Exit: 0}}},
+				{Step: &Step{Match: Match{Argv: []string{"check-a"}}, Respond: Response{Exit: Exit(0)}}},
+				{Step: &Step{Match: Match{Argv: []string{"check-b"}}, Respond: Response{Exit: Exit(0)}}},
 			},
 		},
 	}
@@ -330,7 +330,7 @@ func TestScenario_YAMLRoundTrip(t *testing.T) {
 		Steps: []StepElement{
 			{Step: &Step{
 				Match:   Match{Argv: []string{"cmd", "arg"}},
-				Respond: Response{Exit: 0, Stdout: "output"},
+				Respond: Response{Exit: Exit(0), Stdout: "output"},
 			}},
 		},
 	}
@@ -412,7 +412,7 @@ func TestResponse_Validate_CaptureIdentifiers(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := &Response{Exit: 0, Capture: tt.capture}
+			r := &Response{Exit: Exit(0), Capture: tt.capture}
 			err := r.Validate()
 			if tt.wantErr {
 				require.Error(t, err)
@@ -431,7 +431,7 @@ func TestStepGroup_NestedGroupRejected(t *testing.T) {
 		Mode: "unordered",
 		Steps: []StepElement{
 			{Group: &StepGroup{Mode: "unordered", Steps: []StepElement{
-				{Step: &Step{Match: Match{Argv: []string{"inner"}}, Respond: Response{Exit: 0}}},
+				{Step: &Step{Match: Match{Argv: []string{"inner"}}, Respond: Response{Exit: Exit(0)}}},
 			}}},
 		},
 	}
@@ -536,6 +536,19 @@ func TestMatch_Validate_WithStdin(t *testing.T) {
 	assert.NoError(t, m.Validate())
 }
 
+func TestMatch_Validate_StdinSHA256(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	assert.NoError(t, (&Match{Argv: []string{"cmd"}, StdinSHA256: digest}).Validate())
+
+	err := (&Match{Argv: []string{"cmd"}, Stdin: "x", StdinSHA256: digest}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stdin and stdin_sha256 are mutually exclusive")
+
+	err = (&Match{Argv: []string{"cmd"}, StdinSHA256: "abc"}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stdin_sha256: must be a 64-character hex digest")
+}
+
 // --- Response stdout/stderr_file mutual exclusivity --------------------------
 
 func TestResponse_Validate_MutualExclusivity(t *testing.T) {
@@ -544,12 +557,12 @@ func TestResponse_Validate_MutualExclusivity(t *testing.T) {
 		resp    Response
 		wantErr bool
 	}{
-		{"stdout only", Response{Exit: 0, Stdout: "out"}, false},
-		{"stdout_file only", Response{Exit: 0, StdoutFile: "f.txt"}, false},
-		{"both stdout", Response{Exit: 0, Stdout: "out", StdoutFile: "f.txt"}, true},
-		{"stderr only", Response{Exit: 0, Stderr: "err"}, false},
-		{"stderr_file only", Response{Exit: 0, StderrFile: "f.txt"}, false},
-		{"both stderr", Response{Exit: 0, Stderr: "err", StderrFile: "f.txt"}, true},
+		{"stdout only", Response{Exit: Exit(0), Stdout: "out"}, false},
+		{"stdout_file only", Response{Exit: Exit(0), StdoutFile: "f.txt"}, false},
+		{"both stdout", Response{Exit: Exit(0), Stdout: "out", StdoutFile: "f.txt"}, true},
+		{"stderr only", Response{Exit: Exit(0), Stderr: "err"}, false},
+		{"stderr_file only", Response{Exit: Exit(0), StderrFile: "f.txt"}, false},
+		{"both stderr", Response{Exit: Exit(0), Stderr: "err", StderrFile: "f.txt"}, true},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,97 @@
+package scenario
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ormasoftchile/cli-replay/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// yamlFieldNames returns the yaml keys of a struct type's fields.
+func yamlFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("yaml")
+		name := strings.Split(tag, ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaPropertyNames returns the property keys of a schema object node.
+func schemaPropertyNames(t *testing.T, node map[string]interface{}) []string {
+	t.Helper()
+	props, ok := node["properties"].(map[string]interface{})
+	require.True(t, ok, "schema node has no properties")
+	names := make([]string, 0, len(props))
+	for k := range props {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaNode walks a path of keys from the schema root.
+func schemaNode(t *testing.T, root map[string]interface{}, path ...string) map[string]interface{} {
+	t.Helper()
+	node := root
+	for _, key := range path {
+		next, ok := node[key].(map[string]interface{})
+		require.True(t, ok, "schema path %v: missing %q", path, key)
+		node = next
+	}
+	return node
+}
+
+func TestSchema_CoversModelFields(t *testing.T) {
+	var root map[string]interface{}
+	require.NoError(t, json.Unmarshal(schema.Scenario, &root))
+
+	includeObject := schemaNode(t, root, "definitions", "include")["oneOf"].([]interface{})[1].(map[string]interface{})
+	tests := []struct {
+		name string
+		typ  reflect.Type
+		node map[string]interface{}
+	}{
+		{"scenario", reflect.TypeOf(Scenario{}), root},
+		{"meta", reflect.TypeOf(Meta{}), schemaNode(t, root, "definitions", "meta")},
+		{"security", reflect.TypeOf(Security{}), schemaNode(t, root, "definitions", "security")},
+		{"session", reflect.TypeOf(Session{}), schemaNode(t, root, "definitions", "meta", "properties", "session")},
+		{"defaults", reflect.TypeOf(Defaults{}), schemaNode(t, root, "definitions", "meta", "properties", "defaults")},
+		{"include", reflect.TypeOf(Include{}), includeObject},
+		{"step", reflect.TypeOf(Step{}), schemaNode(t, root, "definitions", "step")},
+		{"match", reflect.TypeOf(Match{}), schemaNode(t, root, "definitions", "match")},
+		{"respond", reflect.TypeOf(Response{}), schemaNode(t, root, "definitions", "respond")},
+		{"calls", reflect.TypeOf(CallBounds{}), schemaNode(t, root, "definitions", "calls")},
+		{"group", reflect.TypeOf(StepGroup{}), schemaNode(t, root, "definitions", "step_group")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, yamlFieldNames(tt.typ), schemaPropertyNames(t, tt.node))
+		})
+	}
+}
+
+func TestSchema_EncodesValidationRules(t *testing.T) {
+	var root map[string]interface{}
+	require.NoError(t, json.Unmarshal(schema.Scenario, &root))
+
+	exit := schemaNode(t, root, "definitions", "respond", "properties", "exit")
+	assert.Equal(t, float64(0), exit["minimum"])
+	assert.Equal(t, float64(255), exit["maximum"])
+
+	mode := schemaNode(t, root, "definitions", "step_group", "properties", "mode")
+	assert.ElementsMatch(t, []interface{}{GroupModeUnordered, GroupModeOrdered}, mode["enum"])
+
+	auto := schemaNode(t, root, "definitions", "meta", "properties", "session", "properties", "auto")
+	assert.Equal(t, []interface{}{SessionAutoPID}, auto["enum"])
+}
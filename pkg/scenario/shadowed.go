@@ -0,0 +1,168 @@
+package scenario
+
+import (
+	"strings"
+
+	"github.com/ormasoftchile/cli-replay/pkg/matcher"
+)
+
+// ShadowedStep describes a step whose calls are swallowed by an earlier
+// step. The engine keeps serving a step until its calls max is used up, so
+// when the earlier step accepts every command the later one does and has a
+// call range (min < max), the later step is only reached once the earlier
+// one has taken its full max: calls meant for it within that range never
+// arrive. With an exact count (min = max) the hand-over is predictable and
+// nothing is reported.
+type ShadowedStep struct {
+	Index      int // Flat index of the shadowed step
+	ShadowedBy int // Flat index of the step that takes its calls
+}
+
+// ShadowedSteps reports the steps that can never be served while an
+// earlier step is within its call range. In ordered sequences (top-level
+// steps and children of ordered groups) only the immediately preceding
+// step is considered, as the engine never moves past a step that still has
+// budget. Children of an unordered group are tried in listed order, so
+// every earlier child of the same group is considered. Groups are
+// boundaries between top-level neighbours.
+func (s *Scenario) ShadowedSteps() []ShadowedStep {
+	var out []ShadowedStep
+	flatIdx := 0
+	var prev *indexedStep
+
+	for _, elem := range s.Steps {
+		switch {
+		case elem.Step != nil:
+			cur := indexedStep{flatIdx, elem.Step}
+			if prev != nil && shadows(prev.step, cur.step) {
+				out = append(out, ShadowedStep{Index: cur.flat, ShadowedBy: prev.flat})
+			}
+			prev = &cur
+			flatIdx++
+		case elem.Group != nil:
+			prev = nil
+			var children []indexedStep
+			for _, child := range elem.Group.Steps {
+				if child.Step == nil {
+					continue
+				}
+				children = append(children, indexedStep{flatIdx, child.Step})
+				flatIdx++
+			}
+			out = append(out, shadowedInGroup(children, elem.Group.Mode == GroupModeOrdered)...)
+		}
+	}
+	return out
+}
+
+// shadowedInGroup returns the shadowed children of a group.
+func shadowedInGroup(children []indexedStep, ordered bool) []ShadowedStep {
+	var out []ShadowedStep
+	for j := 1; j < len(children); j++ {
+		first := 0
+		if ordered {
+			first = j - 1
+		}
+		for i := first; i < j; i++ {
+			if shadows(children[i].step, children[j].step) {
+				out = append(out, ShadowedStep{Index: children[j].flat, ShadowedBy: children[i].flat})
+				break
+			}
+		}
+	}
+	return out
+}
+
+// shadows reports whether earlier, having a call range, accepts every
+// command later does.
+func shadows(earlier, later *Step) bool {
+	bounds := earlier.EffectiveCalls()
+	if bounds.Min == bounds.Max || len(earlier.DependsOn) > 0 {
+		return false
+	}
+	return matchCovers(&earlier.Match, &later.Match)
+}
+
+// matchCovers reports whether every invocation b accepts is also accepted
+// by a. It is conservative: when coverage cannot be decided statically
+// (argv_hash, patterns on both sides), it returns false. match.stdin is
+// ignored, since a stdin mismatch fails the call instead of trying the next
+// step.
+func matchCovers(a, b *Match) bool {
+	if len(a.ArgvHash) > 0 || len(b.ArgvHash) > 0 || a.NormalizeFlags != b.NormalizeFlags {
+		return false
+	}
+	if a.Cwd != "" && a.Cwd != b.Cwd {
+		return false
+	}
+	for k, v := range a.Env {
+		if bv, ok := b.Env[k]; !ok || bv != v {
+			return false
+		}
+	}
+
+	for _, bArgv := range b.Alternatives() {
+		covered := false
+		for _, aArgv := range a.Alternatives() {
+			if argvCovers(a, aArgv, b, bArgv) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// argvCovers reports whether the argv pattern aArgv of a accepts every
+// argv the pattern bArgv of b accepts.
+func argvCovers(a *Match, aArgv []string, b *Match, bArgv []string) bool {
+	if a.NormalizeFlags {
+		aArgv, bArgv = matcher.NormalizeFlags(aArgv), matcher.NormalizeFlags(bArgv)
+	}
+	if a.Wildcards {
+		aArgv = matcher.ExpandWildcards(aArgv)
+	}
+	if b.Wildcards {
+		bArgv = matcher.ExpandWildcards(bArgv)
+	}
+	if len(aArgv) == 0 || len(bArgv) == 0 {
+		return false
+	}
+	switch {
+	case a.AnyArity():
+		aArgv, bArgv = aArgv[:1], bArgv[:1]
+	case b.AnyArity():
+		return false
+	}
+	if len(aArgv) != len(bArgv) {
+		return false
+	}
+	for i := range aArgv {
+		if !elementCovers(aArgv[i], bArgv[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// elementCovers reports whether the argv element pattern pa accepts every
+// value pb accepts. A pattern pb is only covered by an identical pattern or
+// by {{ .any }}.
+func elementCovers(pa, pb string) bool {
+	if pa == pb || isAnyElement(pa) {
+		return true
+	}
+	if strings.Contains(pb, "{{") {
+		return false
+	}
+	return matcher.ArgvMatch([]string{pa}, []string{pb})
+}
+
+// isAnyElement reports whether the argv element is the {{ .any }} wildcard.
+func isAnyElement(elem string) bool {
+	trimmed := strings.TrimSpace(elem)
+	return trimmed == "{{ .any }}" || trimmed == "{{.any}}"
+}
@@ -0,0 +1,180 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowedSteps(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []ShadowedStep
+	}{
+		{
+			name: "identical argv after a call range is shadowed",
+			yaml: `
+meta:
+  name: shadowed
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    calls: {min: 1, max: 5}
+    respond:
+      stdout: pending
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      stdout: ready
+`,
+			want: []ShadowedStep{{Index: 1, ShadowedBy: 0}},
+		},
+		{
+			name: "exact counts hand over predictably",
+			yaml: `
+meta:
+  name: benign
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    calls: {min: 2, max: 2}
+    respond:
+      stdout: pending
+  - match:
+      argv: [kubectl, get, pods]
+    calls: {min: 1, max: 3}
+    respond:
+      stdout: ready
+  - match:
+      argv: [kubectl, apply]
+    respond:
+      exit: 0
+`,
+		},
+		{
+			name: "wildcard and arity any swallow more specific steps",
+			yaml: `
+meta:
+  name: prefix
+steps:
+  - match:
+      argv: [kubectl, get, "{{ .any }}"]
+    calls: {min: 0, max: 3}
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, get, pods]
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl]
+      arity: any
+    calls: {min: 1, max: 10}
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, logs, '{{ .regex "^web-" }}']
+    respond:
+      exit: 0
+`,
+			want: []ShadowedStep{{Index: 1, ShadowedBy: 0}, {Index: 3, ShadowedBy: 2}},
+		},
+		{
+			name: "narrower earlier step does not shadow",
+			yaml: `
+meta:
+  name: narrower
+steps:
+  - match:
+      argv: [kubectl, get, pods]
+    calls: {min: 1, max: 3}
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, get, "{{ .any }}"]
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, logs, '{{ .regex "^web-" }}']
+    calls: {min: 1, max: 3}
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, logs, '{{ .regex "^web-" }}', -f]
+    respond:
+      exit: 0
+`,
+		},
+		{
+			name: "regex accepting the literal shadows it, env restriction does not",
+			yaml: `
+meta:
+  name: regex
+steps:
+  - match:
+      argv: [kubectl, logs, '{{ .regex "^web-" }}']
+    calls: {min: 1, max: 2}
+    respond:
+      exit: 0
+  - match:
+      argv: [kubectl, logs, web-0]
+    respond:
+      exit: 0
+  - match:
+      argv: [az, login]
+      env:
+        AZURE_TENANT: prod
+    calls: {min: 1, max: 2}
+    respond:
+      exit: 0
+  - match:
+      argv: [az, login]
+    respond:
+      exit: 0
+`,
+			want: []ShadowedStep{{Index: 1, ShadowedBy: 0}},
+		},
+		{
+			name: "unordered group children are checked against every earlier child",
+			yaml: `
+meta:
+  name: groups
+steps:
+  - match:
+      argv: [git, status]
+    calls: {min: 1, max: 3}
+    respond:
+      exit: 0
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: [git, status]
+          respond:
+            exit: 0
+        - match:
+            argv: [az, "{{ .any }}"]
+          calls: {min: 1, max: 2}
+          respond:
+            exit: 0
+        - match:
+            argv: [git, fetch]
+          respond:
+            exit: 0
+        - match:
+            argv: [az, login]
+          respond:
+            exit: 0
+`,
+			want: []ShadowedStep{{Index: 4, ShadowedBy: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scn := mustLoad(t, tt.yaml)
+			assert.Equal(t, tt.want, scn.ShadowedSteps())
+		})
+	}
+}
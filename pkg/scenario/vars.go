@@ -0,0 +1,119 @@
+package scenario
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// argvVarRe matches a {{ .name }} variable reference in a match.argv element.
+var argvVarRe = regexp.MustCompile(`\{\{\s*\.([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+
+// argvCaptureRe matches any {{ ... .capture ... }} reference.
+var argvCaptureRe = regexp.MustCompile(`\{\{[^}]*\.capture\b[^}]*\}\}`)
+
+// reservedArgvNames are matcher templates, not variables.
+var reservedArgvNames = map[string]bool{"any": true}
+
+// renderArgvVars substitutes {{ .var }} references in the match.argv of every
+// local step (group children included) with values from meta.vars. As for
+// response templates, a non-empty environment variable of the same name
+// overrides the meta.vars value unless meta.security.deny_env_vars denies it.
+// Matcher templates ({{ .any }}, {{ .regex "..." }}) are left in place.
+// Steps spliced in by includes were rendered against their own file's vars.
+func (s *Scenario) renderArgvVars(getenv func(string) string) error {
+	vars := s.argvVars(getenv)
+	for i := range s.Steps {
+		elem := &s.Steps[i]
+		if elem.Group != nil {
+			for j := range elem.Group.Steps {
+				if step := elem.Group.Steps[j].Step; step != nil {
+					if err := renderStepArgv(step, vars); err != nil {
+						return fmt.Errorf("step %d: group step %d: %w", i, j, err)
+					}
+				}
+			}
+			continue
+		}
+		if elem.Step != nil {
+			if err := renderStepArgv(elem.Step, vars); err != nil {
+				return fmt.Errorf("step %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// argvVars returns meta.vars with environment overrides applied.
+func (s *Scenario) argvVars(getenv func(string) string) map[string]string {
+	var deny []string
+	if s.Meta.Security != nil {
+		deny = s.Meta.Security.DenyEnvVars
+	}
+	vars := make(map[string]string, len(s.Meta.Vars))
+	for k, v := range s.Meta.Vars {
+		vars[k] = v
+		if getenv == nil {
+			continue
+		}
+		if envVal := getenv(k); envVal != "" && !matchesAny(k, deny) {
+			vars[k] = envVal
+		}
+	}
+	return vars
+}
+
+// renderStepArgv substitutes variables into one step's argv, or each of its
+// any_of entries, in place.
+func renderStepArgv(step *Step, vars map[string]string) error {
+	if err := renderArgv(step.Match.Argv, vars); err != nil {
+		return err
+	}
+	for i, argv := range step.Match.AnyOf {
+		if err := renderArgv(argv, vars); err != nil {
+			return fmt.Errorf("any_of[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// renderArgv substitutes variables into argv in place. Captures are
+// rejected by Match.Validate, so they are skipped here.
+func renderArgv(argv []string, vars map[string]string) error {
+	for i, elem := range argv {
+		if !strings.Contains(elem, "{{") || argvCaptureRe.MatchString(elem) {
+			continue
+		}
+		var undefined string
+		rendered := argvVarRe.ReplaceAllStringFunc(elem, func(ref string) string {
+			name := argvVarRe.FindStringSubmatch(ref)[1]
+			if reservedArgvNames[name] {
+				return ref
+			}
+			v, ok := vars[name]
+			if !ok {
+				if undefined == "" {
+					undefined = name
+				}
+				return ref
+			}
+			return v
+		})
+		if undefined != "" {
+			return fmt.Errorf("match.argv[%d]: undefined var %q (not in meta.vars)", i, undefined)
+		}
+		argv[i] = rendered
+	}
+	return nil
+}
+
+// matchesAny reports whether name matches any of the glob patterns.
+func matchesAny(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_ArgvVarsSubstituted(t *testing.T) {
+	t.Setenv("cluster", "")
+	yaml := `
+meta:
+  name: parameterized
+  vars:
+    cluster: prod-east
+    ns: web
+steps:
+  - match:
+      argv: ["kubectl", "--context", "{{ .cluster }}", "get", "pods", "-n={{.ns}}", "{{ .any }}"]
+  - group:
+      mode: unordered
+      steps:
+        - match:
+            argv: ["kubectl", "--context", "{{ .cluster }}", "get", "svc", '{{ .regex "^web-" }}']
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+
+	steps := sc.FlatSteps()
+	assert.Equal(t, []string{"kubectl", "--context", "prod-east", "get", "pods", "-n=web", "{{ .any }}"}, steps[0].Match.Argv)
+	assert.Equal(t, []string{"kubectl", "--context", "prod-east", "get", "svc", `{{ .regex "^web-" }}`}, steps[1].Match.Argv)
+}
+
+func TestLoad_ArgvVarsEnvOverride(t *testing.T) {
+	yaml := `
+meta:
+  name: parameterized
+  vars:
+    cluster: prod-east
+    token: from-vars
+  security:
+    deny_env_vars: ["tok*"]
+steps:
+  - match:
+      argv: ["kubectl", "--context", "{{ .cluster }}", "--token", "{{ .token }}"]
+`
+	t.Setenv("cluster", "staging")
+	t.Setenv("token", "from-env")
+
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kubectl", "--context", "staging", "--token", "from-vars"}, sc.Steps[0].Step.Match.Argv)
+}
+
+func TestLoad_ArgvUndefinedVar(t *testing.T) {
+	yaml := `
+meta:
+  name: parameterized
+steps:
+  - match:
+      argv: ["kubectl", "--context", "{{ .cluster }}"]
+`
+	_, err := Load(strings.NewReader(yaml))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `step 0: match.argv[2]: undefined var "cluster"`)
+}
+
+func TestLoad_ArgvCaptureRejected(t *testing.T) {
+	yaml := `
+meta:
+  name: parameterized
+steps:
+  - match:
+      argv: ["az", "group", "create"]
+    respond:
+      capture:
+        rg: my-rg
+  - match:
+      argv: ["az", "group", "show", "-n", "{{ .capture.rg }}"]
+`
+	_, err := Load(strings.NewReader(yaml))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "argv[4]: captures cannot be used in match.argv")
+}
+
+func TestLoad_ArgvVarsSubstitutedInAnyOf(t *testing.T) {
+	t.Setenv("ns", "")
+	yaml := `
+meta:
+  name: alternatives
+  vars:
+    ns: web
+steps:
+  - match:
+      any_of:
+        - ["kubectl", "get", "po", "-n", "{{ .ns }}"]
+        - ["kubectl", "get", "pods", "-n", "{{ .ns }}"]
+`
+	sc, err := Load(strings.NewReader(yaml))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"kubectl", "get", "po", "-n", "web"},
+		{"kubectl", "get", "pods", "-n", "web"},
+	}, sc.FlatSteps()[0].Match.AnyOf)
+}
@@ -0,0 +1,89 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BatchResult aggregates the outcome of running several scenarios, one
+// isolated session each, as 'cli-replay batch' does.
+type BatchResult struct {
+	Total     int             `json:"total"`
+	Passed    int             `json:"passed"`
+	Failed    int             `json:"failed"`
+	Scenarios []BatchScenario `json:"scenarios"`
+}
+
+// BatchScenario is one scenario's entry in a BatchResult.
+type BatchScenario struct {
+	File     string `json:"file"`
+	Scenario string `json:"scenario"`
+	Passed   bool   `json:"passed"`
+	// ExitCode is the scenario's exec exit code: the child's non-zero
+	// exit, or 1 when verification or setup failed.
+	ExitCode int `json:"exit_code"`
+	// Error says why the scenario failed; empty when it passed.
+	Error string `json:"error,omitempty"`
+	// Result is the scenario's verification result; nil if the session
+	// did not get as far as verifying.
+	Result *VerifyResult `json:"result,omitempty"`
+}
+
+// Add appends a scenario's entry and updates the counts.
+func (b *BatchResult) Add(s BatchScenario) {
+	b.Scenarios = append(b.Scenarios, s)
+	b.Total++
+	if s.Passed {
+		b.Passed++
+	} else {
+		b.Failed++
+	}
+}
+
+// FormatBatchJSON writes the BatchResult as compact JSON to the given writer.
+func FormatBatchJSON(w io.Writer, result *BatchResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(result)
+}
+
+// FormatBatchJUnit writes the BatchResult as JUnit XML, one test suite per
+// scenario built as FormatJUnit does. A scenario that failed for a reason
+// its steps do not show (the child's exit code, or a setup error) gets an
+// extra "exec" test case carrying that failure. If timestamp is zero, the
+// current time is used.
+func FormatBatchJUnit(w io.Writer, result *BatchResult, timestamp time.Time) error {
+	if timestamp.IsZero() {
+		timestamp = time.Now().UTC()
+	}
+	suites := JUnitTestSuites{Name: "cli-replay", Time: "0.000"}
+	for _, s := range result.Scenarios {
+		var suite JUnitTestSuite
+		if s.Result != nil {
+			suite = junitSuite(s.Result, s.File, timestamp)
+		} else {
+			suite = JUnitTestSuite{Name: s.Scenario, Time: "0.000", Timestamp: timestamp.Format(time.RFC3339)}
+		}
+		if !s.Passed && (s.Result == nil || s.Result.Passed) {
+			suite.Tests++
+			suite.Failures++
+			suite.Cases = append(suite.Cases, JUnitTestCase{
+				Name:      "exec",
+				Classname: s.File,
+				Time:      "0.000",
+				Failure: &JUnitFailure{
+					Message: s.Error,
+					Type:    "ExecFailure",
+					Content: fmt.Sprintf("exit code %d", s.ExitCode),
+				},
+			})
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Suites = append(suites.Suites, suite)
+	}
+	return writeJUnit(w, suites)
+}
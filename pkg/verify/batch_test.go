@@ -0,0 +1,42 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatBatchJUnit(t *testing.T) {
+	passing := &VerifyResult{Scenario: "passing", Passed: true, TotalSteps: 1, ConsumedSteps: 1,
+		Steps: []StepResult{{Index: 0, Label: "git status", CallCount: 1, Min: 1, Max: 1, Passed: true}}}
+	batch := &BatchResult{}
+	batch.Add(BatchScenario{File: "a.yaml", Scenario: "passing", Passed: true, Result: passing})
+	// Steps all passed, but the child exited non-zero
+	batch.Add(BatchScenario{File: "b.yaml", Scenario: "child-failed", ExitCode: 3,
+		Error: "child process exited with code 3", Result: passing})
+	batch.Add(BatchScenario{File: "c.yaml", ExitCode: 1, Error: "failed to load scenario: bad yaml"})
+	assert.Equal(t, 3, batch.Total)
+	assert.Equal(t, 1, batch.Passed)
+	assert.Equal(t, 2, batch.Failed)
+
+	var buf bytes.Buffer
+	require.NoError(t, FormatBatchJUnit(&buf, batch, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	var suites JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 2, suites.Failures)
+	require.Len(t, suites.Suites, 3)
+	assert.Equal(t, 0, suites.Suites[0].Failures)
+	require.Len(t, suites.Suites[1].Cases, 2)
+	exec := suites.Suites[1].Cases[1]
+	assert.Equal(t, "exec", exec.Name)
+	require.NotNil(t, exec.Failure)
+	assert.Equal(t, "ExecFailure", exec.Failure.Type)
+	assert.Equal(t, "child process exited with code 3", exec.Failure.Message)
+	assert.Equal(t, "c.yaml", suites.Suites[2].Cases[0].Classname)
+}
@@ -12,8 +12,8 @@ import (
 
 func TestFormatJSON_AllPassed(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	state := []int{1, 1}
 	result := BuildResult("deploy-app", "default", steps, state, nil)
@@ -38,8 +38,8 @@ func TestFormatJSON_AllPassed(t *testing.T) {
 
 func TestFormatJSON_IncompleteSteps(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"kubectl", "apply", "-f", "app.yaml"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "apply", "-f", "app.yaml"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	state := []int{1, 0}
 	result := BuildResult("deploy-app", "default", steps, state, nil)
@@ -148,7 +148,7 @@ func TestFormatJSON_OmitsEmptyGroup(t *testing.T) {
 
 func TestFormatJSON_OmitsEmptyError(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	state := []int{1}
 	result := BuildResult("test", "default", steps, state, nil)
@@ -160,3 +160,58 @@ func TestFormatJSON_OmitsEmptyError(t *testing.T) {
 	// The error field should be omitted when empty
 	assert.NotContains(t, buf.String(), `"error"`)
 }
+
+func TestFormatJSON_StepStatus(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"git", "status"}}},
+		{Match: scenario.Match{Argv: []string{"git", "fetch"}}},
+	}
+	result := BuildResult("git", "default", steps, []int{2, 0}, nil)
+
+	var buf bytes.Buffer
+	require.NoError(t, FormatJSON(&buf, result))
+
+	var raw struct {
+		Passed bool `json:"passed"`
+		Steps  []struct {
+			Status    string `json:"status"`
+			CallCount int    `json:"call_count"`
+			Min       int    `json:"min"`
+			Max       int    `json:"max"`
+		} `json:"steps"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+	assert.False(t, raw.Passed)
+	require.Len(t, raw.Steps, 2)
+	assert.Equal(t, "over", raw.Steps[0].Status)
+	assert.Equal(t, 2, raw.Steps[0].CallCount)
+	assert.Equal(t, 1, raw.Steps[0].Max)
+	assert.Equal(t, "under", raw.Steps[1].Status)
+}
+
+func TestFormatJSON_GroupsNestSteps(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, FormatJSON(&buf, twoGroupResult()))
+
+	var parsed struct {
+		Steps  []map[string]interface{} `json:"steps"`
+		Groups []struct {
+			Name        string                   `json:"name"`
+			MinsMet     bool                     `json:"mins_met"`
+			Passed      bool                     `json:"passed"`
+			PassedSteps int                      `json:"passed_steps"`
+			TotalSteps  int                      `json:"total_steps"`
+			CallCount   int                      `json:"call_count"`
+			Steps       []map[string]interface{} `json:"steps"`
+		} `json:"groups"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &parsed))
+	assert.Len(t, parsed.Steps, 5)
+	require.Len(t, parsed.Groups, 2)
+	assert.True(t, parsed.Groups[0].MinsMet)
+	assert.False(t, parsed.Groups[1].MinsMet)
+	assert.Equal(t, 1, parsed.Groups[1].PassedSteps)
+	assert.Equal(t, 2, parsed.Groups[1].TotalSteps)
+	assert.Len(t, parsed.Groups[1].Steps, 2)
+	assert.Equal(t, "under", parsed.Groups[1].Steps[1]["status"])
+}
@@ -31,6 +31,8 @@ type JUnitTestSuite struct {
 	Time      string          `xml:"time,attr"`
 	Timestamp string          `xml:"timestamp,attr"`
 	Cases     []JUnitTestCase `xml:"testcase"`
+	// Suites nests one suite per step group when the result has groups.
+	Suites []JUnitTestSuite `xml:"testsuite,omitempty"`
 }
 
 // JUnitTestCase represents a single test case within a test suite.
@@ -62,61 +64,88 @@ func FormatJUnit(w io.Writer, result *VerifyResult, scenarioFile string, timesta
 	if timestamp.IsZero() {
 		timestamp = time.Now().UTC()
 	}
+	suite := junitSuite(result, scenarioFile, timestamp)
+	return writeJUnit(w, JUnitTestSuites{
+		Name:     "cli-replay",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     "0.000",
+		Suites:   []JUnitTestSuite{suite},
+	})
+}
 
+// junitSuite converts a VerifyResult into the test suite for its scenario,
+// with one test case per step and a nested suite per step group.
+func junitSuite(result *VerifyResult, scenarioFile string, timestamp time.Time) JUnitTestSuite {
 	// Handle error case (e.g., no state file)
 	if result.Error != "" {
-		return formatJUnitError(w, result, scenarioFile, timestamp)
+		return junitErrorSuite(result, scenarioFile, timestamp)
 	}
 
 	failures := 0
 	skipped := 0
-	cases := make([]JUnitTestCase, len(result.Steps))
+	var cases []JUnitTestCase
+	var groupSuites []JUnitTestSuite
 
-	for i, step := range result.Steps {
-		tc := JUnitTestCase{
-			Name:      stepTestCaseName(step),
-			Classname: scenarioFile,
-			Time:      "0.000",
+	for _, step := range result.Steps {
+		if len(result.Groups) > 0 && step.Group != "" {
+			continue // reported in the group's nested suite
 		}
-
-		if !step.Passed {
+		tc, failed, skip := junitTestCase(step, scenarioFile)
+		if failed {
 			failures++
-			msg := fmt.Sprintf("called %d times, minimum %d required", step.CallCount, step.Min)
-			tc.Failure = &JUnitFailure{
-				Message: msg,
-				Type:    "VerificationFailure",
-				Content: msg,
-			}
-		} else if step.Min == 0 && step.CallCount == 0 {
+		}
+		if skip {
 			skipped++
-			tc.Skipped = &JUnitSkipped{
-				Message: "optional step (min=0), not called",
+		}
+		cases = append(cases, tc)
+	}
+
+	for _, g := range result.Groups {
+		gs := JUnitTestSuite{
+			Name:      result.Scenario + "/" + g.Name,
+			Tests:     len(g.Steps),
+			Time:      "0.000",
+			Timestamp: timestamp.Format(time.RFC3339),
+		}
+		for _, step := range g.Steps {
+			tc, failed, skip := junitTestCase(step, scenarioFile)
+			if failed {
+				gs.Failures++
 			}
+			if skip {
+				gs.Skipped++
+			}
+			gs.Cases = append(gs.Cases, tc)
 		}
+		failures += gs.Failures
+		skipped += gs.Skipped
+		groupSuites = append(groupSuites, gs)
+	}
 
-		cases[i] = tc
+	tests := result.TotalSteps
+	if len(result.ForbiddenCalls) > 0 {
+		cases = append(cases, junitForbiddenCase(result.ForbiddenCalls, scenarioFile))
+		tests++
+		failures++
 	}
 
-	suites := JUnitTestSuites{
-		Name:     "cli-replay",
-		Tests:    result.TotalSteps,
-		Failures: failures,
-		Errors:   0,
-		Time:     "0.000",
-		Suites: []JUnitTestSuite{
-			{
-				Name:      result.Scenario,
-				Tests:     result.TotalSteps,
-				Failures:  failures,
-				Errors:    0,
-				Skipped:   skipped,
-				Time:      "0.000",
-				Timestamp: timestamp.Format(time.RFC3339),
-				Cases:     cases,
-			},
-		},
+	return JUnitTestSuite{
+		Name:      result.Scenario,
+		Tests:     tests,
+		Failures:  failures,
+		Errors:    0,
+		Skipped:   skipped,
+		Time:      "0.000",
+		Timestamp: timestamp.Format(time.RFC3339),
+		Cases:     cases,
+		Suites:    groupSuites,
 	}
+}
 
+// writeJUnit writes suites as indented JUnit XML with the XML header.
+func writeJUnit(w io.Writer, suites JUnitTestSuites) error {
 	if _, err := io.WriteString(w, xml.Header); err != nil {
 		return err
 	}
@@ -129,49 +158,116 @@ func FormatJUnit(w io.Writer, result *VerifyResult, scenarioFile string, timesta
 	return err
 }
 
-// formatJUnitError writes JUnit XML for an error state (e.g., no state file).
-func formatJUnitError(w io.Writer, result *VerifyResult, scenarioFile string, timestamp time.Time) error {
-	suites := JUnitTestSuites{
-		Name:     "cli-replay",
-		Tests:    0,
-		Failures: 0,
-		Errors:   1,
-		Time:     "0.000",
-		Suites: []JUnitTestSuite{
+// junitForbiddenCase reports the calls made to forbidden commands as one
+// failed test case.
+func junitForbiddenCase(calls [][]string, scenarioFile string) JUnitTestCase {
+	lines := make([]string, len(calls))
+	for i, argv := range calls {
+		lines[i] = strings.Join(argv, " ")
+	}
+	return JUnitTestCase{
+		Name:      "forbidden commands",
+		Classname: scenarioFile,
+		Time:      "0.000",
+		Failure: &JUnitFailure{
+			Message: fmt.Sprintf("%d call(s) to forbidden commands", len(calls)),
+			Type:    "ForbiddenCommandFailure",
+			Content: strings.Join(lines, "\n"),
+		},
+	}
+}
+
+// junitTestCase converts a step result into a JUnit test case, reporting
+// whether it counts as a failure or as skipped.
+func junitTestCase(step StepResult, scenarioFile string) (tc JUnitTestCase, failed, skipped bool) {
+	tc = JUnitTestCase{
+		Name:      stepTestCaseName(step),
+		Classname: scenarioFile,
+		Time:      "0.000",
+	}
+
+	if step.DurationMs > 0 {
+		tc.Time = fmt.Sprintf("%.3f", float64(step.DurationMs)/1000)
+	}
+
+	if step.TimedOut {
+		msg := fmt.Sprintf("served in %dms, timeout %s exceeded", step.DurationMs, step.Timeout)
+		tc.Failure = &JUnitFailure{
+			Message: msg,
+			Type:    "TimeoutFailure",
+			Content: msg,
+		}
+		return tc, true, false
+	}
+	if step.GapExceeded {
+		msg := fmt.Sprintf("called %dms after the previous step, expect_within %s exceeded", step.GapMs, step.ExpectWithin)
+		tc.Failure = &JUnitFailure{
+			Message: msg,
+			Type:    "ExpectWithinFailure",
+			Content: msg,
+		}
+		return tc, true, false
+	}
+	if step.AssertFailure != "" {
+		msg := "respond.assert failed: " + step.AssertFailure
+		tc.Failure = &JUnitFailure{
+			Message: msg,
+			Type:    "AssertFailure",
+			Content: msg,
+		}
+		return tc, true, false
+	}
+	if step.Status == StatusOver {
+		msg := fmt.Sprintf("called %d times, maximum %d allowed", step.CallCount, step.Max)
+		tc.Failure = &JUnitFailure{
+			Message: msg,
+			Type:    "VerificationFailure",
+			Content: msg,
+		}
+		return tc, true, false
+	}
+	if !step.Passed {
+		msg := fmt.Sprintf("called %d times, minimum %d required", step.CallCount, step.Min)
+		tc.Failure = &JUnitFailure{
+			Message: msg,
+			Type:    "VerificationFailure",
+			Content: msg,
+		}
+		return tc, true, false
+	}
+	if step.Min == 0 && step.CallCount == 0 {
+		tc.Skipped = &JUnitSkipped{
+			Message: "optional step (min=0), not called",
+		}
+		return tc, false, true
+	}
+	return tc, false, false
+}
+
+// junitErrorSuite builds the test suite for an error state (e.g., no
+// state file): a single errored "state" test case.
+func junitErrorSuite(result *VerifyResult, scenarioFile string, timestamp time.Time) JUnitTestSuite {
+	return JUnitTestSuite{
+		Name:      result.Scenario,
+		Tests:     0,
+		Failures:  0,
+		Errors:    1,
+		Skipped:   0,
+		Time:      "0.000",
+		Timestamp: timestamp.Format(time.RFC3339),
+		Cases: []JUnitTestCase{
 			{
-				Name:      result.Scenario,
-				Tests:     0,
-				Failures:  0,
-				Errors:    1,
-				Skipped:   0,
+				Name:      "state",
+				Classname: scenarioFile,
 				Time:      "0.000",
-				Timestamp: timestamp.Format(time.RFC3339),
-				Cases: []JUnitTestCase{
-					{
-						Name:      "state",
-						Classname: scenarioFile,
-						Time:      "0.000",
-						Failure: &JUnitFailure{
-							Message: result.Error,
-							Type:    "StateError",
-							Content: "no state file found for scenario",
-						},
-					},
+				Failure: &JUnitFailure{
+					Message: result.Error,
+					Type:    "StateError",
+					Content: "no state file found for scenario",
 				},
 			},
 		},
 	}
-
-	if _, err := io.WriteString(w, xml.Header); err != nil {
-		return err
-	}
-	enc := xml.NewEncoder(w)
-	enc.Indent("", "  ")
-	if err := enc.Encode(suites); err != nil {
-		return err
-	}
-	_, err := io.WriteString(w, "\n")
-	return err
 }
 
 // stepTestCaseName builds the JUnit test case name from a StepResult.
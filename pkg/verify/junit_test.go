@@ -16,9 +16,9 @@ var testTimestamp = time.Date(2026, 2, 7, 10, 30, 0, 0, time.UTC)
 
 func TestFormatJUnit_AllPassed(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"kubectl", "apply", "-f", "app.yaml"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "apply", "-f", "app.yaml"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	state := []int{1, 1, 1}
 	result := BuildResult("deploy-app", "default", steps, state, nil)
@@ -63,8 +63,8 @@ func TestFormatJUnit_AllPassed(t *testing.T) {
 
 func TestFormatJUnit_FailureElements(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"docker", "info"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"docker", "info"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	state := []int{1, 0}
 	result := BuildResult("deploy-app", "default", steps, state, nil)
@@ -91,12 +91,32 @@ func TestFormatJUnit_FailureElements(t *testing.T) {
 	assert.Equal(t, "called 0 times, minimum 1 required", suite.Cases[1].Failure.Content)
 }
 
+func TestFormatJUnit_TimeoutFailure(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Respond: scenario.Response{Exit: scenario.Exit(0), Timeout: "10ms"}},
+	}
+	result := BuildResult("deploy-app", "default", steps, []int{1}, nil,
+		WithStepDurations([]time.Duration{1500 * time.Millisecond}))
+
+	var buf bytes.Buffer
+	require.NoError(t, FormatJUnit(&buf, result, "scenario.yaml", testTimestamp))
+
+	var parsed JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+
+	tc := parsed.Suites[0].Cases[0]
+	assert.Equal(t, "1.500", tc.Time)
+	require.NotNil(t, tc.Failure)
+	assert.Equal(t, "TimeoutFailure", tc.Failure.Type)
+	assert.Equal(t, "served in 1500ms, timeout 10ms exceeded", tc.Failure.Message)
+}
+
 func TestFormatJUnit_SkippedForMinZero(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 		{
 			Match:   scenario.Match{Argv: []string{"docker", "info"}},
-			Respond: scenario.Response{Exit: 0},
+			Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls:   &scenario.CallBounds{Min: 0, Max: 3},
 		},
 	}
@@ -149,7 +169,7 @@ func TestFormatJUnit_ErrorState(t *testing.T) {
 
 func TestFormatJUnit_XMLValidity(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	state := []int{1}
 	result := BuildResult("test", "default", steps, state, nil)
@@ -173,7 +193,7 @@ func TestFormatJUnit_AttributeMapping(t *testing.T) {
 	steps := []scenario.Step{
 		{
 			Match:   scenario.Match{Argv: []string{"kubectl", "get", "pods"}},
-			Respond: scenario.Response{Exit: 0},
+			Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls:   &scenario.CallBounds{Min: 2, Max: 5},
 		},
 	}
@@ -254,3 +274,68 @@ func TestStepTestCaseName(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatJUnit_OverMaxFailure(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Calls: &scenario.CallBounds{Min: 1, Max: 2}},
+	}
+	result := BuildResult("polling", "default", steps, []int{3}, nil)
+
+	var buf bytes.Buffer
+	require.NoError(t, FormatJUnit(&buf, result, "scenario.yaml", testTimestamp))
+
+	var parsed JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	assert.Equal(t, 1, parsed.Failures)
+	tc := parsed.Suites[0].Cases[0]
+	require.NotNil(t, tc.Failure)
+	assert.Equal(t, "VerificationFailure", tc.Failure.Type)
+	assert.Equal(t, "called 3 times, maximum 2 allowed", tc.Failure.Message)
+}
+
+func TestFormatJUnit_ForbiddenCalls(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}},
+	}
+	calls := [][]string{{"curl", "https://example.com"}, {"wget", "-q", "x"}}
+	result := BuildResult("egress", "default", steps, []int{1}, nil, WithForbiddenCalls(calls))
+
+	var buf bytes.Buffer
+	require.NoError(t, FormatJUnit(&buf, result, "scenario.yaml", testTimestamp))
+
+	var parsed JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	assert.Equal(t, 2, parsed.Tests)
+	assert.Equal(t, 1, parsed.Failures)
+	cases := parsed.Suites[0].Cases
+	require.Len(t, cases, 2)
+	assert.Nil(t, cases[0].Failure)
+	assert.Equal(t, "forbidden commands", cases[1].Name)
+	require.NotNil(t, cases[1].Failure)
+	assert.Equal(t, "ForbiddenCommandFailure", cases[1].Failure.Type)
+	assert.Equal(t, "2 call(s) to forbidden commands", cases[1].Failure.Message)
+	assert.Contains(t, cases[1].Failure.Content, "curl https://example.com")
+}
+
+func TestFormatJUnit_NestsGroupSuites(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, FormatJUnit(&buf, twoGroupResult(), "grouped.yaml", testTimestamp))
+
+	var parsed JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	assert.Equal(t, 5, parsed.Tests)
+	assert.Equal(t, 1, parsed.Failures)
+
+	suite := parsed.Suites[0]
+	require.Len(t, suite.Cases, 1, "only ungrouped steps are direct cases")
+	assert.Equal(t, "step[0]: login", suite.Cases[0].Name)
+
+	require.Len(t, suite.Suites, 2)
+	assert.Equal(t, "grouped/pre-flight", suite.Suites[0].Name)
+	assert.Equal(t, 2, suite.Suites[0].Tests)
+	assert.Equal(t, 0, suite.Suites[0].Failures)
+	assert.Equal(t, "grouped/checks", suite.Suites[1].Name)
+	assert.Equal(t, 1, suite.Suites[1].Failures)
+	require.NotNil(t, suite.Suites[1].Cases[1].Failure)
+	assert.Equal(t, "[group:checks] step[4]: check api", suite.Suites[1].Cases[1].Name)
+}
@@ -31,8 +31,8 @@ func TestBuildResult_BudgetAware_Table(t *testing.T) {
 		{
 			name: "all required steps met exactly",
 			steps: []scenario.Step{
-				{Match: scenario.Match{Argv: []string{"cmd1"}}, Respond: scenario.Response{Exit: 0}},
-				{Match: scenario.Match{Argv: []string{"cmd2"}}, Respond: scenario.Response{Exit: 0}},
+				{Match: scenario.Match{Argv: []string{"cmd1"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+				{Match: scenario.Match{Argv: []string{"cmd2"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 			},
 			stepCounts:  []int{1, 1},
 			wantPassed:  true,
@@ -42,8 +42,8 @@ func TestBuildResult_BudgetAware_Table(t *testing.T) {
 		{
 			name: "optional step uncalled is still passing",
 			steps: []scenario.Step{
-				{Match: scenario.Match{Argv: []string{"required"}}, Respond: scenario.Response{Exit: 0}},
-				{Match: scenario.Match{Argv: []string{"optional"}}, Respond: scenario.Response{Exit: 0},
+				{Match: scenario.Match{Argv: []string{"required"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+				{Match: scenario.Match{Argv: []string{"optional"}}, Respond: scenario.Response{Exit: scenario.Exit(0)},
 					Calls: &scenario.CallBounds{Min: 0, Max: 3}},
 			},
 			stepCounts:  []int{1, 0},
@@ -54,7 +54,7 @@ func TestBuildResult_BudgetAware_Table(t *testing.T) {
 		{
 			name: "multi-call step meets minimum",
 			steps: []scenario.Step{
-				{Match: scenario.Match{Argv: []string{"poll"}}, Respond: scenario.Response{Exit: 0},
+				{Match: scenario.Match{Argv: []string{"poll"}}, Respond: scenario.Response{Exit: scenario.Exit(0)},
 					Calls: &scenario.CallBounds{Min: 3, Max: 10}},
 			},
 			stepCounts:  []int{5},
@@ -65,7 +65,7 @@ func TestBuildResult_BudgetAware_Table(t *testing.T) {
 		{
 			name: "multi-call step below minimum fails",
 			steps: []scenario.Step{
-				{Match: scenario.Match{Argv: []string{"poll"}}, Respond: scenario.Response{Exit: 0},
+				{Match: scenario.Match{Argv: []string{"poll"}}, Respond: scenario.Response{Exit: scenario.Exit(0)},
 					Calls: &scenario.CallBounds{Min: 3, Max: 10}},
 			},
 			stepCounts:  []int{2},
@@ -76,10 +76,10 @@ func TestBuildResult_BudgetAware_Table(t *testing.T) {
 		{
 			name: "mixed required and optional",
 			steps: []scenario.Step{
-				{Match: scenario.Match{Argv: []string{"setup"}}, Respond: scenario.Response{Exit: 0}},
-				{Match: scenario.Match{Argv: []string{"health"}}, Respond: scenario.Response{Exit: 0},
+				{Match: scenario.Match{Argv: []string{"setup"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+				{Match: scenario.Match{Argv: []string{"health"}}, Respond: scenario.Response{Exit: scenario.Exit(0)},
 					Calls: &scenario.CallBounds{Min: 0, Max: 5}},
-				{Match: scenario.Match{Argv: []string{"deploy"}}, Respond: scenario.Response{Exit: 0}},
+				{Match: scenario.Match{Argv: []string{"deploy"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 			},
 			stepCounts:  []int{1, 3, 1},
 			wantPassed:  true,
@@ -89,7 +89,7 @@ func TestBuildResult_BudgetAware_Table(t *testing.T) {
 		{
 			name: "empty step counts array",
 			steps: []scenario.Step{
-				{Match: scenario.Match{Argv: []string{"cmd"}}, Respond: scenario.Response{Exit: 0}},
+				{Match: scenario.Match{Argv: []string{"cmd"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 			},
 			stepCounts:  []int{},
 			wantPassed:  false,
@@ -113,10 +113,10 @@ func TestBuildResult_BudgetAware_Table(t *testing.T) {
 
 func TestBuildResult_GroupLabelsAndGroupField(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"setup"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"check", "dns"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"check", "api"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"deploy"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"setup"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"check", "dns"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"check", "api"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"deploy"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	groupRanges := []scenario.GroupRange{
 		{Start: 1, End: 3, Name: "pre-flight", TopIndex: 1},
@@ -144,7 +144,7 @@ func TestBuildResult_GroupLabelsAndGroupField(t *testing.T) {
 
 func TestFormatJSON_BudgetFields_Preserved(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"poll"}}, Respond: scenario.Response{Exit: 0},
+		{Match: scenario.Match{Argv: []string{"poll"}}, Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls: &scenario.CallBounds{Min: 2, Max: 10}},
 	}
 	state := []int{5}
@@ -167,7 +167,7 @@ func TestFormatJSON_BudgetFields_Preserved(t *testing.T) {
 
 func TestFormatJUnit_FailedStep_MessageContent(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"cmd"}}, Respond: scenario.Response{Exit: 0},
+		{Match: scenario.Match{Argv: []string{"cmd"}}, Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls: &scenario.CallBounds{Min: 3, Max: 5}},
 	}
 	state := []int{1}
@@ -194,8 +194,8 @@ func TestFormatJUnit_FailedStep_MessageContent(t *testing.T) {
 
 func TestFormatJUnit_OptionalStep_Skipped(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"required"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"optional"}}, Respond: scenario.Response{Exit: 0},
+		{Match: scenario.Match{Argv: []string{"required"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"optional"}}, Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls: &scenario.CallBounds{Min: 0, Max: 3}},
 	}
 	state := []int{1, 0}
@@ -3,7 +3,9 @@
 package verify
 
 import (
+	"path"
 	"strings"
+	"time"
 
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 )
@@ -17,8 +19,114 @@ type VerifyResult struct {
 	ConsumedSteps int          `json:"consumed_steps"`
 	Error         string       `json:"error,omitempty"`
 	Steps         []StepResult `json:"steps"`
+	// Groups summarizes each step group, with its steps nested. Omitted for
+	// scenarios without groups; Steps still lists every step.
+	Groups []GroupResult `json:"groups,omitempty"`
+	// Captures holds the session's captured values when requested via
+	// WithCaptures. Omitted from the report by default.
+	Captures map[string]string `json:"captures,omitempty"`
+	// ForbiddenCalls lists the argv of each call to a command in
+	// meta.security.forbidden_commands. Any entry fails verification.
+	ForbiddenCalls [][]string `json:"forbidden_calls,omitempty"`
 }
 
+// RedactedValue replaces capture values whose names match a redact pattern.
+const RedactedValue = "[REDACTED]"
+
+// buildConfig holds the optional inputs to BuildResult.
+type buildConfig struct {
+	captures       map[string]string
+	redactPatterns []string
+	durations      []time.Duration
+	servedAt       []time.Time
+	lastServedAt   []time.Time
+	assertFailures []string
+	forbiddenCalls [][]string
+}
+
+// BuildOption configures optional content of a VerifyResult.
+type BuildOption func(*buildConfig)
+
+// WithCaptures includes the session's captured values in the result. Values
+// of captures whose names match any of redactPatterns (path.Match globs, the
+// same syntax as meta.security.deny_env_vars) are replaced by RedactedValue.
+func WithCaptures(captures map[string]string, redactPatterns []string) BuildOption {
+	return func(c *buildConfig) {
+		c.captures = captures
+		c.redactPatterns = redactPatterns
+	}
+}
+
+// WithStepDurations supplies the per-step service times recorded during
+// replay. Each step's duration is reported, and a step whose duration
+// exceeds its respond.timeout is marked timed out and fails verification.
+func WithStepDurations(durations []time.Duration) BuildOption {
+	return func(c *buildConfig) {
+		c.durations = durations
+	}
+}
+
+// WithServedTimes supplies when each step was first and last called. The
+// gap from the previous step's last call to each step's first call is
+// reported, and a step whose gap exceeds its expect_within fails
+// verification. Zero times mean the step was not called.
+func WithServedTimes(first, last []time.Time) BuildOption {
+	return func(c *buildConfig) {
+		c.servedAt = first
+		c.lastServedAt = last
+	}
+}
+
+// WithAssertFailures supplies the respond.assert failure recorded for each
+// step, "" for none. A step with a failure fails verification.
+func WithAssertFailures(failures []string) BuildOption {
+	return func(c *buildConfig) {
+		c.assertFailures = failures
+	}
+}
+
+// WithForbiddenCalls supplies the calls made to forbidden commands. Any
+// call fails verification.
+func WithForbiddenCalls(calls [][]string) BuildOption {
+	return func(c *buildConfig) {
+		c.forbiddenCalls = calls
+	}
+}
+
+// redactCaptures copies captures, replacing values whose names match any of
+// patterns with RedactedValue. Returns nil for an empty map.
+func redactCaptures(captures map[string]string, patterns []string) map[string]string {
+	if len(captures) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(captures))
+	for k, v := range captures {
+		if matchesAny(k, patterns) {
+			v = RedactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// matchesAny reports whether name matches any glob pattern. Invalid
+// patterns are skipped.
+func matchesAny(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Step call-count statuses reported in StepResult.Status.
+const (
+	StatusUnder = "under" // called fewer than calls.min times
+	StatusOK    = "ok"    // called within [calls.min, calls.max]
+	StatusOver  = "over"  // called more than calls.max times
+)
+
 // StepResult represents the verification status of a single step.
 type StepResult struct {
 	Index     int    `json:"index"`
@@ -27,19 +135,55 @@ type StepResult struct {
 	CallCount int    `json:"call_count"`
 	Min       int    `json:"min"`
 	Max       int    `json:"max"`
-	Passed    bool   `json:"passed"`
+	// Status compares CallCount with the bounds: under, ok, or over.
+	Status string `json:"status"`
+	Passed bool   `json:"passed"`
+	// DurationMs is the longest wall time, in milliseconds, spent serving a
+	// call to this step. Zero when no timing was recorded.
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	// Timeout echoes respond.timeout when set.
+	Timeout  string `json:"timeout,omitempty"`
+	TimedOut bool   `json:"timed_out"`
+	// GapMs is the time, in milliseconds, from the previous step's last
+	// call to this step's first call. Zero when either was not called.
+	GapMs int64 `json:"gap_ms,omitempty"`
+	// ExpectWithin echoes expect_within when set.
+	ExpectWithin string `json:"expect_within,omitempty"`
+	GapExceeded  bool   `json:"gap_exceeded"`
+	// AssertFailure describes the first failed respond.assert check of
+	// any call to this step.
+	AssertFailure string `json:"assert_failure,omitempty"`
+}
+
+// GroupResult summarizes the verification status of one step group.
+type GroupResult struct {
+	Name string `json:"name"`
+	Mode string `json:"mode"`
+	// MinsMet reports whether every step of the group reached calls.min.
+	MinsMet bool `json:"mins_met"`
+	// Passed reports whether every step of the group passed.
+	Passed      bool         `json:"passed"`
+	TotalSteps  int          `json:"total_steps"`
+	PassedSteps int          `json:"passed_steps"`
+	CallCount   int          `json:"call_count"`
+	Steps       []StepResult `json:"steps"`
 }
 
 // BuildResult constructs a VerifyResult from a scenario's steps and per-step
 // call counts. The steps parameter should be the flat list of leaf steps
 // (from Scenario.FlatSteps()). groupRanges may be nil for scenarios without
 // groups. If stepCounts is nil, an error result is returned with "no state
-// found".
-func BuildResult(scenarioName, session string, steps []scenario.Step, stepCounts []int, groupRanges []scenario.GroupRange) *VerifyResult {
+// found". Options add optional content such as captured values.
+func BuildResult(scenarioName, session string, steps []scenario.Step, stepCounts []int, groupRanges []scenario.GroupRange, opts ...BuildOption) *VerifyResult {
 	if stepCounts == nil {
 		return BuildErrorResult(scenarioName, session, "no state found")
 	}
 
+	var cfg buildConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	// Build lookup from flat index → group name
 	groupNameByIndex := make(map[int]string)
 	for _, gr := range groupRanges {
@@ -64,7 +208,28 @@ func BuildResult(scenarioName, session string, steps []scenario.Step, stepCounts
 			callCount = stepCounts[i]
 		}
 
-		passed := callCount >= bounds.Min
+		var duration time.Duration
+		if i < len(cfg.durations) {
+			duration = cfg.durations[i]
+		}
+		timedOut := false
+		if timeout, err := step.Respond.TimeoutDuration(); err == nil && timeout > 0 {
+			timedOut = duration > timeout
+		}
+
+		gap, measured := cfg.gap(i)
+		gapExceeded := false
+		if within, err := step.ExpectWithinDuration(); err == nil && within > 0 && measured {
+			gapExceeded = gap > within
+		}
+
+		var assertFailure string
+		if i < len(cfg.assertFailures) {
+			assertFailure = cfg.assertFailures[i]
+		}
+
+		status := CallStatus(callCount, bounds)
+		passed := status == StatusOK && !timedOut && !gapExceeded && assertFailure == ""
 		if !passed {
 			allPassed = false
 		}
@@ -85,17 +250,98 @@ func BuildResult(scenarioName, session string, steps []scenario.Step, stepCounts
 			CallCount: callCount,
 			Min:       bounds.Min,
 			Max:       bounds.Max,
+			Status:    status,
 			Passed:    passed,
+
+			DurationMs: duration.Milliseconds(),
+			Timeout:    step.Respond.Timeout,
+			TimedOut:   timedOut,
+
+			GapMs:        gap.Milliseconds(),
+			ExpectWithin: step.ExpectWithin,
+			GapExceeded:  gapExceeded,
+
+			AssertFailure: assertFailure,
 		}
 	}
 
+	result.Groups = buildGroupResults(result.Steps, groupRanges)
 	result.TotalSteps = len(steps)
 	result.ConsumedSteps = consumed
-	result.Passed = allPassed
+	result.ForbiddenCalls = cfg.forbiddenCalls
+	result.Passed = allPassed && len(cfg.forbiddenCalls) == 0
+	result.Captures = redactCaptures(cfg.captures, cfg.redactPatterns)
 
 	return result
 }
 
+// gap returns the time from step i-1's last call to step i's first call,
+// and false when either call was not recorded. A step reached before its
+// predecessor's last call (as in unordered groups) has a zero gap.
+func (c *buildConfig) gap(i int) (time.Duration, bool) {
+	if i < 1 || i >= len(c.servedAt) || i-1 >= len(c.lastServedAt) {
+		return 0, false
+	}
+	first, prev := c.servedAt[i], c.lastServedAt[i-1]
+	if first.IsZero() || prev.IsZero() {
+		return 0, false
+	}
+	if d := first.Sub(prev); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// buildGroupResults aggregates the step results of each group range.
+func buildGroupResults(steps []StepResult, groupRanges []scenario.GroupRange) []GroupResult {
+	if len(groupRanges) == 0 {
+		return nil
+	}
+	groups := make([]GroupResult, 0, len(groupRanges))
+	for _, gr := range groupRanges {
+		g := GroupResult{
+			Name:    gr.Name,
+			Mode:    gr.Mode,
+			MinsMet: true,
+			Passed:  true,
+		}
+		if g.Mode == "" {
+			g.Mode = scenario.GroupModeUnordered
+		}
+		for i := gr.Start; i < gr.End && i < len(steps); i++ {
+			step := steps[i]
+			g.Steps = append(g.Steps, step)
+			g.TotalSteps++
+			g.CallCount += step.CallCount
+			if step.CallCount < step.Min {
+				g.MinsMet = false
+			}
+			if step.Passed {
+				g.PassedSteps++
+			} else {
+				g.Passed = false
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// CallStatus classifies a step's call count against its bounds. A count
+// over calls.max should not happen, since replay stops matching a step once
+// its budget is spent, but can under concurrent updates; it fails
+// verification like a count under calls.min.
+func CallStatus(callCount int, bounds scenario.CallBounds) string {
+	switch {
+	case callCount < bounds.Min:
+		return StatusUnder
+	case callCount > bounds.Max:
+		return StatusOver
+	default:
+		return StatusOK
+	}
+}
+
 // BuildErrorResult constructs a VerifyResult representing an error condition
 // (e.g., no state file found).
 func BuildErrorResult(scenarioName, session, errMsg string) *VerifyResult {
@@ -110,5 +356,5 @@ func BuildErrorResult(scenarioName, session, errMsg string) *VerifyResult {
 
 // StepLabel builds a human-readable label from a step's argv.
 func StepLabel(step scenario.Step) string {
-	return strings.Join(step.Match.Argv, " ")
+	return strings.Join(step.Match.PrimaryArgv(), " ")
 }
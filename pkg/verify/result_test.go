@@ -2,6 +2,7 @@ package verify
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ormasoftchile/cli-replay/pkg/scenario"
 	"github.com/stretchr/testify/assert"
@@ -9,8 +10,8 @@ import (
 
 func TestBuildResult_AllPassed(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	result := BuildResult("test-scenario", "default", steps, []int{1, 1}, nil)
 
@@ -36,8 +37,8 @@ func TestBuildResult_AllPassed(t *testing.T) {
 
 func TestBuildResult_Incomplete(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"kubectl", "apply", "-f", "app.yaml"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "apply", "-f", "app.yaml"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	result := BuildResult("deploy-app", "default", steps, []int{1, 0}, nil)
 
@@ -52,7 +53,7 @@ func TestBuildResult_Incomplete(t *testing.T) {
 
 func TestBuildResult_NoState(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 
 	result := BuildResult("test-scenario", "default", steps, nil, nil)
@@ -69,11 +70,11 @@ func TestBuildResult_OptionalStepMinZero(t *testing.T) {
 	steps := []scenario.Step{
 		{
 			Match:   scenario.Match{Argv: []string{"git", "status"}},
-			Respond: scenario.Response{Exit: 0},
+			Respond: scenario.Response{Exit: scenario.Exit(0)},
 		},
 		{
 			Match:   scenario.Match{Argv: []string{"docker", "info"}},
-			Respond: scenario.Response{Exit: 0},
+			Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls:   &scenario.CallBounds{Min: 0, Max: 3},
 		},
 	}
@@ -133,7 +134,7 @@ func TestBuildResult_MultiCallStep(t *testing.T) {
 	steps := []scenario.Step{
 		{
 			Match:   scenario.Match{Argv: []string{"kubectl", "get", "pods"}},
-			Respond: scenario.Response{Exit: 0},
+			Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls:   &scenario.CallBounds{Min: 2, Max: 5},
 		},
 	}
@@ -151,7 +152,7 @@ func TestBuildResult_MultiCallStep(t *testing.T) {
 func TestBuildResult_GroupFieldEmpty(t *testing.T) {
 	// Group field should be empty string (omitted in JSON) for non-group steps
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	result := BuildResult("test", "default", steps, []int{1}, nil)
 
@@ -160,10 +161,10 @@ func TestBuildResult_GroupFieldEmpty(t *testing.T) {
 
 func TestBuildResult_GroupFieldPopulated(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"setup"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"check", "a"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"check", "b"}}, Respond: scenario.Response{Exit: 0}},
-		{Match: scenario.Match{Argv: []string{"deploy"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"setup"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"check", "a"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"check", "b"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+		{Match: scenario.Match{Argv: []string{"deploy"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	groupRanges := []scenario.GroupRange{
 		{Start: 1, End: 3, Name: "pre-flight", TopIndex: 1},
@@ -185,3 +186,183 @@ func TestBuildResult_GroupFieldPopulated(t *testing.T) {
 	assert.Empty(t, result.Steps[3].Group)
 	assert.Equal(t, "deploy", result.Steps[3].Label)
 }
+
+func TestBuildResult_CapturesOmittedByDefault(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+	}
+	result := BuildResult("test", "default", steps, []int{1}, nil)
+
+	assert.Nil(t, result.Captures)
+}
+
+func TestBuildResult_WithCapturesRedacts(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"git", "status"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+	}
+	captures := map[string]string{"rg_id": "abc-123", "api_token": "s3cret"}
+	result := BuildResult("test", "default", steps, []int{1}, nil,
+		WithCaptures(captures, []string{"*_token"}))
+
+	assert.Equal(t, map[string]string{"rg_id": "abc-123", "api_token": RedactedValue}, result.Captures)
+	assert.Equal(t, "s3cret", captures["api_token"], "input map must not be modified")
+}
+
+func TestBuildResult_WithStepDurationsFlagsTimeout(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"slow"}}, Respond: scenario.Response{Exit: scenario.Exit(0), Timeout: "10ms"}},
+		{Match: scenario.Match{Argv: []string{"fast"}}, Respond: scenario.Response{Exit: scenario.Exit(0), Timeout: "1s"}},
+		{Match: scenario.Match{Argv: []string{"untimed"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
+	}
+	durations := []time.Duration{25 * time.Millisecond, 5 * time.Millisecond, 2 * time.Second}
+	result := BuildResult("test", "default", steps, []int{1, 1, 1}, nil, WithStepDurations(durations))
+
+	assert.False(t, result.Passed)
+	assert.True(t, result.Steps[0].TimedOut)
+	assert.False(t, result.Steps[0].Passed)
+	assert.Equal(t, int64(25), result.Steps[0].DurationMs)
+	assert.Equal(t, "10ms", result.Steps[0].Timeout)
+
+	assert.False(t, result.Steps[1].TimedOut)
+	assert.True(t, result.Steps[1].Passed)
+
+	assert.False(t, result.Steps[2].TimedOut, "steps without a timeout never time out")
+	assert.Equal(t, int64(2000), result.Steps[2].DurationMs)
+}
+
+func TestBuildResult_WithServedTimesMeasuresGaps(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"deploy"}}},
+		{Match: scenario.Match{Argv: []string{"status"}}, ExpectWithin: "1s"},
+		{Match: scenario.Match{Argv: []string{"rollback"}}, ExpectWithin: "1s"},
+		{Match: scenario.Match{Argv: []string{"cleanup"}}, ExpectWithin: "1s"},
+	}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := []time.Time{t0, t0.Add(300 * time.Millisecond), t0.Add(3 * time.Second), {}}
+	last := []time.Time{t0, t0.Add(500 * time.Millisecond), t0.Add(3 * time.Second), {}}
+	result := BuildResult("test", "default", steps, []int{1, 1, 1, 0}, nil, WithServedTimes(first, last))
+
+	assert.Zero(t, result.Steps[0].GapMs, "the first step has no predecessor")
+
+	assert.Equal(t, int64(300), result.Steps[1].GapMs)
+	assert.False(t, result.Steps[1].GapExceeded)
+	assert.True(t, result.Steps[1].Passed)
+
+	assert.Equal(t, int64(2500), result.Steps[2].GapMs, "measured from the previous step's last call")
+	assert.True(t, result.Steps[2].GapExceeded)
+	assert.False(t, result.Steps[2].Passed)
+	assert.Equal(t, "1s", result.Steps[2].ExpectWithin)
+
+	assert.False(t, result.Steps[3].GapExceeded, "an uncalled step has no gap")
+	assert.False(t, result.Passed)
+}
+
+func TestBuildResult_WithAssertFailures(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"az", "group", "show"}}},
+		{Match: scenario.Match{Argv: []string{"az", "group", "list"}}},
+	}
+	failures := []string{"", `stdout does not contain "resourceGroups"`}
+	result := BuildResult("test", "default", steps, []int{1, 1}, nil, WithAssertFailures(failures))
+
+	assert.True(t, result.Steps[0].Passed)
+	assert.Empty(t, result.Steps[0].AssertFailure)
+	assert.False(t, result.Steps[1].Passed)
+	assert.Equal(t, StatusOK, result.Steps[1].Status, "call counts alone are fine")
+	assert.Equal(t, failures[1], result.Steps[1].AssertFailure)
+	assert.False(t, result.Passed)
+}
+
+func TestBuildResult_WithForbiddenCalls(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}},
+	}
+	calls := [][]string{{"curl", "https://example.com"}}
+	result := BuildResult("test", "default", steps, []int{1}, nil, WithForbiddenCalls(calls))
+
+	assert.True(t, result.Steps[0].Passed)
+	assert.Equal(t, calls, result.ForbiddenCalls)
+	assert.False(t, result.Passed, "every step passed, but a forbidden command was invoked")
+
+	result = BuildResult("test", "default", steps, []int{1}, nil, WithForbiddenCalls(nil))
+	assert.True(t, result.Passed)
+}
+
+func TestBuildResult_CallStatuses(t *testing.T) {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"kubectl", "get", "pods"}}, Calls: &scenario.CallBounds{Min: 2, Max: 4}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "apply"}}, Calls: &scenario.CallBounds{Min: 1, Max: 2}},
+		{Match: scenario.Match{Argv: []string{"kubectl", "rollout"}}},
+	}
+
+	tests := []struct {
+		name     string
+		counts   []int
+		statuses []string
+		passed   bool
+	}{
+		{"all ok", []int{2, 2, 1}, []string{StatusOK, StatusOK, StatusOK}, true},
+		{"under min", []int{1, 1, 1}, []string{StatusUnder, StatusOK, StatusOK}, false},
+		{"over max", []int{4, 3, 1}, []string{StatusOK, StatusOver, StatusOK}, false},
+		{"mixed", []int{0, 1, 2}, []string{StatusUnder, StatusOK, StatusOver}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := BuildResult("polling", "default", steps, tt.counts, nil)
+			assert.Equal(t, tt.passed, result.Passed)
+			for i, want := range tt.statuses {
+				assert.Equal(t, want, result.Steps[i].Status, "step %d", i)
+				assert.Equal(t, want == StatusOK, result.Steps[i].Passed, "step %d", i)
+			}
+		})
+	}
+}
+
+// twoGroupResult builds a result with a satisfied "pre-flight" group and a
+// "checks" group whose second step was never called.
+func twoGroupResult() *VerifyResult {
+	steps := []scenario.Step{
+		{Match: scenario.Match{Argv: []string{"login"}}},
+		{Match: scenario.Match{Argv: []string{"az", "account", "show"}}},
+		{Match: scenario.Match{Argv: []string{"docker", "info"}}, Calls: &scenario.CallBounds{Min: 1, Max: 3}},
+		{Match: scenario.Match{Argv: []string{"check", "dns"}}},
+		{Match: scenario.Match{Argv: []string{"check", "api"}}},
+	}
+	groupRanges := []scenario.GroupRange{
+		{Start: 1, End: 3, Name: "pre-flight", Mode: scenario.GroupModeUnordered, TopIndex: 1},
+		{Start: 3, End: 5, Name: "checks", Mode: scenario.GroupModeOrdered, TopIndex: 2},
+	}
+	return BuildResult("grouped", "s1", steps, []int{1, 1, 2, 1, 0}, groupRanges)
+}
+
+func TestBuildResult_GroupSummaries(t *testing.T) {
+	result := twoGroupResult()
+	assert.False(t, result.Passed)
+	assert.Len(t, result.Steps, 5, "flat steps are still reported")
+	assert.Len(t, result.Groups, 2)
+
+	pre := result.Groups[0]
+	assert.Equal(t, "pre-flight", pre.Name)
+	assert.Equal(t, scenario.GroupModeUnordered, pre.Mode)
+	assert.True(t, pre.MinsMet)
+	assert.True(t, pre.Passed)
+	assert.Equal(t, 2, pre.TotalSteps)
+	assert.Equal(t, 2, pre.PassedSteps)
+	assert.Equal(t, 3, pre.CallCount)
+	assert.Equal(t, []int{1, 2}, []int{pre.Steps[0].Index, pre.Steps[1].Index})
+
+	checks := result.Groups[1]
+	assert.Equal(t, "checks", checks.Name)
+	assert.Equal(t, scenario.GroupModeOrdered, checks.Mode)
+	assert.False(t, checks.MinsMet)
+	assert.False(t, checks.Passed)
+	assert.Equal(t, 1, checks.PassedSteps)
+	assert.Equal(t, 1, checks.CallCount)
+	assert.Equal(t, StatusUnder, checks.Steps[1].Status)
+}
+
+func TestBuildResult_NoGroups(t *testing.T) {
+	steps := []scenario.Step{{Match: scenario.Match{Argv: []string{"git", "status"}}}}
+	result := BuildResult("flat", "s1", steps, []int{1}, nil)
+	assert.Nil(t, result.Groups)
+}
@@ -0,0 +1,11 @@
+// Package schema embeds the JSON Schema for cli-replay scenario files.
+package schema
+
+import _ "embed"
+
+// Scenario is the JSON Schema (draft-07) describing the scenario YAML
+// format. pkg/scenario tests check it against the model structs so the
+// two do not drift apart.
+//
+//go:embed scenario.schema.json
+var Scenario []byte
@@ -272,16 +272,16 @@ func TestIntegration_BudgetAware_UnorderedReplay(t *testing.T) {
 	steps := []scenario.Step{
 		{
 			Match:   scenario.Match{Argv: []string{"kubectl", "get", "pods"}},
-			Respond: scenario.Response{Exit: 0},
+			Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls:   &scenario.CallBounds{Min: 1, Max: 2},
 		},
 		{
 			Match:   scenario.Match{Argv: []string{"kubectl", "get", "svc"}},
-			Respond: scenario.Response{Exit: 0},
+			Respond: scenario.Response{Exit: scenario.Exit(0)},
 		},
 		{
 			Match:   scenario.Match{Argv: []string{"kubectl", "get", "nodes"}},
-			Respond: scenario.Response{Exit: 0},
+			Respond: scenario.Response{Exit: scenario.Exit(0)},
 			Calls:   &scenario.CallBounds{Min: 0, Max: 1},
 		},
 	}
@@ -373,7 +373,7 @@ steps:
 
 func TestIntegration_NilState_ErrorResult(t *testing.T) {
 	steps := []scenario.Step{
-		{Match: scenario.Match{Argv: []string{"cmd"}}, Respond: scenario.Response{Exit: 0}},
+		{Match: scenario.Match{Argv: []string{"cmd"}}, Respond: scenario.Response{Exit: scenario.Exit(0)}},
 	}
 	result := verify.BuildResult("test", "default", steps, nil, nil)
 	assert.False(t, result.Passed)